@@ -7,6 +7,7 @@ import (
 
 	"github.com/elves/elvish/pkg/buildinfo"
 	"github.com/elves/elvish/pkg/daemon"
+	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/prog"
 	"github.com/elves/elvish/pkg/shell"
 	"github.com/elves/elvish/pkg/web"
@@ -15,5 +16,6 @@ import (
 func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
-		buildinfo.Program, daemon.Program, web.Program, shell.Program))
+		eval.SpawnHelperProgram, buildinfo.Program, daemon.Program,
+		web.Program, shell.Program))
 }
@@ -6,7 +6,10 @@ require (
 	github.com/mattn/go-isatty v0.0.12
 	github.com/xiaq/persistent v0.0.0-20200820214153-3175cfb92e14
 	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	golang.org/x/sys v0.0.0-20200824131525-c12d262b63d8
+	golang.org/x/text v0.3.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 go 1.14
@@ -9,6 +9,8 @@ import (
 
 	"github.com/elves/elvish/pkg/buildinfo"
 	"github.com/elves/elvish/pkg/daemon"
+	"github.com/elves/elvish/pkg/elvfmt"
+	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/prog"
 	"github.com/elves/elvish/pkg/shell"
 )
@@ -16,5 +18,6 @@ import (
 func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
-		buildinfo.Program, daemon.Program, shell.Program))
+		eval.SpawnHelperProgram, buildinfo.Program, daemon.Program,
+		elvfmt.Program, shell.Program))
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -26,17 +27,24 @@ type Config struct {
 	Name    string
 	Replace diag.Ranging
 	Items   []Item
+	// A hint shown on the right of the mode line, such as the signature of the
+	// command whose argument is being completed.
+	Hint ui.Text
+	// Filters the items with the text typed in the completion UI. If nil,
+	// items containing the text are kept.
+	Filter cli.FilterFunc
 }
 
 // Start starts the completion UI.
 func Start(app cli.App, cfg Config) {
 	if len(cfg.Items) == 0 {
-		app.Notify("no candidates")
+		app.Notify(msg.Get("note-no-candidates"))
 		return
 	}
 	w := cli.NewComboBox(cli.ComboBoxSpec{
 		CodeArea: cli.CodeAreaSpec{
-			Prompt: cli.ModePrompt(" COMPLETING "+cfg.Name+" ", true),
+			Prompt:  cli.ModePrompt(" "+msg.Sprintf("mode-completion", cfg.Name)+" ", true),
+			RPrompt: func() ui.Text { return cfg.Hint },
 		},
 		ListBox: cli.ListBoxSpec{
 			Horizontal:     true,
@@ -57,7 +65,7 @@ func Start(app cli.App, cfg Config) {
 			ExtendStyle: true,
 		},
 		OnFilter: func(w cli.ComboBox, p string) {
-			w.ListBox().Reset(filter(cfg.Items, p), 0)
+			w.ListBox().Reset(filter(cfg.Items, p, cfg.Filter), 0)
 		},
 	})
 	app.MutateState(func(s *cli.State) { s.Addon = w })
@@ -74,8 +82,24 @@ func Close(app cli.App) {
 
 type items []Item
 
-func filter(all []Item, p string) items {
+func filter(all []Item, p string, f cli.FilterFunc) items {
+	if p == "" {
+		return all
+	}
 	var filtered []Item
+	if f != nil {
+		texts := make([]string, len(all))
+		for i, candidate := range all {
+			texts[i] = candidate.ToShow
+		}
+		matches := f(p, texts)
+		for i := 0; i < len(all) && i < len(matches); i++ {
+			if matches[i] {
+				filtered = append(filtered, all[i])
+			}
+		}
+		return filtered
+	}
 	for _, candidate := range all {
 		if strings.Contains(candidate.ToShow, p) {
 			filtered = append(filtered, candidate)
@@ -1,6 +1,7 @@
 package completion
 
 import (
+	"strings"
 	"testing"
 
 	. "github.com/elves/elvish/pkg/cli/clitest"
@@ -46,6 +47,36 @@ func TestFilter(t *testing.T) {
 	)
 }
 
+func TestFilter_CustomFilter(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+	Start(f.App, Config{
+		Name: "WORD",
+		Items: []Item{
+			{ToShow: "foo", ToInsert: "foo"},
+			{ToShow: "bar", ToInsert: "bar"},
+		},
+		Filter: func(p string, texts []string) []bool {
+			matches := make([]bool, len(texts))
+			for i, text := range texts {
+				matches[i] = strings.HasPrefix(text, p)
+			}
+			return matches
+		},
+	})
+
+	// The default filter would keep foo too.
+	f.TTY.Inject(term.K('b'))
+	f.TestTTY(t,
+		"bar\n", Styles,
+		"___",
+		" COMPLETING WORD  b", Styles,
+		"*****************  ", term.DotHere, "\n",
+		"bar", Styles,
+		"+++",
+	)
+}
+
 func TestAccept(t *testing.T) {
 	f := setupStarted(t)
 	defer f.Stop()
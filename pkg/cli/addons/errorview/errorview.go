@@ -0,0 +1,128 @@
+// Package errorview implements an addon that shows a possibly long error, such
+// as an exception with its stack trace, in a scrollable view.
+package errorview
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+// Config keeps the configuration for the errorview addon.
+type Config struct {
+	// Keybinding.
+	Binding cli.Handler
+	// The error to show.
+	Text ui.Text
+}
+
+type widget struct {
+	Config
+	lines []ui.Text
+
+	firstMutex sync.Mutex
+	first      int
+}
+
+func (w *widget) Render(width, height int) *term.Buffer {
+	buf := term.NewBufferBuilder(width).
+		WriteStyled(cli.ModeLine(" "+msg.Get("mode-error")+" ", false)).SetDotHere().Buffer()
+	if height <= 1 {
+		return buf
+	}
+	bodyHeight := height - 1
+
+	w.firstMutex.Lock()
+	if w.first > len(w.lines)-bodyHeight {
+		w.first = len(w.lines) - bodyHeight
+	}
+	if w.first < 0 {
+		w.first = 0
+	}
+	first := w.first
+	w.firstMutex.Unlock()
+
+	needScrollbar := first > 0 || first+bodyHeight < len(w.lines)
+	textWidth := width
+	if needScrollbar {
+		textWidth--
+	}
+	bb := term.NewBufferBuilder(textWidth)
+	for i := first; i < first+bodyHeight && i < len(w.lines); i++ {
+		if i > first {
+			bb.Newline()
+		}
+		bb.WriteStyled(w.lines[i].TrimWcwidth(textWidth))
+	}
+	body := bb.Buffer()
+	if needScrollbar {
+		scrollbar := cli.VScrollbar{
+			Total: len(w.lines), Low: first, High: first + bodyHeight}
+		body.ExtendRight(scrollbar.Render(1, bodyHeight))
+	}
+	buf.Extend(body, false)
+	return buf
+}
+
+func (w *widget) Focus() bool { return false }
+
+func (w *widget) Handle(event term.Event) bool {
+	if w.Binding.Handle(event) {
+		return true
+	}
+	switch event {
+	case term.K(ui.Up):
+		w.scrollBy(-1)
+		return true
+	case term.K(ui.Down):
+		w.scrollBy(1)
+		return true
+	}
+	return false
+}
+
+// Scrolling past the end is corrected in Render, since the height is only
+// known there.
+func (w *widget) scrollBy(delta int) {
+	w.firstMutex.Lock()
+	defer w.firstMutex.Unlock()
+	w.first += delta
+	if w.first < 0 {
+		w.first = 0
+	}
+	if w.first >= len(w.lines) {
+		w.first = len(w.lines) - 1
+	}
+}
+
+// Start starts the errorview addon.
+func Start(app cli.App, cfg Config) {
+	if cfg.Binding == nil {
+		cfg.Binding = cli.DummyHandler{}
+	}
+	w := widget{Config: cfg, lines: cfg.Text.SplitByRune('\n')}
+	app.MutateState(func(s *cli.State) { s.Addon = &w })
+	app.Redraw()
+}
+
+// Text returns the error shown by the errorview addon. It returns false as the
+// second return value if the addon is not active.
+func Text(app cli.App) (ui.Text, bool) {
+	w, ok := cli.Addon(app).(*widget)
+	if !ok {
+		return nil, false
+	}
+	return w.Text, true
+}
+
+// Close closes the errorview addon. It does nothing if the addon is not active.
+func Close(app cli.App) {
+	if _, ok := cli.Addon(app).(*widget); !ok {
+		return
+	}
+	app.MutateState(func(s *cli.State) { s.Addon = nil })
+	app.Redraw()
+}
@@ -0,0 +1,75 @@
+package errorview
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/cli/clitest"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestStart(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	Start(f.App, Config{Text: ui.T("line 1\nline 2", ui.FgRed)})
+	f.TestTTY(t,
+		term.DotHere, "\n",
+		" ERROR \n", Styles,
+		"******* ",
+		"line 1\n", Styles,
+		"!!!!!!",
+		"line 2", Styles,
+		"!!!!!!",
+	)
+}
+
+func TestScrolling(t *testing.T) {
+	f := Setup(WithTTY(func(tty TTYCtrl) { tty.SetSize(4, 10) }))
+	defer f.Stop()
+
+	Start(f.App, Config{Text: ui.T("1\n2\n3\n4")})
+	f.TestTTY(t,
+		term.DotHere, "\n",
+		" ERROR \n", Styles,
+		"******* ",
+		"1         \n", Styles,
+		"         X ",
+		"2        │", Styles,
+		"         -",
+	)
+
+	f.TTY.Inject(term.K(ui.Down))
+	f.TestTTY(t,
+		term.DotHere, "\n",
+		" ERROR \n", Styles,
+		"******* ",
+		"2        │\n", Styles,
+		"         - ",
+		"3         ", Styles,
+		"         X",
+	)
+}
+
+func TestText(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	if _, ok := Text(f.App); ok {
+		t.Errorf("Text returns true when not active")
+	}
+	Start(f.App, Config{Text: ui.T("error")})
+	if text, ok := Text(f.App); !ok || !reflect.DeepEqual(text, ui.T("error")) {
+		t.Errorf("Text returns %v, %v, want %v, true", text, ok, ui.T("error"))
+	}
+}
+
+func TestClose(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	Start(f.App, Config{Text: ui.T("error")})
+	Close(f.App)
+	f.TestTTY(t /* nothing */)
+}
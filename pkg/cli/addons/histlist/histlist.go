@@ -7,6 +7,7 @@ import (
 
 	"github.com/elves/elvish/pkg/cli"
 	"github.com/elves/elvish/pkg/cli/histutil"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/store"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -23,6 +24,10 @@ type Config struct {
 	// CaseSensitive is called to determine whether the filter should be
 	// case-sensitive. Defaults to true if unset.
 	CaseSensitive func() bool
+	// Filters the commands with the filter text. If nil, commands containing
+	// the filter text are kept. When the filter is case-insensitive, both the
+	// filter text and the commands are converted to lower case first.
+	Filter cli.FilterFunc
 }
 
 // Store wraps the AllCmds method. It is a subset of histutil.Store.
@@ -35,7 +40,7 @@ var _ = Store(histutil.Store(nil))
 // Start starts history listing.
 func Start(app cli.App, cfg Config) {
 	if cfg.Store == nil {
-		app.Notify("no history store")
+		app.Notify(msg.Get("note-no-history-store"))
 		return
 	}
 	if cfg.Dedup == nil {
@@ -47,7 +52,7 @@ func Start(app cli.App, cfg Config) {
 
 	cmds, err := cfg.Store.AllCmds()
 	if err != nil {
-		app.Notify("db error: " + err.Error())
+		app.Notify(msg.Sprintf("note-db-error", err))
 	}
 	last := map[string]int{}
 	for i, cmd := range cmds {
@@ -57,12 +62,12 @@ func Start(app cli.App, cfg Config) {
 
 	w := cli.NewComboBox(cli.ComboBoxSpec{
 		CodeArea: cli.CodeAreaSpec{Prompt: func() ui.Text {
-			content := " HISTORY "
+			content := " " + msg.Get("mode-history") + " "
 			if cfg.Dedup() {
-				content += "(dedup on) "
+				content += msg.Get("flag-dedup") + " "
 			}
 			if !cfg.CaseSensitive() {
-				content += "(case-insensitive) "
+				content += msg.Get("flag-case-insensitive") + " "
 			}
 			return cli.ModeLine(content, true)
 		}},
@@ -82,7 +87,7 @@ func Start(app cli.App, cfg Config) {
 			},
 		},
 		OnFilter: func(w cli.ComboBox, p string) {
-			it := cmdItems.filter(p, cfg.Dedup(), cfg.CaseSensitive())
+			it := cmdItems.filter(p, cfg.Dedup(), cfg.CaseSensitive(), cfg.Filter)
 			w.ListBox().Reset(it, it.Len()-1)
 		},
 	})
@@ -96,14 +101,15 @@ type items struct {
 	last    map[string]int
 }
 
-func (it items) filter(p string, dedup, caseSensitive bool) items {
+func (it items) filter(p string, dedup, caseSensitive bool, f cli.FilterFunc) items {
 	if p == "" && !dedup {
 		return it
 	}
 	if !caseSensitive {
 		p = strings.ToLower(p)
 	}
-	var filtered []store.Cmd
+	var candidates []store.Cmd
+	var texts []string
 	for i, entry := range it.entries {
 		text := entry.Text
 		if dedup && it.last[text] != i {
@@ -112,8 +118,25 @@ func (it items) filter(p string, dedup, caseSensitive bool) items {
 		if !caseSensitive {
 			text = strings.ToLower(text)
 		}
-		if strings.Contains(text, p) {
-			filtered = append(filtered, entry)
+		candidates = append(candidates, entry)
+		texts = append(texts, text)
+	}
+	if p == "" {
+		return items{candidates, nil}
+	}
+	var matches []bool
+	if f != nil {
+		matches = f(p, texts)
+	} else {
+		matches = make([]bool, len(texts))
+		for i, text := range texts {
+			matches[i] = strings.Contains(text, p)
+		}
+	}
+	var filtered []store.Cmd
+	for i := 0; i < len(candidates) && i < len(matches); i++ {
+		if matches[i] {
+			filtered = append(filtered, candidates[i])
 		}
 	}
 	return items{filtered, nil}
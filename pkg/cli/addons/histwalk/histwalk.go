@@ -3,10 +3,10 @@ package histwalk
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/elves/elvish/pkg/cli"
 	"github.com/elves/elvish/pkg/cli/histutil"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/cli/term"
 )
 
@@ -30,7 +30,7 @@ type widget struct {
 
 func (w *widget) Render(width, height int) *term.Buffer {
 	cmd, _ := w.cursor.Get()
-	content := cli.ModeLine(fmt.Sprintf(" HISTORY #%d ", cmd.Seq), false)
+	content := cli.ModeLine(" "+msg.Sprintf("mode-history-seq", cmd.Seq)+" ", false)
 	buf := term.NewBufferBuilder(width).WriteStyled(content).Buffer()
 	buf.TrimToLines(0, height)
 	return buf
@@ -60,7 +60,7 @@ func (w *widget) onWalk() {
 // Start starts the histwalk addon.
 func Start(app cli.App, cfg Config) {
 	if cfg.Store == nil {
-		app.Notify("no history store")
+		app.Notify(msg.Get("note-no-history-store"))
 		return
 	}
 	if cfg.Binding == nil {
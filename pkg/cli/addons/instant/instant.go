@@ -4,6 +4,7 @@ package instant
 
 import (
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/cli/term"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -26,7 +27,7 @@ type widget struct {
 
 func (w *widget) Render(width, height int) *term.Buffer {
 	bb := term.NewBufferBuilder(width).
-		WriteStyled(cli.ModeLine(" INSTANT ", false)).SetDotHere()
+		WriteStyled(cli.ModeLine(" "+msg.Get("mode-instant")+" ", false)).SetDotHere()
 	if w.lastErr != nil {
 		bb.Newline().Write(w.lastErr.Error(), ui.FgRed)
 	}
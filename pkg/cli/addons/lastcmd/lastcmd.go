@@ -9,6 +9,7 @@ import (
 
 	"github.com/elves/elvish/pkg/cli"
 	"github.com/elves/elvish/pkg/cli/histutil"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/ui"
 )
 
@@ -32,14 +33,14 @@ var _ = Store(histutil.Store(nil))
 // Start starts lastcmd function.
 func Start(app cli.App, cfg Config) {
 	if cfg.Store == nil {
-		app.Notify("no history store")
+		app.Notify(msg.Get("note-no-history-store"))
 		return
 	}
 	c := cfg.Store.Cursor("")
 	c.Prev()
 	cmd, err := c.Get()
 	if err != nil {
-		app.Notify("db error: " + err.Error())
+		app.Notify(msg.Sprintf("note-db-error", err))
 		return
 	}
 	wordifier := cfg.Wordifier
@@ -61,7 +62,7 @@ func Start(app cli.App, cfg Config) {
 		app.MutateState(func(s *cli.State) { s.Addon = nil })
 	}
 	w := cli.NewComboBox(cli.ComboBoxSpec{
-		CodeArea: cli.CodeAreaSpec{Prompt: cli.ModePrompt(" LASTCMD ", true)},
+		CodeArea: cli.CodeAreaSpec{Prompt: cli.ModePrompt(" "+msg.Get("mode-lastcmd")+" ", true)},
 		ListBox: cli.ListBoxSpec{
 			OverlayHandler: cfg.Binding,
 			OnAccept: func(it cli.Items, i int) {
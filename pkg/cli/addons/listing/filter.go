@@ -0,0 +1,47 @@
+package listing
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MatchFilter returns whether the text of an item matches the filter text. The
+// filter text may appear anywhere in the text, and the match is
+// case-insensitive unless the filter text contains uppercase letters.
+//
+// Both texts are normalized to NFC first, so that precomposed and decomposed
+// forms of the same character match each other. If foldDiacritics is true,
+// diacritics are removed from both texts too, so that "cafe" matches "café".
+func MatchFilter(text, filter string, foldDiacritics bool) bool {
+	text = normalize(text, foldDiacritics)
+	filter = normalize(filter, foldDiacritics)
+	if !hasUpper(filter) {
+		text = strings.ToLower(text)
+	}
+	return strings.Contains(text, filter)
+}
+
+func normalize(s string, foldDiacritics bool) string {
+	if !foldDiacritics {
+		return norm.NFC.String(s)
+	}
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return norm.NFC.String(s)
+	}
+	return folded
+}
+
+func hasUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
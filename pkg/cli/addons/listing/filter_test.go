@@ -0,0 +1,37 @@
+package listing
+
+import "testing"
+
+var matchFilterTests = []struct {
+	text, filter   string
+	foldDiacritics bool
+	want           bool
+}{
+	{"foo bar", "", false, true},
+	{"foo bar", "o b", false, true},
+	{"foo bar", "baz", false, false},
+	// Smart case.
+	{"Foo", "foo", false, true},
+	{"foo", "Foo", false, false},
+	{"Foo", "Foo", false, true},
+	// Precomposed and decomposed forms match each other.
+	{"caf\u00e9", "cafe\u0301", false, true},
+	{"cafe\u0301", "caf\u00e9", false, true},
+	{"café", "cafe", false, false},
+	// Folding diacritics.
+	{"café", "cafe", true, true},
+	{"cafe\u0301", "cafe", true, true},
+	{"cafe", "café", true, true},
+	{"Crème", "creme", true, true},
+	{"crème", "Creme", true, false},
+}
+
+func TestMatchFilter(t *testing.T) {
+	for _, test := range matchFilterTests {
+		got := MatchFilter(test.text, test.filter, test.foldDiacritics)
+		if got != test.want {
+			t.Errorf("MatchFilter(%q, %q, %v) = %v, want %v",
+				test.text, test.filter, test.foldDiacritics, got, test.want)
+		}
+	}
+}
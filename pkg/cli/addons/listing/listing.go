@@ -3,6 +3,7 @@ package listing
 
 import (
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/ui"
 )
 
@@ -42,7 +43,7 @@ func Start(app cli.App, cfg Config) {
 		cfg.Accept = func(string) bool { return false }
 	}
 	if cfg.Caption == "" {
-		cfg.Caption = " LISTING "
+		cfg.Caption = " " + msg.Get("mode-listing") + " "
 	}
 	accept := func(s string) {
 		retain := cfg.Accept(s)
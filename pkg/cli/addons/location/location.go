@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/store"
 	"github.com/elves/elvish/pkg/ui"
@@ -28,8 +29,16 @@ type Config struct {
 	// IterateHidden specifies hidden directories by calling the given function
 	// with all hidden directories.
 	IterateHidden func(func(string))
+	// IterateRecent specifies recently visited directories by calling the
+	// given function with them, most recent first. They are shown right after
+	// pinned directories.
+	IterateRecent func(func(string))
 	// IterateWorksapce specifies workspace configuration.
 	IterateWorkspaces WorkspaceIterator
+	// Filters the directories with the filter text. If nil, the filter text
+	// is matched case-insensitively, and each path component in it matches
+	// part of a path component of the directory.
+	Filter cli.FilterFunc
 }
 
 // Store defines the interface for interacting with the directory history.
@@ -45,7 +54,7 @@ var pinnedScore = math.Inf(1)
 // Start starts the directory history feature.
 func Start(app cli.App, cfg Config) {
 	if cfg.Store == nil {
-		app.Notify("no dir history store")
+		app.Notify(msg.Get("note-no-dir-history-store"))
 		return
 	}
 
@@ -69,26 +78,47 @@ func Start(app cli.App, cfg Config) {
 			wsKind, wsRoot = cfg.IterateWorkspaces.Parse(wd)
 		}
 	}
+	recentIndex := map[string]int{}
+	if cfg.IterateRecent != nil {
+		cfg.IterateRecent(func(s string) {
+			if _, ok := recentIndex[s]; !ok {
+				recentIndex[s] = len(recentIndex)
+			}
+		})
+	}
 	storedDirs, err := cfg.Store.Dirs(blacklist)
 	if err != nil {
-		app.Notify("db error: " + err.Error())
+		app.Notify(msg.Sprintf("note-db-error", err))
 		if len(dirs) == 0 {
 			return
 		}
 	}
+	recentDirs := make([]*store.Dir, len(recentIndex))
+	var otherDirs []store.Dir
 	for _, dir := range storedDirs {
-		if filepath.IsAbs(dir.Path) {
-			dirs = append(dirs, dir)
-		} else if wsKind != "" && hasPathPrefix(dir.Path, wsKind) {
-			dirs = append(dirs, dir)
+		if !filepath.IsAbs(dir.Path) &&
+			!(wsKind != "" && hasPathPrefix(dir.Path, wsKind)) {
+			continue
+		}
+		if i, ok := recentIndex[dir.Path]; ok {
+			dir := dir
+			recentDirs[i] = &dir
+		} else {
+			otherDirs = append(otherDirs, dir)
+		}
+	}
+	for _, dir := range recentDirs {
+		if dir != nil {
+			dirs = append(dirs, *dir)
 		}
 	}
+	dirs = append(dirs, otherDirs...)
 
 	l := list{dirs}
 
 	w := cli.NewComboBox(cli.ComboBoxSpec{
 		CodeArea: cli.CodeAreaSpec{
-			Prompt: cli.ModePrompt(" LOCATION ", true),
+			Prompt: cli.ModePrompt(" "+msg.Get("mode-location")+" ", true),
 		},
 		ListBox: cli.ListBoxSpec{
 			OverlayHandler: cfg.Binding,
@@ -105,7 +135,7 @@ func Start(app cli.App, cfg Config) {
 			},
 		},
 		OnFilter: func(w cli.ComboBox, p string) {
-			w.ListBox().Reset(l.filter(p), 0)
+			w.ListBox().Reset(l.filter(p, cfg.Filter), 0)
 		},
 	})
 	app.MutateState(func(s *cli.State) { s.Addon = w })
@@ -149,10 +179,24 @@ type list struct {
 	dirs []store.Dir
 }
 
-func (l list) filter(p string) list {
+func (l list) filter(p string, f cli.FilterFunc) list {
 	if p == "" {
 		return l
 	}
+	if f != nil {
+		texts := make([]string, len(l.dirs))
+		for i, dir := range l.dirs {
+			texts[i] = fsutil.TildeAbbr(dir.Path)
+		}
+		matches := f(p, texts)
+		var filteredDirs []store.Dir
+		for i := 0; i < len(l.dirs) && i < len(matches); i++ {
+			if matches[i] {
+				filteredDirs = append(filteredDirs, l.dirs[i])
+			}
+		}
+		return list{filteredDirs}
+	}
 	re := makeRegexpForPattern(p)
 	var filteredDirs []store.Dir
 	for _, dir := range l.dirs {
@@ -107,6 +107,37 @@ func TestStart_Pinned(t *testing.T) {
 	f.TTY.TestBuffer(t, wantBuf)
 }
 
+func TestStart_Recent(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	dirs := []store.Dir{
+		{Path: fix("/usr/bin"), Score: 200},
+		{Path: fix("/usr"), Score: 100},
+		{Path: fix("/tmp"), Score: 50},
+	}
+	Start(f.App, Config{
+		Store:         testStore{storedDirs: dirs, wd: fix("/home")},
+		IteratePinned: func(f func(string)) { f(fix("/usr/bin")) },
+		// The working directory, pinned directories and directories not in
+		// the store are not boosted.
+		IterateRecent: func(f func(string)) {
+			f(fix("/home"))
+			f(fix("/tmp"))
+			f(fix("/usr/bin"))
+			f(fix("/opt"))
+			f(fix("/usr"))
+		},
+	})
+	// Test UI.
+	wantBuf := listingBuf(
+		"",
+		"  * "+fix("/usr/bin"), "<- selected",
+		" 50 "+fix("/tmp"),
+		"100 "+fix("/usr"))
+	f.TTY.TestBuffer(t, wantBuf)
+}
+
 func TestStart_HideWd(t *testing.T) {
 	f := Setup()
 	defer f.Stop()
@@ -39,10 +39,19 @@ type File interface {
 	Read() ([]File, []byte, error)
 }
 
-// NewOSCursor returns a Cursor backed by the OS.
-func NewOSCursor() Cursor { return osCursor{lscolors.GetColorist()} }
+// NewOSCursor returns a Cursor backed by the OS. It changes the working
+// directory with the given function, or os.Chdir if it is nil.
+func NewOSCursor(chdir func(dir string) error) Cursor {
+	if chdir == nil {
+		chdir = os.Chdir
+	}
+	return osCursor{chdir, lscolors.GetColorist()}
+}
 
-type osCursor struct{ colorist lscolors.Colorist }
+type osCursor struct {
+	chdir    func(string) error
+	colorist lscolors.Colorist
+}
 
 func (c osCursor) Current() (File, error) {
 	abs, err := filepath.Abs(".")
@@ -63,9 +72,9 @@ func (c osCursor) Parent() (File, error) {
 	return file{filepath.Base(abs), abs, os.ModeDir, c.colorist}, nil
 }
 
-func (c osCursor) Ascend() error { return os.Chdir("..") }
+func (c osCursor) Ascend() error { return c.chdir("..") }
 
-func (c osCursor) Descend(name string) error { return os.Chdir(name) }
+func (c osCursor) Descend(name string) error { return c.chdir(name) }
 
 type emptyDir struct{}
 
@@ -8,6 +8,7 @@ import (
 	"unicode"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/cli/term"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -125,7 +126,7 @@ func (w *widget) descend() {
 // Start starts the navigation function.
 func Start(app cli.App, cfg Config) {
 	if cfg.Cursor == nil {
-		cfg.Cursor = NewOSCursor()
+		cfg.Cursor = NewOSCursor(nil)
 	}
 	if cfg.WidthRatio == nil {
 		cfg.WidthRatio = func() [3]int { return [3]int{1, 3, 4} }
@@ -138,9 +139,10 @@ func Start(app cli.App, cfg Config) {
 		codeArea: cli.NewCodeArea(cli.CodeAreaSpec{
 			Prompt: func() ui.Text {
 				if w.CopyState().ShowHidden {
-					return cli.ModeLine(" NAVIGATING (show hidden) ", true)
+					return cli.ModeLine(
+						" "+msg.Get("mode-navigation")+" "+msg.Get("flag-show-hidden")+" ", true)
 				}
-				return cli.ModeLine(" NAVIGATING ", true)
+				return cli.ModeLine(" "+msg.Get("mode-navigation")+" ", true)
 			},
 		}),
 		colView: cli.NewColView(cli.ColViewSpec{
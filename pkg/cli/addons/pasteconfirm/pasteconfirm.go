@@ -0,0 +1,108 @@
+// Package pasteconfirm implements an addon that previews pasted text in the
+// code area and asks the user to confirm inserting it.
+package pasteconfirm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/cli/term"
+)
+
+// Config keeps the configuration for the pasteconfirm addon.
+type Config struct {
+	// Keybinding.
+	Binding cli.Handler
+	// The pasted text.
+	Text string
+}
+
+type widget struct {
+	Config
+	app cli.App
+}
+
+func (w *widget) Render(width, height int) *term.Buffer {
+	lines := strings.Count(w.Text, "\n") + 1
+	hint := " " + plural(lines, "line") + " pasted, Enter to insert"
+	buf := term.NewBufferBuilder(width).
+		WriteStyled(cli.ModeLine(" "+msg.Get("mode-paste")+" ", false)).SetDotHere().
+		Write(hint).Buffer()
+	buf.TrimToLines(0, height)
+	return buf
+}
+
+func (w *widget) Focus() bool { return false }
+
+func (w *widget) Handle(event term.Event) bool {
+	if w.Binding.Handle(event) {
+		return true
+	}
+	if event == term.K('\n') {
+		Accept(w.app)
+		return true
+	}
+	// Swallow all other events, so that the pasted text is not modified
+	// before it is confirmed.
+	return true
+}
+
+// NeedsConfirm returns whether a pasted text should be confirmed before it is
+// inserted. This is the case when the text spans multiple lines or contains
+// control characters other than tab.
+func NeedsConfirm(text string) bool {
+	for _, r := range text {
+		if r == '\n' || r == '\r' || (r < 0x20 && r != '\t') || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// Start starts the pasteconfirm addon. The pasted text is shown as pending
+// code at the dot of the code area, so that it is highlighted along with the
+// prompt and the rest of the code.
+func Start(app cli.App, cfg Config) {
+	if cfg.Binding == nil {
+		cfg.Binding = cli.DummyHandler{}
+	}
+	app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+		dot := s.Buffer.Dot
+		s.Pending = cli.PendingCode{From: dot, To: dot, Content: cfg.Text}
+	})
+	w := widget{Config: cfg, app: app}
+	app.MutateState(func(s *cli.State) { s.Addon = &w })
+	app.Redraw()
+}
+
+// Accept inserts the pasted text and closes the pasteconfirm addon. It does
+// nothing if the addon is not active.
+func Accept(app cli.App) {
+	if _, ok := cli.Addon(app).(*widget); !ok {
+		return
+	}
+	app.CodeArea().MutateState(func(s *cli.CodeAreaState) { s.ApplyPending() })
+	app.MutateState(func(s *cli.State) { s.Addon = nil })
+	app.Redraw()
+}
+
+// Close discards the pasted text and closes the pasteconfirm addon. It does
+// nothing if the addon is not active.
+func Close(app cli.App) {
+	if _, ok := cli.Addon(app).(*widget); !ok {
+		return
+	}
+	app.CodeArea().MutateState(
+		func(s *cli.CodeAreaState) { s.Pending = cli.PendingCode{} })
+	app.MutateState(func(s *cli.State) { s.Addon = nil })
+	app.Redraw()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,66 @@
+package pasteconfirm
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/cli/clitest"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func setupStarted(t *testing.T) *Fixture {
+	f := Setup()
+	Start(f.App, Config{Text: "echo\nls"})
+	f.TestTTY(t,
+		"echo\n", Styles,
+		"____",
+		"ls", Styles,
+		"__", term.DotHere, "\n",
+		" PASTE  2 lines pasted, Enter to insert", Styles,
+		"*******",
+	)
+	return f
+}
+
+func TestAccept(t *testing.T) {
+	f := setupStarted(t)
+	defer f.Stop()
+
+	f.TTY.Inject(term.K(ui.Enter))
+	f.TestTTY(t, "echo\nls", term.DotHere)
+}
+
+func TestClose(t *testing.T) {
+	f := setupStarted(t)
+	defer f.Stop()
+
+	Close(f.App)
+	f.TestTTY(t /* nothing */)
+}
+
+func TestOtherKeysAreIgnored(t *testing.T) {
+	f := setupStarted(t)
+	defer f.Stop()
+
+	f.TTY.Inject(term.K('x'), term.K(ui.Enter))
+	f.TestTTY(t, "echo\nls", term.DotHere)
+}
+
+var needsConfirmTests = []struct {
+	text string
+	want bool
+}{
+	{"echo foo", false},
+	{"echo\tfoo", false},
+	{"echo foo\n", true},
+	{"echo foo\r", true},
+	{"echo \x1b[31mfoo", true},
+}
+
+func TestNeedsConfirm(t *testing.T) {
+	for _, test := range needsConfirmTests {
+		if got := NeedsConfirm(test.text); got != test.want {
+			t.Errorf("NeedsConfirm(%q) -> %v, want %v", test.text, got, test.want)
+		}
+	}
+}
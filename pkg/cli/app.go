@@ -35,6 +35,10 @@ type App interface {
 	// CommitCode causes the main loop to exit with the current code content. If
 	// this method is called when an event is being handled, the main loop will
 	// exit after the handler returns.
+	//
+	// The BeforeAccept callbacks are called in turn with the code before the
+	// main loop exits. Each of them may replace the code, or reject it by
+	// returning false, in which case the main loop keeps running.
 	CommitCode()
 	// Notify adds a note and requests a redraw.
 	Notify(note string)
@@ -49,6 +53,12 @@ type app struct {
 	RPromptPersistent func() bool
 	BeforeReadline    []func()
 	AfterReadline     []func(string)
+	BeforeAccept      []func(code string) (string, bool)
+	OnResize          []func(width, height int)
+	CursorShape       func() term.CursorShape
+	Title             func(code string) (string, bool)
+	ReportCwd         func() bool
+	AfterFinalRedraw  func(lines int)
 	Highlighter       Highlighter
 	Prompt            Prompt
 	RPrompt           Prompt
@@ -88,6 +98,12 @@ func NewApp(spec AppSpec) App {
 		RPromptPersistent: spec.RPromptPersistent,
 		BeforeReadline:    spec.BeforeReadline,
 		AfterReadline:     spec.AfterReadline,
+		BeforeAccept:      spec.BeforeAccept,
+		OnResize:          spec.OnResize,
+		CursorShape:       spec.CursorShape,
+		Title:             spec.Title,
+		ReportCwd:         spec.ReportCwd,
+		AfterFinalRedraw:  spec.AfterFinalRedraw,
 		Highlighter:       spec.Highlighter,
 		Prompt:            spec.Prompt,
 		RPrompt:           spec.RPrompt,
@@ -102,13 +118,26 @@ func NewApp(spec AppSpec) App {
 	if a.RPromptPersistent == nil {
 		a.RPromptPersistent = func() bool { return false }
 	}
+	if a.CursorShape == nil {
+		a.CursorShape = func() term.CursorShape { return term.CursorDefault }
+	}
+	if a.Title == nil {
+		a.Title = func(string) (string, bool) { return "", false }
+	}
+	if a.ReportCwd == nil {
+		a.ReportCwd = func() bool { return false }
+	}
+	if a.AfterFinalRedraw == nil {
+		a.AfterFinalRedraw = func(int) {}
+	}
 	if a.Highlighter == nil {
 		a.Highlighter = dummyHighlighter{}
 	}
 	if a.Prompt == nil {
 		a.Prompt = NewConstPrompt(nil)
 	}
-	if a.RPrompt == nil {
+	if a.RPrompt == nil || a.TTY.Dumb() {
+		// Dumb terminals can't show the rprompt without cursor addressing.
 		a.RPrompt = NewConstPrompt(nil)
 	}
 	lp.HandleCb(a.handle)
@@ -121,6 +150,7 @@ func NewApp(spec AppSpec) App {
 		RPrompt:        a.RPrompt.Get,
 		Abbreviations:  spec.Abbreviations,
 		QuotePaste:     spec.QuotePaste,
+		OnPaste:        spec.OnPaste,
 		OnSubmit:       a.CommitCode,
 		State:          spec.CodeAreaState,
 
@@ -162,6 +192,10 @@ func (a *app) handle(e event) {
 			a.resetAllStates()
 			a.triggerPrompts(true)
 		case sys.SIGWINCH:
+			height, width := a.TTY.Size()
+			for _, f := range a.OnResize {
+				f(width, height)
+			}
 			a.RedrawFull()
 		}
 	case term.Event:
@@ -207,17 +241,29 @@ func (a *app) redraw(flag redrawFlag) {
 		if hideRPrompt {
 			a.codeArea.MutateState(func(s *CodeAreaState) { s.HideRPrompt = false })
 		}
+		lines := len(bufMain.Lines)
 		// Insert a newline after the buffer and position the cursor there.
 		bufMain.Extend(term.NewBuffer(width), true)
 
+		// Commands should run with the cursor shape configured in the
+		// terminal.
+		a.TTY.SetCursorShape(term.CursorDefault)
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 		a.TTY.ResetBuffer()
+		a.AfterFinalRedraw(lines)
 	} else {
 		bufMain := renderApp(a.codeArea, addon, width, height)
+		a.TTY.SetCursorShape(a.CursorShape())
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 	}
 }
 
+func (a *app) setTitle(code string) {
+	if title, ok := a.Title(code); ok {
+		a.TTY.SetTitle(title)
+	}
+}
+
 // Renders notes. This does not respect height so that overflow notes end up in
 // the scrollback buffer.
 func renderNotes(notes []string, width int) *term.Buffer {
@@ -252,11 +298,18 @@ func (a *app) ReadCode() (string, error) {
 	for _, f := range a.BeforeReadline {
 		f()
 	}
+	a.setTitle("")
+	if a.ReportCwd() {
+		if wd, err := os.Getwd(); err == nil {
+			a.TTY.ReportCwd(wd)
+		}
+	}
 	defer func() {
 		content := a.codeArea.CopyState().Buffer.Content
 		for _, f := range a.AfterReadline {
 			f(content)
 		}
+		a.setTitle(content)
 		a.resetAllStates()
 	}()
 
@@ -347,7 +400,19 @@ func (a *app) CommitEOF() {
 }
 
 func (a *app) CommitCode() {
-	code := a.codeArea.CopyState().Buffer.Content
+	original := a.codeArea.CopyState().Buffer.Content
+	code := original
+	for _, f := range a.BeforeAccept {
+		var ok bool
+		code, ok = f(code)
+		if !ok {
+			return
+		}
+	}
+	if code != original {
+		// Show the code that is actually accepted in the final redraw.
+		SetCodeBuffer(a, CodeBuffer{Content: code, Dot: len(code)})
+	}
 	a.loop.Return(code, nil)
 }
 
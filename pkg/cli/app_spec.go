@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/elves/elvish/pkg/cli/term"
 	"github.com/elves/elvish/pkg/ui"
 )
 
@@ -11,6 +12,19 @@ type AppSpec struct {
 	RPromptPersistent func() bool
 	BeforeReadline    []func()
 	AfterReadline     []func(string)
+	BeforeAccept      []func(code string) (string, bool)
+	OnResize          []func(width, height int)
+	CursorShape       func() term.CursorShape
+	// Called with an empty string when starting to read code, and with the
+	// code that has been read when done, to get the title of the terminal.
+	// The title is left unchanged if it returns false.
+	Title func(code string) (string, bool)
+	// Called when starting to read code; if it returns true, the working
+	// directory is reported to the terminal (see TTY.ReportCwd).
+	ReportCwd func() bool
+	// Called after the final redraw when code is accepted, with the number of
+	// lines that the prompt and the code take on the terminal.
+	AfterFinalRedraw func(lines int)
 
 	Highlighter Highlighter
 	Prompt      Prompt
@@ -19,6 +33,7 @@ type AppSpec struct {
 	OverlayHandler Handler
 	Abbreviations  func(f func(abbr, full string))
 	QuotePaste     func() bool
+	OnPaste        func(text string) bool
 
 	SmallWordAbbreviations func(f func(abbr, full string))
 
@@ -3,6 +3,8 @@ package cli_test
 import (
 	"errors"
 	"io"
+	"os"
+	"reflect"
 	"strings"
 	"syscall"
 	"testing"
@@ -104,6 +106,39 @@ func TestReadCode_CallsAfterReadline(t *testing.T) {
 	}
 }
 
+func TestReadCode_BeforeAcceptCanReplaceCode(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.BeforeAccept = []func(string) (string, bool){
+			func(s string) (string, bool) { return s + " bar", true },
+			func(s string) (string, bool) { return s + " baz", true },
+		}
+	}))
+
+	feedInput(f.TTY, "foo\n")
+	code, _ := f.Wait()
+	if code != "foo bar baz" {
+		t.Errorf("got code %q, want %q", code, "foo bar baz")
+	}
+}
+
+func TestReadCode_BeforeAcceptCanRejectCode(t *testing.T) {
+	calls := 0
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.BeforeAccept = []func(string) (string, bool){
+			// Rejects the first attempt only.
+			func(s string) (string, bool) { calls++; return s, calls > 1 },
+		}
+	}))
+
+	feedInput(f.TTY, "code\n")
+	f.TTY.TestBuffer(t, bb().Write("code").SetDotHere().Buffer())
+	feedInput(f.TTY, "\n")
+	code, _ := f.Wait()
+	if code != "code" {
+		t.Errorf("got code %q, want %q", code, "code")
+	}
+}
+
 func TestReadCode_FinalRedraw(t *testing.T) {
 	f := Setup(WithSpec(func(spec *AppSpec) {
 		spec.CodeAreaState.Buffer.Content = "code"
@@ -172,6 +207,27 @@ func TestReadCode_RedrawsOnSIGWINCH(t *testing.T) {
 		Write("1234567890").SetDotHere().Buffer())
 }
 
+func TestReadCode_CallsOnResizeOnSIGWINCH(t *testing.T) {
+	callCh := make(chan [2]int, 1)
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.OnResize = []func(int, int){
+			func(w, h int) { callCh <- [2]int{w, h} }}
+	}))
+	defer f.Stop()
+
+	f.TTY.SetSize(24, 4)
+	f.TTY.InjectSignal(sys.SIGWINCH)
+
+	select {
+	case size := <-callCh:
+		if size != [2]int{4, 24} {
+			t.Errorf("OnResize hook called with %v, want [4 24]", size)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("OnResize hook not called")
+	}
+}
+
 // Code area.
 
 func TestReadCode_LetsCodeAreaHandleEvents(t *testing.T) {
@@ -296,6 +352,19 @@ func TestReadCode_ShowsRPrompt(t *testing.T) {
 	f.TTY.TestBuffer(t, wantBuf)
 }
 
+func TestReadCode_DoesNotShowRPromptOnDumbTerminal(t *testing.T) {
+	f := Setup(
+		WithSpec(func(spec *AppSpec) {
+			spec.RPrompt = NewConstPrompt(ui.T("R"))
+		}),
+		WithTTY(func(tty TTYCtrl) { tty.SetDumb(true) }))
+	defer f.Stop()
+
+	f.TTY.Inject(term.K('a'))
+
+	f.TTY.TestBuffer(t, bb().Write("a").SetDotHere().Buffer())
+}
+
 func TestReadCode_ShowsRPromptInFinalRedrawIfPersistent(t *testing.T) {
 	f := Setup(WithSpec(func(spec *AppSpec) {
 		spec.CodeAreaState.Buffer.Content = "code"
@@ -376,6 +445,93 @@ func TestReadCode_RespectsAddonFocusMethod(t *testing.T) {
 
 // Misc features.
 
+func TestReadCode_SetsCursorShape(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.CursorShape = func() term.CursorShape { return term.CursorBar }
+	}))
+
+	f.TTY.TestBuffer(t, bb().SetDotHere().Buffer())
+	if shape := f.TTY.CursorShape(); shape != term.CursorBar {
+		t.Errorf("got cursor shape %v, want %v", shape, term.CursorBar)
+	}
+
+	f.TTY.Inject(term.K('\n'))
+	f.Wait()
+	if shape := f.TTY.CursorShape(); shape != term.CursorDefault {
+		t.Errorf("got cursor shape %v after final redraw, want %v",
+			shape, term.CursorDefault)
+	}
+}
+
+func TestReadCode_SetsTitle(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.Title = func(code string) (string, bool) {
+			if code == "" {
+				return "elvish", true
+			}
+			return code, true
+		}
+	}))
+
+	f.TTY.Inject(term.K('e'), term.K('c'), term.K('h'), term.K('o'), term.K('\n'))
+	f.Wait()
+	wantTitles := []string{"elvish", "echo"}
+	if titles := f.TTY.Titles(); !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("got titles %q, want %q", titles, wantTitles)
+	}
+}
+
+func TestReadCode_DoesNotSetTitleWhenTitleReturnsFalse(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.Title = func(string) (string, bool) { return "", false }
+	}))
+
+	f.TTY.Inject(term.K('\n'))
+	f.Wait()
+	if titles := f.TTY.Titles(); len(titles) != 0 {
+		t.Errorf("got titles %q, want none", titles)
+	}
+}
+
+func TestReadCode_ReportsCwd(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.ReportCwd = func() bool { return true }
+	}))
+
+	f.TTY.Inject(term.K('\n'))
+	f.Wait()
+	wd, _ := os.Getwd()
+	if cwds := f.TTY.Cwds(); !reflect.DeepEqual(cwds, []string{wd}) {
+		t.Errorf("got reported directories %q, want %q", cwds, []string{wd})
+	}
+}
+
+func TestReadCode_DoesNotReportCwdByDefault(t *testing.T) {
+	f := Setup()
+
+	f.TTY.Inject(term.K('\n'))
+	f.Wait()
+	if cwds := f.TTY.Cwds(); len(cwds) != 0 {
+		t.Errorf("got reported directories %q, want none", cwds)
+	}
+}
+
+func TestReadCode_CallsAfterFinalRedraw(t *testing.T) {
+	var gotLines int
+	f := Setup(func(spec *AppSpec, tty TTYCtrl) {
+		spec.AfterFinalRedraw = func(lines int) { gotLines = lines }
+		// The code needs 3 lines to completely show.
+		spec.CodeAreaState.Buffer.Content = strings.Repeat("a", 12)
+		tty.SetSize(10, 5)
+	})
+
+	f.TTY.Inject(term.K('\n'))
+	f.Wait()
+	if gotLines != 3 {
+		t.Errorf("got lines %v, want 3", gotLines)
+	}
+}
+
 func TestReadCode_TrimsBufferToMaxHeight(t *testing.T) {
 	f := Setup(func(spec *AppSpec, tty TTYCtrl) {
 		spec.MaxHeight = func() int { return 2 }
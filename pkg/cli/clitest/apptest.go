@@ -19,6 +19,7 @@ var Styles = ui.RuneStylesheet{
 	'#': ui.Stylings(ui.Inverse, ui.FgBlue),
 	'!': ui.FgRed,
 	'?': ui.Stylings(ui.FgBrightWhite, ui.BgRed),
+	'~': ui.Stylings(ui.Underlined, ui.FgRed),
 	'-': ui.FgMagenta,
 	'X': ui.Stylings(ui.Inverse, ui.FgMagenta),
 	'v': ui.FgGreen,
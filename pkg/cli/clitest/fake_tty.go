@@ -40,10 +40,29 @@ type fakeTTY struct {
 	sigCh chan os.Signal
 	// Argument that SetRawInput got.
 	raw int
+	// Argument that SetCursorShape got, and the mutex guarding it.
+	cursorShape      term.CursorShape
+	cursorShapeMutex sync.Mutex
+	// Arguments that SetTitle got, and the mutex guarding them.
+	titles     []string
+	titleMutex sync.Mutex
+	// Arguments that ReportCwd got, and the mutex guarding them.
+	cwds     []string
+	cwdMutex sync.Mutex
+	// Arguments that SetClipboard got, and the mutex guarding them.
+	clipboards     []string
+	clipboardMutex sync.Mutex
+	// Positions that CursorPosition returns in turn, arguments that DrawAbove
+	// got, and the mutex guarding them.
+	cursorPositions []term.Pos
+	drawnAbove      []DrawnAbove
+	cursorMutex     sync.Mutex
 
 	sizeMutex sync.RWMutex
 	// Predefined sizes.
 	height, width int
+	// Whether to pretend to be a dumb terminal.
+	dumb bool
 }
 
 // Initial size of fake TTY.
@@ -81,6 +100,9 @@ func (t *fakeTTY) Size() (h, w int) {
 	return t.height, t.width
 }
 
+// Returns the value specified by using the SetDumb method of TTYCtrl.
+func (t *fakeTTY) Dumb() bool { return t.dumb }
+
 // Returns next event from t.eventCh.
 func (t *fakeTTY) ReadEvent() (term.Event, error) {
 	return <-t.eventCh, nil
@@ -123,6 +145,61 @@ func (t *fakeTTY) UpdateBuffer(bufNotes, buf *term.Buffer, _ bool) error {
 	return nil
 }
 
+// Records the cursor shape.
+func (t *fakeTTY) SetCursorShape(s term.CursorShape) {
+	t.cursorShapeMutex.Lock()
+	defer t.cursorShapeMutex.Unlock()
+	t.cursorShape = s
+}
+
+// Records the title.
+func (t *fakeTTY) SetTitle(title string) {
+	t.titleMutex.Lock()
+	defer t.titleMutex.Unlock()
+	t.titles = append(t.titles, title)
+}
+
+// Records the directory.
+func (t *fakeTTY) ReportCwd(dir string) {
+	t.cwdMutex.Lock()
+	defer t.cwdMutex.Unlock()
+	t.cwds = append(t.cwds, dir)
+}
+
+// Records the text, and returns whether the terminal is not dumb.
+func (t *fakeTTY) SetClipboard(text string) bool {
+	if t.Dumb() {
+		return false
+	}
+	t.clipboardMutex.Lock()
+	defer t.clipboardMutex.Unlock()
+	t.clipboards = append(t.clipboards, text)
+	return true
+}
+
+// Returns the next position injected with the InjectCursorPositions method of
+// TTYCtrl, or false if there is none.
+func (t *fakeTTY) CursorPosition() (term.Pos, bool) {
+	t.cursorMutex.Lock()
+	defer t.cursorMutex.Unlock()
+	if len(t.cursorPositions) == 0 {
+		return term.Pos{}, false
+	}
+	pos := t.cursorPositions[0]
+	t.cursorPositions = t.cursorPositions[1:]
+	return pos, true
+}
+
+// Records the arguments, unless the terminal is dumb.
+func (t *fakeTTY) DrawAbove(up, col int, cells []term.Cell) {
+	if t.Dumb() {
+		return
+	}
+	t.cursorMutex.Lock()
+	defer t.cursorMutex.Unlock()
+	t.drawnAbove = append(t.drawnAbove, DrawnAbove{up, col, cells})
+}
+
 func (t *fakeTTY) NotifySignals() <-chan os.Signal { return t.sigCh }
 
 func (t *fakeTTY) StopSignals() { close(t.sigCh) }
@@ -161,6 +238,12 @@ func (t TTYCtrl) SetSize(h, w int) {
 	t.height, t.width = h, w
 }
 
+// SetDumb sets whether the fake terminal pretends to be a dumb terminal. It
+// should be called before the terminal is used.
+func (t TTYCtrl) SetDumb(dumb bool) {
+	t.dumb = dumb
+}
+
 // Inject injects events to the fake terminal.
 func (t TTYCtrl) Inject(events ...term.Event) {
 	for _, event := range events {
@@ -194,6 +277,59 @@ func (t TTYCtrl) RawInput() int {
 	return t.raw
 }
 
+// CursorShape returns the argument in the last call to the SetCursorShape
+// method of the TTY.
+func (t TTYCtrl) CursorShape() term.CursorShape {
+	t.cursorShapeMutex.Lock()
+	defer t.cursorShapeMutex.Unlock()
+	return t.cursorShape
+}
+
+// Titles returns the arguments in all calls to the SetTitle method of the TTY.
+func (t TTYCtrl) Titles() []string {
+	t.titleMutex.Lock()
+	defer t.titleMutex.Unlock()
+	return append([]string(nil), t.titles...)
+}
+
+// Cwds returns the arguments in all calls to the ReportCwd method of the TTY.
+func (t TTYCtrl) Cwds() []string {
+	t.cwdMutex.Lock()
+	defer t.cwdMutex.Unlock()
+	return append([]string(nil), t.cwds...)
+}
+
+// Clipboards returns the arguments in all successful calls to the SetClipboard
+// method of the TTY.
+func (t TTYCtrl) Clipboards() []string {
+	t.clipboardMutex.Lock()
+	defer t.clipboardMutex.Unlock()
+	return append([]string(nil), t.clipboards...)
+}
+
+// InjectCursorPositions adds positions to be returned by the CursorPosition
+// method of the TTY in turn.
+func (t TTYCtrl) InjectCursorPositions(ps ...term.Pos) {
+	t.cursorMutex.Lock()
+	defer t.cursorMutex.Unlock()
+	t.cursorPositions = append(t.cursorPositions, ps...)
+}
+
+// DrawnAbove records the arguments of a call to the DrawAbove method of the
+// TTY.
+type DrawnAbove struct {
+	Up, Col int
+	Cells   []term.Cell
+}
+
+// DrawnAbove returns the arguments in all calls to the DrawAbove method of the
+// TTY.
+func (t TTYCtrl) DrawnAbove() []DrawnAbove {
+	t.cursorMutex.Lock()
+	defer t.cursorMutex.Unlock()
+	return append([]DrawnAbove(nil), t.drawnAbove...)
+}
+
 // TestBuffer verifies that a buffer will appear within the timeout of 4
 // seconds, and fails the test if it doesn't
 func (t TTYCtrl) TestBuffer(tt *testing.T, b *term.Buffer) {
@@ -44,6 +44,11 @@ type CodeAreaSpec struct {
 	// should be quoted. If this function is not given, the Widget defaults to
 	// not quoting pasted texts.
 	QuotePaste func() bool
+	// A function that is called with the pasted text (after quoting, if
+	// QuotePaste returns true) when a bracketed paste finishes. The text is
+	// only inserted if the function returns true. If this function is not
+	// given, the Widget always inserts pasted texts.
+	OnPaste func(text string) bool
 	// A function that is called on the submit event.
 	OnSubmit func()
 
@@ -55,9 +60,46 @@ type CodeAreaSpec struct {
 type CodeAreaState struct {
 	Buffer      CodeBuffer
 	Pending     PendingCode
+	Selection   Selection
 	HideRPrompt bool
 }
 
+// Selection represents a visual selection in the code buffer, which spans the
+// text between the mark and the dot.
+type Selection struct {
+	// Whether there is a selection.
+	Active bool
+	// Position of the mark, as a byte index into CodeBuffer.Content.
+	Mark int
+}
+
+// SelectedRange returns the range of the selected text, as byte indices into
+// the content of the code buffer. The last return value is false if there is no
+// selection or the selection is empty.
+func (s CodeAreaState) SelectedRange() (from, to int, ok bool) {
+	if !s.Selection.Active {
+		return 0, 0, false
+	}
+	from, to = s.Selection.Mark, s.Buffer.Dot
+	if from > len(s.Buffer.Content) {
+		from = len(s.Buffer.Content)
+	}
+	if from > to {
+		from, to = to, from
+	}
+	return from, to, from < to
+}
+
+// SelectedText returns the selected text, or an empty string if there is no
+// selection.
+func (s CodeAreaState) SelectedText() string {
+	from, to, ok := s.SelectedRange()
+	if !ok {
+		return ""
+	}
+	return s.Buffer.Content[from:to]
+}
+
 // CodeBuffer represents the buffer of the CodeArea widget.
 type CodeBuffer struct {
 	// Content of the buffer.
@@ -132,6 +174,9 @@ func NewCodeArea(spec CodeAreaSpec) CodeArea {
 	if spec.QuotePaste == nil {
 		spec.QuotePaste = func() bool { return false }
 	}
+	if spec.OnPaste == nil {
+		spec.OnPaste = func(string) bool { return true }
+	}
 	if spec.OnSubmit == nil {
 		spec.OnSubmit = func() {}
 	}
@@ -192,7 +237,12 @@ func (w *codeArea) handlePasteSetting(start bool) bool {
 		if w.QuotePaste() {
 			text = parse.Quote(text)
 		}
-		w.MutateState(func(s *CodeAreaState) { s.Buffer.InsertAtDot(text) })
+		if w.OnPaste(text) {
+			w.MutateState(func(s *CodeAreaState) {
+				s.Selection = Selection{}
+				s.Buffer.InsertAtDot(text)
+			})
+		}
 
 		w.pasting = false
 		w.pasteBuffer = bytes.Buffer{}
@@ -300,6 +350,7 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 	case ui.K(ui.Backspace), ui.K('H', ui.Ctrl):
 		w.resetInserts()
 		w.MutateState(func(s *CodeAreaState) {
+			s.Selection = Selection{}
 			c := &s.Buffer
 			// Remove the last rune.
 			_, chop := utf8.DecodeLastRuneInString(c.Content[:c.Dot])
@@ -322,6 +373,7 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 			w.resetInserts()
 		}
 		s := string(key.Rune)
+		w.State.Selection = Selection{}
 		w.State.Buffer.InsertAtDot(s)
 		w.inserts += s
 		w.lastCodeBuffer = w.State.Buffer
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/ui"
 	"github.com/elves/elvish/pkg/wcwidth"
 )
@@ -17,15 +18,31 @@ type view struct {
 
 var stylingForPending = ui.Underlined
 
+var stylingForSelection = ui.Inverse
+
+// Colors of errors in the code. The range of each error is underlined, and
+// both the range and the error message are shown in the color of the error.
+// The colors are cycled through, so that adjacent errors can be told apart.
+var colorsForErrors = []ui.Styling{ui.FgRed, ui.FgYellow, ui.FgMagenta, ui.FgCyan}
+
+func stylingForError(i int) ui.Styling {
+	return colorsForErrors[i%len(colorsForErrors)]
+}
+
 func getView(w *codeArea) *view {
 	s := w.CopyState()
 	code, pFrom, pTo := patchPending(s.Buffer, s.Pending)
 	styledCode, errors := w.Highlighter(code.Content)
+	styledCode = styleErrors(styledCode, errors)
 	if pFrom < pTo {
 		// Apply stylingForPending to [pFrom, pTo)
 		parts := styledCode.Partition(pFrom, pTo)
 		pending := ui.StyleText(parts[1], stylingForPending)
 		styledCode = ui.Concat(parts[0], pending, parts[2])
+	} else if from, to, ok := s.SelectedRange(); ok {
+		parts := styledCode.Partition(from, to)
+		selected := ui.StyleText(parts[1], stylingForSelection)
+		styledCode = ui.Concat(parts[0], selected, parts[2])
 	}
 
 	var rprompt ui.Text
@@ -36,6 +53,28 @@ func getView(w *codeArea) *view {
 	return &view{w.Prompt(), rprompt, styledCode, code.Dot, errors}
 }
 
+// Underlines the ranges of errors that implement diag.Ranger in the code.
+func styleErrors(code ui.Text, errors []error) ui.Text {
+	n := 0
+	for _, seg := range code {
+		n += len(seg.Text)
+	}
+	for i, err := range errors {
+		r, ok := err.(diag.Ranger)
+		if !ok {
+			continue
+		}
+		from, to := r.Range().From, r.Range().To
+		if from < 0 || to > n || from >= to {
+			continue
+		}
+		parts := code.Partition(from, to)
+		styled := ui.StyleText(parts[1], ui.Underlined, stylingForError(i))
+		code = ui.Concat(parts[0], styled, parts[2])
+	}
+	return code
+}
+
 func patchPending(c CodeBuffer, p PendingCode) (CodeBuffer, int, int) {
 	if p.From > p.To || p.From < 0 || p.To > len(c.Content) {
 		// Invalid Pending.
@@ -87,9 +126,11 @@ func renderView(v *view, buf *term.BufferBuilder) {
 		}
 	}
 
-	if len(v.errors) > 0 {
-		for _, err := range v.errors {
-			buf.Newline()
+	for i, err := range v.errors {
+		buf.Newline()
+		if _, ok := err.(diag.Ranger); ok {
+			buf.WriteStyled(ui.T(err.Error(), stylingForError(i)))
+		} else {
 			buf.Write(err.Error())
 		}
 	}
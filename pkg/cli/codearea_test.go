@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/tt"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -101,6 +102,24 @@ var codeAreaRenderTests = []RenderTest{
 		Want: bb(10).Write("> code").SetDotHere().
 			Newline().Write("static error"),
 	},
+	{
+		Name: "errors with ranges in code",
+		Given: NewCodeArea(CodeAreaSpec{
+			Prompt: p(ui.T("> ")),
+			Highlighter: func(code string) (ui.Text, []error) {
+				return ui.T(code), []error{
+					rangedError{diag.Ranging{From: 0, To: 1}, "e1"},
+					rangedError{diag.Ranging{From: 2, To: 3}, "e2"},
+				}
+			},
+			State: CodeAreaState{Buffer: CodeBuffer{Content: "a b", Dot: 3}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("> ").
+			WriteStringSGR("a", "4;31").Write(" ").WriteStringSGR("b", "4;33").
+			SetDotHere().
+			Newline().WriteStringSGR("e1", "31").
+			Newline().WriteStringSGR("e2", "33"),
+	},
 	{
 		Name: "pending code inserting at the dot",
 		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
@@ -156,6 +175,33 @@ var codeAreaRenderTests = []RenderTest{
 		Width: 10, Height: 24,
 		Want: bb(10).Write("code").SetDotHere(),
 	},
+	{
+		Name: "selection with the mark before the dot",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 3},
+			Selection: Selection{Active: true, Mark: 1},
+		}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("c").WriteStringSGR("od", "7").SetDotHere().Write("e"),
+	},
+	{
+		Name: "selection with the mark after the dot",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 1},
+			Selection: Selection{Active: true, Mark: 3},
+		}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("c").SetDotHere().WriteStringSGR("od", "7").Write("e"),
+	},
+	{
+		Name: "inactive selection",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 3},
+			Selection: Selection{Mark: 1},
+		}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("cod").SetDotHere().Write("e"),
+	},
 	{
 		Name: "prioritize lines before the cursor with small height",
 		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
@@ -262,6 +308,22 @@ var codeAreaHandleTests = []HandleTest{
 			term.K('你'), term.K('好'), term.K(ui.Backspace)},
 		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "你", Dot: 3}},
 	},
+	{
+		Name: "inserting clears selection",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 4},
+			Selection: Selection{Active: true, Mark: 1}}}),
+		Events:       []term.Event{term.K('x')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "codex", Dot: 5}},
+	},
+	{
+		Name: "backspace clears selection",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer:    CodeBuffer{Content: "code", Dot: 4},
+			Selection: Selection{Active: true, Mark: 1}}}),
+		Events:       []term.Event{term.K(ui.Backspace)},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "cod", Dot: 3}},
+	},
 	// Regression test for https://b.elv.sh/1178
 	{
 		Name:  "Ctrl-H being equivalent to backspace",
@@ -475,3 +537,25 @@ func TestCodeAreaState_ApplyPending(t *testing.T) {
 			Rets(CodeAreaState{Buffer: CodeBuffer{Content: "x", Dot: 1}, HideRPrompt: true}),
 	})
 }
+
+func TestCodeAreaState_SelectedText(t *testing.T) {
+	selectedText := func(s CodeAreaState) string { return s.SelectedText() }
+	buf := CodeBuffer{Content: "code", Dot: 3}
+	tt.Test(t, tt.Fn("selectedText", selectedText), tt.Table{
+		tt.Args(CodeAreaState{Buffer: buf, Selection: Selection{true, 1}}).Rets("od"),
+		tt.Args(CodeAreaState{Buffer: buf, Selection: Selection{true, 4}}).Rets("e"),
+		// No selection.
+		tt.Args(CodeAreaState{Buffer: buf, Selection: Selection{false, 1}}).Rets(""),
+		// Empty selection.
+		tt.Args(CodeAreaState{Buffer: buf, Selection: Selection{true, 3}}).Rets(""),
+		// The mark is clamped to the end of the buffer.
+		tt.Args(CodeAreaState{Buffer: buf, Selection: Selection{true, 10}}).Rets("e"),
+	})
+}
+
+type rangedError struct {
+	diag.Ranging
+	msg string
+}
+
+func (e rangedError) Error() string { return e.msg }
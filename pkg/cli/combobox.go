@@ -22,6 +22,11 @@ type ComboBoxSpec struct {
 	OnFilter func(ComboBox, string)
 }
 
+// FilterFunc decides which items of a listing match the filter text. It is
+// called with the filter text and the texts of all items, and returns whether
+// each item matches.
+type FilterFunc func(p string, texts []string) []bool
+
 type comboBox struct {
 	codeArea CodeArea
 	listBox  ListBox
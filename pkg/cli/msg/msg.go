@@ -0,0 +1,182 @@
+// Package msg keeps the catalog of user-facing messages of the editor.
+//
+// Each message is identified by an ID like "mode-history", and has a built-in
+// English text. Programs embedding the editor can add translations for the
+// language of the user with AddTranslation; Elvish itself ships none. The text
+// can also be overridden by the user, for example from Elvish code. Messages
+// with arguments use format strings understood by fmt.Sprintf.
+package msg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Built-in English texts of all messages, keyed by ID.
+var builtin = map[string]string{
+	// Mode lines and prompts of modes. The spaces around them are added by the
+	// modes, so they should not be included in translations.
+	"mode-command":     "COMMAND",
+	"mode-completion":  "COMPLETING %s",
+	"mode-error":       "ERROR",
+	"mode-history":     "HISTORY",
+	"mode-history-seq": "HISTORY #%d",
+	"mode-instant":     "INSTANT",
+	"mode-last-output": "LAST OUTPUT",
+	"mode-lastcmd":     "LASTCMD",
+	"mode-listing":     "LISTING",
+	"mode-location":    "LOCATION",
+	"mode-minibuf":     "MINIBUF",
+	"mode-navigation":  "NAVIGATING",
+	"mode-palette":     "COMMAND PALETTE",
+	"mode-paste":       "PASTE",
+	"mode-raw":         "RAW",
+
+	// Filter flags shown after the names of modes.
+	"flag-case-insensitive": "(case-insensitive)",
+	"flag-dedup":            "(dedup on)",
+	"flag-show-hidden":      "(show hidden)",
+
+	// Notes.
+	"note-binding-bytes-out":     "[bytes out] %s",
+	"note-binding-lines-omitted": "[%d more lines of output omitted]",
+	"note-binding-value-out":     "[value out] %s",
+	"note-db-error":              "db error: %s",
+	"note-end-of-history":        "End of history",
+	"note-error":                 "[%s error] %s",
+	"note-error-copied":          "error copied to clipboard",
+	"note-no-candidates":         "no candidates",
+	"note-no-dir-history-store":  "no dir history store",
+	"note-no-history-store":      "no history store",
+	"note-no-saved-output":       "no saved output",
+	"note-see-stack-trace":       `see stack trace with "show $edit:exceptions[%d]"`,
+}
+
+var (
+	mutex        sync.RWMutex
+	translations = map[string]map[string]string{}
+	language     = languageFromEnv()
+	override     func(id string) (string, bool)
+)
+
+// Get returns the text of the message with the given ID. The override set
+// with SetOverride takes precedence, followed by the translation for the
+// current language, followed by the built-in text. It returns the ID itself if
+// the message doesn't exist.
+func Get(id string) string {
+	mutex.RLock()
+	f := override
+	mutex.RUnlock()
+	if f != nil {
+		if text, ok := f(id); ok {
+			return text
+		}
+	}
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if text, ok := lookupTranslation(id); ok {
+		return text
+	}
+	if text, ok := builtin[id]; ok {
+		return text
+	}
+	return id
+}
+
+// Sprintf is like fmt.Sprintf, but uses the text of the message with the given
+// ID as the format.
+func Sprintf(id string, a ...interface{}) string {
+	return fmt.Sprintf(Get(id), a...)
+}
+
+// Builtin returns a copy of the built-in texts of all messages, keyed by ID.
+func Builtin() map[string]string {
+	m := make(map[string]string, len(builtin))
+	for id, text := range builtin {
+		m[id] = text
+	}
+	return m
+}
+
+// AddTranslation adds translated texts for a language, like "zh" or "pt_BR".
+// Messages missing from the translation fall back to the built-in texts.
+func AddTranslation(lang string, texts map[string]string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	t := translations[lang]
+	if t == nil {
+		t = map[string]string{}
+		translations[lang] = t
+	}
+	for id, text := range texts {
+		t[id] = text
+	}
+}
+
+// SetLanguage sets the language used to look up translations, and returns a
+// function that restores the previous language. The language is initially
+// derived from the LC_ALL, LC_MESSAGES and LANG environment variables.
+func SetLanguage(lang string) (restore func()) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	saved := language
+	language = lang
+	return func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		language = saved
+	}
+}
+
+// SetOverride sets a function that overrides the texts of messages, and
+// returns a function that restores the previous override. The function is
+// called with the ID of a message, and should return false if it doesn't
+// override the message.
+func SetOverride(f func(id string) (string, bool)) (restore func()) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	saved := override
+	override = f
+	return func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		override = saved
+	}
+}
+
+// Looks up the translation for the current language, falling back from a
+// language with a territory like "pt_BR" to the language itself like "pt".
+// Must be called with mutex held.
+func lookupTranslation(id string) (string, bool) {
+	for lang := language; lang != ""; {
+		if text, ok := translations[lang][id]; ok {
+			return text, true
+		}
+		i := strings.LastIndexByte(lang, '_')
+		if i == -1 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return "", false
+}
+
+// Derives the language from the locale environment variables, which look like
+// "pt_BR.UTF-8" or "zh_CN@variant".
+func languageFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if i := strings.IndexAny(value, ".@"); i != -1 {
+				value = value[:i]
+			}
+			if value == "C" || value == "POSIX" {
+				return ""
+			}
+			return value
+		}
+	}
+	return ""
+}
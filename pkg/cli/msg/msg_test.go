@@ -0,0 +1,82 @@
+package msg
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestGet(t *testing.T) {
+	defer SetLanguage("")()
+	if got := Get("mode-history"); got != "HISTORY" {
+		t.Errorf("got %q, want built-in text", got)
+	}
+	if got := Get("no-such-message"); got != "no-such-message" {
+		t.Errorf("got %q for nonexistent message, want the ID", got)
+	}
+	if got := Sprintf("mode-completion", "file"); got != "COMPLETING file" {
+		t.Errorf("got %q, want formatted built-in text", got)
+	}
+}
+
+func TestGet_Translation(t *testing.T) {
+	defer func(saved map[string]map[string]string) { translations = saved }(translations)
+	translations = map[string]map[string]string{}
+	AddTranslation("pt", map[string]string{"mode-history": "HISTÓRICO"})
+	AddTranslation("pt_BR", map[string]string{"mode-error": "ERRO"})
+
+	defer SetLanguage("pt_BR")()
+	for id, want := range map[string]string{
+		"mode-error":   "ERRO",
+		"mode-history": "HISTÓRICO", // Falls back to pt
+		"mode-paste":   "PASTE",     // Falls back to the built-in text
+	} {
+		if got := Get(id); got != want {
+			t.Errorf("Get(%q) -> %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestGet_Override(t *testing.T) {
+	AddTranslation("xx", map[string]string{"mode-history": "translated"})
+	defer SetLanguage("xx")()
+	defer SetOverride(func(id string) (string, bool) {
+		if id == "mode-history" {
+			return "overridden", true
+		}
+		return "", false
+	})()
+
+	if got := Get("mode-history"); got != "overridden" {
+		t.Errorf("got %q, want overridden text", got)
+	}
+	if got := Get("mode-error"); got != "ERROR" {
+		t.Errorf("got %q, want built-in text", got)
+	}
+}
+
+var languageFromEnvTests = []struct {
+	lcAll, lcMessages, lang string
+	want                    string
+}{
+	{"", "", "", ""},
+	{"", "", "pt_BR.UTF-8", "pt_BR"},
+	{"", "zh_CN@variant", "pt_BR.UTF-8", "zh_CN"},
+	{"C", "zh_CN", "pt_BR", ""},
+	{"", "", "POSIX", ""},
+}
+
+func TestLanguageFromEnv(t *testing.T) {
+	for _, test := range languageFromEnvTests {
+		restoreLcAll := testutil.WithTempEnv("LC_ALL", test.lcAll)
+		restoreLcMessages := testutil.WithTempEnv("LC_MESSAGES", test.lcMessages)
+		restoreLang := testutil.WithTempEnv("LANG", test.lang)
+		if got := languageFromEnv(); got != test.want {
+			t.Errorf("languageFromEnv() with LC_ALL=%q LC_MESSAGES=%q LANG=%q -> %q, want %q",
+				test.lcAll, test.lcMessages, test.lang, got, test.want)
+		}
+		restoreLang()
+		restoreLcMessages()
+		restoreLcAll()
+	}
+}
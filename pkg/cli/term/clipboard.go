@@ -0,0 +1,17 @@
+package term
+
+import "encoding/base64"
+
+// ClipboardSequence returns the OSC 52 escape sequence that sets the system
+// clipboard to the given text.
+func ClipboardSequence(text string) string {
+	return "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\007"
+}
+
+// SupportsClipboard returns whether the environment indicates a terminal that
+// may support setting the clipboard with the OSC 52 escape sequence. This uses
+// the same heuristics as SupportsTitle, since terminals without windows don't
+// have access to a clipboard either.
+func SupportsClipboard() bool {
+	return SupportsTitle()
+}
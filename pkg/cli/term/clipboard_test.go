@@ -0,0 +1,16 @@
+package term
+
+import "testing"
+
+func TestClipboardSequence(t *testing.T) {
+	tests := []struct{ text, want string }{
+		{"", "\033]52;c;\007"},
+		{"foo", "\033]52;c;Zm9v\007"},
+		{"a\007b", "\033]52;c;YQdi\007"},
+	}
+	for _, test := range tests {
+		if got := ClipboardSequence(test.text); got != test.want {
+			t.Errorf("ClipboardSequence(%q) -> %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package term
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CursorPositionRequest is the escape sequence for requesting a report of the
+// cursor position from the terminal. The terminal replies with a sequence that
+// Reader decodes as a CursorPosition event.
+const CursorPositionRequest = "\033[6n"
+
+// DrawAboveSequence returns escape sequences that draw cells up lines above the
+// cursor, starting from column col, and then return the cursor to where it
+// was. Cells of the line that are not covered are left untouched. The styles
+// of the cells are adapted to the given Multiplexer.
+func DrawAboveSequence(up, col int, cells []Cell, m Multiplexer) string {
+	var sb strings.Builder
+	// Save the cursor, and move it to the destination.
+	sb.WriteString("\0337")
+	if up > 0 {
+		fmt.Fprintf(&sb, "\033[%dA", up)
+	}
+	sb.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&sb, "\033[%dC", col)
+	}
+	style := ""
+	for _, c := range cells {
+		if c.Style != style {
+			fmt.Fprintf(&sb, "\033[0;%sm", m.AdaptSGR(c.Style))
+			style = c.Style
+		}
+		sb.WriteString(c.Text)
+	}
+	if style != "" {
+		sb.WriteString("\033[0m")
+	}
+	// Restore the cursor.
+	sb.WriteString("\0338")
+	return sb.String()
+}
@@ -0,0 +1,24 @@
+package term
+
+import "testing"
+
+func TestDrawAboveSequence(t *testing.T) {
+	tests := []struct {
+		up, col int
+		cells   []Cell
+		want    string
+	}{
+		{2, 5, []Cell{{"a", "31"}, {"b", "31"}, {" ", ""}, {"c", ""}},
+			"\0337\033[2A\r\033[5C\033[0;31mab\033[0;m c\0338"},
+		// No movement needed; styled cells at the end reset the style.
+		{0, 0, []Cell{{"x", "1"}},
+			"\0337\r\033[0;1mx\033[0m\0338"},
+	}
+	for _, test := range tests {
+		got := DrawAboveSequence(test.up, test.col, test.cells, NoMultiplexer)
+		if got != test.want {
+			t.Errorf("DrawAboveSequence(%v, %v, %v) -> %q, want %q",
+				test.up, test.col, test.cells, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package term
+
+import "fmt"
+
+// CursorShape is the shape of the cursor, as supported by the DECSCUSR escape
+// sequence.
+type CursorShape int
+
+// Possible values of CursorShape. The values are the same as the parameters of
+// the DECSCUSR escape sequence.
+const (
+	// The shape configured by the user in the terminal.
+	CursorDefault CursorShape = iota
+	CursorBlinkingBlock
+	CursorBlock
+	CursorBlinkingUnderline
+	CursorUnderline
+	CursorBlinkingBar
+	CursorBar
+)
+
+var cursorShapeNames = []string{
+	"default", "blinking-block", "block", "blinking-underline", "underline",
+	"blinking-bar", "bar",
+}
+
+// ParseCursorShape parses the name of a cursor shape, like "bar" or
+// "blinking-block".
+func ParseCursorShape(name string) (CursorShape, bool) {
+	for i, s := range cursorShapeNames {
+		if s == name {
+			return CursorShape(i), true
+		}
+	}
+	return CursorDefault, false
+}
+
+// String returns the name of the cursor shape.
+func (s CursorShape) String() string {
+	if 0 <= s && int(s) < len(cursorShapeNames) {
+		return cursorShapeNames[s]
+	}
+	return fmt.Sprintf("CursorShape(%d)", int(s))
+}
+
+// Sequence returns the DECSCUSR escape sequence that sets the cursor shape.
+func (s CursorShape) Sequence() string {
+	return fmt.Sprintf("\033[%d q", int(s))
+}
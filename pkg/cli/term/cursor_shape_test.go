@@ -0,0 +1,22 @@
+package term
+
+import "testing"
+
+func TestParseCursorShape(t *testing.T) {
+	for s := CursorDefault; s <= CursorBar; s++ {
+		got, ok := ParseCursorShape(s.String())
+		if got != s || !ok {
+			t.Errorf("ParseCursorShape(%q) -> (%v, %v), want (%v, true)",
+				s.String(), got, ok, s)
+		}
+	}
+	if _, ok := ParseCursorShape("bad"); ok {
+		t.Errorf("ParseCursorShape(%q) -> ok, want !ok", "bad")
+	}
+}
+
+func TestCursorShapeSequence(t *testing.T) {
+	if got := CursorBar.Sequence(); got != "\033[6 q" {
+		t.Errorf("CursorBar.Sequence() -> %q, want %q", got, "\033[6 q")
+	}
+}
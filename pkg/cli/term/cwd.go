@@ -0,0 +1,29 @@
+package term
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// CwdSequence returns escape sequences that report the working directory to the
+// terminal, so that the terminal can open new windows or tabs in the same
+// directory. These are the OSC 7 sequence, which contains a file:// URL with
+// the host name, and the RemoteHost and CurrentDir sequences of iTerm2.
+// Terminals ignore the sequences they don't support.
+//
+// The user name is only used in the RemoteHost sequence, and that sequence is
+// left out if it is empty.
+func CwdSequence(user, host, dir string) string {
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths like C:/foo.
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Host: host, Path: path}
+	s := "\033]7;" + u.String() + "\007"
+	if user != "" {
+		s += "\033]1337;RemoteHost=" + stripControl(user+"@"+host) + "\007"
+	}
+	return s + "\033]1337;CurrentDir=" + stripControl(dir) + "\007"
+}
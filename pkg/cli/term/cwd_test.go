@@ -0,0 +1,28 @@
+package term
+
+import "testing"
+
+func TestCwdSequence(t *testing.T) {
+	tests := []struct{ user, host, dir, want string }{
+		{"elf", "box", "/home/elf",
+			"\033]7;file://box/home/elf\007" +
+				"\033]1337;RemoteHost=elf@box\007" +
+				"\033]1337;CurrentDir=/home/elf\007"},
+		// Without a user name.
+		{"", "box", "/tmp",
+			"\033]7;file://box/tmp\007" +
+				"\033]1337;CurrentDir=/tmp\007"},
+		// Special characters are percent-encoded in the URL, and control
+		// characters are removed from the iTerm2 sequences.
+		{"", "box", "/a b/%/\007c",
+			"\033]7;file://box/a%20b/%25/%07c\007" +
+				"\033]1337;CurrentDir=/a b/%/c\007"},
+	}
+	for _, test := range tests {
+		got := CwdSequence(test.user, test.host, test.dir)
+		if got != test.want {
+			t.Errorf("CwdSequence(%q, %q, %q) -> %q, want %q",
+				test.user, test.host, test.dir, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package term
+
+import (
+	"os"
+	"strings"
+
+	"github.com/elves/elvish/pkg/env"
+)
+
+// IsDumb returns whether the environment indicates a dumb terminal, which
+// doesn't understand escape sequences. This is the case when $TERM is "dumb" or
+// "unknown", or when running under Emacs's shell-mode, which sets $INSIDE_EMACS
+// to a value containing "comint".
+func IsDumb() bool {
+	return isDumb(os.Getenv(env.TERM), os.Getenv(env.INSIDE_EMACS))
+}
+
+func isDumb(term, insideEmacs string) bool {
+	return term == "dumb" || term == "unknown" ||
+		strings.Contains(insideEmacs, "comint")
+}
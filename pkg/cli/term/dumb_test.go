@@ -0,0 +1,26 @@
+package term
+
+import "testing"
+
+var isDumbTests = []struct {
+	term, insideEmacs string
+	want              bool
+}{
+	{"xterm-256color", "", false},
+	{"dumb", "", true},
+	{"unknown", "", true},
+	{"dumb", "27.1,comint", true},
+	{"xterm-256color", "27.1,comint", true},
+	// Terminal emulators inside Emacs are capable.
+	{"eterm-color", "27.1,term:0.96", false},
+	{"xterm-256color", "vterm", false},
+}
+
+func TestIsDumb(t *testing.T) {
+	for _, test := range isDumbTests {
+		if got := isDumb(test.term, test.insideEmacs); got != test.want {
+			t.Errorf("isDumb(%q, %q) -> %v, want %v",
+				test.term, test.insideEmacs, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package term
+
+import (
+	"io"
+	"strings"
+
+	"github.com/elves/elvish/pkg/wcwidth"
+)
+
+// dumbWriter renders the editor UI on a dumb terminal. It doesn't write any
+// escape sequences, and only uses carriage returns to go back to the start of
+// the current line.
+//
+// Since the cursor can't move up, only the last line of the buffer can be
+// updated in place. When any other line changes, the entire buffer is written
+// again below the old one.
+type dumbWriter struct {
+	file   io.Writer
+	curBuf *Buffer
+}
+
+// NewDumbWriter returns a Writer that writes plain text to the given
+// io.Writer, suitable for dumb terminals.
+func NewDumbWriter(f io.Writer) Writer {
+	return &dumbWriter{f, &Buffer{}}
+}
+
+// CurrentBuffer returns the current buffer.
+func (w *dumbWriter) CurrentBuffer() *Buffer {
+	return w.curBuf
+}
+
+// ResetCurrentBuffer resets the current buffer.
+func (w *dumbWriter) ResetCurrentBuffer() {
+	w.curBuf = &Buffer{}
+}
+
+// CommitBuffer updates the terminal display to reflect current buffer.
+func (w *dumbWriter) CommitBuffer(bufNoti, buf *Buffer, fullRefresh bool) error {
+	old := w.curBuf
+	sb := new(strings.Builder)
+	// Width of the line the cursor is on, which needs to be overwritten.
+	oldWidth := 0
+	if len(old.Lines) > 0 {
+		oldWidth = CellsWidth(old.Lines[len(old.Lines)-1])
+	}
+	// Width of what was written on the line the cursor is on.
+	written := 0
+
+	if bufNoti == nil && !fullRefresh && canUpdateLastLine(old, buf) {
+		last := buf.Lines[len(buf.Lines)-1]
+		if old.Dot == buf.Dot && cellsEqual(last, old.Lines[len(old.Lines)-1]) {
+			w.curBuf = buf
+			return nil
+		}
+		sb.WriteString("\r")
+		written = writePlainCells(sb, last)
+	} else {
+		sb.WriteString("\r")
+		if bufNoti != nil {
+			for _, line := range bufNoti.Lines {
+				// The first notification may overwrite the last line of the
+				// old buffer.
+				if w := writePlainCells(sb, line); oldWidth > w {
+					sb.WriteString(strings.Repeat(" ", oldWidth-w))
+				}
+				sb.WriteString("\n")
+				oldWidth = 0
+			}
+		} else if len(old.Lines) > 0 {
+			// Keep the old buffer and start afresh on the next line.
+			sb.WriteString("\n")
+			oldWidth = 0
+		}
+		for i, line := range buf.Lines {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			written = writePlainCells(sb, line)
+		}
+	}
+
+	// Erase what is left of the last line, and move the cursor to the dot if it
+	// is on the last line. If it is on any other line, leave the cursor at the
+	// end; there is no way to get there.
+	moveToDot := buf.Dot.Line == len(buf.Lines)-1 && buf.Dot.Col != written
+	if oldWidth > written {
+		sb.WriteString(strings.Repeat(" ", oldWidth-written))
+		moveToDot = buf.Dot.Line == len(buf.Lines)-1
+	}
+	if moveToDot {
+		sb.WriteString("\r")
+		writePlainCells(sb, cellsBeforeCol(buf.Lines[buf.Dot.Line], buf.Dot.Col))
+	}
+
+	_, err := io.WriteString(w.file, sb.String())
+	if err != nil {
+		return err
+	}
+	w.curBuf = buf
+	return nil
+}
+
+// Returns whether all lines of the two buffers except the last one are the
+// same, so that the new buffer can be shown by only updating the last line.
+func canUpdateLastLine(old, buf *Buffer) bool {
+	if len(old.Lines) == 0 || len(old.Lines) != len(buf.Lines) ||
+		old.Width != buf.Width {
+		return false
+	}
+	for i := 0; i < len(buf.Lines)-1; i++ {
+		if !cellsEqual(old.Lines[i], buf.Lines[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compares the text of two lines, ignoring styles.
+func cellsEqual(cs1, cs2 []Cell) bool {
+	if len(cs1) != len(cs2) {
+		return false
+	}
+	for i := range cs1 {
+		if cs1[i].Text != cs2[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the cells that come before the given column.
+func cellsBeforeCol(cs []Cell, col int) []Cell {
+	w := 0
+	for i, c := range cs {
+		if w >= col {
+			return cs[:i]
+		}
+		w += wcwidth.Of(c.Text)
+	}
+	return cs
+}
+
+// Writes the text of the cells, and returns the total width.
+func writePlainCells(sb *strings.Builder, cs []Cell) int {
+	for _, c := range cs {
+		sb.WriteString(c.Text)
+	}
+	return CellsWidth(cs)
+}
@@ -0,0 +1,45 @@
+package term
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumbWriter(t *testing.T) {
+	sb := &strings.Builder{}
+	testOutput := func(want string) {
+		t.Helper()
+		if sb.String() != want {
+			t.Errorf("got %q, want %q", sb.String(), want)
+		}
+		sb.Reset()
+	}
+	styled := func(s string) *BufferBuilder {
+		return NewBufferBuilder(20).WriteStringSGR(s, "31")
+	}
+
+	w := NewDumbWriter(sb)
+	// Styles are dropped.
+	w.CommitBuffer(nil, styled("~> ech").SetDotHere().Buffer(), false)
+	testOutput("\r~> ech")
+	// The last line is updated in place.
+	w.CommitBuffer(nil, styled("~> echo").SetDotHere().Buffer(), false)
+	testOutput("\r~> echo")
+	// Old content is erased with spaces.
+	w.CommitBuffer(nil, styled("~> e").SetDotHere().Buffer(), false)
+	testOutput("\r~> e   \r~> e")
+	// Moving the dot on the last line rewrites the line up to the dot.
+	w.CommitBuffer(nil, styled("~> ").SetDotHere().Write("e").Buffer(), false)
+	testOutput("\r~> e\r~> ")
+	// Nothing is written when nothing changes.
+	w.CommitBuffer(nil, styled("~> ").SetDotHere().Write("e").Buffer(), false)
+	testOutput("")
+	// When other lines change, the entire buffer is written on a new line.
+	w.CommitBuffer(nil,
+		styled("~> e").SetDotHere().Newline().Write("listing").Buffer(), false)
+	testOutput("\r\n~> e\nlisting")
+	// Notifications overwrite the last line, and are followed by the buffer.
+	w.CommitBuffer(NewBufferBuilder(20).Write("note").Buffer(),
+		styled("~> e").SetDotHere().Buffer(), false)
+	testOutput("\rnote   \n~> e")
+}
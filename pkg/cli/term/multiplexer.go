@@ -0,0 +1,185 @@
+package term
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/pkg/env"
+)
+
+// Multiplexer is a terminal multiplexer that the program may be running
+// inside. Multiplexers implement a terminal of their own, so escape sequences
+// not supported by them don't reach the outer terminal unless they are wrapped
+// in a passthrough sequence.
+type Multiplexer int
+
+// Possible values of Multiplexer.
+const (
+	NoMultiplexer Multiplexer = iota
+	Tmux
+	Screen
+)
+
+// DetectMultiplexer detects the terminal multiplexer from the environment.
+func DetectMultiplexer() Multiplexer {
+	return detectMultiplexer(
+		os.Getenv(env.TMUX), os.Getenv(env.STY), os.Getenv(env.TERM))
+}
+
+func detectMultiplexer(tmux, sty, term string) Multiplexer {
+	switch {
+	case tmux != "":
+		return Tmux
+	case sty != "":
+		return Screen
+	// The environment variables are not passed through SSH or sudo, but $TERM
+	// is.
+	case strings.HasPrefix(term, "tmux"):
+		return Tmux
+	case strings.HasPrefix(term, "screen"):
+		return Screen
+	default:
+		return NoMultiplexer
+	}
+}
+
+// Screen limits the length of a string sequence to this many bytes.
+const screenMaxPassthrough = 768
+
+// Passthrough wraps an escape sequence in passthrough sequences, so that the
+// multiplexer sends it to the outer terminal as is.
+func (m Multiplexer) Passthrough(seq string) string {
+	switch m {
+	case Tmux:
+		// Escape characters in the wrapped sequence are doubled.
+		return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	case Screen:
+		// Long sequences are split into multiple passthrough sequences, which
+		// reach the outer terminal as one sequence.
+		var sb strings.Builder
+		for len(seq) > 0 {
+			n := len(seq)
+			if n > screenMaxPassthrough {
+				n = screenMaxPassthrough
+			}
+			sb.WriteString("\033P" + seq[:n] + "\033\\")
+			seq = seq[n:]
+		}
+		return sb.String()
+	default:
+		return seq
+	}
+}
+
+// AdaptOSC adapts an OSC escape sequence with the given number, like 52 for
+// setting the clipboard, to the multiplexer. Sequences that the multiplexer
+// doesn't support are wrapped in passthrough sequences, or dropped if they
+// can't be passed through. Other sequences are returned as is.
+func (m Multiplexer) AdaptOSC(number int, seq string) string {
+	switch {
+	case m == NoMultiplexer:
+		return seq
+	case number == 52:
+		// Setting the clipboard.
+		return m.Passthrough(seq)
+	case number == 8:
+		// Hyperlinks. The outer terminal would keep the hyperlink open
+		// regardless of how screen moves the cursor, so it is dropped.
+		if m == Screen {
+			return ""
+		}
+		return m.Passthrough(seq)
+	default:
+		return seq
+	}
+}
+
+// SupportsTrueColor returns whether the multiplexer supports 24-bit colors.
+func (m Multiplexer) SupportsTrueColor() bool {
+	return m != Screen
+}
+
+// AdaptSGR adapts the parameters of an SGR sequence, like "1;38;2;255;0;0", to
+// the multiplexer. If the multiplexer doesn't support 24-bit colors, they are
+// replaced with the closest colors in the xterm 256-color palette.
+func (m Multiplexer) AdaptSGR(sgr string) string {
+	if m.SupportsTrueColor() || !strings.Contains(sgr, ";2;") {
+		return sgr
+	}
+	params := strings.Split(sgr, ";")
+	adapted := make([]string, 0, len(params))
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		if (p == "38" || p == "48") && i+4 < len(params) && params[i+1] == "2" {
+			if rgb, ok := parseRGB(params[i+2 : i+5]); ok {
+				adapted = append(adapted, p, "5",
+					strconv.Itoa(closestXTerm256(rgb[0], rgb[1], rgb[2])))
+				i += 4
+				continue
+			}
+		}
+		adapted = append(adapted, p)
+	}
+	return strings.Join(adapted, ";")
+}
+
+func parseRGB(params []string) ([3]int, bool) {
+	var rgb [3]int
+	for i, p := range params {
+		c, err := strconv.Atoi(p)
+		if err != nil || c < 0 || c > 255 {
+			return rgb, false
+		}
+		rgb[i] = c
+	}
+	return rgb, true
+}
+
+// Levels of each component in the 6x6x6 color cube of the xterm 256-color
+// palette, which starts at index 16.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// Returns the index of the closest color in the xterm 256-color palette,
+// considering the color cube and the grayscale ramp at indices 232 to 255 but
+// not the 16 colors at the start, which are often customized.
+func closestXTerm256(r, g, b int) int {
+	ri, gi, bi := closestCubeLevel(r), closestCubeLevel(g), closestCubeLevel(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := distSquared(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	// The grayscale ramp has levels 8, 18, ..., 238.
+	avg := (r + g + b) / 3
+	grayI := (avg - 8 + 5) / 10
+	if grayI < 0 {
+		grayI = 0
+	} else if grayI > 23 {
+		grayI = 23
+	}
+	level := 8 + 10*grayI
+	if distSquared(r, g, b, level, level, level) < cubeDist {
+		return 232 + grayI
+	}
+	return cube
+}
+
+func closestCubeLevel(c int) int {
+	best := 0
+	for i, level := range cubeLevels {
+		if abs(c-level) < abs(c-cubeLevels[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func distSquared(r1, g1, b1, r2, g2, b2 int) int {
+	return (r1-r2)*(r1-r2) + (g1-g2)*(g1-g2) + (b1-b2)*(b1-b2)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,101 @@
+package term
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectMultiplexer(t *testing.T) {
+	tests := []struct {
+		tmux, sty, term string
+		want            Multiplexer
+	}{
+		{"/tmp/tmux-1000/default,1234,0", "", "screen-256color", Tmux},
+		{"", "1234.pts-0.host", "screen", Screen},
+		{"", "", "tmux-256color", Tmux},
+		{"", "", "screen.xterm-256color", Screen},
+		{"", "", "xterm-256color", NoMultiplexer},
+	}
+	for _, test := range tests {
+		got := detectMultiplexer(test.tmux, test.sty, test.term)
+		if got != test.want {
+			t.Errorf("detectMultiplexer(%q, %q, %q) -> %v, want %v",
+				test.tmux, test.sty, test.term, got, test.want)
+		}
+	}
+}
+
+func TestMultiplexer_Passthrough(t *testing.T) {
+	seq := "\033]52;c;Zm9v\007"
+	tests := []struct {
+		m    Multiplexer
+		want string
+	}{
+		{NoMultiplexer, seq},
+		{Tmux, "\033Ptmux;\033\033]52;c;Zm9v\007\033\\"},
+		{Screen, "\033P\033]52;c;Zm9v\007\033\\"},
+	}
+	for _, test := range tests {
+		if got := test.m.Passthrough(seq); got != test.want {
+			t.Errorf("%v.Passthrough(%q) -> %q, want %q", test.m, seq, got, test.want)
+		}
+	}
+}
+
+func TestMultiplexer_Passthrough_SplitsLongSequencesForScreen(t *testing.T) {
+	seq := strings.Repeat("x", screenMaxPassthrough+1)
+	want := "\033P" + seq[:screenMaxPassthrough] + "\033\\" + "\033Px\033\\"
+	if got := Screen.Passthrough(seq); got != want {
+		t.Errorf("Screen.Passthrough(...) -> %q, want %q", got, want)
+	}
+}
+
+func TestMultiplexer_AdaptOSC(t *testing.T) {
+	clipboard := "\033]52;c;Zm9v\007"
+	link := "\033]8;;https://elv.sh\007"
+	title := "\033]2;elvish\007"
+	tests := []struct {
+		m         Multiplexer
+		number    int
+		seq, want string
+	}{
+		{NoMultiplexer, 52, clipboard, clipboard},
+		{Tmux, 52, clipboard, Tmux.Passthrough(clipboard)},
+		{Screen, 52, clipboard, Screen.Passthrough(clipboard)},
+		{Tmux, 8, link, Tmux.Passthrough(link)},
+		{Screen, 8, link, ""},
+		// Sequences supported by the multiplexers are kept as is.
+		{Tmux, 2, title, title},
+		{Screen, 2, title, title},
+	}
+	for _, test := range tests {
+		if got := test.m.AdaptOSC(test.number, test.seq); got != test.want {
+			t.Errorf("%v.AdaptOSC(%d, %q) -> %q, want %q",
+				test.m, test.number, test.seq, got, test.want)
+		}
+	}
+}
+
+func TestMultiplexer_AdaptSGR(t *testing.T) {
+	tests := []struct {
+		m         Multiplexer
+		sgr, want string
+	}{
+		{NoMultiplexer, "38;2;255;0;0", "38;2;255;0;0"},
+		{Tmux, "38;2;255;0;0", "38;2;255;0;0"},
+		{Screen, "38;2;255;0;0", "38;5;196"},
+		{Screen, "1;48;2;0;0;0;4", "1;48;5;16;4"},
+		// Grays use the grayscale ramp.
+		{Screen, "38;2;128;128;128", "38;5;244"},
+		// Other colors are kept as is.
+		{Screen, "1;31;38;5;100", "1;31;38;5;100"},
+		// Malformed true colors are kept as is.
+		{Screen, "38;2;300;0;0", "38;2;300;0;0"},
+		{Screen, "38;2;1", "38;2;1"},
+	}
+	for _, test := range tests {
+		if got := test.m.AdaptSGR(test.sgr); got != test.want {
+			t.Errorf("%v.AdaptSGR(%q) -> %q, want %q", test.m, test.sgr, got, test.want)
+		}
+	}
+}
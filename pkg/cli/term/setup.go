@@ -33,6 +33,10 @@ const (
 
 // setupVT performs setup for VT-like terminals.
 func setupVT(out *os.File) error {
+	if IsDumb() {
+		// Dumb terminals don't understand any of the sequences below.
+		return nil
+	}
 	_, width := sys.GetWinsize(out)
 
 	s := ""
@@ -84,6 +88,10 @@ func setupVT(out *os.File) error {
 
 // restoreVT performs restore for VT-like terminals.
 func restoreVT(out *os.File) error {
+	if IsDumb() {
+		_, err := out.WriteString("\r")
+		return err
+	}
 	s := ""
 	// Turn on autowrap.
 	s += "\033[?7h"
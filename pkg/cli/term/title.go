@@ -0,0 +1,44 @@
+package term
+
+import (
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/elves/elvish/pkg/env"
+)
+
+// TitleSequence returns the OSC 2 escape sequence that sets the title of the
+// terminal window. Control characters are removed from the title, since they
+// may end the sequence early or be interpreted by the terminal.
+func TitleSequence(title string) string {
+	return "\033]2;" + stripControl(title) + "\007"
+}
+
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// SupportsTitle returns whether the environment indicates a terminal that
+// supports setting the title. This is not the case for dumb terminals (see
+// IsDumb), the Linux console and other terminals without windows, and the
+// terminal emulators of Emacs.
+func SupportsTitle() bool {
+	return supportsTitle(os.Getenv(env.TERM), os.Getenv(env.INSIDE_EMACS))
+}
+
+// Values of $TERM that indicate terminals without windows.
+var noTitleTerms = map[string]bool{
+	"": true, "linux": true, "cons25": true, "vt100": true, "vt102": true,
+	"vt220": true,
+}
+
+func supportsTitle(term, insideEmacs string) bool {
+	return !isDumb(term, insideEmacs) && !noTitleTerms[term] &&
+		!strings.HasPrefix(term, "eterm") && insideEmacs == ""
+}
@@ -0,0 +1,39 @@
+package term
+
+import "testing"
+
+func TestTitleSequence(t *testing.T) {
+	tests := []struct{ title, want string }{
+		{"elvish", "\033]2;elvish\007"},
+		{"~/a b", "\033]2;~/a b\007"},
+		// Control characters are removed.
+		{"a\007b\033]2;c\nd\u009ce", "\033]2;ab]2;cde\007"},
+	}
+	for _, test := range tests {
+		if got := TitleSequence(test.title); got != test.want {
+			t.Errorf("TitleSequence(%q) -> %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestSupportsTitle(t *testing.T) {
+	tests := []struct {
+		term, insideEmacs string
+		want              bool
+	}{
+		{"xterm-256color", "", true},
+		{"screen", "", true},
+		{"", "", false},
+		{"dumb", "", false},
+		{"linux", "", false},
+		{"eterm-color", "26.1,term:0.96", false},
+		{"xterm", "28.1,vterm", false},
+	}
+	for _, test := range tests {
+		got := supportsTitle(test.term, test.insideEmacs)
+		if got != test.want {
+			t.Errorf("supportsTitle(%q, %q) -> %v, want %v",
+				test.term, test.insideEmacs, got, test.want)
+		}
+	}
+}
@@ -22,11 +22,18 @@ type Writer interface {
 type writer struct {
 	file   io.Writer
 	curBuf *Buffer
+	mux    Multiplexer
 }
 
 // NewWriter returns a Writer that writes VT100 sequences to the given io.Writer.
 func NewWriter(f io.Writer) Writer {
-	return &writer{f, &Buffer{}}
+	return &writer{f, &Buffer{}, NoMultiplexer}
+}
+
+// NewMultiplexerWriter is like NewWriter, but adapts the styles of the cells to
+// the given terminal multiplexer (see Multiplexer.AdaptSGR).
+func NewMultiplexerWriter(f io.Writer, m Multiplexer) Writer {
+	return &writer{f, &Buffer{}, m}
 }
 
 // CurrentBuffer returns the current buffer.
@@ -99,7 +106,7 @@ func (w *writer) CommitBuffer(bufNoti, buf *Buffer, fullRefresh bool) error {
 
 	switchStyle := func(newstyle string) {
 		if newstyle != style {
-			fmt.Fprintf(bytesBuf, "\033[0;%sm", newstyle)
+			fmt.Fprintf(bytesBuf, "\033[0;%sm", w.mux.AdaptSGR(newstyle))
 			style = newstyle
 		}
 	}
@@ -22,3 +22,15 @@ func TestWriter(t *testing.T) {
 		false)
 	testOutput(hideCursor + "\rnote 1\033[K\n" + "line 1\r\033[6C" + showCursor)
 }
+
+func TestMultiplexerWriter_AdaptsStyles(t *testing.T) {
+	sb := &strings.Builder{}
+	w := NewMultiplexerWriter(sb, Screen)
+	w.CommitBuffer(nil,
+		NewBufferBuilder(10).WriteStringSGR("x", "1;38;2;255;0;0").SetDotHere().Buffer(),
+		false)
+	want := hideCursor + "\r\033[0;1;38;5;196mx\033[0;m\r\033[1C" + showCursor
+	if sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
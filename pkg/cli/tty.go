@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/env"
 	"github.com/elves/elvish/pkg/sys"
 )
 
@@ -44,6 +47,9 @@ type TTY interface {
 
 	// Size returns the height and width of the terminal.
 	Size() (h, w int)
+	// Dumb returns whether the terminal is a dumb terminal, which only
+	// supports plain text, carriage returns and line feeds.
+	Dumb() bool
 
 	// Buffer returns the current buffer. The initial value of the current
 	// buffer is nil.
@@ -52,6 +58,31 @@ type TTY interface {
 	ResetBuffer()
 	// UpdateBuffer updates the current buffer and draw it to the terminal.
 	UpdateBuffer(bufNotes, bufMain *term.Buffer, full bool) error
+	// SetCursorShape sets the shape of the cursor. It is a no-op for dumb
+	// terminals, and when the shape is the same as the last one set.
+	SetCursorShape(s term.CursorShape)
+	// SetTitle sets the title of the terminal window. It is a no-op for
+	// terminals that don't support titles (see term.SupportsTitle), and when
+	// the title is the same as the last one set.
+	SetTitle(title string)
+	// ReportCwd reports the working directory to the terminal (see
+	// term.CwdSequence). It is a no-op for dumb terminals.
+	ReportCwd(dir string)
+	// CursorPosition queries the terminal for the position of the cursor, with
+	// lines and columns counted from 0. It returns false if the position can't
+	// be found, for example because the terminal doesn't reply in time. It
+	// must not be called when input is being read.
+	CursorPosition() (pos term.Pos, ok bool)
+	// DrawAbove draws cells up lines above the cursor, starting from column
+	// col, without moving the cursor or changing the current buffer. It is a
+	// no-op for dumb terminals.
+	DrawAbove(up, col int, cells []term.Cell)
+	// SetClipboard sets the system clipboard with the OSC 52 escape sequence,
+	// and returns whether the sequence was written. It returns false for
+	// terminals that are not known to support it (see term.SupportsClipboard).
+	// Inside tmux and screen, the sequence is passed through to the outer
+	// terminal.
+	SetClipboard(text string) bool
 }
 
 // StdTTY is the terminal connected to inputs from stdin and output to stderr.
@@ -61,20 +92,49 @@ type aTTY struct {
 	in, out *os.File
 	r       term.Reader
 	w       term.Writer
+	dumb    bool
 	sigCh   chan os.Signal
 
+	// Events read while waiting for the reply to a cursor position request,
+	// to be returned by ReadEvent before reading new events.
+	pendingMutex sync.Mutex
+	pending      []term.Event
+
 	rawMutex sync.Mutex
 	raw      int
+
+	cursorShapeMutex sync.Mutex
+	cursorShape      term.CursorShape
+
+	noTitle     bool
+	noClipboard bool
+	mux         term.Multiplexer
+	titleMutex  sync.Mutex
+	title       string
 }
 
-// NewTTY returns a new TTY from input and output terminal files.
+// NewTTY returns a new TTY from input and output terminal files. If the
+// environment indicates a dumb terminal (see term.IsDumb), the TTY renders in a
+// minimal mode that does not use any escape sequences. If it indicates a
+// terminal multiplexer (see term.DetectMultiplexer), the TTY adapts the escape
+// sequences it writes to the multiplexer.
 func NewTTY(in, out *os.File) TTY {
-	return &aTTY{in: in, out: out, w: term.NewWriter(out)}
+	noTitle, noClipboard := !term.SupportsTitle(), !term.SupportsClipboard()
+	if term.IsDumb() {
+		return &aTTY{in: in, out: out, w: term.NewDumbWriter(out), dumb: true,
+			noTitle: noTitle, noClipboard: noClipboard}
+	}
+	mux := term.DetectMultiplexer()
+	return &aTTY{in: in, out: out, w: term.NewMultiplexerWriter(out, mux),
+		noTitle: noTitle, noClipboard: noClipboard, mux: mux}
 }
 
 func (t *aTTY) Setup() (func(), error) {
 	restore, err := term.Setup(t.in, t.out)
 	return func() {
+		// Restore the cursor shape in case it was not restored by the user of
+		// the TTY, for example when exiting on an error.
+		t.SetCursorShape(term.CursorDefault)
 		err := restore()
 		if err != nil {
 			fmt.Println(t.out, "failed to restore terminal properties:", err)
@@ -86,7 +146,14 @@ func (t *aTTY) Size() (h, w int) {
 	return sys.GetWinsize(t.out)
 }
 
+func (t *aTTY) Dumb() bool {
+	return t.dumb
+}
+
 func (t *aTTY) ReadEvent() (term.Event, error) {
+	if event, ok := t.popPending(); ok {
+		return event, nil
+	}
 	if t.r == nil {
 		t.r = term.NewReader(t.in)
 	}
@@ -96,6 +163,17 @@ func (t *aTTY) ReadEvent() (term.Event, error) {
 	return t.r.ReadEvent()
 }
 
+func (t *aTTY) popPending() (term.Event, bool) {
+	t.pendingMutex.Lock()
+	defer t.pendingMutex.Unlock()
+	if len(t.pending) == 0 {
+		return nil, false
+	}
+	event := t.pending[0]
+	t.pending = t.pending[1:]
+	return event, true
+}
+
 func (t *aTTY) consumeRaw() bool {
 	t.rawMutex.Lock()
 	defer t.rawMutex.Unlock()
@@ -131,6 +209,108 @@ func (t *aTTY) UpdateBuffer(bufNotes, bufMain *term.Buffer, full bool) error {
 	return t.w.CommitBuffer(bufNotes, bufMain, full)
 }
 
+func (t *aTTY) SetCursorShape(s term.CursorShape) {
+	t.cursorShapeMutex.Lock()
+	defer t.cursorShapeMutex.Unlock()
+	if t.dumb || s == t.cursorShape {
+		return
+	}
+	t.cursorShape = s
+	t.out.WriteString(s.Sequence())
+}
+
+func (t *aTTY) SetTitle(title string) {
+	t.titleMutex.Lock()
+	defer t.titleMutex.Unlock()
+	if t.noTitle || title == t.title {
+		return
+	}
+	t.title = title
+	t.out.WriteString(term.TitleSequence(title))
+}
+
+func (t *aTTY) ReportCwd(dir string) {
+	if t.dumb {
+		return
+	}
+	user := os.Getenv(env.USER)
+	if user == "" {
+		user = os.Getenv(env.USERNAME)
+	}
+	host, _ := os.Hostname()
+	t.out.WriteString(term.CwdSequence(user, host, dir))
+}
+
+// How long to wait for the terminal to reply to a cursor position request.
+var cursorPositionTimeout = 100 * time.Millisecond
+
+func (t *aTTY) CursorPosition() (term.Pos, bool) {
+	// The Windows console delivers input as events and never replies with a
+	// cursor position report that Reader understands.
+	if t.dumb || runtime.GOOS == "windows" {
+		return term.Pos{}, false
+	}
+	restore, err := term.Setup(t.in, t.out)
+	if restore == nil {
+		return term.Pos{}, false
+	}
+	defer restore()
+	if _, err = t.out.WriteString(term.CursorPositionRequest); err != nil {
+		return term.Pos{}, false
+	}
+
+	r := term.NewReader(t.in)
+	posCh := make(chan term.CursorPosition, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			event, err := r.ReadEvent()
+			if err != nil {
+				if term.IsReadErrorRecoverable(err) && err != term.ErrStopped {
+					continue
+				}
+				return
+			}
+			if pos, ok := event.(term.CursorPosition); ok {
+				posCh <- pos
+				return
+			}
+			// Keep keys typed ahead for the next ReadEvent call.
+			t.pendingMutex.Lock()
+			t.pending = append(t.pending, event)
+			t.pendingMutex.Unlock()
+		}
+	}()
+	defer func() {
+		r.Close()
+		<-done
+	}()
+
+	select {
+	case pos := <-posCh:
+		// The terminal reports 1-based positions.
+		return term.Pos{Line: pos.Line - 1, Col: pos.Col - 1}, true
+	case <-time.After(cursorPositionTimeout):
+		return term.Pos{}, false
+	}
+}
+
+func (t *aTTY) DrawAbove(up, col int, cells []term.Cell) {
+	if t.dumb {
+		return
+	}
+	t.out.WriteString(term.DrawAboveSequence(up, col, cells, t.mux))
+}
+
+func (t *aTTY) SetClipboard(text string) bool {
+	if t.dumb || t.noClipboard {
+		return false
+	}
+	_, err := t.out.WriteString(t.mux.AdaptOSC(52, term.ClipboardSequence(text)))
+	return err == nil
+}
+
 func (t *aTTY) NotifySignals() <-chan os.Signal {
 	t.sigCh = sys.NotifySignals()
 	return t.sigCh
@@ -4,9 +4,14 @@ package cli_test
 
 import (
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/creack/pty"
 	. "github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/testutil"
 	"golang.org/x/sys/unix"
 )
 
@@ -45,3 +50,37 @@ func nextSig(sigch <-chan os.Signal) os.Signal {
 		}
 	}
 }
+
+func TestTTYCursorPosition(t *testing.T) {
+	restore := testutil.WithTempEnv(env.TERM, "xterm")
+	defer restore()
+	master, slave, err := pty.Open()
+	if err != nil {
+		t.Skip("cannot open pty:", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+	tty := NewTTY(slave, slave)
+
+	go func() {
+		// Wait for the request, and reply with a key typed ahead before the
+		// cursor position report.
+		buf := make([]byte, 64)
+		for read := ""; !strings.Contains(read, term.CursorPositionRequest); {
+			n, err := master.Read(buf)
+			if err != nil {
+				return
+			}
+			read += string(buf[:n])
+		}
+		master.Write([]byte("a\033[3;5R"))
+	}()
+
+	pos, ok := tty.CursorPosition()
+	if want := (term.Pos{Line: 2, Col: 4}); !ok || pos != want {
+		t.Errorf("got %v, %v, want %v, true", pos, ok, want)
+	}
+	if event, _ := tty.ReadEvent(); event != term.K('a') {
+		t.Errorf("got event %v, want key typed ahead", event)
+	}
+}
@@ -186,6 +186,26 @@ func (c *client) Dirs(blacklist map[string]struct{}) ([]store.Dir, error) {
 	return res.Dirs, err
 }
 
+func (c *client) PushDir(dir string) error {
+	req := &api.PushDirRequest{Dir: dir}
+	res := &api.PushDirResponse{}
+	return c.call("PushDir", req, res)
+}
+
+func (c *client) PopDir() (string, error) {
+	req := &api.PopDirRequest{}
+	res := &api.PopDirResponse{}
+	err := c.call("PopDir", req, res)
+	return res.Dir, err
+}
+
+func (c *client) DirStack() ([]string, error) {
+	req := &api.DirStackRequest{}
+	res := &api.DirStackResponse{}
+	err := c.call("DirStack", req, res)
+	return res.Dirs, err
+}
+
 func (c *client) SharedVar(name string) (string, error) {
 	req := &api.SharedVarRequest{Name: name}
 	res := &api.SharedVarResponse{}
@@ -204,3 +224,54 @@ func (c *client) DelSharedVar(name string) error {
 	res := &api.DelSharedVarResponse{}
 	return c.call("DelSharedVar", req, res)
 }
+
+func (c *client) Secret(name string) ([]byte, error) {
+	req := &api.SecretRequest{Name: name}
+	res := &api.SecretResponse{}
+	err := c.call("Secret", req, res)
+	return res.Value, err
+}
+
+func (c *client) SetSecret(name string, value []byte) error {
+	req := &api.SetSecretRequest{Name: name, Value: value}
+	res := &api.SetSecretResponse{}
+	return c.call("SetSecret", req, res)
+}
+
+func (c *client) DelSecret(name string) error {
+	req := &api.DelSecretRequest{Name: name}
+	res := &api.DelSecretResponse{}
+	return c.call("DelSecret", req, res)
+}
+
+func (c *client) EnvTrust(path string) (store.EnvTrust, error) {
+	req := &api.EnvTrustRequest{Path: path}
+	res := &api.EnvTrustResponse{}
+	err := c.call("EnvTrust", req, res)
+	return res.Trust, err
+}
+
+func (c *client) SetEnvTrust(path string, t store.EnvTrust) error {
+	req := &api.SetEnvTrustRequest{Path: path, Trust: t}
+	res := &api.SetEnvTrustResponse{}
+	return c.call("SetEnvTrust", req, res)
+}
+
+func (c *client) RetentionPolicy() (store.RetentionPolicy, error) {
+	req := &api.RetentionPolicyRequest{}
+	res := &api.RetentionPolicyResponse{}
+	err := c.call("RetentionPolicy", req, res)
+	return res.Policy, err
+}
+
+func (c *client) SetRetentionPolicy(p store.RetentionPolicy) error {
+	req := &api.SetRetentionPolicyRequest{Policy: p}
+	res := &api.SetRetentionPolicyResponse{}
+	return c.call("SetRetentionPolicy", req, res)
+}
+
+func (c *client) Compact() error {
+	req := &api.CompactRequest{}
+	res := &api.CompactResponse{}
+	return c.call("Compact", req, res)
+}
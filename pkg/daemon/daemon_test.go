@@ -53,7 +53,11 @@ func TestDaemon(t *testing.T) {
 	// Store requests.
 	storetest.TestCmd(t, client)
 	storetest.TestDir(t, client)
+	storetest.TestDirStack(t, client)
 	storetest.TestSharedVar(t, client)
+	storetest.TestSecret(t, client)
+	storetest.TestEnvTrust(t, client)
+	storetest.TestRetention(t, client)
 }
 
 func TestProgram_SpuriousArgument(t *testing.T) {
@@ -115,6 +115,26 @@ type DirsResponse struct {
 	Dirs []store.Dir
 }
 
+// DirStack requests.
+
+type PushDirRequest struct {
+	Dir string
+}
+
+type PushDirResponse struct{}
+
+type PopDirRequest struct{}
+
+type PopDirResponse struct {
+	Dir string
+}
+
+type DirStackRequest struct{}
+
+type DirStackResponse struct {
+	Dirs []string
+}
+
 // SharedVar requests.
 
 type SharedVarRequest struct {
@@ -137,3 +157,61 @@ type DelSharedVarRequest struct {
 }
 
 type DelSharedVarResponse struct{}
+
+// Secret requests.
+
+type SecretRequest struct {
+	Name string
+}
+
+type SecretResponse struct {
+	Value []byte
+}
+
+type SetSecretRequest struct {
+	Name  string
+	Value []byte
+}
+
+type SetSecretResponse struct{}
+
+type DelSecretRequest struct {
+	Name string
+}
+
+type DelSecretResponse struct{}
+
+// Env trust requests.
+
+type EnvTrustRequest struct {
+	Path string
+}
+
+type EnvTrustResponse struct {
+	Trust store.EnvTrust
+}
+
+type SetEnvTrustRequest struct {
+	Path  string
+	Trust store.EnvTrust
+}
+
+type SetEnvTrustResponse struct{}
+
+// Retention requests.
+
+type RetentionPolicyRequest struct{}
+
+type RetentionPolicyResponse struct {
+	Policy store.RetentionPolicy
+}
+
+type SetRetentionPolicyRequest struct {
+	Policy store.RetentionPolicy
+}
+
+type SetRetentionPolicyResponse struct{}
+
+type CompactRequest struct{}
+
+type CompactResponse struct{}
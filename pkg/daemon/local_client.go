@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"errors"
+
+	"github.com/elves/elvish/pkg/store"
+)
+
+// ErrNoDaemon is returned by the Pid method of a Client created by
+// NewLocalClient.
+var ErrNoDaemon = errors.New("not using daemon")
+
+// NewLocalClient creates a Client that accesses the database file directly
+// instead of talking to a daemon. It is used as a fallback when the daemon
+// cannot be spawned.
+func NewLocalClient(dbPath string) Client {
+	return localClient{store.NewFileStore(dbPath)}
+}
+
+// The embedded DBStore also provides the Close method, which closes the
+// database.
+type localClient struct {
+	store.DBStore
+}
+
+func (localClient) ResetConn() error { return nil }
+
+func (localClient) Pid() (int, error) { return -1, ErrNoDaemon }
+
+func (localClient) SockPath() string { return "" }
+
+func (localClient) Version() (int, error) { return Version, nil }
@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/elves/elvish/pkg/daemon/internal/api"
 	"github.com/elves/elvish/pkg/store"
@@ -30,6 +31,17 @@ func Serve(sockpath, dbpath string) {
 		logger.Printf("serving anyway")
 	}
 
+	stopCompact := make(chan struct{})
+	compactDone := make(chan struct{})
+	if err == nil {
+		go func() {
+			compactPeriodically(st, stopCompact)
+			close(compactDone)
+		}()
+	} else {
+		close(compactDone)
+	}
+
 	quitSignals := make(chan os.Signal)
 	quitChan := make(chan struct{})
 	signal.Notify(quitSignals, syscall.SIGTERM, syscall.SIGINT)
@@ -40,6 +52,8 @@ func Serve(sockpath, dbpath string) {
 		case <-quitChan:
 			logger.Printf("No active client, daemon exit")
 		}
+		close(stopCompact)
+		<-compactDone
 		err := os.Remove(sockpath)
 		if err != nil {
 			logger.Printf("failed to remove socket %s: %v", sockpath, err)
@@ -89,3 +103,29 @@ func Serve(sockpath, dbpath string) {
 
 	logger.Println("exiting")
 }
+
+// Parameters for automatic compaction of the store.
+var (
+	compactDelay    = time.Minute
+	compactInterval = 24 * time.Hour
+)
+
+// Compacts the store after compactDelay, and then every compactInterval, until
+// the stop channel is closed.
+func compactPeriodically(st store.Store, stop <-chan struct{}) {
+	timer := time.NewTimer(compactDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			logger.Println("compacting storage")
+			err := st.Compact()
+			if err != nil {
+				logger.Printf("failed to compact storage: %v", err)
+			}
+			timer.Reset(compactInterval)
+		case <-stop:
+			return
+		}
+	}
+}
@@ -124,6 +124,31 @@ func (s *service) Dirs(req *api.DirsRequest, res *api.DirsResponse) error {
 	return err
 }
 
+func (s *service) PushDir(req *api.PushDirRequest, res *api.PushDirResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.PushDir(req.Dir)
+}
+
+func (s *service) PopDir(req *api.PopDirRequest, res *api.PopDirResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	dir, err := s.store.PopDir()
+	res.Dir = dir
+	return err
+}
+
+func (s *service) DirStack(req *api.DirStackRequest, res *api.DirStackResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	dirs, err := s.store.DirStack()
+	res.Dirs = dirs
+	return err
+}
+
 func (s *service) SharedVar(req *api.SharedVarRequest, res *api.SharedVarResponse) error {
 	if s.err != nil {
 		return s.err
@@ -146,3 +171,65 @@ func (s *service) DelSharedVar(req *api.DelSharedVarRequest, res *api.DelSharedV
 	}
 	return s.store.DelSharedVar(req.Name)
 }
+
+func (s *service) Secret(req *api.SecretRequest, res *api.SecretResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	value, err := s.store.Secret(req.Name)
+	res.Value = value
+	return err
+}
+
+func (s *service) SetSecret(req *api.SetSecretRequest, res *api.SetSecretResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetSecret(req.Name, req.Value)
+}
+
+func (s *service) DelSecret(req *api.DelSecretRequest, res *api.DelSecretResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.DelSecret(req.Name)
+}
+
+func (s *service) EnvTrust(req *api.EnvTrustRequest, res *api.EnvTrustResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	t, err := s.store.EnvTrust(req.Path)
+	res.Trust = t
+	return err
+}
+
+func (s *service) SetEnvTrust(req *api.SetEnvTrustRequest, res *api.SetEnvTrustResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetEnvTrust(req.Path, req.Trust)
+}
+
+func (s *service) RetentionPolicy(req *api.RetentionPolicyRequest, res *api.RetentionPolicyResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	p, err := s.store.RetentionPolicy()
+	res.Policy = p
+	return err
+}
+
+func (s *service) SetRetentionPolicy(req *api.SetRetentionPolicyRequest, res *api.SetRetentionPolicyResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetRetentionPolicy(req.Policy)
+}
+
+func (s *service) Compact(req *api.CompactRequest, res *api.CompactResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.Compact()
+}
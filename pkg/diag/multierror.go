@@ -1,6 +1,9 @@
 package diag
 
-import "bytes"
+import (
+	"bytes"
+	"strings"
+)
 
 // MultiError pack multiple errors into one error.
 type MultiError struct {
@@ -26,6 +29,44 @@ func (es MultiError) Error() string {
 	}
 }
 
+// Show shows the errors, using the Show method of those that implement Shower.
+func (es MultiError) Show(indent string) string {
+	switch len(es.Errors) {
+	case 0:
+		return "no error"
+	case 1:
+		return showError(es.Errors[0], indent)
+	default:
+		sb := new(strings.Builder)
+		sb.WriteString("Multiple errors:")
+		for _, e := range es.Errors {
+			sb.WriteString("\n" + indent + "  ")
+			sb.WriteString(showError(e, indent+"  "))
+		}
+		return sb.String()
+	}
+}
+
+func showError(err error, indent string) string {
+	if shower, ok := err.(Shower); ok {
+		return shower.Show(indent)
+	}
+	return "\033[31;1m" + err.Error() + "\033[m"
+}
+
+// UnpackErrors returns the errors packed in err: the Errors field if err is a
+// MultiError, nil if err is nil, or a slice containing just err otherwise.
+func UnpackErrors(err error) []error {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case MultiError:
+		return err.Errors
+	default:
+		return []error{err}
+	}
+}
+
 // Errors concatenate multiple errors into one. If all errors are nil, it
 // returns nil. If there is one non-nil error, it is returned. Otherwise the
 // return value is a MultiError containing all the non-nil arguments. Arguments
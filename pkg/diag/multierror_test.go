@@ -46,3 +46,31 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestMultiError_Show(t *testing.T) {
+	err1 := &Error{Type: "some error", Message: "bad 1",
+		Context: *NewContext("a", "echo x", Ranging{From: 5, To: 6})}
+	err2 := errors.New("bad 2")
+	show := Errors(err1, err2).(Shower).Show("")
+	want := "Multiple errors:\n  " + err1.Show("  ") + "\n  \033[31;1mbad 2\033[m"
+	if show != want {
+		t.Errorf("got %q, want %q", show, want)
+	}
+}
+
+var unpackErrorsTests = []struct {
+	err  error
+	want int
+}{
+	{nil, 0},
+	{errors.New("error"), 1},
+	{Errors(errors.New("error 1"), errors.New("error 2")), 2},
+}
+
+func TestUnpackErrors(t *testing.T) {
+	for _, test := range unpackErrorsTests {
+		if n := len(UnpackErrors(test.err)); n != test.want {
+			t.Errorf("UnpackErrors(%v) returns %d errors, want %d", test.err, n, test.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package edit
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+//elvdoc:var expand-aliases
+//
+// Whether to expand aliases in the code buffer when Space is pressed,
+// defaulting to `$false`.
+//
+// When enabled, pressing Space right after the name of an alias that is the
+// head of a command replaces the name with the body of the alias, so that the
+// command that will be run is visible and can be edited. Example:
+//
+// ```elvish-transcript
+// ~> alias gst 'git status'
+// ~> edit:expand-aliases = $true
+// ~> gst # typing Space after "gst" turns it into "git status "
+// ```
+//
+// Aliases are defined with [`alias`](builtin.html#alias). Quoted names are not
+// expanded.
+
+func initAliasExpansion(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	enabled := newBoolVar(false)
+	overlay := appSpec.OverlayHandler
+	appSpec.OverlayHandler = cli.FuncHandler(func(event term.Event) bool {
+		if event == term.K(' ') && enabled.GetRaw().(bool) {
+			ed.app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+				expandAliasAtDot(ev, &s.Buffer)
+			})
+		}
+		return overlay.Handle(event)
+	})
+	nb.Add("expand-aliases", enabled)
+}
+
+// Replaces the name of an alias that ends at the dot and is the head of a
+// command with the body of the alias.
+func expandAliasAtDot(ev *eval.Evaler, c *cli.CodeBuffer) {
+	if r, _ := utf8.DecodeRuneInString(c.Content[c.Dot:]); c.Dot < len(c.Content) && !unicode.IsSpace(r) {
+		// The dot is in the middle of a word.
+		return
+	}
+	tree, _ := parse.Parse(parse.Source{Name: "[interactive]", Code: c.Content[:c.Dot]})
+	head := findHeadAt(tree.Root, c.Dot)
+	if head == nil || len(head.Indexings) != 1 ||
+		len(head.Indexings[0].Indicies) > 0 || head.Indexings[0].Head.Type != parse.Bareword {
+		return
+	}
+	body, ok := ev.Alias(head.Indexings[0].Head.Value)
+	if !ok {
+		return
+	}
+	from := head.Range().From
+	*c = cli.CodeBuffer{
+		Content: c.Content[:from] + body + c.Content[c.Dot:],
+		Dot:     from + len(body),
+	}
+}
+
+// Finds the innermost command head that ends at the given position.
+func findHeadAt(n parse.Node, p int) *parse.Compound {
+	for _, ch := range parse.Children(n) {
+		if head := findHeadAt(ch, p); head != nil {
+			return head
+		}
+	}
+	if form, ok := n.(*parse.Form); ok && form.Head != nil && form.Head.Range().To == p {
+		return form.Head
+	}
+	return nil
+}
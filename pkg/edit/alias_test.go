@@ -0,0 +1,27 @@
+package edit
+
+import "testing"
+
+func TestExpandAliases(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `alias gst 'git status'`, `edit:expand-aliases = $true`)
+	// Only aliases at command heads are expanded.
+	feedInput(f.TTYCtrl, "gst gst | 'gst' x; { gst }\n")
+	want := "git status gst | 'gst' x; { git status }"
+	if code := <-f.codeCh; code != want {
+		t.Errorf("got code %q, want %q", code, want)
+	}
+}
+
+func TestExpandAliases_Disabled(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `alias gst 'git status'`)
+	feedInput(f.TTYCtrl, "gst \n")
+	if code := <-f.codeCh; code != "gst " {
+		t.Errorf("got code %q, want %q", code, "gst ")
+	}
+}
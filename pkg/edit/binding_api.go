@@ -0,0 +1,164 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+//elvdoc:fn bind
+//
+// ```elvish
+// edit:bind $mode $key $fn
+// ```
+//
+// Binds `$key` to `$fn` in the binding table of `$mode`, like
+// `edit:insert:binding[$key] = $fn` when `$mode` is `insert`. Both the mode and
+// the key are checked when the function is called, so a typo is reported with
+// the place of the call instead of resulting in a binding that never works.
+//
+// ```elvish-transcript
+// ~> edit:bind insert Ctrl-X { edit:insert-at-dot x }
+// ~> edit:bind insert Ctrl-XX { edit:insert-at-dot x }
+// Exception: bad key Ctrl-XX: bad key: XX
+// [tty 2], line 1: edit:bind insert Ctrl-XX { edit:insert-at-dot x }
+// ```
+//
+// @cf edit:binding-conflicts
+
+//elvdoc:fn binding-conflicts
+//
+// ```elvish
+// edit:binding-conflicts
+// ```
+//
+// Outputs a map for each key that is bound in more than one binding table used
+// by the same mode. The listing modes like `histlist` use their own binding
+// tables first, and fall back to `$edit:listing:binding`. The maps have the
+// following keys:
+//
+// -   `mode`: The name of the mode.
+//
+// -   `key`: The key, like `Ctrl-D`.
+//
+// -   `winner`: The name of the binding table whose binding is used.
+//
+// -   `shadowed`: A list of the names of the other binding tables binding the
+//     key.
+//
+// ```elvish-transcript
+// ~> edit:listing:binding[Ctrl-D] = { }
+// ~> edit:binding-conflicts
+// ▶ [&key=Ctrl-D &mode=histlist &shadowed=[listing] &winner=histlist]
+// ```
+//
+// Bindings of the insert mode are not used in other modes, so they never
+// conflict with the bindings of other modes.
+
+var errNoSuchMode = errors.New("no such mode")
+
+// A binding of a mode that consists of multiple binding tables; keys are looked
+// up in the tables in order.
+type layeredBinding struct {
+	mode   string
+	layers []bindingLayer
+}
+
+type bindingLayer struct {
+	name string
+	v    vars.PtrVar
+}
+
+func initBindingAPI(ed *Editor, nb eval.NsBuilder) {
+	nb.AddGoFns("<edit>", map[string]interface{}{
+		"bind": func(mode string, key interface{}, fn eval.Callable) error {
+			return bind(ed.ns, mode, key, fn)
+		},
+		"binding-conflicts": func(fm *eval.Frame) {
+			out := fm.OutputChan()
+			for _, c := range bindingConflicts(ed.layeredBindings) {
+				out <- c
+			}
+		},
+	})
+}
+
+// Creates the binding of a listing mode, which falls back to the common
+// binding of listing modes, and records the layers for binding-conflicts.
+func newListingBinding(ed *Editor, ev *eval.Evaler, mode string, bindingVar, commonBindingVar vars.PtrVar) cli.Handler {
+	ed.layeredBindings = append(ed.layeredBindings, layeredBinding{mode,
+		[]bindingLayer{{mode, bindingVar}, {"listing", commonBindingVar}}})
+	return newMapBinding(ed, ev, bindingVar, commonBindingVar)
+}
+
+func bind(ns *eval.Ns, mode string, k interface{}, fn eval.Callable) error {
+	key, err := toKey(k)
+	if err != nil {
+		return fmt.Errorf("bad key %s: %v", parse.Quote(vals.ToString(k)), err)
+	}
+	v, ok := bindingVarOf(ns, mode)
+	if !ok {
+		return fmt.Errorf("%v: %s", errNoSuchMode, parse.Quote(mode))
+	}
+	binding, _ := v.Get().(BindingMap).Assoc(key, fn)
+	return v.Set(binding)
+}
+
+// Finds the $edit:<mode>:binding variable.
+func bindingVarOf(ns *eval.Ns, mode string) (vars.Var, bool) {
+	if ns == nil {
+		return nil, false
+	}
+	modeNs, ok := ns.Index(mode + eval.NsSuffix)
+	if !ok {
+		return nil, false
+	}
+	v, ok := modeNs.(*eval.Ns).IndexString("binding")
+	if !ok {
+		return nil, false
+	}
+	if _, isBinding := v.Get().(BindingMap); !isBinding {
+		return nil, false
+	}
+	return v, true
+}
+
+// Finds keys bound in more than one layer of the layered bindings. The result
+// is sorted by mode and then key.
+func bindingConflicts(bindings []layeredBinding) []vals.Map {
+	sorted := append([]layeredBinding(nil), bindings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].mode < sorted[j].mode })
+	var conflicts []vals.Map
+	for _, b := range sorted {
+		var keys ui.Keys
+		// The names of the layers binding each key, in order.
+		bound := map[ui.Key][]interface{}{}
+		for _, layer := range b.layers {
+			m := layer.v.Get().(BindingMap)
+			for it := m.Iterator(); it.HasElem(); it.Next() {
+				k, _ := it.Elem()
+				key := k.(ui.Key)
+				if len(bound[key]) == 0 {
+					keys = append(keys, key)
+				}
+				bound[key] = append(bound[key], layer.name)
+			}
+		}
+		sort.Sort(keys)
+		for _, key := range keys {
+			if names := bound[key]; len(names) > 1 {
+				conflicts = append(conflicts, vals.MakeMap(
+					"mode", b.mode, "key", key.String(),
+					"winner", names[0], "shadowed", vals.MakeList(names[1:]...)))
+			}
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,58 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestBind(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`edit:bind insert Ctrl-X { edit:insert-at-dot x }`,
+		`bad-key = ?(edit:bind insert Ctrl-XX { })`,
+		`bad-mode = ?(edit:bind inzert Ctrl-X { })`)
+	f.TTYCtrl.Inject(term.K('X', ui.Ctrl))
+	f.TestTTY(t, "~> x", Styles,
+		"   !", term.DotHere)
+	for _, name := range []string{"bad-key", "bad-mode"} {
+		if getGlobal(f.Evaler, name) == nil {
+			t.Errorf("$%s is nil, want an exception", name)
+		}
+	}
+}
+
+func TestBindingConflicts(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`edit:listing:binding[Ctrl-D] = { }`,
+		`edit:location:binding[Up] = { }`,
+		`conflicts = [(edit:binding-conflicts)]`)
+	testGlobal(t, f.Evaler, "conflicts", vals.MakeList(
+		vals.MakeMap("mode", "histlist", "key", "Ctrl-D",
+			"winner", "histlist", "shadowed", vals.MakeList("listing")),
+		vals.MakeMap("mode", "location", "key", "Up",
+			"winner", "location", "shadowed", vals.MakeList("listing")),
+	))
+}
+
+func TestBindingMap_Repr(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`edit:-instant:binding = (edit:binding-table [
+		   &Ctrl-A=$edit:move-dot-sol~
+		   &Ctrl-B={ edit:insert-at-dot x }
+		   &Ctrl-C=$edit:completion:start~])`,
+		`repr = (repr $edit:-instant:binding)`)
+	testGlobal(t, f.Evaler, "repr",
+		"[&Ctrl-A=$edit:move-dot-sol~ &Ctrl-B={ edit:insert-at-dot x } "+
+			"&Ctrl-C=$edit:completion:start~]")
+}
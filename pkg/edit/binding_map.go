@@ -2,6 +2,7 @@ package edit
 
 import (
 	"errors"
+	"regexp"
 	"sort"
 
 	"github.com/elves/elvish/pkg/eval"
@@ -22,7 +23,9 @@ type BindingMap struct {
 var EmptyBindingMap = BindingMap{vals.EmptyMap}
 
 // Repr returns the representation of the binding table as if it were an
-// ordinary map keyed by strings.
+// ordinary map keyed by strings. Builtin functions of the editor are shown as
+// the variables holding them, like $edit:move-dot-left~, and user-defined
+// functions as their definitions.
 func (bt BindingMap) Repr(indent int) string {
 	var keys ui.Keys
 	for it := bt.Map.Iterator(); it.HasElem(); it.Next() {
@@ -35,12 +38,27 @@ func (bt BindingMap) Repr(indent int) string {
 
 	for _, k := range keys {
 		v, _ := bt.Map.Index(k)
-		builder.WritePair(parse.Quote(k.String()), indent+2, vals.Repr(v, indent+2))
+		builder.WritePair(parse.Quote(k.String()), indent+2, bindingFnRepr(v, indent+2))
 	}
 
 	return builder.String()
 }
 
+// Matches the representation of builtin functions of the editor, like
+// "<builtin <edit>move-dot-left>" or "<builtin <edit:completion>:start>".
+var editBuiltinRepr = regexp.MustCompile(`^<builtin <(edit(?::[^>]*)?)>:?(.*)>$`)
+
+func bindingFnRepr(fn interface{}, indent int) string {
+	repr := vals.Repr(fn, indent)
+	if m := editBuiltinRepr.FindStringSubmatch(repr); m != nil {
+		return "$" + m[1] + ":" + m[2] + eval.FnSuffix
+	}
+	if def, err := vals.Index(fn, "def"); err == nil {
+		return vals.ToString(def)
+	}
+	return repr
+}
+
 // Index converts the index to ui.Key and uses the Index of the inner Map.
 func (bt BindingMap) Index(index interface{}) (interface{}, error) {
 	key, err := toKey(index)
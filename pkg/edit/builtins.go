@@ -8,6 +8,7 @@ import (
 
 	"github.com/elves/elvish/pkg/cli"
 	"github.com/elves/elvish/pkg/cli/addons/stub"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/cli/term"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/parse"
@@ -50,7 +51,7 @@ func closeListing(app cli.App) {
 // Adds a notification saying "End of history".
 
 func endOfHistory(app cli.App) {
-	app.Notify("End of history")
+	app.Notify(msg.Get("note-end-of-history"))
 }
 
 type redrawOpts struct{ Full bool }
@@ -84,7 +85,7 @@ func insertRaw(app cli.App, tty cli.TTY) {
 				return false
 			}
 		}),
-		Name:  " RAW ",
+		Name:  " " + msg.Get("mode-raw") + " ",
 		Focus: false,
 	})
 }
@@ -135,13 +136,83 @@ func toKey(v interface{}) (ui.Key, error) {
 
 //elvdoc:fn smart-enter
 //
-// Inserts a literal newline if the current code is not syntactically complete
-// Elvish code. Accepts the current line otherwise.
+// Inserts a literal newline if the current code is not ready to be accepted.
+// Accepts the current line otherwise.
+//
+// Whether the code is ready to be accepted is decided by calling
+// `$edit:smart-enter-predicate`.
+
+//elvdoc:var smart-enter-predicate
+//
+// A function that `edit:smart-enter` calls with the current code to decide
+// whether to accept it. It should output `$true` to accept the code, or
+// `$false` to insert a newline instead. Defaults to
+// `$edit:is-syntax-complete~`.
+//
+// If the function throws an exception or outputs anything else, the exception
+// or an error is shown as a notification and the default decision is used.
+//
+// For example, the following requires pressing <kbd>Enter</kbd> twice to
+// accept code that spans multiple lines:
+//
+// ```elvish
+// use str
+// edit:smart-enter-predicate = [code]{
+//   and (edit:is-syntax-complete $code) ^
+//       (or (not (str:contains $code "\n")) (str:has-suffix $code "\n"))
+// }
+// ```
+//
+// To always insert a newline when pressing <kbd>Alt-Enter</kbd>:
+//
+// ```elvish
+// edit:insert:binding[Alt-Enter] = { edit:insert-at-dot "\n" }
+// ```
 
-func smartEnter(app cli.App) {
+//elvdoc:fn is-syntax-complete
+//
+// ```elvish
+// edit:is-syntax-complete $code
+// ```
+//
+// Outputs whether `$code` is syntactically complete Elvish code, in other
+// words, whether it has no parse errors caused by reaching the end of the code
+// prematurely. This is the default value of `$edit:smart-enter-predicate`.
+//
+// ```elvish-transcript
+// ~> edit:is-syntax-complete 'put ['
+// ▶ $false
+// ~> edit:is-syntax-complete 'put []'
+// ▶ $true
+// ```
+
+var errSmartEnterPredicateOutput = errors.New(
+	"$edit:smart-enter-predicate should output a single boolean")
+
+func initSmartEnter(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	isSyntaxCompleteFn := eval.NewGoFn("<edit>is-syntax-complete", isSyntaxComplete)
+	predicate := newFnVar(isSyntaxCompleteFn)
+	nb["smart-enter-predicate"] = predicate
+	nb.AddFn("is-syntax-complete", isSyntaxCompleteFn)
+	nb.AddGoFns("<edit>", map[string]interface{}{
+		"smart-enter": func() {
+			smartEnter(ed.app, func(code string) bool {
+				accept, err := callSmartEnterPredicate(
+					ed, ev, predicate.Get().(eval.Callable), code)
+				if err != nil {
+					ed.notifyError("smart-enter", err)
+					return isSyntaxComplete(code)
+				}
+				return accept
+			})
+		},
+	})
+}
+
+func smartEnter(app cli.App, accept func(code string) bool) {
 	// TODO(xiaq): Fix the race condition.
 	buf := cli.GetCodeBuffer(app)
-	if isSyntaxComplete(buf.Content) {
+	if accept(buf.Content) {
 		app.CommitCode()
 	} else {
 		app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
@@ -150,6 +221,24 @@ func smartEnter(app cli.App) {
 	}
 }
 
+// Calls the predicate and returns its output. The standard error of the
+// predicate is shown as notifications.
+func callSmartEnterPredicate(nt notifier, ev *eval.Evaler, fn eval.Callable, code string) (bool, error) {
+	out, err := callForOutputs(nt, ev, fn,
+		eval.CallCfg{Args: []interface{}{code}, From: "[smart-enter]"})
+	if err != nil {
+		return false, err
+	}
+	if len(out) != 1 {
+		return false, errSmartEnterPredicateOutput
+	}
+	accept, ok := out[0].(bool)
+	if !ok {
+		return false, errSmartEnterPredicateOutput
+	}
+	return accept, nil
+}
+
 func isSyntaxComplete(code string) bool {
 	_, err := parse.Parse(parse.Source{Code: code})
 	if err != nil {
@@ -193,7 +282,6 @@ func initMiscBuiltins(app cli.App, nb eval.NsBuilder) {
 		"redraw":         func(opts redrawOpts) { redraw(app, opts) },
 		"return-line":    app.CommitCode,
 		"return-eof":     app.CommitEOF,
-		"smart-enter":    func() { smartEnter(app) },
 		"wordify":        wordify,
 	})
 }
@@ -169,6 +169,53 @@ func TestSmartEnter_AcceptsCodeWhenWholeBufferIsComplete(t *testing.T) {
 	}
 }
 
+func TestSmartEnter_UsesPredicate(t *testing.T) {
+	f := setup(rc(`edit:smart-enter-predicate = [code]{ eq $code 'put []' }`))
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "put", Dot: 3})
+	evals(f.Evaler, `edit:smart-enter`)
+	wantBuf := cli.CodeBuffer{Content: "put\n", Dot: 4}
+	if buf := cli.GetCodeBuffer(f.Editor.app); buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "put []", Dot: 6})
+	evals(f.Evaler, `edit:smart-enter`)
+	wantCode := "put []"
+	if code, _ := f.Wait(); code != wantCode {
+		t.Errorf("got return code %q, want %q", code, wantCode)
+	}
+}
+
+func TestSmartEnter_FallsBackWhenPredicateFails(t *testing.T) {
+	f := setup(rc(`edit:smart-enter-predicate = [code]{ put foo }`))
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "put [", Dot: 5})
+	evals(f.Evaler, `edit:smart-enter`)
+	wantBuf := cli.CodeBuffer{Content: "put [\n", Dot: 6}
+	if buf := cli.GetCodeBuffer(f.Editor.app); buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+	f.TestTTYNotes(t, "[smart-enter error] "+errSmartEnterPredicateOutput.Error())
+}
+
+func TestIsSyntaxComplete(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`incomplete = (edit:is-syntax-complete 'put [')`,
+		`complete = (edit:is-syntax-complete 'put []')`,
+		`default = (eq $edit:smart-enter-predicate $edit:is-syntax-complete~)`)
+	testGlobals(t, f.Evaler, map[string]interface{}{
+		"incomplete": false,
+		"complete":   true,
+		"default":    true,
+	})
+}
+
 func TestWordify(t *testing.T) {
 	f := setup()
 	defer f.Cleanup()
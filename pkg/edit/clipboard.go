@@ -0,0 +1,181 @@
+package edit
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+)
+
+//elvdoc:fn copy-to-clipboard
+//
+// ```elvish
+// edit:copy-to-clipboard $text
+// ```
+//
+// Copies `$text` to the system clipboard. This uses the first of the following
+// commands that is found and succeeds: `pbcopy` (macOS), `wl-copy` (Wayland),
+// `xclip` and `xsel` (X11), and `clip.exe` (Windows and WSL).
+
+//elvdoc:fn start-selection
+//
+// ```elvish
+// edit:start-selection
+// ```
+//
+// Starts a selection in the code buffer by putting the mark at the dot. The
+// text between the mark and the dot is selected, and moving the dot changes
+// the selection. The selection is cleared when text is typed or pasted.
+//
+// @cf edit:clear-selection edit:copy-selection
+
+//elvdoc:fn clear-selection
+//
+// ```elvish
+// edit:clear-selection
+// ```
+//
+// Clears the selection in the code buffer, without changing the text.
+
+//elvdoc:fn copy-selection
+//
+// ```elvish
+// edit:copy-selection
+// ```
+//
+// Copies the selected text to the system clipboard and clears the selection. It
+// is an error if no text is selected.
+//
+// The text is copied with the OSC 52 escape sequence, which works over SSH, in
+// terminals that are likely to support it. In other terminals, the text is
+// copied like [`edit:copy-to-clipboard`](#editcopy-to-clipboard).
+
+//elvdoc:fn paste-from-clipboard
+//
+// ```elvish
+// edit:paste-from-clipboard
+// ```
+//
+// Inserts the content of the system clipboard at the dot, replacing the
+// selection if there is one. This uses the first of the following commands
+// that is found and succeeds: `pbpaste` (macOS), `wl-paste` (Wayland), `xclip`
+// and `xsel` (X11), and `powershell.exe` (Windows and WSL).
+//
+// Reading the clipboard with the OSC 52 escape sequence is not supported, since
+// most terminals disable it for security reasons.
+
+var (
+	errNoClipboardCommand = errors.New("no clipboard command found")
+	errNoSelection        = errors.New("no text selected")
+)
+
+// Commands that copy their standard input to the system clipboard, tried in
+// order.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// Commands that write the content of the system clipboard to their standard
+// output, tried in order.
+var pasteCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+func initClipboard(app cli.App, tty cli.TTY, nb eval.NsBuilder) {
+	nb.AddGoFns("<edit>", map[string]interface{}{
+		"copy-to-clipboard": copyToClipboard,
+		"start-selection": func() {
+			app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+				s.Selection = cli.Selection{Active: true, Mark: s.Buffer.Dot}
+			})
+		},
+		"clear-selection": func() {
+			app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+				s.Selection = cli.Selection{}
+			})
+		},
+		"copy-selection": func() error {
+			return copySelection(app, tty)
+		},
+		"paste-from-clipboard": func() error {
+			return pasteFromClipboard(app)
+		},
+	})
+}
+
+func copySelection(app cli.App, tty cli.TTY) error {
+	var text string
+	app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+		text = s.SelectedText()
+		s.Selection = cli.Selection{}
+	})
+	if text == "" {
+		return errNoSelection
+	}
+	if tty.SetClipboard(text) {
+		return nil
+	}
+	return copyToClipboard(text)
+}
+
+func pasteFromClipboard(app cli.App) error {
+	text, err := readClipboard()
+	if err != nil {
+		return err
+	}
+	app.CodeArea().MutateState(func(s *cli.CodeAreaState) {
+		if from, to, ok := s.SelectedRange(); ok {
+			s.Buffer = cli.CodeBuffer{
+				Content: s.Buffer.Content[:from] + s.Buffer.Content[to:], Dot: from}
+		}
+		s.Selection = cli.Selection{}
+		s.Buffer.InsertAtDot(text)
+	})
+	return nil
+}
+
+func copyToClipboard(text string) error {
+	err := errNoClipboardCommand
+	for _, args := range clipboardCommands {
+		path, lookErr := exec.LookPath(args[0])
+		if lookErr != nil {
+			continue
+		}
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err = cmd.Run(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func readClipboard() (string, error) {
+	err := errNoClipboardCommand
+	for _, args := range pasteCommands {
+		path, lookErr := exec.LookPath(args[0])
+		if lookErr != nil {
+			continue
+		}
+		var out []byte
+		if out, err = exec.Command(path, args[1:]...).Output(); err == nil {
+			text := string(out)
+			if args[0] == "powershell.exe" {
+				// Get-Clipboard outputs the content with a trailing CRLF.
+				text = strings.TrimSuffix(text, "\r\n")
+			}
+			return text, nil
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,94 @@
+package edit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+)
+
+func TestCopyToClipboard_NoCommand(t *testing.T) {
+	restore := setClipboardCommands([][]string{{"elvish-test-no-such-command"}})
+	defer restore()
+
+	err := copyToClipboard("foo")
+	if err != errNoClipboardCommand {
+		t.Errorf("got error %v, want %v", err, errNoClipboardCommand)
+	}
+}
+
+func TestCopySelection(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 5})
+	evals(f.Evaler,
+		`edit:start-selection`,
+		`edit:move-dot-eol`,
+		`edit:copy-selection`)
+	if clips := f.TTYCtrl.Clipboards(); !reflect.DeepEqual(clips, []string{"foo"}) {
+		t.Errorf("got clipboard %q, want %q", clips, []string{"foo"})
+	}
+	if s := f.Editor.app.CodeArea().CopyState(); s.Selection.Active {
+		t.Errorf("selection not cleared after copying")
+	}
+}
+
+func TestCopySelection_NoSelection(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 5})
+	evals(f.Evaler,
+		`exc1 = ?(edit:copy-selection)`,
+		`edit:start-selection`,
+		`exc2 = ?(edit:copy-selection)`)
+	for _, name := range []string{"exc1", "exc2"} {
+		if exc := getGlobal(f.Evaler, name); exc == eval.OK {
+			t.Errorf("copying an empty selection didn't throw")
+		}
+	}
+	if clips := f.TTYCtrl.Clipboards(); len(clips) != 0 {
+		t.Errorf("got clipboard %q, want none", clips)
+	}
+}
+
+func TestClearSelection(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 5})
+	evals(f.Evaler, `edit:start-selection`, `edit:move-dot-eol`)
+	if _, _, ok := f.Editor.app.CodeArea().CopyState().SelectedRange(); !ok {
+		t.Errorf("no selection after moving the dot")
+	}
+	evals(f.Evaler, `edit:clear-selection`)
+	if s := f.Editor.app.CodeArea().CopyState(); s.Selection.Active {
+		t.Errorf("selection not cleared")
+	}
+}
+
+func TestPasteFromClipboard_NoCommand(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	restore := setPasteCommands([][]string{{"elvish-test-no-such-command"}})
+	defer restore()
+
+	evals(f.Evaler, `exc = ?(edit:paste-from-clipboard)`)
+	if exc := getGlobal(f.Evaler, "exc"); exc == eval.OK {
+		t.Errorf("pasting without clipboard commands didn't throw")
+	}
+}
+
+func setClipboardCommands(cmds [][]string) func() {
+	saved := clipboardCommands
+	clipboardCommands = cmds
+	return func() { clipboardCommands = saved }
+}
+
+func setPasteCommands(cmds [][]string) func() {
+	saved := pasteCommands
+	pasteCommands = cmds
+	return func() { pasteCommands = saved }
+}
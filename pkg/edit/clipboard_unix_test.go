@@ -0,0 +1,85 @@
+// +build !windows,!plan9
+
+package edit
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	restore := setClipboardCommands([][]string{
+		{"elvish-test-no-such-command"}, {"sh", "-c", "cat > clipboard"}})
+	defer restore()
+
+	evals(f.Evaler, `edit:copy-to-clipboard foo`)
+	if data, _ := ioutil.ReadFile("clipboard"); string(data) != "foo" {
+		t.Errorf("got clipboard %q, want %q", data, "foo")
+	}
+}
+
+func TestCopySelection_FallsBackToCommands(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	f.TTYCtrl.SetDumb(true)
+	restore := setClipboardCommands([][]string{{"sh", "-c", "cat > clipboard"}})
+	defer restore()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 0})
+	evals(f.Evaler, `edit:start-selection`, `edit:move-dot-right-word`,
+		`edit:copy-selection`)
+	if data, _ := ioutil.ReadFile("clipboard"); string(data) != "echo " {
+		t.Errorf("got clipboard %q, want %q", data, "echo ")
+	}
+}
+
+func TestPasteFromClipboard(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	restore := setPasteCommands([][]string{
+		{"elvish-test-no-such-command"}, {"sh", "-c", "printf bar"}})
+	defer restore()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 5})
+	evals(f.Evaler, `edit:paste-from-clipboard`)
+	wantBuf := cli.CodeBuffer{Content: "echo barfoo", Dot: 8}
+	if buf := cli.GetCodeBuffer(f.Editor.app); buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestPasteFromClipboard_ReplacesSelection(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	restore := setPasteCommands([][]string{{"sh", "-c", "printf bar"}})
+	defer restore()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo foo", Dot: 5})
+	evals(f.Evaler, `edit:start-selection`, `edit:move-dot-eol`,
+		`edit:paste-from-clipboard`)
+	wantBuf := cli.CodeBuffer{Content: "echo bar", Dot: 8}
+	if buf := cli.GetCodeBuffer(f.Editor.app); buf != wantBuf {
+		t.Errorf("got code buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestErrorView_Copy(t *testing.T) {
+	f := setup(rc(`edit:insert:binding[x] = { fail ERROR }`))
+	defer f.Cleanup()
+	restore := setClipboardCommands([][]string{{"sh", "-c", "cat > clipboard"}})
+	defer restore()
+
+	f.TTYCtrl.Inject(term.K('x'), term.K('Y', ui.Ctrl))
+	f.TestTTYNotes(t, "error copied to clipboard")
+	data, _ := ioutil.ReadFile("clipboard")
+	if want := "Exception: ERROR\n[test], line 1:"; !strings.HasPrefix(string(data), want) {
+		t.Errorf("got clipboard %q, want prefix %q", data, want)
+	}
+}
@@ -2,6 +2,7 @@ package edit
 
 import (
 	"github.com/elves/elvish/pkg/cli/addons/stub"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/eval"
 )
 
@@ -19,7 +20,7 @@ func initCommandAPI(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 			"start": func() {
 				stub.Start(ed.app, stub.Config{
 					Binding: binding,
-					Name:    " COMMAND ",
+					Name:    " " + msg.Get("mode-command") + " ",
 					Focus:   false,
 				})
 			},
@@ -0,0 +1,182 @@
+package edit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/ui"
+	"github.com/elves/elvish/pkg/wcwidth"
+)
+
+//elvdoc:var command-duration
+//
+// The duration of the last command entered in the editor, in seconds. This
+// variable is read-only, and is 0 before the first command has finished.
+//
+// This can be used in prompts, for example:
+//
+// ```elvish
+// edit:rprompt = { printf '%.1fs' $edit:command-duration }
+// ```
+
+//elvdoc:var command-badge:enabled
+//
+// A boolean that controls whether a badge is shown after each command entered
+// in the editor has finished. Defaults to `$false`.
+//
+// The badge is drawn on the right side of the first line of the prompt the
+// command was entered in, like some themes of other shells do. When that line
+// can't be found, because the terminal doesn't report the cursor position or
+// the output of the command has scrolled the terminal, the badge is shown on
+// its own line below the output instead. The badge contains a check mark if
+// the command has finished successfully, or a cross and the exit status of the
+// external command if it has failed, followed by the duration of the command.
+// Unlike including the same information in `$edit:rprompt`, the badge stays
+// with the command in the scrollback.
+
+// Keeps the result of the last command.
+type commandBadge struct {
+	tty     cli.TTY
+	enabled vars.PtrVar
+
+	// The number of commands reported, and the duration and the error of the
+	// last one.
+	durationMutex sync.RWMutex
+	n             int
+	duration      time.Duration
+	err           error
+
+	// The number of lines taken by the prompt and the code of the last
+	// accepted command, and the cursor position after accepting it.
+	acceptMutex sync.Mutex
+	promptLines int
+	acceptPos   term.Pos
+	acceptPosOK bool
+}
+
+func initCommandBadge(appSpec *cli.AppSpec, ed *Editor, tty cli.TTY, nb eval.NsBuilder) {
+	cb := &commandBadge{tty: tty, enabled: newBoolVar(false)}
+	ed.commandBadge = cb
+	appSpec.AfterFinalRedraw = func(lines int) {
+		cb.acceptMutex.Lock()
+		defer cb.acceptMutex.Unlock()
+		cb.promptLines = lines
+	}
+	nb["command-duration"] = vars.FromGet(func() interface{} {
+		cb.durationMutex.RLock()
+		defer cb.durationMutex.RUnlock()
+		return cb.duration.Seconds()
+	})
+	nb.AddNs("command-badge", eval.NsBuilder{"enabled": cb.enabled}.Ns())
+}
+
+// Records the cursor position after a command is accepted, if the badge is
+// enabled.
+func (cb *commandBadge) afterReadCode() {
+	if !cb.enabled.GetRaw().(bool) {
+		return
+	}
+	pos, ok := cb.tty.CursorPosition()
+	cb.acceptMutex.Lock()
+	defer cb.acceptMutex.Unlock()
+	cb.acceptPos, cb.acceptPosOK = pos, ok
+}
+
+// Returns how many lines above the cursor the first line of the prompt of the
+// last command is, or false if that can't be found reliably.
+func (cb *commandBadge) linesToPrompt() (int, bool) {
+	cb.acceptMutex.Lock()
+	acceptPos, acceptPosOK, promptLines := cb.acceptPos, cb.acceptPosOK, cb.promptLines
+	cb.acceptPosOK = false
+	cb.acceptMutex.Unlock()
+	if !acceptPosOK || promptLines == 0 || acceptPos.Line < promptLines {
+		return 0, false
+	}
+	pos, ok := cb.tty.CursorPosition()
+	height, _ := cb.tty.Size()
+	// If the cursor is above where it was when the command was accepted, the
+	// command has moved it, for example by clearing the screen. If it is on
+	// the last line, the output of the command may have scrolled the
+	// terminal, and the prompt may have moved.
+	if !ok || pos.Line < acceptPos.Line || pos.Line >= height-1 {
+		return 0, false
+	}
+	return pos.Line - (acceptPos.Line - promptLines), true
+}
+
+// ReportCommand records the duration and the error of a command entered in the
+// editor. It should be called after the command has finished, and before the
+// next call to ReadCode.
+//
+// If $edit:command-badge:enabled is true, it also shows the badge for the
+// command on the terminal, on the first line of the prompt of the command if
+// possible.
+func (ed *Editor) ReportCommand(duration time.Duration, err error) {
+	cb := ed.commandBadge
+	cb.durationMutex.Lock()
+	cb.n++
+	cb.duration, cb.err = duration, err
+	cb.durationMutex.Unlock()
+	if !cb.enabled.GetRaw().(bool) {
+		return
+	}
+
+	_, width := cb.tty.Size()
+	badge := commandBadgeText(duration, err)
+	if up, ok := cb.linesToPrompt(); ok {
+		w := wcwidth.Of(plainText(badge))
+		if w < width {
+			cells := term.NewBufferBuilder(w).WriteStyled(badge).Buffer().Lines[0]
+			cb.tty.DrawAbove(up, width-w, cells)
+			return
+		}
+	}
+	bb := term.NewBufferBuilder(width)
+	if w := wcwidth.Of(plainText(badge)); w < width {
+		bb.WriteSpaces(width - w)
+	}
+	buf := bb.WriteStyled(badge).Buffer()
+	// Position the cursor on a new line, like the final redraw of the editor.
+	buf.Extend(term.NewBuffer(width), true)
+	cb.tty.UpdateBuffer(nil, buf, false)
+	cb.tty.ResetBuffer()
+}
+
+// LastCommand returns the number of commands reported with ReportCommand, and
+// the duration and the error of the last one.
+func (ed *Editor) LastCommand() (n int, duration time.Duration, err error) {
+	cb := ed.commandBadge
+	cb.durationMutex.RLock()
+	defer cb.durationMutex.RUnlock()
+	return cb.n, cb.duration, cb.err
+}
+
+func commandBadgeText(duration time.Duration, err error) ui.Text {
+	var status ui.Text
+	if err == nil {
+		status = ui.T("✔", ui.FgGreen)
+	} else if exit, ok := eval.Reason(err).(eval.ExternalCmdExit); ok && exit.Exited() {
+		status = ui.T("✘ "+strconv.Itoa(exit.ExitStatus()), ui.FgRed)
+	} else {
+		status = ui.T("✘", ui.FgRed)
+	}
+	return ui.Concat(status, ui.T(" "+formatDuration(duration)))
+}
+
+// Formats a duration with a precision suitable for showing to the user, like
+// "35ms", "1.25s" or "3m2s".
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(10 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
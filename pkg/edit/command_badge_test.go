@@ -0,0 +1,109 @@
+package edit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/cli/clitest"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/tt"
+)
+
+func TestCommandBadge_Disabled(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+
+	f.Editor.ReportCommand(1500*time.Millisecond, nil)
+	evals(f.Evaler, `d = $edit:command-duration`)
+	testGlobal(t, f.Evaler, "d", 1.5)
+	if buf := f.TTYCtrl.LastBuffer(); buf != nil {
+		t.Errorf("got buffer %v, want nil", buf)
+	}
+}
+
+func TestCommandBadge_Enabled(t *testing.T) {
+	f := setup(rc(`edit:command-badge:enabled = $true`))
+	defer f.Cleanup()
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+
+	f.Editor.ReportCommand(1500*time.Millisecond, nil)
+	f.TestTTY(t,
+		"                                            ✔ 1.5s", Styles,
+		"                                            v     ", "\n",
+		term.DotHere)
+
+	f.Editor.ReportCommand(35*time.Millisecond, errors.New("bad"))
+	f.TestTTY(t,
+		"                                            ✘ 35ms", Styles,
+		"                                            !     ", "\n",
+		term.DotHere)
+}
+
+func TestCommandBadge_DrawnOnPromptLine(t *testing.T) {
+	f := setup(rc(`edit:command-badge:enabled = $true`))
+	defer f.Cleanup()
+	// The cursor is on line 5 after the command is accepted, and on line 8
+	// after it has finished.
+	f.TTYCtrl.InjectCursorPositions(term.Pos{Line: 5}, term.Pos{Line: 8})
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+
+	f.Editor.ReportCommand(1500*time.Millisecond, nil)
+	// The prompt takes one line, so it is on line 4.
+	wantCells := term.NewBufferBuilder(6).
+		WriteStringSGR("✔", "32").WriteStringSGR(" 1.5s", "").Buffer().Lines[0]
+	want := []clitest.DrawnAbove{{Up: 4, Col: 44, Cells: wantCells}}
+	if drawn := f.TTYCtrl.DrawnAbove(); !reflect.DeepEqual(drawn, want) {
+		t.Errorf("got drawn %v, want %v", drawn, want)
+	}
+}
+
+func TestCommandBadge_FallsBackWhenTerminalMayHaveScrolled(t *testing.T) {
+	f := setup(rc(`edit:command-badge:enabled = $true`))
+	defer f.Cleanup()
+	// The cursor is on the last line after the command has finished.
+	f.TTYCtrl.InjectCursorPositions(
+		term.Pos{Line: 5}, term.Pos{Line: clitest.FakeTTYHeight - 1})
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+
+	f.Editor.ReportCommand(1500*time.Millisecond, nil)
+	if drawn := f.TTYCtrl.DrawnAbove(); len(drawn) != 0 {
+		t.Errorf("got drawn %v, want none", drawn)
+	}
+	f.TestTTY(t,
+		"                                            ✔ 1.5s", Styles,
+		"                                            v     ", "\n",
+		term.DotHere)
+}
+
+func TestCommandBadge_FallsBackWhenCursorMovedUp(t *testing.T) {
+	f := setup(rc(`edit:command-badge:enabled = $true`))
+	defer f.Cleanup()
+	// The command has cleared the screen.
+	f.TTYCtrl.InjectCursorPositions(term.Pos{Line: 5}, term.Pos{Line: 0})
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+
+	f.Editor.ReportCommand(1500*time.Millisecond, nil)
+	if drawn := f.TTYCtrl.DrawnAbove(); len(drawn) != 0 {
+		t.Errorf("got drawn %v, want none", drawn)
+	}
+	f.TestTTY(t,
+		"                                            ✔ 1.5s", Styles,
+		"                                            v     ", "\n",
+		term.DotHere)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tt.Test(t, tt.Fn("formatDuration", formatDuration), tt.Table{
+		tt.Args(35400 * time.Microsecond).Rets("35ms"),
+		tt.Args(1254 * time.Millisecond).Rets("1.25s"),
+		tt.Args(182400 * time.Millisecond).Rets("3m2s"),
+	})
+}
@@ -0,0 +1,19 @@
+// +build !windows,!plan9
+
+package edit
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+)
+
+func TestCommandBadgeText_ShowsExitStatus(t *testing.T) {
+	// A WaitStatus of a process that exited with 2.
+	err := eval.ExternalCmdExit{WaitStatus: syscall.WaitStatus(2 << 8), CmdName: "false"}
+	if got := plainText(commandBadgeText(time.Second, err)); got != "✘ 2 1s" {
+		t.Errorf("got %q, want %q", got, "✘ 2 1s")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/parse/parseutil"
+	"github.com/elves/elvish/pkg/ui"
 )
 
 // An error returned by Complete if the config has not supplied a PureEvaler.
@@ -28,6 +29,12 @@ type Config struct {
 	// Used to generate candidates for a command argument. Defaults to
 	// Filenames.
 	ArgGenerator ArgGenerator
+	// Patterns of files to leave out when completing filenames, in the syntax
+	// of .gitignore files. See FilenameGenerator for details.
+	FilenameIgnore []string
+	// Used to generate a hint shown when completing a command argument, such as
+	// the signature of the command. If nil, no hint is generated.
+	ArgHinter ArgHinter
 }
 
 // Filterer is the type of functions that filter raw candidates.
@@ -38,11 +45,19 @@ type Filterer func(ctxName, seed string, rawItems []RawItem) []RawItem
 // argument to complete, and returns raw candidates or an error.
 type ArgGenerator func(args []string) ([]RawItem, error)
 
+// ArgHinter is the type of functions that generate a hint for a command
+// argument. It takes the same arguments as ArgGenerator, and returns the hint,
+// which may be empty.
+type ArgHinter func(args []string) ui.Text
+
 // Result keeps the result of the completion algorithm.
 type Result struct {
 	Name    string
 	Replace diag.Ranging
 	Items   []completion.Item
+	// A hint to show along with the items. Only generated when completing a
+	// command argument.
+	Hint ui.Text
 }
 
 // RawItem represents completion items before the quoting pass.
@@ -58,6 +73,12 @@ type PureEvaler interface {
 	EachSpecial(func(special string))
 	EachNs(func(string))
 	EachVariableInNs(string, func(string))
+	// Calls the function with the spec of each module that can be imported
+	// with use.
+	EachModule(func(spec string))
+	// Calls the function with the name of each option the command accepts, if
+	// known.
+	EachOption(cmd string, f func(opt string))
 	PurelyEvalPrimary(pn *parse.Primary) interface{}
 	PurelyEvalCompound(*parse.Compound) (string, error)
 	PurelyEvalPartialCompound(*parse.Compound, int) (string, error)
@@ -80,7 +101,7 @@ func Complete(code CodeBuffer, cfg Config) (*Result, error) {
 		cfg.Filterer = FilterPrefix
 	}
 	if cfg.ArgGenerator == nil {
-		cfg.ArgGenerator = GenerateFileNames
+		cfg.ArgGenerator = FilenameGenerator(cfg.FilenameIgnore)
 	}
 
 	// Ignore the error; the function always returns a valid *ChunkNode.
@@ -100,7 +121,8 @@ func Complete(code CodeBuffer, cfg Config) (*Result, error) {
 			return items[i].ToShow < items[j].ToShow
 		})
 		items = dedup(items)
-		return &Result{Name: ctx.name, Items: items, Replace: ctx.interval}, nil
+		return &Result{
+			Name: ctx.name, Items: items, Replace: ctx.interval, Hint: ctx.hint}, nil
 	}
 	return nil, errNoCompletion
 }
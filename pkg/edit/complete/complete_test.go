@@ -24,6 +24,8 @@ type testEvaler struct {
 	specials   []string
 	namespaces []string
 	variables  map[string][]string
+	modules    []string
+	options    map[string][]string
 }
 
 func feed(f func(string), ss []string) {
@@ -40,6 +42,12 @@ func (ev testEvaler) EachVariableInNs(ns string, f func(string)) {
 	feed(f, ev.variables[ns])
 }
 
+func (ev testEvaler) EachModule(f func(string)) { feed(f, ev.modules) }
+
+func (ev testEvaler) EachOption(cmd string, f func(string)) {
+	feed(f, ev.options[cmd])
+}
+
 func (ev testEvaler) PurelyEvalPartialCompound(cn *parse.Compound, upto int) (string, error) {
 	return (*eval.Evaler)(nil).PurelyEvalPartialCompound(cn, upto)
 }
@@ -83,6 +91,8 @@ func TestComplete(t *testing.T) {
 				"ns2:": {"ipsum"},
 			},
 			namespaces: []string{"ns1:", "ns2:"},
+			modules:    []string{"str", "a/b"},
+			options:    map[string][]string{"f": {"foo", "bar"}},
 		},
 		ArgGenerator: func(args []string) ([]RawItem, error) {
 			if len(args) >= 2 && args[0] == "sudo" {
@@ -110,6 +120,19 @@ func TestComplete(t *testing.T) {
 		},
 	}
 
+	hintCfg := Config{
+		PureEvaler:   cfg.PureEvaler,
+		ArgGenerator: dupCfg.ArgGenerator,
+		ArgHinter: func(args []string) ui.Text {
+			return ui.T(fmt.Sprintf("%#v", args))
+		},
+	}
+
+	ignoreCfg := Config{
+		PureEvaler:     cfg.PureEvaler,
+		FilenameIgnore: []string{"*.exe"},
+	}
+
 	allFileNameItems := []completion.Item{
 		fc("a.exe", " "), fc("d"+string(os.PathSeparator), ""), fc("non-exe", " "),
 	}
@@ -133,6 +156,14 @@ func TestComplete(t *testing.T) {
 				},
 			},
 			nil),
+		// Hints are generated for arguments.
+		Args(cb("ls x a"), hintCfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(5, 6),
+				Items: []completion.Item{c("a")},
+				Hint:  ui.T(`[]string{"ls", "x", "a"}`),
+			},
+			nil),
 		// Complete arguments using GenerateFileNames.
 		Args(cb("ls "), cfg).Rets(
 			&Result{
@@ -144,6 +175,25 @@ func TestComplete(t *testing.T) {
 				Name: "argument", Replace: r(3, 4),
 				Items: []completion.Item{fc("a.exe", " ")}},
 			nil),
+		// Files matching FilenameIgnore are left out.
+		Args(cb("ls "), ignoreCfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(3, 3),
+				Items: []completion.Item{
+					fc("d"+string(os.PathSeparator), ""), fc("non-exe", " ")}},
+			nil),
+		Args(cb("p > "), ignoreCfg).Rets(
+			&Result{
+				Name: "redir", Replace: r(4, 4),
+				Items: []completion.Item{
+					fc("d"+string(os.PathSeparator), ""), fc("non-exe", " ")}},
+			nil),
+		// Unless no other file matches.
+		Args(cb("ls a"), ignoreCfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(3, 4),
+				Items: []completion.Item{fc("a.exe", " ")}},
+			nil),
 		// GenerateForSudo completing external commands.
 		Args(cb("sudo "), cfg).Rets(
 			&Result{
@@ -189,6 +239,10 @@ func TestComplete(t *testing.T) {
 		Args(cb("a|"), cfg).Rets(
 			&Result{Name: "command", Replace: r(2, 2), Items: allCommandItems},
 			nil),
+		// Complete after a pipe following a parse error.
+		Args(cb("a ] b|"), cfg).Rets(
+			&Result{Name: "command", Replace: r(6, 6), Items: allCommandItems},
+			nil),
 		// Complete at the beginning of output capture.
 		Args(cb("a ("), cfg).Rets(
 			&Result{Name: "command", Replace: r(3, 3), Items: allCommandItems},
@@ -235,6 +289,43 @@ func TestComplete(t *testing.T) {
 				Name: "variable", Replace: r(7, 7),
 				Items: []completion.Item{c("lorem")}},
 			nil),
+
+		// Completing options.
+		Args(cb("f &"), cfg).Rets(
+			&Result{
+				Name: "option", Replace: r(3, 3),
+				Items: []completion.Item{
+					{ToShow: "bar", ToInsert: "bar="},
+					{ToShow: "foo", ToInsert: "foo="}}},
+			nil),
+		//       01234
+		Args(cb("f x &b"), cfg).Rets(
+			&Result{
+				Name: "option", Replace: r(5, 6),
+				Items: []completion.Item{{ToShow: "bar", ToInsert: "bar="}}},
+			nil),
+		// No options are known for the command.
+		Args(cb("g &"), cfg).Rets(
+			&Result{Name: "option", Replace: r(3, 3), Items: nil},
+			nil),
+
+		// Completing modules.
+		Args(cb("use "), cfg).Rets(
+			&Result{
+				Name: "module", Replace: r(4, 4),
+				Items: []completion.Item{c("a/b"), c("str")}},
+			nil),
+		Args(cb("use s"), cfg).Rets(
+			&Result{
+				Name: "module", Replace: r(4, 5),
+				Items: []completion.Item{c("str")}},
+			nil),
+		// Only the first argument of use is a module spec.
+		Args(cb("use str "), cfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(8, 8),
+				Items: allFileNameItems},
+			nil),
 	})
 
 	// Symlinks and executable bits are not available on Windows.
@@ -275,6 +366,33 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestComplete_QuotesFilenames(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{"a b": "", "it's": "", "~x": ""})
+	cfg := Config{PureEvaler: testEvaler{}}
+	style := ui.StyleFromSGR(lscolors.GetColorist().GetStyle("a b"))
+	item := func(show, insert string) completion.Item {
+		return completion.Item{ToShow: show, ToInsert: insert, ShowStyle: style}
+	}
+
+	tt.Test(t, tt.Fn("Complete", Complete), tt.Table{
+		Args(cb("ls "), cfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(3, 3),
+				Items: []completion.Item{
+					item("a b", "'a b' "), item("it's", "'it''s' "),
+					item("~x", "'~x' ")}},
+			nil),
+		// The quoting typed so far is kept.
+		Args(cb(`ls "a`), cfg).Rets(
+			&Result{
+				Name: "argument", Replace: r(3, 5),
+				Items: []completion.Item{item("a b", `"a b" `)}},
+			nil),
+	})
+}
+
 func cb(s string) CodeBuffer { return CodeBuffer{s, len(s)} }
 
 func c(s string) completion.Item { return completion.Item{ToShow: s, ToInsert: s} }
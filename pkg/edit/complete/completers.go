@@ -6,15 +6,20 @@ import (
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/ui"
 )
 
 var parent = parse.Parent
 
 var completers = []completer{
+	// Must come before completeCommand, since a trailing "&" is parsed as a
+	// separator in the pipeline.
+	completeOption,
 	completeCommand,
 	completeIndex,
 	completeRedir,
 	completeVariable,
+	completeModule,
 	completeArg,
 }
 
@@ -25,6 +30,7 @@ type context struct {
 	seed     string
 	quote    parse.PrimaryType
 	interval diag.Ranging
+	hint     ui.Text
 }
 
 func completeArg(n parse.Node, cfg Config) (*context, []RawItem, error) {
@@ -32,8 +38,9 @@ func completeArg(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	if sep, ok := n.(*parse.Sep); ok {
 		if form, ok := parent(sep).(*parse.Form); ok && form.Head != nil {
 			// Case 1: starting a new argument.
-			ctx := &context{"argument", "", parse.Bareword, range0(n.Range().To)}
 			args := purelyEvalForm(form, "", n.Range().To, ev)
+			ctx := &context{"argument", "", parse.Bareword, range0(n.Range().To),
+				argHint(cfg, args)}
 			items, err := cfg.ArgGenerator(args)
 			return ctx, items, err
 		}
@@ -43,8 +50,9 @@ func completeArg(n parse.Node, cfg Config) (*context, []RawItem, error) {
 			if form, ok := parent(compound).(*parse.Form); ok {
 				if form.Head != nil && form.Head != compound {
 					// Case 2: in an incomplete argument.
-					ctx := &context{"argument", seed, primary.Type, compound.Range()}
 					args := purelyEvalForm(form, seed, compound.Range().From, ev)
+					ctx := &context{"argument", seed, primary.Type, compound.Range(),
+						argHint(cfg, args)}
 					items, err := cfg.ArgGenerator(args)
 					return ctx, items, err
 				}
@@ -54,11 +62,18 @@ func completeArg(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	return nil, nil, errNoCompletion
 }
 
+func argHint(cfg Config, args []string) ui.Text {
+	if cfg.ArgHinter == nil {
+		return nil
+	}
+	return cfg.ArgHinter(args)
+}
+
 func completeCommand(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	ev := cfg.PureEvaler
 	generateForEmpty := func(pos int) (*context, []RawItem, error) {
-		ctx := &context{"command", "", parse.Bareword, range0(pos)}
-		items, err := generateCommands("", ev)
+		ctx := &context{"command", "", parse.Bareword, range0(pos), nil}
+		items, err := generateCommands("", ev, cfg.FilenameIgnore)
 		return ctx, items, err
 	}
 
@@ -89,8 +104,8 @@ func completeCommand(n parse.Node, cfg Config) (*context, []RawItem, error) {
 				if form.Head == compound {
 					// Case 4: At an already started command.
 					ctx := &context{
-						"command", seed, primary.Type, compound.Range()}
-					items, err := generateCommands(seed, ev)
+						"command", seed, primary.Type, compound.Range(), nil}
+					items, err := generateCommands(seed, ev, cfg.FilenameIgnore)
 					return ctx, items, err
 				}
 			}
@@ -99,12 +114,12 @@ func completeCommand(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	return nil, nil, errNoCompletion
 }
 
-// NOTE: This now only supports a single level of indexing; for instance,
-// $a[<Tab> is supported, but $a[x][<Tab> is not.
+// Indices before the one being completed are evaluated purely, so $a[x][<Tab>
+// is supported as long as x is a simple compound.
 func completeIndex(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	ev := cfg.PureEvaler
 	generateForEmpty := func(v interface{}, pos int) (*context, []RawItem, error) {
-		ctx := &context{"index", "", parse.Bareword, range0(pos)}
+		ctx := &context{"index", "", parse.Bareword, range0(pos), nil}
 		return ctx, generateIndices(v), nil
 	}
 
@@ -112,10 +127,8 @@ func completeIndex(n parse.Node, cfg Config) (*context, []RawItem, error) {
 		if is(parent(n), aIndexing) {
 			// We are just after an opening bracket.
 			indexing := parent(n).(*parse.Indexing)
-			if len(indexing.Indicies) == 1 {
-				if indexee := ev.PurelyEvalPrimary(indexing.Head); indexee != nil {
-					return generateForEmpty(indexee, n.Range().To)
-				}
+			if indexee := purelyEvalIndexee(indexing, ev); indexee != nil {
+				return generateForEmpty(indexee, n.Range().To)
 			}
 		}
 		if is(parent(n), aArray) {
@@ -123,10 +136,8 @@ func completeIndex(n parse.Node, cfg Config) (*context, []RawItem, error) {
 			if is(parent(array), aIndexing) {
 				// We are after an existing index and spaces.
 				indexing := parent(array).(*parse.Indexing)
-				if len(indexing.Indicies) == 1 {
-					if indexee := ev.PurelyEvalPrimary(indexing.Head); indexee != nil {
-						return generateForEmpty(indexee, n.Range().To)
-					}
+				if indexee := purelyEvalIndexee(indexing, ev); indexee != nil {
+					return generateForEmpty(indexee, n.Range().To)
 				}
 			}
 		}
@@ -141,12 +152,10 @@ func completeIndex(n parse.Node, cfg Config) (*context, []RawItem, error) {
 				if is(parent(array), aIndexing) {
 					// We are just after an incomplete index.
 					indexing := parent(array).(*parse.Indexing)
-					if len(indexing.Indicies) == 1 {
-						if indexee := ev.PurelyEvalPrimary(indexing.Head); indexee != nil {
-							ctx := &context{
-								"index", seed, primary.Type, compound.Range()}
-							return ctx, generateIndices(indexee), nil
-						}
+					if indexee := purelyEvalIndexee(indexing, ev); indexee != nil {
+						ctx := &context{
+							"index", seed, primary.Type, compound.Range(), nil}
+						return ctx, generateIndices(indexee), nil
 					}
 				}
 			}
@@ -160,8 +169,8 @@ func completeRedir(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	if is(n, aSep) {
 		if is(parent(n), aRedir) {
 			// Empty redirection target.
-			ctx := &context{"redir", "", parse.Bareword, range0(n.Range().To)}
-			items, err := generateFileNames("", false)
+			ctx := &context{"redir", "", parse.Bareword, range0(n.Range().To), nil}
+			items, err := generateFileNames("", false, cfg.FilenameIgnore)
 			return ctx, items, err
 		}
 	}
@@ -170,8 +179,8 @@ func completeRedir(n parse.Node, cfg Config) (*context, []RawItem, error) {
 			if is(parent(compound), &parse.Redir{}) {
 				// Non-empty redirection target.
 				ctx := &context{
-					"redir", seed, primary.Type, compound.Range()}
-				items, err := generateFileNames(seed, false)
+					"redir", seed, primary.Type, compound.Range(), nil}
+				items, err := generateFileNames(seed, false, cfg.FilenameIgnore)
 				return ctx, items, err
 			}
 		}
@@ -192,7 +201,7 @@ func completeVariable(n parse.Node, cfg Config) (*context, []RawItem, error) {
 
 	ctx := &context{
 		"variable", nameSeed, parse.Bareword,
-		diag.Ranging{From: begin, To: primary.Range().To}}
+		diag.Ranging{From: begin, To: primary.Range().To}, nil}
 
 	var items []RawItem
 	ev.EachVariableInNs(ns, func(varname string) {
@@ -210,6 +219,70 @@ func completeVariable(n parse.Node, cfg Config) (*context, []RawItem, error) {
 	return ctx, items, nil
 }
 
+func completeOption(n parse.Node, cfg Config) (*context, []RawItem, error) {
+	ev := cfg.PureEvaler
+	if sep, ok := n.(*parse.Sep); ok && parse.SourceText(sep) == "&" {
+		// Case 1: Just after "&", which is parsed as the background marker of
+		// the pipeline.
+		if pipeline, ok := parent(sep).(*parse.Pipeline); ok && len(pipeline.Forms) > 0 {
+			form := pipeline.Forms[len(pipeline.Forms)-1]
+			if form.Head != nil && form.Range().To <= sep.Range().From {
+				ctx := &context{"option", "", parse.Bareword, range0(n.Range().To), nil}
+				return ctx, generateOptions(form, ev), nil
+			}
+		}
+	}
+	if primary, ok := n.(*parse.Primary); ok {
+		if compound, seed := primaryInSimpleCompound(primary, ev); compound != nil {
+			if pair, ok := parent(compound).(*parse.MapPair); ok && pair.Key == compound {
+				if form, ok := parent(pair).(*parse.Form); ok && form.Head != nil {
+					// Case 2: In an incomplete option name.
+					ctx := &context{
+						"option", seed, primary.Type, compound.Range(), nil}
+					return ctx, generateOptions(form, ev), nil
+				}
+			}
+		}
+	}
+	return nil, nil, errNoCompletion
+}
+
+func completeModule(n parse.Node, cfg Config) (*context, []RawItem, error) {
+	ev := cfg.PureEvaler
+	if sep, ok := n.(*parse.Sep); ok {
+		if form, ok := parent(sep).(*parse.Form); ok && isUse(form, ev) {
+			if len(form.Args) == 0 || form.Args[0].Range().From >= sep.Range().To {
+				// Case 1: Starting the first argument of use.
+				ctx := &context{"module", "", parse.Bareword, range0(n.Range().To), nil}
+				return ctx, generateModules(ev), nil
+			}
+		}
+	}
+	if primary, ok := n.(*parse.Primary); ok {
+		if compound, seed := primaryInSimpleCompound(primary, ev); compound != nil {
+			if form, ok := parent(compound).(*parse.Form); ok && isUse(form, ev) {
+				// Relative module specs are completed as arguments.
+				isRelative := strings.HasPrefix(seed, ".") || strings.HasPrefix(seed, "/")
+				if len(form.Args) > 0 && form.Args[0] == compound && !isRelative {
+					// Case 2: In the first argument of use.
+					ctx := &context{
+						"module", seed, primary.Type, compound.Range(), nil}
+					return ctx, generateModules(ev), nil
+				}
+			}
+		}
+	}
+	return nil, nil, errNoCompletion
+}
+
+func isUse(form *parse.Form, ev PureEvaler) bool {
+	if form.Head == nil {
+		return false
+	}
+	head, err := ev.PurelyEvalCompound(form.Head)
+	return err == nil && head == "use"
+}
+
 func range0(pos int) diag.Ranging {
 	return diag.Ranging{From: pos, To: pos}
 }
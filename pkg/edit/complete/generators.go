@@ -2,15 +2,17 @@ package complete
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/elves/elvish/pkg/cli/lscolors"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/fsutil"
+	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/ui"
 )
 
@@ -19,7 +21,19 @@ var pathSeparator = string(filepath.Separator)
 // GenerateFileNames returns filename candidates that are suitable for completing
 // the last argument. It can be used in Config.ArgGenerator.
 func GenerateFileNames(args []string) ([]RawItem, error) {
-	return generateFileNames(args[len(args)-1], false)
+	return generateFileNames(args[len(args)-1], false, nil)
+}
+
+// FilenameGenerator returns an ArgGenerator that works like GenerateFileNames,
+// but leaves out files matching the ignore patterns, which use the syntax of
+// .gitignore files, unless no other file matches the argument being completed.
+// Patterns containing a slash that is not at the end are matched against the
+// path relative to the working directory; other patterns are matched against
+// the name of the file.
+func FilenameGenerator(ignore []string) ArgGenerator {
+	return func(args []string) ([]RawItem, error) {
+		return generateFileNames(args[len(args)-1], false, ignore)
+	}
 }
 
 // GenerateForSudo generates candidates for sudo.
@@ -29,7 +43,7 @@ func GenerateForSudo(cfg Config, args []string) ([]RawItem, error) {
 		return nil, errNoCompletion
 	case len(args) == 2:
 		// Complete external commands.
-		return generateExternalCommands(args[1], cfg.PureEvaler)
+		return generateExternalCommands(args[1], cfg.PureEvaler, cfg.FilenameIgnore)
 	default:
 		return cfg.ArgGenerator(args[1:])
 	}
@@ -37,20 +51,20 @@ func GenerateForSudo(cfg Config, args []string) ([]RawItem, error) {
 
 // Internal generators, used from completers.
 
-func generateExternalCommands(seed string, ev PureEvaler) ([]RawItem, error) {
+func generateExternalCommands(seed string, ev PureEvaler, ignore []string) ([]RawItem, error) {
 	if fsutil.DontSearch(seed) {
 		// Completing a local external command name.
-		return generateFileNames(seed, true)
+		return generateFileNames(seed, true, ignore)
 	}
 	var items []RawItem
 	ev.EachExternal(func(s string) { items = append(items, PlainItem(s)) })
 	return items, nil
 }
 
-func generateCommands(seed string, ev PureEvaler) ([]RawItem, error) {
+func generateCommands(seed string, ev PureEvaler, ignore []string) ([]RawItem, error) {
 	if fsutil.DontSearch(seed) {
 		// Completing a local external command name.
-		return generateFileNames(seed, true)
+		return generateFileNames(seed, true, ignore)
 	}
 
 	var cands []RawItem
@@ -89,8 +103,25 @@ func generateCommands(seed string, ev PureEvaler) ([]RawItem, error) {
 	return cands, nil
 }
 
-func generateFileNames(seed string, onlyExecutable bool) ([]RawItem, error) {
-	var items []RawItem
+// The number of goroutines used to stat directory entries.
+const statWorkers = 16
+
+// A directory entry, along with the information used for building a
+// candidate.
+type fileEntry struct {
+	// The path of the file, with a trailing path separator if it is a
+	// directory or a symlink to a directory.
+	full  string
+	mode  os.FileMode
+	isDir bool
+	style string
+}
+
+func generateFileNames(seed string, onlyExecutable bool, ignore []string) ([]RawItem, error) {
+	ignoreList, err := compileIgnore(ignore)
+	if err != nil {
+		return nil, err
+	}
 
 	dir, fileprefix := filepath.Split(seed)
 	dirToRead := dir
@@ -98,55 +129,117 @@ func generateFileNames(seed string, onlyExecutable bool) ([]RawItem, error) {
 		dirToRead = "."
 	}
 
-	infos, err := ioutil.ReadDir(dirToRead)
+	names, err := readDirNames(dirToRead)
 	if err != nil {
 		return nil, fmt.Errorf("cannot list directory %s: %v", dirToRead, err)
 	}
-
-	lsColor := lscolors.GetColorist()
-
-	// Make candidates out of elements that match the file component.
-	for _, info := range infos {
-		name := info.Name()
-		// Show dot files iff file part of pattern starts with dot, and vice
-		// versa.
-		if dotfile(fileprefix) != dotfile(name) {
-			continue
+	// Show dot files iff file part of pattern starts with dot, and vice versa.
+	// This is checked before calling stat, since it doesn't require any
+	// information about the file.
+	var matchingNames []string
+	for _, name := range names {
+		if dotfile(fileprefix) == dotfile(name) {
+			matchingNames = append(matchingNames, name)
 		}
+	}
+
+	var items, ignoredItems []RawItem
+	// Whether any file that is not ignored has fileprefix as a prefix.
+	hasPrefixMatch := false
+	for _, entry := range statEntries(dir, matchingNames) {
 		// Only accept searchable directories and executable files if
 		// executableOnly is true.
-		if onlyExecutable && (info.Mode()&0111) == 0 {
+		if entry == nil || (onlyExecutable && entry.mode&0111 == 0) {
 			continue
 		}
-
-		// Full filename for source and getStyle.
-		full := dir + name
-
-		// Will be set to an empty space for non-directories
 		suffix := " "
-
-		if info.IsDir() {
-			full += pathSeparator
+		if entry.isDir {
 			suffix = ""
-		} else if info.Mode()&os.ModeSymlink != 0 {
-			stat, err := os.Stat(full)
-			if err == nil && stat.IsDir() {
-				// Symlink to directory.
-				full += pathSeparator
-				suffix = ""
-			}
 		}
-
-		items = append(items, ComplexItem{
-			Stem:         full,
+		item := ComplexItem{
+			Stem:         entry.full,
 			CodeSuffix:   suffix,
-			DisplayStyle: ui.StyleFromSGR(lsColor.GetStyle(full)),
-		})
+			DisplayStyle: ui.StyleFromSGR(entry.style),
+		}
+		if ignoreList.ignores(entry.full, entry.isDir) {
+			ignoredItems = append(ignoredItems, item)
+			continue
+		}
+		items = append(items, item)
+		if strings.HasPrefix(entry.full[len(dir):], fileprefix) {
+			hasPrefixMatch = true
+		}
+	}
+	// Ignored files are still candidates when no other file matches.
+	if !hasPrefixMatch {
+		items = append(items, ignoredItems...)
 	}
-
 	return items, nil
 }
 
+// Returns the sorted names of the entries in a directory.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Calls stat on the entries of a directory concurrently, since this can be
+// slow for large directories or network file systems. The returned slice has
+// the same order as names; entries that can't be stat'ed, for example because
+// they have been removed, are nil.
+func statEntries(dir string, names []string) []*fileEntry {
+	entries := make([]*fileEntry, len(names))
+	lsColor := lscolors.GetColorist()
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < statWorkers && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				entries[i] = statEntry(dir+names[i], lsColor)
+			}
+		}()
+	}
+	for i := range names {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return entries
+}
+
+func statEntry(full string, lsColor lscolors.Colorist) *fileEntry {
+	info, err := os.Lstat(full)
+	if err != nil {
+		return nil
+	}
+	entry := &fileEntry{full: full, mode: info.Mode()}
+	if info.IsDir() {
+		entry.isDir = true
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		stat, err := os.Stat(full)
+		if err == nil && stat.IsDir() {
+			// Symlink to directory.
+			entry.isDir = true
+		}
+	}
+	if entry.isDir {
+		entry.full += pathSeparator
+	}
+	entry.style = lsColor.GetStyle(entry.full)
+	return entry
+}
+
 func generateIndices(v interface{}) []RawItem {
 	var items []RawItem
 	vals.IterateKeys(v, func(k interface{}) bool {
@@ -158,6 +251,24 @@ func generateIndices(v interface{}) []RawItem {
 	return items
 }
 
+func generateOptions(form *parse.Form, ev PureEvaler) []RawItem {
+	head, err := ev.PurelyEvalCompound(form.Head)
+	if err != nil {
+		return nil
+	}
+	var items []RawItem
+	ev.EachOption(head, func(name string) {
+		items = append(items, ComplexItem{Stem: name, CodeSuffix: "="})
+	})
+	return items
+}
+
+func generateModules(ev PureEvaler) []RawItem {
+	var items []RawItem
+	ev.EachModule(func(spec string) { items = append(items, PlainItem(spec)) })
+	return items
+}
+
 func dotfile(fname string) bool {
 	return strings.HasPrefix(fname, ".")
 }
@@ -0,0 +1,134 @@
+package complete
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// A list of patterns of files to ignore, in the syntax of .gitignore files.
+type ignoreList []ignorePattern
+
+type ignorePattern struct {
+	// The pattern split into segments by /. If the pattern is not anchored,
+	// this has only one segment.
+	segments []string
+	negate   bool
+	dirOnly  bool
+	// Whether the pattern contains a slash that is not at the end, in which
+	// case it is matched against the path relative to the working directory
+	// instead of the name of the file.
+	anchored bool
+}
+
+// Parses patterns in the syntax of .gitignore files. Empty patterns and
+// patterns starting with # are skipped.
+func compileIgnore(patterns []string) (ignoreList, error) {
+	var list ignoreList
+	for _, p := range patterns {
+		if p == "" || p[0] == '#' {
+			continue
+		}
+		var pattern ignorePattern
+		if p[0] == '!' {
+			pattern.negate = true
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			pattern.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		if strings.Contains(p, "/") {
+			pattern.anchored = true
+			pattern.segments = strings.Split(strings.TrimPrefix(p, "/"), "/")
+		} else {
+			pattern.segments = []string{p}
+		}
+		for _, segment := range pattern.segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("bad ignore pattern %s", parse.Quote(p))
+			}
+		}
+		list = append(list, pattern)
+	}
+	return list, nil
+}
+
+// Returns whether a file should be ignored. The name is the path of the file
+// as it appears in the candidate, and isDir is whether it is a directory.
+// Like in .gitignore files, the last matching pattern wins.
+func (l ignoreList) ignores(name string, isDir bool) bool {
+	if len(l) == 0 {
+		return false
+	}
+	base := filepath.Base(name)
+	var rel []string
+	if r, ok := relToWd(name); ok {
+		rel = strings.Split(filepath.ToSlash(r), "/")
+	}
+	ignored := false
+	for _, pattern := range l {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		var matched bool
+		if pattern.anchored {
+			matched = rel != nil && matchSegments(pattern.segments, rel)
+		} else {
+			matched, _ = path.Match(pattern.segments[0], base)
+		}
+		if matched {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// Returns the path of a file relative to the working directory, or false if
+// the file is not inside the working directory.
+func relToWd(name string) (string, bool) {
+	rel := filepath.Clean(name)
+	if filepath.IsAbs(rel) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", false
+		}
+		rel, err = filepath.Rel(wd, rel)
+		if err != nil {
+			return "", false
+		}
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// Matches segments of a pattern against segments of a path. A ** segment
+// matches zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], name[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,48 @@
+package complete
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/tt"
+)
+
+func TestIgnoreList(t *testing.T) {
+	ignores := func(patterns []string, name string, isDir bool) bool {
+		list, err := compileIgnore(patterns)
+		if err != nil {
+			panic(err)
+		}
+		return list.ignores(name, isDir)
+	}
+	tt.Test(t, tt.Fn("ignores", ignores), tt.Table{
+		Args([]string(nil), "a.o", false).Rets(false),
+		Args([]string{"*.o"}, "a.o", false).Rets(true),
+		Args([]string{"*.o"}, "d/a.o", false).Rets(true),
+		Args([]string{"*.o"}, "a.c", false).Rets(false),
+		// Empty patterns and comments are skipped.
+		Args([]string{"", "# *.o"}, "a.o", false).Rets(false),
+		Args([]string{`\#*`}, "#a", false).Rets(true),
+		// Negation; the last matching pattern wins.
+		Args([]string{"*.o", "!keep.o"}, "keep.o", false).Rets(false),
+		Args([]string{"!keep.o", "*.o"}, "keep.o", false).Rets(true),
+		// Patterns ending with a slash only match directories.
+		Args([]string{"build/"}, "build/", true).Rets(true),
+		Args([]string{"build/"}, "build", false).Rets(false),
+		// Patterns with a slash are matched against the relative path.
+		Args([]string{"d/*.o"}, "d/a.o", false).Rets(true),
+		Args([]string{"d/*.o"}, "e/d/a.o", false).Rets(false),
+		Args([]string{"/a.o"}, "a.o", false).Rets(true),
+		Args([]string{"/a.o"}, "d/a.o", false).Rets(false),
+		Args([]string{"**/d/*.o"}, "e/d/a.o", false).Rets(true),
+		Args([]string{"e/**/a.o"}, "e/d/f/a.o", false).Rets(true),
+		Args([]string{"e/**/a.o"}, "e/a.o", false).Rets(true),
+		Args([]string{"d/*.o"}, "../d/a.o", false).Rets(false),
+	})
+}
+
+func TestCompileIgnore_BadPattern(t *testing.T) {
+	_, err := compileIgnore([]string{"[a"})
+	if err == nil {
+		t.Errorf("got nil error, want error")
+	}
+}
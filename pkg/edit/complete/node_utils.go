@@ -3,6 +3,7 @@ package complete
 import (
 	"reflect"
 
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/parse"
 )
 
@@ -58,3 +59,27 @@ func purelyEvalForm(form *parse.Form, seed string, upto int, ev PureEvaler) []st
 	words = append(words, seed)
 	return words
 }
+
+// Evaluates the value indexed by the last index of an indexing expression,
+// which is the head indexed by all the other indices. Returns nil if the head
+// or any of the other indices can't be evaluated purely.
+func purelyEvalIndexee(indexing *parse.Indexing, ev PureEvaler) interface{} {
+	if len(indexing.Indicies) == 0 {
+		return nil
+	}
+	v := ev.PurelyEvalPrimary(indexing.Head)
+	for _, array := range indexing.Indicies[:len(indexing.Indicies)-1] {
+		if v == nil || len(array.Compounds) != 1 {
+			return nil
+		}
+		key, err := ev.PurelyEvalCompound(array.Compounds[0])
+		if err != nil {
+			return nil
+		}
+		v, err = vals.Index(v, key)
+		if err != nil {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,274 @@
+package edit
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/pkg/edit/complete"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var completion:bash:enabled
+//
+// A boolean that controls whether the arguments of external commands are
+// completed with the completion scripts of Bash, if there is neither an entry
+// for the command nor a default entry in
+// [`$edit:completion:arg-completer`](#editcompletionarg-completer), and no
+// spec registered with [`edit:complete-spec`](#editcomplete-spec). Defaults to
+// `$true`.
+//
+// Commands without a Bash completion fall back to completing filenames.
+//
+// @cf edit:complete-bash
+
+//elvdoc:var completion:bash:scripts
+//
+// A list of the Bash scripts that are sourced to define completions, before
+// loading the completion of the command dynamically when the
+// [bash-completion](https://github.com/scop/bash-completion) package supports
+// it. Defaults to a list containing the main script of bash-completion, if
+// it is found in one of the usual places.
+//
+// Scripts that don't exist are ignored, so completions defined by a single
+// script can be added like this:
+//
+// ```elvish
+// edit:completion:bash:scripts = [$@edit:completion:bash:scripts ~/.config/foo-completion.bash]
+// ```
+
+//elvdoc:fn complete-bash
+//
+// ```elvish
+// edit:complete-bash $args...
+// ```
+//
+// Completes the last argument of a command using its Bash completion, which
+// is found in the same way as when
+// [`$edit:completion:bash:enabled`](#editcompletionbashenabled) is true. The
+// first argument is the name of the command. Falls back to completing
+// filenames if the command doesn't have a Bash completion.
+//
+// A new Bash process is started each time, with the same environment
+// variables as Elvish. The completion functions of Bash are called with the
+// usual `COMP_WORDS`, `COMP_CWORD`, `COMP_LINE` and `COMP_POINT` variables,
+// but the arguments are not quoted again, so completions that depend on
+// quoting may not work as in Bash.
+//
+// This function can be used to complete the arguments of an Elvish function
+// that wraps an external command:
+//
+// ```elvish
+// fn g [@a]{ git $@a }
+// edit:completion:arg-completer[g] = [_ @a]{ edit:complete-bash git $@a }
+// ```
+
+// Places where the main script of bash-completion is usually installed.
+var bashCompletionScripts = []string{
+	"/usr/share/bash-completion/bash_completion",
+	"/usr/local/share/bash-completion/bash_completion",
+	"/usr/local/etc/profile.d/bash_completion.sh",
+	"/opt/homebrew/etc/profile.d/bash_completion.sh",
+	"/etc/bash_completion",
+}
+
+// The script run by Bash to complete a command. The arguments are the number
+// of scripts to source, the scripts, and the words of the command.
+//
+// The first line of the output contains the options of the completion spec
+// given with -o, and the following lines the candidates. The script exits with
+// 1 if the command doesn't have a completion spec.
+const bashCompleteScript = `
+n=$1
+shift
+for ((i = 0; i < n; i++)); do
+  [[ -r $1 ]] && . "$1" >/dev/null 2>&1
+  shift
+done
+
+COMP_WORDS=("$@")
+COMP_CWORD=$(($# - 1))
+COMP_LINE="$*"
+COMP_POINT=${#COMP_LINE}
+COMP_TYPE=9
+COMP_KEY=9
+cmd=${COMP_WORDS[0]}
+cur=${COMP_WORDS[COMP_CWORD]}
+prev=${COMP_WORDS[COMP_CWORD-1]}
+
+spec=$(complete -p -- "$cmd" 2>/dev/null)
+if [[ -z $spec ]]; then
+  if declare -F __load_completion >/dev/null; then
+    __load_completion "$cmd" >/dev/null 2>&1
+  elif declare -F _completion_loader >/dev/null; then
+    _completion_loader "$cmd" >/dev/null 2>&1
+  fi
+  spec=$(complete -p -- "$cmd" 2>/dev/null)
+fi
+# _minimal is the fallback of the loader of bash-completion.
+[[ -z $spec || $spec == *" -F _minimal "* ]] && exit 1
+
+eval "args=(${spec#complete })"
+fn= opts=() compgen_args=()
+for ((i = 0; i < ${#args[@]} - 1; i++)); do
+  case ${args[i]} in
+    --) ;;
+    -F) fn=${args[++i]} ;;
+    -o) opts+=("${args[++i]}") ;;
+    -[AGWXPSC]) compgen_args+=("${args[i]}" "${args[++i]}") ;;
+    *) compgen_args+=("${args[i]}") ;;
+  esac
+done
+
+words=()
+if ((${#compgen_args[@]})); then
+  while IFS= read -r word; do
+    words+=("$word")
+  done < <(compgen "${compgen_args[@]}" -- "$cur" 2>/dev/null)
+fi
+COMPREPLY=()
+if [[ -n $fn ]]; then
+  "$fn" "$cmd" "$cur" "$prev" >/dev/null 2>&1
+fi
+echo "${opts[*]}"
+for word in "${words[@]}" "${COMPREPLY[@]}"; do
+  echo "$word"
+done
+`
+
+// Completes arguments of commands with the completion scripts of Bash.
+type bashCompleter struct {
+	enabled vars.PtrVar
+	scripts vars.PtrVar
+
+	mutex sync.Mutex
+	// Commands known not to have a Bash completion, keyed by the command and
+	// the scripts joined with NUL characters.
+	noSpec map[string]bool
+}
+
+func newBashCompleter() *bashCompleter {
+	var scripts []interface{}
+	for _, script := range bashCompletionScripts {
+		if _, err := os.Stat(script); err == nil {
+			scripts = append(scripts, script)
+			break
+		}
+	}
+	return &bashCompleter{
+		enabled: newBoolVar(true),
+		scripts: newListVar(vals.MakeList(scripts...)),
+		noSpec:  map[string]bool{},
+	}
+}
+
+// Generates candidates for the last argument. If the command doesn't have a
+// Bash completion, returns false instead.
+func (bc *bashCompleter) generate(args []string) ([]complete.RawItem, bool, error) {
+	var scripts []string
+	err := vals.Iterate(bc.scripts.Get(), func(v interface{}) bool {
+		scripts = append(scripts, vals.ToString(v))
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	key := strings.Join(append([]string{args[0]}, scripts...), "\x00")
+	bc.mutex.Lock()
+	noSpec := bc.noSpec[key]
+	bc.mutex.Unlock()
+	if noSpec {
+		return nil, false, nil
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return nil, false, nil
+	}
+	cmdArgs := []string{"--norc", "--noprofile", "-c", bashCompleteScript,
+		"elvish", strconv.Itoa(len(scripts))}
+	cmdArgs = append(cmdArgs, scripts...)
+	cmdArgs = append(cmdArgs, args...)
+	output, err := runBash(bash, cmdArgs)
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		bc.mutex.Lock()
+		bc.noSpec[key] = true
+		bc.mutex.Unlock()
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	opts := map[string]bool{}
+	for _, opt := range strings.Fields(lines[0]) {
+		opts[opt] = true
+	}
+	cands := lines[1:]
+	if len(cands) == 0 {
+		seed := args[len(args)-1]
+		switch {
+		case opts["default"] || opts["bashdefault"]:
+			items, err := complete.GenerateFileNames([]string{seed})
+			return items, true, err
+		case opts["dirnames"]:
+			items, err := generateDirNames(seed)
+			return items, true, err
+		}
+	}
+	items := make([]complete.RawItem, len(cands))
+	for i, cand := range cands {
+		item := complete.ComplexItem{Stem: cand, CodeSuffix: " "}
+		if opts["filenames"] {
+			if info, err := os.Stat(cand); err == nil && info.IsDir() {
+				item = complete.ComplexItem{Stem: cand + "/"}
+			}
+		}
+		if opts["nospace"] {
+			item.CodeSuffix = ""
+		}
+		items[i] = item
+	}
+	return items, true, nil
+}
+
+// Runs Bash and returns its output. The process is killed when Elvish is
+// interrupted.
+func runBash(bash string, args []string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(bash, args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	intCh, cleanup := eval.ListenInterrupts()
+	defer cleanup()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return stdout.String(), err
+	case <-intCh:
+		cmd.Process.Kill()
+		<-done
+		return "", eval.ErrInterrupted
+	}
+}
+
+func (bc *bashCompleter) completeBash(args []string) ([]complete.RawItem, error) {
+	if len(args) == 0 {
+		return nil, errs.ArityMismatch{
+			What: "arguments", ValidLow: 1, ValidHigh: -1, Actual: 0}
+	}
+	items, ok, err := bc.generate(args)
+	if !ok && err == nil {
+		return complete.GenerateFileNames(args)
+	}
+	return items, err
+}
@@ -0,0 +1,90 @@
+package edit
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+var bashScript = `
+_foo() { COMPREPLY=($(compgen -W "alpha beta $3" -- "$2")); }
+complete -F _foo foo
+complete -o nospace -W "x1 x2" bar
+_none() { :; }
+complete -o default -F _none baz
+complete -o filenames -W "d f" quux
+`
+
+func setupBashCompletion(t *testing.T) *fixture {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	f := setup()
+	testutil.ApplyDir(testutil.Dir{
+		"completion.bash": bashScript,
+		"d":               testutil.Dir{},
+		"f":               "",
+	})
+	evals(f.Evaler,
+		`edit:completion:bash:scripts = [completion.bash]`,
+		`fn c [@a]{ put [(edit:complete-bash $@a)] }`)
+	return f
+}
+
+func TestCompleteBash(t *testing.T) {
+	f := setupBashCompletion(t)
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`fn-words = (c foo a)`,
+		`fn-prev = (c foo prev '')`,
+		`word-list = (c bar '')`,
+		`default = (c baz '')`,
+		`filenames = (c quux '')`,
+		`no-spec = (c qux '')`)
+	testGlobals(t, f.Evaler, map[string]interface{}{
+		"fn-words": vals.MakeList(complexItem{Stem: "alpha", CodeSuffix: " "}),
+		"fn-prev": vals.MakeList(
+			complexItem{Stem: "alpha", CodeSuffix: " "},
+			complexItem{Stem: "beta", CodeSuffix: " "},
+			complexItem{Stem: "prev", CodeSuffix: " "}),
+		"word-list": vals.MakeList(
+			complexItem{Stem: "x1"}, complexItem{Stem: "x2"}),
+		"filenames": vals.MakeList(
+			complexItem{Stem: "d/"}, complexItem{Stem: "f", CodeSuffix: " "}),
+	})
+	// Falling back to completing filenames.
+	for _, name := range []string{"default", "no-spec"} {
+		if n := getGlobal(f.Evaler, name).(vals.List).Len(); n != 3 {
+			t.Errorf("$%s has %d elements, want 3", name, n)
+		}
+	}
+}
+
+func TestCompletionAddon_UsesBashCompletion(t *testing.T) {
+	f := setupBashCompletion(t)
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "foo \t")
+	f.TestTTY(t,
+		"~> foo alpha \n", Styles,
+		"   !!! ______",
+		" COMPLETING argument  ", Styles,
+		"********************* ", term.DotHere, "\n",
+		"alpha  beta  foo", Styles,
+		"+++++           ",
+	)
+}
+
+func TestCompletionAddon_BashCompletionDisabled(t *testing.T) {
+	f := setupBashCompletion(t)
+	defer f.Cleanup()
+	evals(f.Evaler, `edit:completion:bash:enabled = $false`)
+
+	feedInput(f.TTYCtrl, "foo c\t")
+	f.TestTTY(t, "~> foo completion.bash ", Styles,
+		"   !!!                 ", term.DotHere)
+}
@@ -0,0 +1,665 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/edit/complete"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+//elvdoc:fn complete-spec
+//
+// ```elvish
+// edit:complete-spec $name $spec
+// ```
+//
+// Registers a declarative specification of the flags, subcommands and
+// arguments accepted by the command `$name`, replacing any spec registered
+// before. The same spec is used for several purposes:
+//
+// -   When completing an argument of `$name` and
+//     [`$edit:completion:arg-completer`](#editcompletionarg-completer) has no
+//     entry for `$name`, candidates are generated from the spec: flags after
+//     `-`, subcommands, and arguments according to their types.
+//
+// -   Flags that are not defined in the spec are highlighted as errors when
+//     typing a command.
+//
+// -   [`edit:spec-help`](#editspec-help) renders a help summary from the spec.
+//
+// `$spec` is a map with the following keys, all of which are optional:
+//
+// -   `desc`: A description of the command.
+//
+// -   `flags`: A list of maps, each describing a flag with the following keys:
+//
+//     -   `short`: The single-character short form, without the dash.
+//
+//     -   `long`: The long form, without the two dashes. At least one of
+//         `short` and `long` must be given.
+//
+//     -   `desc`: A description of the flag.
+//
+//     -   `arg`: If given, the flag takes an argument of this type.
+//
+// -   `args`: A list of the types of positional arguments. If its last element
+//     is the string `...`, the type before it is used for all following
+//     arguments. Defaults to `[file ...]`.
+//
+// -   `subcommands`: A map from the names of subcommands to their specs, which
+//     have the same format. Flags of a command are also accepted after its
+//     subcommands.
+//
+// The type of an argument is one of the following:
+//
+// -   `file`: A filename.
+//
+// -   `dir`: A directory name.
+//
+// -   `none`: An argument that is not completed.
+//
+// -   A list of strings, the possible values of the argument.
+//
+// -   A function, which is called with the argument being completed, and outputs
+//     the candidates in the same way as an [argument
+//     completer](#argument-completer).
+//
+// Example:
+//
+// ```elvish
+// edit:complete-spec pkg [
+//   &desc='Manage packages'
+//   &flags=[[&short=v &long=verbose &desc='Show more output']
+//           [&short=C &long=root &arg=dir &desc='Use this root directory']]
+//   &subcommands=[
+//     &install=[&desc='Install packages' &args=[{ pkg list-available } ...]]
+//     &remove=[&desc='Remove packages' &args=[{ pkg list-installed } ...]]
+//     &search=[&desc='Search for packages' &args=[none]]
+//   ]
+// ]
+// ```
+//
+// @cf edit:complete-getopt
+
+//elvdoc:fn spec-help
+//
+// ```elvish
+// edit:spec-help $name $subcommand...
+// ```
+//
+// Outputs a help summary of the command `$name`, or one of its subcommands,
+// from the spec registered with [`edit:complete-spec`](#editcomplete-spec).
+// This can be used to implement `--help` for a wrapper function.
+//
+// ```elvish-transcript
+// ~> print (edit:spec-help pkg)
+// Usage: pkg [flags] <subcommand> [args...]
+//
+// Manage packages
+//
+// Flags:
+//   -v, --verbose
+//         Show more output
+//   -C, --root dir
+//         Use this root directory
+//
+// Subcommands:
+//   install  Install packages
+//   remove   Remove packages
+//   search   Search for packages
+// ~> print (edit:spec-help pkg search)
+// Usage: pkg search [flags] [args...]
+//
+// Search for packages
+//
+// Flags:
+//   -v, --verbose
+//         Show more output
+//   -C, --root dir
+//         Use this root directory
+// ```
+
+// A registry of command specs.
+type cmdSpecs struct {
+	mutex sync.RWMutex
+	specs map[string]*cmdSpec
+}
+
+func newCmdSpecs() *cmdSpecs { return &cmdSpecs{specs: map[string]*cmdSpec{}} }
+
+func (cs *cmdSpecs) get(name string) (*cmdSpec, bool) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	spec, ok := cs.specs[strings.TrimPrefix(name, "e:")]
+	return spec, ok
+}
+
+func (cs *cmdSpecs) set(name string, spec *cmdSpec) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.specs[name] = spec
+}
+
+type cmdSpec struct {
+	desc     string
+	flags    []*specFlag
+	args     []*specArg
+	variadic bool
+	subcmds  map[string]*cmdSpec
+}
+
+type specFlag struct {
+	short rune
+	long  string
+	desc  string
+	// The type of the argument of the flag, or nil if it doesn't take one.
+	arg *specArg
+}
+
+type specArg struct {
+	// One of "file", "dir", "none", "choices" and "fn".
+	kind    string
+	choices []string
+	fn      eval.Callable
+}
+
+var fileArgs = []*specArg{{kind: "file"}}
+
+var errFlagWithoutName = errors.New("flag spec should have at least one of short and long")
+
+func parseCmdSpec(v interface{}) (*cmdSpec, error) {
+	spec := &cmdSpec{args: fileArgs, variadic: true}
+	var errField error
+	err := vals.IterateKeys(v, func(k interface{}) bool {
+		var field interface{}
+		field, errField = vals.Index(v, k)
+		if errField != nil {
+			return false
+		}
+		switch k {
+		case "desc":
+			errField = vals.ScanToGo(field, &spec.desc)
+		case "flags":
+			spec.flags = nil
+			errField = iterateSpecList(field, func(v interface{}) error {
+				flag, err := parseSpecFlag(v)
+				spec.flags = append(spec.flags, flag)
+				return err
+			})
+		case "args":
+			spec.args, spec.variadic = nil, false
+			errField = iterateSpecList(field, func(v interface{}) error {
+				if v == "..." {
+					spec.variadic = true
+					return nil
+				}
+				if spec.variadic {
+					return errors.New("... must be the last element of args")
+				}
+				arg, err := parseSpecArg(v)
+				spec.args = append(spec.args, arg)
+				return err
+			})
+			if errField == nil && spec.variadic && len(spec.args) == 0 {
+				errField = errors.New("... must follow an argument type")
+			}
+		case "subcommands":
+			spec.subcmds = map[string]*cmdSpec{}
+			var errSub error
+			errField = vals.IterateKeys(field, func(name interface{}) bool {
+				var sub interface{}
+				sub, errSub = vals.Index(field, name)
+				if errSub == nil {
+					spec.subcmds[vals.ToString(name)], errSub = parseCmdSpec(sub)
+				}
+				return errSub == nil
+			})
+			if errField == nil {
+				errField = errSub
+			}
+		default:
+			errField = errs.BadValue{What: "key of command spec",
+				Valid: "desc, flags, args or subcommands", Actual: vals.Repr(k, vals.NoPretty)}
+		}
+		return errField == nil
+	})
+	if err == nil {
+		err = errField
+	}
+	return spec, err
+}
+
+func iterateSpecList(v interface{}, f func(interface{}) error) error {
+	var errElem error
+	err := vals.Iterate(v, func(elem interface{}) bool {
+		errElem = f(elem)
+		return errElem == nil
+	})
+	if err == nil {
+		err = errElem
+	}
+	return err
+}
+
+func parseSpecFlag(v interface{}) (*specFlag, error) {
+	flag := &specFlag{}
+	var errField error
+	err := vals.IterateKeys(v, func(k interface{}) bool {
+		var field interface{}
+		field, errField = vals.Index(v, k)
+		if errField != nil {
+			return false
+		}
+		switch k {
+		case "short":
+			var s string
+			errField = vals.ScanToGo(field, &s)
+			r, size := utf8.DecodeRuneInString(s)
+			if errField == nil && (r == utf8.RuneError || size != len(s)) {
+				errField = fmt.Errorf(
+					"short flag should be exactly one rune, got %v", parse.Quote(s))
+			}
+			flag.short = r
+		case "long":
+			errField = vals.ScanToGo(field, &flag.long)
+		case "desc":
+			errField = vals.ScanToGo(field, &flag.desc)
+		case "arg":
+			flag.arg, errField = parseSpecArg(field)
+		default:
+			errField = errs.BadValue{What: "key of flag spec",
+				Valid: "short, long, desc or arg", Actual: vals.Repr(k, vals.NoPretty)}
+		}
+		return errField == nil
+	})
+	if err == nil {
+		err = errField
+	}
+	if err == nil && flag.short == 0 && flag.long == "" {
+		err = errFlagWithoutName
+	}
+	return flag, err
+}
+
+func parseSpecArg(v interface{}) (*specArg, error) {
+	switch v := v.(type) {
+	case string:
+		switch v {
+		case "file", "dir", "none":
+			return &specArg{kind: v}, nil
+		}
+	case eval.Callable:
+		return &specArg{kind: "fn", fn: v}, nil
+	case vals.List:
+		var choices []string
+		err := vals.Iterate(v, func(v interface{}) bool {
+			choices = append(choices, vals.ToString(v))
+			return true
+		})
+		return &specArg{kind: "choices", choices: choices}, err
+	}
+	return nil, errs.BadValue{What: "argument type",
+		Valid: "file, dir, none, list or fn", Actual: vals.Repr(v, vals.NoPretty)}
+}
+
+// The state after walking some arguments of a command according to its spec.
+type specWalk struct {
+	// The specs of the command and the subcommands found, outermost first.
+	specs []*cmdSpec
+	// The names of the subcommands found.
+	subcmds []string
+	// The number of positional arguments after the last subcommand.
+	npos int
+	// Whether "--" has been seen.
+	flagsDone bool
+	// If not nil, the next argument is the argument of this flag.
+	flagArg *specFlag
+}
+
+func (w *specWalk) spec() *cmdSpec { return w.specs[len(w.specs)-1] }
+
+// Looks up a flag in the innermost spec first.
+func (w *specWalk) findFlag(match func(*specFlag) bool) *specFlag {
+	for i := len(w.specs) - 1; i >= 0; i-- {
+		for _, flag := range w.specs[i].flags {
+			if match(flag) {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+// Walks the arguments of a command, not including the command name. If badFlag
+// is not nil, it is called with the index of each argument containing an
+// undefined flag.
+func (spec *cmdSpec) walk(args []string, badFlag func(i int, flag string)) *specWalk {
+	w := &specWalk{specs: []*cmdSpec{spec}}
+	reportBad := func(i int, flag string) {
+		if badFlag != nil {
+			badFlag(i, flag)
+		}
+	}
+	for i, arg := range args {
+		switch {
+		case w.flagArg != nil:
+			w.flagArg = nil
+		case !w.flagsDone && arg == "--":
+			w.flagsDone = true
+		case !w.flagsDone && strings.HasPrefix(arg, "--"):
+			name, hasValue := arg[2:], false
+			if eq := strings.IndexByte(name, '='); eq != -1 {
+				name, hasValue = name[:eq], true
+			}
+			flag := w.findFlag(func(f *specFlag) bool { return f.long == name })
+			if flag == nil {
+				reportBad(i, "--"+name)
+			} else if flag.arg != nil && !hasValue {
+				w.flagArg = flag
+			}
+		case !w.flagsDone && len(arg) > 1 && arg[0] == '-':
+			for j, r := range arg[1:] {
+				flag := w.findFlag(func(f *specFlag) bool { return f.short == r })
+				if flag == nil {
+					reportBad(i, "-"+string(r))
+					break
+				}
+				if flag.arg != nil {
+					// The rest of the argument, if any, is the argument of the
+					// flag.
+					if 1+j+utf8.RuneLen(r) == len(arg) {
+						w.flagArg = flag
+					}
+					break
+				}
+			}
+		default:
+			if sub, ok := w.spec().subcmds[arg]; ok && w.npos == 0 {
+				w.specs = append(w.specs, sub)
+				w.subcmds = append(w.subcmds, arg)
+				continue
+			}
+			w.npos++
+		}
+	}
+	return w
+}
+
+// Generates candidates for the last argument of a command, not including the
+// command name.
+func (spec *cmdSpec) complete(ev *eval.Evaler, args []string) ([]complete.RawItem, error) {
+	w := spec.walk(args[:len(args)-1], nil)
+	seed := args[len(args)-1]
+	if w.flagArg != nil {
+		return w.flagArg.arg.generate(ev, seed)
+	}
+	if !w.flagsDone && strings.HasPrefix(seed, "-") {
+		if eq := strings.IndexByte(seed, '='); strings.HasPrefix(seed, "--") && eq != -1 {
+			// Complete the argument in --flag=arg.
+			name := seed[2:eq]
+			flag := w.findFlag(func(f *specFlag) bool { return f.long == name })
+			if flag == nil || flag.arg == nil {
+				return nil, nil
+			}
+			items, err := flag.arg.generate(ev, seed[eq+1:])
+			return prefixItems(seed[:eq+1], items), err
+		}
+		var items []complete.RawItem
+		for i := len(w.specs) - 1; i >= 0; i-- {
+			for _, flag := range w.specs[i].flags {
+				if flag.short != 0 {
+					items = append(items, flagItem("-"+string(flag.short), flag))
+				}
+				if flag.long != "" {
+					items = append(items, flagItem("--"+flag.long, flag))
+				}
+			}
+		}
+		return items, nil
+	}
+	if subcmds := w.spec().subcmds; len(subcmds) > 0 && w.npos == 0 {
+		var items []complete.RawItem
+		for _, name := range sortedSubcmds(subcmds) {
+			item := complete.ComplexItem{Stem: name, CodeSuffix: " "}
+			if desc := subcmds[name].desc; desc != "" {
+				item.Display = name + " (" + desc + ")"
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	argTypes := w.spec().args
+	switch {
+	case w.npos < len(argTypes):
+		return argTypes[w.npos].generate(ev, seed)
+	case w.spec().variadic:
+		return argTypes[len(argTypes)-1].generate(ev, seed)
+	}
+	return nil, nil
+}
+
+func flagItem(stem string, flag *specFlag) complete.RawItem {
+	item := complete.ComplexItem{Stem: stem}
+	if flag.desc != "" {
+		item.Display = stem + " (" + flag.desc + ")"
+	}
+	return item
+}
+
+func prefixItems(prefix string, items []complete.RawItem) []complete.RawItem {
+	prefixed := make([]complete.RawItem, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case complete.ComplexItem:
+			item.Stem = prefix + item.Stem
+			if item.Display != "" {
+				item.Display = prefix + item.Display
+			}
+			prefixed[i] = item
+		default:
+			prefixed[i] = complete.PlainItem(prefix + item.String())
+		}
+	}
+	return prefixed
+}
+
+func (arg *specArg) generate(ev *eval.Evaler, seed string) ([]complete.RawItem, error) {
+	switch arg.kind {
+	case "file":
+		return complete.GenerateFileNames([]string{seed})
+	case "dir":
+		return generateDirNames(seed)
+	case "choices":
+		items := make([]complete.RawItem, len(arg.choices))
+		for i, choice := range arg.choices {
+			items[i] = complete.PlainItem(choice)
+		}
+		return items, nil
+	case "fn":
+		return callArgGenerator(ev, arg.fn, []interface{}{seed})
+	default:
+		return nil, nil
+	}
+}
+
+// Generates the names of the directories that complete seed.
+func generateDirNames(seed string) ([]complete.RawItem, error) {
+	items, err := complete.GenerateFileNames([]string{seed})
+	var dirs []complete.RawItem
+	for _, item := range items {
+		if strings.HasSuffix(item.String(), string(filepath.Separator)) {
+			dirs = append(dirs, item)
+		}
+	}
+	return dirs, err
+}
+
+func sortedSubcmds(subcmds map[string]*cmdSpec) []string {
+	names := make([]string, 0, len(subcmds))
+	for name := range subcmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Renders a help summary. The name includes the names of the subcommands.
+func (spec *cmdSpec) help(name string, parents []*cmdSpec) string {
+	var sb strings.Builder
+	sb.WriteString("Usage: " + name + " [flags]")
+	if len(spec.subcmds) > 0 {
+		sb.WriteString(" <subcommand>")
+	}
+	sb.WriteString(" [args...]\n")
+	if spec.desc != "" {
+		sb.WriteString("\n" + spec.desc + "\n")
+	}
+
+	var flags []*specFlag
+	flags = append(flags, spec.flags...)
+	for i := len(parents) - 1; i >= 0; i-- {
+		flags = append(flags, parents[i].flags...)
+	}
+	if len(flags) > 0 {
+		sb.WriteString("\nFlags:\n")
+		for _, flag := range flags {
+			sb.WriteString("  ")
+			switch {
+			case flag.short != 0 && flag.long != "":
+				sb.WriteString("-" + string(flag.short) + ", --" + flag.long)
+			case flag.short != 0:
+				sb.WriteString("-" + string(flag.short))
+			default:
+				sb.WriteString("--" + flag.long)
+			}
+			if flag.arg != nil {
+				sb.WriteString(" " + flag.arg.String())
+			}
+			sb.WriteString("\n")
+			if flag.desc != "" {
+				sb.WriteString("        " + flag.desc + "\n")
+			}
+		}
+	}
+
+	if len(spec.subcmds) > 0 {
+		sb.WriteString("\nSubcommands:\n")
+		names := sortedSubcmds(spec.subcmds)
+		width := 0
+		for _, name := range names {
+			if w := utf8.RuneCountInString(name); w > width {
+				width = w
+			}
+		}
+		for _, name := range names {
+			line := "  " + name
+			if desc := spec.subcmds[name].desc; desc != "" {
+				line += strings.Repeat(" ", width-utf8.RuneCountInString(name)+2) + desc
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// String returns the name of the type shown in help summaries.
+func (arg *specArg) String() string {
+	switch arg.kind {
+	case "choices":
+		return strings.Join(arg.choices, "|")
+	case "fn", "none":
+		return "arg"
+	default:
+		return arg.kind
+	}
+}
+
+func initCompleteSpec(ev *eval.Evaler, specs *cmdSpecs, nb eval.NsBuilder) {
+	nb.AddGoFns("<edit>", map[string]interface{}{
+		"complete-spec": func(name string, v interface{}) error {
+			spec, err := parseCmdSpec(v)
+			if err != nil {
+				return err
+			}
+			specs.set(name, spec)
+			return nil
+		},
+		"spec-help": func(name string, subcmds ...string) (string, error) {
+			spec, ok := specs.get(name)
+			if !ok {
+				return "", fmt.Errorf("no spec registered for %s", parse.Quote(name))
+			}
+			var parents []*cmdSpec
+			for _, subcmd := range subcmds {
+				sub, ok := spec.subcmds[subcmd]
+				if !ok {
+					return "", fmt.Errorf("no subcommand %s in spec of %s",
+						parse.Quote(subcmd), parse.Quote(name))
+				}
+				parents = append(parents, spec)
+				spec = sub
+				name += " " + subcmd
+			}
+			return spec.help(name, parents), nil
+		},
+	})
+}
+
+// Finds arguments containing flags not defined in the specs of the commands
+// they are passed to. Arguments that end at the end of the code are skipped,
+// since they may still be being typed.
+func checkSpecFlags(ev *eval.Evaler, specs *cmdSpecs, tree parse.Tree) error {
+	var errors []error
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		if form, ok := n.(*parse.Form); ok && form.Head != nil {
+			errors = append(errors, checkFormFlags(ev, specs, tree.Source, form)...)
+		}
+		for _, child := range parse.Children(n) {
+			walk(child)
+		}
+	}
+	walk(tree.Root)
+	return diag.Errors(errors...)
+}
+
+func checkFormFlags(ev *eval.Evaler, specs *cmdSpecs, src parse.Source, form *parse.Form) []error {
+	name, err := ev.PurelyEvalCompound(form.Head)
+	if err != nil {
+		return nil
+	}
+	spec, ok := specs.get(name)
+	if !ok {
+		return nil
+	}
+	var args []string
+	for _, cn := range form.Args {
+		arg, err := ev.PurelyEvalCompound(cn)
+		if err != nil {
+			// The rest of the arguments can't be interpreted reliably.
+			break
+		}
+		args = append(args, arg)
+	}
+	var errors []error
+	spec.walk(args, func(i int, flag string) {
+		r := form.Args[i].Range()
+		if r.To == len(src.Code) {
+			return
+		}
+		errors = append(errors, &diag.Error{
+			Type: "flag error", Message: "undefined flag " + flag,
+			Context: *diag.NewContext(src.Name, src.Code, r)})
+	})
+	return errors
+}
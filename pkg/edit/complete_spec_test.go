@@ -0,0 +1,207 @@
+package edit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/edit/complete"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+var pkgSpec = `edit:complete-spec pkg [
+  &desc='Manage packages'
+  &flags=[[&short=v &long=verbose &desc='Show more output']
+          [&short=C &long=root &arg=dir &desc='Use this root directory']
+          [&long=color &arg=[always never]]]
+  &subcommands=[
+    &install=[&desc='Install packages' &args=[[_]{ put foo bar } ...]
+              &flags=[[&short=n &long=dry-run]]]
+    &search=[&desc='Search for packages' &args=[none]]
+  ]
+]`
+
+func TestCompleteSpec_Complete(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"d": testutil.Dir{}, "f": ""})
+	evals(f.Evaler, pkgSpec)
+	spec, ok := f.Editor.specs.get("pkg")
+	if !ok {
+		t.Fatalf("spec of pkg not registered")
+	}
+
+	tests := []struct {
+		args      []string
+		wantItems []complete.RawItem
+	}{
+		{[]string{""}, []complete.RawItem{
+			complete.ComplexItem{Stem: "install", CodeSuffix: " ",
+				Display: "install (Install packages)"},
+			complete.ComplexItem{Stem: "search", CodeSuffix: " ",
+				Display: "search (Search for packages)"},
+		}},
+		{[]string{"-"}, []complete.RawItem{
+			complete.ComplexItem{Stem: "-v", Display: "-v (Show more output)"},
+			complete.ComplexItem{Stem: "--verbose", Display: "--verbose (Show more output)"},
+			complete.ComplexItem{Stem: "-C", Display: "-C (Use this root directory)"},
+			complete.ComplexItem{Stem: "--root", Display: "--root (Use this root directory)"},
+			complete.ComplexItem{Stem: "--color"},
+		}},
+		// Flags of subcommands come first.
+		{[]string{"install", "-"}, []complete.RawItem{
+			complete.ComplexItem{Stem: "-n"},
+			complete.ComplexItem{Stem: "--dry-run"},
+			complete.ComplexItem{Stem: "-v", Display: "-v (Show more output)"},
+			complete.ComplexItem{Stem: "--verbose", Display: "--verbose (Show more output)"},
+			complete.ComplexItem{Stem: "-C", Display: "-C (Use this root directory)"},
+			complete.ComplexItem{Stem: "--root", Display: "--root (Use this root directory)"},
+			complete.ComplexItem{Stem: "--color"},
+		}},
+		{[]string{"--color", ""}, []complete.RawItem{
+			complete.PlainItem("always"), complete.PlainItem("never")}},
+		{[]string{"--color="}, []complete.RawItem{
+			complete.PlainItem("--color=always"), complete.PlainItem("--color=never")}},
+		{[]string{"-v", "install", "a", ""}, []complete.RawItem{
+			complete.PlainItem("foo"), complete.PlainItem("bar")}},
+		{[]string{"search", "x", ""}, nil},
+		// After --, arguments starting with - are not flags.
+		{[]string{"install", "--", "-"}, []complete.RawItem{
+			complete.PlainItem("foo"), complete.PlainItem("bar")}},
+	}
+	for _, test := range tests {
+		items, err := spec.complete(f.Evaler, test.args)
+		if err != nil {
+			t.Errorf("complete(%q) -> error %v", test.args, err)
+		}
+		if !reflect.DeepEqual(items, test.wantItems) {
+			t.Errorf("complete(%q) -> %#v, want %#v", test.args, items, test.wantItems)
+		}
+	}
+
+	// Only directories are completed for arguments of type dir.
+	items, err := spec.complete(f.Evaler, []string{"-C", ""})
+	if len(items) != 1 || items[0].String() != "d/" || err != nil {
+		t.Errorf("complete(-C '') -> %v, %v, want [d/], nil", items, err)
+	}
+}
+
+func TestCompleteSpec_ArgCompleterTakesPrecedence(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler, pkgSpec,
+		`edit:completion:arg-completer[pkg] = [@_]{ put from-arg-completer }`)
+
+	feedInput(f.TTYCtrl, "pkg \t")
+	f.TestTTY(t, "~> pkg from-arg-completer", Styles,
+		"   !!!", term.DotHere)
+}
+
+func TestCompleteSpec_BadSpec(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`bad-key = ?(edit:complete-spec x [&foo=bar])`,
+		`flag-without-name = ?(edit:complete-spec x [&flags=[[&desc=foo]]])`,
+		`bad-short = ?(edit:complete-spec x [&flags=[[&short=ab]]])`,
+		`bad-arg = ?(edit:complete-spec x [&args=[bad]])`,
+		`bad-variadic = ?(edit:complete-spec x [&args=[... file]])`,
+		`bad-sub = ?(edit:complete-spec x [&subcommands=[&a=[&foo=bar]]])`,
+		`good = ?(edit:complete-spec x [&args=[file dir none [a b] [_]{ } ...]])`)
+	for _, name := range []string{"bad-key", "flag-without-name", "bad-short",
+		"bad-arg", "bad-variadic", "bad-sub"} {
+		if getGlobal(f.Evaler, name) == nil {
+			t.Errorf("$%s is nil, want an exception", name)
+		}
+	}
+	testGlobal(t, f.Evaler, "good", eval.OK)
+}
+
+func TestSpecHelp(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, pkgSpec,
+		`help = (edit:spec-help pkg)`,
+		`install-help = (edit:spec-help pkg install)`,
+		`no-spec = ?(edit:spec-help foo)`,
+		`no-subcmd = ?(edit:spec-help pkg foo)`)
+	testGlobals(t, f.Evaler, map[string]interface{}{
+		"help": `Usage: pkg [flags] <subcommand> [args...]
+
+Manage packages
+
+Flags:
+  -v, --verbose
+        Show more output
+  -C, --root dir
+        Use this root directory
+  --color always|never
+
+Subcommands:
+  install  Install packages
+  search   Search for packages
+`,
+		"install-help": `Usage: pkg install [flags] [args...]
+
+Install packages
+
+Flags:
+  -n, --dry-run
+  -v, --verbose
+        Show more output
+  -C, --root dir
+        Use this root directory
+  --color always|never
+`,
+	})
+	for _, name := range []string{"no-spec", "no-subcmd"} {
+		if getGlobal(f.Evaler, name) == nil {
+			t.Errorf("$%s is nil, want an exception", name)
+		}
+	}
+}
+
+func TestCheckSpecFlags(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler, pkgSpec)
+	specs := f.Editor.specs
+
+	tests := []struct {
+		code    string
+		wantErr string
+	}{
+		{"pkg -v --root / install -n foo", ""},
+		{"pkg --color=never -vC / search x", ""},
+		{"pkg -- -x y", ""},
+		{"other -x y", ""},
+		// Flags still being typed are not checked.
+		{"pkg --ver", ""},
+		{"pkg --ver x", "undefined flag --ver"},
+		{"pkg -vx y", "undefined flag -x"},
+		{"pkg search -n x", "undefined flag -n"},
+		{"put (pkg --bad x)", "undefined flag --bad"},
+	}
+	for _, test := range tests {
+		err := checkSpecFlags(f.Evaler, specs, mustParse(test.code))
+		switch {
+		case test.wantErr == "" && err != nil:
+			t.Errorf("checkSpecFlags(%q) -> %v, want no error", test.code, err)
+		case test.wantErr != "" && (err == nil || !hasMessage(err, test.wantErr)):
+			t.Errorf("checkSpecFlags(%q) -> %v, want %q", test.code, err, test.wantErr)
+		}
+	}
+}
+
+func hasMessage(err error, msg string) bool {
+	for _, e := range diag.UnpackErrors(err) {
+		if e, ok := e.(*diag.Error); ok && e.Message == msg {
+			return true
+		}
+	}
+	return false
+}
@@ -16,12 +16,16 @@ import (
 	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/strutil"
+	"github.com/elves/elvish/pkg/ui"
 	"github.com/xiaq/persistent/hash"
 )
 
 //elvdoc:var completion:arg-completer
 //
 // A map containing argument completers.
+//
+// Argument completers can't write files or change the environment; trying to
+// do so throws an exception. They can still run external commands.
 
 //elvdoc:var completion:binding
 //
@@ -29,8 +33,30 @@ import (
 
 //elvdoc:var completion:matcher
 //
-// A map mapping from context names to matcher functions. See the
-// [Matcher](#matcher) section.
+// A map mapping from context names to matcher functions. Besides the completion
+// types, the keys `histlist` and `location` specify the matchers of the history
+// and location modes. See the [Matcher](#matcher) section.
+//
+// Like argument completers, matchers can't write files or change the
+// environment.
+
+//elvdoc:var completion:filename:ignore
+//
+// A list of patterns of files that are left out when completing filenames,
+// using the syntax of `.gitignore` files. Defaults to an empty list.
+//
+// A pattern matches the name of a file, unless it contains a slash that is not
+// at the end, in which case it matches the path relative to the working
+// directory, and `**` matches any number of directories. A pattern ending with
+// a slash only matches directories, and a pattern starting with `!` includes
+// the files that a previous pattern leaves out.
+//
+// Files left out are still completed when no other file in the same directory
+// starts with the name being completed.
+//
+// ```elvish
+// edit:completion:filename:ignore = ['*.o' '*~' node_modules/ '!keep.o']
+// ```
 
 //elvdoc:fn complete-filename
 //
@@ -42,7 +68,10 @@ import (
 // other arguments are ignored. If the last argument does not contain a path
 // (either absolute or relative to the current directory), then the current
 // directory is used. Relevant files are output as `edit:complex-candidate`
-// objects.
+// objects, styled according to `$E:LS_COLORS`; the names of directories end
+// with a path separator. Files matching
+// [`$edit:completion:filename:ignore`](#editcompletionfilenameignore) are left
+// out.
 //
 // This function is the default handler for any commands without
 // explicit handlers in `$edit:completion:arg-completer`. See [Argument
@@ -157,7 +186,7 @@ func complexCandidate(fm *eval.Frame, opts complexCandidateOpts, stem string) co
 // Starts the completion mode. However, if all the candidates share a non-empty
 // prefix and that prefix starts with the seed, inserts the prefix instead.
 
-func completionStart(app cli.App, binding cli.Handler, cfg complete.Config, smart bool) {
+func completionStart(app cli.App, binding cli.Handler, cfg complete.Config, filterFor func(string) cli.FilterFunc, smart bool) {
 	buf := app.CodeArea().CopyState().Buffer
 	result, err := complete.Complete(
 		complete.CodeBuffer{Content: buf.Content, Dot: buf.Dot}, cfg)
@@ -196,7 +225,7 @@ func completionStart(app cli.App, binding cli.Handler, cfg complete.Config, smar
 	}
 	completion.Start(app, completion.Config{
 		Name: result.Name, Replace: result.Replace, Items: result.Items,
-		Binding: binding})
+		Hint: result.Hint, Binding: binding, Filter: filterFor(result.Name)})
 }
 
 //elvdoc:fn completion:close
@@ -206,22 +235,42 @@ func completionStart(app cli.App, binding cli.Handler, cfg complete.Config, smar
 func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(EmptyBindingMap)
 	binding := newMapBinding(ed, ev, bindingVar)
-	matcherMapVar := newMapVar(vals.EmptyMap)
+	matcherMapVar := ed.matchers
 	argGeneratorMapVar := newMapVar(vals.EmptyMap)
+	bc := newBashCompleter()
+	ignoreVar := newListVar(vals.EmptyList)
+	filenameIgnore := func() []string {
+		var patterns []string
+		vals.Iterate(ignoreVar.Get(), func(v interface{}) bool {
+			patterns = append(patterns, vals.ToString(v))
+			return true
+		})
+		return patterns
+	}
+	generateFileNames := func(args []string) ([]complete.RawItem, error) {
+		return complete.FilenameGenerator(filenameIgnore())(args)
+	}
 	cfg := func() complete.Config {
 		return complete.Config{
-			PureEvaler: pureEvaler{ev},
+			PureEvaler: pureEvaler{ev, ed.cmdIndex},
 			Filterer: adaptMatcherMap(
 				ed, ev, matcherMapVar.Get().(vals.Map)),
 			ArgGenerator: adaptArgGeneratorMap(
-				ev, argGeneratorMapVar.Get().(vals.Map)),
+				ev, argGeneratorMapVar.Get().(vals.Map), ed.specs, bc,
+				generateFileNames),
+			FilenameIgnore: filenameIgnore(),
+			ArgHinter:      func(args []string) ui.Text { return signatureHint(ev, args) },
 		}
 	}
+	filterFor := func(name string) cli.FilterFunc {
+		return adaptMatcherForMode(ed, ev, matcherMapVar.Get().(vals.Map), name)
+	}
 	generateForSudo := func(args []string) ([]complete.RawItem, error) {
 		return complete.GenerateForSudo(cfg(), args)
 	}
 	nb.AddGoFns("<edit>", map[string]interface{}{
-		"complete-filename": wrapArgGenerator(complete.GenerateFileNames),
+		"complete-bash":     wrapArgGenerator(bc.completeBash),
+		"complete-filename": wrapArgGenerator(generateFileNames),
 		"complete-getopt":   completeGetopt,
 		"complete-sudo":     wrapArgGenerator(generateForSudo),
 		"complex-candidate": complexCandidate,
@@ -229,16 +278,22 @@ func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 		"match-subseq":      wrapMatcher(strutil.HasSubseq),
 		"match-substr":      wrapMatcher(strings.Contains),
 	})
+	initCompleteSpec(ev, ed.specs, nb)
 	app := ed.app
 	nb.AddNs("completion",
 		eval.NsBuilder{
 			"arg-completer": argGeneratorMapVar,
 			"binding":       bindingVar,
 			"matcher":       matcherMapVar,
-		}.AddGoFns("<edit:completion>:", map[string]interface{}{
+		}.AddNs("bash", eval.NsBuilder{
+			"enabled": bc.enabled,
+			"scripts": bc.scripts,
+		}.Ns()).AddNs("filename", eval.NsBuilder{
+			"ignore": ignoreVar,
+		}.Ns()).AddGoFns("<edit:completion>:", map[string]interface{}{
 			"accept":      func() { listingAccept(app) },
-			"smart-start": func() { completionStart(app, binding, cfg(), true) },
-			"start":       func() { completionStart(app, binding, cfg(), false) },
+			"smart-start": func() { completionStart(app, binding, cfg(), filterFor, true) },
+			"start":       func() { completionStart(app, binding, cfg(), filterFor, false) },
 			"close":       func() { completion.Close(app) },
 			"up":          func() { listingUp(app) },
 			"down":        func() { listingDown(app) },
@@ -249,6 +304,55 @@ func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 		}).Ns())
 }
 
+// Generates a hint from the signature of the command, if it is a user-defined
+// function that takes any arguments or options or has documentation. The
+// parameter receiving the argument being completed is highlighted, and the first
+// line of the documentation follows the signature.
+func signatureHint(ev *eval.Evaler, args []string) ui.Text {
+	fn, ok := lookupCmdFn(ev, args[0])
+	if !ok {
+		return nil
+	}
+	sig, ok := eval.SignatureOf(fn)
+	if !ok {
+		return nil
+	}
+	// Only show the first line of the documentation.
+	doc := strings.TrimSpace(sig.Doc)
+	if i := strings.IndexByte(doc, '\n'); i != -1 {
+		doc = doc[:i]
+	}
+	if len(sig.ArgNames) == 0 && len(sig.OptNames) == 0 {
+		if doc == "" {
+			return nil
+		}
+		return ui.T(args[0] + " - " + doc)
+	}
+	current := sig.ArgIndex(len(args) - 2)
+	hint := ui.T(args[0] + " [")
+	for i := range sig.ArgNames {
+		if i > 0 {
+			hint = append(hint, ui.T(" ")...)
+		}
+		if i == current {
+			hint = append(hint, ui.T(sig.ArgString(i), ui.Inverse)...)
+		} else {
+			hint = append(hint, ui.T(sig.ArgString(i))...)
+		}
+	}
+	for i := range sig.OptNames {
+		if i > 0 || len(sig.ArgNames) > 0 {
+			hint = append(hint, ui.T(" ")...)
+		}
+		hint = append(hint, ui.T(sig.OptString(i))...)
+	}
+	hint = append(hint, ui.T("]")...)
+	if doc != "" {
+		hint = append(hint, ui.T(" - "+doc)...)
+	}
+	return hint
+}
+
 // A wrapper type implementing Elvish value methods.
 type complexItem complete.ComplexItem
 
@@ -379,47 +483,14 @@ func adaptMatcherMap(nt notifier, ev *eval.Evaler, m vals.Map) complete.Filterer
 		if matcher == nil {
 			return complete.FilterPrefix(ctxName, seed, rawItems)
 		}
-		input := make(chan interface{})
-		stopInputFeeder := make(chan struct{})
-		defer close(stopInputFeeder)
-		// Feed a string representing all raw candidates to the input channel.
-		go func() {
-			defer close(input)
-			for _, rawItem := range rawItems {
-				select {
-				case input <- rawItem.String():
-				case <-stopInputFeeder:
-					return
-				}
-			}
-		}()
-
-		// TODO: Supply the Chan component of port 2.
-		port1, collect, err := eval.CapturePort()
-		if err != nil {
-			nt.notifyf("cannot create pipe to run completion matcher: %v", err)
-			return nil
-		}
-
-		err = ev.Call(matcher,
-			eval.CallCfg{Args: []interface{}{seed}, From: "[editor matcher]"},
-			eval.EvalCfg{Ports: []*eval.Port{
-				// TODO: Supply the Chan component of port 2.
-				{Chan: input, File: eval.DevNull}, port1, {File: os.Stderr}}})
-		outputs := collect()
-
-		if err != nil {
-			nt.notifyError("matcher", err)
-			// Continue with whatever values have been output
-		}
-		if len(outputs) != len(rawItems) {
-			nt.notifyf(
-				"matcher has output %v values, not equal to %v inputs",
-				len(outputs), len(rawItems))
+		texts := make([]string, len(rawItems))
+		for i, rawItem := range rawItems {
+			texts[i] = rawItem.String()
 		}
+		matches := callMatcher(nt, ev, matcher, seed, texts)
 		filtered := []complete.RawItem{}
-		for i := 0; i < len(rawItems) && i < len(outputs); i++ {
-			if vals.Bool(outputs[i]) {
+		for i := 0; i < len(rawItems) && i < len(matches); i++ {
+			if matches[i] {
 				filtered = append(filtered, rawItems[i])
 			}
 		}
@@ -427,63 +498,152 @@ func adaptMatcherMap(nt notifier, ev *eval.Evaler, m vals.Map) complete.Filterer
 	}
 }
 
-func adaptArgGeneratorMap(ev *eval.Evaler, m vals.Map) complete.ArgGenerator {
+// Adapts the matcher for a mode in $edit:completion:matcher into a filter for
+// the items of the mode. Returns nil if there is no matcher, in which case the
+// mode keeps its own way of filtering.
+func adaptMatcherForMode(nt notifier, ev *eval.Evaler, m vals.Map, mode string) cli.FilterFunc {
+	matcher, ok := lookupFn(m, mode)
+	if !ok {
+		nt.notifyf("matcher for %s not a function, ignoring", mode)
+	}
+	if matcher == nil {
+		return nil
+	}
+	return func(p string, texts []string) []bool {
+		return callMatcher(nt, ev, matcher, p, texts)
+	}
+}
+
+// Restrictions that apply to matchers and argument completers. External
+// commands are allowed, since many argument completers need to run them.
+var completionRestrictions = eval.Restrictions{
+	NoFileWrites: true, NoEnvChanges: true}
+
+// Calls a matcher with the seed, feeding it the texts as inputs, and returns
+// the booleans it outputs.
+func callMatcher(nt notifier, ev *eval.Evaler, matcher eval.Callable, seed string, texts []string) []bool {
+	input := make(chan interface{})
+	stopInputFeeder := make(chan struct{})
+	defer close(stopInputFeeder)
+	// Feed the texts to the input channel.
+	go func() {
+		defer close(input)
+		for _, text := range texts {
+			select {
+			case input <- text:
+			case <-stopInputFeeder:
+				return
+			}
+		}
+	}()
+
+	// TODO: Supply the Chan component of port 2.
+	port1, collect, err := eval.CapturePort()
+	if err != nil {
+		nt.notifyf("cannot create pipe to run completion matcher: %v", err)
+		return nil
+	}
+
+	err = ev.Call(matcher,
+		eval.CallCfg{Args: []interface{}{seed}, From: "[editor matcher]"},
+		eval.EvalCfg{Ports: []*eval.Port{
+			// TODO: Supply the Chan component of port 2.
+			{Chan: input, File: eval.DevNull}, port1, {File: os.Stderr}},
+			Interrupt:    eval.ListenInterrupts,
+			Restrictions: completionRestrictions})
+	outputs := collect()
+
+	if err != nil {
+		nt.notifyError("matcher", err)
+		// Continue with whatever values have been output
+	}
+	if len(outputs) != len(texts) {
+		nt.notifyf(
+			"matcher has output %v values, not equal to %v inputs",
+			len(outputs), len(texts))
+	}
+	matches := make([]bool, len(outputs))
+	for i, output := range outputs {
+		matches[i] = vals.Bool(output)
+	}
+	return matches
+}
+
+func adaptArgGeneratorMap(ev *eval.Evaler, m vals.Map, specs *cmdSpecs, bc *bashCompleter, generateFileNames complete.ArgGenerator) complete.ArgGenerator {
 	return func(args []string) ([]complete.RawItem, error) {
+		if _, explicit := m.Index(args[0]); !explicit {
+			if spec, ok := specs.get(args[0]); ok {
+				return spec.complete(ev, args[1:])
+			}
+		}
 		gen, ok := lookupFn(m, args[0])
 		if !ok {
 			return nil, fmt.Errorf("arg completer for %s not a function", args[0])
 		}
 		if gen == nil {
-			return complete.GenerateFileNames(args)
+			if _, isFn := lookupCmdFn(ev, args[0]); !isFn && bc.enabled.Get().(bool) {
+				if items, ok, err := bc.generate(args); ok || err != nil {
+					return items, err
+				}
+			}
+			return generateFileNames(args)
 		}
 		argValues := make([]interface{}, len(args))
 		for i, arg := range args {
 			argValues[i] = arg
 		}
-		var output []complete.RawItem
-		var outputMutex sync.Mutex
-		collect := func(item complete.RawItem) {
-			outputMutex.Lock()
-			defer outputMutex.Unlock()
-			output = append(output, item)
-		}
-		valueCb := func(ch <-chan interface{}) {
-			for v := range ch {
-				switch v := v.(type) {
-				case string:
-					collect(complete.PlainItem(v))
-				case complexItem:
-					collect(complete.ComplexItem(v))
-				default:
-					collect(complete.PlainItem(vals.ToString(v)))
-				}
+		return callArgGenerator(ev, gen, argValues)
+	}
+}
+
+// Calls an argument generator implemented in Elvish, and collects the values
+// and lines it outputs as candidates.
+func callArgGenerator(ev *eval.Evaler, gen eval.Callable, argValues []interface{}) ([]complete.RawItem, error) {
+	var output []complete.RawItem
+	var outputMutex sync.Mutex
+	collect := func(item complete.RawItem) {
+		outputMutex.Lock()
+		defer outputMutex.Unlock()
+		output = append(output, item)
+	}
+	valueCb := func(ch <-chan interface{}) {
+		for v := range ch {
+			switch v := v.(type) {
+			case string:
+				collect(complete.PlainItem(v))
+			case complexItem:
+				collect(complete.ComplexItem(v))
+			default:
+				collect(complete.PlainItem(vals.ToString(v)))
 			}
 		}
-		bytesCb := func(r *os.File) {
-			buffered := bufio.NewReader(r)
-			for {
-				line, err := buffered.ReadString('\n')
-				if line != "" {
-					collect(complete.PlainItem(strutil.ChopLineEnding(line)))
-				}
-				if err != nil {
-					break
-				}
+	}
+	bytesCb := func(r *os.File) {
+		buffered := bufio.NewReader(r)
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				collect(complete.PlainItem(strutil.ChopLineEnding(line)))
+			}
+			if err != nil {
+				break
 			}
 		}
-		port1, done, err := eval.PipePort(valueCb, bytesCb)
-		if err != nil {
-			panic(err)
-		}
-		err = ev.Call(gen,
-			eval.CallCfg{Args: argValues, From: "[editor arg generator]"},
-			eval.EvalCfg{Ports: []*eval.Port{
-				// TODO: Supply the Chan component of port 2.
-				nil, port1, {File: os.Stderr}}})
-		done()
-
-		return output, err
 	}
+	port1, done, err := eval.PipePort(valueCb, bytesCb)
+	if err != nil {
+		panic(err)
+	}
+	err = ev.Call(gen,
+		eval.CallCfg{Args: argValues, From: "[editor arg generator]"},
+		eval.EvalCfg{Ports: []*eval.Port{
+			// TODO: Supply the Chan component of port 2.
+			nil, port1, {File: os.Stderr}},
+			Interrupt:    eval.ListenInterrupts,
+			Restrictions: completionRestrictions})
+	done()
+
+	return output, err
 }
 
 func lookupFn(m vals.Map, ctxName string) (eval.Callable, bool) {
@@ -502,9 +662,12 @@ func lookupFn(m vals.Map, ctxName string) (eval.Callable, bool) {
 	return fn, true
 }
 
-type pureEvaler struct{ ev *eval.Evaler }
+type pureEvaler struct {
+	ev       *eval.Evaler
+	cmdIndex *fsutil.CommandIndex
+}
 
-func (pureEvaler) EachExternal(f func(string)) { fsutil.EachExternal(f) }
+func (pe pureEvaler) EachExternal(f func(string)) { pe.cmdIndex.Each(f) }
 
 func (pureEvaler) EachSpecial(f func(string)) {
 	for name := range eval.IsBuiltinSpecial {
@@ -515,9 +678,27 @@ func (pureEvaler) EachSpecial(f func(string)) {
 func (pe pureEvaler) EachNs(f func(string)) { pe.ev.EachNsInTop(f) }
 
 func (pe pureEvaler) EachVariableInNs(ns string, f func(string)) {
+	if ns == "e:" {
+		pe.cmdIndex.Each(func(cmd string) { f(cmd + eval.FnSuffix) })
+		return
+	}
 	pe.ev.EachVariableInTop(ns, f)
 }
 
+func (pe pureEvaler) EachModule(f func(string)) { pe.ev.EachModule(f) }
+
+func (pe pureEvaler) EachOption(cmd string, f func(string)) {
+	fn, ok := lookupCmdFn(pe.ev, cmd)
+	if !ok {
+		return
+	}
+	if sig, ok := eval.SignatureOf(fn); ok {
+		for _, name := range sig.OptNames {
+			f(name)
+		}
+	}
+}
+
 func (pe pureEvaler) PurelyEvalPrimary(pn *parse.Primary) interface{} {
 	return pe.ev.PurelyEvalPrimary(pn)
 }
@@ -62,6 +62,25 @@ func TestCompleteFilename(t *testing.T) {
 			complexItem{Stem: "./d/b", CodeSuffix: " "}))
 }
 
+func TestCompleteFilename_Ignore(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a.o": "", "b.c": ""})
+
+	evals(f.Evaler,
+		`edit:completion:filename:ignore = ['*.o']`,
+		`@cands = (edit:complete-filename ls '')`,
+		`@ignored-only = (edit:complete-filename ls a)`)
+	testGlobals(t, f.Evaler, map[string]interface{}{
+		"cands": vals.MakeList(complexItem{Stem: "b.c", CodeSuffix: " "}),
+		// Ignored files are output when no other file matches, and are
+		// filtered later along with the other files.
+		"ignored-only": vals.MakeList(
+			complexItem{Stem: "b.c", CodeSuffix: " "},
+			complexItem{Stem: "a.o", CodeSuffix: " "}),
+	})
+}
+
 func TestComplexCandidate(t *testing.T) {
 	restore := prog.SetShowDeprecations(true)
 	defer restore()
@@ -140,6 +159,19 @@ func TestCompletionArgCompleter_BytesOutput(t *testing.T) {
 	)
 }
 
+func TestCompletionArgCompleter_Restricted(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`fn foo { }`,
+		`edit:completion:arg-completer[foo] = [@args]{
+		   put ?(set-env X y)[class][name]
+		 }`,
+		`@cands = (edit:complete-sudo sudo foo '')`)
+	testGlobal(t, f.Evaler, "cands", vals.MakeList("restriction-error"))
+}
+
 func TestCompleteSudo(t *testing.T) {
 	f := setup()
 	defer f.Cleanup()
@@ -171,6 +203,23 @@ func TestCompletionMatcher(t *testing.T) {
 	)
 }
 
+func TestCompletionMatcher_FiltersInCompletionMode(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"foo": "", "oof": ""})
+
+	evals(f.Evaler, `edit:completion:matcher[argument] = $edit:match-prefix~`)
+	feedInput(f.TTYCtrl, "echo \to")
+	f.TestTTY(t,
+		"~> echo oof \n", Styles,
+		"   vvvv ____",
+		" COMPLETING argument  o", Styles,
+		"*********************  ", term.DotHere, "\n",
+		"oof", Styles,
+		"+++",
+	)
+}
+
 func TestBuiltinMatchers(t *testing.T) {
 	f := setup()
 	defer f.Cleanup()
@@ -206,3 +255,92 @@ func TestBuiltinMatchers_Options(t *testing.T) {
 		"d": vals.MakeList(false, true, false),
 	})
 }
+
+func TestCompletionAddon_ShowsSignatureHint(t *testing.T) {
+	f := setup(rc(`fn f [x y:num @rest &k=v]{ }`))
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a": "", "b": ""})
+
+	feedInput(f.TTYCtrl, "f a \t")
+	f.TestTTY(t,
+		"~> f a a \n", Styles,
+		"   v   __",
+		" COMPLETING argument  ", Styles,
+		"********************* ", term.DotHere,
+		"      f [x y:num @rest &k=v]\n", Styles,
+		"           +++++             ",
+		"a  b", Styles,
+		"+   ",
+	)
+}
+
+func TestCompletionAddon_ShowsDocInHint(t *testing.T) {
+	f := setup(rc(`fn f [x]{ "Does f.\nMore details."; nop }`))
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a": "", "b": ""})
+
+	feedInput(f.TTYCtrl, "f \t")
+	f.TestTTY(t,
+		"~> f a \n", Styles,
+		"   v __",
+		" COMPLETING argument  ", Styles,
+		"********************* ", term.DotHere,
+		"             f [x] - Does f.\n", Styles,
+		"                +           ",
+		"a  b", Styles,
+		"+   ",
+	)
+}
+
+func TestCompletionAddon_CompletesOptions(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler, `fn foo [&verbose=$false &version=$false]{ }`)
+
+	feedInput(f.TTYCtrl, "foo &\t")
+	f.TestTTY(t,
+		"~> foo &ver", Styles,
+		"   vvv b", term.DotHere,
+	)
+
+	feedInput(f.TTYCtrl, "\t")
+	f.TestTTY(t,
+		"~> foo &verbose=\n", Styles,
+		"   vvv b________",
+		" COMPLETING option  ", Styles,
+		"******************* ", term.DotHere, "\n",
+		"verbose  version", Styles,
+		"+++++++         ",
+	)
+}
+
+func TestCompletionAddon_CompletesModules(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "use readl\t")
+	f.TestTTY(t, "~> use readline-binding", Styles,
+		"   vvv                 ", term.DotHere)
+}
+
+func TestCompletionAddon_CompletesNestedIndices(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler, `m = [&a=[&bar=x &baz=y]]`)
+
+	feedInput(f.TTYCtrl, "put $m[a][\t")
+	f.TestTTY(t,
+		"~> put $m[a][ba", Styles,
+		"   vvv $$b bb", term.DotHere,
+	)
+
+	feedInput(f.TTYCtrl, "\t")
+	f.TestTTY(t,
+		"~> put $m[a][bar\n", Styles,
+		"   vvv $$b bb___",
+		" COMPLETING index  ", Styles,
+		"****************** ", term.DotHere, "\n",
+		"bar  baz", Styles,
+		"+++     ",
+	)
+}
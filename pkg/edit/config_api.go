@@ -60,6 +60,117 @@ func initAfterReadline(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder)
 	})
 }
 
+//elvdoc:var before-accept
+//
+// A list of functions to call when the code is about to be accepted, for
+// example by pressing Enter. Each function is called with a single string
+// argument containing the code, and may:
+//
+// -   Output nothing, to accept the code unchanged.
+//
+// -   Output a single string, to replace the code. Later functions are called
+//     with the replaced code, and the replaced code is what gets executed.
+//
+// -   Throw an exception, to reject the code. The editor stays open with the
+//     code unchanged, and the exception is shown as a notification.
+//
+// Any other output also rejects the code.
+//
+// Example of asking for confirmation before running a dangerous command:
+//
+// ```elvish
+// edit:before-accept = [$@edit:before-accept [code]{
+//   if (re:match '^\s*rm\s+-rf\s+/\s*$' $code) {
+//     fail 'refusing to run rm -rf /'
+//   }
+// }]
+// ```
+//
+// The standard error of the functions is shown as notifications.
+
+func initBeforeAccept(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
+	hook := newListVar(vals.EmptyList)
+	nb["before-accept"] = hook
+	appSpec.BeforeAccept = append(appSpec.BeforeAccept, func(code string) (string, bool) {
+		return callBeforeAccept(nt, ev, hook.Get().(vals.List), code)
+	})
+}
+
+// Calls the before-accept hooks in turn, passing the code replaced by the
+// previous hook to the next. Returns false if the code is rejected.
+func callBeforeAccept(nt notifier, ev *eval.Evaler, hook vals.List, code string) (string, bool) {
+	i := -1
+	for it := hook.Iterator(); it.HasElem(); it.Next() {
+		i++
+		name := fmt.Sprintf("$<edit>:before-accept[%d]", i)
+		fn, ok := it.Elem().(eval.Callable)
+		if !ok {
+			nt.notifyf("%s not function", name)
+			continue
+		}
+
+		out, err := callForOutputs(nt, ev, fn,
+			eval.CallCfg{Args: []interface{}{code}, From: name})
+		if err != nil {
+			nt.notifyError("before-accept", err)
+			return code, false
+		}
+		switch {
+		case len(out) == 0:
+		case len(out) == 1 && vals.Kind(out[0]) == "string":
+			code = out[0].(string)
+		default:
+			nt.notifyf("%s should output nothing or a single string", name)
+			return code, false
+		}
+	}
+	return code, true
+}
+
+//elvdoc:var term-width
+//
+// The width of the terminal, in columns. This variable is read-only.
+//
+// @cf edit:term-height edit:on-resize
+
+//elvdoc:var term-height
+//
+// The height of the terminal, in lines. This variable is read-only.
+//
+// @cf edit:term-width edit:on-resize
+
+//elvdoc:var on-resize
+//
+// A list of functions to call when the terminal is resized while the editor is
+// active. Each function is called with two arguments, the new width and height
+// of the terminal.
+//
+// Example:
+//
+// ```elvish
+// edit:on-resize = [[w h]{ echo 'resized to '$w'x'$h }]
+// ```
+//
+// @cf edit:term-width edit:term-height
+
+func initTermSize(appSpec *cli.AppSpec, tty cli.TTY, ev *eval.Evaler, nb eval.NsBuilder) {
+	nb.Add("term-width", vars.FromGet(func() interface{} {
+		_, width := tty.Size()
+		return vals.FromGo(width)
+	}))
+	nb.Add("term-height", vars.FromGet(func() interface{} {
+		height, _ := tty.Size()
+		return vals.FromGo(height)
+	}))
+
+	hook := newListVar(vals.EmptyList)
+	nb["on-resize"] = hook
+	appSpec.OnResize = append(appSpec.OnResize, func(width, height int) {
+		callHooks(ev, "$<edit>:on-resize", hook.Get().(vals.List),
+			vals.FromGo(width), vals.FromGo(height))
+	})
+}
+
 //elvdoc:var add-cmd-filters
 //
 // List of filters to run before adding a command to history.
@@ -1,9 +1,13 @@
 package edit
 
 import (
+	"strconv"
 	"testing"
 
+	"github.com/elves/elvish/pkg/cli/clitest"
 	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/sys"
 )
 
 func TestBeforeReadline(t *testing.T) {
@@ -43,6 +47,70 @@ func TestAfterReadline(t *testing.T) {
 	})
 }
 
+func TestBeforeAccept_ReplacesCode(t *testing.T) {
+	f := setup(rc(
+		`edit:before-accept = [[code]{ put 'echo '$code } [code]{ }]`))
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "foo\n")
+	if code, _ := f.Wait(); code != "echo foo" {
+		t.Errorf("got code %q, want %q", code, "echo foo")
+	}
+}
+
+func TestBeforeAccept_RejectsCodeOnException(t *testing.T) {
+	f := setup(rc(
+		`edit:before-accept = [[code]{ if (eq $code 'echo x') { fail dangerous } }]`))
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "echo x\n")
+	f.TestTTYNotes(t, "[before-accept error] dangerous\n",
+		`see stack trace with "show $edit:exceptions[0]"`)
+	f.TestTTY(t, "~> echo x", Styles,
+		"   vvvv  ", term.DotHere)
+}
+
+func TestBeforeAccept_RejectsCodeOnInvalidOutput(t *testing.T) {
+	f := setup(rc(`edit:before-accept = [[code]{ put a b }]`))
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "echo x\n")
+	f.TestTTYNotes(t,
+		"$<edit>:before-accept[0] should output nothing or a single string")
+	f.TestTTY(t, "~> echo x", Styles,
+		"   vvvv  ", term.DotHere)
+}
+
+func TestTermSize(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`w = $edit:term-width`,
+		`h = $edit:term-height`)
+	testGlobals(t, f.Evaler, map[string]interface{}{
+		"w": strconv.Itoa(clitest.FakeTTYWidth),
+		"h": strconv.Itoa(clitest.FakeTTYHeight),
+	})
+}
+
+func TestOnResize(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	evals(f.Evaler,
+		`called-with = []`,
+		`edit:on-resize = [[w h]{ called-with = [$w $h] }]`)
+
+	f.TTYCtrl.SetSize(24, 30)
+	f.TTYCtrl.InjectSignal(sys.SIGWINCH)
+	// Wait for the redraw triggered by the signal, which happens after the hooks
+	// are called.
+	f.TTYCtrl.TestBuffer(t,
+		term.NewBufferBuilder(30).Write("~> ").SetDotHere().Buffer())
+
+	testGlobal(t, f.Evaler, "called-with", vals.MakeList("30", "24"))
+}
+
 func TestAddCmdFilters(t *testing.T) {
 	cases := []struct {
 		name        string
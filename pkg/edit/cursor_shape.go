@@ -0,0 +1,88 @@
+package edit
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var cursor-shape:insert
+//
+// The shape of the cursor when no mode is active, i.e. when typing inserts text
+// into the command line. Defaults to `bar`.
+//
+// The shape can be one of `default`, `block`, `underline` and `bar`, or one of
+// the last three prefixed with `blinking-`, like `blinking-bar`. The `default`
+// shape is the one configured in the terminal. The cursor shape is changed
+// using the DECSCUSR escape sequence, which is ignored by terminals that don't
+// support it.
+//
+// The cursor shape is always reset to `default` when the editor exits, so that
+// commands run with the cursor shape configured in the terminal.
+//
+// @cf edit:cursor-shape:command edit:cursor-shape:filter
+
+//elvdoc:var cursor-shape:command
+//
+// The shape of the cursor when the cursor stays in the command line but typing
+// does not insert text, like in the command mode. Defaults to `block`.
+//
+// See [`$edit:cursor-shape:insert`](#editcursor-shapeinsert) for possible
+// values.
+
+//elvdoc:var cursor-shape:filter
+//
+// The shape of the cursor when the cursor is in the filter of a mode, like in
+// the history listing mode. Defaults to `underline`.
+//
+// See [`$edit:cursor-shape:insert`](#editcursor-shapeinsert) for possible
+// values.
+
+func initCursorShape(appSpec *cli.AppSpec, ed *Editor, nb eval.NsBuilder) {
+	var m sync.RWMutex
+	insert, command, filter := term.CursorBar, term.CursorBlock, term.CursorUnderline
+	appSpec.CursorShape = func() term.CursorShape {
+		m.RLock()
+		defer m.RUnlock()
+		addon := cli.Addon(ed.app)
+		if addon == nil {
+			return insert
+		}
+		if focuser, ok := addon.(cli.Focuser); ok && !focuser.Focus() {
+			return command
+		}
+		return filter
+	}
+	nb.AddNs("cursor-shape", eval.NsBuilder{
+		"insert":  cursorShapeVar(&insert, &m),
+		"command": cursorShapeVar(&command, &m),
+		"filter":  cursorShapeVar(&filter, &m),
+	}.Ns())
+}
+
+func cursorShapeVar(p *term.CursorShape, m *sync.RWMutex) vars.Var {
+	return vars.FromSetGet(
+		func(v interface{}) error {
+			name, ok := v.(string)
+			shape, valid := term.ParseCursorShape(name)
+			if !ok || !valid {
+				return errs.BadValue{What: "cursor shape",
+					Valid:  "default, block, underline, bar or a blinking- variant",
+					Actual: vals.Repr(v, vals.NoPretty)}
+			}
+			m.Lock()
+			defer m.Unlock()
+			*p = shape
+			return nil
+		},
+		func() interface{} {
+			m.RLock()
+			defer m.RUnlock()
+			return p.String()
+		})
+}
@@ -0,0 +1,70 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestCursorShape(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "echo")
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere)
+	testCursorShape(t, f, term.CursorBar)
+
+	evals(f.Evaler, `edit:insert:binding[Ctrl-'['] = $edit:command:start~`)
+	f.TTYCtrl.Inject(term.K('[', ui.Ctrl))
+	f.TestTTY(t,
+		"~> echo", Styles,
+		"   vvvv", term.DotHere, "\n",
+		" COMMAND ", Styles,
+		"*********",
+	)
+	testCursorShape(t, f, term.CursorBlock)
+
+	evals(f.Evaler, `edit:histlist:start`)
+	f.TestTTY(t,
+		"~> echo\n", Styles,
+		"   vvvv",
+		" HISTORY (dedup on)  ", Styles,
+		"******************** ", term.DotHere, "\n")
+	testCursorShape(t, f, term.CursorUnderline)
+}
+
+func TestCursorShape_Configurable(t *testing.T) {
+	f := setup(rc(`edit:cursor-shape:insert = blinking-block`))
+	defer f.Cleanup()
+
+	f.TestTTY(t, "~> ", term.DotHere)
+	testCursorShape(t, f, term.CursorBlinkingBlock)
+
+	evals(f.Evaler,
+		`exc = ?(edit:cursor-shape:insert = bad)`,
+		`s = $edit:cursor-shape:insert`)
+	if exc := getGlobal(f.Evaler, "exc"); exc == eval.OK {
+		t.Errorf("setting an invalid cursor shape didn't throw")
+	}
+	testGlobal(t, f.Evaler, "s", "blinking-block")
+}
+
+func TestCursorShape_ResetAfterAccepting(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+	testCursorShape(t, f, term.CursorDefault)
+}
+
+func testCursorShape(t *testing.T, f *fixture, want term.CursorShape) {
+	t.Helper()
+	if shape := f.TTYCtrl.CursorShape(); shape != want {
+		t.Errorf("got cursor shape %v, want %v", shape, want)
+	}
+}
@@ -27,6 +27,8 @@ insert:binding = (binding-table [
 
   &Alt-,=  $lastcmd:start~
   &Alt-.=  $insert-last-word~
+  &Alt-o=  $last-output:start~
+  &'Ctrl-]'= $jump-to-def~
   &Ctrl-R= $histlist:start~
   &Ctrl-L= $location:start~
   &Ctrl-N= $navigation:start~
@@ -107,6 +109,16 @@ lastcmd:binding = (binding-table [
 minibuf:binding = (binding-table [
   &Ctrl-'['= $listing:close~
 ])
+
+paste-confirm:binding = (binding-table [
+  &Enter=    $paste-confirm:accept~
+  &Ctrl-'['= $paste-confirm:close~
+])
+
+error-view:binding = (binding-table [
+  &Ctrl-Y=   $error-view:copy~
+  &Ctrl-'['= $error-view:close~
+])
 `
 
 // vi: set et:
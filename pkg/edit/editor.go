@@ -7,14 +7,19 @@ package edit
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/addons/errorview"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/ui"
 )
 
 // Editor is the interface line editor for Elvish.
@@ -24,21 +29,36 @@ type Editor struct {
 
 	excMutex sync.RWMutex
 	excList  vals.List
+
+	specs            *cmdSpecs
+	matchers         vars.PtrVar
+	layeredBindings  []layeredBinding
+	lastOutput       *lastOutput
+	commandBadge     *commandBadge
+	errorViewBinding cli.Handler
+	// The prompt and the rprompt.
+	prompts []cli.Prompt
+	// External commands, used for highlighting and completion.
+	cmdIndex *fsutil.CommandIndex
 }
 
-// An interface that wraps notifyf and notifyError. It is only implemented by
-// the *Editor type; functions may take a notifier instead of *Editor argument
-// to make it clear that they do not depend on other parts of *Editor.
+// An interface that wraps notifyf, notifyError and notifyBindingError. It is
+// only implemented by the *Editor type; functions may take a notifier instead
+// of *Editor argument to make it clear that they do not depend on other parts
+// of *Editor.
 type notifier interface {
 	notifyf(format string, args ...interface{})
 	notifyError(ctx string, e error)
+	notifyBindingError(e error)
 }
 
 // NewEditor creates a new editor from input and output terminal files.
 func NewEditor(tty cli.TTY, ev *eval.Evaler, st store.Store) *Editor {
 	// Declare the Editor with a nil App first; some initialization functions
 	// require a notifier as an argument, but does not use it immediately.
-	ed := &Editor{excList: vals.EmptyList}
+	ed := &Editor{excList: vals.EmptyList, specs: newCmdSpecs(),
+		matchers: newMapVar(vals.EmptyMap),
+		cmdIndex: fsutil.NewCommandIndex(fsutil.DefaultCommandIndexInterval)}
 	nb := eval.NsBuilder{}
 	appSpec := cli.AppSpec{TTY: tty}
 
@@ -47,15 +67,26 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st store.Store) *Editor {
 		// TODO(xiaq): Report the error.
 	}
 
-	initHighlighter(&appSpec, ev)
+	initHighlighter(&appSpec, ed, ev)
 	initMaxHeight(&appSpec, nb)
 	initReadlineHooks(&appSpec, ev, nb)
+	initBeforeAccept(&appSpec, ed, ev, nb)
+	initTermSize(&appSpec, tty, ev, nb)
 	initAddCmdFilters(&appSpec, ev, nb, hs)
 	initInsertAPI(&appSpec, ed, ev, nb)
+	initAliasExpansion(&appSpec, ed, ev, nb)
+	initPasteConfirm(&appSpec, ed, ev, nb)
+	initJumpToDef(&appSpec, ed, ev, nb)
 	initPrompts(&appSpec, ed, ev, nb)
+	ed.prompts = []cli.Prompt{appSpec.Prompt, appSpec.RPrompt}
+	initCursorShape(&appSpec, ed, nb)
+	initTitle(&appSpec, ed, ev, nb)
+	initReportCwd(&appSpec, tty, ev, nb)
+	initCommandBadge(&appSpec, ed, tty, nb)
 	ed.app = cli.NewApp(appSpec)
 
 	initExceptionsAPI(ed, nb)
+	initBindingAPI(ed, nb)
 	initCommandAPI(ed, ev, nb)
 	initListings(ed, ev, st, hs, nb)
 	initNavigation(ed, ev, nb)
@@ -63,10 +94,14 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st store.Store) *Editor {
 	initHistWalk(ed, ev, hs, nb)
 	initInstant(ed, ev, nb)
 	initMinibuf(ed, ev, nb)
+	initErrorView(ed, ev, nb)
+	initClipboard(ed.app, tty, nb)
+	initMessages(nb)
 
 	initBufferBuiltins(ed.app, nb)
 	initTTYBuiltins(ed.app, tty, nb)
 	initMiscBuiltins(ed.app, nb)
+	initSmartEnter(ed, ev, nb)
 	initStateAPI(ed.app, nb)
 	initStoreAPI(ed.app, nb, hs)
 
@@ -95,7 +130,9 @@ func evalDefaultBinding(ev *eval.Evaler, ns *eval.Ns) {
 
 // ReadCode reads input from the user.
 func (ed *Editor) ReadCode() (string, error) {
-	return ed.app.ReadCode()
+	code, err := ed.app.ReadCode()
+	ed.commandBadge.afterReadCode()
+	return code, err
 }
 
 // Ns returns a namespace for manipulating the editor from Elvish code.
@@ -109,13 +146,62 @@ func (ed *Editor) notifyf(format string, args ...interface{}) {
 
 func (ed *Editor) notifyError(ctx string, e error) {
 	if exc, ok := e.(*eval.Exception); ok {
-		ed.excMutex.Lock()
-		defer ed.excMutex.Unlock()
-		ed.excList = ed.excList.Cons(exc)
-		ed.notifyf("[%v error] %v\n"+
-			`see stack trace with "show $edit:exceptions[%d]"`,
-			ctx, e, ed.excList.Len()-1)
+		i := ed.addException(exc)
+		ed.app.Notify(msg.Sprintf("note-error", ctx, e) + "\n" +
+			showTraceback(exc.StackTrace) + msg.Sprintf("note-see-stack-trace", i))
 	} else {
-		ed.notifyf("[%v error] %v", ctx, e)
+		ed.app.Notify(msg.Sprintf("note-error", ctx, e))
+	}
+}
+
+// Exceptions from bindings are shown in the error view along with the full
+// stack trace, since the user is usually interested in debugging them. Other
+// errors are shown as notes.
+func (ed *Editor) notifyBindingError(e error) {
+	exc, ok := e.(*eval.Exception)
+	if !ok {
+		ed.notifyError("binding", e)
+		return
+	}
+	ed.addException(exc)
+	errorview.Start(ed.app, errorview.Config{
+		Binding: ed.errorViewBinding,
+		Text:    ui.ParseSGREscapedText(exc.Show("")),
+	})
+}
+
+// Adds an exception to $edit:exceptions and returns its index.
+func (ed *Editor) addException(exc *eval.Exception) int {
+	ed.excMutex.Lock()
+	defer ed.excMutex.Unlock()
+	ed.excList = ed.excList.Cons(exc)
+	return ed.excList.Len() - 1
+}
+
+// Shows a stack trace with more than one frame, one frame per line along with
+// the relevant source. Notes can't be styled, so the styling used to highlight
+// the culprit is removed. Returns an empty string for stack traces with at most
+// one frame.
+func showTraceback(tb *eval.StackTrace) string {
+	if tb == nil || tb.Next == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for ; tb != nil; tb = tb.Next {
+		sb.WriteString("  ")
+		if tb.FnName != "" {
+			sb.WriteString(tb.FnName + " at ")
+		}
+		sb.WriteString(plainText(ui.ParseSGREscapedText(tb.Head.ShowCompact("    "))))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func plainText(t ui.Text) string {
+	var sb strings.Builder
+	for _, seg := range t {
+		sb.WriteString(seg.Text)
 	}
+	return sb.String()
 }
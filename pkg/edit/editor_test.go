@@ -37,3 +37,16 @@ func testCommands(t *testing.T, store store.Store, wantCmds ...string) {
 		t.Errorf("got cmds %v, want %v", cmds, wantCmds)
 	}
 }
+
+func TestEditor_NotifiesExceptionWithTraceback(t *testing.T) {
+	f := setup(rc(
+		`fn f { fail ERROR }`,
+		`edit:prompt = { f }`))
+	defer f.Cleanup()
+
+	f.TestTTYNotes(t,
+		"[prompt error] ERROR\n",
+		"  fail at [test], line 1: fn f { fail ERROR }\n",
+		"  f at [test], line 1: edit:prompt = { f }\n",
+		`see stack trace with "show $edit:exceptions[0]"`)
+}
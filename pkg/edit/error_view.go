@@ -0,0 +1,46 @@
+package edit
+
+import (
+	"github.com/elves/elvish/pkg/cli/addons/errorview"
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/eval"
+)
+
+//elvdoc:var error-view:binding
+//
+// Binding for the error-view mode, which is started when a binding throws an
+// exception and shows the exception along with its stack trace. The Up and Down
+// keys scroll the view.
+
+//elvdoc:fn error-view:copy
+//
+// Copies the error shown in the error-view mode to the system clipboard. See
+// [`edit:copy-to-clipboard`](#edit:copy-to-clipboard) for how the clipboard is
+// accessed.
+
+//elvdoc:fn error-view:close
+//
+// Closes the error-view mode.
+
+func initErrorView(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	bindingVar := newBindingVar(EmptyBindingMap)
+	ed.errorViewBinding = newMapBinding(ed, ev, bindingVar)
+	app := ed.app
+	nb.AddNs("error-view",
+		eval.NsBuilder{
+			"binding": bindingVar,
+		}.AddGoFns("<edit:error-view>:", map[string]interface{}{
+			"copy": func() error {
+				text, ok := errorview.Text(app)
+				if !ok {
+					return nil
+				}
+				err := copyToClipboard(plainText(text))
+				if err == nil {
+					app.Notify(msg.Get("note-error-copied"))
+				}
+				return err
+			},
+			"close": func() { errorview.Close(app) },
+		}).Ns())
+}
@@ -0,0 +1,50 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+var excStyles = ui.RuneStylesheet{
+	'*': ui.Stylings(ui.Bold, ui.FgWhite, ui.BgMagenta),
+	'!': ui.Stylings(ui.Bold, ui.FgRed),
+	'_': ui.Stylings(ui.Bold, ui.Underlined),
+}
+
+func TestErrorView_ShowsBindingException(t *testing.T) {
+	f := setup(rc(
+		`fn f { fail ERROR }`,
+		`edit:insert:binding[x] = { f }`))
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(term.K('x'))
+	f.TestTTY(t,
+		"~> ", term.DotHere, "\n",
+		" ERROR \n", excStyles,
+		"******* ",
+		"Exception: ERROR\n", excStyles,
+		"           !!!!!",
+		"Traceback:\n",
+		"  [test], line 1:\n",
+		"    fn f { fail ERROR }\n", excStyles,
+		"           ___________",
+		"  [test], line 1:\n",
+		"    edit:insert:binding[x] = { f }", excStyles,
+		"                               __",
+	)
+	evals(f.Evaler, `excs = (count $edit:exceptions)`)
+	testGlobal(t, f.Evaler, "excs", "1")
+
+	f.TTYCtrl.Inject(term.K('[', ui.Ctrl))
+	f.TestTTY(t, "~> ", term.DotHere)
+}
+
+func TestErrorView_CopyIsNoOpWhenInactive(t *testing.T) {
+	f := setup(rc(`edit:insert:binding[x] = $edit:error-view:copy~`))
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(term.K('x'))
+	f.TestTTY(t, "~> ", term.DotHere)
+}
@@ -5,31 +5,31 @@ import (
 	"os/exec"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/edit/highlight"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/parse"
 )
 
-func initHighlighter(appSpec *cli.AppSpec, ev *eval.Evaler) {
+func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler) {
 	appSpec.Highlighter = highlight.NewHighlighter(highlight.Config{
-		Check:      func(tree parse.Tree) error { return check(ev, tree) },
-		HasCommand: func(cmd string) bool { return hasCommand(ev, cmd) },
+		Check:      func(tree parse.Tree) error { return check(ev, ed.specs, tree) },
+		HasCommand: func(cmd string) bool { return hasCommand(ev, ed.cmdIndex, cmd) },
 	})
 }
 
-func check(ev *eval.Evaler, tree parse.Tree) error {
-	err := ev.CheckTree(tree, nil)
-	if err == nil {
-		return nil
-	}
-	return err
+func check(ev *eval.Evaler, specs *cmdSpecs, tree parse.Tree) error {
+	return diag.Errors(ev.CheckTree(tree, nil), checkSpecFlags(ev, specs, tree))
 }
 
-func hasCommand(ev *eval.Evaler, cmd string) bool {
+func hasCommand(ev *eval.Evaler, ix *fsutil.CommandIndex, cmd string) bool {
 	if eval.IsBuiltinSpecial[cmd] {
 		return true
 	}
+	if _, ok := ev.Alias(cmd); ok {
+		return true
+	}
 	if fsutil.DontSearch(cmd) {
 		return isDirOrExecutable(cmd) || hasExternalCommand(cmd)
 	}
@@ -37,7 +37,7 @@ func hasCommand(ev *eval.Evaler, cmd string) bool {
 	sigil, qname := eval.SplitSigil(cmd)
 	if sigil != "" {
 		// The @ sign is only valid when referring to external commands.
-		return hasExternalCommand(cmd)
+		return ix.Has(cmd)
 	}
 
 	first, rest := eval.SplitQName(qname)
@@ -48,7 +48,7 @@ func hasCommand(ev *eval.Evaler, cmd string) bool {
 			return true
 		}
 	case first == "e:":
-		return hasExternalCommand(rest)
+		return ix.Has(rest)
 	default:
 		// Qualified name. Find the top-level module first.
 		if hasQualifiedFn(ev, first, rest) {
@@ -57,45 +57,68 @@ func hasCommand(ev *eval.Evaler, cmd string) bool {
 	}
 
 	// If all failed, it can still be an external command.
-	return hasExternalCommand(cmd)
+	return ix.Has(cmd)
+}
+
+// Looks up the function called by a command with the given name, which may be
+// qualified. External commands are not considered.
+func lookupCmdFn(ev *eval.Evaler, name string) (eval.Callable, bool) {
+	first, rest := eval.SplitQName(name)
+	if rest == "" {
+		if fn, ok := lookupFnInNs(ev.Global, first); ok {
+			return fn, true
+		}
+		return lookupFnInNs(ev.Builtin, first)
+	}
+	return lookupQualifiedFn(ev, first, rest)
 }
 
 func hasQualifiedFn(ev *eval.Evaler, firstNs string, rest string) bool {
+	_, ok := lookupQualifiedFn(ev, firstNs, rest)
+	return ok
+}
+
+func lookupQualifiedFn(ev *eval.Evaler, firstNs string, rest string) (eval.Callable, bool) {
 	if rest == "" {
-		return false
+		return nil, false
 	}
 	modVal, ok := ev.Global.Index(firstNs)
 	if !ok {
 		modVal, ok = ev.Builtin.Index(firstNs)
 		if !ok {
-			return false
+			return nil, false
 		}
 	}
 	mod, ok := modVal.(*eval.Ns)
 	if !ok {
-		return false
+		return nil, false
 	}
 	segs := eval.SplitQNameSegs(rest)
 	for _, seg := range segs[:len(segs)-1] {
 		modVal, ok = mod.Index(seg)
 		if !ok {
-			return false
+			return nil, false
 		}
 		mod, ok = modVal.(*eval.Ns)
 		if !ok {
-			return false
+			return nil, false
 		}
 	}
-	return hasFn(mod, segs[len(segs)-1])
+	return lookupFnInNs(mod, segs[len(segs)-1])
 }
 
 func hasFn(ns *eval.Ns, name string) bool {
+	_, ok := lookupFnInNs(ns, name)
+	return ok
+}
+
+func lookupFnInNs(ns *eval.Ns, name string) (eval.Callable, bool) {
 	fnVar, ok := ns.Index(name + eval.FnSuffix)
 	if !ok {
-		return false
+		return nil, false
 	}
-	_, ok = fnVar.(eval.Callable)
-	return ok
+	fn, ok := fnVar.(eval.Callable)
+	return fn, ok
 }
 
 func isDirOrExecutable(fname string) bool {
@@ -103,6 +126,8 @@ func isDirOrExecutable(fname string) bool {
 	return err == nil && (stat.IsDir() || stat.Mode()&0111 != 0)
 }
 
+// Checks an external command given as a path; commands in PATH are looked up
+// with a fsutil.CommandIndex instead.
 func hasExternalCommand(cmd string) bool {
 	_, err := exec.LookPath(cmd)
 	return err == nil
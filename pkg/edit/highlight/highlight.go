@@ -28,35 +28,29 @@ var MaxBlockForLate = 10 * time.Millisecond
 
 // Highlights a piece of Elvish code.
 func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []error) {
+	// Errors are returned instead of highlighted, so that they can be rendered
+	// along with the highlighted code, each with its own styling.
 	var errors []error
-	var errorRegions []region
 
 	tree, errParse := parse.Parse(parse.Source{Name: "[tty]", Code: code})
 	if errParse != nil {
 		for _, err := range errParse.(*parse.Error).Entries {
 			if err.Context.From != len(code) {
 				errors = append(errors, err)
-				errorRegions = append(errorRegions,
-					region{
-						err.Context.From, err.Context.To,
-						semanticRegion, errorRegion})
 			}
 		}
 	}
 
 	if cfg.Check != nil {
-		err := cfg.Check(tree)
-		if r, ok := err.(diag.Ranger); ok && r.Range().From != len(code) {
-			errors = append(errors, err)
-			errorRegions = append(errorRegions,
-				region{
-					r.Range().From, r.Range().To, semanticRegion, errorRegion})
+		for _, err := range diag.UnpackErrors(cfg.Check(tree)) {
+			if r, ok := err.(diag.Ranger); ok && r.Range().From != len(code) {
+				errors = append(errors, err)
+			}
 		}
 	}
 
 	var text ui.Text
 	regions := getRegionsInner(tree.Root)
-	regions = append(regions, errorRegions...)
 	regions = fixRegions(regions)
 	lastEnd := 0
 	var cmdRegions []cmdRegion
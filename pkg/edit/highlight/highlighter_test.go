@@ -16,7 +16,7 @@ var any = anyMatcher{}
 var noErrors []error
 
 var styles = ui.RuneStylesheet{
-	'?':  ui.Stylings(ui.FgBrightWhite, ui.BgRed),
+	'*':  ui.Bold,
 	'$':  ui.FgMagenta,
 	'\'': ui.FgYellow,
 	'v':  ui.FgGreen,
@@ -60,11 +60,18 @@ func TestHighlighter_HighlightRegions(t *testing.T) {
 func TestHighlighter_ParseErrors(t *testing.T) {
 	hl := NewHighlighter(Config{})
 	tt.Test(t, tt.Fn("hl.Get", hl.Get), tt.Table{
-		// Parse error is highlighted and returned
+		// Parse error is returned; the code is highlighted normally
 		Args("ls ]").Rets(
 			ui.MarkLines(
 				"ls ]", styles,
-				"vv ?"),
+				"vv *"),
+			matchErrors(parseErrorMatcher{3, 4})),
+		// Code after a parse error is still highlighted
+		Args("ls ] x | ls $y").Rets(
+			ui.Concat(
+				ui.T("ls", ui.FgGreen), ui.T(" "), ui.T("] x "),
+				ui.T("|", ui.FgGreen), ui.T(" "), ui.T("ls", ui.FgGreen),
+				ui.T(" "), ui.T("$y", ui.FgMagenta)),
 			matchErrors(parseErrorMatcher{3, 4})),
 		// Errors at the end are ignored
 		Args("ls $").Rets(any, noErrors),
@@ -83,12 +90,13 @@ func TestHighlighter_CheckErrors(t *testing.T) {
 	}
 
 	tt.Test(t, tt.Fn("getWithCheckError", getWithCheckError), tt.Table{
-		// Check error is highlighted and returned
+		// Check error is returned; the code is highlighted normally
 		Args("code 1", fakeCheckError{5, 6}).Rets(
-			ui.MarkLines(
-				"code 1", styles,
-				"vvvv ?"),
+			ui.Concat(ui.T("code", ui.FgGreen), ui.T(" "), ui.T("1")),
 			[]error{fakeCheckError{5, 6}}),
+		// Multiple check errors are all returned
+		Args("code 1 2", diag.Errors(fakeCheckError{5, 6}, fakeCheckError{7, 8})).
+			Rets(any, []error{fakeCheckError{5, 6}, fakeCheckError{7, 8}}),
 		// Check errors at the end are ignored
 		Args("code 2", fakeCheckError{6, 6}).
 			Rets(any, noErrors),
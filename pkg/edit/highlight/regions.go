@@ -56,8 +56,6 @@ const (
 	commandRegion = "command"
 	// A region for keywords in special forms, like "else" in an "if" form.
 	keywordRegion = "keyword"
-	// A region of parse or compilation error.
-	errorRegion = "error"
 )
 
 func getRegions(n parse.Node) []region {
@@ -176,7 +174,8 @@ func emitRegionsInFor(n *parse.Form, f func(parse.Node, regionKind, string)) {
 
 func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
 	// Highlight "except", the exception variable after it, "else" and
-	// "finally".
+	// "finally". When an except clause has both an exception class and a
+	// variable, the class is highlighted as a keyword.
 	i := 1
 	matchKW := func(text string) bool {
 		if i < len(n.Args) && sourceText(n.Args[i]) == text {
@@ -185,11 +184,19 @@ func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
 		}
 		return false
 	}
-	if matchKW("except") {
-		if i+1 < len(n.Args) && len(n.Args[i+1].Indexings) > 0 {
-			f(n.Args[i+1], semanticRegion, variableRegion)
+	for matchKW("except") {
+		i++
+		words := 0
+		for i+words < len(n.Args) && words < 2 && isBareword(n.Args[i+words]) {
+			words++
 		}
-		i += 3
+		if words == 2 {
+			f(n.Args[i], semanticRegion, keywordRegion)
+		}
+		if words > 0 {
+			f(n.Args[i+words-1], semanticRegion, variableRegion)
+		}
+		i += words + 1
 	}
 	if matchKW("else") {
 		i += 2
@@ -197,6 +204,10 @@ func emitRegionsInTry(n *parse.Form, f func(parse.Node, regionKind, string)) {
 	matchKW("finally")
 }
 
+func isBareword(n *parse.Compound) bool {
+	return len(n.Indexings) == 1 && n.Indexings[0].Head.Type == parse.Bareword
+}
+
 func emitRegionsInPrimary(n *parse.Primary, f func(parse.Node, regionKind, string)) {
 	switch n.Type {
 	case parse.Bareword:
@@ -141,6 +141,20 @@ func TestGetRegions(t *testing.T) {
 			{28, 29, lexicalRegion, "}"},
 		}),
 
+		Args("try { } except fail-error e { } except { }").Rets([]region{
+			{0, 3, semanticRegion, commandRegion}, // try
+			{4, 5, lexicalRegion, "{"},
+			{6, 7, lexicalRegion, "}"},
+			{8, 14, semanticRegion, keywordRegion},   // except
+			{15, 25, semanticRegion, keywordRegion},  // fail-error
+			{26, 27, semanticRegion, variableRegion}, // e
+			{28, 29, lexicalRegion, "{"},
+			{30, 31, lexicalRegion, "}"},
+			{32, 38, semanticRegion, keywordRegion}, // except
+			{39, 40, lexicalRegion, "{"},
+			{41, 42, lexicalRegion, "}"},
+		}),
+
 		Args("try { } finally { }").Rets([]region{
 			{0, 3, semanticRegion, commandRegion}, // try
 			{4, 5, lexicalRegion, "{"},
@@ -28,7 +28,6 @@ var stylingFor = map[string]ui.Styling{
 
 	commandRegion: ui.FgGreen,
 	keywordRegion: ui.FgYellow,
-	errorRegion:   ui.Stylings(ui.FgBrightWhite, ui.BgRed),
 }
 
 var (
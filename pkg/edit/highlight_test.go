@@ -11,6 +11,7 @@ import (
 	"github.com/elves/elvish/pkg/env"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/testutil"
 	"github.com/elves/elvish/pkg/tt"
@@ -31,8 +32,9 @@ func TestHighlighter(t *testing.T) {
 	feedInput(f.TTYCtrl, "x")
 	f.TestTTY(t,
 		"~> put $truex", Styles,
-		"   vvv ??????", term.DotHere, "\n",
-		"compilation error: 4-10 in [tty]: variable $truex not found",
+		"   vvv ~~~~~~", term.DotHere, "\n",
+		"compilation error: 4-10 in [tty]: variable $truex not found", Styles,
+		"!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!",
 	)
 }
 
@@ -43,10 +45,10 @@ func TestCheck(t *testing.T) {
 	ev.Global = eval.NsBuilder{"good": vars.FromInit(0)}.Ns()
 
 	tt.Test(t, tt.Fn("check", check), tt.Table{
-		tt.Args(ev, mustParse("")).Rets(noError),
-		tt.Args(ev, mustParse("echo $good")).Rets(noError),
+		tt.Args(ev, newCmdSpecs(), mustParse("")).Rets(noError),
+		tt.Args(ev, newCmdSpecs(), mustParse("echo $good")).Rets(noError),
 		// TODO: Check the range of the returned error
-		tt.Args(ev, mustParse("echo $bad")).Rets(anyError),
+		tt.Args(ev, newCmdSpecs(), mustParse("echo $bad")).Rets(anyError),
 	})
 }
 
@@ -77,6 +79,7 @@ func TestMakeHasCommand(t *testing.T) {
 				AddNs("b", eval.NsBuilder{}.AddFn("good", goodFn).Ns()).
 				Ns()).
 		Ns()
+	ev.SetAlias("alias", "put")
 
 	// Set up environment.
 	testDir, cleanup := testutil.InTestDir()
@@ -102,7 +105,9 @@ func TestMakeHasCommand(t *testing.T) {
 	mustMkdirAll("a/b/c")
 	mustMkExecutable("a/b/c/executable")
 
-	tt.Test(t, tt.Fn("hasCommand", hasCommand), tt.Table{
+	ix := fsutil.NewCommandIndex(fsutil.DefaultCommandIndexInterval)
+	hasCmd := func(ev *eval.Evaler, cmd string) bool { return hasCommand(ev, ix, cmd) }
+	tt.Test(t, tt.Fn("hasCommand", hasCmd), tt.Table{
 		// Builtin special form
 		tt.Args(ev, "if").Rets(true),
 		// Builtin function
@@ -112,6 +117,8 @@ func TestMakeHasCommand(t *testing.T) {
 		// Function in modules
 		tt.Args(ev, "a:good").Rets(true),
 		tt.Args(ev, "a:b:good").Rets(true),
+		// Alias
+		tt.Args(ev, "alias").Rets(true),
 
 		// Non-searching directory and external
 		tt.Args(ev, "./a").Rets(true),
@@ -16,9 +16,15 @@ import (
 // Starts the instant mode. In instant mode, any text entered at the command
 // line is evaluated immediately, with the output displayed.
 //
-// **WARNING**: Beware of unintended consequences when using destructive
-// commands. For example, if you type `sudo rm -rf /tmp/*` in the instant mode,
-// Elvish will attempt to evaluate `sudo rm -rf /` when you typed that far.
+// To avoid unintended consequences of evaluating partially typed code, the
+// code can't run external commands, write files or change the environment;
+// trying to do any of these throws an exception. For example, if you type
+// `sudo rm -rf /tmp/*` in the instant mode, Elvish won't attempt to run
+// `sudo rm -rf /` when you have typed that far.
+//
+// **WARNING**: Functions defined in Elvish code can still change variables,
+// and functions implemented by modules are only restricted if they implement
+// the checks.
 
 func initInstant(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(EmptyBindingMap)
@@ -31,6 +37,9 @@ func initInstant(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 		}).Ns())
 }
 
+var instantRestrictions = eval.Restrictions{
+	NoExternalCommands: true, NoFileWrites: true, NoEnvChanges: true}
+
 func instantStart(app cli.App, ev *eval.Evaler, binding cli.Handler) {
 	execute := func(code string) ([]string, error) {
 		outPort, collect, err := eval.CaptureStringPort()
@@ -40,8 +49,9 @@ func instantStart(app cli.App, ev *eval.Evaler, binding cli.Handler) {
 		err = ev.Eval(
 			parse.Source{Name: "[instant]", Code: code},
 			eval.EvalCfg{
-				Ports:     []*eval.Port{nil, outPort},
-				Interrupt: eval.ListenInterrupts})
+				Ports:        []*eval.Port{nil, outPort},
+				Interrupt:    eval.ListenInterrupts,
+				Restrictions: instantRestrictions})
 		return collect(), err
 	}
 	instant.Start(app, instant.Config{Binding: binding, Execute: execute})
@@ -1,6 +1,7 @@
 package edit
 
 import (
+	"os"
 	"testing"
 
 	"github.com/elves/elvish/pkg/cli"
@@ -61,3 +62,24 @@ func TestInstantAddon_ByteOutput(t *testing.T) {
 		"hello",
 	)
 }
+
+func TestInstantAddon_Restricted(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	cli.SetCodeBuffer(f.Editor.app, cli.CodeBuffer{Content: "echo x > ou", Dot: 11})
+	evals(f.Evaler, "edit:-instant:start")
+	feedInput(f.TTYCtrl, "t")
+	f.TestTTY(t,
+		"~> echo x > out", Styles,
+		"   vvvv   v     ", term.DotHere, "\n",
+		" INSTANT \n", Styles,
+		"*********",
+		"restriction violated: writing files is not allowed", Styles,
+		"!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!",
+		"\n",
+	)
+	if _, err := os.Stat("out"); err == nil {
+		t.Errorf("file written in instant mode")
+	}
+}
@@ -0,0 +1,90 @@
+package edit
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/parse/parseutil"
+)
+
+//elvdoc:fn jump-to-def
+//
+// Opens the definition of the function whose name is under the dot in an
+// external editor, using the [`open-def`](builtin.html#open-def) command.
+//
+// Since the external editor needs the terminal, the current command line is
+// saved and replaced with an `open-def` command, which is then executed. The
+// saved command line is restored at the next prompt.
+
+var errNoFnAtDot = errors.New("no user-defined function at dot")
+
+func initJumpToDef(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	var j jumpToDef
+	appSpec.BeforeReadline = append(appSpec.BeforeReadline,
+		func() { j.restore(ed.app) })
+	nb.AddGoFn("<edit>", "jump-to-def", func() error { return j.jump(ed.app, ev) })
+}
+
+// Keeps the command line saved by jump-to-def.
+type jumpToDef struct {
+	saved *cli.CodeBuffer
+}
+
+func (j *jumpToDef) jump(app cli.App, ev *eval.Evaler) error {
+	buf := app.CodeArea().CopyState().Buffer
+	name, ok := fnNameAtDot(ev, buf)
+	if !ok {
+		return errNoFnAtDot
+	}
+	j.saved = &buf
+	cmd := "open-def $" + name + eval.FnSuffix
+	cli.SetCodeBuffer(app, cli.CodeBuffer{Content: cmd, Dot: len(cmd)})
+	app.CommitCode()
+	return nil
+}
+
+func (j *jumpToDef) restore(app cli.App) {
+	if j.saved != nil {
+		cli.SetCodeBuffer(app, *j.saved)
+		j.saved = nil
+	}
+}
+
+// Finds the name of the user-defined function called by the command under the
+// dot.
+func fnNameAtDot(ev *eval.Evaler, buf cli.CodeBuffer) (string, bool) {
+	tree, _ := parse.Parse(parse.Source{Name: "[interactive]", Code: buf.Content})
+	for n := parseutil.FindLeafNode(tree.Root, buf.Dot); n != nil; n = parse.Parent(n) {
+		form, ok := parse.Parent(n).(*parse.Form)
+		if !ok || form.Head != n {
+			continue
+		}
+		name, err := ev.PurelyEvalCompound(form.Head)
+		if err != nil || strings.IndexFunc(name, notAllowedInVariableName) != -1 {
+			return "", false
+		}
+		if !hasUserDefinedFn(ev, name) {
+			return "", false
+		}
+		return name, true
+	}
+	return "", false
+}
+
+func hasUserDefinedFn(ev *eval.Evaler, name string) bool {
+	fn, ok := lookupCmdFn(ev, name)
+	if !ok {
+		return false
+	}
+	_, ok = eval.SignatureOf(fn)
+	return ok
+}
+
+func notAllowedInVariableName(r rune) bool {
+	return !(r >= 0x80 ||
+		('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') ||
+		r == '-' || r == '_' || r == ':')
+}
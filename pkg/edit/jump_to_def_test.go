@@ -0,0 +1,84 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestJumpToDef(t *testing.T) {
+	f := setup(rc(`fn f { }`))
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "f a b")
+	f.TTYCtrl.Inject(term.K(ui.Home), term.K(']', ui.Ctrl))
+	if code := <-f.codeCh; code != "open-def $f~" {
+		t.Errorf("got code %q, want %q", code, "open-def $f~")
+	}
+}
+
+func TestJumpToDef_RestoresCommandLine(t *testing.T) {
+	app := cli.NewApp(cli.AppSpec{})
+	ev := eval.NewEvaler()
+	evals(ev, `fn f { }`)
+	cli.SetCodeBuffer(app, cli.CodeBuffer{Content: "f a b", Dot: 1})
+
+	var j jumpToDef
+	err := j.jump(app, ev)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	cli.SetCodeBuffer(app, cli.CodeBuffer{})
+	j.restore(app)
+	wantBuf := cli.CodeBuffer{Content: "f a b", Dot: 1}
+	if buf := app.CodeArea().CopyState().Buffer; buf != wantBuf {
+		t.Errorf("got buffer %v, want %v", buf, wantBuf)
+	}
+	// The saved command line is only restored once.
+	cli.SetCodeBuffer(app, cli.CodeBuffer{})
+	j.restore(app)
+	if buf := app.CodeArea().CopyState().Buffer; buf != (cli.CodeBuffer{}) {
+		t.Errorf("got buffer %v, want empty", buf)
+	}
+}
+
+func TestJumpToDef_NoUserDefinedFn(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	feedInput(f.TTYCtrl, "put a")
+	f.TTYCtrl.Inject(term.K(']', ui.Ctrl))
+	f.TestTTYNotes(t,
+		"[binding error] no user-defined function at dot")
+}
+
+func TestFnNameAtDot(t *testing.T) {
+	f := setup(rc(`fn f { }`, `m: = (ns [&g~={ }])`))
+	defer f.Cleanup()
+
+	tests := []struct {
+		code     string
+		dot      int
+		wantName string
+		wantOK   bool
+	}{
+		{"f a", 0, "f", true},
+		{"f a", 1, "f", true},
+		{"f a", 3, "", false},
+		{"put (f a)", 6, "f", true},
+		{"echo | m:g", 10, "m:g", true},
+		{"put a", 0, "", false},
+		{"nonexistent", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, test := range tests {
+		name, ok := fnNameAtDot(f.Evaler, cli.CodeBuffer{Content: test.code, Dot: test.dot})
+		if name != test.wantName || ok != test.wantOK {
+			t.Errorf("fnNameAtDot(%q, %d) -> (%q, %v), want (%q, %v)",
+				test.code, test.dot, name, ok, test.wantName, test.wantOK)
+		}
+	}
+}
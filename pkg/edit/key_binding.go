@@ -1,40 +1,51 @@
 package edit
 
 import (
-	"bufio"
-	"io"
-	"os"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/cli/term"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
 	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/strutil"
 	"github.com/elves/elvish/pkg/ui"
 )
 
 type mapBinding struct {
-	nt      notifier
-	ev      *eval.Evaler
-	mapVars []vars.PtrVar
+	nt notifier
+	ev *eval.Evaler
+	// The current values of the binding variables, updated by watchers when
+	// the variables are assigned.
+	mutex sync.RWMutex
+	maps  []BindingMap
 }
 
 func newMapBinding(nt notifier, ev *eval.Evaler, mapVars ...vars.PtrVar) cli.Handler {
-	return mapBinding{nt, ev, mapVars}
+	b := &mapBinding{nt: nt, ev: ev, maps: make([]BindingMap, len(mapVars))}
+	for i, v := range mapVars {
+		i := i
+		b.maps[i] = v.GetRaw().(BindingMap)
+		v.Watch(func(m interface{}) {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			b.maps[i] = m.(BindingMap)
+		})
+	}
+	return b
 }
 
-func (b mapBinding) Handle(e term.Event) bool {
+func (b *mapBinding) Handle(e term.Event) bool {
 	k, ok := e.(term.KeyEvent)
 	if !ok {
 		return false
 	}
-	maps := make([]BindingMap, len(b.mapVars))
-	for i, v := range b.mapVars {
-		maps[i] = v.GetRaw().(BindingMap)
-	}
-	f := indexLayeredBindings(ui.Key(k), maps...)
+	b.mutex.RLock()
+	f := indexLayeredBindings(ui.Key(k), b.maps...)
+	b.mutex.RUnlock()
 	if f == nil {
 		return false
 	}
@@ -61,54 +72,64 @@ func indexLayeredBindings(k ui.Key, bindings ...BindingMap) eval.Callable {
 var bindingSource = parse.Source{Name: "[editor binding]"}
 
 func callWithNotifyPorts(nt notifier, ev *eval.Evaler, f eval.Callable, args ...interface{}) {
-	notifyPort, cleanup := makeNotifyPort(nt)
-	defer cleanup()
-
-	err := ev.Call(f,
-		eval.CallCfg{Args: args, From: "[editor binding]"},
-		eval.EvalCfg{Ports: []*eval.Port{nil, notifyPort, notifyPort}})
+	cbs, summarize := notifyCallbacks(nt)
+	err := ev.CallWithOutputCallback(f,
+		eval.CallCfg{Args: args, From: "[editor binding]"}, eval.EvalCfg{}, cbs)
+	summarize()
 	if err != nil {
-		nt.notifyError("binding", err)
+		nt.notifyBindingError(err)
 	}
 }
 
-func makeNotifyPort(nt notifier) (*eval.Port, func()) {
-	ch := make(chan interface{})
-	r, w, err := os.Pipe()
-	if err != nil {
-		panic(err)
-	}
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		// Relay value outputs
-		for v := range ch {
-			nt.notifyf("[value out] %s", vals.Repr(v, vals.NoPretty))
+// Calls a function and returns its outputs, with byte outputs split into lines
+// like in output captures. The stderr of the function is shown as notes.
+func callForOutputs(nt notifier, ev *eval.Evaler, f eval.Callable, callCfg eval.CallCfg) ([]interface{}, error) {
+	var outs []interface{}
+	cbs, summarize := notifyCallbacks(nt)
+	cbs.Value = func(v interface{}) { outs = append(outs, v) }
+	cbs.Bytes = func(line string) { outs = append(outs, strutil.ChopLineEnding(line)) }
+	err := ev.CallWithOutputCallback(f, callCfg, eval.EvalCfg{}, cbs)
+	summarize()
+	return outs, err
+}
+
+// Maximum number of notes generated from the output of a single call. The rest
+// of the output is summarized in one note.
+const maxNotifyNotes = 100
+
+// How long to wait for the outputs after the call returns. Processes started
+// in the background may keep the outputs open indefinitely, so the outputs are
+// abandoned after this much time.
+var notifyDrainTimeout = 100 * time.Millisecond
+
+// Returns output callbacks that show value and byte outputs as notes. The
+// returned function shows how many lines have been omitted; it should be
+// called after the callbacks are no longer used.
+func notifyCallbacks(nt notifier) (eval.OutputCallbacks, func()) {
+	notes, omitted := 0, 0
+	notifyf := func(format string, args ...interface{}) {
+		if notes < maxNotifyNotes {
+			notes++
+			nt.notifyf(format, args...)
+		} else {
+			omitted++
 		}
-		wg.Done()
-	}()
-	go func() {
-		// Relay byte outputs
-		reader := bufio.NewReader(r)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if line != "" {
-					nt.notifyf("[bytes out] %s", line)
-				}
-				if err != io.EOF {
-					nt.notifyf("[bytes error] %s", err)
-				}
-				break
-			}
-			nt.notifyf("[bytes out] %s", line[:len(line)-1])
+	}
+	notifyBytes := func(line string) {
+		notifyf(msg.Get("note-binding-bytes-out"), strutil.ChopLineEnding(line))
+	}
+	cbs := eval.OutputCallbacks{
+		Value: func(v interface{}) {
+			notifyf(msg.Get("note-binding-value-out"), vals.Repr(v, vals.NoPretty))
+		},
+		Bytes:        notifyBytes,
+		StderrBytes:  notifyBytes,
+		DrainTimeout: notifyDrainTimeout,
+	}
+	summarize := func() {
+		if omitted > 0 {
+			nt.notifyf(msg.Get("note-binding-lines-omitted"), omitted)
 		}
-		wg.Done()
-	}()
-	port := &eval.Port{Chan: ch, File: w, CloseChan: true, CloseFile: true}
-	cleanup := func() {
-		port.Close()
-		wg.Wait()
 	}
-	return port, cleanup
+	return cbs, summarize
 }
@@ -0,0 +1,65 @@
+package edit
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+)
+
+type fakeNotifier struct {
+	mutex sync.Mutex
+	notes []string
+}
+
+func (n *fakeNotifier) notifyf(format string, args ...interface{}) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.notes = append(n.notes, fmt.Sprintf(format, args...))
+}
+
+func (n *fakeNotifier) notifyError(ctx string, e error) {
+	n.notifyf("[%v error] %v", ctx, e)
+}
+
+func (n *fakeNotifier) notifyBindingError(e error) {
+	n.notifyError("binding", e)
+}
+
+func TestCallWithNotifyPorts(t *testing.T) {
+	var nt fakeNotifier
+	f := eval.NewGoFn("f", func(fm *eval.Frame) {
+		fm.OutputChan() <- "foo"
+		fm.OutputFile().WriteString("bar\nbaz")
+		fm.ErrorFile().WriteString("quux\n")
+	})
+	callWithNotifyPorts(&nt, eval.NewEvaler(), f)
+
+	// The relative order of the outputs is not deterministic.
+	sort.Strings(nt.notes)
+	wantNotes := []string{
+		"[bytes out] bar", "[bytes out] baz", "[bytes out] quux", "[value out] foo"}
+	if !reflect.DeepEqual(nt.notes, wantNotes) {
+		t.Errorf("got notes %q, want %q", nt.notes, wantNotes)
+	}
+}
+
+func TestNotifyCallbacks_SummarizesOverflow(t *testing.T) {
+	var nt fakeNotifier
+	cbs, summarize := notifyCallbacks(&nt)
+	for i := 0; i < maxNotifyNotes+10; i++ {
+		cbs.Bytes("line\n")
+	}
+	summarize()
+
+	if len(nt.notes) != maxNotifyNotes+1 {
+		t.Fatalf("got %d notes, want %d", len(nt.notes), maxNotifyNotes+1)
+	}
+	wantLast := "[10 more lines of output omitted]"
+	if last := nt.notes[len(nt.notes)-1]; last != wantLast {
+		t.Errorf("got last note %q, want %q", last, wantLast)
+	}
+}
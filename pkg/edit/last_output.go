@@ -0,0 +1,184 @@
+package edit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/addons/listing"
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+//elvdoc:var last-output:enabled
+//
+// A boolean that controls whether the byte output of commands entered in the
+// editor is saved, so that it can be inserted into later commands. Defaults to
+// `$false`.
+//
+// When enabled, commands write their output to a pipe instead of the terminal,
+// and Elvish copies it to the terminal. As a result, programs that behave
+// differently when writing to a terminal (for instance, `ls` disabling colors
+// or full-screen programs like `vim`) may not work as expected.
+
+//elvdoc:var last-output:lines
+//
+// A read-only list of the lines the last command wrote to its standard output.
+// Only the last 1000 lines are kept. This is only updated when
+// `$edit:last-output:enabled` is true.
+
+//elvdoc:var last-output:binding
+//
+// Binding for the last-output mode.
+
+//elvdoc:fn last-output:start
+//
+// Starts the last-output mode, which shows the lines in
+// `$edit:last-output:lines` along with their indices. Typing filters the lines
+// by either their indices or their content, and accepting a line inserts it at
+// the dot.
+
+// Maximum number of lines kept from the output of the last command.
+const maxLastOutputLines = 1000
+
+// How long to wait for the output of a command to be drained after the command
+// has finished. Background jobs may keep the pipe open indefinitely.
+var lastOutputDrainTimeout = 100 * time.Millisecond
+
+// Keeps the output of the last command.
+type lastOutput struct {
+	enabled vars.PtrVar
+
+	linesMutex sync.RWMutex
+	lines      vals.List
+}
+
+func initLastOutput(ed *Editor, ev *eval.Evaler, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
+	lo := &lastOutput{enabled: newBoolVar(false), lines: vals.EmptyList}
+	ed.lastOutput = lo
+	bindingVar := newBindingVar(EmptyBindingMap)
+	binding := newListingBinding(ed, ev, "last-output", bindingVar, commonBindingVar)
+	nb.AddNs("last-output",
+		eval.NsBuilder{
+			"binding": bindingVar,
+			"enabled": lo.enabled,
+			"lines": vars.FromGet(func() interface{} {
+				lo.linesMutex.RLock()
+				defer lo.linesMutex.RUnlock()
+				return lo.lines
+			}),
+		}.AddGoFn("<edit:last-output>", "start", func() {
+			lo.start(ed.app, binding)
+		}).Ns())
+}
+
+func (lo *lastOutput) start(app cli.App, binding cli.Handler) {
+	lo.linesMutex.RLock()
+	lines := lo.lines
+	lo.linesMutex.RUnlock()
+	if lines.Len() == 0 {
+		app.Notify(msg.Get("note-no-saved-output"))
+		return
+	}
+	listing.Start(app, listing.Config{
+		Binding: binding,
+		Caption: " " + msg.Get("mode-last-output") + " ",
+		GetItems: func(q string) ([]listing.Item, int) {
+			var items []listing.Item
+			i := 0
+			for it := lines.Iterator(); it.HasElem(); it.Next() {
+				line := it.Elem().(string)
+				index := strconv.Itoa(i)
+				if strings.HasPrefix(index, q) || strings.Contains(line, q) {
+					items = append(items, listing.Item{
+						ToAccept: line,
+						ToShow:   ui.T(fmt.Sprintf("%3s %s", index, line))})
+				}
+				i++
+			}
+			return items, 0
+		},
+		Accept: func(s string) bool {
+			insertAtDot(app, s)
+			return false
+		},
+	})
+}
+
+func (lo *lastOutput) setLines(lines []string) {
+	if len(lines) > maxLastOutputLines {
+		lines = lines[len(lines)-maxLastOutputLines:]
+	}
+	list := vals.EmptyList
+	for _, line := range lines {
+		list = list.Cons(line)
+	}
+	lo.linesMutex.Lock()
+	defer lo.linesMutex.Unlock()
+	lo.lines = list
+}
+
+// CaptureOutput prepares for capturing the output of a command that would
+// normally write to out. It returns the file the command should write to
+// instead, and a function that should be called after the command has
+// finished.
+//
+// If $edit:last-output:enabled is false, it returns out itself and a function
+// that does nothing. Otherwise, it returns the write end of a pipe, whose
+// content is copied to out and saved for $edit:last-output:lines when the
+// returned function is called.
+func (ed *Editor) CaptureOutput(out *os.File) (*os.File, func()) {
+	lo := ed.lastOutput
+	if !lo.enabled.GetRaw().(bool) {
+		return out, func() {}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		ed.notifyError("last-output", err)
+		return out, func() {}
+	}
+
+	var linesMutex sync.Mutex
+	var lines []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer r.Close()
+		buffered := bufio.NewReader(io.TeeReader(r, out))
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				linesMutex.Lock()
+				lines = append(lines, strings.TrimRight(line, "\r\n"))
+				if len(lines) > 2*maxLastOutputLines {
+					// Avoid keeping the entire output of long-running commands.
+					lines = append([]string(nil), lines[len(lines)-maxLastOutputLines:]...)
+				}
+				linesMutex.Unlock()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return w, func() {
+		w.Close()
+		select {
+		case <-done:
+		case <-time.After(lastOutputDrainTimeout):
+		}
+		linesMutex.Lock()
+		defer linesMutex.Unlock()
+		lo.setLines(lines)
+	}
+}
@@ -0,0 +1,101 @@
+package edit
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestCaptureOutput_Disabled(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	out, done := f.Editor.CaptureOutput(os.Stdout)
+	if out != os.Stdout {
+		t.Errorf("got %v, want os.Stdout", out)
+	}
+	done()
+	evals(f.Evaler, `lines = $edit:last-output:lines`)
+	testGlobal(t, f.Evaler, "lines", vals.EmptyList)
+}
+
+func TestCaptureOutput_Enabled(t *testing.T) {
+	f := setup(rc(`edit:last-output:enabled = $true`))
+	defer f.Cleanup()
+
+	r, w := mustPipe()
+	capture(f.Editor, w, "foo\nbar\r\nlorem")
+	w.Close()
+
+	if data, _ := ioutil.ReadAll(r); string(data) != "foo\nbar\r\nlorem" {
+		t.Errorf("got output %q, want %q", data, "foo\nbar\r\nlorem")
+	}
+	evals(f.Evaler, `lines = $edit:last-output:lines`)
+	testGlobal(t, f.Evaler, "lines", vals.MakeList("foo", "bar", "lorem"))
+}
+
+func TestCaptureOutput_KeepsLastLines(t *testing.T) {
+	f := setup(rc(`edit:last-output:enabled = $true`))
+	defer f.Cleanup()
+
+	r, w := mustPipe()
+	go ioutil.ReadAll(r)
+	var data []byte
+	for i := 0; i < maxLastOutputLines*3; i++ {
+		data = append(data, "line\n"...)
+	}
+	capture(f.Editor, w, string(data))
+	w.Close()
+	evals(f.Evaler, `n = (count $edit:last-output:lines)`)
+	testGlobal(t, f.Evaler, "n", "1000")
+}
+
+func TestLastOutputAddon(t *testing.T) {
+	f := setup(rc(`edit:last-output:enabled = $true`))
+	defer f.Cleanup()
+
+	r, w := mustPipe()
+	go ioutil.ReadAll(r)
+	capture(f.Editor, w, "/usr/bin\n/home/elf\n")
+	w.Close()
+
+	f.TTYCtrl.Inject(term.K('o', ui.Alt))
+	f.TestTTY(t,
+		"~> \n",
+		" LAST OUTPUT  ", Styles,
+		"************* ", term.DotHere, "\n",
+		"  0 /usr/bin                                      \n", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+		"  1 /home/elf                                     ",
+	)
+
+	f.TTYCtrl.Inject(term.K('1'), term.K('\n'))
+	f.TestTTY(t, "~> /home/elf", Styles,
+		"   !!!!!!!!!", term.DotHere)
+}
+
+func TestLastOutputAddon_NoSavedOutput(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(term.K('o', ui.Alt))
+	f.TestTTYNotes(t, "no saved output")
+}
+
+func capture(ed *Editor, out *os.File, s string) {
+	w, done := ed.CaptureOutput(out)
+	w.WriteString(s)
+	done()
+}
+
+func mustPipe() (*os.File, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	return r, w
+}
@@ -2,6 +2,7 @@ package edit
 
 import (
 	"os"
+	"sync"
 
 	"github.com/elves/elvish/pkg/cli"
 	"github.com/elves/elvish/pkg/cli/addons/histlist"
@@ -41,12 +42,14 @@ func initListings(ed *Editor, ev *eval.Evaler, st store.Store, histStore histuti
 
 	initHistlist(ed, ev, histStore, bindingVar, nb)
 	initLastcmd(ed, ev, histStore, bindingVar, nb)
+	initLastOutput(ed, ev, bindingVar, nb)
 	initLocation(ed, ev, st, bindingVar, nb)
+	initPalette(ed, ev, bindingVar, nb)
 }
 
 func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(EmptyBindingMap)
-	binding := newMapBinding(ed, ev, bindingVar, commonBindingVar)
+	binding := newListingBinding(ed, ev, "histlist", bindingVar, commonBindingVar)
 	dedup := newBoolVar(true)
 	caseSensitive := newBoolVar(true)
 	nb.AddNs("histlist",
@@ -62,6 +65,8 @@ func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonB
 					Dedup: func() bool {
 						return dedup.Get().(bool)
 					},
+					Filter: adaptMatcherForMode(
+						ed, ev, ed.matchers.Get().(vals.Map), "histlist"),
 				})
 			},
 			"toggle-case-sensitivity": func() {
@@ -79,7 +84,7 @@ func initHistlist(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonB
 
 func initLastcmd(ed *Editor, ev *eval.Evaler, histStore histutil.Store, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(EmptyBindingMap)
-	binding := newMapBinding(ed, ev, bindingVar, commonBindingVar)
+	binding := newListingBinding(ed, ev, "lastcmd", bindingVar, commonBindingVar)
 	nb.AddNs("lastcmd",
 		eval.NsBuilder{
 			"binding": bindingVar,
@@ -96,7 +101,8 @@ func initLocation(ed *Editor, ev *eval.Evaler, st store.Store, commonBindingVar
 	hiddenVar := newListVar(vals.EmptyList)
 	workspacesVar := newMapVar(vals.EmptyMap)
 
-	binding := newMapBinding(ed, ev, bindingVar, commonBindingVar)
+	binding := newListingBinding(ed, ev, "location", bindingVar, commonBindingVar)
+	recent := &recentDirs{}
 	workspaceIterator := location.WorkspaceIterator(
 		adaptToIterateStringPair(workspacesVar))
 
@@ -111,7 +117,10 @@ func initLocation(ed *Editor, ev *eval.Evaler, st store.Store, commonBindingVar
 				Binding: binding, Store: dirStore{ev, st},
 				IteratePinned:     adaptToIterateString(pinnedVar),
 				IterateHidden:     adaptToIterateString(hiddenVar),
+				IterateRecent:     recent.iterate,
 				IterateWorkspaces: workspaceIterator,
+				Filter: adaptMatcherForMode(
+					ed, ev, ed.matchers.Get().(vals.Map), "location"),
 			})
 		}).Ns())
 	ev.AddAfterChdir(func(string) {
@@ -121,6 +130,7 @@ func initLocation(ed *Editor, ev *eval.Evaler, st store.Store, commonBindingVar
 			return
 		}
 		st.AddDir(wd, 1)
+		recent.add(wd)
 		kind, root := workspaceIterator.Parse(wd)
 		if kind != "" {
 			st.AddDir(kind+wd[len(root):], 1)
@@ -128,6 +138,37 @@ func initLocation(ed *Editor, ev *eval.Evaler, st store.Store, commonBindingVar
 	})
 }
 
+// Maximum number of recently visited directories that are shown first in the
+// location mode.
+const maxRecentDirs = 5
+
+// Keeps the directories visited in this session, most recent first.
+type recentDirs struct {
+	mutex sync.Mutex
+	dirs  []string
+}
+
+func (r *recentDirs) add(dir string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	dirs := []string{dir}
+	for _, d := range r.dirs {
+		if d != dir && len(dirs) < maxRecentDirs {
+			dirs = append(dirs, d)
+		}
+	}
+	r.dirs = dirs
+}
+
+func (r *recentDirs) iterate(f func(string)) {
+	r.mutex.Lock()
+	dirs := r.dirs
+	r.mutex.Unlock()
+	for _, dir := range dirs {
+		f(dir)
+	}
+}
+
 //elvdoc:fn listing:accept
 //
 // Accepts the current selected listing item.
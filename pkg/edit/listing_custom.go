@@ -3,7 +3,6 @@ package edit
 import (
 	"bufio"
 	"os"
-	"strings"
 	"sync"
 
 	"github.com/elves/elvish/pkg/cli"
@@ -16,11 +15,12 @@ import (
 )
 
 type customListingOpts struct {
-	Binding    BindingMap
-	Caption    string
-	KeepBottom bool
-	Accept     eval.Callable
-	AutoAccept bool
+	Binding        BindingMap
+	Caption        string
+	KeepBottom     bool
+	Accept         eval.Callable
+	AutoAccept     bool
+	FoldDiacritics bool
 }
 
 func (*customListingOpts) SetDefaultOptions() {}
@@ -28,6 +28,12 @@ func (*customListingOpts) SetDefaultOptions() {}
 //elvdoc:fn listing:start-custom
 //
 // Starts a custom listing addon.
+//
+// When the items are given as a list, the items whose `to-filter` contains the
+// filter text are shown. The match is case-insensitive unless the filter text
+// contains uppercase letters, and different Unicode forms of the same
+// character match each other. If the `&fold-diacritics` option is true,
+// diacritics are ignored too, so that `cafe` matches `café`.
 
 func listingStartCustom(ed *Editor, fm *eval.Frame, opts customListingOpts, items interface{}) {
 	var binding cli.Handler
@@ -76,7 +82,7 @@ func listingStartCustom(ed *Editor, fm *eval.Frame, opts customListingOpts, item
 			vals.Iterate(items, func(v interface{}) bool {
 				toFilter, toFilterOk := getToFilter(v)
 				item, itemOk := getListingItem(v)
-				if toFilterOk && itemOk && strings.Contains(toFilter, q) {
+				if toFilterOk && itemOk && listing.MatchFilter(toFilter, q, opts.FoldDiacritics) {
 					// TODO(xiaq): Report type error when ok is false.
 					convertedItems = append(convertedItems, item)
 				}
@@ -39,6 +39,34 @@ func TestLocationAddon(t *testing.T) {
 	)
 }
 
+func TestLocationAddon_RecentDirsFirst(t *testing.T) {
+	f := setup(storeOp(func(s store.Store) {
+		s.AddDir("/usr/bin", 1)
+		s.AddDir("/usr/bin", 1)
+		s.AddDir("/usr/bin", 1)
+	}))
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a": testutil.Dir{}, "b": testutil.Dir{}})
+
+	for _, dir := range []string{"a", "../b", ".."} {
+		err := f.Evaler.Chdir(dir)
+		if err != nil {
+			t.Fatal("chdir:", err)
+		}
+	}
+
+	f.TTYCtrl.Inject(term.K('L', ui.Ctrl))
+	f.TestTTY(t,
+		"~> \n",
+		" LOCATION  ", Styles,
+		"********** ", term.DotHere, "\n",
+		" 10 ~/b                                           \n", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+		" 10 ~/a\n",
+		" 28 /usr/bin",
+	)
+}
+
 func TestLocationAddon_Workspace(t *testing.T) {
 	f := setup(storeOp(func(s store.Store) {
 		s.AddDir("/usr/bin", 1)
@@ -62,6 +62,24 @@ func TestHistlistAddon(t *testing.T) {
 	)
 }
 
+func TestHistlistAddon_UsesMatcher(t *testing.T) {
+	f := setup(storeOp(func(s store.Store) {
+		s.AddCmd("ls")
+		s.AddCmd("echo ls")
+	}))
+	defer f.Cleanup()
+	evals(f.Evaler, `edit:completion:matcher[histlist] = $edit:match-prefix~`)
+
+	f.TTYCtrl.Inject(term.K('R', ui.Ctrl), term.K('l'))
+	f.TestTTY(t,
+		"~> \n",
+		" HISTORY (dedup on)  l", Styles,
+		"********************  ", term.DotHere, "\n",
+		"   1 ls                                           ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
 func TestLastCmdAddon(t *testing.T) {
 	f := setup(storeOp(func(s store.Store) {
 		s.AddCmd("echo hello world")
@@ -108,6 +126,24 @@ func TestCustomListing_PassingList(t *testing.T) {
 	)
 }
 
+func TestCustomListing_FiltersList(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`items = [[&to-filter=Café &to-accept=cafe &to-show=Café]
+		          [&to-filter=car &to-accept=car &to-show=car]]`,
+		`edit:listing:start-custom $items &caption=A &fold-diacritics`)
+	f.TTYCtrl.Inject(term.K('c'), term.K('a'), term.K('f'), term.K('e'))
+	f.TestTTY(t,
+		"~> \n",
+		"A cafe", Styles,
+		"*     ", term.DotHere, "\n",
+		"Café                                              ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
 func TestCustomListing_PassingValueCallback(t *testing.T) {
 	f := setup()
 	defer f.Cleanup()
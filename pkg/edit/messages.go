@@ -0,0 +1,54 @@
+package edit
+
+import (
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var messages
+//
+// A map from message IDs to texts, overriding the texts of messages shown by
+// the editor, like the names of modes and notes. Defaults to an empty map.
+// Entries with unknown IDs or non-string values are ignored.
+//
+// Some messages contain placeholders like `%s` and `%d`, which are replaced by
+// their arguments; overriding texts should contain the same placeholders in the
+// same order. The names of modes are shown with spaces around them, so they
+// should not include the spaces.
+//
+// Example:
+//
+// ```elvish
+// edit:messages = [&mode-history=HIST &note-end-of-history='no more history']
+// ```
+//
+// When no override is given, the editor shows the built-in English text.
+//
+// @cf edit:builtin-messages
+
+//elvdoc:var builtin-messages
+//
+// A read-only map from all message IDs to their built-in English texts. Useful
+// for finding the IDs of messages to override in
+// [`$edit:messages`](#editmessages).
+
+func initMessages(nb eval.NsBuilder) {
+	messages := newMapVar(vals.EmptyMap)
+	msg.SetOverride(func(id string) (string, bool) {
+		text, ok := messages.Get().(vals.Map).Index(id)
+		if !ok {
+			return "", false
+		}
+		s, ok := text.(string)
+		return s, ok
+	})
+	nb["messages"] = messages
+
+	builtin := vals.EmptyMap
+	for id, text := range msg.Builtin() {
+		builtin = builtin.Assoc(id, text)
+	}
+	nb["builtin-messages"] = vars.NewReadOnly(builtin)
+}
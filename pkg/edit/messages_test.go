@@ -0,0 +1,38 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestMessages_Override(t *testing.T) {
+	f := setup(rc(
+		`edit:messages = [&mode-command=CMD]`,
+		`edit:insert:binding[Ctrl-'['] = $edit:command:start~`))
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(term.K('[', ui.Ctrl))
+	f.TestTTY(t,
+		"~> ", term.DotHere, "\n",
+		" CMD ", Styles,
+		"*****",
+	)
+}
+
+func TestMessages_OverrideNote(t *testing.T) {
+	f := setup(rc(`edit:messages = [&note-end-of-history='no more']`))
+	defer f.Cleanup()
+
+	evals(f.Evaler, `edit:end-of-history`)
+	f.TestTTYNotes(t, "no more")
+}
+
+func TestBuiltinMessages(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `text = $edit:builtin-messages[mode-history]`)
+	testGlobal(t, f.Evaler, "text", "HISTORY")
+}
@@ -2,6 +2,7 @@ package edit
 
 import (
 	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/msg"
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/parse"
 )
@@ -19,7 +20,7 @@ func initMinibuf(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 
 func minibufStart(ed *Editor, ev *eval.Evaler, binding cli.Handler) {
 	w := cli.NewCodeArea(cli.CodeAreaSpec{
-		Prompt:         cli.ModePrompt(" MINIBUF ", true),
+		Prompt:         cli.ModePrompt(" "+msg.Get("mode-minibuf")+" ", true),
 		OverlayHandler: binding,
 		OnSubmit:       func() { minibufSubmit(ed, ev) },
 		// TODO: Add Highlighter. Right now the async highlighter is not
@@ -38,10 +39,16 @@ func minibufSubmit(ed *Editor, ev *eval.Evaler) {
 	cli.SetAddon(app, nil)
 	code := codeArea.CopyState().Buffer.Content
 	src := parse.Source{Name: "[minibuf]", Code: code}
-	notifyPort, cleanup := makeNotifyPort(ed)
-	defer cleanup()
-	ports := []*eval.Port{eval.DevNullClosedChan, notifyPort, notifyPort}
-	err := ev.Eval(src, eval.EvalCfg{Ports: ports})
+	cbs, summarize := notifyCallbacks(ed)
+	stdout, stderr, done, err := eval.CallbackPorts(cbs)
+	if err != nil {
+		app.Notify(err.Error())
+		return
+	}
+	ports := []*eval.Port{eval.DevNullClosedChan, stdout, stderr}
+	err = ev.Eval(src, eval.EvalCfg{Ports: ports})
+	done()
+	summarize()
 	if err != nil {
 		app.Notify(err.Error())
 	}
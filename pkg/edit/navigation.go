@@ -125,6 +125,9 @@ func initNavigation(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 			"start": func() {
 				navigation.Start(app, navigation.Config{
 					Binding: binding,
+					// Change directories with the Evaler, so that the chdir
+					// hooks are run.
+					Cursor: navigation.NewOSCursor(ev.Chdir),
 					WidthRatio: func() [3]int {
 						return convertNavWidthRatio(widthRatioVar.Get())
 					},
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/elves/elvish/pkg/cli/lscolors"
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/testutil"
 
 	"github.com/elves/elvish/pkg/cli/term"
@@ -59,6 +60,25 @@ func TestNavigation(t *testing.T) {
 	)
 }
 
+func TestNavigation_RunsChdirHooks(t *testing.T) {
+	f, cleanup := setupNav()
+	defer cleanup()
+
+	evals(f.Evaler, `dirs = []`, `@after-chdir = [d]{ dirs = [$@dirs $d] }`)
+	f.TTYCtrl.Inject(term.K('N', ui.Ctrl))
+	f.TestTTY(t,
+		filepath.Join("~", "d"), "> ", term.DotHere, "\n",
+		" NAVIGATING  \n", Styles,
+		"************ ",
+		" d      a                 \n", Styles,
+		"###### ++++++++++++++++++ ",
+		"        e                ", Styles,
+		"       //////////////////",
+	)
+	evals(f.Evaler, `edit:navigation:down`, `edit:navigation:right`, `edit:navigation:left`)
+	testGlobal(t, f.Evaler, "dirs", vals.MakeList("e", ".."))
+}
+
 func TestNavigation_WidthRatio(t *testing.T) {
 	f, cleanup := setupNav()
 	defer cleanup()
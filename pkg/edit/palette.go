@@ -0,0 +1,170 @@
+package edit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/addons/listing"
+	"github.com/elves/elvish/pkg/cli/msg"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+//elvdoc:var palette:binding
+//
+// Binding for the command palette mode.
+
+//elvdoc:fn palette:start
+//
+// Starts the command palette mode, which lists the functions of the editor
+// that can be called without arguments, along with the keys bound to them in
+// each mode. Functions that are bound to keys but not part of the editor, like
+// user-defined functions, are listed too. Typing filters the commands by their
+// names and keys, and accepting a command calls it.
+//
+// The mode has no default key binding; it can be bound like this:
+//
+// ```elvish
+// edit:insert:binding[Ctrl-P] = $edit:palette:start~
+// ```
+
+func initPalette(ed *Editor, ev *eval.Evaler, commonBindingVar vars.PtrVar, nb eval.NsBuilder) {
+	bindingVar := newBindingVar(EmptyBindingMap)
+	binding := newListingBinding(ed, ev, "palette", bindingVar, commonBindingVar)
+	nb.AddNs("palette",
+		eval.NsBuilder{
+			"binding": bindingVar,
+		}.AddGoFn("<edit:palette>", "start", func() {
+			paletteStart(ed, ev, binding)
+		}).Ns())
+}
+
+// A command shown in the command palette.
+type paletteCommand struct {
+	name string
+	fn   eval.Callable
+	// Keys bound to the command, like "Ctrl-R (insert)".
+	keys []string
+}
+
+func paletteStart(ed *Editor, ev *eval.Evaler, binding cli.Handler) {
+	cmds := paletteCommands(ed.ns)
+	shown := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		shown[i] = cmd.name
+		if len(cmd.keys) > 0 {
+			shown[i] += "  " + strings.Join(cmd.keys, ", ")
+		}
+	}
+	listing.Start(ed.app, listing.Config{
+		Binding: binding,
+		Caption: " " + msg.Get("mode-palette") + " ",
+		GetItems: func(q string) ([]listing.Item, int) {
+			var items []listing.Item
+			for i, s := range shown {
+				if listing.MatchFilter(s, q, false) {
+					items = append(items, listing.Item{
+						ToAccept: strconv.Itoa(i), ToShow: ui.T(s)})
+				}
+			}
+			return items, 0
+		},
+		Accept: func(s string) bool {
+			i, _ := strconv.Atoi(s)
+			// The command may start another mode, so the palette is closed
+			// before calling it.
+			cli.SetAddon(ed.app, nil)
+			callWithNotifyPorts(ed, ev, cmds[i].fn)
+			return true
+		},
+	})
+}
+
+// Collects the functions in the editor namespace that can be called without
+// arguments, and the functions bound in the binding tables of the modes.
+// Functions and namespaces whose names start with "-" are left out.
+func paletteCommands(ns *eval.Ns) []*paletteCommand {
+	var builtins, others []*paletteCommand
+	byFn := map[eval.Callable]*paletteCommand{}
+	var bindings []modeBinding
+	var walk func(ns *eval.Ns, prefix string)
+	walk = func(ns *eval.Ns, prefix string) {
+		ns.IterateKeys(func(k interface{}) bool {
+			name := k.(string)
+			if strings.HasPrefix(name, "-") {
+				return true
+			}
+			v, _ := ns.Index(name)
+			switch {
+			case strings.HasSuffix(name, eval.FnSuffix):
+				fn, ok := v.(eval.Callable)
+				if ok && !eval.RequiresArgs(fn) {
+					cmd := &paletteCommand{
+						name: "edit:" + prefix + strings.TrimSuffix(name, eval.FnSuffix), fn: fn}
+					builtins = append(builtins, cmd)
+					byFn[fn] = cmd
+				}
+			case strings.HasSuffix(name, eval.NsSuffix):
+				if sub, ok := v.(*eval.Ns); ok {
+					walk(sub, prefix+name)
+				}
+			case name == "binding":
+				if m, ok := v.(BindingMap); ok && prefix != "" {
+					bindings = append(bindings,
+						modeBinding{strings.TrimSuffix(prefix, eval.NsSuffix), m})
+				}
+			}
+			return true
+		})
+	}
+	if ns != nil {
+		walk(ns, "")
+	}
+
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].mode < bindings[j].mode })
+	for _, b := range bindings {
+		var keys []ui.Key
+		for it := b.m.Iterator(); it.HasElem(); it.Next() {
+			k, _ := it.Elem()
+			keys = append(keys, k.(ui.Key))
+		}
+		sort.Sort(ui.Keys(keys))
+		for _, k := range keys {
+			fn := b.m.GetKey(k)
+			cmd, ok := byFn[fn]
+			if !ok {
+				cmd = &paletteCommand{name: callableName(fn), fn: fn}
+				others = append(others, cmd)
+				byFn[fn] = cmd
+			}
+			cmd.keys = append(cmd.keys, fmt.Sprintf("%s (%s)", k, b.mode))
+		}
+	}
+
+	sortPaletteCommands(builtins)
+	sortPaletteCommands(others)
+	return append(builtins, others...)
+}
+
+type modeBinding struct {
+	mode string
+	m    BindingMap
+}
+
+// Returns the name of a function that is not part of the editor, which is its
+// definition with whitespaces collapsed if it is user-defined.
+func callableName(fn eval.Callable) string {
+	if def, err := vals.Index(fn, "def"); err == nil {
+		return strings.Join(strings.Fields(vals.ToString(def)), " ")
+	}
+	return vals.Repr(fn, vals.NoPretty)
+}
+
+func sortPaletteCommands(cmds []*paletteCommand) {
+	sort.SliceStable(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+}
@@ -0,0 +1,54 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestPalette_ShowsKeys(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `edit:palette:start`)
+	feedInput(f.TTYCtrl, "location:st")
+	f.TestTTY(t,
+		"~> \n",
+		" COMMAND PALETTE  location:st", Styles,
+		"*****************            ", term.DotHere, "\n",
+		"edit:location:start  Ctrl-L (insert)              ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
+func TestPalette_CallsUserDefinedBinding(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`edit:insert:binding[Alt-p] = { edit:insert-at-dot palette }`,
+		`edit:palette:start`)
+	feedInput(f.TTYCtrl, "alt-p")
+	f.TestTTY(t,
+		"~> \n",
+		" COMMAND PALETTE  alt-p", Styles,
+		"*****************      ", term.DotHere, "\n",
+		"{ edit:insert-at-dot palette }  Alt-p (insert)    ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+	f.TTYCtrl.Inject(term.K(ui.Enter))
+	f.TestTTY(t, "~> palette", Styles,
+		"   !!!!!!!", term.DotHere)
+}
+
+func TestPalette_LeavesOutFunctionsRequiringArgs(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	for _, cmd := range paletteCommands(f.Editor.ns) {
+		if cmd.name == "edit:insert-at-dot" {
+			t.Errorf("edit:insert-at-dot is in the palette")
+		}
+	}
+}
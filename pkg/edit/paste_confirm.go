@@ -0,0 +1,52 @@
+package edit
+
+import (
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/cli/addons/pasteconfirm"
+	"github.com/elves/elvish/pkg/eval"
+)
+
+//elvdoc:var paste-confirm:enabled
+//
+// A boolean that controls whether bracketed pastes that span multiple lines or
+// contain control characters need to be confirmed before they are inserted.
+// Defaults to `$true`.
+//
+// When confirmation is needed, the pasted text is shown in the code area as
+// pending code, and the paste-confirm mode is started. This protects against
+// pasting malicious code copied from web pages, which can hide newlines or
+// escape sequences that are not visible in the browser.
+
+//elvdoc:var paste-confirm:binding
+//
+// Binding for the paste-confirm mode.
+
+//elvdoc:fn paste-confirm:accept
+//
+// Inserts the pasted text and closes the paste-confirm mode.
+
+//elvdoc:fn paste-confirm:close
+//
+// Discards the pasted text and closes the paste-confirm mode.
+
+func initPasteConfirm(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	enabled := newBoolVar(true)
+	bindingVar := newBindingVar(EmptyBindingMap)
+	binding := newMapBinding(ed, ev, bindingVar)
+	appSpec.OnPaste = func(text string) bool {
+		if !enabled.GetRaw().(bool) || !pasteconfirm.NeedsConfirm(text) {
+			return true
+		}
+		pasteconfirm.Start(ed.app, pasteconfirm.Config{Binding: binding, Text: text})
+		return false
+	}
+
+	nb.AddNs("paste-confirm",
+		eval.NsBuilder{
+			"binding": bindingVar,
+			"enabled": enabled,
+		}.AddGoFns("<edit:paste-confirm>:", map[string]interface{}{
+			"accept": func() { pasteconfirm.Accept(ed.app) },
+			"close":  func() { pasteconfirm.Close(ed.app) },
+		}).Ns())
+}
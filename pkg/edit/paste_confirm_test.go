@@ -0,0 +1,63 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestPasteConfirm_Accept(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(
+		term.PasteSetting(true),
+		term.K('p'), term.K('u'), term.K('t'), term.K('\n'),
+		term.K('p'), term.K('u'), term.K('t'),
+		term.PasteSetting(false))
+	f.TestTTY(t,
+		"~> put\n", Styles,
+		"   VVV",
+		"   put", Styles,
+		"   VVV", term.DotHere, "\n",
+		" PASTE  2 lines pasted, Enter to insert", Styles,
+		"*******",
+	)
+
+	f.TTYCtrl.Inject(term.K('\n'), term.K('\n'))
+	wantCode := "put\nput"
+	if code := <-f.codeCh; code != wantCode {
+		t.Errorf("Got code %q, want %q", code, wantCode)
+	}
+}
+
+func TestPasteConfirm_Close(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	f.TTYCtrl.Inject(
+		term.PasteSetting(true),
+		term.K('a'), term.K('\n'),
+		term.PasteSetting(false),
+		term.K('[', ui.Ctrl), term.K('\n'))
+	if code := <-f.codeCh; code != "" {
+		t.Errorf("Got code %q, want %q", code, "")
+	}
+}
+
+func TestPasteConfirm_Disabled(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `edit:paste-confirm:enabled = $false`)
+	f.TTYCtrl.Inject(
+		term.PasteSetting(true),
+		term.K('a'), term.K('\n'), term.K('b'),
+		term.PasteSetting(false),
+		term.K('\n'))
+	wantCode := "a\nb"
+	if code := <-f.codeCh; code != wantCode {
+		t.Errorf("Got code %q, want %q", code, wantCode)
+	}
+}
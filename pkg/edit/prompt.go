@@ -1,9 +1,10 @@
 package edit
 
 import (
-	"io/ioutil"
+	"context"
 	"os"
 	"os/user"
+	"strings"
 	"sync"
 	"time"
 
@@ -53,16 +54,21 @@ import (
 // See [RPrompt Persistency](#rprompt-persistency).
 
 func initPrompts(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
+	// Prompts computed for a readline cycle are no longer needed after it
+	// ends, so cancel the callbacks that are still running.
+	pc := newPromptContext()
+	appSpec.AfterReadline = append(appSpec.AfterReadline,
+		func(string) { pc.reset() })
 	promptVal, rpromptVal := getDefaultPromptVals()
-	initPrompt(&appSpec.Prompt, "prompt", promptVal, nt, ev, nb)
-	initPrompt(&appSpec.RPrompt, "rprompt", rpromptVal, nt, ev, nb)
+	initPrompt(&appSpec.Prompt, "prompt", promptVal, pc, nt, ev, nb)
+	initPrompt(&appSpec.RPrompt, "rprompt", rpromptVal, pc, nt, ev, nb)
 
 	rpromptPersistentVar := newBoolVar(false)
 	appSpec.RPromptPersistent = func() bool { return rpromptPersistentVar.Get().(bool) }
 	nb["rprompt-persistent"] = rpromptPersistentVar
 }
 
-func initPrompt(p *cli.Prompt, name string, val eval.Callable, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
+func initPrompt(p *cli.Prompt, name string, val eval.Callable, pc *promptContext, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
 	computeVar := vars.FromPtr(&val)
 	nb[name] = computeVar
 	eagernessVar := newIntVar(5)
@@ -75,7 +81,7 @@ func initPrompt(p *cli.Prompt, name string, val eval.Callable, nt notifier, ev *
 
 	*p = prompt.New(prompt.Config{
 		Compute: func() ui.Text {
-			return callForStyledText(nt, ev, name, computeVar.Get().(eval.Callable))
+			return callForStyledText(pc.get(), nt, ev, name, computeVar.Get().(eval.Callable))
 		},
 		Eagerness: func() int { return eagernessVar.GetRaw().(int) },
 		StaleThreshold: func() time.Duration {
@@ -83,11 +89,46 @@ func initPrompt(p *cli.Prompt, name string, val eval.Callable, nt notifier, ev *
 			return time.Duration(seconds * float64(time.Second))
 		},
 		StaleTransform: func(original ui.Text) ui.Text {
-			return callForStyledText(nt, ev, name+" stale transform", staleTransformVar.Get().(eval.Callable), original)
+			return callForStyledText(pc.get(), nt, ev, name+" stale transform", staleTransformVar.Get().(eval.Callable), original)
 		},
 	})
 }
 
+// TriggerPrompts requests the prompt and the rprompt to be recomputed, even if
+// their eagerness would not cause them to be. It can be called regardless of
+// whether the editor is active.
+func (ed *Editor) TriggerPrompts() {
+	for _, p := range ed.prompts {
+		p.Trigger(true)
+	}
+}
+
+// Keeps the context for calling prompt callbacks.
+type promptContext struct {
+	mutex  sync.Mutex
+	ctx    context.Context
+	cancel func()
+}
+
+func newPromptContext() *promptContext {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &promptContext{ctx: ctx, cancel: cancel}
+}
+
+func (pc *promptContext) get() context.Context {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	return pc.ctx
+}
+
+// Cancels the current context and replaces it with a new one.
+func (pc *promptContext) reset() {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.cancel()
+	pc.ctx, pc.cancel = context.WithCancel(context.Background())
+}
+
 func getDefaultPromptVals() (prompt, rprompt eval.Callable) {
 	user, userErr := user.Current()
 	isRoot := userErr == nil && user.Uid == "0"
@@ -127,14 +168,11 @@ func defaultStaleTransform(original ui.Text) ui.Text {
 
 // Calls a function with the given arguments and closed input, and concatenates
 // its outputs to a styled text. Used to call prompts and stale transformers.
-func callForStyledText(nt notifier, ev *eval.Evaler, ctx string, fn eval.Callable, args ...interface{}) ui.Text {
-	var (
-		result      ui.Text
-		resultMutex sync.Mutex
-	)
+// The call is interrupted when ctx is cancelled, in which case errors are not
+// shown.
+func callForStyledText(ctx context.Context, nt notifier, ev *eval.Evaler, name string, fn eval.Callable, args ...interface{}) ui.Text {
+	var result ui.Text
 	add := func(v interface{}) {
-		resultMutex.Lock()
-		defer resultMutex.Unlock()
 		newResult, err := result.Concat(v)
 		if err != nil {
 			nt.notifyf("invalid output type from prompt: %s", vals.Kind(v))
@@ -143,38 +181,23 @@ func callForStyledText(nt notifier, ev *eval.Evaler, ctx string, fn eval.Callabl
 		}
 	}
 
-	// Value outputs are concatenated.
-	valuesCb := func(ch <-chan interface{}) {
-		for v := range ch {
-			add(v)
-		}
-	}
-	// Byte output is added to the prompt as a single unstyled text.
-	bytesCb := func(r *os.File) {
-		allBytes, err := ioutil.ReadAll(r)
-		if err != nil {
-			nt.notifyf("error reading prompt byte output: %v", err)
-		}
-		if len(allBytes) > 0 {
-			add(ui.ParseSGREscapedText(string(allBytes)))
-		}
+	// Value outputs are concatenated, and byte output is added to the prompt
+	// as a single unstyled text after the function returns.
+	var bytes strings.Builder
+	cbs, summarize := notifyCallbacks(nt)
+	cbs.Value = add
+	cbs.Bytes = func(line string) { bytes.WriteString(line) }
+
+	err := ev.CallWithOutputCallback(fn,
+		eval.CallCfg{Args: args, From: "[" + name + "]"},
+		eval.EvalCfg{Context: ctx}, cbs)
+	summarize()
+	if bytes.Len() > 0 {
+		add(ui.ParseSGREscapedText(bytes.String()))
 	}
 
-	port1, done1, err := eval.PipePort(valuesCb, bytesCb)
-	if err != nil {
-		nt.notifyf("cannot create pipe for prompt: %v", err)
-		return nil
-	}
-	port2, done2 := makeNotifyPort(nt)
-
-	err = ev.Call(fn,
-		eval.CallCfg{Args: args, From: "[" + ctx + "]"},
-		eval.EvalCfg{Ports: []*eval.Port{nil, port1, port2}})
-	done1()
-	done2()
-
-	if err != nil {
-		nt.notifyError(ctx, err)
+	if err != nil && ctx.Err() == nil {
+		nt.notifyError(name, err)
 	}
 	return result
 }
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/elves/elvish/pkg/cli/clitest"
 	"github.com/elves/elvish/pkg/cli/term"
+	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/testutil"
 	"github.com/elves/elvish/pkg/ui"
 )
@@ -55,6 +57,30 @@ func TestPrompt_NotifiesException(t *testing.T) {
 	testGlobal(t, f.Evaler, "excs", "1")
 }
 
+func TestPrompt_CallbackCancelledAfterReadline(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+	cancelled := make(chan struct{})
+	f.Evaler.Global.Append(eval.NsBuilder{}.AddGoFn("", "wait-cancel",
+		func(fm *eval.Frame) {
+			<-fm.Context().Done()
+			close(cancelled)
+		}).Ns())
+	evals(f.Evaler,
+		`edit:-prompt-eagerness = 10`,
+		`edit:prompt = { wait-cancel }`)
+
+	// Trigger an update of the prompt, and accept the code while the update
+	// is still in progress.
+	feedInput(f.TTYCtrl, "a\n")
+	f.Wait()
+	select {
+	case <-cancelled:
+	case <-time.After(testutil.ScaledMs(2000)):
+		t.Errorf("prompt callback not cancelled after readline")
+	}
+}
+
 func TestRPrompt(t *testing.T) {
 	f := setup(rc(`edit:rprompt = { put 'RRR' }`))
 	defer f.Cleanup()
@@ -0,0 +1,34 @@
+package edit
+
+import (
+	"os"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+)
+
+//elvdoc:var report-cwd
+//
+// A boolean that controls whether the working directory is reported to the
+// terminal before each prompt and after each directory change. Defaults to
+// `$false`.
+//
+// The directory is reported with the OSC 7 escape sequence, as well as the
+// `CurrentDir` and `RemoteHost` escape sequences of iTerm2. Terminals that
+// support them can use the directory to open new windows or tabs in the same
+// directory; other terminals ignore them.
+
+func initReportCwd(appSpec *cli.AppSpec, tty cli.TTY, ev *eval.Evaler, nb eval.NsBuilder) {
+	enabled := newBoolVar(false)
+	isEnabled := func() bool { return enabled.GetRaw().(bool) }
+	appSpec.ReportCwd = isEnabled
+	ev.AddAfterChdir(func(string) {
+		if !isEnabled() {
+			return
+		}
+		if wd, err := os.Getwd(); err == nil {
+			tty.ReportCwd(wd)
+		}
+	})
+	nb["report-cwd"] = enabled
+}
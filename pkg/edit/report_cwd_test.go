@@ -0,0 +1,36 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/pkg/cli/term"
+)
+
+func TestReportCwd_DisabledByDefault(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	os.Mkdir("d", 0700)
+	evals(f.Evaler, `cd d`)
+	if cwds := f.TTYCtrl.Cwds(); len(cwds) != 0 {
+		t.Errorf("got reported directories %q, want none", cwds)
+	}
+}
+
+func TestReportCwd_ReportsAfterChdir(t *testing.T) {
+	f := setup(rc(`edit:report-cwd = $true`))
+	defer f.Cleanup()
+
+	// Wait for the prompt, before which the directory is reported.
+	f.TestTTY(t, "~> ", term.DotHere)
+	wd, _ := os.Getwd()
+	os.Mkdir("d", 0700)
+	evals(f.Evaler, `cd d`)
+	wantCwds := []string{wd, filepath.Join(wd, "d")}
+	if cwds := f.TTYCtrl.Cwds(); !reflect.DeepEqual(cwds, wantCwds) {
+		t.Errorf("got reported directories %q, want %q", cwds, wantCwds)
+	}
+}
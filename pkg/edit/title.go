@@ -0,0 +1,88 @@
+package edit
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/pkg/cli"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var title
+//
+// A function that outputs the title of the terminal window, or `$nil` to leave
+// the title unchanged. Defaults to `$nil`.
+//
+// The function is called with two arguments, the code being run and the
+// working directory, before and after the editor reads each command: when the
+// editor starts reading a command, the code is an empty string; when the
+// command has been read and is about to run, the code is the command. The
+// outputs of the function are concatenated to form the title.
+//
+// The title is set with the OSC 2 escape sequence. Control characters in the
+// title are removed, and the title is not set in terminals known not to support
+// it, like the Linux console or Emacs.
+//
+// For example, the following shows the running command in the title, or the
+// working directory when no command is running:
+//
+// ```elvish
+// edit:title = [code dir]{
+//   if (eq $code '') { put $dir } else { put $code }
+// }
+// ```
+
+func initTitle(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	var m sync.RWMutex
+	var fn eval.Callable
+	appSpec.Title = func(code string) (string, bool) {
+		m.RLock()
+		f := fn
+		m.RUnlock()
+		if f == nil {
+			return "", false
+		}
+		wd, _ := os.Getwd()
+		title, err := callTitle(ed, ev, f, code, wd)
+		if err != nil {
+			ed.notifyError("title", err)
+			return "", false
+		}
+		return title, true
+	}
+	nb.Add("title", vars.FromSetGet(
+		func(v interface{}) error {
+			f, ok := v.(eval.Callable)
+			if v != nil && !ok {
+				return errs.BadValue{What: "title",
+					Valid: "callable or $nil", Actual: vals.Kind(v)}
+			}
+			m.Lock()
+			defer m.Unlock()
+			fn = f
+			return nil
+		},
+		func() interface{} {
+			m.RLock()
+			defer m.RUnlock()
+			return fn
+		}))
+}
+
+// Calls the title function and concatenates its outputs.
+func callTitle(nt notifier, ev *eval.Evaler, fn eval.Callable, code, wd string) (string, error) {
+	outs, err := callForOutputs(nt, ev, fn,
+		eval.CallCfg{Args: []interface{}{code, wd}, From: "[editor title]"})
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, out := range outs {
+		sb.WriteString(vals.ToString(out))
+	}
+	return sb.String(), nil
+}
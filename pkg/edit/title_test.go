@@ -0,0 +1,58 @@
+package edit
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+)
+
+func TestTitle_DefaultLeavesTitleUnchanged(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler, `edit:return-line`)
+	f.Wait()
+	if titles := f.TTYCtrl.Titles(); len(titles) != 0 {
+		t.Errorf("got titles %q, want none", titles)
+	}
+}
+
+func TestTitle_Configurable(t *testing.T) {
+	f := setup(rc(`edit:title = [code dir]{ put $dir ' ' $code }`))
+	defer f.Cleanup()
+
+	wd, _ := os.Getwd()
+	feedInput(f.TTYCtrl, "echo\n")
+	f.Wait()
+	wantTitles := []string{wd + " ", wd + " echo"}
+	if titles := f.TTYCtrl.Titles(); !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("got titles %q, want %q", titles, wantTitles)
+	}
+}
+
+func TestTitle_Exception(t *testing.T) {
+	f := setup(rc(`edit:title = [code dir]{ fail bad }`))
+	defer f.Cleanup()
+
+	f.TestTTYNotes(t,
+		"[title error] bad\n",
+		`see stack trace with "show $edit:exceptions[0]"`)
+	if titles := f.TTYCtrl.Titles(); len(titles) != 0 {
+		t.Errorf("got titles %q, want none", titles)
+	}
+}
+
+func TestTitle_BadValue(t *testing.T) {
+	f := setup()
+	defer f.Cleanup()
+
+	evals(f.Evaler,
+		`exc = ?(edit:title = foo)`,
+		`edit:title = $nil`)
+	if exc := getGlobal(f.Evaler, "exc"); exc == eval.OK {
+		t.Errorf("setting an invalid title function didn't throw")
+	}
+	testGlobal(t, f.Evaler, "edit:title", nil)
+}
@@ -0,0 +1,409 @@
+// Package elvfmt implements a formatter for Elvish source code.
+//
+// The formatter works on the parse tree, and only changes the whitespace,
+// newlines and semicolons between syntax elements; everything else, including
+// comments, is kept as is. Specifically, it:
+//
+//   - Puts one space between the elements of a form, and around pipe symbols;
+//
+//   - Indents the bodies of lambdas and captures that span multiple lines, as
+//     well as continuation lines, by two spaces for each level;
+//
+//   - Keeps line breaks between pipelines and list elements, collapsing
+//     consecutive blank lines into one;
+//
+//   - Breaks forms and pipelines that are longer than MaxWidth columns at
+//     element boundaries, using line continuations ("^") in forms.
+package elvfmt
+
+import (
+	"strings"
+
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/wcwidth"
+)
+
+// MaxWidth is the width beyond which forms and pipelines are broken into
+// multiple lines.
+const MaxWidth = 80
+
+const indentUnit = "  "
+
+// Format formats Elvish source code. It returns an error if the code has
+// parse errors, which always has type *parse.Error.
+func Format(src parse.Source) (string, error) {
+	tree, err := parse.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	f := &formatter{}
+	f.lines(parse.Children(tree.Root))
+	if f.sb.Len() > 0 {
+		f.sb.WriteByte('\n')
+	}
+	return f.sb.String(), nil
+}
+
+type formatter struct {
+	sb     strings.Builder
+	indent int
+	// Width of the current line so far.
+	col int
+	// Number of enclosing blocks written on a single line. Forms and
+	// pipelines in such blocks are never broken.
+	inline int
+}
+
+func (f *formatter) write(s string) {
+	f.sb.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i != -1 {
+		f.col = wcwidth.Of(s[i+1:])
+	} else {
+		f.col += wcwidth.Of(s)
+	}
+}
+
+// Starts a new line at the current indentation.
+func (f *formatter) newline() {
+	f.write("\n" + strings.Repeat(indentUnit, f.indent))
+}
+
+// Returns whether writing s on the current line after a space would make the
+// line longer than MaxWidth. Only the first line of s is considered.
+// Always returns false inside blocks written on a single line.
+func (f *formatter) exceeds(s string) bool {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	return f.inline == 0 && f.col > len(indentUnit)*f.indent && f.col+1+wcwidth.Of(s) > MaxWidth
+}
+
+func (f *formatter) node(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.Pipeline:
+		f.pipeline(n)
+	case *parse.Form:
+		f.form(n)
+	case *parse.Primary:
+		f.primary(n)
+	default:
+		f.verbatim(n)
+	}
+}
+
+// Writes a node as is, except for its descendants that are formatted.
+func (f *formatter) verbatim(n parse.Node) {
+	children := parse.Children(n)
+	if len(children) == 0 {
+		f.write(parse.SourceText(n))
+		return
+	}
+	for _, ch := range children {
+		if _, ok := ch.(*parse.Sep); ok {
+			f.write(parse.SourceText(ch))
+		} else {
+			f.node(ch)
+		}
+	}
+}
+
+// Writes a sequence of items, like the pipelines of a chunk or the elements of
+// a list, separated by Seps containing whitespace, newlines, semicolons and
+// comments. Newlines are kept, with consecutive blank lines collapsed into
+// one; comments are kept; semicolons are only kept between items on the same
+// line. Returns whether the sequence ends with a newline, or a comment that
+// must be followed by one.
+func (f *formatter) lines(children []parse.Node) bool {
+	started, semicolon, afterComment := false, false, false
+	newlines := 0
+	separate := func() {
+		if started {
+			switch {
+			case newlines > 0 || afterComment:
+				if newlines > 1 {
+					// A blank line, without trailing indentation.
+					f.write("\n")
+				}
+				f.newline()
+			case semicolon:
+				f.write("; ")
+			default:
+				f.write(" ")
+			}
+		}
+		started, semicolon, afterComment = true, false, false
+		newlines = 0
+	}
+	for _, ch := range children {
+		if _, ok := ch.(*parse.Sep); !ok {
+			separate()
+			f.node(ch)
+			continue
+		}
+		for _, tok := range tokenize(parse.SourceText(ch)) {
+			switch tok.kind {
+			case newlineToken, continuationToken:
+				if started {
+					newlines++
+				}
+			case semicolonToken:
+				semicolon = started
+			case commentToken, textToken:
+				separate()
+				f.write(tok.text)
+				afterComment = tok.kind == commentToken
+			}
+		}
+	}
+	return newlines > 0 || afterComment
+}
+
+func (f *formatter) pipeline(n *parse.Pipeline) {
+	afterPipe, newline, indented := false, false, false
+	for _, ch := range parse.Children(n) {
+		form, ok := ch.(*parse.Form)
+		if !ok {
+			for _, tok := range tokenize(parse.SourceText(ch)) {
+				switch tok.kind {
+				case textToken:
+					// A pipe symbol or the background indicator.
+					f.write(" " + tok.text)
+					afterPipe = tok.text == "|"
+				case commentToken:
+					f.write(" " + tok.text)
+				case newlineToken, continuationToken:
+					newline = newline || afterPipe
+				}
+			}
+			continue
+		}
+		if afterPipe {
+			if newline || f.exceeds(parse.SourceText(form)) {
+				if !indented {
+					f.indent++
+					indented = true
+				}
+				f.newline()
+			} else {
+				f.write(" ")
+			}
+			afterPipe, newline = false, false
+		}
+		f.form(form)
+	}
+	if indented {
+		f.indent--
+	}
+}
+
+func (f *formatter) form(n *parse.Form) {
+	started, space, lineBreak, indented := false, false, false, false
+	item := func(text string, write func()) {
+		if started {
+			if lineBreak || (space && text != "=" && f.exceeds(text)) {
+				if !indented {
+					f.indent++
+					indented = true
+				}
+				f.write(" ^")
+				f.newline()
+			} else if space {
+				f.write(" ")
+			}
+		}
+		write()
+		started, space, lineBreak = true, false, false
+	}
+	for _, ch := range parse.Children(n) {
+		if _, ok := ch.(*parse.Sep); !ok {
+			item(parse.SourceText(ch), func() { f.node(ch) })
+			continue
+		}
+		for _, tok := range tokenize(parse.SourceText(ch)) {
+			switch tok.kind {
+			case spaceToken, newlineToken:
+				space = started
+			case continuationToken:
+				lineBreak = started
+			case commentToken:
+				// A comment always ends the form.
+				f.write(" " + tok.text)
+			case textToken:
+				// The equal sign of a spacey assignment.
+				text := tok.text
+				item(text, func() { f.write(text) })
+			}
+		}
+	}
+	if indented {
+		f.indent--
+	}
+}
+
+func (f *formatter) primary(n *parse.Primary) {
+	switch n.Type {
+	case parse.Lambda:
+		children := parse.Children(n)
+		if parse.SourceText(children[0]) == "[" {
+			// Argument and option list.
+			end := 1
+			for parse.SourceText(children[end]) != "]" {
+				end++
+			}
+			f.list(children[1:end])
+		}
+		f.block("{", n.Chunk, "}", true)
+	case parse.OutputCapture:
+		f.block("(", n.Chunk, ")", false)
+	case parse.ExceptionCapture:
+		f.block("?(", n.Chunk, ")", false)
+	case parse.List, parse.Map:
+		children := parse.Children(n)
+		f.list(children[1 : len(children)-1])
+	default:
+		f.verbatim(n)
+	}
+}
+
+// Writes a list enclosed in brackets, given the children between the brackets.
+func (f *formatter) list(children []parse.Node) {
+	if isBlank(children) {
+		f.write("[]")
+		return
+	}
+	f.write("[")
+	f.indent++
+	if startsWithNewline(children) {
+		f.newline()
+	}
+	endsLine := f.lines(children)
+	f.indent--
+	if endsLine {
+		f.newline()
+	}
+	f.write("]")
+}
+
+// Writes a chunk enclosed in brackets. A chunk spanning multiple lines starts
+// on a new line and is indented, with the closing bracket on its own line.
+// Otherwise it is written on the same line, padded with spaces if spaced is
+// true.
+func (f *formatter) block(open string, chunk *parse.Chunk, close string, spaced bool) {
+	f.write(open)
+	children := parse.Children(chunk)
+	switch {
+	case isBlank(children):
+		if spaced {
+			f.write(" ")
+		}
+	case strings.Contains(parse.SourceText(chunk), "\n"):
+		f.indent++
+		f.newline()
+		f.lines(children)
+		f.indent--
+		f.newline()
+	default:
+		if spaced {
+			f.write(" ")
+		}
+		f.inline++
+		f.lines(children)
+		f.inline--
+		if spaced {
+			f.write(" ")
+		}
+	}
+	f.write(close)
+}
+
+// Returns whether the children consist of only whitespace.
+func isBlank(children []parse.Node) bool {
+	for _, ch := range children {
+		if _, ok := ch.(*parse.Sep); !ok {
+			return false
+		}
+		for _, tok := range tokenize(parse.SourceText(ch)) {
+			if tok.kind == commentToken || tok.kind == textToken {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func startsWithNewline(children []parse.Node) bool {
+	for _, ch := range children {
+		if _, ok := ch.(*parse.Sep); !ok {
+			return false
+		}
+		for _, tok := range tokenize(parse.SourceText(ch)) {
+			switch tok.kind {
+			case newlineToken, continuationToken:
+				return true
+			case spaceToken:
+			default:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	spaceToken tokenKind = iota
+	newlineToken
+	semicolonToken
+	commentToken
+	// A "^" followed by a newline.
+	continuationToken
+	// Anything else, like brackets and pipe symbols.
+	textToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// Splits the text of a Sep into tokens. Runs of inline whitespace become one
+// spaceToken, and carriage returns are dropped.
+func tokenize(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\r':
+			i++
+		case s[i] == '\n':
+			tokens = append(tokens, token{newlineToken, "\n"})
+			i++
+		case s[i] == ';':
+			tokens = append(tokens, token{semicolonToken, ";"})
+			i++
+		case s[i] == '#':
+			j := i + strings.IndexAny(s[i:]+"\n", "\r\n")
+			tokens = append(tokens, token{commentToken, strings.TrimRight(s[i:j], " \t")})
+			i = j
+		case s[i] == '^' && strings.HasPrefix(strings.TrimPrefix(s[i+1:], "\r"), "\n"):
+			tokens = append(tokens, token{continuationToken, "^"})
+			i += 1 + strings.IndexByte(s[i:], '\n')
+		case parse.IsInlineWhitespace(rune(s[i])):
+			j := i
+			for j < len(s) && parse.IsInlineWhitespace(rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{spaceToken, " "})
+			i = j
+		default:
+			j := i + strings.IndexAny(s[i:]+"\n", " \t\r\n;#^")
+			if j == i {
+				// A lone "^" not followed by a newline.
+				j++
+			}
+			tokens = append(tokens, token{textToken, s[i:j]})
+			i = j
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,132 @@
+package elvfmt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elves/elvish/pkg/parse"
+)
+
+var formatTests = []struct {
+	name string
+	code string
+	want string
+}{
+	{"empty", "", ""},
+	{"blank", " \n\n ", ""},
+	{"spaces in form", "echo  a\tb   ", "echo a b\n"},
+	{"spaces around pipes", "a|b   |  c", "a | b | c\n"},
+	{"background", "a &", "a &\n"},
+	{"semicolons", "a ;b;c;", "a; b; c\n"},
+	{"newlines", "a\n\n\n\nb\r\nc\n", "a\n\nb\nc\n"},
+	{"comments", "# c1\na  # c2\n\n  # c3\nb", "# c1\na # c2\n\n# c3\nb\n"},
+	{"spacey assignment", "a   b =  c", "a b = c\n"},
+	{"redirections", "a >out  2>&1 < in", "a >out 2>&1 < in\n"},
+	{"strings and indexings kept", "echo 'a  b' \"c\n  d\" $x[ 0 ]", "echo 'a  b' \"c\n  d\" $x[ 0 ]\n"},
+
+	{"single-line lambda", "if $x {a}  else {   b   }", "if $x {a} else { b }\n"},
+	{"empty lambda", "f = {  }", "f = { }\n"},
+	{"multi-line lambda",
+		"fn f [a  b  &k=v]{ echo $a\n      echo $b }",
+		"fn f [a b &k=v]{\n  echo $a\n  echo $b\n}\n"},
+	{"nested lambdas",
+		"each [x]{\nif $x {\nput $x\n}\n} [a]",
+		"each [x]{\n  if $x {\n    put $x\n  }\n} [a]\n"},
+	{"comment at start of lambda",
+		"f = { # c\n  a }",
+		"f = {\n  # c\n  a\n}\n"},
+
+	{"single-line capture", "echo ( put a ) ?(  fail b)", "echo (put a) ?(fail b)\n"},
+	{"multi-line capture", "x = (\nput a\nput b)", "x = (\n  put a\n  put b\n)\n"},
+
+	{"list", "echo [ a  b ] [ &k=v  &k2=v2 ] [ & ]", "echo [a b] [&k=v &k2=v2] [&]\n"},
+	{"empty list", "echo [ ] [\n]", "echo [] []\n"},
+	{"multi-line list",
+		"x = [\na b\n c\n]",
+		"x = [\n  a b\n  c\n]\n"},
+	{"list with comment",
+		"x = [a # c\n b]",
+		"x = [a # c\n  b]\n"},
+	{"list with comment at end",
+		"x = [a # c\n]",
+		"x = [a # c\n]\n"},
+
+	{"line continuation", "echo a ^\n     b ^\n c", "echo a ^\n  b ^\n  c\n"},
+	{"newline after pipe", "a |\nb |   # c\n    c", "a |\n  b | # c\n  c\n"},
+	{"long form",
+		"echo " + strings.Repeat("aaaaaaaaa ", 10),
+		"echo " + strings.TrimSpace(strings.Repeat("aaaaaaaaa ", 7)) + " ^\n" +
+			"  " + strings.TrimSpace(strings.Repeat("aaaaaaaaa ", 3)) + "\n"},
+	{"long pipeline",
+		"echo " + strings.Repeat("a", 40) + " | echo " + strings.Repeat("b", 40),
+		"echo " + strings.Repeat("a", 40) + " |\n  echo " + strings.Repeat("b", 40) + "\n"},
+}
+
+func TestFormat(t *testing.T) {
+	for _, test := range formatTests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Format(parse.Source{Name: "[test]", Code: test.code})
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			// Formatting is idempotent.
+			again, err := Format(parse.Source{Name: "[test]", Code: got})
+			if err != nil {
+				t.Fatalf("formatted code has error %v", err)
+			}
+			if again != got {
+				t.Errorf("formatting again got:\n%s\nwant:\n%s", again, got)
+			}
+			// Formatting doesn't change the syntax tree.
+			if a, b := summarize(t, test.code), summarize(t, got); a != b {
+				t.Errorf("formatting changed syntax tree:\n%s\nto:\n%s", a, b)
+			}
+		})
+	}
+}
+
+func TestFormat_ParseError(t *testing.T) {
+	_, err := Format(parse.Source{Name: "[test]", Code: "echo ]"})
+	if parse.GetError(err) == nil {
+		t.Errorf("got error %v, want parse error", err)
+	}
+}
+
+// Summarizes the syntax tree of code, leaving out Seps.
+func summarize(t *testing.T, code string) string {
+	tree, err := parse.Parse(parse.Source{Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	var walk func(n parse.Node, depth int)
+	walk = func(n parse.Node, depth int) {
+		name := reflect.TypeOf(n).Elem().Name()
+		var children []parse.Node
+		for _, ch := range parse.Children(n) {
+			if _, ok := ch.(*parse.Sep); !ok {
+				children = append(children, ch)
+			}
+		}
+		if len(children) == 0 {
+			text := strings.TrimSpace(parse.SourceText(n))
+			if p, ok := n.(*parse.Primary); ok && (p.Type == parse.List || p.Type == parse.Map) {
+				// Whitespace in empty lists and maps is normalized.
+				text = strings.Join(strings.Fields(text), "")
+			}
+			fmt.Fprintf(&sb, "%*s%s %q\n", depth, "", name, text)
+			return
+		}
+		fmt.Fprintf(&sb, "%*s%s\n", depth, "", name)
+		for _, ch := range children {
+			walk(ch, depth+1)
+		}
+	}
+	walk(tree.Root, 0)
+	return sb.String()
+}
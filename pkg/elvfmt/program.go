@@ -0,0 +1,57 @@
+package elvfmt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/prog"
+)
+
+// Program is the formatter subprogram. It formats the files given as
+// arguments, or the standard input if there are none, and writes the result to
+// the standard output.
+var Program prog.Program = program{}
+
+type program struct{}
+
+func (program) ShouldRun(f *prog.Flags) bool { return f.Fmt }
+
+func (program) Run(fds [3]*os.File, _ *prog.Flags, args []string) error {
+	if len(args) == 0 {
+		code, err := ioutil.ReadAll(fds[0])
+		if err != nil {
+			return fmt.Errorf("cannot read stdin: %v", err)
+		}
+		return formatTo(fds, parse.Source{Name: "[stdin]", Code: string(code)})
+	}
+	failed := false
+	for _, name := range args {
+		code, err := ioutil.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(fds[2], "cannot read %q: %v\n", name, err)
+			failed = true
+			continue
+		}
+		err = formatTo(fds, parse.Source{Name: name, Code: string(code), IsFile: true})
+		if err != nil {
+			failed = true
+		}
+	}
+	if failed {
+		return prog.Exit(2)
+	}
+	return nil
+}
+
+func formatTo(fds [3]*os.File, src parse.Source) error {
+	out, err := Format(src)
+	if err != nil {
+		diag.ShowError(fds[2], err)
+		return prog.Exit(2)
+	}
+	fds[1].WriteString(out)
+	return nil
+}
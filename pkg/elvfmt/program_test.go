@@ -0,0 +1,52 @@
+package elvfmt
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/prog"
+	. "github.com/elves/elvish/pkg/prog/progtest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestProgram_Stdin(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+	f.FeedIn("echo  a|  b\n")
+
+	exit := prog.Run(f.Fds(), Elvish("-fmt"), Program)
+
+	if exit != 0 {
+		t.Errorf("got exit %v, want 0", exit)
+	}
+	f.TestOut(t, 1, "echo a | b\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestProgram_Files(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a.elv": "echo  a", "b.elv": "echo  b"})
+
+	exit := prog.Run(f.Fds(), Elvish("-fmt", "a.elv", "b.elv"), Program)
+
+	if exit != 0 {
+		t.Errorf("got exit %v, want 0", exit)
+	}
+	f.TestOut(t, 1, "echo a\necho b\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestProgram_Errors(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"bad.elv": "echo ]", "good.elv": "echo  a"})
+
+	exit := prog.Run(f.Fds(), Elvish("-fmt", "bad.elv", "nonexistent.elv", "good.elv"), Program)
+
+	if exit != 2 {
+		t.Errorf("got exit %v, want 2", exit)
+	}
+	f.TestOut(t, 1, "echo a\n")
+	f.TestOutSnippet(t, 2, "parse error")
+	f.TestOutSnippet(t, 2, `cannot read "nonexistent.elv"`)
+}
@@ -7,13 +7,26 @@ package env
 // Note that some of these env vars may be significant only in special
 // circumstances, such as when running unit tests.
 const (
-	ELVISH_TEST_TIME_SCALE = "ELVISH_TEST_TIME_SCALE"
-	HOME                   = "HOME"
-	LS_COLORS              = "LS_COLORS"
-	PATH                   = "PATH"
-	PATHEXT                = "PATHEXT"
-	PWD                    = "PWD"
-	SHLVL                  = "SHLVL"
-	USERNAME               = "USERNAME"
-	XDG_RUNTIME_DIR        = "XDG_RUNTIME_DIR"
+	CONDA_DEFAULT_ENV        = "CONDA_DEFAULT_ENV"
+	EDITOR                   = "EDITOR"
+	ELVISH_FETCH_MODULES     = "ELVISH_FETCH_MODULES"
+	ELVISH_SECRET_PASSPHRASE = "ELVISH_SECRET_PASSPHRASE"
+	ELVISH_TEST_TIME_SCALE   = "ELVISH_TEST_TIME_SCALE"
+	HOME                     = "HOME"
+	INSIDE_EMACS             = "INSIDE_EMACS"
+	KUBECONFIG               = "KUBECONFIG"
+	LS_COLORS                = "LS_COLORS"
+	OLDPWD                   = "OLDPWD"
+	PATH                     = "PATH"
+	PATHEXT                  = "PATHEXT"
+	PWD                      = "PWD"
+	SHLVL                    = "SHLVL"
+	STY                      = "STY"
+	TERM                     = "TERM"
+	TMUX                     = "TMUX"
+	USER                     = "USER"
+	USERNAME                 = "USERNAME"
+	VIRTUAL_ENV              = "VIRTUAL_ENV"
+	VISUAL                   = "VISUAL"
+	XDG_RUNTIME_DIR          = "XDG_RUNTIME_DIR"
 )
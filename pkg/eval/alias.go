@@ -0,0 +1,192 @@
+package eval
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Aliases: names of commands that are replaced with other commands and
+// arguments when compiling code.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"alias":   aliasFn,
+		"unalias": unalias,
+	})
+}
+
+//elvdoc:fn alias
+//
+// ```elvish
+// alias $name $body
+// ```
+//
+// Defines an alias. When a command named `$name` is compiled, its head is
+// replaced with the words of `$body`, which are prepended to the arguments of
+// the command. Example:
+//
+// ```elvish-transcript
+// ~> alias hello 'echo Hello'
+// ~> hello world
+// Hello world
+// ```
+//
+// The body must be a single command whose words are all barewords or quoted
+// strings, like `'ls -l'` or `"git commit --amend"`. It may not run a special
+// command.
+//
+// Aliases are expanded when code is compiled, before functions and external
+// commands are looked up, so they take precedence over functions of the same
+// name. The head of the body is not expanded again, so `alias ls 'ls -F'`
+// calls the `ls` command. Since a piece of code is compiled before it runs, an
+// alias can only be used in code entered after the `alias` command has run.
+//
+// Aliases are only expanded in code that is not read from a file, like code
+// typed in the interactive editor or evaluated with [`eval`](#eval), so they
+// don't change the behavior of modules and scripts. Aliases are listed with
+// [`alias:list`](alias.html#aliaslist), and can be expanded in the editor
+// buffer with [`$edit:expand-aliases`](edit.html#editexpand-aliases).
+//
+// @cf unalias
+
+//elvdoc:fn unalias
+//
+// ```elvish
+// unalias $name
+// ```
+//
+// Removes an alias. Does nothing if the alias doesn't exist.
+//
+// @cf alias
+
+func aliasFn(fm *Frame, name, body string) error {
+	return fm.Evaler.SetAlias(name, body)
+}
+
+func unalias(fm *Frame, name string) {
+	fm.Evaler.UnsetAlias(name)
+}
+
+var (
+	errEmptyAliasName = errors.New("alias name must not be empty")
+	errSpecialAlias   = errors.New("alias name must not be a special command")
+	errBadAliasBody   = errors.New(
+		"alias body must be a single command with only literal strings as words")
+	errSpecialAliasHead = errors.New("alias body must not run a special command")
+)
+
+// An alias, defined with the alias builtin.
+type alias struct {
+	// The body as given.
+	body string
+	// The words of the body; the first one is the command head.
+	words []string
+}
+
+// Aliases of an Evaler. The map is never modified in place, so that it can be
+// used by the compiler without locking.
+type aliasRegistry struct {
+	mutex   sync.RWMutex
+	aliases map[string]alias
+}
+
+func (r *aliasRegistry) get() map[string]alias {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.aliases
+}
+
+// Calls f with a copy of the aliases, and replaces the aliases with the copy.
+func (r *aliasRegistry) update(f func(map[string]alias)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	aliases := make(map[string]alias, len(r.aliases)+1)
+	for name, a := range r.aliases {
+		aliases[name] = a
+	}
+	f(aliases)
+	r.aliases = aliases
+}
+
+// SetAlias defines an alias, replacing the one with the same name if it
+// exists. See the doc of the alias builtin for the syntax of the body.
+func (ev *Evaler) SetAlias(name, body string) error {
+	if name == "" {
+		return errEmptyAliasName
+	}
+	if IsBuiltinSpecial[name] {
+		return errSpecialAlias
+	}
+	words, err := parseAliasBody(body)
+	if err != nil {
+		return err
+	}
+	ev.aliases.update(func(m map[string]alias) { m[name] = alias{body, words} })
+	return nil
+}
+
+// UnsetAlias removes an alias. It does nothing if the alias doesn't exist.
+func (ev *Evaler) UnsetAlias(name string) {
+	if _, ok := ev.aliases.get()[name]; ok {
+		ev.aliases.update(func(m map[string]alias) { delete(m, name) })
+	}
+}
+
+// Alias returns the body of an alias and whether it exists.
+func (ev *Evaler) Alias(name string) (string, bool) {
+	a, ok := ev.aliases.get()[name]
+	return a.body, ok
+}
+
+// AliasNames returns the names of all aliases, sorted.
+func (ev *Evaler) AliasNames() []string {
+	aliases := ev.aliases.get()
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the aliases that apply when compiling the given source. Aliases are
+// only expanded in code that is not from a file, like code typed in the
+// editor, so that they don't change the behavior of modules and scripts.
+func (ev *Evaler) aliasesFor(src parse.Source) map[string]alias {
+	if src.IsFile {
+		return nil
+	}
+	return ev.aliases.get()
+}
+
+// Parses the body of an alias into words.
+func parseAliasBody(body string) ([]string, error) {
+	tree, err := parse.Parse(parse.Source{Name: "[alias]", Code: body})
+	if err != nil {
+		return nil, err
+	}
+	pipelines := tree.Root.Pipelines
+	if len(pipelines) != 1 || len(pipelines[0].Forms) != 1 || pipelines[0].Background {
+		return nil, errBadAliasBody
+	}
+	form := pipelines[0].Forms[0]
+	if form.Head == nil || len(form.Assignments) > 0 || len(form.Opts) > 0 ||
+		len(form.Redirs) > 0 {
+		return nil, errBadAliasBody
+	}
+	words := make([]string, 0, 1+len(form.Args))
+	for _, cn := range append([]*parse.Compound{form.Head}, form.Args...) {
+		word, ok := oneString(cn)
+		if !ok {
+			return nil, errBadAliasBody
+		}
+		words = append(words, word)
+	}
+	if IsBuiltinSpecial[words[0]] {
+		return nil, errSpecialAliasHead
+	}
+	return words, nil
+}
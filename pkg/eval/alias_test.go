@@ -0,0 +1,77 @@
+package eval_test
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+func TestAlias(t *testing.T) {
+	TestWithSetup(t,
+		func(ev *Evaler) { ev.SetAlias("p", "put foo 'a b'") },
+		That("p bar").Puts("foo", "a b", "bar"),
+		// Aliases defined with the alias builtin are expanded in code compiled
+		// after it has run.
+		That("alias q 'put q'; eval 'q x'").Puts("q", "x"),
+		// The head of the body is not expanded again.
+		That("alias put 'put x'; eval 'put y'").Puts("x", "y"),
+		That("alias p2 p; eval 'p2'").Throws(AnyError),
+		// Aliases take precedence over functions.
+		That("fn f { put fn }; alias f 'put alias'; eval 'f'").Puts("alias"),
+		// Aliases of builtins that take lambdas with an implicit $it.
+		That("alias em emap; eval 'put x | em { put $it }'").Puts("x"),
+
+		That("alias q 'put q'; unalias q; eval 'q'").Throws(AnyError),
+		That("unalias nonexistent").DoesNothing(),
+
+		That("alias '' put").Throws(ErrorWithMessage("alias name must not be empty")),
+		That("alias if put").Throws(
+			ErrorWithMessage("alias name must not be a special command")),
+		That("alias q if").Throws(
+			ErrorWithMessage("alias body must not run a special command")),
+		That("alias q 'put (echo)'").Throws(ErrorWithMessage(
+			"alias body must be a single command with only literal strings as words")),
+		That("alias q 'each { put x }'").Throws(AnyError),
+		That("alias q 'put a; put b'").Throws(AnyError),
+		That("alias q 'put a | put b'").Throws(AnyError),
+		That("alias q 'put &foo=bar'").Throws(AnyError),
+		That("alias q 'put >a'").Throws(AnyError),
+		That("alias q 'put ['").Throws(AnyError),
+	)
+}
+
+func TestAlias_NotExpandedInFiles(t *testing.T) {
+	ev := NewEvaler()
+	ev.SetAlias("f", "put alias")
+	err := ev.Eval(parse.Source{Name: "a.elv", Code: "fn f { put fn }; x = (f)", IsFile: true},
+		EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := ev.Global.Index("x")
+	if x != "fn" {
+		t.Errorf("got $x = %v, want fn", x)
+	}
+}
+
+func TestAlias_Introspection(t *testing.T) {
+	ev := NewEvaler()
+	ev.SetAlias("b", "put b")
+	ev.SetAlias("a", "put a")
+	if body, ok := ev.Alias("a"); body != "put a" || !ok {
+		t.Errorf("Alias(a) -> (%q, %v), want (%q, true)", body, ok, "put a")
+	}
+	if _, ok := ev.Alias("c"); ok {
+		t.Errorf("Alias(c) -> ok, want !ok")
+	}
+	names := ev.AliasNames()
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("AliasNames() -> %v, want [a b]", names)
+	}
+	ev.UnsetAlias("a")
+	if names := ev.AliasNames(); len(names) != 1 || names[0] != "b" {
+		t.Errorf("AliasNames() after UnsetAlias -> %v, want [b]", names)
+	}
+}
@@ -0,0 +1,39 @@
+package eval
+
+import (
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Types that can be used in type annotations of closure arguments, like
+// [x:num]{ }, mapped to functions that check whether a value is of the type.
+var argTypeCheckers = map[string]func(interface{}) bool{
+	"any":    func(interface{}) bool { return true },
+	"bool":   kindIs("bool"),
+	"fn":     kindIs("fn"),
+	"list":   kindIs("list"),
+	"map":    kindIs("map"),
+	"num":    isNum,
+	"string": kindIs("string"),
+}
+
+func kindIs(kind string) func(interface{}) bool {
+	return func(v interface{}) bool { return vals.Kind(v) == kind }
+}
+
+// Numbers may be passed as strings, as in "f 1", so anything that can be
+// converted to a number is accepted.
+func isNum(v interface{}) bool {
+	var f float64
+	return vals.ScanToGo(v, &f) == nil
+}
+
+// Checks that v is of the type specified by the type annotation of the
+// argument. The what argument describes the argument in the error message.
+func checkArgType(typ, what string, v interface{}) error {
+	if typ == "" || argTypeCheckers[typ](v) {
+		return nil
+	}
+	return errs.BadValue{
+		What: what, Valid: "of type " + typ, Actual: vals.Repr(v, vals.NoPretty)}
+}
@@ -34,6 +34,10 @@ func BenchmarkEval_UpVariableAccess(b *testing.B) {
 	benchmarkEval(b, "x = val; { nop $x }")
 }
 
+func BenchmarkEval_ConstantExpressions(b *testing.B) {
+	benchmarkEval(b, "nop a'b'{c,d} [x [y z]] [&k=v][k]")
+}
+
 func benchmarkEval(b *testing.B, code string) {
 	ev := NewEvaler()
 	src := parse.Source{Name: "[benchmark]", Code: code}
@@ -41,6 +41,9 @@ func execFn(fm *Frame, args ...interface{}) error {
 		}
 	}
 
+	if err := fm.CheckRestriction(RestrictExternalCommands); err != nil {
+		return err
+	}
 	var err error
 	argstrings[0], err = exec.LookPath(argstrings[0])
 	if err != nil {
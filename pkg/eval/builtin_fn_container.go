@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"sort"
 
 	"github.com/elves/elvish/pkg/eval/errs"
@@ -18,7 +19,8 @@ func init() {
 	addBuiltinFns(map[string]interface{}{
 		"ns": nsFn,
 
-		"make-map": makeMap,
+		"make-map":         makeMap,
+		"make-ordered-map": makeOrderedMap,
 
 		"range":  rangeFn,
 		"repeat": repeat,
@@ -26,6 +28,10 @@ func init() {
 		"assoc":  assoc,
 		"dissoc": dissoc,
 
+		"assoc-in":  assocIn,
+		"dissoc-in": dissocIn,
+		"update-in": updateIn,
+
 		"all": all,
 		"one": one,
 
@@ -102,34 +108,82 @@ func nsFn(m hashmap.Map) (*Ns, error) {
 
 func makeMap(input Inputs) (vals.Map, error) {
 	m := vals.EmptyMap
-	var errMakeMap error
+	err := iteratePairInputs("make-map", input, func(k, v interface{}) {
+		m = m.Assoc(k, v)
+	})
+	return m, err
+}
+
+//elvdoc:fn make-ordered-map
+//
+// ```elvish
+// make-ordered-map $input?
+// ```
+//
+// Like [`make-map`](#make-map), but outputs an ordered map, which remembers the
+// order in which its keys were first inserted.
+//
+// An ordered map can be used like a map. Its keys are iterated, printed and
+// converted to JSON in insertion order. Assigning to an existing key keeps its
+// position, and a new key is added at the end. Two ordered maps are equal if
+// they have equal pairs in the same order.
+//
+// Examples:
+//
+// ```elvish-transcript
+// ~> make-ordered-map [[z 1] [a 2]]
+// ▶ (make-ordered-map [[z 1] [a 2]])
+// ~> m = (make-ordered-map [[z 1] [a 2]])
+// ~> m[m] = 3
+// ~> keys $m
+// ▶ z
+// ▶ a
+// ▶ m
+// ~> to-json [$m]
+// {"z":"1","a":"2","m":"3"}
+// ```
+//
+// @cf make-map
+
+func makeOrderedMap(input Inputs) (vals.OrderedMap, error) {
+	m := vals.EmptyOrderedMap
+	err := iteratePairInputs("make-ordered-map", input, func(k, v interface{}) {
+		m = m.Put(k, v)
+	})
+	return m, err
+}
+
+// Calls f with the two elements of each input, which must be a container with
+// two elements.
+func iteratePairInputs(what string, input Inputs, f func(k, v interface{})) error {
+	var errPair error
 	input(func(v interface{}) {
-		if errMakeMap != nil {
+		if errPair != nil {
 			return
 		}
 		if !vals.CanIterate(v) {
-			errMakeMap = errs.BadValue{
-				What: "input to make-map", Valid: "iterable", Actual: vals.Kind(v)}
+			errPair = errs.BadValue{
+				What: "input to " + what, Valid: "iterable", Actual: vals.Kind(v)}
 			return
 		}
 		if l := vals.Len(v); l != 2 {
-			errMakeMap = errs.BadValue{
-				What: "input to make-map", Valid: "iterable with 2 elements",
+			errPair = errs.BadValue{
+				What: "input to " + what, Valid: "iterable with 2 elements",
 				Actual: fmt.Sprintf("%v with %v elements", vals.Kind(v), l)}
 			return
 		}
 		elems, err := vals.Collect(v)
 		if err != nil {
-			errMakeMap = err
+			errPair = err
 			return
 		}
 		if len(elems) != 2 {
-			errMakeMap = fmt.Errorf("internal bug: collected %v values", len(elems))
+			errPair = fmt.Errorf("internal bug: collected %v values", len(elems))
 			return
 		}
-		m = m.Assoc(elems[0], elems[1])
+		f(elems[0], elems[1])
 	})
-	return m, errMakeMap
+	return errPair
 }
 
 //elvdoc:fn range
@@ -141,6 +195,16 @@ func makeMap(input Inputs) (vals.Map, error) {
 // Output `$low`, `$low` + `$step`, ..., proceeding as long as smaller than
 // `$high`. If not given, `$low` defaults to 0.
 //
+// If all the arguments are exact numbers, like integers or fractions, the
+// outputs are exact too, even if they are too large to be represented by a
+// `float64`:
+//
+// ```elvish-transcript
+// ~> range 18446744073709551615 18446744073709551617
+// ▶ (num 18446744073709551615)
+// ▶ (num 18446744073709551616)
+// ```
+//
 // Examples:
 //
 // ```elvish-transcript
@@ -173,16 +237,16 @@ func makeMap(input Inputs) (vals.Map, error) {
 // Etymology:
 // [Python](https://docs.python.org/3/library/functions.html#func-range).
 
-type rangeOpts struct{ Step float64 }
+type rangeOpts struct{ Step number }
 
-func (o *rangeOpts) SetDefaultOptions() { o.Step = 1 }
+func (o *rangeOpts) SetDefaultOptions() { o.Step = number{big.NewInt(1)} }
 
-func rangeFn(fm *Frame, opts rangeOpts, args ...float64) error {
-	var lower, upper float64
+func rangeFn(fm *Frame, opts rangeOpts, args ...number) error {
+	var lower, upper number
 
 	switch len(args) {
 	case 1:
-		upper = args[0]
+		lower, upper = number{big.NewInt(0)}, args[0]
 	case 2:
 		lower, upper = args[0], args[1]
 	default:
@@ -190,12 +254,39 @@ func rangeFn(fm *Frame, opts rangeOpts, args ...float64) error {
 	}
 
 	out := fm.OutputChan()
-	for f := lower; f < upper; f += opts.Step {
-		out <- vals.FromGo(f)
+	if exact, anyRat := classifyNums([]number{lower, upper, opts.Step}); exact &&
+		(anyRat || !fitsFloat64(lower) || !fitsFloat64(upper)) {
+		// Iterate with exact numbers when float64 would lose precision.
+		r := new(big.Rat).Set(vals.NumToBigRat(lower.v))
+		upperRat, step := vals.NumToBigRat(upper.v), vals.NumToBigRat(opts.Step.v)
+		for ; r.Cmp(upperRat) < 0; r.Add(r, step) {
+			select {
+			case out <- vals.NormalizeBigRat(new(big.Rat).Set(r)):
+			case <-fm.Interrupts():
+				return ErrInterrupted
+			}
+		}
+		return nil
+	}
+
+	step := vals.NumToFloat64(opts.Step.v)
+	for f := vals.NumToFloat64(lower.v); f < vals.NumToFloat64(upper.v); f += step {
+		select {
+		case out <- vals.FromGo(f):
+		case <-fm.Interrupts():
+			return ErrInterrupted
+		}
 	}
 	return nil
 }
 
+// Reports whether a number is a float64, or an exact number that can be
+// represented exactly by a float64.
+func fitsFloat64(n number) bool {
+	_, ok := n.normalize().(float64)
+	return ok
+}
+
 //elvdoc:fn repeat
 //
 // ```elvish
@@ -215,11 +306,16 @@ func rangeFn(fm *Frame, opts rangeOpts, args ...float64) error {
 //
 // Etymology: [Clojure](https://clojuredocs.org/clojure.core/repeat).
 
-func repeat(fm *Frame, n int, v interface{}) {
+func repeat(fm *Frame, n int, v interface{}) error {
 	out := fm.OutputChan()
 	for i := 0; i < n; i++ {
-		out <- v
+		select {
+		case out <- v:
+		case <-fm.Interrupts():
+			return ErrInterrupted
+		}
 	}
+	return nil
 }
 
 //elvdoc:fn assoc
@@ -281,6 +377,102 @@ func dissoc(a, k interface{}) (interface{}, error) {
 	return a2, nil
 }
 
+//elvdoc:fn assoc-in
+//
+// ```elvish
+// assoc-in $container $path $v
+// ```
+//
+// Like `assoc`, but takes a list of keys leading to a value in nested
+// containers, and outputs a modified version of `$container` in which that
+// value is `$v`. Keys that are missing from maps along the path are associated
+// with empty maps.
+//
+// ```elvish-transcript
+// ~> assoc-in [&a=[&b=[x y]]] [a b 0] z
+// ▶ [&a=[&b=[z y]]]
+// ~> assoc-in [&] [a b] c
+// ▶ [&a=[&b=c]]
+// ```
+//
+// Etymology: [Clojure](https://clojuredocs.org/clojure.core/assoc-in).
+//
+// @cf assoc dissoc-in update-in
+
+func assocIn(a, path, v interface{}) (interface{}, error) {
+	keys, err := vals.Collect(path)
+	if err != nil {
+		return nil, err
+	}
+	return vals.AssocIn(a, keys, v)
+}
+
+//elvdoc:fn dissoc-in
+//
+// ```elvish
+// dissoc-in $container $path
+// ```
+//
+// Like `dissoc`, but takes a non-empty list of keys leading to a value in
+// nested containers, and outputs a modified version of `$container` with the
+// last key removed from the map containing it. If a key along the path is
+// missing, the same container is returned.
+//
+// ```elvish-transcript
+// ~> dissoc-in [&a=[&b=c &d=e]] [a b]
+// ▶ [&a=[&d=e]]
+// ```
+//
+// @cf dissoc assoc-in update-in
+
+func dissocIn(a, path interface{}) (interface{}, error) {
+	keys, err := vals.Collect(path)
+	if err != nil {
+		return nil, err
+	}
+	return vals.DissocIn(a, keys)
+}
+
+//elvdoc:fn update-in
+//
+// ```elvish
+// update-in $container $path $f
+// ```
+//
+// Takes a list of keys leading to a value in nested containers, and outputs a
+// modified version of `$container` in which that value is replaced with the
+// output of calling `$f` with it. All the keys along the path must exist, and
+// `$f` must output exactly one value.
+//
+// ```elvish-transcript
+// ~> update-in [&counts=[&a=(float64 1)]] [counts a] [x]{ + $x 1 }
+// ▶ [&counts=[&a=(float64 2)]]
+// ```
+//
+// Etymology: [Clojure](https://clojuredocs.org/clojure.core/update-in).
+//
+// @cf assoc-in dissoc-in
+
+func updateIn(fm *Frame, a, path interface{}, f Callable) (interface{}, error) {
+	keys, err := vals.Collect(path)
+	if err != nil {
+		return nil, err
+	}
+	return vals.UpdateIn(a, keys, func(old interface{}) (interface{}, error) {
+		outputs, err := fm.CaptureOutput(func(fm *Frame) error {
+			return f.Call(fm, []interface{}{old}, NoOpts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(outputs) != 1 {
+			return nil, errs.ArityMismatch{What: "output of the callback",
+				ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+		}
+		return outputs[0], nil
+	})
+}
+
 //elvdoc:fn all
 //
 // ```elvish
@@ -475,6 +667,13 @@ func hasValue(container, value interface{}) (bool, error) {
 			}
 		}
 		return false, nil
+	case vals.OrderedMap:
+		found := false
+		container.IteratePairs(func(_, v interface{}) bool {
+			found = vals.Equal(v, value)
+			return !found
+		})
+		return found, nil
 	default:
 		var found bool
 		err := vals.Iterate(container, func(v interface{}) bool {
@@ -2,6 +2,7 @@ package eval_test
 
 import (
 	"math"
+	"math/big"
 	"testing"
 
 	. "github.com/elves/elvish/pkg/eval"
@@ -37,9 +38,28 @@ func TestBuiltinFnContainer(t *testing.T) {
 					Actual: "list with 1 elements"},
 				"make-map [[k]]"),
 
+		That("make-ordered-map [[z v1] [a v2] [z v3]]").
+			Puts(vals.MakeOrderedMap("z", "v3", "a", "v2")),
+		That("put [k1 v1] | make-ordered-map").
+			Puts(vals.MakeOrderedMap("k1", "v1")),
+		That("make-ordered-map [[k]]").Throws(
+			errs.BadValue{
+				What: "input to make-ordered-map", Valid: "iterable with 2 elements",
+				Actual: "list with 1 elements"},
+			"make-ordered-map [[k]]"),
+		That("m = (make-ordered-map [[z 1] [a 2]]); m[m] = 3; m[z] = 4; keys $m; put $m[z]").
+			Puts("z", "a", "m", "4"),
+		That("m = (make-ordered-map [[z 1] [a 2]]); del m[z]; repr $m; has-key $m a; has-value $m 2").
+			Prints("(make-ordered-map [[a 2]])\n").Puts(true, true),
+		That("to-json [(make-ordered-map [[z 1] [a [&b=c]]])]").
+			Prints(`{"z":"1","a":{"b":"c"}}`+"\n"),
+		That("to-yaml [(make-ordered-map [[z 1] [a 2]])]").Prints("z: \"1\"\na: \"2\"\n"),
+
 		That(`range 3`).Puts(0.0, 1.0, 2.0),
 		That(`range 1 3`).Puts(1.0, 2.0),
 		That(`range 0 10 &step=3`).Puts(0.0, 3.0, 6.0, 9.0),
+		That(`range 0 1 &step=1/3`).Puts(0.0, big.NewRat(1, 3), big.NewRat(2, 3)),
+		That("range "+z+" "+z1).Puts(bigInt(z)),
 
 		That(`repeat 4 foo`).Puts("foo", "foo", "foo", "foo"),
 
@@ -149,3 +169,25 @@ func TestBuiltinFnContainer(t *testing.T) {
 			Puts("x", "x", "x", "x", "l", "o", "r", "e", "m"),
 	)
 }
+
+func TestAssocIn_DissocIn_UpdateIn(t *testing.T) {
+	Test(t,
+		That("assoc-in [&a=[&b=[x y]]] [a b 0] z").
+			Puts(vals.MakeMap("a", vals.MakeMap("b", vals.MakeList("z", "y")))),
+		That("assoc-in [&] [a b] c").Puts(vals.MakeMap("a", vals.MakeMap("b", "c"))),
+		That("assoc-in [&] a c").Puts(vals.MakeMap("a", "c")),
+		That("assoc-in [&] $true c").Throws(ErrorWithMessage("cannot iterate bool")),
+
+		That("dissoc-in [&a=[&b=c &d=e]] [a b]").
+			Puts(vals.MakeMap("a", vals.MakeMap("d", "e"))),
+		That("dissoc-in [&a=b] [x y]").Puts(vals.MakeMap("a", "b")),
+		That("dissoc-in [&a=b] []").Throws(ErrorWithMessage("path must not be empty")),
+
+		That("update-in [&counts=[&a=(num 1)]] [counts a] [x]{ + $x 1 }").
+			Puts(vals.MakeMap("counts", vals.MakeMap("a", 2.0))),
+		That("update-in [a b] [1] [x]{ put $x $x }").Throws(errs.ArityMismatch{
+			What: "output of the callback", ValidLow: 1, ValidHigh: 1, Actual: 2}),
+		That("update-in [a b] [1] [x]{ fail bad }").Throws(FailError{"bad"}),
+		That("update-in [&] [a] [x]{ put $x }").Throws(vals.NoSuchKey("a")),
+	)
+}
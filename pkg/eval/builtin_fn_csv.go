@@ -0,0 +1,258 @@
+package eval
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// CSV.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"from-csv": fromCSV,
+		"to-csv":   toCSV,
+	})
+}
+
+//elvdoc:fn from-csv
+//
+// ```elvish
+// from-csv &header=$false &delimiter=,
+// ```
+//
+// Takes bytes stdin, parses it as CSV and puts each row on structured stdout
+// as soon as it is read.
+//
+// By default, each row is output as a list of strings, and rows may have
+// different numbers of fields. If `&header` is true, the first row is used as
+// the names of the columns, and each following row is output as a map from the
+// names to the fields; all rows must then have the same number of fields.
+//
+// The `&delimiter` option specifies the field delimiter, which must be a single
+// character. Use `&delimiter="\t"` for TSV.
+//
+// Examples:
+//
+// ```elvish-transcript
+// ~> print "a,b\n1,\"x y\"\n" | from-csv
+// ▶ [a b]
+// ▶ [1 'x y']
+// ~> print "name,lang\nelvish,go\n" | from-csv &header
+// ▶ [&lang=go &name=elvish]
+// ~> print "a\tb\n" | from-csv &delimiter="\t"
+// ▶ [a b]
+// ```
+//
+// @cf to-csv from-json
+
+type fromCSVOpts struct {
+	Header    bool
+	Delimiter string
+}
+
+func (o *fromCSVOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func fromCSV(fm *Frame, opts fromCSVOpts) error {
+	delim, err := csvDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(fm.InputFile())
+	r.Comma = delim
+	if !opts.Header {
+		r.FieldsPerRecord = -1
+	}
+	out := fm.OutputChan()
+	var header []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case !opts.Header:
+			vec := vals.EmptyList
+			for _, field := range record {
+				vec = vec.Cons(field)
+			}
+			out <- vec
+		case header == nil:
+			header = record
+		default:
+			m := vals.EmptyMap
+			for i, field := range record {
+				m = m.Assoc(header[i], field)
+			}
+			out <- m
+		}
+	}
+}
+
+//elvdoc:fn to-csv
+//
+// ```elvish
+// to-csv &header=$false &delimiter=, $input?
+// ```
+//
+// Takes structured stdin, converts each value to a CSV row and writes the
+// result on bytes stdout. Fields are quoted when needed.
+//
+// By default, the inputs must be lists. If `&header` is true, the inputs must
+// be maps instead; a header row is written first, using the sorted keys of the
+// first map as the names of the columns, and each map is written as a row with
+// its values in the same order. All maps must have the same keys.
+//
+// Fields must be strings or numbers. The `&delimiter` option works like in
+// [`from-csv`](#from-csv).
+//
+// Examples:
+//
+// ```elvish-transcript
+// ~> put [a b] [1 'x y,z'] | to-csv
+// a,b
+// 1,"x y,z"
+// ~> to-csv &header [[&name=elvish &lang=go]]
+// lang,name
+// go,elvish
+// ```
+//
+// @cf from-csv to-json
+
+type toCSVOpts struct {
+	Header    bool
+	Delimiter string
+}
+
+func (o *toCSVOpts) SetDefaultOptions() { o.Delimiter = "," }
+
+func toCSV(fm *Frame, opts toCSVOpts, inputs Inputs) error {
+	delim, err := csvDelimiter(opts.Delimiter)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(fm.OutputFile())
+	w.Comma = delim
+	var header []string
+	var errEncode error
+	inputs(func(v interface{}) {
+		if errEncode != nil {
+			return
+		}
+		var record []string
+		if opts.Header {
+			if header == nil {
+				header, errEncode = csvHeader(v)
+				if errEncode != nil {
+					return
+				}
+				if errEncode = w.Write(header); errEncode != nil {
+					return
+				}
+			}
+			record, errEncode = csvMapRecord(v, header)
+		} else {
+			record, errEncode = csvListRecord(v)
+		}
+		if errEncode != nil {
+			return
+		}
+		// Flush after each row, so that the output can be consumed as soon as
+		// it is produced.
+		if errEncode = w.Write(record); errEncode == nil {
+			w.Flush()
+			errEncode = w.Error()
+		}
+	})
+	return errEncode
+}
+
+// Parses the value of a &delimiter option.
+func csvDelimiter(s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError ||
+		r == '"' || r == '\r' || r == '\n' {
+		return 0, errs.BadValue{What: "&delimiter option",
+			Valid: "single character other than quote or newline", Actual: parse.Quote(s)}
+	}
+	return r, nil
+}
+
+// Returns the sorted keys of a map input, to be used as the header row.
+func csvHeader(v interface{}) ([]string, error) {
+	if _, ok := v.(vals.Map); !ok {
+		return nil, fmt.Errorf("input to to-csv &header must be map, got %s", vals.Kind(v))
+	}
+	var header []string
+	var errKey error
+	vals.IterateKeys(v, func(k interface{}) bool {
+		var key string
+		key, errKey = csvField(k)
+		header = append(header, key)
+		return errKey == nil
+	})
+	if errKey != nil {
+		return nil, errKey
+	}
+	sort.Strings(header)
+	return header, nil
+}
+
+// Converts a map input to a row, with the values in the order of header.
+func csvMapRecord(v interface{}, header []string) ([]string, error) {
+	m, ok := v.(vals.Map)
+	if !ok {
+		return nil, fmt.Errorf("input to to-csv &header must be map, got %s", vals.Kind(v))
+	}
+	if m.Len() != len(header) {
+		return nil, fmt.Errorf("input to to-csv has %d keys, want %d", m.Len(), len(header))
+	}
+	record := make([]string, len(header))
+	for i, key := range header {
+		value, ok := m.Index(key)
+		if !ok {
+			return nil, fmt.Errorf("input to to-csv has no key %s", parse.Quote(key))
+		}
+		field, err := csvField(value)
+		if err != nil {
+			return nil, err
+		}
+		record[i] = field
+	}
+	return record, nil
+}
+
+// Converts a list input to a row.
+func csvListRecord(v interface{}) ([]string, error) {
+	list, ok := v.(vals.List)
+	if !ok {
+		return nil, fmt.Errorf("input to to-csv must be list, got %s", vals.Kind(v))
+	}
+	record := make([]string, 0, list.Len())
+	for it := list.Iterator(); it.HasElem(); it.Next() {
+		field, err := csvField(it.Elem())
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, field)
+	}
+	return record, nil
+}
+
+func csvField(v interface{}) (string, error) {
+	switch v.(type) {
+	case string, float64, *big.Int, *big.Rat:
+		return vals.ToString(v), nil
+	default:
+		return "", fmt.Errorf("CSV field must be string or number, got %s", vals.Kind(v))
+	}
+}
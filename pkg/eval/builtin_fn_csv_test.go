@@ -0,0 +1,44 @@
+package eval_test
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestFromCSV(t *testing.T) {
+	Test(t,
+		That("print \"a,b\n1,\\\"x y\\\"\nc\n\" | from-csv").Puts(
+			vals.MakeList("a", "b"), vals.MakeList("1", "x y"), vals.MakeList("c")),
+		That("print \"name,lang\nelvish,go\nfoo,bar\n\" | from-csv &header").Puts(
+			vals.MakeMap("name", "elvish", "lang", "go"),
+			vals.MakeMap("name", "foo", "lang", "bar")),
+		That("print \"a\tb,c\n\" | from-csv &delimiter=\"\\t\"").Puts(
+			vals.MakeList("a", "b,c")),
+		// Rows are output as soon as they are read.
+		That("print \"a,b\n1\n\" | from-csv &header").Throws(AnyError),
+		That("print \"a\n\\\"b\n\" | from-csv").Puts(vals.MakeList("a")).Throws(AnyError),
+		That("print a | from-csv &delimiter=ab").Throws(AnyError),
+		That("print a | from-csv &delimiter=''").Throws(AnyError),
+	)
+}
+
+func TestToCSV(t *testing.T) {
+	Test(t,
+		That(`put [a b] [(num 1) 'x y,z' "q\"uote"] | to-csv`).Prints(
+			"a,b\n1,\"x y,z\",\"q\"\"uote\"\n"),
+		That(`to-csv &header [[&name=elvish &lang=go] [&name=foo &lang=bar]]`).Prints(
+			"lang,name\ngo,elvish\nbar,foo\n"),
+		That(`to-csv &delimiter="\t" [[a b]]`).Prints("a\tb\n"),
+		That(`to-csv [foo]`).Throws(AnyError),
+		That(`to-csv [[[]]]`).Throws(AnyError),
+		That(`to-csv &header [[a]]`).Throws(AnyError),
+		That(`to-csv &header [[&a=x] [&b=y]]`).Prints("a\nx\n").Throws(AnyError),
+		That(`to-csv &header [[&a=x] [&a=y &b=z]]`).Prints("a\nx\n").Throws(AnyError),
+		That(`to-csv &delimiter='"' [[a]]`).Throws(AnyError),
+		// Round trip
+		That(`to-csv &header [[&a="x\ny" &b='']] | from-csv &header`).Puts(
+			vals.MakeMap("a", "x\ny", "b", "")),
+	)
+}
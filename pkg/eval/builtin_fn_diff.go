@@ -0,0 +1,220 @@
+package eval
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Deep comparison.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"eq-deep": eqDeep,
+		"diff":    diff,
+	})
+}
+
+//elvdoc:fn eq-deep
+//
+// ```elvish
+// eq-deep $values...
+// ```
+//
+// Determine whether all `$value`s are deeply equal. Writes `$true` when given no
+// or one argument.
+//
+// Two lists are deeply equal if they have the same length and their elements
+// are deeply equal. Two map-like values, such as maps,
+// [ordered maps](#make-ordered-map) and the fields of records of the same type,
+// are deeply equal if they have the same keys and the values of each key are
+// deeply equal, regardless of the kinds of the values and the order of the keys.
+// Other values are deeply equal if they are equal according to `eq`.
+//
+// ```elvish-transcript
+// ~> eq-deep [&a=[1 2]] [&a=[1 2]]
+// ▶ $true
+// ~> eq [&a=1 &b=2] (make-ordered-map [[b 2] [a 1]])
+// ▶ $false
+// ~> eq-deep [&a=1 &b=2] (make-ordered-map [[b 2] [a 1]])
+// ▶ $true
+// ```
+//
+// @cf eq diff
+
+func eqDeep(args ...interface{}) bool {
+	for i := 0; i+1 < len(args); i++ {
+		equal := true
+		diffValues(vals.EmptyList, args[i], args[i+1], func(diffEntry) bool {
+			equal = false
+			return false
+		})
+		if !equal {
+			return false
+		}
+	}
+	return true
+}
+
+//elvdoc:fn diff
+//
+// ```elvish
+// diff $old $new
+// ```
+//
+// Compares two values deeply, like `eq-deep`, and outputs the differences
+// between them, one map for each difference. Outputs nothing if the values are
+// deeply equal.
+//
+// Each map has the following keys:
+//
+// -   `path`: A list of the keys and indices leading from the top-level values
+//     to the values that differ. Indices of lists are strings. The path is empty
+//     if the top-level values themselves differ.
+//
+// -   `op`: `add` if the path only exists in `$new`, `remove` if it only exists
+//     in `$old`, and `replace` if it exists in both but the values differ.
+//
+// -   `old`: The value in `$old`, or `$nil` if the op is `add`.
+//
+// -   `new`: The value in `$new`, or `$nil` if the op is `remove`.
+//
+// Lists are compared element by element; if one list is longer than the other,
+// the extra elements are reported as added or removed. Differences in map-like
+// values are reported in the order of the keys of `$old`, followed by the keys
+// only in `$new`. The keys of maps are sorted, while the keys of ordered maps and
+// records keep their order.
+//
+// ```elvish-transcript
+// ~> diff [&name=elvish &tags=[shell]] [&name=elvish &tags=[shell language] &v=1]
+// ▶ [&new=language &old=$nil &op=add &path=[tags 1]]
+// ▶ [&new=1 &old=$nil &op=add &path=[v]]
+// ~> diff a [a]
+// ▶ [&new=[a] &old=a &op=replace &path=[]]
+// ```
+//
+// **Note**: This command shadows the external `diff` command. Use `e:diff` to
+// run the external command.
+//
+// @cf eq-deep
+
+func diff(fm *Frame, old, new interface{}) {
+	out := fm.OutputChan()
+	diffValues(vals.EmptyList, old, new, func(e diffEntry) bool {
+		out <- vals.MakeMap("path", e.path, "op", e.op, "old", e.old, "new", e.new)
+		return true
+	})
+}
+
+type diffEntry struct {
+	path     vals.List
+	op       string
+	old, new interface{}
+}
+
+// Calls f with each difference between old and new, stopping early if f
+// returns false. Returns whether the comparison ran to completion.
+func diffValues(path vals.List, old, new interface{}, f func(diffEntry) bool) bool {
+	switch {
+	case isDiffList(old) && isDiffList(new):
+		return diffLists(path, old.(vals.List), new.(vals.List), f)
+	case isDiffMap(old) && isDiffMap(new) && sameRecordType(old, new):
+		return diffMaps(path, old, new, f)
+	case vals.Equal(old, new):
+		return true
+	default:
+		return f(diffEntry{path, "replace", old, new})
+	}
+}
+
+func diffLists(path vals.List, old, new vals.List, f func(diffEntry) bool) bool {
+	itOld, itNew := old.Iterator(), new.Iterator()
+	for i := 0; itOld.HasElem() || itNew.HasElem(); i++ {
+		elemPath := path.Cons(strconv.Itoa(i))
+		var ok bool
+		switch {
+		case !itNew.HasElem():
+			ok = f(diffEntry{elemPath, "remove", itOld.Elem(), nil})
+		case !itOld.HasElem():
+			ok = f(diffEntry{elemPath, "add", nil, itNew.Elem()})
+		default:
+			ok = diffValues(elemPath, itOld.Elem(), itNew.Elem(), f)
+		}
+		if !ok {
+			return false
+		}
+		if itOld.HasElem() {
+			itOld.Next()
+		}
+		if itNew.HasElem() {
+			itNew.Next()
+		}
+	}
+	return true
+}
+
+func diffMaps(path vals.List, old, new interface{}, f func(diffEntry) bool) bool {
+	for _, k := range diffKeys(old) {
+		keyPath := path.Cons(k)
+		oldValue, _ := vals.Index(old, k)
+		var ok bool
+		if vals.HasKey(new, k) {
+			newValue, _ := vals.Index(new, k)
+			ok = diffValues(keyPath, oldValue, newValue, f)
+		} else {
+			ok = f(diffEntry{keyPath, "remove", oldValue, nil})
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, k := range diffKeys(new) {
+		if vals.HasKey(old, k) {
+			continue
+		}
+		newValue, _ := vals.Index(new, k)
+		if !f(diffEntry{path.Cons(k), "add", nil, newValue}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the keys of a map-like value in the order differences are reported.
+func diffKeys(m interface{}) []interface{} {
+	var keys []interface{}
+	vals.IterateKeys(m, func(k interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	switch m.(type) {
+	case vals.OrderedMap, vals.Record:
+	default:
+		sort.Slice(keys, func(i, j int) bool { return keyText(keys[i]) < keyText(keys[j]) })
+	}
+	return keys
+}
+
+func isDiffList(v interface{}) bool {
+	_, ok := v.(vals.List)
+	return ok
+}
+
+func isDiffMap(v interface{}) bool {
+	switch v.(type) {
+	case vals.Map, vals.OrderedMap, vals.StructMap, vals.Record:
+		return true
+	}
+	return false
+}
+
+// Records are only compared field by field with records of the same type.
+func sameRecordType(a, b interface{}) bool {
+	ra, aIsRecord := a.(vals.Record)
+	rb, bIsRecord := b.(vals.Record)
+	if aIsRecord || bIsRecord {
+		return aIsRecord && bIsRecord && ra.Type() == rb.Type()
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package eval_test
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestEqDeep(t *testing.T) {
+	Test(t,
+		That("eq-deep").Puts(true),
+		That("eq-deep a").Puts(true),
+		That("eq-deep [&a=[1 [&b=2]]] [&a=[1 [&b=2]]]").Puts(true),
+		That("eq-deep [&a=[1 [&b=2]]] [&a=[1 [&b=3]]]").Puts(false),
+		That("eq-deep [1 2] [1 2 3]").Puts(false),
+		That("eq-deep a a a").Puts(true),
+		That("eq-deep a a b").Puts(false),
+		// Map-like values are compared by their keys and values.
+		That("eq-deep [&a=1 &b=2] (make-ordered-map [[b 2] [a 1]])").Puts(true),
+		That("eq-deep (make-ordered-map [[a 1] [b 2]]) (make-ordered-map [[b 2] [a 1]])").
+			Puts(true),
+		// Records are only compared with records of the same type.
+		That("p~ = (defrecord p x); eq-deep (p [1]) (p [1])").Puts(true),
+		That("p~ = (defrecord p x); eq-deep (p [1]) [&x=[1]]").Puts(false),
+		That("p~ = (defrecord p x); q~ = (defrecord p x); eq-deep (p 1) (q 1)").
+			Puts(false),
+	)
+}
+
+func TestDiff(t *testing.T) {
+	Test(t,
+		That("diff [&a=[1 2]] [&a=[1 2]]").DoesNothing(),
+		That("diff a [a]").Puts(
+			vals.MakeMap("path", vals.EmptyList, "op", "replace", "old", "a", "new", vals.MakeList("a"))),
+		That("diff [a b c] [a x]").Puts(
+			vals.MakeMap("path", vals.MakeList("1"), "op", "replace", "old", "b", "new", "x"),
+			vals.MakeMap("path", vals.MakeList("2"), "op", "remove", "old", "c", "new", nil)),
+		That("diff [&name=elvish &tags=[shell] &old=x] [&name=elvish &tags=[shell language] &v=1]").Puts(
+			vals.MakeMap("path", vals.MakeList("old"), "op", "remove", "old", "x", "new", nil),
+			vals.MakeMap("path", vals.MakeList("tags", "1"), "op", "add", "old", nil, "new", "language"),
+			vals.MakeMap("path", vals.MakeList("v"), "op", "add", "old", nil, "new", "1")),
+		// Keys of ordered maps keep their order.
+		That("diff (make-ordered-map [[z 1] [a 1]]) (make-ordered-map [[z 2] [a 2]]) | each [d]{ put $d[path][0] }").
+			Puts("z", "a"),
+		That("p~ = (defrecord p x y); diff (p 1 [a]) (p 1 [b]) | each [d]{ put $d[path] }").
+			Puts(vals.MakeList("y", "0")),
+		That("diff a").Throws(errs.ArityMismatch{
+			What: "arguments here", ValidLow: 2, ValidHigh: 2, Actual: 1}),
+	)
+}
@@ -3,6 +3,8 @@ package eval
 import (
 	"errors"
 	"os"
+
+	"github.com/elves/elvish/pkg/eval/vars"
 )
 
 // ErrNonExistentEnvVar is raised by the get-env command when the environment
@@ -48,8 +50,8 @@ func init() {
 	addBuiltinFns(map[string]interface{}{
 		"has-env":   hasEnv,
 		"get-env":   getEnv,
-		"set-env":   os.Setenv,
-		"unset-env": os.Unsetenv,
+		"set-env":   setEnv,
+		"unset-env": unsetEnv,
 	})
 }
 
@@ -101,3 +103,17 @@ func getEnv(key string) (string, error) {
 	}
 	return value, nil
 }
+
+func setEnv(fm *Frame, name, value string) error {
+	if err := fm.CheckRestriction(RestrictEnvChanges); err != nil {
+		return err
+	}
+	return vars.SetEnv(name, value)
+}
+
+func unsetEnv(fm *Frame, name string) error {
+	if err := fm.CheckRestriction(RestrictEnvChanges); err != nil {
+		return err
+	}
+	return vars.UnsetEnv(name)
+}
@@ -1,9 +1,17 @@
 package eval
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"sync"
+	"time"
 
-	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval/errs"
 	"github.com/elves/elvish/pkg/eval/vals"
 )
 
@@ -14,15 +22,21 @@ import (
 func init() {
 	addBuiltinFns(map[string]interface{}{
 		"run-parallel": runParallel,
+		"capture":      captureFn,
+		"with-timeout": withTimeout,
 		// Exception and control
-		"fail":        fail,
-		"multi-error": multiErrorFn,
-		"return":      returnFn,
-		"break":       breakFn,
-		"continue":    continueFn,
+		"fail":            fail,
+		"exception-class": exceptionClass,
+		"defer":           deferFn,
+		"multi-error":     multiErrorFn,
+		"return":          returnFn,
+		"break":           breakFn,
+		"continue":        continueFn,
 		// Iterations.
-		"each":  each,
-		"peach": peach,
+		"each":    each,
+		"emap":    emap,
+		"efilter": efilter,
+		"peach":   peach,
 	})
 }
 
@@ -83,6 +97,223 @@ func runParallel(fm *Frame, functions ...Callable) error {
 	return MakePipelineError(exceptions)
 }
 
+//elvdoc:fn capture
+//
+// ```elvish
+// capture &max-bytes=-1 &max-values=-1 $callable
+// ```
+//
+// Call `$callable` with its value output, byte output and byte error output
+// captured separately, and output a map containing everything it did:
+//
+// -   `values`: A list of the values it outputted.
+//
+// -   `stdout` and `stderr`: The bytes it wrote to the output and the error
+//     output, as strings. The bytes are kept as is, so they may contain
+//     binary data and do not need to end in a newline.
+//
+// -   `exception`: The exception it threw, or `$nil` if it did not throw any.
+//
+// An exception thrown by `$callable` is not propagated. Example:
+//
+// ```elvish-transcript
+// ~> capture { put foo; echo bar; echo baz >&2; fail qux }
+// ▶ [&values=[foo] &stdout="bar\n" &stderr="baz\n" &exception=[&reason=[&content=qux &type=fail]]]
+// ```
+//
+// The `&max-bytes` option limits the number of bytes captured from each of
+// the output and the error output, and the `&max-values` option limits the
+// number of values captured. A negative limit means no limit. When a limit is
+// exceeded, `$callable` is interrupted and `capture` throws an exception
+// instead of outputting a map:
+//
+// ```elvish-transcript
+// ~> capture &max-values=2 { range 10 }
+// Exception: capture limit exceeded: more than 2 values
+// [tty 1], line 1: capture &max-values=2 { range 10 }
+// ```
+//
+// @cf run-parallel
+
+type captureOpts struct {
+	MaxBytes  int
+	MaxValues int
+}
+
+func (o *captureOpts) SetDefaultOptions() { o.MaxBytes, o.MaxValues = -1, -1 }
+
+// CaptureLimitExceeded is thrown by the capture command when the output
+// exceeds the limits given in its options.
+type CaptureLimitExceeded struct {
+	// What exceeded the limit, like "values" or "bytes of stdout".
+	What  string
+	Limit int
+}
+
+// Error implements the error interface.
+func (e CaptureLimitExceeded) Error() string {
+	return fmt.Sprintf("capture limit exceeded: more than %d %s", e.Limit, e.What)
+}
+
+type captureResult struct {
+	Values    vals.List
+	Stdout    string
+	Stderr    string
+	Exception interface{}
+}
+
+func (captureResult) IsStructMap() {}
+
+func captureFn(fm *Frame, opts captureOpts, f Callable) (captureResult, error) {
+	ctx, cancel := context.WithCancel(fm.ctx)
+	defer cancel()
+	var (
+		exceededMutex sync.Mutex
+		exceeded      error
+	)
+	// Records the first limit that is exceeded, and interrupts the call.
+	// Outputs after that are still read but discarded, so that the writers
+	// are not blocked before they notice the interruption.
+	exceed := func(what string, limit int) {
+		exceededMutex.Lock()
+		defer exceededMutex.Unlock()
+		if exceeded == nil {
+			exceeded = CaptureLimitExceeded{what, limit}
+			cancel()
+		}
+	}
+
+	var values []interface{}
+	stdout := &limitedBuffer{limit: opts.MaxBytes,
+		exceed: func() { exceed("bytes of stdout", opts.MaxBytes) }}
+	stderr := &limitedBuffer{limit: opts.MaxBytes,
+		exceed: func() { exceed("bytes of stderr", opts.MaxBytes) }}
+	outPort, outDone, err := PipePort(
+		func(ch <-chan interface{}) {
+			for v := range ch {
+				if opts.MaxValues >= 0 && len(values) >= opts.MaxValues {
+					exceed("values", opts.MaxValues)
+					continue
+				}
+				values = append(values, v)
+			}
+		},
+		func(r *os.File) { io.Copy(stdout, r) })
+	if err != nil {
+		return captureResult{}, err
+	}
+	errPort, errDone, err := PipePort(
+		func(ch <-chan interface{}) {
+			for range ch {
+			}
+		},
+		func(r *os.File) { io.Copy(stderr, r) })
+	if err != nil {
+		outDone()
+		return captureResult{}, err
+	}
+
+	newFm := fm.fork("[capture]")
+	newFm.ctx = ctx
+	newFm.ports[1] = outPort
+	newFm.ports[2] = errPort
+	errCall := f.Call(newFm, NoArgs, NoOpts)
+	outDone()
+	errDone()
+
+	if exceeded != nil {
+		return captureResult{}, exceeded
+	}
+	var exc interface{}
+	if errCall != nil {
+		if e, ok := errCall.(*Exception); ok {
+			exc = e
+		} else {
+			exc = &Exception{errCall, nil}
+		}
+	}
+	return captureResult{
+		vals.MakeList(values...), stdout.buf.String(), stderr.buf.String(), exc}, nil
+}
+
+// An io.Writer that saves up to limit bytes and calls exceed when more bytes
+// are written. A negative limit means no limit.
+type limitedBuffer struct {
+	buf    bytes.Buffer
+	limit  int
+	exceed func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit >= 0 && b.buf.Len()+len(p) > b.limit {
+		b.buf.Write(p[:b.limit-b.buf.Len()])
+		b.exceed()
+		// Pretend that all the bytes have been written, so that the output
+		// keeps being drained.
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+//elvdoc:fn with-timeout
+//
+// ```elvish
+// with-timeout $duration $callable
+// ```
+//
+// Calls `$callable`, interrupting it if it does not finish within
+// `$duration`, which is in any form accepted by `sleep`, like `1.5`, `100ms`
+// or a duration value. When the time is up, the builtin commands
+// called by `$callable` stop as if interrupted by Ctrl-C, the external commands
+// are killed, and `with-timeout` throws an exception of class `timeout-error`:
+//
+// ```elvish-transcript
+// ~> with-timeout 1s { sleep 5; echo done }
+// Exception: timed out after 1s
+// [tty 1], line 1: with-timeout 1s { sleep 5; echo done }
+// ~> try { with-timeout 100ms { e:sleep 5 } } except e { put $e[reason] }
+// ▶ <unknown timed out after 100ms>
+// ```
+//
+// Outputs of `$callable` written before the timeout are kept. Timeouts can be
+// nested; the innermost one that is due first wins.
+//
+// This is useful for keeping commands that may take a long time, like those
+// in prompt functions, from blocking the shell.
+//
+// @cf sleep
+
+// TimeoutExceeded is thrown by with-timeout when the callable does not finish
+// in time.
+type TimeoutExceeded struct {
+	Timeout time.Duration
+}
+
+// Error implements the error interface.
+func (e TimeoutExceeded) Error() string {
+	return "timed out after " + e.Timeout.String()
+}
+
+func withTimeout(fm *Frame, duration interface{}, f Callable) error {
+	d, err := vals.ScanDuration(duration)
+	if err != nil || d < 0 {
+		return errs.BadValue{What: "timeout",
+			Valid:  "non-negative number of seconds or duration",
+			Actual: vals.Repr(duration, vals.NoPretty)}
+	}
+	ctx, cancel := context.WithTimeout(fm.ctx, d)
+	defer cancel()
+	newFm := fm.fork("[with-timeout]")
+	newFm.ctx = ctx
+	err = f.Call(newFm, NoArgs, NoOpts)
+	// Only report a timeout when it is this deadline that has passed, rather
+	// than an outer deadline or an interrupt signal.
+	if ctx.Err() == context.DeadlineExceeded && fm.ctx.Err() == nil {
+		return TimeoutExceeded{d}
+	}
+	return err
+}
+
 //elvdoc:fn each
 //
 // ```elvish
@@ -114,6 +345,11 @@ func each(fm *Frame, f Callable, inputs Inputs) error {
 		if broken {
 			return
 		}
+		if fm.IsInterrupted() {
+			broken = true
+			err = ErrInterrupted
+			return
+		}
 		newFm := fm.fork("closure of each")
 		ex := f.Call(newFm, []interface{}{v}, NoOpts)
 		newFm.Close()
@@ -133,65 +369,309 @@ func each(fm *Frame, f Callable, inputs Inputs) error {
 	return err
 }
 
+//elvdoc:fn emap
+//
+// ```elvish
+// emap $f $input-list?
+// ```
+//
+// Like `each`, but is optimized for calling `$f` on a large number of inputs.
+//
+// When `$f` is a lambda without an argument list, like `{ * $it 2 }`, it takes
+// one argument named `$it`:
+//
+// ```elvish-transcript
+// ~> range 5 8 | emap { * $it $it }
+// ▶ (float64 25)
+// ▶ (float64 36)
+// ▶ (float64 49)
+// ~> emap [x]{ put $x[:3] } [lorem ipsum]
+// ▶ lor
+// ▶ ips
+// ```
+//
+// @cf each efilter
+
+func emap(fm *Frame, f Callable, inputs Inputs) error {
+	base := fm.fork("closure of emap")
+	defer base.Close()
+	return callEach(fm, base, inputs, func(callFm *Frame, v interface{}) error {
+		return f.Call(callFm, []interface{}{v}, NoOpts)
+	})
+}
+
+//elvdoc:fn efilter
+//
+// ```elvish
+// efilter $f $input-list?
+// ```
+//
+// Calls `$f` on all inputs, and outputs the inputs for which `$f` outputs a
+// booleanly true value. The value outputs of `$f` are not passed through, and
+// there must be exactly one of them for each input.
+//
+// Like with `emap`, when `$f` is a lambda without an argument list, it takes
+// one argument named `$it`:
+//
+// ```elvish-transcript
+// ~> range 10 | efilter { == 0 (% $it 3) }
+// ▶ (float64 0)
+// ▶ (float64 3)
+// ▶ (float64 6)
+// ▶ (float64 9)
+// ~> efilter [s]{ has-prefix $s l } [lorem ipsum lorem]
+// ▶ lorem
+// ▶ lorem
+// ```
+//
+// The `break` and `continue` commands work in `$f` like they do in `each`.
+//
+// @cf emap each
+
+func efilter(fm *Frame, f Callable, inputs Inputs) error {
+	// The value outputs of f are sent to a goroutine, which collects them until
+	// it receives efilterDone and then sends them back.
+	ch := make(chan interface{}, 1)
+	collected := make(chan []interface{})
+	go func() {
+		var outputs []interface{}
+		for v := range ch {
+			if _, done := v.(efilterDone); done {
+				collected <- outputs
+				outputs = nil
+			} else {
+				outputs = append(outputs, v)
+			}
+		}
+	}()
+	defer close(ch)
+
+	base := fm.fork("closure of efilter")
+	defer base.Close()
+	base.ports[1] = &Port{File: base.ports[1].File, Chan: ch}
+	out := fm.OutputChan()
+	return callEach(fm, base, inputs, func(callFm *Frame, v interface{}) error {
+		err := f.Call(callFm, []interface{}{v}, NoOpts)
+		ch <- efilterDone{}
+		outputs := <-collected
+		if err != nil {
+			return err
+		}
+		if len(outputs) != 1 {
+			return errs.ArityMismatch{What: "value outputs of the predicate",
+				ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+		}
+		if vals.Bool(outputs[0]) {
+			out <- v
+		}
+		return nil
+	})
+}
+
+type efilterDone struct{}
+
+// Calls call with each input and a copy of base, which saves forking a new
+// frame for each input. Handles break and continue like each.
+func callEach(fm *Frame, base *Frame, inputs Inputs, call func(*Frame, interface{}) error) error {
+	broken := false
+	var err error
+	inputs(func(v interface{}) {
+		if broken {
+			return
+		}
+		if fm.IsInterrupted() {
+			broken = true
+			err = ErrInterrupted
+			return
+		}
+		callFm := *base
+		ex := call(&callFm, v)
+		if ex != nil {
+			switch Reason(ex) {
+			case nil, Continue:
+				// nop
+			case Break:
+				broken = true
+			default:
+				broken = true
+				err = ex
+			}
+		}
+	})
+	return err
+}
+
 //elvdoc:fn peach
 //
 // ```elvish
-// peach $f $input-list?
+// peach &workers=0 &ordered=$true $f $input-list?
 // ```
 //
 // Call `$f` on all inputs, possibly in parallel.
 //
-// Example (your output will differ):
+// The `&workers` option limits the number of calls to `$f` that can run at the
+// same time. If it is 0 (the default), there is no limit.
+//
+// If `&ordered` is true (the default), the value outputs of `$f` are written in
+// the order of the inputs, like with `each`. Otherwise they are written as soon
+// as they are produced, which uses less memory when `$f` outputs a lot of
+// values. Byte outputs are always written as soon as they are produced.
+//
+// Example:
 //
 // ```elvish-transcript
-// ~> range 1 7 | peach [x]{ + $x 10 }
-// ▶ 12
+// ~> range 1 7 | peach &workers=2 [x]{ + $x 10 }
 // ▶ 11
+// ▶ 12
 // ▶ 13
-// ▶ 16
-// ▶ 15
 // ▶ 14
+// ▶ 15
+// ▶ 16
 // ```
 //
+// If any call to `$f` throws an exception, no more calls are started; after
+// the calls that are still running have finished, the first exception is
+// thrown. Like in `each`, `break` stops starting new calls and `continue` is
+// ignored.
+//
 // This command is intended for homogeneous processing of possibly unbound data. If
 // you need to do a fixed number of heterogeneous things in parallel, use
 // `run-parallel`.
 //
 // @cf each run-parallel
 
-func peach(fm *Frame, f Callable, inputs Inputs) error {
-	var w sync.WaitGroup
-	broken := false
-	var err error
+type peachOpts struct {
+	Workers int
+	Ordered bool
+}
+
+func (o *peachOpts) SetDefaultOptions() { o.Ordered = true }
+
+func peach(fm *Frame, opts peachOpts, f Callable, inputs Inputs) error {
+	if opts.Workers < 0 {
+		return errs.BadValue{What: "workers option",
+			Valid: "non-negative", Actual: strconv.Itoa(opts.Workers)}
+	}
+	var sem chan struct{}
+	if opts.Workers > 0 {
+		sem = make(chan struct{}, opts.Workers)
+	}
+	var out *orderedOutput
+	if opts.Ordered {
+		out = &orderedOutput{ch: fm.OutputChan(), pending: map[int][]interface{}{}}
+	}
+
+	var (
+		w      sync.WaitGroup
+		mutex  sync.Mutex
+		broken bool
+		err    error
+	)
+	stopped := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return broken || err != nil
+	}
+	i := 0
 	inputs(func(v interface{}) {
-		if broken || err != nil {
+		if stopped() {
 			return
 		}
+		if fm.IsInterrupted() {
+			mutex.Lock()
+			err = ErrInterrupted
+			mutex.Unlock()
+			return
+		}
+		if sem != nil {
+			sem <- struct{}{}
+			// Check again, since the wait may be long.
+			if stopped() {
+				<-sem
+				return
+			}
+		}
+		seq := i
+		i++
 		w.Add(1)
 		go func() {
-			newFm := fm.fork("closure of peach")
-			newFm.ports[0] = DevNullClosedChan
-			ex := f.Call(newFm, []interface{}{v}, NoOpts)
-			newFm.Close()
+			defer w.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			var ex error
+			if out == nil {
+				newFm := fm.fork("closure of peach")
+				newFm.ports[0] = DevNullClosedChan
+				ex = f.Call(newFm, []interface{}{v}, NoOpts)
+				newFm.Close()
+			} else {
+				var vs []interface{}
+				ch := make(chan interface{})
+				collected := make(chan struct{})
+				go func() {
+					for v := range ch {
+						vs = append(vs, v)
+					}
+					close(collected)
+				}()
+				newFm := fm.forkWithOutput("closure of peach",
+					&Port{Chan: ch, File: fm.OutputFile()})
+				newFm.ports[0] = DevNullClosedChan
+				ex = f.Call(newFm, []interface{}{v}, NoOpts)
+				newFm.Close()
+				close(ch)
+				<-collected
+				out.put(seq, vs)
+			}
 
 			if ex != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
 				switch Reason(ex) {
 				case nil, Continue:
 					// nop
 				case Break:
 					broken = true
 				default:
-					broken = true
-					err = diag.Errors(err, ex)
+					if err == nil {
+						err = ex
+					}
 				}
 			}
-			w.Done()
 		}()
 	})
 	w.Wait()
 	return err
 }
 
+// Writes the value outputs of calls in peach in the order of the calls.
+type orderedOutput struct {
+	ch      chan<- interface{}
+	mutex   sync.Mutex
+	next    int
+	pending map[int][]interface{}
+}
+
+// Records the value outputs of the seq-th call, and writes all value outputs
+// that are no longer waiting for earlier calls.
+func (o *orderedOutput) put(seq int, vs []interface{}) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.pending[seq] = vs
+	for {
+		vs, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		for _, v := range vs {
+			o.ch <- v
+		}
+		delete(o.pending, o.next)
+		o.next++
+	}
+}
+
 // FailError is an error returned by the "fail" command.
 type FailError struct{ Content interface{} }
 
@@ -242,6 +722,115 @@ func fail(v interface{}) error {
 	return FailError{v}
 }
 
+//elvdoc:fn exception-class
+//
+// ```elvish
+// exception-class $name
+// ```
+//
+// Outputs the exception class with the given name. The `class` field of an
+// exception contains its class, and the `parent` field of a class contains its
+// parent class. See [`try`](language.html#exception-control-try) for the list
+// of exception classes.
+//
+// ```elvish-transcript
+// ~> eq ?(fail bad)[class] (exception-class fail-error)
+// ▶ $true
+// ~> put (exception-class external-cmd-exited)[parent][name]
+// ▶ external-cmd-error
+// ```
+
+func exceptionClass(name string) (*ExceptionClass, error) {
+	if c, ok := excClassesByName[name]; ok {
+		return c, nil
+	}
+	return nil, errs.BadValue{
+		What: "exception class", Valid: "a known exception class", Actual: name}
+}
+
+//elvdoc:fn defer
+//
+// ```elvish
+// defer $callable $args...
+// ```
+//
+// Arranges for `$callable` to be called with `$args` when the enclosing
+// function returns, either normally or by throwing an exception. The arguments
+// are evaluated when `defer` is called. Functions deferred in the same function
+// are called in the reverse order they are deferred.
+//
+// If the enclosing function throws an exception, it is rethrown after all the
+// deferred functions have been called. Otherwise, the first exception thrown by
+// a deferred function is rethrown.
+//
+// Since the bodies of control structures like `if`, `for` and `try` are
+// functions too, functions deferred inside them are called when the body
+// finishes. It is an error to call `defer` outside functions.
+//
+// ```elvish-transcript
+// ~> fn f {
+//      tmp = (mktemp -d)
+//      defer { rm -r $tmp }
+//      defer $echo~ 'cleaning up'
+//      echo 'working in '$tmp
+//    }
+// ~> f
+// working in /tmp/tmp.6UVvfx4Qsk
+// cleaning up
+// ```
+
+var errDeferOutsideFunction = errors.New("defer can only be used in functions")
+
+func deferFn(fm *Frame, f Callable, args ...interface{}) error {
+	return fm.Defer(f, args...)
+}
+
+// Defer arranges for f to be called with args when the function enclosing the
+// frame returns, like the defer builtin. It returns an error if the frame is
+// not in a function.
+func (fm *Frame) Defer(f Callable, args ...interface{}) error {
+	if fm.deferred == nil {
+		return errDeferOutsideFunction
+	}
+	fm.deferred.add(f, args)
+	return nil
+}
+
+// Functions registered with the defer builtin, along with their arguments.
+type deferredFns struct {
+	mutex sync.Mutex
+	calls []deferredCall
+}
+
+type deferredCall struct {
+	fn   Callable
+	args []interface{}
+}
+
+func (d *deferredFns) add(fn Callable, args []interface{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.calls = append(d.calls, deferredCall{fn, args})
+}
+
+// Calls the deferred functions in reverse order. The err argument is the result
+// of the function that deferred them; it is returned if non-nil. Otherwise the
+// first error from the deferred functions is returned.
+func (d *deferredFns) run(fm *Frame, err error) error {
+	d.mutex.Lock()
+	calls := d.calls
+	d.calls = nil
+	d.mutex.Unlock()
+	for i := len(calls) - 1; i >= 0; i-- {
+		call := calls[i]
+		callErr := call.fn.Call(fm.fork("deferred function"), call.args, NoOpts)
+		if err == nil {
+			err = callErr
+		}
+	}
+	return err
+}
+
 func multiErrorFn(excs ...*Exception) error {
 	return PipelineError{excs}
 }
@@ -2,8 +2,10 @@ package eval_test
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
 	"github.com/elves/elvish/pkg/eval/vals"
@@ -23,7 +25,100 @@ func TestBuiltinFnFlow(t *testing.T) {
 		That(`range 10 | each [x]{ if (== $x 4) { fail haha }; put $x }`).
 			Puts(0.0, 1.0, 2.0, 3.0).Throws(AnyError),
 		// TODO(xiaq): Test that "each" does not close the stdin.
-		// TODO: test peach
+
+		That(`put 1 233 | emap $put~`).Puts("1", "233"),
+		That(`emap [x]{ put $x[:3] } [lorem ipsum]`).Puts("lor", "ips"),
+		That(`range 5 8 | emap { * $it $it }`).Puts(25.0, 36.0, 49.0),
+		That(`range 10 | emap { if (== $it 4) { break }; put $it }`).
+			Puts(0.0, 1.0, 2.0, 3.0),
+		That(`range 4 | emap { if (== $it 1) { continue }; put $it }`).
+			Puts(0.0, 2.0, 3.0),
+		That(`range 10 | emap { if (== $it 2) { fail haha }; put $it }`).
+			Puts(0.0, 1.0).Throws(FailError{"haha"}),
+		// $it is only implicit in lambdas without an argument list passed
+		// directly to emap or efilter.
+		That(`emap []{ put $it } [a]`).DoesNotCompile(),
+		That(`f = { put $it }`).DoesNotCompile(),
+		// Nested lambdas capture $it like other variables.
+		That(`emap { each [x]{ put $it$x } [b] } [a]`).Puts("ab"),
+		That(`emap { emap { put $it } [b] } [a]`).Puts("b"),
+
+		That(`range 10 | efilter { == 0 (% $it 3) }`).Puts(0.0, 3.0, 6.0, 9.0),
+		That(`efilter [s]{ has-prefix $s l } [lorem ipsum lorem]`).
+			Puts("lorem", "lorem"),
+		That(`efilter { echo $it; put $true } [a b]`).
+			Puts("a", "b").Prints("a\nb\n"),
+		That(`range 10 | efilter { if (== $it 3) { break }; put $true }`).
+			Puts(0.0, 1.0, 2.0),
+		That(`efilter { put $true $true } [a]`).Throws(errs.ArityMismatch{
+			What: "value outputs of the predicate", ValidLow: 1, ValidHigh: 1, Actual: 2}),
+		That(`efilter { fail bad } [a]`).Throws(FailError{"bad"}),
+
+		That(`range 1 7 | peach [x]{ + $x 10 }`).
+			Puts(11.0, 12.0, 13.0, 14.0, 15.0, 16.0),
+		That(`range 1 7 | peach &workers=2 [x]{ + $x 10 }`).
+			Puts(11.0, 12.0, 13.0, 14.0, 15.0, 16.0),
+		// Outputs are written in the order of the inputs, even when calls
+		// finish in a different order.
+		That(`peach [x]{ sleep (* $x 0.02); put $x } [3 2 1]`).Puts("3", "2", "1"),
+		That(`peach &ordered=$false [x]{ sleep (* $x 0.02); put $x } [3 2 1]`).
+			Puts("1", "2", "3"),
+		That(`peach [x]{ put $x $x } [1 2]`).Puts("1", "1", "2", "2"),
+		// The number of concurrent calls is limited by &workers.
+		That(`peach &workers=1 &ordered=$false [x]{ sleep (* $x 0.02); put $x } [3 2 1]`).
+			Puts("3", "2", "1"),
+		That(`peach &workers=-1 $put~ [1]`).Throws(AnyError),
+		// The first exception is thrown after running calls have finished.
+		That(`peach [x]{ fail bad } [1]`).Throws(FailError{"bad"}),
+		That(`x = ''`,
+			`try { peach &workers=2 [i]{ if (== $i 0) { sleep 0.01; fail bad } else { sleep 0.02; x = done } } [(range 2)] } except { }`,
+			`put $x`).Puts("done"),
+		That(`range 10 | peach &workers=1 [x]{ if (== $x 4) { break }; put $x }`).
+			Puts(0.0, 1.0, 2.0, 3.0),
+
+		That(`put (capture { put foo; echo bar; echo baz >&2 })[values stdout stderr]`).
+			Puts(vals.MakeList("foo"), "bar\n", "baz\n"),
+		That(`eq (capture { put foo })[exception] $nil`).Puts(true),
+		That(`c = (capture { put foo; fail bar })`,
+			`put $c[values] $c[exception][reason][content]`).
+			Puts(vals.MakeList("foo"), "bar"),
+		// Nothing leaks to the outer output.
+		That(`capture { put foo; echo bar; echo baz >&2 } | count`).
+			Puts("1").Prints(""),
+		// Byte outputs are kept as is.
+		That(`put (capture { print "a\x00b"; print "\xff" >&2 })[stdout stderr]`).
+			Puts("a\x00b", "\u00ff"),
+		// Limits.
+		That(`put (capture &max-bytes=4 &max-values=1 { put foo; print bar })[values stdout]`).
+			Puts(vals.MakeList("foo"), "bar"),
+		That(`capture &max-values=2 { range 10 }`).
+			Throws(CaptureLimitExceeded{"values", 2}, "capture &max-values=2 { range 10 }"),
+		That(`capture &max-bytes=3 { print abcd }`).
+			Throws(CaptureLimitExceeded{"bytes of stdout", 3}),
+		That(`capture &max-bytes=3 { print abcd >&2 }`).
+			Throws(CaptureLimitExceeded{"bytes of stderr", 3}),
+		// The callable is interrupted when a limit is exceeded.
+		That(`capture &max-bytes=10 { while $true { echo foo } }`).
+			Throws(CaptureLimitExceeded{"bytes of stdout", 10}),
+		That(`capture &max-values=0 { while $true { put foo } }`).
+			Throws(CaptureLimitExceeded{"values", 0}),
+
+		That(`with-timeout 5 { put foo }`).Puts("foo"),
+		That(`with-timeout 10ms { put foo; sleep 5; put bar }`).Puts("foo").
+			Throws(TimeoutExceeded{10 * time.Millisecond},
+				"with-timeout 10ms { put foo; sleep 5; put bar }"),
+		That(`with-timeout 0.01 { while $true { } }`).
+			Throws(TimeoutExceeded{10 * time.Millisecond}),
+		// The deadline that passes first is reported.
+		That(`with-timeout 10ms { with-timeout 5s { sleep 5 } }`).
+			Throws(TimeoutExceeded{10 * time.Millisecond}),
+		That(`with-timeout 5s { with-timeout 10ms { sleep 5 } }`).
+			Throws(TimeoutExceeded{10 * time.Millisecond}),
+		That(`put ?(with-timeout 0 { sleep 5 })[class][name]`).
+			Puts("timeout-error"),
+		That(`with-timeout foo { }`).Throws(errs.BadValue{What: "timeout",
+			Valid: "non-negative number of seconds or duration", Actual: "foo"}),
+		That(`with-timeout -1 { }`).Throws(AnyError),
 
 		That("fail haha").Throws(FailError{"haha"}, "fail haha"),
 		That("fn f { fail haha }", "fail ?(f)").Throws(
@@ -34,5 +129,23 @@ func TestBuiltinFnFlow(t *testing.T) {
 		That("put ?(fail 1)[reason][content]").Puts("1"),
 
 		That(`return`).Throws(Return),
+
+		// Deferred functions are called in reverse order after the body.
+		That("fn f { defer $echo~ 1; defer $echo~ 2; echo body }", "f").
+			Prints("body\n2\n1\n"),
+		// Arguments are evaluated when defer is called.
+		That("fn f { x = 1; defer $put~ $x; x = 2 }", "f").Puts("1"),
+		// Deferred functions are called when the body throws.
+		That("fn f { defer $echo~ cleanup; fail bad }", "f").
+			Prints("cleanup\n").Throws(FailError{"bad"}, "fail bad ", "f"),
+		// Exceptions from the body take precedence.
+		That("fn f { defer $fail~ cleanup; fail body }", "f").
+			Throws(FailError{"body"}, "fail body ", "f"),
+		That("fn f { defer $fail~ cleanup }", "f").
+			Throws(FailError{"cleanup"}, "f"),
+		// Bodies of control structures are functions too.
+		That("fn f { if $true { defer $echo~ a }; echo b }", "f").
+			Prints("a\nb\n"),
+		That("defer $echo~ foo").Throws(AnyError),
 	)
 }
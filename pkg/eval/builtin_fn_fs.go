@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/elves/elvish/pkg/env"
 	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/store"
 )
@@ -14,6 +15,9 @@ import (
 // ErrStoreNotConnected is thrown by dir-history when the store is not connected.
 var ErrStoreNotConnected = errors.New("store not connected")
 
+// ErrNoOldPwd is thrown by "cd -" when there is no previous directory.
+var ErrNoOldPwd = errors.New("no previous directory")
+
 //elvdoc:fn path-\*
 //
 // ```elvish
@@ -36,6 +40,9 @@ func init() {
 		// Directory
 		"cd":          cd,
 		"dir-history": dirs,
+		"pushd":       pushd,
+		"popd":        popd,
+		"dir-stack":   dirStack,
 
 		// Path
 		"path-abs":      filepath.Abs,
@@ -54,16 +61,23 @@ func init() {
 //elvdoc:fn cd
 //
 // ```elvish
-// cd $dirname
+// cd $dirname?
 // ```
 //
 // Change directory. This affects the entire process; i.e., all threads
 // whether running indirectly (e.g., prompt functions) or started explicitly
 // by commands such as [`peach`](#peach).
 //
-// Note that Elvish's `cd` does not support `cd -`.
+// If `$dirname` is omitted, changes to the home directory. If `$dirname` is
+// `-`, changes to the previous directory, which is kept in `$E:OLDPWD`.
+//
+// The functions in [`$before-chdir`](#before-chdir) and
+// [`$after-chdir`](#after-chdir) are called before and after changing
+// directory. They are also called when the directory is changed in other ways,
+// like by assigning to [`$pwd`](#pwd), using the navigation mode of the editor,
+// or using a directory as a command.
 //
-// @cf pwd
+// @cf pwd pushd auto-cd
 
 func cd(fm *Frame, args ...string) error {
 	var dir string
@@ -76,6 +90,12 @@ func cd(fm *Frame, args ...string) error {
 		}
 	case 1:
 		dir = args[0]
+		if dir == "-" {
+			dir = os.Getenv(env.OLDPWD)
+			if dir == "" {
+				return ErrNoOldPwd
+			}
+		}
 	default:
 		return ErrArgs
 	}
@@ -121,6 +141,85 @@ func dirs(fm *Frame) error {
 	return nil
 }
 
+//elvdoc:fn pushd
+//
+// ```elvish
+// pushd $dirname
+// ```
+//
+// Push the current directory onto the directory stack, and change to
+// `$dirname`. The directory stack is kept in the store, so it is shared by all
+// Elvish sessions.
+//
+// @cf popd dir-stack
+
+func pushd(fm *Frame, dir string) error {
+	if fm.DaemonClient == nil {
+		return ErrStoreNotConnected
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	err = fm.Chdir(dir)
+	if err != nil {
+		return err
+	}
+	return fm.DaemonClient.PushDir(wd)
+}
+
+//elvdoc:fn popd
+//
+// ```elvish
+// popd
+// ```
+//
+// Remove the directory on the top of the directory stack, and change to it.
+//
+// @cf pushd dir-stack
+
+func popd(fm *Frame) error {
+	if fm.DaemonClient == nil {
+		return ErrStoreNotConnected
+	}
+	dir, err := fm.DaemonClient.PopDir()
+	if err != nil {
+		return err
+	}
+	err = fm.Chdir(dir)
+	if err != nil {
+		// Put the directory back, so that it is not lost.
+		fm.DaemonClient.PushDir(dir)
+		return err
+	}
+	return nil
+}
+
+//elvdoc:fn dir-stack
+//
+// ```elvish
+// dir-stack
+// ```
+//
+// Output all directories on the directory stack, starting from the top.
+//
+// @cf pushd popd
+
+func dirStack(fm *Frame) error {
+	if fm.DaemonClient == nil {
+		return ErrStoreNotConnected
+	}
+	dirs, err := fm.DaemonClient.DirStack()
+	if err != nil {
+		return err
+	}
+	out := fm.OutputChan()
+	for _, dir := range dirs {
+		out <- dir
+	}
+	return nil
+}
+
 //elvdoc:fn tilde-abbr
 //
 // ```elvish
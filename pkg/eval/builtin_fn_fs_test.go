@@ -7,12 +7,14 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/elves/elvish/pkg/daemon"
 	. "github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/testutil"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
 	"github.com/elves/elvish/pkg/fsutil"
 	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/store"
 )
 
 // For error injection into the fsutil.GetHome function.
@@ -60,6 +62,10 @@ func TestBuiltinCd(t *testing.T) {
 		// determine the user's home directory.
 		That(`unset-env HOME; cd; set-env HOME `+tmpHome).Throws(
 			errors.New("can't resolve ~: user unknown"), "cd"),
+		// `cd -` changes to the previous directory.
+		That(`cd `+d1Path+`; cd `+tmpHome+`; cd -; put $pwd $E:OLDPWD`).
+			Puts(d1Path, tmpHome),
+		That(`unset-env OLDPWD; cd -`).Throws(ErrNoOldPwd, "cd -"),
 	)
 }
 
@@ -68,5 +74,33 @@ func TestBuiltinDirHistory(t *testing.T) {
 	// is available.
 	Test(t,
 		That(`dir-history`).Throws(ErrStoreNotConnected, "dir-history"),
+		That(`pushd /`).Throws(ErrStoreNotConnected, "pushd /"),
+		That(`popd`).Throws(ErrStoreNotConnected, "popd"),
+		That(`dir-stack`).Throws(ErrStoreNotConnected, "dir-stack"),
+	)
+}
+
+func TestBuiltinDirStack(t *testing.T) {
+	tmpHome, cleanup := testutil.InTempHome()
+	defer cleanup()
+	testutil.MustMkdirAll("d1", "d2")
+	d1Path := filepath.Join(tmpHome, "d1")
+	d2Path := filepath.Join(tmpHome, "d2")
+
+	client := daemon.NewLocalClient(filepath.Join(tmpHome, "db"))
+	defer client.Close()
+	setup := func(ev *Evaler) { ev.InstallDaemonClient(client) }
+	TestWithSetup(t, setup,
+		That(`pushd `+d1Path+`; pushd `+d2Path+`; put $pwd; dir-stack`).
+			Puts(d2Path, d1Path, tmpHome),
+		That(`popd; put $pwd; popd; put $pwd`).Puts(d1Path, tmpHome),
+		That(`popd`).Throws(store.ErrEmptyDirStack, "popd"),
+	)
+
+	// The directory is kept on the stack if changing to it fails.
+	client.PushDir(filepath.Join(tmpHome, "nonexistent"))
+	TestWithSetup(t, setup,
+		That(`popd`).Throws(AnyError, "popd"),
+		That(`dir-stack`).Puts(filepath.Join(tmpHome, "nonexistent")),
 	)
 }
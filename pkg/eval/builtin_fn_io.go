@@ -1,13 +1,20 @@
 package eval
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval/errs"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/strutil"
 )
@@ -452,12 +459,21 @@ func fromLines(fm *Frame) {
 //elvdoc:fn from-json
 //
 // ```elvish
-// from-json
+// from-json &lines=$false
 // ```
 //
 // Takes bytes stdin, parses it as JSON and puts the result on structured stdout.
 // The input can contain multiple JSONs, which can, but do not have to, be
-// separated with whitespaces.
+// separated with whitespaces. Values are output as soon as they are parsed, so
+// `from-json` can be used on a stream of JSONs that doesn't end.
+//
+// If `&lines` is true, the input is parsed as [JSON Lines](https://jsonlines.org):
+// each line must contain exactly one JSON, and empty lines are ignored. Errors
+// include the line number.
+//
+// JSON numbers become `float64` values, except for integers that can't be
+// represented by a `float64` without losing precision, which become exact big
+// integers.
 //
 // Examples:
 //
@@ -478,15 +494,67 @@ func fromLines(fm *Frame) {
 // {"k": "v"}' | from-json
 // ▶ a
 // ▶ [&k=v]
+// ~> echo 12345678901234567890 | from-json
+// ▶ (num 12345678901234567890)
+// ~> echo '1 2' | from-json &lines
+// Exception: line 1: more than one JSON
+// [tty 1], line 1: echo '1 2' | from-json &lines
 // ```
 //
 // @cf to-json
 
-func fromJSON(fm *Frame) error {
+type fromJSONOpts struct{ Lines bool }
+
+func (*fromJSONOpts) SetDefaultOptions() {}
+
+func fromJSON(fm *Frame, opts fromJSONOpts) error {
 	in := fm.InputFile()
 	out := fm.OutputChan()
 
-	dec := json.NewDecoder(in)
+	if !opts.Lines {
+		return decodeJSONStream(json.NewDecoder(in), out)
+	}
+	reader := bufio.NewReader(in)
+	for lineno := 1; ; lineno++ {
+		line, errRead := reader.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			if err := decodeJSONLine(line, out); err != nil {
+				return fmt.Errorf("line %d: %w", lineno, err)
+			}
+		}
+		if errRead != nil {
+			if errRead == io.EOF {
+				return nil
+			}
+			return errRead
+		}
+	}
+}
+
+var errMultipleJSONs = errors.New("more than one JSON")
+
+// Decodes a line that must contain exactly one JSON.
+func decodeJSONLine(line string, out chan<- interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errMultipleJSONs
+	}
+	converted, err := fromJSONInterface(v)
+	if err != nil {
+		return err
+	}
+	out <- converted
+	return nil
+}
+
+// Decodes all JSONs from dec.
+func decodeJSONStream(dec *json.Decoder, out chan<- interface{}) error {
+	dec.UseNumber()
 	for {
 		var v interface{}
 		err := dec.Decode(&v)
@@ -504,13 +572,21 @@ func fromJSON(fm *Frame) error {
 	}
 }
 
-// Converts a interface{} that results from json.Unmarshal to an Elvish value.
+// Converts a interface{} that results from json.Unmarshal, with numbers decoded
+// as json.Number, to an Elvish value.
 func fromJSONInterface(v interface{}) (interface{}, error) {
 	switch v := v.(type) {
 	case nil, bool, string:
 		return v, nil
-	case float64:
-		return v, nil
+	case json.Number:
+		n, err := vals.ScanNum(string(v))
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := n.(*big.Int); ok {
+			return vals.NormalizeBigInt(i), nil
+		}
+		return n, nil
 	case []interface{}:
 		vec := vals.EmptyList
 		for _, elem := range v {
@@ -570,10 +646,21 @@ func toLines(fm *Frame, inputs Inputs) {
 //elvdoc:fn to-json
 //
 // ```elvish
-// to-json
+// to-json &indent='' &sort-keys=$false &strict-numbers=$false $input?
 // ```
 //
 // Takes structured stdin, convert it to JSON and puts the result on bytes stdout.
+// Each value is written on its own line, so the output is in the
+// [JSON Lines](https://jsonlines.org) format unless `&indent` is given.
+//
+// If `&indent` is not empty, the JSONs are pretty-printed, using `&indent` for
+// each level of indentation. If `&sort-keys` is true, the keys of maps are
+// sorted; otherwise they appear in an unspecified order.
+//
+// Big integers are written exactly, and fractions are written as their
+// nearest `float64` values. Many JSON parsers can't read integers larger than
+// 2^53 exactly; if `&strict-numbers` is true, such integers and fractions
+// cause an exception instead.
 //
 // ```elvish-transcript
 // ~> put a | to-json
@@ -582,23 +669,143 @@ func toLines(fm *Frame, inputs Inputs) {
 // ["lorem","ipsum"]
 // ~> put [&lorem=ipsum] | to-json
 // {"lorem":"ipsum"}
+// ~> to-json &indent='  ' &sort-keys [[&b=[x] &a=y]]
+// {
+//   "a": "y",
+//   "b": [
+//     "x"
+//   ]
+// }
 // ```
 //
 // @cf from-json
 
-func toJSON(fm *Frame, inputs Inputs) error {
-	encoder := json.NewEncoder(fm.OutputFile())
+type toJSONOpts struct {
+	Indent        string
+	SortKeys      bool
+	StrictNumbers bool
+}
+
+func (*toJSONOpts) SetDefaultOptions() {}
+
+func toJSON(fm *Frame, opts toJSONOpts, inputs Inputs) error {
+	out := fm.OutputFile()
 
 	var errEncode error
 	inputs(func(v interface{}) {
 		if errEncode != nil {
 			return
 		}
-		errEncode = encoder.Encode(v)
+		var buf bytes.Buffer
+		errEncode = encodeJSON(&buf, v, opts)
+		if errEncode != nil {
+			return
+		}
+		if opts.Indent != "" {
+			var indented bytes.Buffer
+			errEncode = json.Indent(&indented, buf.Bytes(), "", opts.Indent)
+			if errEncode != nil {
+				return
+			}
+			buf = indented
+		}
+		buf.WriteByte('\n')
+		_, errEncode = out.Write(buf.Bytes())
 	})
 	return errEncode
 }
 
+// Writes the compact JSON encoding of v to buf.
+func encodeJSON(buf *bytes.Buffer, v interface{}, opts toJSONOpts) error {
+	switch v := v.(type) {
+	case *big.Int:
+		if _, inexact := vals.NormalizeBigInt(v).(*big.Int); inexact && opts.StrictNumbers {
+			return errs.BadValue{What: "number in to-json",
+				Valid: "integer within ±2^53", Actual: v.String()}
+		}
+		buf.WriteString(v.String())
+		return nil
+	case *big.Rat:
+		if opts.StrictNumbers {
+			return errs.BadValue{What: "number in to-json",
+				Valid: "integer or float64", Actual: v.String()}
+		}
+		return encodeJSONLeaf(buf, vals.NumToFloat64(v))
+	case vals.List:
+		buf.WriteByte('[')
+		for it, first := v.Iterator(), true; it.HasElem(); it.Next() {
+			if first {
+				first = false
+			} else {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, it.Elem(), opts); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case vals.Map, vals.OrderedMap, vals.StructMap, vals.PseudoStructMap:
+		return encodeJSONObject(buf, v, opts)
+	default:
+		return encodeJSONLeaf(buf, v)
+	}
+}
+
+// A key of a map, and its name in JSON.
+type jsonKey struct {
+	key  interface{}
+	name string
+}
+
+func encodeJSONObject(buf *bytes.Buffer, m interface{}, opts toJSONOpts) error {
+	var keys []jsonKey
+	var errKey error
+	vals.IterateKeys(m, func(k interface{}) bool {
+		switch k.(type) {
+		case string, float64, *big.Int, *big.Rat:
+			keys = append(keys, jsonKey{k, vals.ToString(k)})
+			return true
+		default:
+			errKey = fmt.Errorf("map key must be string or number, got %s", vals.Kind(k))
+			return false
+		}
+	})
+	if errKey != nil {
+		return errKey
+	}
+	if opts.SortKeys {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+	}
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodeJSONLeaf(buf, k.name)
+		buf.WriteByte(':')
+		v, err := vals.Index(m, k.key)
+		if err != nil {
+			return err
+		}
+		if err := encodeJSON(buf, v, opts); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// Writes v to buf using the default JSON encoding.
+func encodeJSONLeaf(buf *bytes.Buffer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
 //elvdoc:fn fopen
 //
 // ```elvish
@@ -619,7 +826,7 @@ func toJSON(fm *Frame, inputs Inputs) error {
 // ~> fclose $f
 // ```
 //
-// @cf fclose
+// @cf fclose file:open
 
 func fopen(name string) (vals.File, error) {
 	// TODO support opening files for writing etc as well.
@@ -672,7 +879,7 @@ func fclose(f vals.File) error {
 // # $p should be closed with prclose and pwclose afterwards
 // ```
 //
-// @cf prclose pwclose
+// @cf prclose pwclose file:pipe
 
 func pipe() (vals.Pipe, error) {
 	r, w, err := os.Pipe()
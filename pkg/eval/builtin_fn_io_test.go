@@ -46,6 +46,15 @@ func TestBuiltinFnIO(t *testing.T) {
 		That(`echo '[null, "foo"]' | from-json`).Puts(
 			vals.MakeList(nil, "foo")),
 		That(`echo 'invalid' | from-json`).Throws(AnyError),
+		// Big integers
+		That(`echo '[12345678901234567890, 1.5, 2]' | from-json`).Puts(
+			vals.MakeList(bigInt("12345678901234567890"), 1.5, 2.0)),
+		// JSON Lines
+		That("print '1\n\n[\"a\"]\n{\"k\": 2}' | from-json &lines").Puts(
+			1.0, vals.MakeList("a"), vals.MakeMap("k", 2.0)),
+		That("print '1\n2 3\n' | from-json &lines").Puts(1.0).Throws(
+			AnyError),
+		That("print '1\n[\n' | from-json &lines").Puts(1.0).Throws(AnyError),
 
 		That(`put "l\norem" ipsum | to-lines`).Prints("l\norem\nipsum\n"),
 		That(`put [&k=v &a=[1 2]] foo | to-json`).
@@ -53,5 +62,23 @@ func TestBuiltinFnIO(t *testing.T) {
 "foo"
 `),
 		That(`put [$nil foo] | to-json`).Prints("[null,\"foo\"]\n"),
+		That(`put [[] [&]] | to-json`).Prints("[[],{}]\n"),
+		// Indentation and key ordering
+		That(`to-json &indent='  ' &sort-keys [[&b=[x] &a=y &c=[&]]]`).Prints(
+			"{\n  \"a\": \"y\",\n  \"b\": [\n    \"x\"\n  ],\n  \"c\": {}\n}\n"),
+		That(`to-json &sort-keys [[&(float64 2)=x &(float64 1)=y]]`).Prints(
+			`{"1":"y","2":"x"}`+"\n"),
+		That(`to-json [[&[]=x]]`).Throws(AnyError),
+		// Numbers
+		That(`put [(float64 1.5) (num 12345678901234567890) (num 1/4)] | to-json`).Prints(
+			"[1.5,12345678901234567890,0.25]\n"),
+		That(`to-json &strict-numbers [(float64 1.5) (num 9007199254740992)]`).Prints(
+			"1.5\n9007199254740992\n"),
+		That(`to-json &strict-numbers [(num 9007199254740993)]`).Throws(AnyError),
+		That(`to-json &strict-numbers [(num 1/4)]`).Throws(AnyError),
+		That(`to-json [(float64 nan)]`).Throws(AnyError),
+		// Round trip
+		That(`put [(num 12345678901234567890)] | to-json | from-json`).Puts(
+			vals.MakeList(bigInt("12345678901234567890"))),
 	)
 }
@@ -8,11 +8,15 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
+	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/eval/errs"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
 	"github.com/elves/elvish/pkg/parse"
@@ -26,7 +30,10 @@ func init() {
 		"kind-of":    kindOf,
 		"constantly": constantly,
 
-		"resolve": resolve,
+		"resolve":  resolve,
+		"open-def": openDef,
+		"fn-info":  fnInfo,
+		"doc":      doc,
 
 		"eval":    eval,
 		"use-mod": useMod,
@@ -169,10 +176,208 @@ func resolve(fm *Frame, head string) string {
 	}
 }
 
+//elvdoc:fn open-def
+//
+// ```elvish
+// open-def $fn
+// ```
+//
+// Opens the file where the user-defined function `$fn` is defined in an
+// external editor, at the line where the definition starts.
+//
+// The editor is taken from `$E:VISUAL`, or `$E:EDITOR` if `$E:VISUAL` is empty,
+// or `vi` if both are empty. It is invoked as `$editor +$line $file`, which is
+// understood by most editors.
+//
+// Throws an exception if `$fn` is not a user-defined function, or if it is not
+// defined in a file (for instance, when it is defined in the interactive
+// editor).
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> cat ~/.elvish/lib/greet.elv
+// # Greets someone.
+// fn hello [name]{ echo 'Hello, '$name }
+// ~> use greet
+// ~> E:EDITOR = echo
+// ~> open-def $greet:hello~
+// +2 /home/elf/.elvish/lib/greet.elv
+// ```
+//
+// @cf resolve
+
+// ErrNotUserDefinedFn is thrown when a function value is expected to be a
+// user-defined function, but is not.
+var ErrNotUserDefinedFn = errors.New("not a user-defined function")
+
+// ErrFnNotInFile is thrown by open-def when the function is not defined in a
+// file.
+var ErrFnNotInFile = errors.New("function is not defined in a file")
+
+func openDef(fm *Frame, fn Callable) error {
+	c, ok := fn.(*closure)
+	if !ok {
+		return ErrNotUserDefinedFn
+	}
+	src, r := c.SrcMeta.Locate(c.DefRange)
+	if !src.IsFile {
+		return ErrFnNotInFile
+	}
+	line := strings.Count(src.Code[:r.From], "\n") + 1
+
+	editor := strings.Fields(os.Getenv(env.VISUAL))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv(env.EDITOR))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	args := make([]interface{}, 0, len(editor)+1)
+	for _, arg := range editor[1:] {
+		args = append(args, arg)
+	}
+	args = append(args, "+"+strconv.Itoa(line), src.Name)
+	return ExternalCmd{editor[0]}.Call(fm, args, NoOpts)
+}
+
+//elvdoc:fn fn-info
+//
+// ```elvish
+// fn-info $fn
+// ```
+//
+// Outputs a map describing the user-defined function `$fn`, with the following
+// keys:
+//
+// -   `name`: The name of the function if it is defined with `fn`, or an empty
+//     string otherwise.
+//
+// -   `arg-names` and `opt-names`: Lists of the names of the arguments and
+//     options.
+//
+// -   `rest-arg`: The name of the rest argument, or an empty string if there is
+//     none.
+//
+// -   `doc`: The [docstring](language.html#docstrings) of the function. If it
+//     has none, the comment lines immediately before the line where the function
+//     is defined, with the leading `#` and one following space removed from each
+//     line.
+//
+// -   `src`: The name of the source where the function is defined, which is a
+//     path if `is-file` is true.
+//
+// -   `is-file`: Whether the function is defined in a file.
+//
+// -   `line` and `column`: The position where the definition starts, both
+//     starting from 1.
+//
+// Throws an exception if `$fn` is not a user-defined function.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> cat ~/.elvish/lib/greet.elv
+// # Greets someone.
+// fn hello [name &greeting=Hello]{ echo $greeting', '$name }
+// ~> use greet
+// ~> info = (fn-info $greet:hello~)
+// ~> put $info[name arg-names opt-names doc line]
+// ▶ hello
+// ▶ [name]
+// ▶ [greeting]
+// ▶ 'Greets someone.'
+// ▶ 2
+// ```
+//
+// @cf open-def doc
+
+func fnInfo(fn Callable) (vals.Map, error) {
+	c, ok := fn.(*closure)
+	if !ok {
+		return nil, ErrNotUserDefinedFn
+	}
+	restArg := ""
+	if c.RestArg != -1 {
+		restArg = c.ArgNames[c.RestArg]
+	}
+	src, r := c.SrcMeta.Locate(c.DefRange)
+	lineStart := strings.LastIndexByte(src.Code[:r.From], '\n') + 1
+	line := strings.Count(src.Code[:lineStart], "\n") + 1
+	column := utf8.RuneCountInString(src.Code[lineStart:r.From]) + 1
+	return vals.MakeMap(
+		"name", c.Name,
+		"arg-names", listOfStrings(c.ArgNames),
+		"opt-names", listOfStrings(c.OptNames),
+		"rest-arg", restArg,
+		"doc", c.docText(),
+		"src", src.Name,
+		"is-file", src.IsFile,
+		"line", strconv.Itoa(line),
+		"column", strconv.Itoa(column)), nil
+}
+
+//elvdoc:fn doc
+//
+// ```elvish
+// doc $fn
+// ```
+//
+// Prints the documentation of the user-defined function `$fn`, which is the
+// same as the `doc` field of the output of `fn-info`. Prints nothing if the
+// function has no documentation.
+//
+// Throws an exception if `$fn` is not a user-defined function.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> fn hello [name]{
+//      'Greets someone.'
+//      echo 'Hello, '$name
+//    }
+// ~> doc $hello~
+// Greets someone.
+// ~> fn &doc='Says goodbye.' bye [name]{ echo 'Bye, '$name }
+// ~> doc $bye~
+// Says goodbye.
+// ```
+//
+// @cf fn-info
+
+func doc(fm *Frame, fn Callable) error {
+	c, ok := fn.(*closure)
+	if !ok {
+		return ErrNotUserDefinedFn
+	}
+	text := c.docText()
+	if text == "" {
+		return nil
+	}
+	_, err := fm.OutputFile().WriteString(strings.TrimSuffix(text, "\n") + "\n")
+	return err
+}
+
+// Returns the comment lines at the end of code, with the leading "#" and one
+// following space removed.
+func docComment(code string) string {
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	i := len(lines)
+	for i > 0 && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "#") {
+		i--
+	}
+	doc := lines[i:]
+	for j, line := range doc {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "#")
+		doc[j] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(doc, "\n")
+}
+
 //elvdoc:fn eval
 //
 // ```elvish
-// eval $code &ns=$nil
+// eval $code &ns=$nil &source-map=$nil
 // ```
 //
 // Evaluates `$code`, which should be a string. The evaluation happens in the
@@ -182,6 +387,24 @@ func resolve(fm *Frame, head string) string {
 // If `$code` fails to parse or compile, the parse error or compilation error is
 // raised as an exception.
 //
+// When `$code` is generated from other source code, like a template file, the
+// `&source-map` option can be used to make errors and stack traces point to
+// the original source code. It is a list of maps, each mapping a range of
+// `$code` to a range of another source, with the following keys:
+//
+// -   `from` and `to`: The range in `$code`, as byte offsets; `from` is
+//     inclusive and `to` is exclusive.
+//
+// -   `origin`: The original source, as a map with `name` and `code` keys and
+//     an optional `is-file` key, like the output of [`src`](#src).
+//
+// -   `origin-from` and `origin-to`: The range in the original source.
+//
+// A position within a range of `$code` is mapped to the same position relative
+// to the range in the original source if both ranges have the same length,
+// and to the whole range in the original source otherwise. Positions outside
+// all the ranges are not mapped.
+//
 // Examples:
 //
 // ```elvish-transcript
@@ -204,13 +427,34 @@ func resolve(fm *Frame, head string) string {
 // compilation error: variable $x not found
 // [tty 4], line 1: put $x
 // ```
+//
+// An example of using `&source-map`, in which the first line of `$code` is
+// generated from `tpl.elv`:
+//
+// ```elvish-transcript
+// ~> tpl = 'fail oops'
+// ~> eval "fail oops\necho after" &source-map=[[&from=0 &to=9 &origin=[&name=tpl.elv &code=$tpl] &origin-from=0 &origin-to=9]]
+// Exception: oops
+// tpl.elv, line 1: fail oops
+// [tty 2], line 1: eval "fail oops\necho after" &source-map=[[&from=0 &to=9 &origin=[&name=tpl.elv &code=$tpl] &origin-from=0 &origin-to=9]]
+// ```
 
-type evalOpts struct{ Ns *Ns }
+type evalOpts struct {
+	Ns        *Ns
+	SourceMap vals.List
+}
 
 func (*evalOpts) SetDefaultOptions() {}
 
 func eval(fm *Frame, opts evalOpts, code string) error {
 	src := parse.Source{Name: fmt.Sprintf("[eval %d]", nextEvalCount()), Code: code}
+	if opts.SourceMap != nil {
+		sourceMap, err := scanSourceMap(opts.SourceMap)
+		if err != nil {
+			return err
+		}
+		src = src.WithSourceMap(sourceMap)
+	}
 	ns := opts.Ns
 	if ns == nil {
 		ns = new(Ns)
@@ -218,6 +462,51 @@ func eval(fm *Frame, opts evalOpts, code string) error {
 	return evalInner(fm, src, ns, fm.traceback)
 }
 
+func scanSourceMap(l vals.List) (parse.SourceMap, error) {
+	var sourceMap parse.SourceMap
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		var m parse.Mapping
+		var origin interface{}
+		err := scanFields(it.Elem(),
+			"from", &m.From, "to", &m.To, "origin", &origin,
+			"origin-from", &m.OriginFrom, "origin-to", &m.OriginTo)
+		if err != nil {
+			return nil, err
+		}
+		err = scanFields(origin, "name", &m.Origin.Name, "code", &m.Origin.Code)
+		if err != nil {
+			return nil, err
+		}
+		if isFile, err := vals.Index(origin, "is-file"); err == nil {
+			m.Origin.IsFile = vals.Bool(isFile)
+		}
+		if m.From < 0 || m.From > m.To ||
+			m.OriginFrom < 0 || m.OriginFrom > m.OriginTo || m.OriginTo > len(m.Origin.Code) {
+			return nil, errs.BadValue{What: "source map entry",
+				Valid: "valid ranges", Actual: vals.Repr(it.Elem(), vals.NoPretty)}
+		}
+		sourceMap = append(sourceMap, m)
+	}
+	return sourceMap, nil
+}
+
+// Scans the fields of a map-like value into pointers. The arguments after v
+// are pairs of keys and pointers.
+func scanFields(v interface{}, keyPtrs ...interface{}) error {
+	for i := 0; i < len(keyPtrs); i += 2 {
+		key, ptr := keyPtrs[i].(string), keyPtrs[i+1]
+		field, err := vals.Index(v, key)
+		if err != nil {
+			return err
+		}
+		err = vals.ScanToGo(field, ptr)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
 // Used to generate unique names for each source passed to eval.
 var (
 	evalCount      int
@@ -312,7 +601,7 @@ func source(fm *Frame, fname string) error {
 	// Amalgamate the up and local scope into a new scope to use as the global
 	// scope to evaluate the code in.
 	g := amalgamateNs(fm.local, fm.up)
-	op, err := compile(fm.Builtin.static(), g.static(), tree, fm.ErrorFile())
+	op, err := fm.Evaler.compile(tree, g, fm.ErrorFile())
 	if err != nil {
 		return err
 	}
@@ -384,6 +673,9 @@ var TimeAfter = func(fm *Frame, d time.Duration) <-chan time.Time {
 // See the [Go documentation](https://golang.org/pkg/time/#ParseDuration) for
 // more information about how durations are parsed.
 //
+// A duration can also be a duration value, like the ones output by
+// [`time:duration`](time.html#timeduration).
+//
 // Examples:
 //
 // ```elvish-transcript
@@ -397,25 +689,10 @@ var TimeAfter = func(fm *Frame, d time.Duration) <-chan time.Time {
 // ```
 
 func sleep(fm *Frame, duration interface{}) error {
-	var d time.Duration
-
-	switch duration := duration.(type) {
-	case float64:
-		d = time.Duration(float64(time.Second) * duration)
-	case string:
-		f, err := strconv.ParseFloat(duration, 64)
-		if err == nil { // it's a simple number assumed to have units == seconds
-			d = time.Duration(float64(time.Second) * f)
-		} else {
-			d, err = time.ParseDuration(duration)
-			if err != nil {
-				return errors.New("invalid sleep duration")
-			}
-		}
-	default:
+	d, err := vals.ScanDuration(duration)
+	if err != nil {
 		return errors.New("invalid sleep duration")
 	}
-
 	if d < 0 {
 		return fmt.Errorf("sleep duration must be >= zero")
 	}
@@ -2,12 +2,14 @@ package eval_test
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
 	. "github.com/elves/elvish/pkg/eval"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/testutil"
 )
 
@@ -26,6 +28,32 @@ func TestBuiltinFnMisc(t *testing.T) {
 		That("eval 'put $x'").Throws(AnyError),
 		// Exception.
 		That("eval 'fail x'").Throws(FailError{"x"}),
+		// Source map.
+		That("m = [[&from=0 &to=6 &origin=[&name=tpl.elv &code='nop; fail x'] &origin-from=5 &origin-to=11]]",
+			"e = ?(eval 'fail x' &source-map=$m)",
+			"put $e[stack][0][file begin end]").
+			Puts("tpl.elv", "5", "11"),
+		That("m = [[&from=0 &to=6 &origin=[&name=tpl.elv &code='fail x'] &origin-from=0 &origin-to=6]]",
+			"eval 'fail x' &source-map=$m").
+			Throws(FailError{"x"}, "fail x", "eval 'fail x' &source-map=$m"),
+		// Ranges with different lengths are mapped to the whole range.
+		That("m = [[&from=0 &to=10 &origin=[&name=tpl.elv &code='{{fail}}'] &origin-from=0 &origin-to=8]]",
+			"e = ?(eval 'fail x; nop' &source-map=$m)",
+			"put $e[stack][0][file begin end]").
+			Puts("tpl.elv", "0", "8"),
+		// Ranges outside the source map are not mapped.
+		That("m = [[&from=0 &to=3 &origin=[&name=tpl.elv &code='nop'] &origin-from=0 &origin-to=3]]",
+			"e = ?(eval 'nop; fail x' &source-map=$m)",
+			"put $e[stack][0][begin end]").
+			Puts("5", "11"),
+		// Compilation errors.
+		That("m = [[&from=0 &to=6 &origin=[&name=tpl.elv &code='put $x'] &origin-from=0 &origin-to=6]]",
+			"eval 'put $x' &source-map=$m").
+			Throws(ErrorWithMessage("compilation error: 4-6 in tpl.elv: variable $x not found")),
+		// Bad source maps.
+		That("eval nop &source-map=[[&from=0 &to=3]]").Throws(AnyError),
+		That("eval nop &source-map=[[&from=0 &to=3 &origin=[&name=a &code=nop] &origin-from=0 &origin-to=30]]").
+			Throws(AnyError),
 
 		// Test the "time" builtin.
 		//
@@ -78,6 +106,9 @@ func timeAfterMock(fm *Frame, d time.Duration) <-chan time.Time {
 }
 
 func TestSleep(t *testing.T) {
+	defer func(saved func(*Frame, time.Duration) <-chan time.Time) {
+		TimeAfter = saved
+	}(TimeAfter)
 	TimeAfter = timeAfterMock
 	Test(t,
 		That(`sleep 0`).Puts(0*time.Second),
@@ -119,3 +150,50 @@ func TestResolve(t *testing.T) {
 		That(`resolve external`).Puts("$external~"),
 	)
 }
+
+func TestFnInfo(t *testing.T) {
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustWriteFile("mod.elv", []byte(
+		"# Greets someone.\n#\n#   Indented.\nfn hello [name @rest &greeting=Hello]{ }\n"+
+			"x = 1\nfn bare { }\n"), 0600)
+	modPath := filepath.Join(libdir, "mod.elv")
+
+	TestWithSetup(t, func(ev *Evaler) { ev.SetLibDir(libdir) },
+		That("use mod; fn-info $mod:hello~").Puts(vals.MakeMap(
+			"name", "hello",
+			"arg-names", vals.MakeList("name", "rest"),
+			"opt-names", vals.MakeList("greeting"),
+			"rest-arg", "rest",
+			"doc", "Greets someone.\n\n  Indented.",
+			"src", modPath,
+			"is-file", true,
+			"line", "4",
+			"column", "10")),
+		That("use mod; fn-info $mod:bare~").Puts(vals.MakeMap(
+			"name", "bare",
+			"arg-names", vals.EmptyList,
+			"opt-names", vals.EmptyList,
+			"rest-arg", "",
+			"doc", "",
+			"src", modPath,
+			"is-file", true,
+			"line", "6",
+			"column", "9")),
+		That("put (fn-info [x]{ })[name arg-names is-file line]").
+			Puts("", vals.MakeList("x"), false, "1"),
+		That("fn-info $put~").Throws(ErrNotUserDefinedFn),
+		That("fn f { 'Docstring.'; nop }; put (fn-info $f~)[doc]").Puts("Docstring."),
+	)
+}
+
+func TestDoc(t *testing.T) {
+	Test(t,
+		That("fn f { 'Line 1.\nLine 2.'; nop }; doc $f~").Prints("Line 1.\nLine 2.\n"),
+		That("fn &doc=Doc. f { }; doc $f~").Prints("Doc.\n"),
+		That("# Comment.\nfn f { }; doc $f~").Prints("Comment.\n"),
+		That("doc { }").DoesNothing(),
+		That("doc $put~").Throws(ErrNotUserDefinedFn),
+	)
+}
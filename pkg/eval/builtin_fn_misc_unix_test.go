@@ -4,6 +4,7 @@ package eval_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -29,6 +30,9 @@ func interruptedTimeAfterMock(fm *Frame, d time.Duration) <-chan time.Time {
 }
 
 func TestInterruptedSleep(t *testing.T) {
+	defer func(saved func(*Frame, time.Duration) <-chan time.Time) {
+		TimeAfter = saved
+	}(TimeAfter)
 	TimeAfter = interruptedTimeAfterMock
 	Test(t,
 		// Special-case that should result in the sleep being interrupted. See
@@ -36,3 +40,20 @@ func TestInterruptedSleep(t *testing.T) {
 		That(`sleep 1s`).Throws(ErrInterrupted, "sleep 1s"),
 	)
 }
+
+func TestOpenDef(t *testing.T) {
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"a.elv": "# Comment.\nfn f [x]{\n  put $x\n}\n",
+	})
+	defer testutil.WithTempEnv("VISUAL", "")()
+	defer testutil.WithTempEnv("EDITOR", "echo editor")()
+
+	TestWithSetup(t, func(ev *Evaler) { ev.SetLibDir(libdir) },
+		That("use a", "open-def $a:f~").
+			Prints("editor +2 "+filepath.Join(libdir, "a.elv")+"\n"),
+		That("fn f { }", "open-def $f~").Throws(ErrFnNotInFile, "open-def $f~"),
+		That("open-def $put~").Throws(ErrNotUserDefinedFn, "open-def $put~"),
+	)
+}
@@ -1,6 +1,8 @@
 package eval
 
 import (
+	"math"
+	"math/big"
 	"math/rand"
 
 	"github.com/elves/elvish/pkg/eval/vals"
@@ -23,8 +25,9 @@ import (
 
 func init() {
 	addBuiltinFns(map[string]interface{}{
-		// Constructor
+		// Constructors
 		"float64": toFloat64,
+		"num":     num,
 
 		// Comparison
 		"<":  lt,
@@ -65,6 +68,73 @@ func toFloat64(f float64) float64 {
 	return f
 }
 
+//elvdoc:fn num
+//
+// ```elvish
+// num $number
+// ```
+//
+// Convert a string to a number. Integers that are too large to be represented
+// exactly by a `float64` are converted to big integers, and fractions like
+// `1/3` are converted to exact rational numbers:
+//
+// ```elvish-transcript
+// ~> num 10
+// ▶ (float64 10)
+// ~> num 100000000000000000000
+// ▶ (num 100000000000000000000)
+// ~> num 1/3
+// ▶ (num 1/3)
+// ```
+//
+// Like `float64`, this command is seldom needed, since commands that operate on
+// numbers convert strings to numbers in the same way. See the discussion of the
+// [number](language.html#number) data type.
+
+func num(n number) interface{} {
+	return n.normalize()
+}
+
+// A number argument, which is either a float64 for inexact numbers, or a
+// *big.Int or *big.Rat for exact ones. See vals.ScanNum.
+type number struct{ v interface{} }
+
+func (n *number) ScanElvish(v interface{}) error {
+	var err error
+	n.v, err = vals.ScanNum(v)
+	return err
+}
+
+// Returns the number as an Elvish value.
+func (n number) normalize() interface{} {
+	switch v := n.v.(type) {
+	case *big.Int:
+		return vals.NormalizeBigInt(v)
+	case *big.Rat:
+		return vals.NormalizeBigRat(v)
+	default:
+		return v
+	}
+}
+
+// Reports whether all numbers are exact, and whether any of them is a
+// rational. Like in vals.NumToBigRat, float64 values that are integers within
+// ±2^53 are exact.
+func classifyNums(nums []number) (exact, anyRat bool) {
+	exact = true
+	for _, n := range nums {
+		switch v := n.v.(type) {
+		case float64:
+			if vals.NumToBigRat(v) == nil {
+				exact = false
+			}
+		case *big.Rat:
+			anyRat = true
+		}
+	}
+	return exact, anyRat
+}
+
 //elvdoc:fn &lt; &lt;= == != &gt; &gt;=
 //
 // ```elvish
@@ -110,54 +180,55 @@ func toFloat64(f float64) float64 {
 // ▶ $true
 // ```
 
-func lt(nums ...float64) bool {
-	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] < nums[i+1]) {
-			return false
-		}
-	}
-	return true
+func lt(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a < b },
+		func(c int) bool { return c < 0 })
 }
 
-func le(nums ...float64) bool {
-	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] <= nums[i+1]) {
-			return false
-		}
-	}
-	return true
+func le(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a <= b },
+		func(c int) bool { return c <= 0 })
 }
 
-func eqNum(nums ...float64) bool {
-	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] == nums[i+1]) {
-			return false
-		}
-	}
-	return true
+func eqNum(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a == b },
+		func(c int) bool { return c == 0 })
 }
 
-func ne(nums ...float64) bool {
-	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] != nums[i+1]) {
-			return false
-		}
-	}
-	return true
+func ne(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a != b },
+		func(c int) bool { return c != 0 })
 }
 
-func gt(nums ...float64) bool {
-	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] > nums[i+1]) {
-			return false
-		}
-	}
-	return true
+func gt(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a > b },
+		func(c int) bool { return c > 0 })
 }
 
-func ge(nums ...float64) bool {
+func ge(nums ...number) bool {
+	return compareNums(nums,
+		func(a, b float64) bool { return a >= b },
+		func(c int) bool { return c >= 0 })
+}
+
+// Reports whether every adjacent pair of numbers satisfies a relationship. The
+// numbers are compared exactly with cmp if they are all exact, and as float64
+// values with f otherwise.
+func compareNums(nums []number, f func(a, b float64) bool, cmp func(int) bool) bool {
+	exact, _ := classifyNums(nums)
 	for i := 0; i < len(nums)-1; i++ {
-		if !(nums[i] >= nums[i+1]) {
+		var ok bool
+		if exact {
+			ok = cmp(vals.NumToBigRat(nums[i].v).Cmp(vals.NumToBigRat(nums[i+1].v)))
+		} else {
+			ok = f(vals.NumToFloat64(nums[i].v), vals.NumToFloat64(nums[i+1].v))
+		}
+		if !ok {
 			return false
 		}
 	}
@@ -203,34 +274,27 @@ func ge(nums ...float64) bool {
 // -   `/` becomes a synonym for `cd /`, due to the implicit cd feature. (The
 // implicit cd feature will probably change to avoid this oddity).
 
-func plus(nums ...float64) float64 {
-	sum := 0.0
-	for _, f := range nums {
-		sum += f
-	}
-	return sum
+func plus(nums ...number) interface{} {
+	return reduceNums(number{big.NewInt(0)}, nums,
+		func(a, b float64) float64 { return a + b }, (*big.Rat).Add)
 }
 
-func minus(sum float64, nums ...float64) float64 {
+func minus(first number, nums ...number) interface{} {
 	if len(nums) == 0 {
 		// Unary -
-		return -sum
+		return reduceNums(number{big.NewInt(0)}, []number{first},
+			func(a, b float64) float64 { return a - b }, (*big.Rat).Sub)
 	}
-	for _, f := range nums {
-		sum -= f
-	}
-	return sum
+	return reduceNums(first, nums,
+		func(a, b float64) float64 { return a - b }, (*big.Rat).Sub)
 }
 
-func times(nums ...float64) float64 {
-	prod := 1.0
-	for _, f := range nums {
-		prod *= f
-	}
-	return prod
+func times(nums ...number) interface{} {
+	return reduceNums(number{big.NewInt(1)}, nums,
+		func(a, b float64) float64 { return a * b }, (*big.Rat).Mul)
 }
 
-func slash(fm *Frame, args ...float64) error {
+func slash(fm *Frame, args ...number) error {
 	if len(args) == 0 {
 		// cd /
 		return fm.Chdir("/")
@@ -240,12 +304,46 @@ func slash(fm *Frame, args ...float64) error {
 	return nil
 }
 
-func divide(fm *Frame, prod float64, nums ...float64) {
-	out := fm.OutputChan()
-	for _, f := range nums {
-		prod /= f
+func divide(fm *Frame, prod number, nums ...number) {
+	quo := func(z, a, b *big.Rat) *big.Rat { return z.Quo(a, b) }
+	for _, n := range nums {
+		if r := vals.NumToBigRat(n.v); r != nil && r.Sign() == 0 {
+			// Division by exact zero; fall back to float64 division, which
+			// gives infinities or NaN.
+			quo = nil
+			break
+		}
+	}
+	fm.OutputChan() <- reduceNums(prod, nums,
+		func(a, b float64) float64 { return a / b }, quo)
+}
+
+// Applies an arithmetic operation to numbers from left to right, starting
+// with init. When init and all the numbers are exact and rop is not nil, the
+// result is exact; otherwise it is a float64.
+//
+// For compatibility with the float64 arithmetic used by earlier versions, an
+// exact result is normalized to a float64 when it is an integer that can be
+// represented exactly by a float64, or when it is a fraction but none of the
+// arguments is a fraction.
+func reduceNums(init number, nums []number, fop func(a, b float64) float64, rop func(z, a, b *big.Rat) *big.Rat) interface{} {
+	exact, anyRat := classifyNums(append([]number{init}, nums...))
+	if !exact || rop == nil {
+		acc := vals.NumToFloat64(init.v)
+		for _, n := range nums {
+			acc = fop(acc, vals.NumToFloat64(n.v))
+		}
+		return acc
 	}
-	out <- vals.FromGo(prod)
+	acc := new(big.Rat).Set(vals.NumToBigRat(init.v))
+	for _, n := range nums {
+		rop(acc, acc, vals.NumToBigRat(n.v))
+	}
+	if !acc.IsInt() && !anyRat {
+		f, _ := acc.Float64()
+		return f
+	}
+	return vals.NormalizeBigRat(acc)
 }
 
 //elvdoc:fn %
@@ -262,11 +360,31 @@ func divide(fm *Frame, prod float64, nums ...float64) {
 // ▶ 2
 // ```
 
-func mod(a, b int) (int, error) {
-	if b == 0 {
-		return 0, ErrArgs
+func mod(a, b number) (interface{}, error) {
+	x, xOK := toBigInt(a)
+	y, yOK := toBigInt(b)
+	if !xOK || !yOK || y.Sign() == 0 {
+		return nil, ErrArgs
+	}
+	if x.IsInt64() && y.IsInt64() {
+		// Output small integers as strings, like earlier versions.
+		return vals.FromGo(int(x.Int64() % y.Int64())), nil
+	}
+	return vals.NormalizeBigInt(new(big.Int).Rem(x, y)), nil
+}
+
+// Converts a number to a *big.Int if it is an integer.
+func toBigInt(n number) (*big.Int, bool) {
+	switch v := n.v.(type) {
+	case *big.Int:
+		return v, true
+	case float64:
+		if math.Trunc(v) == v && !math.IsInf(v, 0) {
+			i, _ := big.NewFloat(v).Int(nil)
+			return i, true
+		}
 	}
-	return a % b, nil
+	return nil, false
 }
 
 //elvdoc:fn randint
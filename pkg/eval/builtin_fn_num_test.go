@@ -2,6 +2,7 @@ package eval_test
 
 import (
 	"math"
+	"math/big"
 	"testing"
 
 	. "github.com/elves/elvish/pkg/eval"
@@ -38,6 +39,45 @@ func TestBuiltinFnNum(t *testing.T) {
 		That("% 23 7").Puts("2"),
 		That("% 1 0").Throws(AnyError),
 
+		// Exact arithmetic on big integers and rationals.
+		That("num 10").Puts(10.0),
+		That("num "+z).Puts(bigInt(z)),
+		That("num 2/4").Puts(big.NewRat(1, 2)),
+		That("num x").Throws(AnyError),
+		// Leading zeros don't make numbers octal.
+		That("+ 010 1").Puts(11.0),
+		That("num 010/4").Puts(big.NewRat(5, 2)),
+		That("+ 0o10 0x10 0b10").Puts(26.0),
+		That("+ "+z+" 1").Puts(bigInt(z1)),
+		That("- "+z1+" 1").Puts(bigInt(z)),
+		That("- "+z1+" "+z).Puts(1.0),
+		That("* 4294967296 4294967296").Puts(bigInt("18446744073709551616")),
+		That("+ 1/3 1/6").Puts(big.NewRat(1, 2)),
+		That("+ 1/2 1/2").Puts(1.0),
+		That("/ 1/2 3").Puts(big.NewRat(1, 6)),
+		// Without rational arguments, division outputs float64.
+		That("/ 1 4").Puts(0.25),
+		That("/ "+z+" 2").Puts(bigInt("50000000000000000000")),
+		That("/ 1/2 0").Puts(math.Inf(1)),
+		// Exact integer results are float64 values when they are small enough,
+		// and stay exact when used again.
+		That("x = (* 3000000001 1); * $x 3000000001").
+			Puts(bigInt("9000000006000000001")),
+		That("+ (float64 "+maxExact+") 1").Puts(bigInt("9007199254740993")),
+		// Larger float64 values are inexact, like other inexact arguments.
+		That("+ (float64 "+maxExact+"0) 1").Puts(9.007199254740992e16),
+		// Inexact arguments make the result inexact.
+		That("+ "+z+" 0.5").Puts(1e20),
+		That("== "+z+" "+z1).Puts(false),
+		That("< "+z+" "+z1).Puts(true),
+		That("< 1/3 0.34").Puts(true),
+		That("== 1/2 0.5").Puts(true),
+		That("% "+z1+" 7").Puts(3.0),
+		That("% "+z1+" "+z).Puts(1.0),
+		That("eq (num "+z+") (num "+z+")").Puts(true),
+		That("kind-of (num 1/3)").Puts("number"),
+		That("repr (num 1/3) (num "+z+")").Prints("(num 1/3) (num "+z+")\n"),
+
 		That("randint 1 2").Puts("1"),
 		That("i = (randint 10 100); >= $i 10; < $i 100").Puts(true, true),
 		That("randint 2 1").Throws(ErrArgs, "randint 2 1"),
@@ -46,3 +86,18 @@ func TestBuiltinFnNum(t *testing.T) {
 		That("randint 1 2 3").Throws(ErrorWithType(errs.ArityMismatch{}), "randint 1 2 3"),
 	)
 }
+
+const (
+	z  = "100000000000000000000"
+	z1 = "100000000000000000001"
+	// 2^53.
+	maxExact = "9007199254740992"
+)
+
+func bigInt(s string) *big.Int {
+	z, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		panic("cannot parse as big.Int: " + s)
+	}
+	return z
+}
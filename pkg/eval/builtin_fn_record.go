@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/xiaq/persistent/hash"
+)
+
+// Records.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"defrecord": defrecord,
+	})
+}
+
+//elvdoc:fn defrecord
+//
+// ```elvish
+// defrecord $name $field-name...
+// ```
+//
+// Defines a new record type with the given name and fields, and outputs its
+// constructor. The constructor is usually assigned to a function variable:
+//
+// ```elvish-transcript
+// ~> point~ = (defrecord point x y)
+// ```
+//
+// The constructor takes the values of the fields either all as arguments, in
+// the same order as the fields, or all as options:
+//
+// ```elvish-transcript
+// ~> point 1 2
+// ▶ (point &x=1 &y=2)
+// ~> point &y=2 &x=1
+// ▶ (point &x=1 &y=2)
+// ```
+//
+// A record behaves like a read-only map whose keys are the names of the
+// fields. Its kind is the name of its type, and `assoc` can be used to create
+// a copy of it with a field changed:
+//
+// ```elvish-transcript
+// ~> p = (point 1 2)
+// ~> put $p[x] (kind-of $p) (keys $p)
+// ▶ 1
+// ▶ point
+// ▶ x
+// ▶ y
+// ~> assoc $p y 3
+// ▶ (point &x=1 &y=3)
+// ```
+//
+// Iterating over a record gives the values of its fields, so a record can be
+// matched against variables by destructuring:
+//
+// ```elvish-transcript
+// ~> x y = (all $p)
+// ~> put $x $y
+// ▶ 1
+// ▶ 2
+// ```
+//
+// Each call to `defrecord` defines a distinct type; records of different types
+// are never equal, even if the types have the same name and fields. The
+// constructor has a `name` field and a `fields` field, containing the name and
+// the names of the fields of the type.
+
+func defrecord(name string, fields ...string) (Callable, error) {
+	t, err := vals.NewRecordType(name, fields...)
+	if err != nil {
+		return nil, err
+	}
+	return recordCtor{t}, nil
+}
+
+// The constructor of a record type.
+type recordCtor struct{ t *vals.RecordType }
+
+var errMixedRecordFields = errors.New(
+	"values of record fields must be either all arguments or all options")
+
+// Kind returns "fn".
+func (recordCtor) Kind() string { return "fn" }
+
+// Equal compares the record types.
+func (c recordCtor) Equal(other interface{}) bool {
+	c2, ok := other.(recordCtor)
+	return ok && c.t == c2.t
+}
+
+// Hash hashes the name of the record type.
+func (c recordCtor) Hash() uint32 { return hash.String(c.t.Name()) }
+
+// Repr returns the representation of the record type.
+func (c recordCtor) Repr(indent int) string { return c.t.Repr(indent) }
+
+// Call creates a record.
+func (c recordCtor) Call(fm *Frame, args []interface{}, opts map[string]interface{}) error {
+	fields := c.t.FieldNames()
+	values := args
+	switch {
+	case len(opts) == 0:
+		if len(args) != len(fields) {
+			return errs.ArityMismatch{What: "arguments here",
+				ValidLow: len(fields), ValidHigh: len(fields), Actual: len(args)}
+		}
+	case len(args) == 0:
+		for name := range opts {
+			if !c.t.HasField(name) {
+				return fmt.Errorf("unknown option %s", parse.Quote(name))
+			}
+		}
+		values = make([]interface{}, len(fields))
+		for i, field := range fields {
+			v, ok := opts[field]
+			if !ok {
+				return fmt.Errorf("missing option %s", parse.Quote(field))
+			}
+			values[i] = v
+		}
+	default:
+		return errMixedRecordFields
+	}
+	r, err := c.t.New(values...)
+	if err != nil {
+		return err
+	}
+	fm.OutputChan() <- r
+	return nil
+}
+
+// Fields returns the name and fields of the record type.
+func (c recordCtor) Fields() vals.StructMap { return recordCtorFields{c.t} }
+
+type recordCtorFields struct{ t *vals.RecordType }
+
+func (recordCtorFields) IsStructMap() {}
+
+func (f recordCtorFields) Name() string { return f.t.Name() }
+
+func (f recordCtorFields) Fields() vals.List {
+	list := vals.EmptyList
+	for _, field := range f.t.FieldNames() {
+		list = list.Cons(field)
+	}
+	return list
+}
@@ -0,0 +1,46 @@
+package eval_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestDefrecord(t *testing.T) {
+	Test(t,
+		That("point~ = (defrecord point x y); repr (point 1 2) (point &y=2 &x=1)").
+			Prints("(point &x=1 &y=2) (point &x=1 &y=2)\n"),
+		That("point~ = (defrecord point x y); p = (point 1 2); put $p[y] (kind-of $p) (keys $p)").
+			Puts("2", "point", "x", "y"),
+		That("point~ = (defrecord point x y); repr (assoc (point 1 2) y 3)").
+			Prints("(point &x=1 &y=3)\n"),
+		That("point~ = (defrecord point x y); x y = (all (point 1 2)); put $x $y").
+			Puts("1", "2"),
+		That("point~ = (defrecord point x y); put $point~[name] $point~[fields]").
+			Puts("point", vals.MakeList("x", "y")),
+		That("point~ = (defrecord point x y); repr $point~").
+			Prints("<record-type point [x y]>\n"),
+		That("unit~ = (defrecord unit); eq (unit) (unit)").Puts(true),
+
+		// Equality is nominal.
+		That("point~ = (defrecord point x y); eq (point 1 2) (point 1 2)").Puts(true),
+		That("a~ = (defrecord point x y); b~ = (defrecord point x y); eq (a 1 2) (b 1 2)").
+			Puts(false),
+
+		// Errors.
+		That("point~ = (defrecord point x y); point 1").Throws(
+			errs.ArityMismatch{What: "arguments here", ValidLow: 2, ValidHigh: 2, Actual: 1},
+			"point 1"),
+		That("point~ = (defrecord point x y); point &x=1").Throws(
+			errors.New("missing option y"), "point &x=1"),
+		That("point~ = (defrecord point x y); point &x=1 &y=2 &z=3").Throws(
+			errors.New("unknown option z"), "point &x=1 &y=2 &z=3"),
+		That("point~ = (defrecord point x y); point 1 &y=2").Throws(AnyError),
+		That("point~ = (defrecord point x y); assoc (point 1 2) z 3").Throws(AnyError),
+		That("defrecord ''").Throws(AnyError),
+		That("defrecord point x x").Throws(AnyError),
+	)
+}
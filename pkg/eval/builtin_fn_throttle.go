@@ -0,0 +1,280 @@
+package eval
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/xiaq/persistent/hash"
+)
+
+// Rate limiting.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"throttle": throttle,
+		"debounce": debounce,
+	})
+}
+
+//elvdoc:fn throttle
+//
+// ```elvish
+// throttle $interval $f
+// ```
+//
+// Outputs a function that calls `$f` at most once every `$interval`. The
+// interval uses the same format as the argument of [`sleep`](#sleep).
+//
+// When the function is called within `$interval` after the last time `$f` was
+// called, it doesn't call `$f`, but outputs the same values and bytes, and
+// throws the same exception, as that call. Arguments and options are passed
+// to `$f` when it is called, and ignored otherwise. A call of `$f` that throws
+// an exception doesn't count; the next call of the function calls `$f` again.
+//
+// This is useful for prompts and hooks that run slow commands, like ones that
+// access the network:
+//
+// ```elvish
+// weather = (throttle 10m { curl -s 'wttr.in?format=%t' })
+// edit:rprompt = { $weather }
+// ```
+//
+// @cf debounce
+
+//elvdoc:fn debounce
+//
+// ```elvish
+// debounce $interval $f
+// ```
+//
+// Outputs a function that delays calling `$f` until it hasn't been called for
+// `$interval`. The interval uses the same format as the argument of
+// [`sleep`](#sleep).
+//
+// When the function is called, it waits until `$interval` has passed without
+// another call, and then calls `$f` with the arguments and options of the last
+// call. All calls during the wait return after `$f` has been called, with the
+// same outputs and exception as that call. If a call is interrupted during the
+// wait, only that call fails, and the other calls keep waiting.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> f~ = (debounce 0.1 [x]{ echo called with $x })
+// ~> run-parallel { f 1 } { sleep 0.05; f 2 }
+// called with 2
+// called with 2
+// ```
+//
+// @cf throttle
+
+func throttle(interval interface{}, f Callable) (Callable, error) {
+	d, err := parseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	return &throttled{f: f, interval: d}, nil
+}
+
+func debounce(interval interface{}, f Callable) (Callable, error) {
+	d, err := parseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	return &debounced{f: f, interval: d}, nil
+}
+
+func parseInterval(v interface{}) (time.Duration, error) {
+	d, err := vals.ScanDuration(v)
+	if err != nil || d < 0 {
+		return 0, errs.BadValue{What: "interval",
+			Valid: "non-negative duration", Actual: vals.Repr(v, vals.NoPretty)}
+	}
+	return d, nil
+}
+
+// A call whose outputs are shared by several calls of a throttled or debounced
+// function.
+type sharedCall struct {
+	done   chan struct{}
+	values []interface{}
+	bytes  []byte
+	err    error
+	// Whether the call was abandoned because the caller making it was
+	// interrupted. The outputs of an abandoned call are not shared.
+	abandoned bool
+}
+
+func newSharedCall() *sharedCall { return &sharedCall{done: make(chan struct{})} }
+
+// Calls f and saves its outputs, and closes c.done. If fm is interrupted, the
+// call is marked as abandoned.
+func (c *sharedCall) run(fm *Frame, f Callable, args []interface{}, opts map[string]interface{}) {
+	defer close(c.done)
+	var buf bytes.Buffer
+	c.err = fm.PipeOutput(
+		func(fm *Frame) error { return f.Call(fm, args, opts) },
+		func(ch <-chan interface{}) {
+			for v := range ch {
+				c.values = append(c.values, v)
+			}
+		},
+		func(r *os.File) { io.Copy(&buf, r) })
+	c.bytes = buf.Bytes()
+	c.abandoned = fm.IsInterrupted()
+}
+
+// Marks the call as abandoned without making it, and closes c.done.
+func (c *sharedCall) abandon() {
+	c.abandoned = true
+	close(c.done)
+}
+
+// Waits for the call made by another caller to finish. If the call was
+// abandoned, it returns false, and the caller should retry. Otherwise it
+// writes the outputs of the call to the output of fm, and returns true along
+// with the error of the call.
+func (c *sharedCall) wait(fm *Frame) (bool, error) {
+	select {
+	case <-c.done:
+	case <-fm.Interrupts():
+		return true, ErrInterrupted
+	}
+	if c.abandoned {
+		return false, nil
+	}
+	return true, c.output(fm)
+}
+
+// Writes the outputs of the call to the output of fm, and returns its error.
+func (c *sharedCall) output(fm *Frame) error {
+	out := fm.OutputChan()
+	for _, v := range c.values {
+		out <- v
+	}
+	if len(c.bytes) > 0 {
+		fm.OutputFile().Write(c.bytes)
+	}
+	return c.err
+}
+
+type throttled struct {
+	f        Callable
+	interval time.Duration
+
+	mutex sync.Mutex
+	// The last successful or ongoing call, and when it started.
+	last      *sharedCall
+	lastStart time.Time
+}
+
+func (t *throttled) Call(fm *Frame, args []interface{}, opts map[string]interface{}) error {
+	for {
+		t.mutex.Lock()
+		now := time.Now()
+		if t.last != nil && now.Sub(t.lastStart) < t.interval {
+			c := t.last
+			t.mutex.Unlock()
+			if ok, err := c.wait(fm); ok {
+				return err
+			}
+			continue
+		}
+		c := newSharedCall()
+		t.last, t.lastStart = c, now
+		t.mutex.Unlock()
+
+		c.run(fm, t.f, args, opts)
+		if c.err != nil || c.abandoned {
+			// Don't reuse the outcome of a failed or abandoned call; the next
+			// call will call the function again.
+			t.mutex.Lock()
+			if t.last == c {
+				t.last = nil
+			}
+			t.mutex.Unlock()
+		}
+		return c.output(fm)
+	}
+}
+
+func (*throttled) Kind() string                 { return "fn" }
+func (t *throttled) Equal(rhs interface{}) bool { return t == rhs }
+func (t *throttled) Hash() uint32               { return hash.Pointer(unsafe.Pointer(t)) }
+
+func (t *throttled) Repr(indent int) string {
+	return "<throttled " + vals.Repr(t.f, indent) + ">"
+}
+
+type debounced struct {
+	f        Callable
+	interval time.Duration
+
+	mutex sync.Mutex
+	// The pending call and when it should happen, along with its arguments
+	// and options, which are updated by each call during the wait.
+	pending     *sharedCall
+	pendingTime time.Time
+	args        []interface{}
+	opts        map[string]interface{}
+}
+
+func (d *debounced) Call(fm *Frame, args []interface{}, opts map[string]interface{}) error {
+	d.mutex.Lock()
+	d.pendingTime = time.Now().Add(d.interval)
+	d.args, d.opts = args, opts
+	for d.pending != nil {
+		c := d.pending
+		d.mutex.Unlock()
+		if ok, err := c.wait(fm); ok {
+			return err
+		}
+		// The caller making the call was interrupted; take over from it,
+		// keeping the arguments and options of the last call.
+		d.mutex.Lock()
+	}
+	c := newSharedCall()
+	d.pending = c
+	d.mutex.Unlock()
+
+	// This is the first call during the wait, or the first call to take
+	// over; wait until there have been no calls for the interval, and make
+	// the actual call.
+	for {
+		d.mutex.Lock()
+		remaining := time.Until(d.pendingTime)
+		if remaining <= 0 {
+			args, opts := d.args, d.opts
+			d.pending, d.args, d.opts = nil, nil, nil
+			d.mutex.Unlock()
+			c.run(fm, d.f, args, opts)
+			return c.output(fm)
+		}
+		d.mutex.Unlock()
+		select {
+		case <-time.After(remaining):
+		case <-fm.Interrupts():
+			// Only this caller fails; other callers waiting for the call
+			// will take over.
+			d.mutex.Lock()
+			d.pending = nil
+			d.mutex.Unlock()
+			c.abandon()
+			return ErrInterrupted
+		}
+	}
+}
+
+func (*debounced) Kind() string                 { return "fn" }
+func (d *debounced) Equal(rhs interface{}) bool { return d == rhs }
+func (d *debounced) Hash() uint32               { return hash.Pointer(unsafe.Pointer(d)) }
+
+func (d *debounced) Repr(indent int) string {
+	return "<debounced " + vals.Repr(d.f, indent) + ">"
+}
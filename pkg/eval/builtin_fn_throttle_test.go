@@ -0,0 +1,125 @@
+package eval_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/parse"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestThrottle(t *testing.T) {
+	Test(t,
+		That(`n = 0; f~ = (throttle 1h { n = (+ $n 1); put $n; echo $n })`,
+			`f; f; put $n`).
+			Puts(1.0, 1.0, 1.0).Prints("1\n1\n"),
+		That(`n = 0; f~ = (throttle 0 { n = (+ $n 1) })`, `f; f; put $n`).
+			Puts(2.0),
+		// Calls after the interval call the function again.
+		That(`n = 0; f~ = (throttle 0.02 { n = (+ $n 1) })`,
+			`f; f; sleep 0.05; f; put $n`).Puts(2.0),
+		// Exceptions are shared too.
+		That(`f~ = (throttle 1h { fail bad })`, `f; f`).Throws(FailError{"bad"}),
+		// Failed calls don't count.
+		That(`n = 0; f~ = (throttle 1h { n = (+ $n 1); fail bad })`,
+			`try { f } except { }; try { f } except { }; put $n`).Puts(2.0),
+		That(`n = 0; f~ = (throttle 1h { n = (+ $n 1); if (== $n 1) { fail bad } })`,
+			`try { f } except { }; f; f; put $n`).Puts(2.0),
+		// Arguments are passed.
+		That(`f~ = (throttle 1h [x]{ put $x })`, `f a; f b`).Puts("a", "a"),
+		That(`throttle -1 { }`).Throws(errs.BadValue{
+			What: "interval", Valid: "non-negative duration", Actual: "-1"}),
+		That(`throttle bad { }`).Throws(errs.BadValue{
+			What: "interval", Valid: "non-negative duration", Actual: "bad"}),
+		That(`kind-of (throttle 1 { })`).Puts("fn"),
+	)
+}
+
+func TestDebounce(t *testing.T) {
+	Test(t,
+		That(`n = 0; f~ = (debounce 0 [x]{ n = (+ $n 1); put $x })`, `f a; f b; put $n`).
+			Puts("a", "b", 2.0),
+		// Calls during the wait are coalesced, and the arguments of the last
+		// call are used.
+		That(`n = 0; f~ = (debounce 0.05 [x]{ n = (+ $n 1); echo $x })`,
+			`run-parallel { f 1 } { sleep 0.01; f 2 }`, `put $n`).
+			Puts(1.0).Prints("2\n2\n"),
+		That(`f~ = (debounce 0 { fail bad })`, `f`).Throws(FailError{"bad"}),
+		That(`debounce -1 { }`).Throws(AnyError),
+		That(`kind-of (debounce 1 { })`).Puts("fn"),
+	)
+}
+
+func TestThrottle_InterruptOnlyFailsInterruptedCall(t *testing.T) {
+	ev, log := setupRateLimitTest(t, `f~ = (throttle 1h { sleep 0.1; log called })`)
+	testInterruptOnlyFailsInterruptedCall(t, ev, "f", "f")
+	if want := []interface{}{"called"}; !reflect.DeepEqual(log(), want) {
+		t.Errorf("got log %v, want %v", log(), want)
+	}
+}
+
+func TestDebounce_InterruptOnlyFailsInterruptedCall(t *testing.T) {
+	ev, log := setupRateLimitTest(t, `f~ = (debounce 0.1 [x]{ log $x })`)
+	testInterruptOnlyFailsInterruptedCall(t, ev, "f a", "f b")
+	if want := []interface{}{"b"}; !reflect.DeepEqual(log(), want) {
+		t.Errorf("got log %v, want %v", log(), want)
+	}
+}
+
+// Returns an Evaler with a log function, and a function to get the logged
+// values, after evaluating code.
+func setupRateLimitTest(t *testing.T, code string) (*Evaler, func() []interface{}) {
+	t.Helper()
+	var mutex sync.Mutex
+	var log []interface{}
+	ev := NewEvaler()
+	ev.Global.Append(NsBuilder{}.AddGoFn("", "log", func(v interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		log = append(log, v)
+	}).Ns())
+	err := ev.Eval(parse.Source{Name: "[test]", Code: code}, EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev, func() []interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return log
+	}
+}
+
+// Evaluates code1 and then code2 in parallel, and interrupts code1 while both
+// are waiting. Only code1 should fail.
+func testInterruptOnlyFailsInterruptedCall(t *testing.T, ev *Evaler, code1, code2 string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eval := func(code string, ctx context.Context) <-chan error {
+		ch := make(chan error, 1)
+		go func() {
+			ch <- ev.Eval(parse.Source{Name: "[test]", Code: code},
+				EvalCfg{Context: ctx})
+		}()
+		return ch
+	}
+
+	err1 := eval(code1, ctx)
+	time.Sleep(20 * time.Millisecond)
+	err2 := eval(code2, context.Background())
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-err1; Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v from interrupted call, want %v", err, ErrInterrupted)
+	}
+	if err := <-err2; err != nil {
+		t.Errorf("got error %v from other call, want nil", err)
+	}
+}
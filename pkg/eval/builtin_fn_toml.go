@@ -0,0 +1,195 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// TOML.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"from-toml": fromTOML,
+		"to-toml":   toTOML,
+	})
+}
+
+//elvdoc:fn from-toml
+//
+// ```elvish
+// from-toml
+// ```
+//
+// Takes bytes stdin, parses it as a TOML document and puts the result, a map,
+// on structured stdout.
+//
+// Tables become maps and arrays become lists. Integers and floating-point
+// numbers become numbers, and dates and times become strings in the RFC 3339
+// format.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> echo 'title = "example"
+// [owner]
+// name = "elf"' | from-toml
+// ▶ [&owner=[&name=elf] &title=example]
+// ```
+//
+// @cf to-toml from-json from-yaml
+
+func fromTOML(fm *Frame) error {
+	var m map[string]interface{}
+	if _, err := toml.DecodeReader(fm.InputFile(), &m); err != nil {
+		return err
+	}
+	v, err := fromTOMLValue(m)
+	if err != nil {
+		return err
+	}
+	fm.OutputChan() <- v
+	return nil
+}
+
+// Converts a value decoded by the toml package to an Elvish value.
+func fromTOMLValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool, string, float64:
+		return v, nil
+	case int64:
+		return vals.NormalizeBigInt(big.NewInt(v)), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []interface{}:
+		vec := vals.EmptyList
+		for _, item := range v {
+			converted, err := fromTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			vec = vec.Cons(converted)
+		}
+		return vec, nil
+	case []map[string]interface{}:
+		vec := vals.EmptyList
+		for _, item := range v {
+			converted, err := fromTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			vec = vec.Cons(converted)
+		}
+		return vec, nil
+	case map[string]interface{}:
+		m := vals.EmptyMap
+		for key, value := range v {
+			converted, err := fromTOMLValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(key, converted)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unexpected toml type: %T", v)
+	}
+}
+
+//elvdoc:fn to-toml
+//
+// ```elvish
+// to-toml $input?
+// ```
+//
+// Takes maps on structured stdin, converts each of them to a TOML document and
+// writes the result on bytes stdout.
+//
+// Map keys must be strings or numbers. Numbers that are integers are written
+// as TOML integers, and must fit in 64 bits. Since TOML has no null value,
+// `$nil` can't be converted.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> to-toml [[&title=example &owner=[&name=elf]]]
+// title = "example"
+//
+// [owner]
+//   name = "elf"
+// ```
+//
+// @cf from-toml to-json to-yaml
+
+func toTOML(fm *Frame, inputs Inputs) error {
+	enc := toml.NewEncoder(fm.OutputFile())
+	var errEncode error
+	inputs(func(v interface{}) {
+		if errEncode != nil {
+			return
+		}
+		switch v.(type) {
+		case vals.Map, vals.OrderedMap:
+		default:
+			errEncode = fmt.Errorf("input to to-toml must be map, got %s", vals.Kind(v))
+			return
+		}
+		var converted interface{}
+		converted, errEncode = toTOMLValue(v)
+		if errEncode != nil {
+			return
+		}
+		errEncode = enc.Encode(converted)
+	})
+	return errEncode
+}
+
+// Converts an Elvish value to a value accepted by the toml package.
+func toTOMLValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool, string:
+		return v, nil
+	case float64:
+		return exportFloat64(v), nil
+	case *big.Int:
+		if !v.IsInt64() {
+			return nil, fmt.Errorf("integer %s is too large for TOML", v)
+		}
+		return v.Int64(), nil
+	case *big.Rat:
+		return vals.NumToFloat64(v), nil
+	case vals.List:
+		seq := make([]interface{}, 0, v.Len())
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			item, err := toTOMLValue(it.Elem())
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, item)
+		}
+		return seq, nil
+	case vals.Map, vals.OrderedMap, vals.StructMap, vals.PseudoStructMap:
+		m := map[string]interface{}{}
+		var errConvert error
+		vals.IterateKeys(v, func(k interface{}) bool {
+			switch k.(type) {
+			case string, float64, *big.Int, *big.Rat:
+			default:
+				errConvert = fmt.Errorf("map key must be string or number, got %s", vals.Kind(k))
+				return false
+			}
+			var value interface{}
+			value, errConvert = vals.Index(v, k)
+			if errConvert == nil {
+				m[vals.ToString(k)], errConvert = toTOMLValue(value)
+			}
+			return errConvert == nil
+		})
+		return m, errConvert
+	default:
+		return nil, fmt.Errorf("cannot convert %s to TOML", vals.Kind(v))
+	}
+}
@@ -0,0 +1,35 @@
+package eval_test
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestFromTOML(t *testing.T) {
+	Test(t,
+		That("print 'title = \"example\"\nn = 3\nd = 1979-05-27T07:32:00Z\n[owner]\nname = \"elf\"\n[[items]]\na = 1.5\n' | from-toml").Puts(
+			vals.MakeMap("title", "example", "n", 3.0, "d", "1979-05-27T07:32:00Z",
+				"owner", vals.MakeMap("name", "elf"),
+				"items", vals.MakeList(vals.MakeMap("a", 1.5)))),
+		That("print 'a = [1, 2]' | from-toml").Puts(
+			vals.MakeMap("a", vals.MakeList(1.0, 2.0))),
+		That("print 'a = ' | from-toml").Throws(AnyError),
+	)
+}
+
+func TestToTOML(t *testing.T) {
+	Test(t,
+		That(`to-toml [[&title=example &n=(float64 3) &f=(float64 1.5) &owner=[&name=elf]]]`).Prints(
+			"f = 1.5\nn = 3\ntitle = \"example\"\n\n[owner]\n  name = \"elf\"\n"),
+		That(`to-toml [[&a=[x y]]]`).Prints("a = [\"x\", \"y\"]\n"),
+		That(`to-toml [foo]`).Throws(AnyError),
+		That(`to-toml [[&a=$nil]]`).Throws(AnyError),
+		That(`to-toml [[&a=(num 12345678901234567890)]]`).Throws(AnyError),
+		That(`to-toml [[&[]=a]]`).Throws(AnyError),
+		// Round trip
+		That(`to-toml [[&a=[&b=(num 2)]]] | from-toml`).Puts(
+			vals.MakeMap("a", vals.MakeMap("b", 2.0))),
+	)
+}
@@ -0,0 +1,354 @@
+package eval
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+	"gopkg.in/yaml.v3"
+)
+
+// YAML.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"from-yaml": fromYAML,
+		"to-yaml":   toYAML,
+	})
+}
+
+//elvdoc:fn from-yaml
+//
+// ```elvish
+// from-yaml
+// ```
+//
+// Takes bytes stdin, parses it as a stream of YAML documents and puts the
+// result on structured stdout. Each document is output as soon as it is
+// parsed.
+//
+// Mappings become [ordered maps](#make-ordered-map) that keep the order of
+// the keys, sequences become lists, `null` becomes `$nil`, and `true` and
+// `false` become booleans. Integers and floating-point numbers become numbers,
+// and other scalars, including ones with tags that are not part of YAML 1.2
+// like `!!timestamp`, become strings. Aliases are replaced with the nodes they
+// refer to, and merge keys (`<<`) are supported.
+//
+// Examples:
+//
+// ```elvish-transcript
+// ~> echo 'name: elvish
+// tags: [shell, language]' | from-yaml
+// ▶ (make-ordered-map [[name elvish] [tags [shell language]]])
+// ~> echo "a\n---\n[1, 2.5, true]" | from-yaml
+// ▶ a
+// ▶ [(float64 1) (float64 2.5) $true]
+// ```
+//
+// @cf to-yaml from-json from-toml
+
+func fromYAML(fm *Frame) error {
+	dec := yaml.NewDecoder(fm.InputFile())
+	out := fm.OutputChan()
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		v, err := (&yamlConverter{}).convert(&doc)
+		if err != nil {
+			return err
+		}
+		out <- v
+	}
+}
+
+var errYAMLRecursiveAlias = errors.New("YAML alias refers to a node containing it")
+
+// Converts YAML nodes to Elvish values.
+type yamlConverter struct {
+	// Values of the anchored nodes that have been converted, and nodes being
+	// converted. Since values are immutable, nodes referred to by many aliases
+	// only need to be converted once, which also keeps documents like the
+	// "billion laughs" from taking exponential time and memory.
+	anchored   map[*yaml.Node]interface{}
+	converting map[*yaml.Node]bool
+}
+
+func (c *yamlConverter) convert(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.convert(node.Content[0])
+	case yaml.AliasNode:
+		target := node.Alias
+		if v, ok := c.anchored[target]; ok {
+			return v, nil
+		}
+		if c.converting[target] {
+			return nil, errYAMLRecursiveAlias
+		}
+		if c.converting == nil {
+			c.converting = make(map[*yaml.Node]bool)
+		}
+		c.converting[target] = true
+		v, err := c.convert(target)
+		delete(c.converting, target)
+		if err != nil {
+			return nil, err
+		}
+		if c.anchored == nil {
+			c.anchored = make(map[*yaml.Node]interface{})
+		}
+		c.anchored[target] = v
+		return v, nil
+	case yaml.SequenceNode:
+		vec := vals.EmptyList
+		for _, item := range node.Content {
+			v, err := c.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			vec = vec.Cons(v)
+		}
+		return vec, nil
+	case yaml.MappingNode:
+		m := vals.EmptyOrderedMap
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				err := c.merge(&m, value)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			k, err := c.convert(key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := c.convert(value)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Put(k, v)
+		}
+		return m, nil
+	default:
+		return convertYAMLScalar(node)
+	}
+}
+
+// Adds the pairs of the mappings referred to by a merge key ("<<") to m,
+// except for the keys m already has. The value of the merge key is either a
+// mapping or a sequence of mappings.
+func (c *yamlConverter) merge(m *vals.OrderedMap, node *yaml.Node) error {
+	v, err := c.convert(node)
+	if err != nil {
+		return err
+	}
+	sources := []interface{}{v}
+	if list, ok := v.(vals.List); ok {
+		sources = sources[:0]
+		for it := list.Iterator(); it.HasElem(); it.Next() {
+			sources = append(sources, it.Elem())
+		}
+	}
+	for _, source := range sources {
+		sourceMap, ok := source.(vals.OrderedMap)
+		if !ok {
+			return fmt.Errorf("line %d: YAML merge key must refer to mappings", node.Line)
+		}
+		sourceMap.IteratePairs(func(k, v interface{}) bool {
+			if !m.HasKey(k) {
+				*m = m.Put(k, v)
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// Matches integers written in decimal, which are resolved as floating-point
+// numbers by the yaml package when they don't fit in 64 bits.
+var yamlDecimalInt = regexp.MustCompile(`^[-+]?[0-9]+$`)
+
+func convertYAMLScalar(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!int":
+		n, ok := new(big.Int).SetString(node.Value, 0)
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid YAML integer %s", node.Line, node.Value)
+		}
+		return vals.NormalizeBigInt(n), nil
+	case "!!float":
+		if yamlDecimalInt.MatchString(node.Value) {
+			n, _ := new(big.Int).SetString(node.Value, 10)
+			return vals.NormalizeBigInt(n), nil
+		}
+		var f float64
+		err := node.Decode(&f)
+		return f, err
+	default:
+		// Strings, as well as scalars with other tags, like timestamps.
+		return node.Value, nil
+	}
+}
+
+//elvdoc:fn to-yaml
+//
+// ```elvish
+// to-yaml $input?
+// ```
+//
+// Takes structured stdin, converts each value to a YAML document and writes
+// the result on bytes stdout. Documents are separated by `---` lines.
+//
+// Maps and lists are written in block style. The keys of maps are sorted,
+// except for [ordered maps](#make-ordered-map), which keep their order. Numbers that are integers are written as YAML integers. Multi-line strings
+// are written as literal block scalars when possible, and strings that would
+// otherwise be read back as other types, like `true` or `10`, are quoted.
+//
+// Examples:
+//
+// ```elvish-transcript
+// ~> to-yaml [[&name=elvish &tags=[shell language] &version=(num 15)]]
+// name: elvish
+// tags:
+//   - shell
+//   - language
+// version: 15
+// ~> put a b | to-yaml
+// a
+// ---
+// b
+// ```
+//
+// @cf from-yaml to-json to-toml
+
+func toYAML(fm *Frame, inputs Inputs) error {
+	out := fm.OutputFile()
+	first := true
+	var errEncode error
+	inputs(func(v interface{}) {
+		if errEncode != nil {
+			return
+		}
+		var node *yaml.Node
+		node, errEncode = toYAMLNode(v)
+		if errEncode != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if first {
+			first = false
+		} else {
+			buf.WriteString("---\n")
+		}
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if errEncode = enc.Encode(node); errEncode != nil {
+			return
+		}
+		if errEncode = enc.Close(); errEncode != nil {
+			return
+		}
+		_, errEncode = out.Write(buf.Bytes())
+	})
+	return errEncode
+}
+
+// Converts an Elvish value to a YAML node.
+func toYAMLNode(v interface{}) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case *big.Int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}, nil
+	case bool, string, float64, *big.Rat:
+		var value interface{} = v
+		switch v := v.(type) {
+		case float64:
+			value = exportFloat64(v)
+		case *big.Rat:
+			value = vals.NumToFloat64(v)
+		}
+		node := &yaml.Node{}
+		err := node.Encode(value)
+		return node, err
+	case vals.List:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			item, err := toYAMLNode(it.Elem())
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+	case vals.Map, vals.OrderedMap, vals.StructMap, vals.PseudoStructMap:
+		var keys []interface{}
+		vals.IterateKeys(v, func(k interface{}) bool {
+			keys = append(keys, k)
+			return true
+		})
+		if _, ordered := v.(vals.OrderedMap); !ordered {
+			sort.Slice(keys, func(i, j int) bool { return keyText(keys[i]) < keyText(keys[j]) })
+		}
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, k := range keys {
+			value, err := vals.Index(v, k)
+			if err != nil {
+				return nil, err
+			}
+			keyNode, err := toYAMLNode(k)
+			if err != nil {
+				return nil, err
+			}
+			valueNode, err := toYAMLNode(value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s to YAML", vals.Kind(v))
+	}
+}
+
+// Returns the text used to sort a key of a map.
+func keyText(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return vals.Repr(k, vals.NoPretty)
+}
+
+// Converts a float64 to an int64 if it is an integer that can be represented
+// exactly, so that it is written as an integer in formats that distinguish
+// integers from floating-point numbers.
+func exportFloat64(f float64) interface{} {
+	if f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+		return int64(f)
+	}
+	return f
+}
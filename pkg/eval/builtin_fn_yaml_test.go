@@ -0,0 +1,49 @@
+package eval_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestFromYAML(t *testing.T) {
+	Test(t,
+		That("print 'name: elvish\ntags: [shell, language]\n' | from-yaml").Puts(
+			vals.MakeOrderedMap("name", "elvish", "tags", vals.MakeList("shell", "language"))),
+		// The order of keys is kept.
+		That("print 'b: 1\na: 2\n' | from-yaml | keys (one)").Puts("b", "a"),
+		That("print \"a\n---\n[1, 2.5, true, null]\n\" | from-yaml").Puts(
+			"a", vals.MakeList(1.0, 2.5, true, nil)),
+		That("print '12345678901234567890' | from-yaml").Puts(
+			bigInt("12345678901234567890")),
+		That("print '[0x10, 0o10, -7, .inf, ''10'', !!str 10, ~]' | from-yaml").Puts(
+			vals.MakeList(16.0, 8.0, -7.0, math.Inf(1), "10", "10", nil)),
+		// Anchors, aliases and merge keys.
+		That("print 'a: &x [1]\nb: *x\n' | from-yaml").Puts(
+			vals.MakeOrderedMap("a", vals.MakeList(1.0), "b", vals.MakeList(1.0))),
+		That("print 'a: &x {k: 1, l: 2}\nb: {<<: *x, l: 3}\n' | from-yaml | put (one)[b]").Puts(
+			vals.MakeOrderedMap("k", 1.0, "l", 3.0)),
+		That("print 'a: &x [*x]' | from-yaml").Throws(AnyError),
+		That("print '' | from-yaml").DoesNothing(),
+		That("print \"a\n---\n[b\n\" | from-yaml").Puts("a").Throws(AnyError),
+	)
+}
+
+func TestToYAML(t *testing.T) {
+	Test(t,
+		That(`to-yaml [[&name=elvish &tags=[shell language] &version=(num 15)]]`).Prints(
+			"name: elvish\ntags:\n  - shell\n  - language\nversion: 15\n"),
+		That(`put a b | to-yaml`).Prints("a\n---\nb\n"),
+		That(`to-yaml [[$nil $true (float64 1.5) (num 1/4) "10" "a\nb\n" [] [&]]]`).Prints(
+			"- null\n- true\n- 1.5\n- 0.25\n- \"10\"\n- |\n  a\n  b\n- []\n- {}\n"),
+		That(`to-yaml [(num 12345678901234567890)]`).Prints(
+			"12345678901234567890\n"),
+		That(`to-yaml [(ns [&])]`).Throws(AnyError),
+		// Round trip
+		That(`to-yaml [[&a=[x [&b=c]] &(float64 1)=y]] | from-yaml`).Puts(
+			vals.MakeOrderedMap(1.0, "y", "a", vals.MakeList("x", vals.MakeOrderedMap("b", "c")))),
+		That(`to-yaml [(make-ordered-map [[b (num 1)] [a (num 2)]])]`).Prints("b: 1\na: 2\n"),
+	)
+}
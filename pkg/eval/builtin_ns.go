@@ -47,7 +47,7 @@ import (
 //elvdoc:var paths
 //
 // A list of search paths, kept in sync with `$E:PATH`. It is easier to use than
-// `$E:PATH`.
+// `$E:PATH`, and is the same as `$E:list:PATH`.
 
 //elvdoc:var pid
 //
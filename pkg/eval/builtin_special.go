@@ -17,11 +17,13 @@ package eval
 // closures functioning as code blocks.
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/env"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
 	"github.com/elves/elvish/pkg/parse"
@@ -42,15 +44,17 @@ func (err noSuchModule) Error() string { return "no such module: " + err.spec }
 func init() {
 	// Needed to avoid initialization loop
 	builtinSpecials = map[string]compileBuiltin{
-		"del":   compileDel,
-		"fn":    compileFn,
-		"use":   compileUse,
-		"and":   compileAnd,
-		"or":    compileOr,
-		"if":    compileIf,
-		"while": compileWhile,
-		"for":   compileFor,
-		"try":   compileTry,
+		"del":    compileDel,
+		"fn":     compileFn,
+		"const":  compileConst,
+		"use":    compileUse,
+		"pragma": compilePragma,
+		"and":    compileAnd,
+		"or":     compileOr,
+		"if":     compileIf,
+		"while":  compileWhile,
+		"for":    compileFor,
+		"try":    compileTry,
 	}
 	for name := range builtinSpecials {
 		IsBuiltinSpecial[name] = true
@@ -89,8 +93,8 @@ func compileDel(cp *compiler, fn *parse.Form) effectOp {
 			continue
 		}
 		if len(indices) == 0 {
-			if ref.scope == envScope {
-				f = delEnvVarOp{ref.subNames[0]}
+			if ref.scope == envScope || ref.scope == envListScope {
+				f = delEnvVarOp{cn.Range(), ref.subNames[0]}
 			} else if ref.scope == localScope && len(ref.subNames) == 0 {
 				f = delLocalVarOp{ref.index}
 				cp.thisScope().deleted[ref.index] = true
@@ -113,10 +117,20 @@ func (op delLocalVarOp) exec(fm *Frame) error {
 	return nil
 }
 
-type delEnvVarOp struct{ name string }
+type delEnvVarOp struct {
+	diag.Ranging
+	name string
+}
 
-func (op delEnvVarOp) exec(*Frame) error {
-	return os.Unsetenv(op.name)
+func (op delEnvVarOp) exec(fm *Frame) error {
+	if err := fm.CheckRestriction(RestrictEnvChanges); err != nil {
+		return fm.errorp(op, err)
+	}
+	err := vars.UnsetEnv(op.name)
+	if err != nil {
+		return fm.errorp(op, err)
+	}
+	return nil
 }
 
 func newDelElementOp(ref *varRef, begin, headEnd int, indexOps []valuesOp) effectOp {
@@ -161,7 +175,7 @@ func (op *delElemOp) exec(fm *Frame) error {
 	return nil
 }
 
-// FnForm = 'fn' StringPrimary LambdaPrimary
+// FnForm = 'fn' { MapPair } StringPrimary LambdaPrimary
 //
 // fn f []{foobar} is a shorthand for set '&'f = []{foobar}.
 func compileFn(cp *compiler, fn *parse.Form) effectOp {
@@ -171,17 +185,31 @@ func compileFn(cp *compiler, fn *parse.Form) effectOp {
 	bodyNode := args.nextMustLambda()
 	args.mustEnd()
 
+	doc := ""
+	for _, opt := range fn.Opts {
+		if optName := mustString(cp, opt.Key, "option name must be literal string"); optName != "doc" {
+			cp.errorpf(opt.Key, "unknown option %s", parse.Quote(optName))
+		}
+		if opt.Value == nil {
+			cp.errorpf(opt.Key, "doc must have a value")
+		} else {
+			doc = mustString(cp, opt.Value, "doc must be a literal string")
+		}
+	}
+
 	// Define the variable before compiling the body, so that the body may refer
 	// to the function itself.
 	index := cp.thisScope().add(name + FnSuffix)
 	op := cp.lambda(bodyNode)
 
-	return fnOp{index, op}
+	return fnOp{name, index, op, doc}
 }
 
 type fnOp struct {
+	name     string
 	varIndex int
 	lambdaOp valuesOp
+	doc      string
 }
 
 func (op fnOp) exec(fm *Frame) error {
@@ -194,6 +222,10 @@ func (op fnOp) exec(fm *Frame) error {
 		return err
 	}
 	c := values[0].(*closure)
+	c.Name = op.name
+	if op.doc != "" {
+		c.Doc = op.doc
+	}
 	c.Op = fnWrap{c.Op}
 	return fm.local.slots[op.varIndex].Set(c)
 }
@@ -211,6 +243,83 @@ func (op fnWrap) exec(fm *Frame) error {
 	return nil
 }
 
+// ConstForm = 'const' StringPrimary Compound
+func compileConst(cp *compiler, fn *parse.Form) effectOp {
+	args := cp.walkArgs(fn)
+	nameNode := args.next()
+	valueNode := args.next()
+	args.mustEnd()
+
+	name := mustString(cp, nameNode, "constant name must be a literal string")
+	if sigil, qname := SplitSigil(name); sigil != "" || qname == "" {
+		cp.errorpf(nameNode, "constant name must not be empty or have a sigil")
+	} else if _, rest := SplitQName(qname); rest != "" {
+		cp.errorpf(nameNode, "constant name must be unqualified")
+	}
+	// Compile the value before defining the variable, so that the value may
+	// refer to a variable of the same name in an outer scope.
+	valueOp := cp.compoundOp(valueNode)
+	index := cp.thisScope().add(name)
+	return constOp{nameNode.Range(), index, valueOp}
+}
+
+type constOp struct {
+	diag.Ranging
+	varIndex int
+	valueOp  valuesOp
+}
+
+func (op constOp) exec(fm *Frame) error {
+	v, err := evalForValue(fm, op.valueOp, "value of constant")
+	if err != nil {
+		return err
+	}
+	fm.local.slots[op.varIndex] = vars.NewReadOnly(freeze(v, map[*Ns]*Ns{}))
+	return nil
+}
+
+// Returns a copy of v in which the variables of all namespaces are read-only,
+// including namespaces nested in lists and maps. Other values are immutable
+// and returned as is. The frozen map records namespaces already frozen.
+func freeze(v interface{}, frozen map[*Ns]*Ns) interface{} {
+	switch v := v.(type) {
+	case *Ns:
+		if ns, ok := frozen[v]; ok {
+			return ns
+		}
+		ns := &Ns{make([]vars.Var, len(v.slots)), v.names}
+		frozen[v] = ns
+		for i, slot := range v.slots {
+			if slot != nil {
+				ns.slots[i] = vars.NewReadOnly(freeze(slot.Get(), frozen))
+			}
+		}
+		return ns
+	case vals.List:
+		list := vals.EmptyList
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			list = list.Cons(freeze(it.Elem(), frozen))
+		}
+		return list
+	case vals.Map:
+		m := vals.EmptyMap
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			k, e := it.Elem()
+			m = m.Assoc(freeze(k, frozen), freeze(e, frozen))
+		}
+		return m
+	case vals.OrderedMap:
+		m := vals.EmptyOrderedMap
+		v.IteratePairs(func(k, e interface{}) bool {
+			m = m.Put(freeze(k, frozen), freeze(e, frozen))
+			return true
+		})
+		return m
+	default:
+		return v
+	}
+}
+
 // UseForm = 'use' StringPrimary
 func compileUse(cp *compiler, fn *parse.Form) effectOp {
 	var name, spec string
@@ -225,6 +334,11 @@ func compileUse(cp *compiler, fn *parse.Form) effectOp {
 		// Use the last path component as the name; for instance, if path =
 		// "a/b/c/d", name is "d". If path doesn't have slashes, name = path.
 		name = spec[strings.LastIndexByte(spec, '/')+1:]
+		if spec == "strict" {
+			// "use strict" is the same as "pragma strict".
+			cp.strict[len(cp.strict)-1] = true
+			return nopOp{}
+		}
 	case 2:
 		// TODO(xiaq): Allow using variable as module path
 		spec = mustString(cp, fn.Args[0],
@@ -246,7 +360,7 @@ type useOp struct {
 }
 
 func (op useOp) exec(fm *Frame) error {
-	ns, err := use(fm, op.spec, fm.addTraceback(op))
+	ns, err := use(fm, op.spec, fm.addTraceback(op, ""))
 	if err != nil {
 		return fm.errorp(op, err)
 	}
@@ -266,7 +380,10 @@ func use(fm *Frame, spec string, st *StackTrace) (*Ns, error) {
 				return nil, err
 			}
 		}
-		path := filepath.Clean(dir + "/" + spec + ".elv")
+		path, err := filepath.Abs(filepath.Join(dir, spec+".elv"))
+		if err != nil {
+			return nil, err
+		}
 		return useFromFile(fm, spec, path, st)
 	}
 	if ns, ok := fm.Evaler.modules[spec]; ok {
@@ -279,7 +396,46 @@ func use(fm *Frame, spec string, st *StackTrace) (*Ns, error) {
 	if fm.libDir == "" {
 		return nil, noSuchModule{spec}
 	}
-	return useFromFile(fm, spec, fm.libDir+"/"+spec+".elv", st)
+	path := filepath.Join(fm.libDir, spec+".elv")
+	ns, err := useFromFile(fm, spec, path, st)
+	if _, notFound := err.(noSuchModule); notFound && shouldFetchModules(fm) && isRemoteSpec(spec) {
+		if err := fetchModule(fm, spec, st); err != nil {
+			return nil, err
+		}
+		return useFromFile(fm, spec, path, st)
+	}
+	return ns, err
+}
+
+// Returns whether modules that are not found should be fetched. Since fetching
+// runs code from the network, it is off unless the Evaler turns it on or the
+// user opts in with an environment variable.
+func shouldFetchModules(fm *Frame) bool {
+	return fm.FetchModules || os.Getenv(env.ELVISH_FETCH_MODULES) == "1"
+}
+
+// Returns whether a module spec starts with a domain name, like
+// "github.com/user/repo/mod".
+func isRemoteSpec(spec string) bool {
+	i := strings.IndexByte(spec, '/')
+	return i != -1 && strings.Contains(spec[:i], ".")
+}
+
+// Fetches the package containing a module with the fetch-module function of
+// the epm module.
+func fetchModule(fm *Frame, spec string, st *StackTrace) error {
+	epm, err := use(fm, "epm", st)
+	if err != nil {
+		return err
+	}
+	v, _ := epm.Index("fetch-module" + FnSuffix)
+	fetch, ok := v.(Callable)
+	if !ok {
+		return errors.New("epm module has no fetch-module function")
+	}
+	newFm := fm.fork("fetch module")
+	defer newFm.Close()
+	return fetch.Call(newFm, []interface{}{spec}, NoOpts)
 }
 
 func useFromFile(fm *Frame, spec, path string, st *StackTrace) (*Ns, error) {
@@ -319,14 +475,37 @@ func evalInner(fm *Frame, src parse.Source, ns *Ns, st *StackTrace) error {
 	}
 	newFm := &Frame{
 		fm.Evaler, src, ns, new(Ns),
-		fm.intCh, fm.ports, fm.traceback, fm.background}
-	op, err := compile(newFm.Builtin.static(), ns.static(), tree, fm.ErrorFile())
+		fm.ctx, fm.ports, fm.traceback, fm.background, fm.spawn,
+		fm.restrictions, nil, fm.callDepth, fm.pipelineDepth}
+	op, err := fm.Evaler.compile(tree, ns, fm.ErrorFile())
 	if err != nil {
 		return err
 	}
 	return op.Exec(newFm)
 }
 
+// PragmaForm = 'pragma' StringPrimary
+func compilePragma(cp *compiler, fn *parse.Form) effectOp {
+	args := cp.walkArgs(fn)
+	nameNode := args.next()
+	args.mustEnd()
+
+	name := mustString(cp, nameNode, "pragma name must be a literal string")
+	switch name {
+	case "strict":
+		cp.strict[len(cp.strict)-1] = true
+	default:
+		cp.errorpf(nameNode, "unknown pragma %s", name)
+	}
+	return nopOp{}
+}
+
+// An effectOp that does nothing, compiled from forms that only affect
+// compilation.
+type nopOp struct{}
+
+func (nopOp) exec(fm *Frame) error { return nil }
+
 // compileAnd compiles the "and" special form.
 //
 // The and special form evaluates arguments until a false-ish values is found
@@ -561,31 +740,17 @@ func compileTry(cp *compiler, fn *parse.Form) effectOp {
 	args := cp.walkArgs(fn)
 	bodyNode := args.nextMustLambda()
 	logger.Printf("body is %q", parse.SourceText(bodyNode))
-	var exceptVarNode *parse.Compound
-	var exceptNode *parse.Primary
-	if args.nextIs("except") {
+	var excepts []exceptOp
+	for args.nextIs("except") {
 		logger.Println("except-ing")
-		n := args.peek()
-		// Is this a variable?
-		if len(n.Indexings) == 1 && n.Indexings[0].Head.Type == parse.Bareword {
-			exceptVarNode = n
-			args.next()
-		}
-		exceptNode = args.nextMustLambda()
+		excepts = append(excepts, compileExcept(cp, args))
 	}
 	elseNode := args.nextMustLambdaIfAfter("else")
 	finallyNode := args.nextMustLambdaIfAfter("finally")
 	args.mustEnd()
 
-	var exceptVar lvalue
-	var bodyOp, exceptOp, elseOp, finallyOp valuesOp
+	var bodyOp, elseOp, finallyOp valuesOp
 	bodyOp = cp.primaryOp(bodyNode)
-	if exceptVarNode != nil {
-		exceptVar = cp.compileOneLValue(exceptVarNode)
-	}
-	if exceptNode != nil {
-		exceptOp = cp.primaryOp(exceptNode)
-	}
 	if elseNode != nil {
 		elseOp = cp.primaryOp(elseNode)
 	}
@@ -593,42 +758,82 @@ func compileTry(cp *compiler, fn *parse.Form) effectOp {
 		finallyOp = cp.primaryOp(finallyNode)
 	}
 
-	return &tryOp{fn.Range(), bodyOp, exceptVar, exceptOp, elseOp, finallyOp}
+	return &tryOp{fn.Range(), bodyOp, excepts, elseOp, finallyOp}
+}
+
+// Compiles an except clause after the "except" keyword. The clause may have
+// no words, a variable, or an exception class followed by a variable. A single
+// word is always a variable, even if it is also the name of a class.
+func compileExcept(cp *compiler, args *argsWalker) exceptOp {
+	var words []*parse.Compound
+	for len(words) < 2 && isBarewordCompound(args.peek()) {
+		words = append(words, args.next())
+	}
+	class := excClassRoot
+	var varNode *parse.Compound
+	switch len(words) {
+	case 1:
+		varNode = words[0]
+	case 2:
+		c, ok := excClassesByName[parse.SourceText(words[0])]
+		if !ok {
+			cp.errorpf(words[0], "unknown exception class %s", parse.SourceText(words[0]))
+		}
+		class, varNode = c, words[1]
+	}
+	bodyNode := args.nextMustLambda()
+	op := exceptOp{class: class}
+	// The variable must be compiled first to make it available in the body.
+	if varNode != nil {
+		op.varOp = cp.compileOneLValue(varNode)
+	}
+	op.bodyOp = cp.primaryOp(bodyNode)
+	return op
+}
+
+func isBarewordCompound(n *parse.Compound) bool {
+	return len(n.Indexings) == 1 && n.Indexings[0].Head.Type == parse.Bareword
 }
 
 type tryOp struct {
 	diag.Ranging
 	bodyOp    valuesOp
-	exceptVar lvalue
-	exceptOp  valuesOp
+	excepts   []exceptOp
 	elseOp    valuesOp
 	finallyOp valuesOp
 }
 
+type exceptOp struct {
+	class  *ExceptionClass
+	varOp  lvalue
+	bodyOp valuesOp
+}
+
 func (op *tryOp) exec(fm *Frame) error {
 	body := execLambdaOp(fm, op.bodyOp)
-	var exceptVar vars.Var
-	if op.exceptVar.ref != nil {
-		var err error
-		exceptVar, err = derefLValue(fm, op.exceptVar)
-		if err != nil {
-			return fm.errorp(op, err)
-		}
-	}
-	except := execLambdaOp(fm, op.exceptOp)
 	elseFn := execLambdaOp(fm, op.elseOp)
 	finally := execLambdaOp(fm, op.finallyOp)
 
 	err := body.Call(fm.fork("try body"), NoArgs, NoOpts)
 	if err != nil {
-		if except != nil {
-			if exceptVar != nil {
-				err := exceptVar.Set(err.(*Exception))
-				if err != nil {
-					return fm.errorp(op, err)
+		exc := err.(*Exception)
+		class := ExceptionClassOf(exc.Reason)
+		for _, except := range op.excepts {
+			if !class.Is(except.class) {
+				continue
+			}
+			if except.varOp.ref != nil {
+				exceptVar, errDeref := derefLValue(fm, except.varOp)
+				if errDeref != nil {
+					return fm.errorp(op, errDeref)
+				}
+				if errSet := exceptVar.Set(exc); errSet != nil {
+					return fm.errorp(op, errSet)
 				}
 			}
-			err = except.Call(fm.fork("try except"), NoArgs, NoOpts)
+			err = execLambdaOp(fm, except.bodyOp).Call(
+				fm.fork("try except"), NoArgs, NoOpts)
+			break
 		}
 	} else {
 		if elseFn != nil {
@@ -1,12 +1,18 @@
 package eval_test
 
 import (
+	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/elves/elvish/pkg/env"
 	. "github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/mods/bundled"
+	"github.com/elves/elvish/pkg/eval/vars"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
 	"github.com/elves/elvish/pkg/prog"
@@ -70,8 +76,45 @@ func TestBuiltinSpecial(t *testing.T) {
 		That("try { fail tr } except { fail ex } finally { fail final }").Throws(ErrorWithMessage(
 			"final")),
 
+		// try - except clauses with exception classes
+		That("try { fail tr } except fail-error e { put $e[reason][content] }").
+			Puts("tr"),
+		That("try { fail tr } except exception e { put caught }").Puts("caught"),
+		That("try { fail tr } except arity-error e { put bad }").
+			Throws(ErrorWithMessage("tr")),
+		That("try { e:false } except external-cmd-error e { put caught }").
+			Puts("caught"),
+		That("try { e:false } except external-cmd-exited e { put $e[reason][exit-status] }").
+			Puts("1"),
+		That("try { [x]{ } } except type-error e { put bad } except arity-error e { put arity }").
+			Puts("arity"),
+		That("try { [x:num]{ } foo } except type-error e { put type }").Puts("type"),
+		That("try { fail 1 | fail 2 } except pipeline-error e { put pipeline }").
+			Puts("pipeline"),
+		// A single word is always a variable, even if it names a class.
+		That("try { [x]{ } } except fail-error { put $fail-error[class][name] }").
+			Puts("arity-error"),
+		That("try { fail tr } except exception { put $exception[reason][content] }").
+			Puts("tr"),
+		// The first matching clause is used.
+		That("try { fail tr } except fail-error e { put first } except { put second }").
+			Puts("first"),
+		// Exceptions not caught by any clause still run finally.
+		That("try { fail tr } except arity-error e { put bad } finally { put final }").
+			Puts("final").Throws(ErrorWithMessage("tr")),
+
+		// try - re-raising exceptions with fail keeps their class and reason
+		That("try { try { fail tr } except fail-error e { put inner; fail $e } } "+
+			"except fail-error e { put $e[reason][content] }").
+			Puts("inner", "tr"),
+		That("try { fail tr } except e { fail $e }").Throws(FailError{"tr"}, "fail tr "),
+		That("try { try { e:false } except external-cmd-error e { fail $e } } "+
+			"except external-cmd-exited e { put $e[class][name] }").
+			Puts("external-cmd-exited"),
+
 		// try - wrong use
 		That("try { nop } except @a { }").DoesNotCompile(),
+		That("try { nop } except no-such-class e { }").DoesNotCompile(),
 
 		// while
 		That("x=0; while (< $x 4) { put $x; x=(+ $x 1) }").
@@ -114,6 +157,33 @@ func TestBuiltinSpecial(t *testing.T) {
 
 		// return.
 		That("fn f []{ put a; return; put b }; f").Puts("a"),
+
+		// const.
+		That("const x foo; put $x").Puts("foo"),
+		That("const x foo; fn f { put $x }; f").Puts("foo"),
+		That("x = foo; { const x $x'bar'; put $x }; put $x").
+			Puts("foobar", "foo"),
+		That("const x foo; x = bar").Throws(vars.ErrSetReadOnlyVar, "x = bar"),
+		That("const x [&k=[a b]]; x[k][0] = c").
+			Throws(vars.ErrSetReadOnlyVar, "x[k][0] = c"),
+		That("const x foo; try { x = bar } except { put caught }; put $x").
+			Puts("caught", "foo"),
+		// Namespaces in the value are frozen deeply, without affecting the
+		// original namespace.
+		That("n = (ns [&k=v]); const c: $n; c:k = w").
+			Throws(vars.ErrSetReadOnlyVar, "c:k = w"),
+		That("n: = (ns [&k=v]); const l [[$n:]]; n:k = w; put $l[0][0][k] $n:k").
+			Puts("v", "w"),
+		That("const x (put a b)").Throws(
+			errs.ArityMismatch{
+				What:     "value of constant",
+				ValidLow: 1, ValidHigh: 1, Actual: 2},
+			"(put a b)"),
+		That("const x").DoesNotCompile(),
+		That("const x a b").DoesNotCompile(),
+		That("const $x a").DoesNotCompile(),
+		That("const @x a").DoesNotCompile(),
+		That("const a:x a").DoesNotCompile(),
 	)
 }
 
@@ -159,6 +229,9 @@ func TestUse(t *testing.T) {
 		That(`use a/b/c/x; put $x:d $x:lorem`).Puts("a/b/c/d", "lorem"),
 		// relative uses from top-level
 		That(`use ./d; put $d:name`).Puts("d"),
+		// modules are cached by their resolved paths
+		That(`use has-init; use ./has-init; use ./a/../has-init`).Puts("has-init"),
+		That(`use a/b/c/d; use ./a/b/c/d d2; is $d: $d2:`).Puts(true),
 
 		// Renaming module
 		That(`use a/b/c/d mod; put $mod:name`).Puts("a/b/c/d"),
@@ -175,6 +248,147 @@ func TestUse(t *testing.T) {
 	)
 }
 
+func TestLoadedModules_ReloadModule(t *testing.T) {
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.MustWriteFile("m.elv", []byte("x = old"), 0600)
+
+	ev := NewEvaler()
+	ev.SetLibDir(libdir)
+	r := EvalAndCollect(t, ev, []string{"use m; f = { put $m:x }"})
+	if r.Exception != nil {
+		t.Fatalf("got exception %v", r.Exception)
+	}
+	path, _ := filepath.Abs("m.elv")
+	wantModules := []string{path, "builtin"}
+	if modules := ev.LoadedModules(); !reflect.DeepEqual(modules, wantModules) {
+		t.Errorf("got loaded modules %v, want %v", modules, wantModules)
+	}
+
+	testutil.MustWriteFile("m.elv", []byte("x = new"), 0600)
+	if err := ev.ReloadModule(path, EvalCfg{}); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	r = EvalAndCollect(t, ev, []string{"put $m:x; $f"})
+	if want := []interface{}{"new", "new"}; !reflect.DeepEqual(r.ValueOut, want) {
+		t.Errorf("got values %v, want %v", r.ValueOut, want)
+	}
+
+	// The namespace is unchanged when reloading fails.
+	testutil.MustWriteFile("m.elv", []byte("x = broken; fail bad"), 0600)
+	if err := ev.ReloadModule(path, EvalCfg{}); err == nil {
+		t.Errorf("got nil error when reloading failing module")
+	}
+	r = EvalAndCollect(t, ev, []string{"put $m:x"})
+	if want := []interface{}{"new"}; !reflect.DeepEqual(r.ValueOut, want) {
+		t.Errorf("got values %v, want %v", r.ValueOut, want)
+	}
+
+	// Code compiled before reloading still works when the variables of the
+	// module are defined in a different order, or removed.
+	testutil.MustWriteFile("m.elv", []byte("x = 1; y = 2"), 0600)
+	if err := ev.ReloadModule(path, EvalCfg{}); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	r = EvalAndCollect(t, ev, []string{"fn g { put $m:y }; g"})
+	if want := []interface{}{"2"}; !reflect.DeepEqual(r.ValueOut, want) {
+		t.Errorf("got values %v, want %v", r.ValueOut, want)
+	}
+	testutil.MustWriteFile("m.elv", []byte("y = 3"), 0600)
+	if err := ev.ReloadModule(path, EvalCfg{}); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	r = EvalAndCollect(t, ev, []string{"g; has-key $m: x"})
+	if want := []interface{}{"3", false}; !reflect.DeepEqual(r.ValueOut, want) {
+		t.Errorf("got values %v, want %v", r.ValueOut, want)
+	}
+
+	if err := ev.ReloadModule("builtin", EvalCfg{}); err == nil {
+		t.Errorf("got nil error when reloading builtin module")
+	}
+	if err := ev.ReloadModule("bad", EvalCfg{}); err == nil {
+		t.Errorf("got nil error when reloading module that is not loaded")
+	}
+}
+
+func TestEachModule(t *testing.T) {
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"m.elv":   "",
+		"a":       testutil.Dir{"b.elv": "", "c.txt": ""},
+		".hidden": testutil.Dir{"x.elv": ""},
+	})
+
+	ev := NewEvaler()
+	ev.SetLibDir(libdir)
+	ev.InstallModule("foo", &Ns{})
+	var modules []string
+	ev.EachModule(func(spec string) { modules = append(modules, spec) })
+	sort.Strings(modules)
+
+	wantModules := []string{"a/b", "builtin", "foo", "m"}
+	for spec := range bundled.Get() {
+		wantModules = append(wantModules, spec)
+	}
+	sort.Strings(wantModules)
+	if !reflect.DeepEqual(modules, wantModules) {
+		t.Errorf("got modules %v, want %v", modules, wantModules)
+	}
+}
+
+func TestUse_FetchesRemoteModules(t *testing.T) {
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	var fetched []string
+	fakeEpm := NsBuilder{}.AddGoFn("epm:", "fetch-module", func(spec string) {
+		fetched = append(fetched, spec)
+		testutil.MustMkdirAll(filepath.Join("example.com", "u", "r"))
+		testutil.MustWriteFile(
+			filepath.Join("example.com", "u", "r", "mod.elv"), []byte("put mod"), 0600)
+	}).Ns()
+	setup := func(fetch bool) func(*Evaler) {
+		return func(ev *Evaler) {
+			ev.SetLibDir(libdir)
+			ev.FetchModules = fetch
+			ev.InstallModule("epm", fakeEpm)
+		}
+	}
+
+	restore := testutil.WithTempEnv(env.ELVISH_FETCH_MODULES, "")
+	defer restore()
+	TestWithSetup(t, setup(false),
+		That("use example.com/u/r/mod").Throws(AnyError),
+	)
+	TestWithSetup(t, setup(true),
+		// Module is fetched when not found.
+		That("use example.com/u/r/mod").Puts("mod"),
+		// Module is not fetched again when found.
+		That("use example.com/u/r/mod").Puts("mod"),
+		// Module is not found even after fetching.
+		That("use example.com/u/r/other").Throws(AnyError),
+		// Module specs without domains are not fetched.
+		That("use u/r/mod").Throws(AnyError),
+	)
+
+	// Fetching can also be turned on with an environment variable.
+	os.Setenv(env.ELVISH_FETCH_MODULES, "0")
+	TestWithSetup(t, setup(false),
+		That("use example.com/u/r/other").Throws(AnyError),
+	)
+	os.Setenv(env.ELVISH_FETCH_MODULES, "1")
+	TestWithSetup(t, setup(false),
+		That("use example.com/u/r/other").Throws(AnyError),
+	)
+
+	wantFetched := []string{
+		"example.com/u/r/mod", "example.com/u/r/other", "example.com/u/r/other"}
+	if !reflect.DeepEqual(fetched, wantFetched) {
+		t.Errorf("got fetched %v, want %v", fetched, wantFetched)
+	}
+}
+
 // Regression test for #1072
 func TestUse_WarnsAboutDeprecatedFeatures(t *testing.T) {
 	restore := prog.SetShowDeprecations(true)
@@ -12,3 +12,19 @@ var (
 	// NoOpts is an empty option map. It can be used as an argument to Call.
 	NoOpts = map[string]interface{}{}
 )
+
+// RequiresArgs reports whether fn is a Go-native or user-defined function that
+// can't be called without arguments.
+func RequiresArgs(fn Callable) bool {
+	switch fn := fn.(type) {
+	case *goFn:
+		return len(fn.normalArgs) > 0
+	case *closure:
+		for i := range fn.ArgNames {
+			if i != fn.RestArg && (fn.ArgDefaultOps == nil || fn.ArgDefaultOps[i] == nil) {
+				return true
+			}
+		}
+	}
+	return false
+}
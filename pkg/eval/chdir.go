@@ -4,22 +4,31 @@ import (
 	"os"
 
 	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/eval/vars"
 )
 
-// Chdir changes the current directory. On success it also updates the PWD
-// environment variable and records the new directory in the directory history.
+// Chdir changes the current directory. On success it also updates the PWD and
+// OLDPWD environment variables and records the new directory in the directory
+// history.
 // It runs the functions in beforeChdir immediately before changing the
 // directory, and the functions in afterChdir immediately after (if chdir was
 // successful). It returns nil as long as the directory changing part succeeds.
 func (ev *Evaler) Chdir(path string) error {
+	if err := ev.CheckRestriction(RestrictEnvChanges); err != nil {
+		return err
+	}
 	for _, hook := range ev.beforeChdir {
 		hook(path)
 	}
 
+	oldPwd, errOldPwd := os.Getwd()
 	err := os.Chdir(path)
 	if err != nil {
 		return err
 	}
+	if errOldPwd == nil {
+		vars.SetEnv(env.OLDPWD, oldPwd)
+	}
 
 	for _, hook := range ev.afterChdir {
 		hook(path)
@@ -30,7 +39,7 @@ func (ev *Evaler) Chdir(path string) error {
 		logger.Println("getwd after cd:", err)
 		return nil
 	}
-	os.Setenv(env.PWD, pwd)
+	vars.SetEnv(env.PWD, pwd)
 
 	return nil
 }
@@ -2,6 +2,7 @@ package eval_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/elves/elvish/pkg/env"
@@ -73,6 +74,30 @@ func TestChdirError(t *testing.T) {
 	}
 }
 
+func TestImplicitCd(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.MustMkdirAll("d")
+	d := filepath.Join(dir, "d")
+
+	Test(t,
+		That(`cd `+parse.Quote(dir)+`; ./d; put $pwd`).Puts(d),
+		That(`cd `+parse.Quote(dir)+`; ./d foo`).Throws(ErrImplicitCdNoArg),
+		// Directories without a slash are only used when $auto-cd is true.
+		That(`cd `+parse.Quote(dir)+`; d`).Throws(AnyError),
+		That(`cd `+parse.Quote(dir)+`; auto-cd = $true; d; put $pwd`).Puts(d),
+		// Implicit cd also runs the chdir hooks.
+		That(`
+			cd `+parse.Quote(dir)+`
+			dir-in-after = ''
+			@after-chdir = [dst]{ dir-in-after = $dst }
+			auto-cd = $true
+			d
+			put $dir-in-after
+			`).Puts("d"),
+	)
+}
+
 // Saves the current working directory, and returns a function for returning to
 // it.
 func saveWd() func() {
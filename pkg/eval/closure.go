@@ -3,6 +3,7 @@ package eval
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/elves/elvish/pkg/diag"
@@ -15,7 +16,18 @@ import (
 
 // A user-defined function in Elvish code. Each closure has its unique identity.
 type closure struct {
+	// The name of the function, if it is defined with fn. Empty for anonymous
+	// functions.
+	Name     string
 	ArgNames []string
+	// Type annotations of arguments, in the same order as ArgNames. Nil if no
+	// argument has a type annotation; otherwise an element is "" if the
+	// argument does not have a type annotation.
+	ArgTypes []string
+	// Operations that evaluate the default values of arguments, in the same
+	// order as ArgNames. Nil if no argument has a default value; otherwise an
+	// element is nil if the argument does not have a default value.
+	ArgDefaultOps []valuesOp
 	// The index of the rest argument. -1 if there is no rest argument.
 	RestArg     int
 	OptNames    []string
@@ -24,6 +36,9 @@ type closure struct {
 	Captured    *Ns
 	SrcMeta     parse.Source
 	DefRange    diag.Ranging
+	// The docstring, from a string literal at the start of the body or the
+	// &doc option of fn. Empty if there is none.
+	Doc string
 }
 
 var _ Callable = &closure{}
@@ -60,18 +75,21 @@ func listOfStrings(ss []string) vals.List {
 func (c *closure) Call(fm *Frame, args []interface{}, opts map[string]interface{}) error {
 	if c.RestArg != -1 {
 		if len(args) < len(c.ArgNames)-1 {
-			return errs.ArityMismatch{
-				What:     "arguments here",
-				ValidLow: len(c.ArgNames) - 1, ValidHigh: -1, Actual: len(args)}
+			return c.arityMismatch(len(c.ArgNames)-1, -1, len(args))
 		}
 	} else {
-		if len(args) != len(c.ArgNames) {
-			return errs.ArityMismatch{
-				What:     "arguments here",
-				ValidLow: len(c.ArgNames), ValidHigh: len(c.ArgNames), Actual: len(args)}
+		if low := c.requiredArgs(); len(args) < low || len(args) > len(c.ArgNames) {
+			return c.arityMismatch(low, len(c.ArgNames), len(args))
 		}
 	}
 
+	if err := c.checkArgTypes(args); err != nil {
+		return err
+	}
+	if err := fm.enterCall(); err != nil {
+		return err
+	}
+
 	// This evalCtx is dedicated to the current form, so we modify it in place.
 	// BUG(xiaq): When evaluating closures, async access to global variables
 	// and ports can be problematic.
@@ -86,8 +104,13 @@ func (c *closure) Call(fm *Frame, args []interface{}, opts map[string]interface{
 		fm.local.names[i] = name
 	}
 	if c.RestArg == -1 {
-		for i, _ := range c.ArgNames {
-			fm.local.slots[i] = vars.FromInit(args[i])
+		for i := range c.ArgNames {
+			if i < len(args) {
+				fm.local.slots[i] = vars.FromInit(args[i])
+			} else {
+				// Filled in below, after all options are set.
+				fm.local.slots[i] = vars.FromInit(nil)
+			}
 		}
 	} else {
 		for i := 0; i < c.RestArg; i++ {
@@ -119,7 +142,89 @@ func (c *closure) Call(fm *Frame, args []interface{}, opts map[string]interface{
 	}
 
 	fm.srcMeta = c.SrcMeta
-	return c.Op.exec(fm)
+	fm.deferred = new(deferredFns)
+	// Evaluate default values of omitted arguments. This is done lazily, so
+	// that the default values can depend on earlier arguments and options.
+	if c.RestArg == -1 {
+		for i := len(args); i < len(c.ArgNames); i++ {
+			v, err := evalForValue(fm, c.ArgDefaultOps[i], "argument default value")
+			if err != nil {
+				return err
+			}
+			if err := checkArgType(c.argType(i), c.argWhat(i), v); err != nil {
+				return err
+			}
+			fm.local.slots[i].Set(v)
+		}
+	}
+	return fm.deferred.run(fm, c.Op.exec(fm))
+}
+
+// Returns the docstring of the closure, or the comment lines immediately before
+// the line where it is defined if it has no docstring.
+func (c *closure) docText() string {
+	if c.Doc != "" {
+		return c.Doc
+	}
+	code := c.SrcMeta.Code
+	return docComment(code[:strings.LastIndexByte(code[:c.DefRange.From], '\n')+1])
+}
+
+func (c *closure) arityMismatch(low, high, actual int) error {
+	return errs.ArityMismatch{
+		What:     "arguments here",
+		ValidLow: low, ValidHigh: high, Actual: actual,
+		FnName: c.Name,
+		FnDef:  c.SrcMeta.Context(c.DefRange),
+	}
+}
+
+// Checks the arguments against the type annotations. It must be called after
+// the number of arguments has been checked.
+func (c *closure) checkArgTypes(args []interface{}) error {
+	if c.ArgTypes == nil {
+		return nil
+	}
+	restOff := len(args) - len(c.ArgNames)
+	for i, v := range args {
+		j := i
+		if c.RestArg != -1 && i >= c.RestArg {
+			if i <= c.RestArg+restOff {
+				j = c.RestArg
+			} else {
+				j = i - restOff
+			}
+		}
+		what := c.argWhat(j)
+		if j == c.RestArg {
+			what = "element of " + what
+		}
+		if err := checkArgType(c.argType(j), what, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *closure) argType(i int) string {
+	if c.ArgTypes == nil {
+		return ""
+	}
+	return c.ArgTypes[i]
+}
+
+func (c *closure) argWhat(i int) string {
+	return "argument $" + c.ArgNames[i]
+}
+
+// Returns the number of arguments that don't have default values.
+func (c *closure) requiredArgs() int {
+	for i, op := range c.ArgDefaultOps {
+		if op != nil {
+			return i
+		}
+	}
+	return len(c.ArgNames)
 }
 
 func (c *closure) Fields() vals.StructMap { return closureFields{c} }
@@ -129,9 +234,16 @@ type closureFields struct{ c *closure }
 func (closureFields) IsStructMap() {}
 
 func (cf closureFields) ArgNames() vals.List { return listOfStrings(cf.c.ArgNames) }
+
+func (cf closureFields) ArgTypes() vals.List {
+	sig, _ := SignatureOf(cf.c)
+	return listOfStrings(sig.ArgTypes)
+}
+
 func (cf closureFields) RestArg() string     { return strconv.Itoa(cf.c.RestArg) }
 func (cf closureFields) OptNames() vals.List { return listOfStrings(cf.c.OptNames) }
 func (cf closureFields) Src() parse.Source   { return cf.c.SrcMeta }
+func (cf closureFields) Doc() string         { return cf.c.Doc }
 
 func (cf closureFields) OptDefaults() vals.List {
 	return vals.MakeList(cf.c.OptDefaults...)
@@ -1,8 +1,10 @@
 package eval_test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/eval/errs"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
@@ -15,24 +17,30 @@ func TestClosure(t *testing.T) {
 		That("x = { }; put [&$x= foo][$x]").Puts("foo"),
 
 		That("f = [x]{ }", "$f a b").Throws(
-			errs.ArityMismatch{
-				What:     "arguments here",
-				ValidLow: 1, ValidHigh: 1, Actual: 2},
+			argsMismatch("", "f = [x]{ }\n$f a b", "[x]{ }", 1, 1, 2),
 			"$f a b"),
 		That("f = [x y]{ }", "$f a").Throws(
-			errs.ArityMismatch{
-				What:     "arguments here",
-				ValidLow: 2, ValidHigh: 2, Actual: 1},
+			argsMismatch("", "f = [x y]{ }\n$f a", "[x y]{ }", 2, 2, 1),
 			"$f a"),
+		That("f = [x y=1 z=2]{ }", "$f").Throws(
+			argsMismatch("", "f = [x y=1 z=2]{ }\n$f", "[x y=1 z=2]{ }", 1, 3, 0),
+			"$f"),
+		That("f = [x y=1 z=2]{ }", "$f a b c d").Throws(
+			argsMismatch("", "f = [x y=1 z=2]{ }\n$f a b c d", "[x y=1 z=2]{ }", 1, 3, 4),
+			"$f a b c d"),
 		That("f = [x y @rest]{ }", "$f a").Throws(
-			errs.ArityMismatch{
-				What:     "arguments here",
-				ValidLow: 2, ValidHigh: -1, Actual: 1},
+			argsMismatch("", "f = [x y @rest]{ }\n$f a", "[x y @rest]{ }", 2, -1, 1),
 			"$f a"),
+		// Arity mismatch of a function defined with fn contains its name.
+		That("fn f [x]{ }", "f").Throws(
+			argsMismatch("f", "fn f [x]{ }\nf", "[x]{ }", 1, 1, 0),
+			"f"),
 
 		That("[]{ } &k=v").Throws(AnyError),
 
 		That("all [a b]{ }[arg-names]").Puts("a", "b"),
+		That("all [a:num b @c:string]{ }[arg-types]").Puts("num", "any", "string"),
+		That("all [a b]{ }[arg-types]").Puts("any", "any"),
 		That("put [@r]{ }[rest-arg]").Puts("0"),
 		That("all [&opt=def]{ }[opt-names]").Puts("opt"),
 		That("all [&opt=def]{ }[opt-defaults]").Puts("def"),
@@ -43,5 +51,33 @@ func TestClosure(t *testing.T) {
 
 		// Regression test for https://b.elv.sh/1126
 		That("fn f { body }; put $f~[body]").Puts(" body "),
+
+		// Docstrings.
+		That("put { 'doc'; put x }[doc]").Puts("doc"),
+		That("put { \"doc\"\n put x }[doc]").Puts("doc"),
+		That("f = { 'doc'; put x }; $f").Puts("x"),
+		That("fn f [a]{ 'doc'; put $a }; put $f~[doc]; f x").Puts("doc", "x"),
+		That("fn &doc=doc f { }; put $f~[doc]").Puts("doc"),
+		That("fn &doc=opt f { 'body'; nop }; put $f~[doc]").Puts("opt"),
+		// A string literal on its own is not a docstring.
+		That("put { put x }[doc]").Puts(""),
+		That("put { 'doc' }[doc]").Puts(""),
+		That("put { 'doc' x; put x }[doc]").Puts(""),
+		That("put { doc; put x }[doc]").Puts(""),
+		That("fn &foo=bar f { }").DoesNotCompile(),
+		That("fn &doc=$x f { }").DoesNotCompile(),
 	)
 }
+
+// Returns the error from calling a closure with the wrong number of arguments,
+// where the closure is defined by def in code.
+func argsMismatch(name, code, def string, low, high, actual int) error {
+	from := strings.Index(code, def)
+	return errs.ArityMismatch{
+		What:     "arguments here",
+		ValidLow: low, ValidHigh: high, Actual: actual,
+		FnName: name,
+		FnDef: diag.NewContext("[test]", code,
+			diag.Ranging{From: from, To: from + len(def)}),
+	}
+}
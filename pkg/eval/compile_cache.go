@@ -0,0 +1,128 @@
+package eval
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// The maximum number of entries in a compileCache.
+const compileCacheSize = 256
+
+// A cache of compilation results. Compiling a source only depends on the source
+// itself, the names in the builtin and global namespaces and the aliases, so a
+// hash of them is used as the key; adding names to the builtin namespace, for
+// instance, invalidates all the entries compiled without them.
+//
+// This speeds up evaluating the same code repeatedly within one Evaler, like
+// with the eval builtin, reloading modules, or checking code that hasn't
+// changed. When the cache is full, the least recently used entry is evicted.
+//
+// The cache is not persisted in the store or on disk, so it doesn't speed up
+// startup: compiled ops are trees of Go closures, which can't be serialized.
+// Persisting compilation results would need a serializable representation of
+// ops, which the compiler doesn't have.
+type compileCache struct {
+	mutex   sync.Mutex
+	entries map[compileCacheKey]*list.Element
+	// Elements are *compileCacheEntry values, the most recently used first.
+	lru *list.List
+}
+
+type compileCacheKey [sha256.Size]byte
+
+type compileCacheEntry struct {
+	key    compileCacheKey
+	result compileResult
+}
+
+type compileResult struct {
+	op  Op
+	err error
+	// Whether deprecations were written when compiling.
+	warned bool
+}
+
+func newCompileCache() *compileCache {
+	return &compileCache{
+		entries: make(map[compileCacheKey]*list.Element), lru: list.New()}
+}
+
+// Compiles a parsed tree with the given builtin and global namespaces and
+// aliases, reusing an earlier result if there is one. Deprecations are only
+// written to w when the tree is actually compiled; results compiled without
+// writing deprecations are not reused when w is not nil.
+func (c *compileCache) compile(b, g *Ns, aliases map[string]alias, tree parse.Tree, w io.Writer) (Op, error) {
+	key := makeCompileCacheKey(b, g, aliases, tree.Source)
+	c.mutex.Lock()
+	if e, ok := c.entries[key]; ok {
+		result := e.Value.(*compileCacheEntry).result
+		if result.warned || w == nil {
+			c.lru.MoveToFront(e)
+			c.mutex.Unlock()
+			return result.op, result.err
+		}
+	}
+	c.mutex.Unlock()
+
+	op, err := compile(b.static(), g.static(), aliases, tree, w)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	result := compileResult{op, err, w != nil}
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*compileCacheEntry).result = result
+		c.lru.MoveToFront(e)
+		return op, err
+	}
+	if len(c.entries) >= compileCacheSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compileCacheEntry).key)
+	}
+	c.entries[key] = c.lru.PushFront(&compileCacheEntry{key, result})
+	return op, err
+}
+
+func makeCompileCacheKey(b, g *Ns, aliases map[string]alias, src parse.Source) compileCacheKey {
+	h := sha256.New()
+	// Each string is prefixed with its length, so that different sequences of
+	// strings are always written differently.
+	var lenBuf [binary.MaxVarintLen64]byte
+	writeString := func(s string) {
+		h.Write(lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(s)))])
+		io.WriteString(h, s)
+	}
+	writeNames := func(names []string) {
+		h.Write(lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(names)))])
+		for _, name := range names {
+			writeString(name)
+		}
+	}
+	writeNames(b.names)
+	writeNames(g.names)
+	aliasNames := make([]string, 0, len(aliases))
+	for name := range aliases {
+		aliasNames = append(aliasNames, name)
+	}
+	sort.Strings(aliasNames)
+	writeNames(aliasNames)
+	for _, name := range aliasNames {
+		writeNames(aliases[name].words)
+	}
+	writeString(src.Name)
+	if src.IsFile {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+	writeString(src.Code)
+	var key compileCacheKey
+	h.Sum(key[:0])
+	return key
+}
@@ -0,0 +1,103 @@
+package eval
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/prog"
+)
+
+func TestCompileCache(t *testing.T) {
+	ev := NewEvaler()
+	// Compiles the code, and returns whether a new entry was added to the cache.
+	compiles := func(name, code string) bool {
+		t.Helper()
+		tree, err := parse.Parse(parse.Source{Name: name, Code: code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := len(ev.compileCache.entries)
+		_, err = ev.compile(tree, ev.Global, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(ev.compileCache.entries) > before
+	}
+
+	if !compiles("[test]", "put x") {
+		t.Errorf("compiling new code did not add an entry")
+	}
+	if compiles("[test]", "put x") {
+		t.Errorf("compiling the same code again did not reuse the result")
+	}
+	if !compiles("[test]", "put y") {
+		t.Errorf("compiling different code reused the result")
+	}
+	if !compiles("[other]", "put x") {
+		t.Errorf("compiling code with a different name reused the result")
+	}
+	ev.Global = NsBuilder{"x": vars.FromInit("")}.Ns()
+	if !compiles("[test]", "put x") {
+		t.Errorf("compiling with a different global namespace reused the result")
+	}
+	ev.Builtin.Append(NsBuilder{"new-builtin": vars.FromInit("")}.Ns())
+	if !compiles("[test]", "put x") {
+		t.Errorf("compiling after the builtin namespace changed reused the result")
+	}
+	ev.SetAlias("x", "echo")
+	if !compiles("[test]", "put x") {
+		t.Errorf("compiling after an alias was defined reused the result")
+	}
+}
+
+func TestCompileCache_WritesDeprecationsWhenRequested(t *testing.T) {
+	restore := prog.SetShowDeprecations(true)
+	defer restore()
+	ev := NewEvaler()
+	tree, _ := parse.Parse(parse.Source{Name: "[test]", Code: "ord a"})
+
+	ev.compile(tree, ev.Global, nil)
+	var buf bytes.Buffer
+	ev.compile(tree, ev.Global, &buf)
+
+	if buf.Len() == 0 {
+		t.Errorf("deprecation not written")
+	}
+}
+
+func TestCompileCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCompileCache()
+	b, g := new(Ns), new(Ns)
+	src := func(i int) parse.Source {
+		code := "put " + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		return parse.Source{Name: "[test]", Code: code}
+	}
+	compile := func(i int) {
+		tree, _ := parse.Parse(src(i))
+		c.compile(b, g, nil, tree, nil)
+	}
+	cached := func(i int) bool {
+		_, ok := c.entries[makeCompileCacheKey(b, g, nil, src(i))]
+		return ok
+	}
+
+	for i := 0; i < compileCacheSize; i++ {
+		compile(i)
+	}
+	// Use the first entry, so that the second one becomes the least recently
+	// used.
+	compile(0)
+	compile(compileCacheSize)
+
+	if len(c.entries) != compileCacheSize {
+		t.Errorf("got %d entries, want %d", len(c.entries), compileCacheSize)
+	}
+	if !cached(0) {
+		t.Errorf("recently used entry evicted")
+	}
+	if cached(1) {
+		t.Errorf("least recently used entry not evicted")
+	}
+}
@@ -1,10 +1,16 @@
 package eval
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/eval/errs"
@@ -59,6 +65,9 @@ type chunkOp struct {
 	subops []effectOp
 }
 
+// DefaultPipelineBufferSize is the default value of $pipeline-buffer-size.
+const DefaultPipelineBufferSize = 32
+
 func (op chunkOp) exec(fm *Frame) error {
 	for _, subop := range op.subops {
 		err := subop.exec(fm)
@@ -81,10 +90,12 @@ func (cp *compiler) pipelineOp(n *parse.Pipeline) effectOp {
 	return &pipelineOp{n.Range(), n.Background, parse.SourceText(n), formOps}
 }
 
+// Compiles pipelines. When a pipeline has a compilation error, the error is
+// recorded and compilation continues with the next pipeline.
 func (cp *compiler) pipelineOps(ns []*parse.Pipeline) []effectOp {
 	ops := make([]effectOp, len(ns))
 	for i, n := range ns {
-		ops[i] = cp.pipelineOp(n)
+		cp.recoverErrors(func() { ops[i] = cp.pipelineOp(n) })
 	}
 	return ops
 }
@@ -96,16 +107,17 @@ type pipelineOp struct {
 	subops []effectOp
 }
 
-const pipelineChanBufferSize = 32
-
 func (op *pipelineOp) exec(fm *Frame) error {
 	if fm.IsInterrupted() {
 		return fm.errorp(op, ErrInterrupted)
 	}
+	if err := fm.checkPipelineDepth(); err != nil {
+		return fm.errorp(op, err)
+	}
 
 	if op.bg {
 		fm = fm.fork("background job" + op.source)
-		fm.intCh = nil
+		fm.ctx = context.Background()
 		fm.background = true
 		fm.Evaler.state.addNumBgJobs(1)
 
@@ -122,11 +134,13 @@ func (op *pipelineOp) exec(fm *Frame) error {
 	errors := make([]*Exception, nforms)
 
 	var nextIn *Port
+	bufferSize := fm.Evaler.state.getPipelineBufferSize()
 
 	// For each form, create a dedicated evalCtx and run asynchronously
 	for i, formOp := range op.subops {
 		hasChanInput := i > 0
 		newFm := fm.fork("[form op]")
+		newFm.pipelineDepth++
 		if i > 0 {
 			newFm.ports[0] = nextIn
 		}
@@ -138,7 +152,7 @@ func (op *pipelineOp) exec(fm *Frame) error {
 			if e != nil {
 				return fm.errorpf(op, "failed to create pipe: %s", e)
 			}
-			ch := make(chan interface{}, pipelineChanBufferSize)
+			ch := make(chan interface{}, bufferSize)
 			newFm.ports[1] = &Port{
 				File: writer, Chan: ch, CloseFile: true, CloseChan: true}
 			nextIn = &Port{
@@ -216,14 +230,27 @@ func (cp *compiler) formOp(n *parse.Form) effectOp {
 	var argOps []valuesOp
 
 	if n.Head != nil {
+		implicitIt := false
+		// Arguments from the body of an alias, prepended to the arguments of
+		// the form.
+		var aliasArgOps []valuesOp
 		headStr, ok := oneString(n.Head)
 		if ok {
+			if a, isAlias := cp.aliases[headStr]; isAlias {
+				// The head of the alias is resolved without expanding aliases
+				// again, so that an alias can call a command of the same name.
+				headStr = a.words[0]
+				for _, word := range a.words[1:] {
+					aliasArgOps = append(aliasArgOps, literalValues(n.Head, word))
+				}
+			}
 			special, fnRef := resolveCmdHeadInternally(cp, headStr, n.Head)
 			switch {
 			case special != nil:
 				specialOp = special(cp, n)
 			case fnRef != nil:
-				headOp = variableOp{n.Head.Range(), false, headStr + FnSuffix, fnRef}
+				headOp = variableOp{n.Head.Range(), false, headStr + FnSuffix, fnRef, false}
+				implicitIt = fnRef.scope == builtinScope && implicitItCmds[headStr]
 			default:
 				headOp = literalValues(n.Head, ExternalCmd{headStr})
 			}
@@ -232,7 +259,16 @@ func (cp *compiler) formOp(n *parse.Form) effectOp {
 			// expression.
 			headOp = cp.compoundOp(n.Head)
 		}
-		argOps = cp.compoundOps(n.Args)
+		switch {
+		case specialOp != nil:
+			// Arguments of special forms are compiled by the special forms
+			// themselves; compiling them again would report errors in them
+			// twice.
+		case implicitIt:
+			argOps = append(aliasArgOps, cp.implicitItArgOps(n.Args)...)
+		default:
+			argOps = append(aliasArgOps, cp.compoundOps(n.Args)...)
+		}
 	} else {
 		// Assignment form.
 		lhs := cp.parseCompoundLValues(n.Vars)
@@ -247,7 +283,10 @@ func (cp *compiler) formOp(n *parse.Form) effectOp {
 		spaceyAssignOp = &assignOp{n.Range(), lhs, rhs}
 	}
 
-	optsOp := cp.mapPairs(n.Opts)
+	var optsOp *mapPairsOp
+	if specialOp == nil {
+		optsOp = cp.mapPairs(n.Opts)
+	}
 	redirOps := cp.redirOps(n.Redirs)
 	// TODO: n.ErrorRedir
 
@@ -284,6 +323,8 @@ func (op *formOp) exec(fm *Frame) (errRet error) {
 		// Save variables.
 		var saveVars []vars.Var
 		var saveVals []interface{}
+		// Whether each variable was unset, for variables that can be unset.
+		var saveUnset []bool
 		for _, lv := range op.tempLValues {
 			variable, err := derefLValue(fm, lv)
 			if err != nil {
@@ -300,33 +341,41 @@ func (op *formOp) exec(fm *Frame) (errRet error) {
 			}
 			val := v.Get()
 			saveVals = append(saveVals, val)
+			u, ok := v.(vars.Unsettable)
+			saveUnset = append(saveUnset, ok && !u.IsSet())
 			logger.Printf("saved %s = %s", v, val)
 		}
-		// Do assignment.
-		for _, subop := range op.assignmentOps {
-			err := subop.exec(fm)
-			if err != nil {
-				return err
-			}
-		}
 		// Defer variable restoration. Will be executed even if an error
-		// occurs when evaling other part of the form.
+		// occurs when doing the assignment or evaling other part of the form.
+		// The first error is kept.
 		defer func() {
 			for i, v := range saveVars {
-				val := saveVals[i]
-				if val == nil {
-					// TODO(xiaq): Old value is nonexistent. We should delete
-					// the variable. However, since the compiler now doesn't
-					// delete it, we don't delete it in the evaler either.
-					val = ""
+				var err error
+				if saveUnset[i] {
+					err = v.(vars.Unsettable).Unset()
+				} else {
+					val := saveVals[i]
+					if val == nil {
+						// TODO(xiaq): Old value is nonexistent. We should delete
+						// the variable. However, since the compiler now doesn't
+						// delete it, we don't delete it in the evaler either.
+						val = ""
+					}
+					err = v.Set(val)
 				}
-				err := v.Set(val)
-				if err != nil {
+				if err != nil && errRet == nil {
 					errRet = err
 				}
-				logger.Printf("restored %s = %s", v, val)
+				logger.Printf("restored %s = %s", v, saveVals[i])
 			}
 		}()
+		// Do assignment.
+		for _, subop := range op.assignmentOps {
+			err := subop.exec(fm)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// redirs
@@ -336,6 +385,16 @@ func (op *formOp) exec(fm *Frame) (errRet error) {
 			return err
 		}
 	}
+	if len(op.redirOps) > 0 {
+		defer func() {
+			err := fm.closeValuesRedirs()
+			// The result of a successful command may be OK, a non-nil
+			// *Exception.
+			if err != nil && Reason(errRet) == nil {
+				errRet = fm.errorp(op, err)
+			}
+		}()
+	}
 
 	if op.specialOp != nil {
 		return op.specialOp.exec(fm)
@@ -378,8 +437,18 @@ func (op *formOp) exec(fm *Frame) (errRet error) {
 	}
 
 	if headFn != nil {
-		fm.traceback = fm.addTraceback(op)
+		fm.traceExec(op, headFn, args, convertedOpts)
+		fm.traceback = fm.addTraceback(op, fnName(headFn))
+		tracing := fm.Evaler.tracing()
+		var start time.Time
+		if tracing {
+			start = time.Now()
+		}
 		err := headFn.Call(fm, args, convertedOpts)
+		if tracing {
+			fm.Evaler.trace(TraceEvent{Kind: TraceCall, Stack: fm.traceback,
+				Start: start, Duration: time.Since(start), Err: err})
+		}
 		if _, ok := err.(*Exception); ok {
 			return err
 		}
@@ -453,7 +522,7 @@ func (cp *compiler) redirOp(n *parse.Redir) effectOp {
 		// TODO: Record and get redirection sign position
 		cp.errorpf(n, "bad redirection sign")
 	}
-	return &redirOp{n.Range(), dstOp, cp.compoundOp(n.Right), n.RightIsFd, n.Mode, flag}
+	return &redirOp{n.Range(), dstOp, cp.compoundOp(n.Right), n.RightIsFd, n.Mode, n.Part, flag}
 }
 
 func (cp *compiler) redirOps(ns []*parse.Redir) []effectOp {
@@ -485,6 +554,7 @@ type redirOp struct {
 	srcOp   valuesOp
 	srcIsFd bool
 	mode    parse.RedirMode
+	part    parse.PortPart
 	flag    int
 }
 
@@ -529,7 +599,6 @@ func (op *redirOp) exec(fm *Frame) error {
 	}
 
 	fm.growPorts(dst + 1)
-	fm.ports[dst].Close()
 
 	if op.srcIsFd {
 		src, err := evalForFd(fm, op.srcOp, true, "redirection source")
@@ -539,11 +608,11 @@ func (op *redirOp) exec(fm *Frame) error {
 		switch {
 		case src == -1:
 			// close
-			fm.ports[dst] = &Port{}
+			op.setPort(fm, dst, &Port{})
 		case src >= len(fm.ports) || fm.ports[src] == nil:
 			return fm.errorp(op, invalidFD{src})
 		default:
-			fm.ports[dst] = fm.ports[src].Fork()
+			op.setPort(fm, dst, fm.ports[src].Fork())
 		}
 		return nil
 	}
@@ -551,17 +620,23 @@ func (op *redirOp) exec(fm *Frame) error {
 	if err != nil {
 		return fm.errorp(op, err)
 	}
+	var f *os.File
+	closeFile := false
 	switch src := src.(type) {
 	case string:
-		f, err := os.OpenFile(src, op.flag, defaultFileRedirPerm)
+		if isWriteFlag(op.flag) {
+			if err := fm.CheckRestriction(RestrictFileWrites); err != nil {
+				return fm.errorp(op, err)
+			}
+		}
+		f, err = os.OpenFile(src, op.flag, defaultFileRedirPerm)
 		if err != nil {
 			return fm.errorpf(op, "failed to open file %s: %s", vals.Repr(src, vals.NoPretty), err)
 		}
-		fm.ports[dst] = &Port{File: f, CloseFile: true, Chan: chanForFileRedir(op.mode)}
+		closeFile = true
 	case vals.File:
-		fm.ports[dst] = &Port{File: src, CloseFile: false, Chan: chanForFileRedir(op.mode)}
+		f = src
 	case vals.Pipe:
-		var f *os.File
 		switch op.mode {
 		case parse.Read:
 			f = src.ReadEnd
@@ -570,15 +645,188 @@ func (op *redirOp) exec(fm *Frame) error {
 		default:
 			return fm.errorpf(op, "can only use < or > with pipes")
 		}
-		fm.ports[dst] = &Port{File: f, CloseFile: false, Chan: chanForFileRedir(op.mode)}
+	case RedirTarget:
+		f, err = src.RedirFile(op.mode)
+		if err != nil {
+			return fm.errorp(op, err)
+		}
 	default:
 		return fm.errorp(op.srcOp, errs.BadValue{
 			What:  "redirection source",
-			Valid: "string, file or pipe", Actual: vals.Kind(src)})
+			Valid: "string, file, pipe, coprocess or connection", Actual: vals.Kind(src)})
+	}
+	if op.part != parse.ValuesOnly {
+		op.setPort(fm, dst, &Port{File: f, CloseFile: closeFile, Chan: chanForFileRedir(op.mode)})
+		return nil
 	}
+	port, err := valuesPortForFile(f, closeFile, op.mode)
+	if err != nil {
+		return fm.errorp(op, err)
+	}
+	op.setPort(fm, dst, port)
 	return nil
 }
 
+// RedirTarget may be implemented by values other than strings, files and pipes
+// to allow them to be used as the source of redirections, like coprocesses.
+type RedirTarget interface {
+	// RedirFile returns the file to use for a redirection with the given mode,
+	// or an error if the mode is not supported. The file is not closed after
+	// the redirection.
+	RedirFile(mode parse.RedirMode) (*os.File, error)
+}
+
+// Replaces the port at dst with p, or only its value or byte part if the
+// redirection applies to one part. The parts that are replaced are closed.
+//
+// When only one part of an input port is redirected, the other part is replaced
+// with an empty one, since commands like all and only-values read both parts
+// until they end, and would otherwise wait for the rest of the original input.
+func (op *redirOp) setPort(fm *Frame, dst int, p *Port) {
+	old := fm.ports[dst]
+	if old == nil {
+		old = &Port{}
+	}
+	valuesPart := func(p *Port) *Port {
+		return &Port{Chan: p.Chan, CloseChan: p.CloseChan, redirDone: p.redirDone}
+	}
+	bytesPart := func(p *Port) *Port {
+		return &Port{File: p.File, CloseFile: p.CloseFile}
+	}
+	if op.mode == parse.Read && op.part != parse.WholePort {
+		old.Close()
+		old = DevNullClosedChan
+	}
+	switch op.part {
+	case parse.ValuesOnly:
+		valuesPart(old).Close()
+		bytesPart(p).Close()
+		fm.ports[dst] = &Port{File: old.File, CloseFile: old.CloseFile,
+			Chan: p.Chan, CloseChan: p.CloseChan, redirDone: p.redirDone}
+	case parse.BytesOnly:
+		bytesPart(old).Close()
+		valuesPart(p).Close()
+		fm.ports[dst] = &Port{File: p.File, CloseFile: p.CloseFile,
+			Chan: old.Chan, CloseChan: old.CloseChan, redirDone: old.redirDone}
+	default:
+		old.Close()
+		fm.ports[dst] = p
+	}
+}
+
+var errValuesRedirMode = errors.New("can only use <, > or >> when redirecting values")
+
+// Returns a port whose value channel is connected to f, with values encoded as
+// JSON, one per line. Values are read as they are consumed, and written as they
+// arrive. A value that can't be decoded or encoded is an error, returned when
+// the port is closed with closeValuesRedirs.
+func valuesPortForFile(f *os.File, closeFile bool, mode parse.RedirMode) (*Port, error) {
+	closeIfOwned := func() {
+		if closeFile {
+			f.Close()
+		}
+	}
+	switch mode {
+	case parse.Read:
+		ch := make(chan interface{})
+		// Closed when the port is closed, possibly before all the values are
+		// read.
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		var err error
+		go func() {
+			// Close done before ch, so that the error is known when the
+			// reader sees the end of the values.
+			defer close(ch)
+			defer close(done)
+			defer closeIfOwned()
+			err = decodeValuesRedir(json.NewDecoder(f), ch, stop)
+		}()
+		return &Port{Chan: ch, redirDone: func() error {
+			close(stop)
+			select {
+			case <-done:
+				return err
+			default:
+				// The values not read yet don't matter.
+				return nil
+			}
+		}}, nil
+	case parse.Write, parse.Append:
+		ch := make(chan interface{})
+		done := make(chan struct{})
+		var err error
+		go func() {
+			defer close(done)
+			defer closeIfOwned()
+			for v := range ch {
+				if err != nil {
+					// Keep draining the channel after an error.
+					continue
+				}
+				var buf bytes.Buffer
+				err = encodeJSON(&buf, v, toJSONOpts{})
+				if err == nil {
+					buf.WriteByte('\n')
+					_, err = f.Write(buf.Bytes())
+				}
+			}
+		}()
+		return &Port{Chan: ch, CloseChan: true, redirDone: func() error {
+			<-done
+			return err
+		}}, nil
+	default:
+		closeIfOwned()
+		return nil, errValuesRedirMode
+	}
+}
+
+// Like decodeJSONStream, but stops when stop is closed.
+func decodeValuesRedir(dec *json.Decoder, out chan<- interface{}, stop <-chan struct{}) error {
+	dec.UseNumber()
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		converted, err := fromJSONInterface(v)
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- converted:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Closes the value channels of the ports that serve values redirections, and
+// returns the first error from them. Ports closed this way are not closed
+// again by Frame.Close.
+func (fm *Frame) closeValuesRedirs() error {
+	var firstErr error
+	for _, p := range fm.ports {
+		if p == nil || p.redirDone == nil {
+			continue
+		}
+		if p.CloseChan {
+			close(p.Chan)
+		}
+		err := p.redirDone()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		p.CloseChan, p.redirDone = false, nil
+	}
+	return firstErr
+}
+
 func evalForFd(fm *Frame, op valuesOp, closeOK bool, what string) (int, error) {
 	value, err := evalForValue(fm, op, what)
 	if err != nil {
@@ -36,6 +36,15 @@ func TestCompileEffect(t *testing.T) {
 		That(`put 233 42 19 | each [x]{+ $x 10}`).Puts(243.0, 52.0, 29.0),
 		// Pipeline draining.
 		That(`range 100 | put x`).Puts("x"),
+		// Values beyond the buffer size wait for slow readers, and are never
+		// discarded.
+		That(`range 100 | { sleep 0.1; count }`).Puts("100"),
+		That(`pipeline-buffer-size = 1; range 100 | { sleep 0.1; count }`).
+			Puts("100"),
+		That(`pipeline-buffer-size = 100; range 100 | slurp`).Puts(""),
+		That(`put $pipeline-buffer-size`).Puts("32"),
+		That(`pipeline-buffer-size = 0`).Throws(AnyError),
+		That(`pipeline-buffer-size = abc`).Throws(AnyError),
 		// Background pipeline.
 		That(
 			"notify-bg-job-success = $false",
@@ -109,6 +118,17 @@ func TestCompileEffect(t *testing.T) {
 		That("li=[foo bar] for x $li { put $x }").Puts("foo", "bar"),
 		// Spacey assignment with temporary assignment
 		That("x = 1; x=2 y = (+ 1 $x); put $x $y").Puts("1", 3.0),
+		// Variables are restored when the command throws an exception.
+		That("x = 1; try { x=2 fail bad } except { }; put $x").Puts("1"),
+		// Variables are restored when a later temporary assignment fails.
+		That("x = 1; try { x=2 y=[][1] nop } except { }; put $x").Puts("1"),
+		// Environment variables that were unset are unset again.
+		That("del E:TEMP_ASSIGN; E:TEMP_ASSIGN=foo get-env TEMP_ASSIGN; has-env TEMP_ASSIGN").
+			Puts("foo", false),
+		That("E:TEMP_ASSIGN=bar; E:TEMP_ASSIGN=foo nop; get-env TEMP_ASSIGN").
+			Puts("bar"),
+		That("del E:TEMP_ASSIGN; E:list:TEMP_ASSIGN=[a b] nop; has-env TEMP_ASSIGN").
+			Puts(false),
 
 		// Concurrently creating a new variable and accessing existing variable.
 		// Run with "go test -race".
@@ -173,6 +193,42 @@ func TestCompileEffect(t *testing.T) {
 		That("echo abc > bytes", "each $echo~ < bytes").Prints("abc\n"),
 		That("echo def > bytes", "only-values < bytes | count").Puts("0"),
 
+		// Redirecting only values or only bytes.
+		That("{ put foo [&k=v]; echo bar } >&values out.json", "cat out.json").
+			Prints("bar\n\"foo\"\n{\"k\":\"v\"}\n"),
+		That("put foo >&values out.json", "put bar >>&values out.json",
+			"only-values <&values out.json").Puts("foo", "bar"),
+		That("{ put foo; echo bar } >&bytes out", "slurp < out").
+			Puts("foo", "bar\n"),
+		That("{ put foo; echo bar } >&values &2 | slurp").Puts("bar\n"),
+		That("{ put foo; echo bar } >&bytes &- ").Puts("foo"),
+		// Later redirections of the same part replace earlier ones.
+		That("put foo >&values out.json >&values out2.json",
+			"slurp < out.json", "slurp < out2.json").Puts("", "\"foo\"\n"),
+		That("echo '[1' > bad.json", "only-values <&values bad.json").
+			Throws(AnyError),
+		That("echo '\"foo\" [1' > bad.json", "only-values <&values bad.json").
+			Puts("foo").Throws(AnyError),
+		// Values are read as they are consumed; this would block forever, since
+		// the write end of the pipe is only closed after the first value is
+		// read.
+		That("p = (pipe)", "echo '\"foo\"' > $p",
+			"only-values <&values $p | each [x]{ put $x; pwclose $p }",
+			"prclose $p").Puts("foo"),
+		// Values that can't be encoded are errors; values before them are
+		// still written.
+		That("put foo [&[k]=v] bar >&values out.json").Throws(AnyError),
+		That("try { put foo [&[k]=v] bar >&values out.json } except { }",
+			"slurp < out.json").Puts("\"foo\"\n"),
+		That("put foo <>&values out.json").Throws(AnyError),
+		// Redirecting one part of the input replaces the other part with an
+		// empty one; this would block forever if the byte input from the pipe
+		// was still read.
+		That("p = (pipe)", "put foo >&values out.json",
+			"{ only-values <&values out.json; all <&values out.json } < $p",
+			"pwclose $p; prclose $p").Puts("foo", "foo"),
+		That("echo foo > out", "put x y | count <&bytes out").Puts("1"),
+
 		// Invalid redirection destination.
 		That("echo []> test").Throws(
 			errs.BadValue{
@@ -189,7 +245,7 @@ func TestCompileEffect(t *testing.T) {
 		That("echo > []").Throws(
 			errs.BadValue{
 				What:  "redirection source",
-				Valid: "string, file or pipe", Actual: "list"},
+				Valid: "string, file, pipe, coprocess or connection", Actual: "list"},
 			"[]"),
 	)
 }
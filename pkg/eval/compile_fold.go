@@ -0,0 +1,159 @@
+package eval
+
+import (
+	"errors"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// This file implements constant folding. When all the operands of a value
+// operation are known at compile time, the compiler evaluates the operation
+// right away and replaces it with a literalValuesOp, so that it doesn't need to
+// be evaluated again every time the code runs. Folding is done as the ops are
+// built, so it does not need a separate walk of the op tree.
+//
+// An operation is only folded when evaluating it can't fail and has no side
+// effects, so that folding never changes the behavior of the code; everything
+// else is left to runtime. In particular, glob patterns are never folded, since
+// globbing depends on the filesystem.
+
+// Returns the values of op if it is a literal whose values can be used as
+// operands of folded operations.
+func constValues(op valuesOp) ([]interface{}, bool) {
+	lit, ok := op.(literalValuesOp)
+	if !ok {
+		return nil, false
+	}
+	for _, v := range lit.values {
+		if _, isGlob := v.(GlobPattern); isGlob {
+			return nil, false
+		}
+	}
+	return lit.values, true
+}
+
+// Returns the values of all ops if they are all constant.
+func allConstValues(ops []valuesOp) ([][]interface{}, bool) {
+	vss := make([][]interface{}, len(ops))
+	for i, op := range ops {
+		vs, ok := constValues(op)
+		if !ok {
+			return nil, false
+		}
+		vss[i] = vs
+	}
+	return vss, true
+}
+
+// Folds a compound expression without a leading tilde, like a'b'{c,d}.
+func foldCompound(r diag.Ranging, subops []valuesOp) valuesOp {
+	vss, ok := allConstValues(subops)
+	if !ok {
+		return nil
+	}
+	vs := vss[0]
+	for _, us := range vss[1:] {
+		var err error
+		vs, err = outerProduct(vs, us, concatStrings)
+		if err != nil {
+			return nil
+		}
+	}
+	return literalValuesOp{r, vs}
+}
+
+// Only strings are concatenated at compile time; concatenating other values
+// may fail or depend on the values in more complex ways.
+func concatStrings(lhs, rhs interface{}) (interface{}, error) {
+	l, lok := lhs.(string)
+	r, rok := rhs.(string)
+	if !lok || !rok {
+		return nil, errNotFoldable
+	}
+	return l + r, nil
+}
+
+var errNotFoldable = errors.New("not foldable")
+
+// Folds a sequence of values, like the elements of a braced list or an array.
+func foldSeq(r diag.Ranging, subops []valuesOp) valuesOp {
+	vss, ok := allConstValues(subops)
+	if !ok {
+		return nil
+	}
+	var values []interface{}
+	for _, vs := range vss {
+		values = append(values, vs...)
+	}
+	return literalValuesOp{r, values}
+}
+
+// Folds a list literal, like [a b c].
+func foldList(r diag.Ranging, subops []valuesOp) valuesOp {
+	vss, ok := allConstValues(subops)
+	if !ok {
+		return nil
+	}
+	list := vals.EmptyList
+	for _, vs := range vss {
+		for _, v := range vs {
+			list = list.Cons(v)
+		}
+	}
+	return literalValues(r, list)
+}
+
+// Folds a map literal, like [&a=b].
+func foldMap(r diag.Ranging, pairsOp *mapPairsOp) valuesOp {
+	keyss, ok := allConstValues(pairsOp.keysOps)
+	if !ok {
+		return nil
+	}
+	valuess, ok := allConstValues(pairsOp.valuesOps)
+	if !ok {
+		return nil
+	}
+	m := vals.EmptyMap
+	for i, keys := range keyss {
+		values := valuess[i]
+		if len(keys) != len(values) {
+			// Leave the error to runtime.
+			return nil
+		}
+		for j, key := range keys {
+			m = m.Assoc(key, values[j])
+		}
+	}
+	return literalValues(r, m)
+}
+
+// Folds an indexing expression, like [a b c][0].
+func foldIndexing(r diag.Ranging, headOp valuesOp, indexOps []valuesOp) valuesOp {
+	vs, ok := constValues(headOp)
+	if !ok {
+		return nil
+	}
+	indicess, ok := allConstValues(indexOps)
+	if !ok {
+		return nil
+	}
+	for _, indices := range indicess {
+		newvs := make([]interface{}, 0, len(vs)*len(indices))
+		for _, v := range vs {
+			for _, index := range indices {
+				if vals.CheckDeprecatedIndex(v, index) != "" {
+					// Leave the deprecation warning to runtime.
+					return nil
+				}
+				result, err := vals.Index(v, index)
+				if err != nil {
+					return nil
+				}
+				newvs = append(newvs, result)
+			}
+		}
+		vs = newvs
+	}
+	return literalValuesOp{r, vs}
+}
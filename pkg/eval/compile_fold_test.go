@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+var foldTests = []struct {
+	code string
+	// The values of the folded op, or nil if the op should not be folded.
+	want []interface{}
+}{
+	{"a'b'\"c\"", []interface{}{"abc"}},
+	{"a{b,c}d", []interface{}{"abd", "acd"}},
+	{"{a,b}{c,d}", []interface{}{"ac", "ad", "bc", "bd"}},
+	{"[a [b c]]", []interface{}{vals.MakeList("a", vals.MakeList("b", "c"))}},
+	{"[&a=b &c]", []interface{}{vals.MakeMap("a", "b", "c", true)}},
+	{"[a b c][1]", []interface{}{"b"}},
+	{"[&a=[b c]][a][0]", []interface{}{"b"}},
+
+	// Not folded.
+	{"a$x", nil},
+	{"[$x]", nil},
+	{"[&$x=a]", nil},
+	{"~/a", nil},
+	{"a*", nil},
+	{"[a *]", nil},
+	{"(put a)b", nil},
+	// Errors are left to runtime.
+	{"[&{a,b}=c]", nil},
+	{"[a][1]", nil},
+	{"[a b c][0:1]", nil},
+}
+
+func TestConstantFolding(t *testing.T) {
+	for _, test := range foldTests {
+		t.Run(test.code, func(t *testing.T) {
+			op := compileCompoundForTest(t, test.code)
+			lit, folded := op.(literalValuesOp)
+			if test.want == nil {
+				if folded {
+					t.Errorf("got folded op with values %v, want not folded", lit.values)
+				}
+				return
+			}
+			if !folded {
+				t.Fatalf("got %T, want folded op", op)
+			}
+			if !vals.Equal(vals.MakeList(lit.values...), vals.MakeList(test.want...)) {
+				t.Errorf("got values %v, want %v", lit.values, test.want)
+			}
+		})
+	}
+}
+
+func compileCompoundForTest(t *testing.T, code string) valuesOp {
+	t.Helper()
+	n := &parse.Compound{}
+	err := parse.ParseAs(parse.Source{Name: "[test]", Code: code}, n, nil)
+	if err != nil {
+		t.Fatalf("parse %q: %v", code, err)
+	}
+	g := &staticNs{}
+	g.add("x")
+	cp := &compiler{
+		builtin:  NewEvaler().Builtin.static(),
+		scopes:   []*staticNs{g},
+		captures: []*staticUpNs{new(staticUpNs)},
+		srcMeta:  parse.Source{Name: "[test]", Code: code},
+		strict:   []bool{false},
+	}
+	return cp.compoundOp(n)
+}
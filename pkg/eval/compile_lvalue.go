@@ -63,10 +63,10 @@ func (cp *compiler) parseIndexingLValue(n *parse.Indexing) lvaluesGroup {
 			segs := SplitQNameSegs(qname)
 			if len(segs) == 1 {
 				// Unqualified name - implicit local
-				ref = &varRef{localScope, cp.thisScope().addInner(segs[0]), nil}
+				ref = &varRef{scope: localScope, index: cp.thisScope().addInner(segs[0])}
 			} else if len(segs) == 2 && (segs[0] == "local:" || segs[0] == ":") {
 				// Qualified local name
-				ref = &varRef{localScope, cp.thisScope().addInner(segs[1]), nil}
+				ref = &varRef{scope: localScope, index: cp.thisScope().addInner(segs[1])}
 			} else {
 				cp.errorpf(n, "cannot create variable $%s; new variables can only be created in the local scope", qname)
 			}
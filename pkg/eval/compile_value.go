@@ -38,7 +38,13 @@ func (cp *compiler) compoundOp(n *parse.Compound) valuesOp {
 		indexings = indexings[1:]
 	}
 
-	return compoundOp{n.Range(), tilde, cp.indexingOps(indexings)}
+	subops := cp.indexingOps(indexings)
+	if !tilde {
+		if op := foldCompound(n.Range(), subops); op != nil {
+			return op
+		}
+	}
+	return compoundOp{n.Range(), tilde, subops}
 }
 
 type loneTildeOp struct{ diag.Ranging }
@@ -102,10 +108,17 @@ func (op compoundOp) exec(fm *Frame) ([]interface{}, error) {
 	}
 	if hasGlob {
 		newvs := make([]interface{}, 0, len(vs))
+		// A braced list can expand a wildcard pattern into several patterns,
+		// like in *.{go,md}. It is only an error when none of them matches.
+		nGlobs, nNoMatches := 0, 0
 		for _, v := range vs {
 			if gp, ok := v.(GlobPattern); ok {
+				nGlobs++
 				results, err := doGlob(gp, fm.Interrupts())
-				if err != nil {
+				if err == ErrWildcardNoMatch {
+					nNoMatches++
+					continue
+				} else if err != nil {
 					return nil, fm.errorp(op, err)
 				}
 				newvs = append(newvs, results...)
@@ -113,6 +126,9 @@ func (op compoundOp) exec(fm *Frame) ([]interface{}, error) {
 				newvs = append(newvs, v)
 			}
 		}
+		if nNoMatches > 0 && nNoMatches == nGlobs {
+			return nil, fm.errorp(op, ErrWildcardNoMatch)
+		}
 		vs = newvs
 	}
 	return vs, nil
@@ -194,7 +210,7 @@ func doTilde(v interface{}) (interface{}, error) {
 }
 
 func (cp *compiler) arrayOp(n *parse.Array) valuesOp {
-	return seqValuesOp{n.Range(), cp.compoundOps(n.Compounds)}
+	return cp.seqValuesOp(n.Range(), cp.compoundOps(n.Compounds))
 }
 
 func (cp *compiler) arrayOps(ns []*parse.Array) []valuesOp {
@@ -209,7 +225,11 @@ func (cp *compiler) indexingOp(n *parse.Indexing) valuesOp {
 	if len(n.Indicies) == 0 {
 		return cp.primaryOp(n.Head)
 	}
-	return &indexingOp{n.Range(), cp.primaryOp(n.Head), cp.arrayOps(n.Indicies)}
+	headOp, indexOps := cp.primaryOp(n.Head), cp.arrayOps(n.Indicies)
+	if op := foldIndexing(n.Range(), headOp, indexOps); op != nil {
+		return op
+	}
+	return &indexingOp{n.Range(), headOp, indexOps}
 }
 
 func (cp *compiler) indexingOps(ns []*parse.Indexing) []valuesOp {
@@ -245,7 +265,8 @@ func (op *indexingOp) exec(fm *Frame) ([]interface{}, error) {
 				}
 				deprecation := vals.CheckDeprecatedIndex(v, index)
 				if deprecation != "" {
-					fm.Deprecate(deprecation, diag.NewContext(fm.srcMeta.Name, fm.srcMeta.Code, indexOp))
+					fm.Deprecate(Deprecation{Message: deprecation, Level: 15},
+						fm.srcMeta.Context(indexOp))
 				}
 				newvs = append(newvs, result)
 			}
@@ -265,7 +286,7 @@ func (cp *compiler) primaryOp(n *parse.Primary) valuesOp {
 		if ref == nil {
 			cp.errorpf(n, "variable $%s not found", qname)
 		}
-		return &variableOp{n.Range(), sigil != "", qname, ref}
+		return &variableOp{n.Range(), sigil != "", qname, ref, cp.isStrict()}
 	case parse.Wildcard:
 		seg, err := wildcardToSegment(parse.SourceText(n))
 		if err != nil {
@@ -283,19 +304,57 @@ func (cp *compiler) primaryOp(n *parse.Primary) valuesOp {
 	case parse.OutputCapture:
 		return outputCaptureOp{n.Range(), cp.chunkOp(n.Chunk)}
 	case parse.List:
-		return listOp{n.Range(), cp.compoundOps(n.Elements)}
+		subops := cp.compoundOps(n.Elements)
+		if op := foldList(n.Range(), subops); op != nil {
+			return op
+		}
+		return listOp{n.Range(), subops}
 	case parse.Lambda:
 		return cp.lambda(n)
 	case parse.Map:
-		return mapOp{n.Range(), cp.mapPairs(n.MapPairs)}
+		pairsOp := cp.mapPairs(n.MapPairs)
+		if op := foldMap(n.Range(), pairsOp); op != nil {
+			return op
+		}
+		return mapOp{n.Range(), pairsOp}
 	case parse.Braced:
-		return seqValuesOp{n.Range(), cp.compoundOps(n.Braced)}
+		return cp.seqValuesOp(n.Range(), cp.compoundOps(n.Braced))
 	default:
 		cp.errorpf(n, "bad PrimaryType; parser bug")
 		return literalValues(n, parse.SourceText(n))
 	}
 }
 
+// Commands whose lambda arguments without an argument list take one argument
+// named "it".
+var implicitItCmds = map[string]bool{"emap": true, "efilter": true}
+
+// Like compoundOps, but compiles arguments that are lambdas without an argument
+// list as taking one argument named "it".
+func (cp *compiler) implicitItArgOps(ns []*parse.Compound) []valuesOp {
+	ops := make([]valuesOp, len(ns))
+	for i, n := range ns {
+		if lambda := lambdaArg(n); lambda != nil {
+			ops[i] = cp.lambda(lambda, "it")
+		} else {
+			ops[i] = cp.compoundOp(n)
+		}
+	}
+	return ops
+}
+
+// Returns the lambda if the compound expression consists of just a lambda, or
+// nil otherwise.
+func lambdaArg(n *parse.Compound) *parse.Primary {
+	if len(n.Indexings) != 1 || len(n.Indexings[0].Indicies) > 0 {
+		return nil
+	}
+	if head := n.Indexings[0].Head; head.Type == parse.Lambda {
+		return head
+	}
+	return nil
+}
+
 func (cp *compiler) primaryOps(ns []*parse.Primary) []valuesOp {
 	ops := make([]valuesOp, len(ns))
 	for i, n := range ns {
@@ -309,6 +368,8 @@ type variableOp struct {
 	explode bool
 	qname   string
 	ref     *varRef
+	// Whether strict mode was on where the variable is used.
+	strict bool
 }
 
 func (op variableOp) exec(fm *Frame) ([]interface{}, error) {
@@ -316,6 +377,11 @@ func (op variableOp) exec(fm *Frame) ([]interface{}, error) {
 	if variable == nil {
 		return nil, fm.errorpf(op, "variable $%s not found", op.qname)
 	}
+	if op.strict && (op.ref.scope == envScope || op.ref.scope == envListScope) {
+		if err := checkEnvSet(op.ref.subNames[0]); err != nil {
+			return nil, fm.errorp(op, err)
+		}
+	}
 	value := variable.Get()
 	if op.explode {
 		vs, err := vals.Collect(value)
@@ -365,10 +431,14 @@ func (op outputCaptureOp) exec(fm *Frame) ([]interface{}, error) {
 	return fm.CaptureOutput(op.subop.exec)
 }
 
-func (cp *compiler) lambda(n *parse.Primary) valuesOp {
+// Compiles a lambda. If the lambda has no argument list, it takes the given
+// implicit arguments instead.
+func (cp *compiler) lambda(n *parse.Primary, implicitArgs ...string) valuesOp {
 	// Parse signature.
 	var (
 		argNames      []string
+		argTypes      []string
+		argDefaults   []*argDefault
 		restArg       int = -1
 		optNames      []string
 		optDefaultOps []valuesOp
@@ -376,12 +446,30 @@ func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 	if len(n.Elements) > 0 {
 		// Argument list.
 		argNames = make([]string, len(n.Elements))
+		argDefaults = make([]*argDefault, len(n.Elements))
 		for i, arg := range n.Elements {
-			ref := mustString(cp, arg, "argument name must be literal string")
+			var ref string
+			if d := splitArgDefault(arg); d != nil {
+				ref = d.name
+				argDefaults[i] = d
+			} else {
+				ref = mustString(cp, arg, "argument name must be literal string")
+				if i > 0 && argDefaults[i-1] != nil {
+					cp.errorpf(arg, "argument without default value must not follow one with default value")
+				}
+			}
 			sigil, qname := SplitSigil(ref)
-			name, rest := SplitQName(qname)
-			if rest != "" {
-				cp.errorpf(arg, "argument name must be unqualified")
+			name, typ := SplitQName(qname)
+			if typ != "" {
+				// The part after the first colon is a type annotation.
+				if _, ok := argTypeCheckers[typ]; !ok {
+					cp.errorpf(arg, "unknown argument type %s", parse.Quote(typ))
+				}
+				name = name[:len(name)-1]
+				if argTypes == nil {
+					argTypes = make([]string, len(n.Elements))
+				}
+				argTypes[i] = typ
 			}
 			if name == "" {
 				cp.errorpf(arg, "argument name must not be empty")
@@ -394,6 +482,11 @@ func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 			}
 			argNames[i] = name
 		}
+		if restArg != -1 && argDefaults[len(argDefaults)-1] != nil {
+			cp.errorpf(n, "arguments with default values must not be used with a rest argument")
+		}
+	} else if len(implicitArgs) > 0 && len(n.MapPairs) == 0 && strings.HasPrefix(parse.SourceText(n), "{") {
+		argNames = implicitArgs
 	}
 	if len(n.MapPairs) > 0 {
 		optNames = make([]string, len(n.MapPairs))
@@ -423,23 +516,103 @@ func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 	for _, optName := range optNames {
 		thisScope.add(optName)
 	}
+	// Default values of arguments are compiled in the scope of the closure, so
+	// that they can refer to other arguments and options.
+	var argDefaultOps []valuesOp
+	for i, d := range argDefaults {
+		if d != nil {
+			if argDefaultOps == nil {
+				argDefaultOps = make([]valuesOp, len(argDefaults))
+			}
+			argDefaultOps[i] = cp.argDefaultOp(d)
+		}
+	}
 	scopeSizeInit := len(thisScope.names)
-	chunkOp := cp.chunkOp(n.Chunk)
-	scopeOp := wrapScopeOp(chunkOp, thisScope.names[scopeSizeInit:])
+	doc, hasDoc := docString(n.Chunk)
+	var bodyOp effectOp
+	if hasDoc {
+		bodyOp = chunkOp{n.Chunk.Range(), cp.pipelineOps(n.Chunk.Pipelines[1:])}
+	} else {
+		bodyOp = cp.chunkOp(n.Chunk)
+	}
+	scopeOp := wrapScopeOp(bodyOp, thisScope.names[scopeSizeInit:])
 	cp.popScope()
 
-	return &lambdaOp{n.Range(), argNames, restArg, optNames, optDefaultOps, thisUp, scopeOp, cp.srcMeta}
+	return &lambdaOp{n.Range(), argNames, argTypes, argDefaultOps, restArg, optNames, optDefaultOps, thisUp, scopeOp, cp.srcMeta, doc}
+}
+
+// Returns the docstring of a closure body, which is a quoted string on its own
+// at the start of the body, followed by other code.
+func docString(n *parse.Chunk) (string, bool) {
+	if len(n.Pipelines) < 2 {
+		return "", false
+	}
+	p := n.Pipelines[0]
+	if len(p.Forms) != 1 || p.Background {
+		return "", false
+	}
+	f := p.Forms[0]
+	if f.Head == nil || len(f.Assignments) > 0 || len(f.Vars) > 0 ||
+		len(f.Args) > 0 || len(f.Opts) > 0 || len(f.Redirs) > 0 {
+		return "", false
+	}
+	pn := onePrimary(f.Head)
+	if pn == nil || (pn.Type != parse.SingleQuoted && pn.Type != parse.DoubleQuoted) {
+		return "", false
+	}
+	return pn.Value, true
+}
+
+// An argument with a default value, like b=2 in [a b=2]{ }.
+type argDefault struct {
+	node *parse.Compound
+	name string
+	// The text after "=" in the first indexing.
+	prefix     string
+	prefixNode *parse.Indexing
+	// Rest of the indexings, forming the default value along with prefix.
+	rest []*parse.Indexing
+}
+
+// Splits an argument in the signature into a name and a default value. Returns
+// nil if the argument does not have a default value.
+func splitArgDefault(n *parse.Compound) *argDefault {
+	if len(n.Indexings) == 0 {
+		return nil
+	}
+	first := n.Indexings[0]
+	if first.Head.Type != parse.Bareword || len(first.Indicies) > 0 {
+		return nil
+	}
+	i := strings.IndexByte(first.Head.Value, '=')
+	if i == -1 {
+		return nil
+	}
+	return &argDefault{
+		n, first.Head.Value[:i], first.Head.Value[i+1:], first, n.Indexings[1:]}
+}
+
+func (cp *compiler) argDefaultOp(d *argDefault) valuesOp {
+	subops := cp.indexingOps(d.rest)
+	if d.prefix != "" || len(subops) == 0 {
+		subops = append(
+			[]valuesOp{literalValues(d.prefixNode, d.prefix)}, subops...)
+	}
+	return compoundOp{d.node.Range(), false, subops}
 }
 
 type lambdaOp struct {
 	diag.Ranging
 	argNames      []string
+	argTypes      []string
+	argDefaultOps []valuesOp
 	restArg       int
 	optNames      []string
 	optDefaultOps []valuesOp
 	capture       *staticUpNs
 	subop         effectOp
 	srcMeta       parse.Source
+	doc           string
 }
 
 func (op *lambdaOp) exec(fm *Frame) ([]interface{}, error) {
@@ -460,7 +633,7 @@ func (op *lambdaOp) exec(fm *Frame) ([]interface{}, error) {
 		}
 		optDefaults[i] = defaultValue
 	}
-	return []interface{}{&closure{op.argNames, op.restArg, op.optNames, optDefaults, op.subop, capture, op.srcMeta, op.Range()}}, nil
+	return []interface{}{&closure{"", op.argNames, op.argTypes, op.argDefaultOps, op.restArg, op.optNames, optDefaults, op.subop, capture, op.srcMeta, op.Range(), op.doc}}, nil
 }
 
 type mapOp struct {
@@ -542,6 +715,13 @@ func literalValues(r diag.Ranger, vs ...interface{}) valuesOp {
 	return literalValuesOp{r.Range(), vs}
 }
 
+func (cp *compiler) seqValuesOp(r diag.Ranging, subops []valuesOp) valuesOp {
+	if op := foldSeq(r, subops); op != nil {
+		return op
+	}
+	return seqValuesOp{r, subops}
+}
+
 type seqValuesOp struct {
 	diag.Ranging
 	subops []valuesOp
@@ -57,12 +57,16 @@ func TestCompileValue(t *testing.T) {
 		// Map expression errors if number of keys and values in a single pair
 		// does not match.
 		That("put [&{a b}={foo bar lorem}]").Throws(ErrorWithMessage("2 keys but 3 values")),
+		// Errors in constant expressions are only thrown when they are evaluated.
+		That("if $false { put [a][1] [&{a b}=c] }").DoesNothing(),
 
 		// String Literals
 		// ---------------
 		That(`put 'such \"''literal'`).Puts(`such \"'literal`),
 		That(`put "much \n\033[31;1m$cool\033[m"`).
 			Puts("much \n\033[31;1m$cool\033[m"),
+		That("fn f {\n  put '''\n    SELECT * FROM t\n      WHERE x = '$y'\n    '''\n}", "f").
+			Puts("SELECT * FROM t\n  WHERE x = '$y'"),
 
 		// Captures
 		// ---------
@@ -124,6 +128,8 @@ func TestCompileValue(t *testing.T) {
 		That("ns: = (ns [&a= val]); put $ns:a").Puts("val"),
 		// Multi-level namespace access is supported.
 		That("ns: = (ns [&a:= (ns [&b= val])]); put $ns:a:b").Puts("val"),
+		// The same qualified name can refer to variables in different namespaces.
+		That("for n: [(ns [&x=a]) (ns [&y=b &x=c])] { put $n:x }").Puts("a", "c"),
 		// Multi-level namespace access can have a leading colon to signal that
 		// the first component is unqualified.
 		That("ns: = (ns [&a:= (ns [&b= val])]); put $:ns:a:b").Puts("val"),
@@ -174,8 +180,58 @@ func TestCompileValue(t *testing.T) {
 		// Option default value.
 		That("[a &k=v]{ put $a $k } foo").Puts("foo", "v"),
 
-		// Argument name must be unqualified.
+		// Argument default values.
+		That("[a b=2]{ put $a $b } foo").Puts("foo", "2"),
+		That("[a b=2]{ put $a $b } foo bar").Puts("foo", "bar"),
+		That("[a b=]{ put $b } foo").Puts(""),
+		That("[a b='x y']{ put $b } foo").Puts("x y"),
+		// Default values can refer to earlier arguments and options.
+		That("[a b=$a.bak &suffix=txt c=(put $b.$suffix)]{ put $b $c } foo").
+			Puts("foo.bak", "foo.bak.txt"),
+		// Default values are evaluated lazily, every time they are needed.
+		That("n = 0; f = [a=(n = (+ $n 1); put $n)]{ put $a }",
+			"$f; $f x; $f").Puts(1.0, "x", 2.0),
+		// Arguments without default values must come first.
+		That("[a=1 b]{ }").DoesNotCompile(),
+		// Default values can't be used with rest arguments.
+		That("[a b=1 @c]{ }").DoesNotCompile(),
+		That("[@a=1]{ }").DoesNotCompile(),
+		// Default value must be one value.
+		That("[a=(put foo bar)]{ } ").Throws(
+			errs.ArityMismatch{
+				What: "argument default value", ValidLow: 1, ValidHigh: 1, Actual: 2},
+			"a=(put foo bar)", "[a=(put foo bar)]{ } "),
+
+		// Argument type annotations.
+		That("[x:num y:string]{ put $x $y } 1 foo").Puts("1", "foo"),
+		That("[x:num]{ put $x } (float64 1)").Puts(1.0),
+		That("[x:list=(put [])]{ put $x }").Puts(vals.EmptyList),
+		That("[x:bool @rest:string]{ put $rest } $true a b").
+			Puts(vals.MakeList("a", "b")),
+		That("[x:num]{ } foo").Throws(
+			errs.BadValue{
+				What: "argument $x", Valid: "of type num", Actual: "foo"},
+			"[x:num]{ } foo"),
+		That("[x y:map]{ } a [b]").Throws(
+			errs.BadValue{
+				What: "argument $y", Valid: "of type map", Actual: "[b]"},
+			"[x y:map]{ } a [b]"),
+		That("[@rest:fn]{ } $put~ foo").Throws(
+			errs.BadValue{
+				What: "element of argument $rest", Valid: "of type fn", Actual: "foo"},
+			"[@rest:fn]{ } $put~ foo"),
+		That("[a @rest b:num]{ } a b c").Throws(
+			errs.BadValue{
+				What: "argument $b", Valid: "of type num", Actual: "c"},
+			"[a @rest b:num]{ } a b c"),
+		// Default values are also checked.
+		That("[x:num=foo]{ }").Throws(
+			errs.BadValue{
+				What: "argument $x", Valid: "of type num", Actual: "foo"},
+			"[x:num=foo]{ }"),
+		// Argument types must be known.
 		That("[a:b]{ }").DoesNotCompile(),
+		That("[a:num:b]{ }").DoesNotCompile(),
 		// Argument name must not be empty.
 		That("['']{ }").DoesNotCompile(),
 		That("[@]{ }").DoesNotCompile(),
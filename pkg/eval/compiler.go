@@ -24,6 +24,13 @@ type compiler struct {
 	deprecations deprecationRegistry
 	// Information about the source.
 	srcMeta parse.Source
+	// Compilation errors recovered from so far.
+	errors []error
+	// Aliases to expand in command heads.
+	aliases map[string]alias
+	// Whether strict mode is on in each of the lexical scopes, turned on with
+	// "pragma strict". Inner scopes start with the mode of the outer scope.
+	strict []bool
 }
 
 type capture struct {
@@ -44,12 +51,22 @@ type Op struct {
 	Src  parse.Source
 }
 
-func compile(b, g *staticNs, tree parse.Tree, w io.Writer) (op Op, err error) {
+// Shows a deprecation for the given range of the source. Each deprecation is
+// shown at most once for each location.
+func (cp *compiler) deprecate(r diag.Ranger, dep Deprecation) {
+	if cp.warn == nil {
+		return
+	}
+	cp.deprecations.show(cp.warn, dep,
+		cp.srcMeta.Context(r))
+}
+
+func compile(b, g *staticNs, aliases map[string]alias, tree parse.Tree, w io.Writer) (op Op, err error) {
 	g = g.clone()
 	gLenInit := len(g.names)
 	cp := &compiler{
 		b, []*staticNs{g}, []*staticUpNs{new(staticUpNs)},
-		w, newDeprecationRegistry(), tree.Source}
+		w, newDeprecationRegistry(), tree.Source, nil, aliases, []bool{false}}
 	defer func() {
 		r := recover()
 		if r == nil {
@@ -63,6 +80,9 @@ func compile(b, g *staticNs, tree parse.Tree, w io.Writer) (op Op, err error) {
 		}
 	}()
 	chunkOp := cp.chunkOp(tree.Root)
+	if len(cp.errors) > 0 {
+		return Op{}, diag.Errors(cp.errors...)
+	}
 	scopeOp := wrapScopeOp(chunkOp, g.names[gLenInit:])
 
 	return Op{scopeOp.exec, tree.Source}, nil
@@ -75,14 +95,60 @@ func (cp *compiler) errorpf(r diag.Ranger, format string, args ...interface{}) {
 	panic(&diag.Error{
 		Type:    compilationErrorType,
 		Message: fmt.Sprintf(format, args...),
-		Context: *diag.NewContext(cp.srcMeta.Name, cp.srcMeta.Code, r)})
+		Context: *cp.srcMeta.Context(r)})
+}
+
+// Calls f, recovering from the compilation error it raises and recording it,
+// so that compilation can continue and report further errors. Scopes pushed by
+// f are popped when recovering.
+func (cp *compiler) recoverErrors(f func()) {
+	nScopes := len(cp.scopes)
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		e := GetCompilationError(r)
+		if e == nil {
+			// Resume the panic; it is not supposed to be handled here.
+			panic(r)
+		}
+		cp.errors = append(cp.errors, e)
+		cp.scopes = cp.scopes[:nScopes]
+		cp.captures = cp.captures[:nScopes]
+	}()
+	f()
 }
 
 // GetCompilationError returns a *diag.Error if the given value is a compilation
-// error. Otherwise it returns nil.
+// error, or the first one if it contains multiple compilation errors. Otherwise
+// it returns nil.
 func GetCompilationError(e interface{}) *diag.Error {
-	if e, ok := e.(*diag.Error); ok && e.Type == compilationErrorType {
-		return e
+	if errs := GetCompilationErrors(e); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// GetCompilationErrors returns all the compilation errors in the given value,
+// which may be a single *diag.Error, or a diag.MultiError when the compiler
+// found multiple errors. Otherwise it returns nil.
+func GetCompilationErrors(e interface{}) []*diag.Error {
+	switch e := e.(type) {
+	case *diag.Error:
+		if e.Type == compilationErrorType {
+			return []*diag.Error{e}
+		}
+	case diag.MultiError:
+		var errs []*diag.Error
+		for _, err := range e.Errors {
+			compileErr, ok := err.(*diag.Error)
+			if !ok || compileErr.Type != compilationErrorType {
+				return nil
+			}
+			errs = append(errs, compileErr)
+		}
+		return errs
 	}
 	return nil
 }
@@ -95,6 +161,7 @@ func (cp *compiler) pushScope() (*staticNs, *staticUpNs) {
 	up := new(staticUpNs)
 	cp.scopes = append(cp.scopes, sc)
 	cp.captures = append(cp.captures, up)
+	cp.strict = append(cp.strict, cp.strict[len(cp.strict)-1])
 	return sc, up
 }
 
@@ -103,4 +170,10 @@ func (cp *compiler) popScope() {
 	cp.scopes = cp.scopes[:len(cp.scopes)-1]
 	cp.captures[len(cp.captures)-1] = nil
 	cp.captures = cp.captures[:len(cp.captures)-1]
+	cp.strict = cp.strict[:len(cp.strict)-1]
+}
+
+// Returns whether strict mode is on in the current scope.
+func (cp *compiler) isStrict() bool {
+	return cp.strict[len(cp.strict)-1]
 }
@@ -0,0 +1,202 @@
+package eval
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/xiaq/persistent/hash"
+)
+
+// Coprocesses: external commands that keep running in the background, with
+// their stdin and stdout connected to pipes.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"coproc":      coproc,
+		"coproc-wait": coprocWait,
+	})
+}
+
+// Coproc is a coprocess started by the coproc command.
+type Coproc struct {
+	Name string
+	// The write end of the pipe connected to the stdin of the process, and the
+	// read end of the pipe connected to its stdout.
+	Stdin, Stdout *os.File
+
+	proc     *os.Process
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func (*Coproc) Kind() string                  { return "coproc" }
+func (co *Coproc) Equal(rhs interface{}) bool { return co == rhs }
+func (co *Coproc) Hash() uint32               { return hash.Pointer(unsafe.Pointer(co)) }
+
+func (co *Coproc) Repr(int) string {
+	return "<coproc " + parse.Quote(co.Name) + " " + strconv.Itoa(co.proc.Pid) + ">"
+}
+
+func (co *Coproc) Fields() vals.StructMap { return coprocFields{co} }
+
+var errCoprocRedirMode = errors.New("can only use < or > with coprocesses")
+
+// RedirFile implements RedirTarget. Redirecting input reads from the stdout of
+// the process, and redirecting output writes to its stdin.
+func (co *Coproc) RedirFile(mode parse.RedirMode) (*os.File, error) {
+	switch mode {
+	case parse.Read:
+		return co.Stdout, nil
+	case parse.Write:
+		return co.Stdin, nil
+	default:
+		return nil, errCoprocRedirMode
+	}
+}
+
+type coprocFields struct{ co *Coproc }
+
+func (coprocFields) IsStructMap()       {}
+func (f coprocFields) Name() string     { return f.co.Name }
+func (f coprocFields) Pid() string      { return strconv.Itoa(f.co.proc.Pid) }
+func (f coprocFields) Stdin() *os.File  { return f.co.Stdin }
+func (f coprocFields) Stdout() *os.File { return f.co.Stdout }
+
+// Closes the stdin of the process, waits for it to exit and closes its stdout.
+// It is safe to call wait multiple times; only the first call waits.
+func (co *Coproc) wait(fm *Frame) error {
+	co.waitOnce.Do(func() {
+		co.Stdin.Close()
+		if done := fm.Interrupts(); done != nil {
+			waited := make(chan struct{})
+			defer close(waited)
+			go func() {
+				select {
+				case <-done:
+					co.proc.Kill()
+				case <-waited:
+				}
+			}()
+		}
+		state, err := co.proc.Wait()
+		co.Stdout.Close()
+		if err != nil {
+			co.waitErr = err
+			return
+		}
+		co.waitErr = NewExternalCmdExit(co.Name, state.Sys().(syscall.WaitStatus), co.proc.Pid)
+	})
+	return co.waitErr
+}
+
+//elvdoc:fn coproc
+//
+// ```elvish
+// coproc $command $arg...
+// ```
+//
+// Starts an external command in the background, with its stdin and stdout
+// connected to pipes, and outputs a coprocess value. Its stderr is the same as
+// that of `coproc`. This is useful for driving interactive programs like `bc`
+// or `sqlite3`.
+//
+// A coprocess can be used in redirections like a [pipe](#pipe): when
+// redirecting the output of a command to a coprocess with `>`, the output is
+// written to the stdin of the process; when redirecting the input of a command
+// from a coprocess with `<`, the input is read from the stdout of the process.
+// Example:
+//
+// ```elvish-transcript
+// ~> co = (coproc bc)
+// ~> echo '1 + 2' > $co
+// ~> read-line < $co
+// ▶ 3
+// ~> coproc-wait $co
+// ```
+//
+// A coprocess has the following fields:
+//
+// -   `name`: The name of the command.
+//
+// -   `pid`: The process ID.
+//
+// -   `stdin` and `stdout`: The two ends of the pipes, as files. The process
+//     sees an end of input when `stdin` is closed with `fclose`.
+//
+// The process keeps running until it exits by itself, and must be waited for
+// with `coproc-wait` to release its resources.
+//
+// Spawning a coprocess respects the `nice`, `ionice` and `ulimit` commands,
+// but does not support the options of external commands.
+//
+// @cf coproc-wait pipe
+
+func coproc(fm *Frame, name string, args ...interface{}) (*Coproc, error) {
+	if err := fm.CheckRestriction(RestrictExternalCommands); err != nil {
+		return nil, err
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	argv := make([]string, len(args)+1)
+	argv[0] = path
+	for i, a := range args {
+		argv[i+1] = vals.ToString(a)
+	}
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return nil, err
+	}
+	var stderr *os.File
+	if len(fm.ports) > 2 && fm.ports[2] != nil {
+		stderr = fm.ports[2].File
+	}
+	files := []*os.File{stdinR, stdoutW, stderr}
+	sys := makeSysProcAttr(fm.background)
+	proc, err := startProcess(path, argv, &os.ProcAttr{Files: files, Sys: sys}, fm.spawn)
+	// The other ends of the pipes now belong to the process.
+	stdinR.Close()
+	stdoutW.Close()
+	if err != nil {
+		stdinW.Close()
+		stdoutR.Close()
+		return nil, err
+	}
+	return &Coproc{Name: name, Stdin: stdinW, Stdout: stdoutR, proc: proc}, nil
+}
+
+//elvdoc:fn coproc-wait
+//
+// ```elvish
+// coproc-wait $coproc
+// ```
+//
+// Closes the stdin of a coprocess, waits for it to exit and closes its stdout.
+// Throws an exception if the process exits with a non-zero status, like when
+// running the command normally.
+//
+// Output of the process that has not been read before calling `coproc-wait`
+// is discarded. If the process writes more output than the pipe can buffer
+// after its stdin is closed, it is blocked until it is killed, so make sure to
+// read all of its output before waiting for it.
+//
+// @cf coproc
+
+func coprocWait(fm *Frame, co *Coproc) error {
+	return co.wait(fm)
+}
@@ -0,0 +1,30 @@
+// +build !windows,!plan9,!js
+
+package eval_test
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestCoproc(t *testing.T) {
+	Test(t,
+		That("co = (coproc cat); echo foo > $co; read-line < $co; coproc-wait $co").
+			Puts("foo"),
+		That("co = (coproc cat); echo foo > $co[stdin]; read-line < $co[stdout]; coproc-wait $co").
+			Puts("foo"),
+		// Closing stdin sends an end of input to the process.
+		That("co = (coproc tr a-z A-Z); echo foo > $co; fclose $co[stdin]; slurp < $co; coproc-wait $co").
+			Puts("FOO\n"),
+		That("co = (coproc sh -c 'exit 3'); coproc-wait $co").
+			Throws(CmdExit(ExternalCmdExit{CmdName: "sh", WaitStatus: exitWaitStatus(3)})),
+		// Waiting again returns the same result.
+		That("co = (coproc cat); coproc-wait $co; coproc-wait $co").DoesNothing(),
+		That("co = (coproc cat); put $co[name]; coproc-wait $co").Puts("cat"),
+		That("co = (coproc cat); kind-of $co; coproc-wait $co").Puts("coproc"),
+		That("co = (coproc cat); try { echo foo <> $co } finally { coproc-wait $co }").
+			Throws(AnyError, "<> $co"),
+	)
+}
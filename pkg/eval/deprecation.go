@@ -1,11 +1,34 @@
 package eval
 
 import (
+	"fmt"
+	"io"
 	"sync"
 
 	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/prog"
 )
 
+// Deprecation describes a deprecated usage.
+type Deprecation struct {
+	// What is deprecated, like `the "ord" command is deprecated`.
+	Message string
+	// How to upgrade, like `use "str:to-codepoints" instead`. Can be empty.
+	Hint string
+	// The minor version of Elvish in which the usage was deprecated, like 15
+	// for 0.15.0. The deprecation is only shown if prog.DeprecationLevel is at
+	// least this level.
+	Level int
+}
+
+// Returns the text of the deprecation message, including the hint.
+func (dep Deprecation) text() string {
+	if dep.Hint == "" {
+		return dep.Message
+	}
+	return dep.Message + "; " + dep.Hint
+}
+
 type deprecationRegistry struct {
 	mutex      sync.Mutex
 	registered map[deprecation]struct{}
@@ -32,3 +55,24 @@ func (r *deprecationRegistry) register(dep deprecation) bool {
 	r.registered[dep] = struct{}{}
 	return true
 }
+
+// Writes a deprecation at ctx to w, unless it is hidden by
+// prog.DeprecationLevel or has been shown for the same location before.
+func (r *deprecationRegistry) show(w io.Writer, dep Deprecation, ctx *diag.Context) {
+	if !prog.ShowDeprecation(dep.Level) ||
+		!r.register(deprecation{ctx.Name, ctx.Ranging, dep.text()}) {
+		return
+	}
+	err := diag.Error{Type: "deprecation", Message: dep.text(), Context: *ctx}
+	fmt.Fprintln(w, err.Show(""))
+}
+
+// Deprecated builtin functions, keyed by their variable names.
+var deprecatedBuiltins = map[string]Deprecation{
+	"-source~":    {`the "source" command is deprecated`, `use "eval" instead`, 15},
+	"ord~":        {`the "ord" command is deprecated`, `use "str:to-codepoints" instead`, 15},
+	"chr~":        {`the "chr" command is deprecated`, `use "str:from-codepoints" instead`, 15},
+	"has-prefix~": {`the "has-prefix" command is deprecated`, `use "str:has-prefix" instead`, 15},
+	"has-suffix~": {`the "has-suffix" command is deprecated`, `use "str:has-suffix" instead`, 15},
+	"esleep~":     {`the "esleep" command is deprecated`, `use "sleep" instead`, 15},
+}
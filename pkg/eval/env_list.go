@@ -85,6 +85,22 @@ func (envli *envListVar) Set(v interface{}) error {
 
 	envli.Lock()
 	defer envli.Unlock()
-	os.Setenv(envli.envName, strings.Join(paths, pathListSeparator))
-	return nil
+	return vars.SetEnv(envli.envName, strings.Join(paths, pathListSeparator))
+}
+
+// IsSet implements the vars.Unsettable interface.
+func (envli *envListVar) IsSet() bool {
+	_, ok := os.LookupEnv(envli.envName)
+	return ok
+}
+
+// Unset implements the vars.Unsettable interface.
+func (envli *envListVar) Unset() error {
+	return vars.UnsetEnv(envli.envName)
+}
+
+// Watch implements the vars.Watchable interface. The watchers are called with
+// the new value of the list every time the environment variable is changed.
+func (envli *envListVar) Watch(f func(newValue interface{})) func() {
+	return vars.WatchEnv(envli.envName, func(interface{}) { f(envli.Get()) })
 }
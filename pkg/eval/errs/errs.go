@@ -4,6 +4,8 @@ package errs
 import (
 	"fmt"
 	"strconv"
+
+	"github.com/elves/elvish/pkg/diag"
 )
 
 // OutOfRange encodes an error where a value is out of its valid range.
@@ -44,6 +46,11 @@ type ArityMismatch struct {
 	ValidLow  int
 	ValidHigh int
 	Actual    int
+	// The name of the function being called and where it is defined, when the
+	// mismatch is between the arguments and the parameters of a user-defined
+	// function. FnName is empty for anonymous functions.
+	FnName string
+	FnDef  *diag.Context
 }
 
 func (e ArityMismatch) Error() string {
@@ -60,6 +67,20 @@ func (e ArityMismatch) Error() string {
 	}
 }
 
+// Show shows the error. If the definition of the function being called is
+// known, it is also shown.
+func (e ArityMismatch) Show(indent string) string {
+	msg := "\033[31;1m" + e.Error() + "\033[m"
+	if e.FnDef == nil {
+		return msg
+	}
+	fn := "function"
+	if e.FnName != "" {
+		fn = "fn " + e.FnName
+	}
+	return msg + "\n" + indent + fn + " defined at " + e.FnDef.ShowCompact(indent)
+}
+
 func nValues(n int) string {
 	if n == 1 {
 		return "1 value"
@@ -2,6 +2,8 @@ package errs
 
 import (
 	"testing"
+
+	"github.com/elves/elvish/pkg/diag"
 )
 
 var errorMessageTests = []struct {
@@ -41,3 +43,33 @@ func TestErrorMessages(t *testing.T) {
 		}
 	}
 }
+
+func TestArityMismatch_Show(t *testing.T) {
+	def := diag.NewContext("[test]", "fn f [x]{ }", diag.Ranging{From: 5, To: 11})
+	tests := []struct {
+		err  ArityMismatch
+		want string
+	}{
+		{
+			ArityMismatch{What: "arguments here", ValidLow: 1, ValidHigh: 1, Actual: 0},
+			"\033[31;1marity mismatch: arguments here must be 1 value, but is 0 values\033[m",
+		},
+		{
+			ArityMismatch{What: "arguments here", ValidLow: 1, ValidHigh: 1, Actual: 0,
+				FnName: "f", FnDef: def},
+			"\033[31;1marity mismatch: arguments here must be 1 value, but is 0 values\033[m\n" +
+				"fn f defined at [test], line 1: fn f \033[1;4m[x]{ }\033[m",
+		},
+		{
+			ArityMismatch{What: "arguments here", ValidLow: 1, ValidHigh: 1, Actual: 0,
+				FnDef: def},
+			"\033[31;1marity mismatch: arguments here must be 1 value, but is 0 values\033[m\n" +
+				"function defined at [test], line 1: fn f \033[1;4m[x]{ }\033[m",
+		},
+	}
+	for _, test := range tests {
+		if got := test.err.Show(""); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
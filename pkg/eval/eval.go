@@ -3,13 +3,19 @@
 package eval
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/elves/elvish/pkg/daemon"
-	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval/errs"
 	"github.com/elves/elvish/pkg/eval/mods/bundled"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
@@ -34,6 +40,7 @@ const (
 const (
 	defaultValuePrefix        = "▶ "
 	defaultNotifyBgJobSuccess = true
+	defaultTraceExecPrefix    = "+ "
 	initIndent                = vals.NoPretty
 )
 
@@ -54,9 +61,33 @@ type Evaler struct {
 	// Internal modules are indexed by use specs. External modules are indexed by
 	// absolute paths.
 	modules map[string]*Ns
+	// Whether to fetch a module with a spec that starts with a domain name,
+	// like "github.com/user/repo/mod", using the epm module when it is not
+	// found in the library directory. Fetching is also turned on when the
+	// ELVISH_FETCH_MODULES environment variable is "1".
+	FetchModules bool
 
 	deprecations deprecationRegistry
 
+	// Tracers, stored as a []Tracer so that they can be read without locking.
+	// Modifications are guarded by tracersMutex.
+	tracers      atomic.Value
+	tracersMutex sync.Mutex
+
+	compileCache *compileCache
+
+	aliases aliasRegistry
+
+	// Maximum depth of nested function calls and pipelines. Exceeding them
+	// throws a RecursionLimitExceeded exception instead of exhausting the
+	// memory. A non-positive value means no limit. They should be set before
+	// any code is evaluated.
+	MaxCallDepth     int
+	MaxPipelineDepth int
+	// What evaluated code is not allowed to do. It should be set before any
+	// code is evaluated.
+	Restrictions Restrictions
+
 	// Dependencies.
 	//
 	// TODO: Remove these dependency by providing more general extension points.
@@ -100,6 +131,24 @@ type evalerScopes struct {
 //
 // Number of background jobs.
 
+//elvdoc:var auto-cd
+//
+// Whether to change to a directory when its name is used as a command, even if
+// the name doesn't contain a slash, defaulting to `$false`. Example:
+//
+// ```elvish-transcript
+// ~> auto-cd = $true
+// ~> mkdir d
+// ~> d
+// ~/d>
+// ```
+//
+// A name that is also the name of an external command still runs the command.
+// Names that contain a slash, like `./d` or `d/`, are always used to change
+// directory when they are directories, regardless of this variable.
+//
+// @cf cd
+
 //elvdoc:var notify-bg-job-success
 //
 // Whether to notify success of background jobs, defaulting to `$true`.
@@ -123,6 +172,27 @@ type evalerScopes struct {
 //
 // Note that you almost always want some trailing whitespace for readability.
 
+//elvdoc:var pipeline-buffer-size
+//
+// The number of values that can be buffered between two commands in a
+// pipeline, defaulting to 32. When the buffer is full, the command writing
+// values waits until the next command reads some; no value is ever discarded.
+//
+// Since the command writing values only finishes after the next command has
+// read all but the last `$pipeline-buffer-size` of its values, a pipeline where
+// the next command doesn't read values but waits for the end of its byte
+// input never finishes if the values don't fit in the buffer. Elvish doesn't
+// detect such deadlocks; either increase the buffer size, or stop the values
+// from reaching the next command:
+//
+// ```elvish-transcript
+// ~> pipeline-buffer-size = 100
+// ~> range 100 | slurp
+// ▶ ''
+// ~> range 1000 | only-bytes | slurp
+// ▶ ''
+// ```
+
 // NewEvaler creates a new Evaler.
 func NewEvaler() *Evaler {
 	builtin := builtinNs.Ns()
@@ -132,6 +202,8 @@ func NewEvaler() *Evaler {
 			valuePrefix:        defaultValuePrefix,
 			notifyBgJobSuccess: defaultNotifyBgJobSuccess,
 			numBgJobs:          0,
+			pipelineBufferSize: DefaultPipelineBufferSize,
+			traceExecPrefix:    defaultTraceExecPrefix,
 		},
 		evalerScopes: evalerScopes{
 			Global:  new(Ns),
@@ -143,6 +215,10 @@ func NewEvaler() *Evaler {
 		bundled: bundled.Get(),
 
 		deprecations: newDeprecationRegistry(),
+		compileCache: newCompileCache(),
+
+		MaxCallDepth:     DefaultMaxCallDepth,
+		MaxPipelineDepth: DefaultMaxPipelineDepth,
 	}
 
 	beforeChdirElvish, afterChdirElvish := vector.Empty, vector.Empty
@@ -159,9 +235,22 @@ func NewEvaler() *Evaler {
 		&ev.state.valuePrefix, &ev.state.mutex)
 	moreBuiltinsBuilder["notify-bg-job-success"] = vars.FromPtrWithMutex(
 		&ev.state.notifyBgJobSuccess, &ev.state.mutex)
+	moreBuiltinsBuilder["auto-cd"] = vars.FromPtrWithMutex(
+		&ev.state.autoCd, &ev.state.mutex)
 	moreBuiltinsBuilder["num-bg-jobs"] = vars.FromGet(func() interface{} {
 		return strconv.Itoa(ev.state.getNumBgJobs())
 	})
+	moreBuiltinsBuilder["pipeline-buffer-size"] = vars.FromSetGet(
+		func(v interface{}) error {
+			var n int
+			if vals.ScanToGo(v, &n) != nil || n < 1 {
+				return errs.BadValue{What: "$pipeline-buffer-size",
+					Valid: "positive integer", Actual: vals.Repr(v, vals.NoPretty)}
+			}
+			ev.state.setPipelineBufferSize(n)
+			return nil
+		},
+		func() interface{} { return strconv.Itoa(ev.state.getPipelineBufferSize()) })
 	moreBuiltinsBuilder["pwd"] = NewPwdVar(ev)
 
 	moreBuiltins := moreBuiltinsBuilder.Ns()
@@ -221,18 +310,110 @@ func (ev *Evaler) SetArgs(args []string) {
 	for _, arg := range args {
 		v = v.Cons(arg)
 	}
-	// TODO: Avoid creating the variable dynamically; instead, always create the
-	// variable, and set its value here.
-	ev.Builtin.slots = append(ev.Builtin.slots, vars.NewReadOnly(v))
-	ev.Builtin.names = append(ev.Builtin.names, "args")
+	// The builtin namespace always has $args, so that code using it can be
+	// compiled before SetArgs is called.
+	if i := ev.Builtin.lookup("args"); i != -1 {
+		ev.Builtin.slots[i] = vars.NewReadOnly(v)
+	} else {
+		ev.Builtin.slots = append(ev.Builtin.slots, vars.NewReadOnly(v))
+		ev.Builtin.names = append(ev.Builtin.names, "args")
+	}
 }
 
 // SetLibDir sets the library directory, in which external modules are to be
-// found.
+// found. A relative path is made absolute, so that modules are cached by their
+// absolute paths.
 func (ev *Evaler) SetLibDir(libDir string) {
+	if abs, err := filepath.Abs(libDir); err == nil {
+		libDir = abs
+	}
 	ev.libDir = libDir
 }
 
+// LoadedModules returns the keys of all the modules that have been installed or
+// loaded, sorted. Modules loaded from files are keyed by their absolute paths,
+// and other modules by their use specs.
+func (ev *Evaler) LoadedModules() []string {
+	keys := make([]string, 0, len(ev.modules))
+	for key := range ev.modules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EachModule calls f with the spec of each module that can be imported with use
+// without a relative path, in no particular order: installed modules, bundled
+// modules and .elv files in the library directory. Hidden directories in the
+// library directory are skipped.
+func (ev *Evaler) EachModule(f func(spec string)) {
+	for key := range ev.modules {
+		if !filepath.IsAbs(key) {
+			f(key)
+		}
+	}
+	for spec := range ev.bundled {
+		if _, installed := ev.modules[spec]; !installed {
+			f(spec)
+		}
+	}
+	if ev.libDir == "" {
+		return
+	}
+	filepath.Walk(ev.libDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != ev.libDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".elv") {
+			rel, err := filepath.Rel(ev.libDir, path)
+			if err == nil {
+				f(filepath.ToSlash(strings.TrimSuffix(rel, ".elv")))
+			}
+		}
+		return nil
+	})
+}
+
+// ReloadModule evaluates the source of a loaded module again, identified by a
+// key returned by LoadedModules. The namespace of the module is updated in
+// place, so that code that has already imported the module sees the new
+// definitions; variables that the new source no longer defines are removed. The namespace is not changed if the evaluation fails. Modules
+// installed with InstallModule can't be reloaded.
+func (ev *Evaler) ReloadModule(key string, cfg EvalCfg) error {
+	ns, ok := ev.modules[key]
+	if !ok {
+		return fmt.Errorf("module %s is not loaded", parse.Quote(key))
+	}
+	var src parse.Source
+	if code, ok := ev.bundled[key]; ok {
+		src = parse.Source{Name: "[bundled " + key + "]", Code: code}
+	} else if filepath.IsAbs(key) {
+		code, err := readFileUTF8(key)
+		if err != nil {
+			return err
+		}
+		src = parse.Source{Name: key, Code: code, IsFile: true}
+	} else {
+		return fmt.Errorf("module %s is not written in Elvish and can't be reloaded", parse.Quote(key))
+	}
+	cfg.fillDefaults(ev)
+	newNs := new(Ns)
+	err := ev.execOp(Op{func(fm *Frame) error {
+		return evalInner(fm, src, newNs, nil)
+	}, src}, cfg)
+	if err != nil {
+		return err
+	}
+	ns.replace(newNs)
+	return nil
+}
+
 // growPorts makes the size of ec.ports at least n, adding nil's if necessary.
 func (fm *Frame) growPorts(n int) {
 	if len(fm.ports) >= n {
@@ -253,11 +434,19 @@ type EvalCfg struct {
 	// Callback to get a channel of interrupt signals and a function to call
 	// when the channel is no longer needed.
 	Interrupt func() (<-chan struct{}, func())
+	// If not nil, the evaluation is interrupted when the context is cancelled,
+	// in addition to when the channel from Interrupt is closed. Unlike
+	// interrupt signals, which are also delivered to external commands by the
+	// terminal, cancelling the context also kills external commands.
+	Context context.Context
 	// Whether the Eval method should try to put the Elvish in the foreground
 	// after the code is executed.
 	PutInFg bool
 	// If not nil, used the given global namespace, instead of Evaler's own.
 	Global *Ns
+	// Restrictions that apply to the evaluation, in addition to the
+	// Restrictions of the Evaler.
+	Restrictions Restrictions
 }
 
 func (cfg *EvalCfg) fillDefaults(ev *Evaler) {
@@ -323,12 +512,35 @@ func (ev *Evaler) Call(f Callable, callCfg CallCfg, evalCfg EvalCfg) error {
 	return ev.execOp(Op{exec, parse.Source{Name: callCfg.From}}, evalCfg)
 }
 
+// CallWithOutputCallback is like Call, but the outputs of the function are
+// passed to the callbacks in cbs instead of written to the output ports in
+// evalCfg; only the input port in evalCfg is used. It returns after the
+// callbacks have processed all the outputs, or after cbs.DrainTimeout.
+func (ev *Evaler) CallWithOutputCallback(f Callable, callCfg CallCfg, evalCfg EvalCfg, cbs OutputCallbacks) error {
+	stdout, stderr, done, err := CallbackPorts(cbs)
+	if err != nil {
+		return err
+	}
+	defer done()
+	var stdin *Port
+	if len(evalCfg.Ports) > 0 {
+		stdin = evalCfg.Ports[0]
+	}
+	evalCfg.Ports = []*Port{stdin, stdout, stderr}
+	return ev.Call(f, callCfg, evalCfg)
+}
+
 func (ev *Evaler) execOp(op Op, cfg EvalCfg) error {
-	var intCh <-chan struct{}
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if cfg.Interrupt != nil {
 		ch, cleanup := cfg.Interrupt()
 		defer cleanup()
-		intCh = ch
+		var cancel func()
+		ctx, cancel = withInterruptSignals(ctx, ch)
+		defer cancel()
 	}
 	if cfg.PutInFg {
 		defer func() {
@@ -339,7 +551,8 @@ func (ev *Evaler) execOp(op Op, cfg EvalCfg) error {
 			}
 		}()
 	}
-	fm := &Frame{ev, op.Src, cfg.Global, new(Ns), intCh, cfg.Ports, nil, false}
+	fm := &Frame{ev, op.Src, cfg.Global, new(Ns), ctx, cfg.Ports,
+		nil, false, nil, cfg.Restrictions, nil, 0, 0}
 	return op.Exec(fm)
 }
 
@@ -347,19 +560,32 @@ func (ev *Evaler) execOp(op Op, cfg EvalCfg) error {
 // It always tries to compile the code even if there is a parse error; both
 // return values may be non-nil. If w is not nil, deprecation messages are
 // written to it.
-func (ev *Evaler) Check(src parse.Source, w io.Writer) (*parse.Error, *diag.Error) {
+//
+// The compilation error may contain multiple errors; use GetCompilationErrors
+// to get all of them.
+func (ev *Evaler) Check(src parse.Source, w io.Writer) (*parse.Error, error) {
 	tree, parseErr := parse.ParseWithDeprecation(src, w)
 	return parse.GetError(parseErr), ev.CheckTree(tree, w)
 }
 
 // CheckTree checks the given parsed source tree for compilation errors. If w is
 // not nil, deprecation messages are written to it.
-func (ev *Evaler) CheckTree(tree parse.Tree, w io.Writer) *diag.Error {
+//
+// The returned error may contain multiple errors; use GetCompilationErrors to
+// get all of them.
+func (ev *Evaler) CheckTree(tree parse.Tree, w io.Writer) error {
 	_, compileErr := ev.compile(tree, ev.Global, w)
-	return GetCompilationError(compileErr)
+	if GetCompilationError(compileErr) == nil {
+		return nil
+	}
+	return compileErr
 }
 
-// Compiles a parsed tree.
+// Compiles a parsed tree, reusing earlier results when possible.
 func (ev *Evaler) compile(tree parse.Tree, g *Ns, w io.Writer) (Op, error) {
-	return compile(ev.Builtin.static(), g.static(), tree, w)
+	aliases := ev.aliasesFor(tree.Source)
+	if ev.compileCache == nil {
+		return compile(ev.Builtin.static(), g.static(), aliases, tree, w)
+	}
+	return ev.compileCache.compile(ev.Builtin, g, aliases, tree, w)
 }
@@ -2,6 +2,8 @@ package eval_test
 
 import (
 	"bytes"
+	"context"
+	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -11,6 +13,7 @@ import (
 	. "github.com/elves/elvish/pkg/eval"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/parse"
 	"github.com/elves/elvish/pkg/prog"
 	"github.com/elves/elvish/pkg/testutil"
@@ -25,6 +28,13 @@ func TestNumBgJobs(t *testing.T) {
 	)
 }
 
+func TestSetArgs(t *testing.T) {
+	setup := func(ev *Evaler) { ev.SetArgs([]string{"foo", "bar"}) }
+	TestWithSetup(t, setup,
+		That("put $@args").Puts("foo", "bar"),
+	)
+}
+
 func TestEvalTimeDeprecate(t *testing.T) {
 	restore := prog.SetShowDeprecations(true)
 	defer restore()
@@ -33,15 +43,36 @@ func TestEvalTimeDeprecate(t *testing.T) {
 
 	TestWithSetup(t, func(ev *Evaler) {
 		ev.Global = NsBuilder{}.AddGoFn("", "dep", func(fm *Frame) {
-			fm.Deprecate("deprecated", nil)
+			fm.Deprecate(Deprecation{Message: "x is deprecated", Hint: "use y instead", Level: 15}, nil)
 		}).Ns()
 	},
-		That("dep").PrintsStderrWith("deprecated"),
+		That("dep").PrintsStderrWith("x is deprecated; use y instead"),
 		// Deprecation message is only shown once.
 		That("dep 2> tmp.txt; dep").DoesNothing(),
 	)
 }
 
+func TestDeprecationLevel(t *testing.T) {
+	restore := prog.SetDeprecationLevel(14)
+	defer restore()
+
+	TestWithSetup(t, func(ev *Evaler) {
+		ev.Global = NsBuilder{}.AddGoFn("", "dep", func(fm *Frame, level int) {
+			fm.Deprecate(Deprecation{Message: "deprecated", Level: level}, nil)
+		}).Ns()
+	},
+		That("dep 14").PrintsStderrWith("deprecated"),
+		// Deprecations introduced after the level are hidden.
+		That("dep 15").DoesNothing(),
+	)
+
+	errOutput := new(bytes.Buffer)
+	NewEvaler().Check(parse.Source{Code: "ord a"}, errOutput)
+	if errOutput.Len() != 0 {
+		t.Errorf("got warning %q, want none", errOutput.String())
+	}
+}
+
 func TestCompileTimeDeprecation(t *testing.T) {
 	restore := prog.SetShowDeprecations(true)
 	defer restore()
@@ -58,17 +89,23 @@ func TestCompileTimeDeprecation(t *testing.T) {
 	}
 
 	warning := errOutput.String()
-	wantWarning := `the "ord" command is deprecated`
+	wantWarning := `the "ord" command is deprecated; use "str:to-codepoints" instead`
 	if !strings.Contains(warning, wantWarning) {
 		t.Errorf("got warning %q, want warning containing %q", warning, wantWarning)
 	}
 }
 
 func TestMiscEval(t *testing.T) {
+	sep := string(os.PathListSeparator)
 	Test(t,
 		// Pseudo-namespace E:
 		That("E:FOO=lorem; put $E:FOO").Puts("lorem"),
 		That("del E:FOO; put $E:FOO").Puts(""),
+		// List views of environment variables with E:list:
+		That("E:FOO = a"+sep+"b; put $E:list:FOO").Puts(vals.MakeList("a", "b")),
+		That("E:list:FOO = [a b c]; put $E:FOO").Puts("a"+sep+"b"+sep+"c"),
+		That("E:list:FOO = [a"+sep+"b]").Throws(ErrPathCannotContainColonZero),
+		That("E:FOO = a; del E:list:FOO; has-env FOO").Puts(false),
 	)
 }
 
@@ -131,6 +168,40 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestEval_CancelledContext(t *testing.T) {
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "nop"},
+		eval.EvalCfg{Context: ctx})
+	if Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v, want %v", err, ErrInterrupted)
+	}
+}
+
+func TestEval_ContextCancelledDuringEvaluation(t *testing.T) {
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ev.Global.Append(NsBuilder{}.AddGoFn("", "cancel", cancel).Ns())
+
+	var log []interface{}
+	logFn := NewGoFn("log", func(v interface{}) { log = append(log, v) })
+	ev.Global.Append(NsBuilder{}.AddFn("log", logFn).Ns())
+
+	// Both range and each should stop after the context is cancelled.
+	err := ev.Eval(
+		parse.Source{Name: "[test]", Code: "range 1000 | each [x]{ log $x; cancel }"},
+		eval.EvalCfg{Context: ctx})
+	if err == nil {
+		t.Errorf("got nil error, want error")
+	}
+	if !reflect.DeepEqual(log, []interface{}{0.0}) {
+		t.Errorf("got log %v, want [0.0]", log)
+	}
+}
+
 var checkTests = []struct {
 	name           string
 	code           string
@@ -162,3 +233,23 @@ func TestCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestCheck_MultipleCompilationErrors(t *testing.T) {
+	ev := NewEvaler()
+	code := "echo $a; echo $b | echo $c\nfn f { echo $d }; f"
+	_, compileErr := ev.Check(parse.Source{Code: code}, nil)
+
+	errs := GetCompilationErrors(compileErr)
+	var gotRanges []string
+	for _, err := range errs {
+		gotRanges = append(gotRanges, code[err.Context.From:err.Context.To])
+	}
+	// At most one error is reported for each pipeline.
+	wantRanges := []string{"$a", "$b", "$d"}
+	if !reflect.DeepEqual(gotRanges, wantRanges) {
+		t.Errorf("got errors at %v, want %v", gotRanges, wantRanges)
+	}
+	if GetCompilationError(compileErr) != errs[0] {
+		t.Errorf("GetCompilationError doesn't return the first error")
+	}
+}
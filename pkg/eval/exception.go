@@ -28,7 +28,10 @@ type Exception struct {
 // nodes form a DAG.
 type StackTrace struct {
 	Head *diag.Context
-	Next *StackTrace
+	// Name of the function called at Head. Empty if Head is not a function
+	// call or the function has no name.
+	FnName string
+	Next   *StackTrace
 }
 
 // Reason returns the Reason field if err is an *Exception. Otherwise it returns
@@ -72,6 +75,19 @@ func (exc *Exception) Show(indent string) string {
 			for tb := exc.StackTrace; tb != nil; tb = tb.Next {
 				buf.WriteString("\n" + indent + "  ")
 				buf.WriteString(tb.Head.Show(indent + "    "))
+				// Collapse long runs of the same frame, which are typical of
+				// runaway recursions.
+				repeats := 0
+				for next := tb.Next; next != nil && sameFrame(next, tb); next = next.Next {
+					repeats++
+				}
+				if repeats >= minCollapsedFrames {
+					fmt.Fprintf(buf, "\n%s  [same frame repeated %d more times]",
+						indent, repeats)
+					for ; repeats > 0; repeats-- {
+						tb = tb.Next
+					}
+				}
 			}
 		}
 	}
@@ -89,6 +105,15 @@ func (exc *Exception) Show(indent string) string {
 	return buf.String()
 }
 
+// Runs of the same frame in a stack trace are collapsed when they have at least
+// this many repetitions.
+const minCollapsedFrames = 3
+
+func sameFrame(a, b *StackTrace) bool {
+	return a.Head.Name == b.Head.Name && a.Head.Source == b.Head.Source &&
+		a.Head.Ranging == b.Head.Ranging
+}
+
 // Kind returns "exception".
 func (exc *Exception) Kind() string {
 	return "exception"
@@ -125,6 +150,35 @@ type excFields struct{ e *Exception }
 func (excFields) IsStructMap()    {}
 func (f excFields) Reason() error { return f.e.Reason }
 
+func (f excFields) Class() *ExceptionClass { return ExceptionClassOf(f.e.Reason) }
+
+// Stack returns the stack trace as a list of maps, innermost first.
+func (f excFields) Stack() vals.List {
+	li := vals.EmptyList
+	for tb := f.e.StackTrace; tb != nil; tb = tb.Next {
+		li = li.Cons(vals.MakeMap(
+			"file", tb.Head.Name,
+			"begin", strconv.Itoa(tb.Head.From),
+			"end", strconv.Itoa(tb.Head.To),
+			"fn-name", tb.FnName))
+	}
+	return li
+}
+
+// Returns the name of a function for use in stack traces.
+func fnName(fn Callable) string {
+	switch fn := fn.(type) {
+	case *closure:
+		return fn.Name
+	case *goFn:
+		return fn.name
+	case ExternalCmd:
+		return fn.Name
+	default:
+		return ""
+	}
+}
+
 // PipelineError represents the errors of pipelines, in which multiple commands
 // may error.
 type PipelineError struct {
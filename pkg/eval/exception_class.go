@@ -0,0 +1,121 @@
+package eval
+
+import (
+	"unsafe"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/xiaq/persistent/hash"
+)
+
+// ExceptionClass classifies exceptions by their reasons. The except clauses of
+// the try special command can specify a class to only catch exceptions of that
+// class.
+//
+// Classes form a hierarchy rooted at the "exception" class, to which all
+// exceptions belong. All the classes are predefined, and each class is
+// represented by a unique *ExceptionClass.
+type ExceptionClass struct {
+	name   string
+	parent *ExceptionClass
+}
+
+var (
+	excClassRoot      = &ExceptionClass{"exception", nil}
+	excClassFail      = &ExceptionClass{"fail-error", excClassRoot}
+	excClassArity     = &ExceptionClass{"arity-error", excClassRoot}
+	excClassType      = &ExceptionClass{"type-error", excClassRoot}
+	excClassPipeline  = &ExceptionClass{"pipeline-error", excClassRoot}
+	excClassInterrupt = &ExceptionClass{"interrupt-error", excClassRoot}
+	excClassRecursion = &ExceptionClass{"recursion-error", excClassRoot}
+	excClassRestrict  = &ExceptionClass{"restriction-error", excClassRoot}
+	excClassTimeout   = &ExceptionClass{"timeout-error", excClassRoot}
+	excClassExternal  = &ExceptionClass{"external-cmd-error", excClassRoot}
+	excClassExited    = &ExceptionClass{"external-cmd-exited", excClassExternal}
+	excClassSignaled  = &ExceptionClass{"external-cmd-signaled", excClassExternal}
+	excClassStopped   = &ExceptionClass{"external-cmd-stopped", excClassExternal}
+)
+
+var excClassesByName = map[string]*ExceptionClass{}
+
+func init() {
+	for _, c := range []*ExceptionClass{
+		excClassRoot, excClassFail, excClassArity, excClassType,
+		excClassPipeline, excClassInterrupt, excClassRecursion,
+		excClassRestrict, excClassTimeout, excClassExternal,
+		excClassExited, excClassSignaled, excClassStopped} {
+		excClassesByName[c.name] = c
+	}
+}
+
+// ExceptionClassOf returns the class of an exception with the given reason.
+func ExceptionClassOf(reason error) *ExceptionClass {
+	switch reason := reason.(type) {
+	case FailError:
+		return excClassFail
+	case errs.ArityMismatch:
+		return excClassArity
+	case errs.BadValue:
+		return excClassType
+	case PipelineError:
+		return excClassPipeline
+	case RecursionLimitExceeded:
+		return excClassRecursion
+	case RestrictionViolation:
+		return excClassRestrict
+	case TimeoutExceeded:
+		return excClassTimeout
+	case ExternalCmdExit:
+		switch {
+		case reason.Exited():
+			return excClassExited
+		case reason.Signaled():
+			return excClassSignaled
+		case reason.Stopped():
+			return excClassStopped
+		default:
+			return excClassExternal
+		}
+	}
+	if reason == ErrInterrupted {
+		return excClassInterrupt
+	}
+	return excClassRoot
+}
+
+// Is returns whether c is the same as cls or a descendant of it.
+func (c *ExceptionClass) Is(cls *ExceptionClass) bool {
+	for ; c != nil; c = c.parent {
+		if c == cls {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind returns "exception-class".
+func (c *ExceptionClass) Kind() string { return "exception-class" }
+
+// Repr returns an opaque representation containing the name of the class.
+func (c *ExceptionClass) Repr(int) string { return "<exception-class " + c.name + ">" }
+
+// Equal compares by address.
+func (c *ExceptionClass) Equal(rhs interface{}) bool { return c == rhs }
+
+// Hash returns the hash of the address.
+func (c *ExceptionClass) Hash() uint32 { return hash.Pointer(unsafe.Pointer(c)) }
+
+func (c *ExceptionClass) Fields() vals.StructMap { return excClassFields{c} }
+
+type excClassFields struct{ c *ExceptionClass }
+
+func (excClassFields) IsStructMap()   {}
+func (f excClassFields) Name() string { return f.c.name }
+
+// Parent returns the parent class, or $nil for the root class.
+func (f excClassFields) Parent() interface{} {
+	if f.c.parent == nil {
+		return nil
+	}
+	return f.c.parent
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/elves/elvish/pkg/diag"
 	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
 
 	. "github.com/elves/elvish/pkg/eval/evaltest"
 	"github.com/elves/elvish/pkg/eval/vals"
@@ -32,9 +33,10 @@ func TestException(t *testing.T) {
 		Hash(hash.Pointer(unsafe.Pointer(exc))).
 		Equal(exc).
 		NotEqual(makeException(errors.New("error"))).
-		AllKeys("reason").
+		AllKeys("class", "reason", "stack").
+		Index("class", ExceptionClassOf(err)).
 		Index("reason", err).
-		IndexError("stack", vals.NoSuchKey("stack")).
+		Index("stack", vals.EmptyList).
 		Repr("[&reason=[&content=error &type=fail]]")
 
 	vals.TestValue(t, OK).
@@ -51,6 +53,16 @@ func makeException(cause error, entries ...*diag.Context) *Exception {
 	return &Exception{cause, s}
 }
 
+func TestException_Stack(t *testing.T) {
+	Test(t,
+		That("fn f { fail foo }; put ?(f)[stack][0][file begin end fn-name]").
+			Puts("[test]", "7", "16", "fail"),
+		That("fn f { fail foo }; put ?(f)[stack][1][file begin end fn-name]").
+			Puts("[test]", "25", "26", "f"),
+		That("count ?(fail foo)[stack]").Puts("1"),
+	)
+}
+
 func TestFlow_Fields(t *testing.T) {
 	Test(t,
 		That("put ?(return)[reason][type name]").Puts("flow", "return"),
@@ -92,3 +104,54 @@ func TestErrorMethods(t *testing.T) {
 		tt.Args(Flow(1000)).Rets("!(BAD FLOW: 1000)"),
 	})
 }
+
+func TestException_Show_CollapsesRepeatedFrames(t *testing.T) {
+	inner := diag.NewContext("a.elv", "f; g", diag.Ranging{From: 3, To: 4})
+	outer := diag.NewContext("a.elv", "f; g", diag.Ranging{From: 0, To: 1})
+	exc := makeException(errors.New("error"), inner, inner, inner, inner, outer)
+	want := "Exception: \033[31;1merror\033[m\n" +
+		"Traceback:\n" +
+		"  " + inner.Show("    ") + "\n" +
+		"  [same frame repeated 3 more times]\n" +
+		"  " + outer.Show("    ")
+	if got := exc.Show(""); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Short runs are not collapsed.
+	exc = makeException(errors.New("error"), inner, inner, outer)
+	want = "Exception: \033[31;1merror\033[m\n" +
+		"Traceback:\n" +
+		"  " + inner.Show("    ") + "\n" +
+		"  " + inner.Show("    ") + "\n" +
+		"  " + outer.Show("    ")
+	if got := exc.Show(""); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExceptionClass(t *testing.T) {
+	c := ExceptionClassOf(FailError{"error"})
+	vals.TestValue(t, c).
+		Kind("exception-class").
+		Hash(hash.Pointer(unsafe.Pointer(c))).
+		Equal(c).
+		NotEqual(ExceptionClassOf(errors.New("error"))).
+		AllKeys("name", "parent").
+		Index("name", "fail-error").
+		Index("parent", ExceptionClassOf(errors.New("error"))).
+		Repr("<exception-class fail-error>")
+
+	Test(t,
+		That("put (exception-class exception)[name]").Puts("exception"),
+		That("put (exception-class exception)[parent]").Puts(nil),
+		That("put (exception-class external-cmd-exited)[parent][name]").
+			Puts("external-cmd-error"),
+		That("eq ?(fail bad)[class] (exception-class fail-error)").Puts(true),
+		That("put ?(nop (fail bad))[class][name]").Puts("fail-error"),
+		That("put (exception-class recursion-error)[parent][name]").
+			Puts("exception"),
+		That("exception-class bad").Throws(errs.BadValue{
+			What: "exception class", Valid: "a known exception class", Actual: "bad"}),
+	)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/fsutil"
@@ -45,13 +46,17 @@ func (e ExternalCmd) Repr(int) string {
 
 // Call calls an external command.
 func (e ExternalCmd) Call(fm *Frame, argVals []interface{}, opts map[string]interface{}) error {
-	if len(opts) > 0 {
-		return ErrExternalCmdOpts
+	attrs, err := fm.spawn.withOpts(opts)
+	if err != nil {
+		return err
 	}
-	if fsutil.DontSearch(e.Name) {
+	if fsutil.DontSearch(e.Name) || (fm.Evaler.state.getAutoCd() && !inPath(e.Name)) {
 		stat, err := os.Stat(e.Name)
 		if err == nil && stat.IsDir() {
 			// implicit cd
+			if len(opts) > 0 {
+				return ErrExternalCmdOpts
+			}
 			if len(argVals) > 0 {
 				return ErrImplicitCdNoArg
 			}
@@ -73,6 +78,9 @@ func (e ExternalCmd) Call(fm *Frame, argVals []interface{}, opts map[string]inte
 		args[i+1] = vals.ToString(a)
 	}
 
+	if err := fm.CheckRestriction(RestrictExternalCommands); err != nil {
+		return err
+	}
 	path, err := exec.LookPath(e.Name)
 	if err != nil {
 		return err
@@ -80,11 +88,35 @@ func (e ExternalCmd) Call(fm *Frame, argVals []interface{}, opts map[string]inte
 
 	args[0] = path
 
-	sys := makeSysProcAttr(fm.background)
-	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	sys := makeSysProcAttr(fm.background || (attrs != nil && attrs.newPgroup))
+	proc, err := startProcess(path, args, &os.ProcAttr{Files: files, Sys: sys}, attrs)
 	if err != nil {
 		return err
 	}
+	tracing := fm.Evaler.tracing()
+	var start time.Time
+	if tracing {
+		start = time.Now()
+		fm.Evaler.trace(TraceEvent{Kind: TraceExternalStart, Stack: fm.traceback,
+			Start: start, Pid: proc.Pid})
+	}
+
+	if done := fm.Interrupts(); done != nil {
+		// Interrupt signals are also delivered to the command by the terminal,
+		// and the command may choose to handle them. Other cancellations, like
+		// the editor abandoning a prompt callback, kill the command.
+		waited := make(chan struct{})
+		defer close(waited)
+		go func() {
+			select {
+			case <-done:
+				if !interruptedBySignal(fm.ctx) {
+					proc.Kill()
+				}
+			case <-waited:
+			}
+		}()
+	}
 
 	state, err := proc.Wait()
 	if err != nil {
@@ -94,5 +126,16 @@ func (e ExternalCmd) Call(fm *Frame, argVals []interface{}, opts map[string]inte
 		// calling `Wait` twice on a particular process object.
 		return err
 	}
-	return NewExternalCmdExit(e.Name, state.Sys().(syscall.WaitStatus), proc.Pid)
+	err = NewExternalCmdExit(e.Name, state.Sys().(syscall.WaitStatus), proc.Pid)
+	if tracing {
+		fm.Evaler.trace(TraceEvent{Kind: TraceExternalEnd, Stack: fm.traceback,
+			Start: start, Duration: time.Since(start), Pid: proc.Pid, Err: err})
+	}
+	return err
+}
+
+// Returns whether an external command can be found in the search path.
+func inPath(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
 }
@@ -3,7 +3,15 @@
 package eval_test
 
 import (
+	"context"
 	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/testutil"
 )
 
 func exitWaitStatus(exit uint32) syscall.WaitStatus {
@@ -12,3 +20,30 @@ func exitWaitStatus(exit uint32) syscall.WaitStatus {
 	// for a process that exits normally; i.e., not due to a signal.
 	return syscall.WaitStatus(exit << 8)
 }
+
+func TestExternalCmd_KilledWhenContextIsCancelled(t *testing.T) {
+	ev := NewEvaler()
+	ctx, cancel := context.WithTimeout(context.Background(), testutil.ScaledMs(10))
+	defer cancel()
+
+	start := time.Now()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "e:sleep 10"},
+		EvalCfg{Context: ctx})
+	if err == nil {
+		t.Errorf("got nil error, want error")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("external command ran for %v after the context was cancelled", d)
+	}
+}
+
+func TestExternalCmd_KilledByWithTimeout(t *testing.T) {
+	start := time.Now()
+	Test(t,
+		That("with-timeout 10ms { e:sleep 10 }").
+			Throws(TimeoutExceeded{10 * time.Millisecond}),
+	)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("external command ran for %v after the timeout", d)
+	}
+}
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/parse"
-	"github.com/elves/elvish/pkg/prog"
 	"github.com/elves/elvish/pkg/strutil"
 )
 
@@ -23,12 +23,30 @@ type Frame struct {
 
 	local, up *Ns
 
-	intCh <-chan struct{}
+	// Cancelled when the evaluation should be interrupted.
+	ctx   context.Context
 	ports []*Port
 
 	traceback *StackTrace
 
 	background bool
+
+	// Attributes applied to spawned external commands, set with the nice,
+	// ionice and ulimit commands. Nil if there are none.
+	spawn *spawnAttrs
+
+	// Restrictions in effect in addition to the Restrictions of the Evaler,
+	// set with EvalCfg.
+	restrictions Restrictions
+
+	// Functions registered with the defer builtin. Shared by all the frames
+	// forked from the frame of a closure call, so that they are called when
+	// the closure returns. Nil outside closures.
+	deferred *deferredFns
+
+	// Depth of nested function calls and pipelines, checked against the
+	// limits in the Evaler.
+	callDepth, pipelineDepth int
 }
 
 // Close releases resources allocated for this frame. It always returns a nil
@@ -119,8 +137,9 @@ func (fm *Frame) fork(name string) *Frame {
 	return &Frame{
 		fm.Evaler, fm.srcMeta,
 		fm.local, fm.up,
-		fm.intCh, newPorts,
-		fm.traceback, fm.background,
+		fm.ctx, newPorts,
+		fm.traceback, fm.background, fm.spawn, fm.restrictions, fm.deferred,
+		fm.callDepth, fm.pipelineDepth,
 	}
 }
 
@@ -152,10 +171,11 @@ func (fm *Frame) PipeOutput(f func(*Frame) error, vCb func(<-chan interface{}),
 	return err
 }
 
-func (fm *Frame) addTraceback(r diag.Ranger) *StackTrace {
+func (fm *Frame) addTraceback(r diag.Ranger, fnName string) *StackTrace {
 	return &StackTrace{
-		Head: diag.NewContext(fm.srcMeta.Name, fm.srcMeta.Code, r.Range()),
-		Next: fm.traceback,
+		Head:   fm.srcMeta.Context(r),
+		FnName: fnName,
+		Next:   fm.traceback,
 	}
 }
 
@@ -168,7 +188,7 @@ func (fm *Frame) errorp(r diag.Ranger, e error) error {
 		return e
 	default:
 		return &Exception{e, &StackTrace{
-			Head: diag.NewContext(fm.srcMeta.Name, fm.srcMeta.Code, r.Range()),
+			Head: fm.srcMeta.Context(r),
 			Next: fm.traceback,
 		}}
 	}
@@ -179,16 +199,13 @@ func (fm *Frame) errorpf(r diag.Ranger, format string, args ...interface{}) erro
 	return fm.errorp(r, fmt.Errorf(format, args...))
 }
 
-// Deprecate shows a deprecation message. The message is not shown if the same
-// deprecation message has been shown for the same location before.
-func (fm *Frame) Deprecate(msg string, ctx *diag.Context) {
+// Deprecate shows a deprecation for the source range in ctx, or the current
+// command if ctx is nil. The deprecation is not shown if it is hidden by
+// prog.DeprecationLevel, or if the same deprecation has been shown for the
+// same location before.
+func (fm *Frame) Deprecate(dep Deprecation, ctx *diag.Context) {
 	if ctx == nil {
 		ctx = fm.traceback.Head
 	}
-	dep := deprecation{ctx.Name, ctx.Ranging, msg}
-	if prog.ShowDeprecations && fm.deprecations.register(dep) {
-		err := diag.Error{
-			Type: "deprecation", Message: dep.message, Context: *ctx}
-		fm.ErrorFile().WriteString(err.Show("") + "\n")
-	}
+	fm.deprecations.show(fm.ErrorFile(), dep, ctx)
 }
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/elves/elvish/pkg/eval/vals"
@@ -32,6 +35,8 @@ const (
 	// noMatchOK indicates that the "nomatch-ok" glob index modifer was
 	// present.
 	noMatchOK GlobFlag = 1 << iota
+	// statOutput indicates that the "stat" glob index modifier was present.
+	statOutput
 )
 
 func (f GlobFlag) Has(g GlobFlag) bool {
@@ -49,6 +54,7 @@ var (
 	ErrWildcardNoMatch       = errors.New("wildcard has no match")
 	ErrMultipleTypeModifiers = errors.New("only one type modifier allowed")
 	ErrUnknownTypeModifier   = errors.New("unknown type modifier")
+	ErrBadDepthModifier      = errors.New("depth modifier must be a positive integer")
 )
 
 var runeMatchers = map[string]func(rune) bool{
@@ -76,6 +82,16 @@ func (gp GlobPattern) Index(k interface{}) (interface{}, error) {
 	switch {
 	case modifier == "nomatch-ok":
 		gp.Flags |= noMatchOK
+	case modifier == "nocase":
+		gp.IgnoreCase = true
+	case modifier == "stat":
+		gp.Flags |= statOutput
+	case strings.HasPrefix(modifier, "depth:"):
+		depth, err := strconv.Atoi(modifier[len("depth:"):])
+		if err != nil || depth <= 0 {
+			return nil, ErrBadDepthModifier
+		}
+		gp.MaxDepth = depth
 	case strings.HasPrefix(modifier, "but:"):
 		gp.Buts = append(gp.Buts, modifier[len("but:"):])
 	case modifier == "match-hidden":
@@ -144,6 +160,10 @@ func (gp GlobPattern) Concat(v interface{}) (interface{}, error) {
 		gp.append(rhs.Segments[0])
 		gp.Flags |= rhs.Flags
 		gp.Buts = append(gp.Buts, rhs.Buts...)
+		gp.IgnoreCase = gp.IgnoreCase || rhs.IgnoreCase
+		if rhs.MaxDepth != 0 {
+			gp.MaxDepth = rhs.MaxDepth
+		}
 		// This handles illegal cases such as `**[type:regular]x*[type:directory]`.
 		if gp.TypeCb != nil && rhs.TypeCb != nil {
 			return nil, ErrMultipleTypeModifiers
@@ -163,8 +183,10 @@ func (gp GlobPattern) RConcat(v interface{}) (interface{}, error) {
 		segs := stringToSegments(lhs)
 		// We know gp contains exactly one segment.
 		segs = append(segs, gp.Segments[0])
-		return GlobPattern{Pattern: glob.Pattern{Segments: segs}, Flags: gp.Flags,
-			Buts: gp.Buts, TypeCb: gp.TypeCb}, nil
+		return GlobPattern{
+			Pattern: glob.Pattern{Segments: segs,
+				IgnoreCase: gp.IgnoreCase, MaxDepth: gp.MaxDepth},
+			Flags: gp.Flags, Buts: gp.Buts, TypeCb: gp.TypeCb}, nil
 	}
 
 	return nil, vals.ErrConcatNotImplemented
@@ -230,12 +252,86 @@ func stringToSegments(s string) []glob.Segment {
 	return segs
 }
 
+// splitExclusions splits segments of the form x~y, where both x and y contain
+// wildcards, into the pattern x and the exclusion pattern y. The exclusion
+// pattern may contain further exclusions, like in x~y~z.
+func splitExclusions(segs []glob.Segment) ([]glob.Segment, [][]glob.Segment) {
+	sawWild := false
+	for i, seg := range segs {
+		switch seg := seg.(type) {
+		case glob.Wild:
+			sawWild = true
+		case glob.Literal:
+			j := strings.IndexByte(seg.Data, '~')
+			if !sawWild || j == -1 {
+				continue
+			}
+			var tail []glob.Segment
+			if j+1 < len(seg.Data) {
+				tail = append(tail, glob.Literal{Data: seg.Data[j+1:]})
+			}
+			tail = append(tail, segs[i+1:]...)
+			if !hasWild(tail) {
+				continue
+			}
+			head := append([]glob.Segment(nil), segs[:i]...)
+			if j > 0 {
+				head = append(head, glob.Literal{Data: seg.Data[:j]})
+			}
+			exclusion, moreExclusions := splitExclusions(tail)
+			return head, append([][]glob.Segment{exclusion}, moreExclusions...)
+		}
+	}
+	return segs, nil
+}
+
+func hasWild(segs []glob.Segment) bool {
+	for _, seg := range segs {
+		if glob.IsWild(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded returns whether a path matches any of the exclusion patterns. An
+// exclusion pattern without slashes is matched against the last component of
+// the path, and one with slashes is matched against the whole path.
+func excluded(p string, exclusions []glob.Pattern) bool {
+	for _, exclusion := range exclusions {
+		target := p
+		if !matchesSlash(exclusion.Segments) {
+			target = path.Base(p)
+		}
+		if exclusion.Match(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSlash(segs []glob.Segment) bool {
+	for _, seg := range segs {
+		if glob.IsSlash(seg) || glob.IsWild1(seg, glob.StarStar) {
+			return true
+		}
+	}
+	return false
+}
+
 func doGlob(gp GlobPattern, abort <-chan struct{}) ([]interface{}, error) {
 	but := make(map[string]struct{})
 	for _, s := range gp.Buts {
 		but[s] = struct{}{}
 	}
 
+	segs, exclusionSegs := splitExclusions(gp.Segments)
+	gp.Segments = segs
+	exclusions := make([]glob.Pattern, len(exclusionSegs))
+	for i, segs := range exclusionSegs {
+		exclusions[i] = glob.Pattern{Segments: segs, IgnoreCase: gp.IgnoreCase}
+	}
+
 	vs := make([]interface{}, 0)
 	if !gp.Glob(func(pathInfo glob.PathInfo) bool {
 		select {
@@ -248,9 +344,16 @@ func doGlob(gp GlobPattern, abort <-chan struct{}) ([]interface{}, error) {
 		if _, ignore := but[pathInfo.Path]; ignore {
 			return true
 		}
+		if excluded(pathInfo.Path, exclusions) {
+			return true
+		}
 
 		if gp.TypeCb == nil || gp.TypeCb(pathInfo.Info.Mode()) {
-			vs = append(vs, pathInfo.Path)
+			if gp.Flags.Has(statOutput) {
+				vs = append(vs, statMap(pathInfo))
+			} else {
+				vs = append(vs, pathInfo.Path)
+			}
 		}
 		return true
 	}) {
@@ -261,3 +364,33 @@ func doGlob(gp GlobPattern, abort <-chan struct{}) ([]interface{}, error) {
 	}
 	return vs, nil
 }
+
+// statMap converts the result of globbing to a map, used when the "stat"
+// modifier is present.
+func statMap(pathInfo glob.PathInfo) vals.Map {
+	info := pathInfo.Info
+	return vals.MakeMap(
+		"path", pathInfo.Path,
+		"name", info.Name(),
+		"type", fileTypeName(info.Mode()),
+		"size", strconv.FormatInt(info.Size(), 10),
+		"mode", fmt.Sprintf("%#o", info.Mode().Perm()),
+		"mod-time", info.ModTime().Format(time.RFC3339Nano))
+}
+
+func fileTypeName(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode.IsRegular():
+		return "regular"
+	case mode&os.ModeNamedPipe != 0:
+		return "named-pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	default:
+		return "other"
+	}
+}
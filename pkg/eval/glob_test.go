@@ -107,3 +107,78 @@ func TestGlob_Type(t *testing.T) {
 		That("put **[type:unknown]").Throws(ErrUnknownTypeModifier),
 	)
 }
+
+func TestGlob_NoCase(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustMkdirAll("Dir")
+	testutil.MustCreateEmpty("a.GO", "b.go", "c.md", "Dir/d.Go")
+
+	Test(t,
+		That("put *.go").Puts("b.go"),
+		That("put *[nocase].go").Puts("a.GO", "b.go"),
+		That("put dir/*[nocase].go").Puts("Dir/d.Go"),
+	)
+}
+
+func TestGlob_Depth(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustMkdirAll("1/2/3")
+	testutil.MustCreateEmpty("a.go", "1/a.go", "1/2/3/a.go")
+
+	Test(t,
+		That("put **[depth:1].go").Puts("a.go"),
+		That("put **[depth:2].go").Puts("1/a.go", "a.go"),
+		That("put **[depth:0]").Throws(ErrBadDepthModifier),
+		That("put **[depth:x]").Throws(ErrBadDepthModifier),
+	)
+}
+
+func TestGlob_Exclusion(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustMkdirAll("d")
+	testutil.MustCreateEmpty("a.go", "a_test.go", "b_gen.go", "d/c.go", "d/c_test.go", "x~")
+
+	Test(t,
+		That("put *.go~*_test.go").Puts("a.go", "b_gen.go"),
+		That("put *.go~*_test.go~*_gen.go").Puts("a.go"),
+		That("put **.go~*_test.go").Puts("d/c.go", "a.go", "b_gen.go"),
+		That("put **.go~d/*").Puts("a.go", "a_test.go", "b_gen.go"),
+		// A ~ not followed by a wildcard is matched literally.
+		That("put *~").Puts("x~"),
+	)
+}
+
+func TestGlob_Braces(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustCreateEmpty("a.go", "b.md")
+
+	Test(t,
+		That("put *.{go,md}").Puts("a.go", "b.md"),
+		That("put *.{go,txt}").Puts("a.go"),
+		That("put *.{c,txt}").Throws(ErrWildcardNoMatch),
+	)
+}
+
+func TestGlob_Stat(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustMkdirAll("d")
+	testutil.MustWriteFile("foo", []byte("content"), 0600)
+
+	Test(t,
+		That("put *[stat] | each [x]{ put $x[path] $x[type] }").Puts(
+			"d", "dir", "foo", "regular"),
+		That("put *[stat][type:regular] | each [x]{ put $x[name] $x[size] }").Puts(
+			"foo", "7"),
+		That("put *[stat][type:regular] | each [x]{ put $x[mode] }").Puts("0600"),
+	)
+}
@@ -1,15 +1,26 @@
 package eval
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 )
 
-// Interrupts returns a channel that is closed when an interrupt signal comes.
+// Context returns the context of the frame, which is cancelled when the
+// evaluation is interrupted, either by an interrupt signal or by cancelling the
+// context passed in EvalCfg. Go functions that may block for a long time should
+// stop when it is cancelled, and return ErrInterrupted.
+func (fm *Frame) Context() context.Context {
+	return fm.ctx
+}
+
+// Interrupts returns a channel that is closed when the evaluation is
+// interrupted. It is a shorthand for fm.Context().Done().
 func (fm *Frame) Interrupts() <-chan struct{} {
-	return fm.intCh
+	return fm.ctx.Done()
 }
 
 // ErrInterrupted is thrown when the execution is interrupted by a signal.
@@ -25,10 +36,39 @@ func (fm *Frame) IsInterrupted() bool {
 	}
 }
 
+type interruptedBySignalKey struct{}
+
+// Derives a context that is also cancelled when intCh is closed. The returned
+// function must be called to release the resources associated with the
+// context.
+func withInterruptSignals(parent context.Context, intCh <-chan struct{}) (context.Context, func()) {
+	bySignal := new(int32)
+	ctx, cancel := context.WithCancel(
+		context.WithValue(parent, interruptedBySignalKey{}, bySignal))
+	go func() {
+		select {
+		case <-intCh:
+			atomic.StoreInt32(bySignal, 1)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Returns whether the context has been cancelled because of an interrupt
+// signal, as opposed to the cancellation of the context passed in EvalCfg.
+func interruptedBySignal(ctx context.Context) bool {
+	bySignal, ok := ctx.Value(interruptedBySignalKey{}).(*int32)
+	return ok && atomic.LoadInt32(bySignal) == 1
+}
+
 // ListenInterrupts returns a channel that is closed when SIGINT or SIGQUIT
-// has been received by the process. It also returns a function that should be
-// called when the channel is no longer needed.
+// has been received by the process, unless the signal is trapped with
+// TrapSignal. It also returns a function that should be called when the channel
+// is no longer needed.
 func ListenInterrupts() (<-chan struct{}, func()) {
+	atomic.AddInt32(&interruptListeners, 1)
 	sigCh := make(chan os.Signal)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGQUIT)
 	// Channel to return, closed after receiving the first SIGINT or SIGQUIT.
@@ -44,8 +84,8 @@ func ListenInterrupts() (<-chan struct{}, func()) {
 	loop:
 		for {
 			select {
-			case <-sigCh:
-				if !closed {
+			case sig := <-sigCh:
+				if !closed && !IsSignalTrapped(sig) {
 					close(intCh)
 					closed = true
 				}
@@ -54,6 +94,7 @@ func ListenInterrupts() (<-chan struct{}, func()) {
 			}
 		}
 		signal.Stop(sigCh)
+		atomic.AddInt32(&interruptListeners, -1)
 		close(stopped)
 	}()
 
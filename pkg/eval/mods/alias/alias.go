@@ -0,0 +1,46 @@
+// Package alias exposes functions for inspecting aliases as the alias: module.
+package alias
+
+import (
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Ns makes the namespace for the alias: module, for the aliases of the given
+// Evaler.
+func Ns(ev *eval.Evaler) *eval.Ns {
+	return eval.NsBuilder{}.AddGoFns("alias:", map[string]interface{}{
+		"list": func() vals.Map { return list(ev) },
+	}).Ns()
+}
+
+//elvdoc:fn list
+//
+// ```elvish
+// alias:list
+// ```
+//
+// Outputs a map from the names of all aliases to their bodies. Example:
+//
+// ```elvish-transcript
+// ~> alias ll 'ls -l'
+// ~> alias gst 'git status'
+// ~> alias:list
+// ▶ [&gst='git status' &ll='ls -l']
+// ~> keys (alias:list)
+// ▶ gst
+// ▶ ll
+// ```
+//
+// Aliases are defined with [`alias`](builtin.html#alias), and removed with
+// [`unalias`](builtin.html#unalias).
+
+func list(ev *eval.Evaler) vals.Map {
+	m := vals.EmptyMap
+	for _, name := range ev.AliasNames() {
+		if body, ok := ev.Alias(name); ok {
+			m = m.Assoc(name, body)
+		}
+	}
+	return m
+}
@@ -0,0 +1,21 @@
+package alias
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestList(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("alias", Ns(ev)).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("alias:list").Puts(vals.EmptyMap),
+		That("alias ll 'ls -l'; alias gst 'git status'; alias:list").
+			Puts(vals.MakeMap("gst", "git status", "ll", "ls -l")),
+		That("alias ll 'ls -l'; unalias ll; alias:list").Puts(vals.EmptyMap),
+	)
+}
@@ -107,13 +107,22 @@ fn -tilde-expand [p]{
       -info "Installing "$pkg
       mkdir -p $dest
       git clone ($-method-handler[git][src] $pkg $dom-cfg) $dest
+      version = (locked-version $pkg)
+      if $version {
+        -info "Checking out locked version "$version
+        git -C $dest checkout -q $version
+      } else {
+        -write-lock $pkg (git -C $dest rev-parse HEAD)
+      }
     }
 
     &upgrade= [pkg dom-cfg]{
       dest = (dest $pkg)
       -info "Updating "$pkg
       try {
-        git -C $dest pull
+        git -C $dest fetch -q
+        git -C $dest checkout -q origin/HEAD
+        -write-lock $pkg (git -C $dest rev-parse HEAD)
       } except _ {
           -error "Something failed, please check error above and retry."
       }
@@ -145,6 +154,35 @@ fn -tilde-expand [p]{
   ]
 ]
 
+# Return the filename of the lock file, which records the versions of installed
+# packages
+fn -lock-file {
+  put $-lib-dir/epm-lock.json
+}
+
+# Read the lock file, or return an empty map if it does not exist
+fn -read-lock {
+  file = (-lock-file)
+  if ?(test -f $file) {
+    from-json < $file
+  } else {
+    put [&]
+  }
+}
+
+fn -write-lock-map [lock]{
+  mkdir -p $-lib-dir
+  put $lock | to-json > (-lock-file)
+}
+
+fn -write-lock [pkg version]{
+  -write-lock-map (assoc (-read-lock) $pkg $version)
+}
+
+fn -remove-lock [pkg]{
+  -write-lock-map (dissoc (-read-lock) $pkg)
+}
+
 # Return the filename of the domain config file for the given domain
 # (regardless of whether it exists)
 fn -domain-config-file [dom]{
@@ -231,6 +269,7 @@ fn -uninstall-package [pkg]{
   dest = (dest $pkg)
   -info "Removing package "$pkg
   rm -rf $dest
+  -remove-lock $pkg
 }
 
 ######################################################################
@@ -254,6 +293,17 @@ fn metadata [pkg]{
   put $res
 }
 
+# Return the version of a package recorded in the lock file, or $false if
+# there is none
+fn locked-version [pkg]{
+  lock = (-read-lock)
+  if (has-key $lock $pkg) {
+    put $lock[$pkg]
+  } else {
+    put $false
+  }
+}
+
 # Print out information about a package
 fn query [pkg]{
   data = (metadata $pkg)
@@ -351,4 +401,17 @@ fn uninstall [@pkgs]{
   for pkg $pkgs {
     -uninstall-package $pkg
   }
+}
+
+# Install the package containing the module with the given spec, like
+# github.com/user/repo/mod, unless it is already installed. Used by "use" to
+# fetch modules that are not installed.
+fn fetch-module [spec]{
+  dom = (-package-domain $spec)
+  cfg = (-domain-config $dom)
+  if (not $cfg) {
+    fail "No config for domain '"$dom"'."
+  }
+  pkg = (str:split / $spec | take (+ $cfg[levels] 1) | str:join /)
+  install &silent-if-installed=$true $pkg
 }`
@@ -0,0 +1,241 @@
+// Package bytes exposes functions for working with binary data as the bytes:
+// module.
+package bytes
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io/ioutil"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Ns is the namespace for the bytes: module.
+var Ns = eval.NsBuilder{}.AddGoFns("bytes:", map[string]interface{}{
+	"from-string": fromString,
+	"read-file":   readFile,
+	"slurp":       slurp,
+
+	"from-hex":    fromHex,
+	"to-hex":      toHex,
+	"from-base64": fromBase64,
+	"to-base64":   toBase64,
+
+	"hash": hashData,
+}).Ns()
+
+// Binary data given as an argument, either bytes or a string.
+type data string
+
+func (d *data) ScanElvish(v interface{}) error {
+	switch v := v.(type) {
+	case vals.Bytes:
+		*d = data(v)
+	case string:
+		*d = data(v)
+	default:
+		return errMustBeBytesOrString
+	}
+	return nil
+}
+
+var errMustBeBytesOrString = errors.New("must be bytes or string")
+
+//elvdoc:fn from-string
+//
+// ```elvish
+// bytes:from-string $string
+// ```
+//
+// Outputs the bytes of `$string`.
+//
+// ```elvish-transcript
+// ~> bytes:from-string 你好
+// ▶ (bytes:from-hex e4bda0e5a5bd)
+// ~> count (bytes:from-string 你好)
+// ▶ 6
+// ```
+
+func fromString(s string) vals.Bytes { return vals.Bytes(s) }
+
+//elvdoc:fn read-file
+//
+// ```elvish
+// bytes:read-file $path
+// ```
+//
+// Outputs the content of the file at `$path` as bytes.
+//
+// @cf bytes:slurp
+
+func readFile(path string) (vals.Bytes, error) {
+	b, err := ioutil.ReadFile(path)
+	return vals.Bytes(b), err
+}
+
+//elvdoc:fn slurp
+//
+// ```elvish
+// bytes:slurp
+// ```
+//
+// Reads all of the byte input and outputs it as bytes. This is like
+// [`slurp`](builtin.html#slurp), except that the output is bytes instead of a
+// string.
+//
+// ```elvish-transcript
+// ~> echo hi | bytes:slurp
+// ▶ (bytes:from-hex 68690a)
+// ```
+//
+// @cf bytes:read-file
+
+func slurp(fm *eval.Frame) (vals.Bytes, error) {
+	b, err := ioutil.ReadAll(fm.InputFile())
+	return vals.Bytes(b), err
+}
+
+//elvdoc:fn from-hex
+//
+// ```elvish
+// bytes:from-hex $hex
+// ```
+//
+// Decodes `$hex`, a string of hexadecimal digits, into bytes.
+//
+// ```elvish-transcript
+// ~> bytes:from-hex 68690a
+// ▶ (bytes:from-hex 68690a)
+// ~> print (bytes:from-hex 68690a)
+// hi
+// ```
+//
+// @cf bytes:to-hex
+
+func fromHex(s string) (vals.Bytes, error) {
+	b, err := hex.DecodeString(s)
+	return vals.Bytes(b), err
+}
+
+//elvdoc:fn to-hex
+//
+// ```elvish
+// bytes:to-hex $data
+// ```
+//
+// Encodes `$data`, which can be bytes or a string, as a string of lowercase
+// hexadecimal digits.
+//
+// ```elvish-transcript
+// ~> bytes:to-hex hi
+// ▶ 6869
+// ```
+//
+// @cf bytes:from-hex
+
+func toHex(d data) string { return hex.EncodeToString([]byte(d)) }
+
+type base64Opts struct {
+	URL bool `name:"url"`
+}
+
+func (*base64Opts) SetDefaultOptions() {}
+
+func (opts base64Opts) encoding() *base64.Encoding {
+	if opts.URL {
+		return base64.URLEncoding
+	}
+	return base64.StdEncoding
+}
+
+//elvdoc:fn from-base64
+//
+// ```elvish
+// bytes:from-base64 &url=$false $string
+// ```
+//
+// Decodes `$string` as base64 into bytes. When `&url` is true, the URL-safe
+// alphabet, which uses `-` and `_` instead of `+` and `/`, is used.
+//
+// ```elvish-transcript
+// ~> bytes:from-base64 /w==
+// ▶ (bytes:from-hex ff)
+// ~> bytes:from-base64 &url _w==
+// ▶ (bytes:from-hex ff)
+// ```
+//
+// @cf bytes:to-base64
+
+func fromBase64(opts base64Opts, s string) (vals.Bytes, error) {
+	b, err := opts.encoding().DecodeString(s)
+	return vals.Bytes(b), err
+}
+
+//elvdoc:fn to-base64
+//
+// ```elvish
+// bytes:to-base64 &url=$false $data
+// ```
+//
+// Encodes `$data`, which can be bytes or a string, as base64. The `&url`
+// option is the same as in [`bytes:from-base64`](#bytesfrom-base64).
+//
+// ```elvish-transcript
+// ~> bytes:to-base64 (bytes:from-hex ff)
+// ▶ /w==
+// ~> bytes:to-base64 &url (bytes:from-hex ff)
+// ▶ _w==
+// ```
+//
+// @cf bytes:from-base64
+
+func toBase64(opts base64Opts, d data) string {
+	return opts.encoding().EncodeToString([]byte(d))
+}
+
+type hashOpts struct{ Algo string }
+
+func (opts *hashOpts) SetDefaultOptions() { opts.Algo = "sha256" }
+
+var hashFuncs = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var errBadHashAlgo = errors.New("hash algorithm must be one of md5, sha1, sha256 and sha512")
+
+//elvdoc:fn hash
+//
+// ```elvish
+// bytes:hash &algo=sha256 $data
+// ```
+//
+// Computes the hash of `$data`, which can be bytes or a string, and outputs it
+// as a string of lowercase hexadecimal digits. The `&algo` option specifies
+// the hash algorithm, and can be `md5`, `sha1`, `sha256` or `sha512`.
+//
+// ```elvish-transcript
+// ~> bytes:hash abc
+// ▶ ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+// ~> bytes:hash &algo=md5 (bytes:from-string a)
+// ▶ 0cc175b9c0f1b6a831c399e269772661
+// ```
+
+func hashData(opts hashOpts, d data) (string, error) {
+	newHash, ok := hashFuncs[opts.Algo]
+	if !ok {
+		return "", errBadHashAlgo
+	}
+	h := newHash()
+	h.Write([]byte(d))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,60 @@
+package bytes
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestBytes(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{"bin": "\xff\x00a"})
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("bytes", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("bytes:from-string 你好").Puts(vals.Bytes("你好")),
+		That("kind-of (bytes:from-string a)").Puts("bytes"),
+
+		That("bytes:read-file bin").Puts(vals.Bytes("\xff\x00a")),
+		That("bytes:read-file nonexistent").Throws(AnyError),
+		That("print (bytes:from-hex ff00) | bytes:slurp").Puts(vals.Bytes("\xff\x00")),
+
+		// Operations on bytes.
+		That("b = (bytes:read-file bin)", "count $b", "put $b[0] $b[1..]").
+			Puts("3", "255", vals.Bytes("\x00a")),
+		That("put (bytes:from-hex ff)(bytes:from-string a)").Puts(vals.Bytes("\xffa")),
+		That("eq (bytes:from-string a) a").Puts(false),
+		That("eq (bytes:from-string a) (bytes:from-hex 61)").Puts(true),
+		That("to-string (bytes:from-hex 6869)").Puts("hi"),
+		That("print (bytes:from-hex 6869)").Prints("hi"),
+
+		That("bytes:from-hex 68690a").Puts(vals.Bytes("hi\n")),
+		That("bytes:from-hex xx").Throws(AnyError),
+		That("bytes:to-hex (bytes:from-string hi)").Puts("6869"),
+		That("bytes:to-hex hi").Puts("6869"),
+		That("bytes:to-hex [hi]").Throws(AnyError),
+
+		That("bytes:from-base64 /w==").Puts(vals.Bytes("\xff")),
+		That("bytes:from-base64 &url _w==").Puts(vals.Bytes("\xff")),
+		That("bytes:from-base64 '!'").Throws(AnyError),
+		That("bytes:to-base64 (bytes:from-hex ff)").Puts("/w=="),
+		That("bytes:to-base64 &url (bytes:from-hex ff)").Puts("_w=="),
+
+		That("bytes:hash abc").
+			Puts("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"),
+		That("bytes:hash &algo=md5 (bytes:from-string a)").
+			Puts("0cc175b9c0f1b6a831c399e269772661"),
+		That("bytes:hash &algo=sha1 abc").
+			Puts("a9993e364706816aba3e25717850c26c9cd0d89d"),
+		That("bytes:hash &algo=sha512 ''").
+			Puts("cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce"+
+				"47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e"),
+		That("bytes:hash &algo=crc32 abc").Throws(errBadHashAlgo),
+	)
+}
@@ -0,0 +1,392 @@
+// Package direnv implements the direnv: module, which loads environment
+// variables from trusted per-directory files when changing directory.
+package direnv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/store"
+)
+
+// FileName is the name of per-directory environment files.
+const FileName = ".elvish-env"
+
+//elvdoc:var enabled
+//
+// Whether to load environment files, defaulting to `$false`.
+//
+// When enabled, each time the working directory changes, the nearest file
+// named `.elvish-env` in the working directory or its ancestors is looked for.
+// If the file is trusted, it is evaluated, and the environment variables it
+// defines are set. They are restored to their previous values when leaving the
+// directory containing the file, or when the file changes.
+//
+// A file is trusted after it is allowed with [`direnv:allow`](#direnvallow),
+// as long as its content doesn't change. Files that are not trusted are not
+// evaluated, and a message is shown instead, unless they have been denied with
+// [`direnv:deny`](#direnvdeny). Whether files are trusted is recorded in the
+// persistent store, so it is shared by all Elvish sessions.
+//
+// The file is evaluated in an Elvish interpreter separate from the one of the
+// session, which can't run external commands, write files, or change the
+// environment variables or the working directory. The variable `$dir` is the
+// directory containing the file. After evaluating the file:
+//
+// -   The variable `$env`, if defined, is a map from the names of environment
+//     variables to their values. A value of `$nil` unsets the variable.
+//
+// -   Other variables defined by the file become the keys of
+//     [`$direnv:vars`](#direnvvars).
+//
+// Outputs of the file are discarded, and errors are shown.
+//
+// Example `.elvish-env` file:
+//
+// ```elvish
+// env = [&GOPATH=$dir/go &GOFLAGS=-mod=vendor &GOPROXY=$nil]
+// project = myproject
+// ```
+//
+// To enable loading environment files, add the following to `rc.elv`:
+//
+// ```elvish
+// use direnv
+// direnv:enabled = $true
+// ```
+
+//elvdoc:var file
+//
+// The path of the environment file that is loaded, or `$nil` if no file is
+// loaded.
+
+//elvdoc:var vars
+//
+// A map of the variables defined by the environment file that is loaded, other
+// than `$env` and `$dir`. It is an empty map if no file is loaded.
+
+//elvdoc:fn allow
+//
+// ```elvish
+// direnv:allow $path?
+// ```
+//
+// Trusts the environment file at `$path` with its current content, and reloads
+// the environment. The default `$path` is the nearest environment file in the
+// working directory or its ancestors.
+
+//elvdoc:fn deny
+//
+// ```elvish
+// direnv:deny $path?
+// ```
+//
+// Stops trusting the environment file at `$path`, and reloads the environment.
+// No message is shown for denied files. The default `$path` is the same as in
+// [`direnv:allow`](#direnvallow).
+
+//elvdoc:fn reload
+//
+// ```elvish
+// direnv:reload
+// ```
+//
+// Evaluates the environment file again, even if it hasn't changed.
+
+// Ns makes the direnv: namespace, and registers a hook with the Evaler for
+// loading environment files after changing directory.
+func Ns(ev *eval.Evaler, s store.Store) *eval.Ns {
+	return ns(ev, s, os.Stderr)
+}
+
+func ns(ev *eval.Evaler, s store.Store, stderr io.Writer) *eval.Ns {
+	l := &loader{ev: ev, store: s, stderr: stderr, vars: vals.EmptyMap}
+	ev.AddAfterChdir(func(string) { l.update(false) })
+	return eval.NsBuilder{
+		"enabled": vars.FromSetGet(l.setEnabled, l.getEnabled),
+		"file": vars.FromGet(func() interface{} {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			if l.file == "" {
+				return nil
+			}
+			return l.file
+		}),
+		"vars": vars.FromGet(func() interface{} {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			return l.vars
+		}),
+	}.AddGoFns("direnv:", map[string]interface{}{
+		"allow":  l.allow,
+		"deny":   l.deny,
+		"reload": func() { l.update(true) },
+	}).Ns()
+}
+
+type loader struct {
+	ev     *eval.Evaler
+	store  store.Store
+	stderr io.Writer
+
+	mutex   sync.Mutex
+	enabled bool
+	// The loaded file and the hash of its content.
+	file string
+	hash string
+	vars vals.Map
+	// The previous values of the environment variables set by the loaded
+	// file; nil for variables that were unset.
+	saved map[string]*string
+	// The file and hash that a message was last shown for, to avoid showing
+	// the same message every time the working directory changes.
+	warned string
+}
+
+func (l *loader) getEnabled() interface{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.enabled
+}
+
+func (l *loader) setEnabled(v interface{}) error {
+	enabled, ok := v.(bool)
+	if !ok {
+		return errs.BadValue{What: "enabled", Valid: "bool", Actual: vals.Kind(v)}
+	}
+	l.mutex.Lock()
+	l.enabled = enabled
+	if !enabled {
+		l.unload()
+	}
+	l.mutex.Unlock()
+	if enabled {
+		l.update(false)
+	}
+	return nil
+}
+
+func (l *loader) allow(args ...string) error {
+	path, err := l.pathArg(args)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = l.store.SetEnvTrust(path, store.EnvTrust{Allowed: true, Hash: hash(content)})
+	if err != nil {
+		return err
+	}
+	l.update(true)
+	return nil
+}
+
+func (l *loader) deny(args ...string) error {
+	path, err := l.pathArg(args)
+	if err != nil {
+		return err
+	}
+	err = l.store.SetEnvTrust(path, store.EnvTrust{Denied: true})
+	if err != nil {
+		return err
+	}
+	l.update(true)
+	return nil
+}
+
+// Returns the absolute path of the environment file given as an optional
+// argument, or the nearest one.
+func (l *loader) pathArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path, _, ok := find(wd)
+		if !ok {
+			return "", fmt.Errorf("no %s in the working directory or its ancestors", FileName)
+		}
+		return path, nil
+	case 1:
+		return filepath.Abs(args[0])
+	default:
+		return "", errs.ArityMismatch{
+			What: "arguments here", ValidLow: 0, ValidHigh: 1, Actual: len(args)}
+	}
+}
+
+// Loads the nearest environment file if it is trusted, after unloading the
+// loaded one. Unless force is true, nothing is done if the nearest file is
+// already loaded and hasn't changed.
+func (l *loader) update(force bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.enabled {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	path, content, found := find(wd)
+	h := hash(content)
+	if !force && (found && path == l.file && h == l.hash || !found && l.file == "") {
+		return
+	}
+	l.unload()
+	if !found {
+		return
+	}
+	trust, err := l.store.EnvTrust(path)
+	if err != nil {
+		fmt.Fprintf(l.stderr, "direnv: can't check whether %s is trusted: %v\n", path, err)
+		return
+	}
+	if trust.Denied {
+		return
+	}
+	if !trust.Allowed || trust.Hash != h {
+		if force || l.warned != path+"\x00"+h {
+			l.warned = path + "\x00" + h
+			fmt.Fprintf(l.stderr,
+				"direnv: %s is not trusted; run direnv:allow to trust it\n", path)
+		}
+		return
+	}
+	l.load(path, h, content)
+}
+
+// Evaluates the environment file and applies it. Must be called with the
+// mutex held.
+func (l *loader) load(path, h string, content []byte) {
+	fileEv := eval.NewEvaler()
+	fileEv.Restrictions = eval.Restrictions{
+		NoExternalCommands: true, NoFileWrites: true, NoEnvChanges: true}
+	fileEv.Global = eval.NsBuilder{
+		"dir": vars.NewReadOnly(filepath.Dir(path)),
+	}.Ns()
+	err := fileEv.Eval(
+		parse.Source{Name: path, Code: string(content), IsFile: true}, eval.EvalCfg{})
+	if err != nil {
+		fmt.Fprintf(l.stderr, "direnv: error in %s:\n", path)
+		diag.ShowError(l.stderr, err)
+		return
+	}
+
+	env := map[string]*string{}
+	fileVars := vals.EmptyMap
+	var bad []string
+	fileEv.Global.IterateKeys(func(k interface{}) bool {
+		name := k.(string)
+		if name == "dir" || strings.HasSuffix(name, eval.FnSuffix) ||
+			strings.HasSuffix(name, eval.NsSuffix) {
+			return true
+		}
+		v, _ := fileEv.Global.IndexString(name)
+		if name != "env" {
+			fileVars = fileVars.Assoc(name, v.Get())
+			return true
+		}
+		m, ok := v.Get().(vals.Map)
+		if !ok {
+			bad = append(bad, "$env must be a map")
+			return true
+		}
+		for it := m.Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			name, ok1 := k.(string)
+			value, ok2 := v.(string)
+			switch {
+			case ok1 && ok2:
+				env[name] = &value
+			case ok1 && v == nil:
+				env[name] = nil
+			default:
+				bad = append(bad, "bad entry in $env: "+vals.Repr(k, vals.NoPretty))
+			}
+		}
+		return true
+	})
+	if len(bad) > 0 {
+		fmt.Fprintf(l.stderr, "direnv: error in %s: %s\n", path, strings.Join(bad, "; "))
+		return
+	}
+	if err := l.ev.CheckRestriction(eval.RestrictEnvChanges); err != nil {
+		fmt.Fprintf(l.stderr, "direnv: can't load %s: %v\n", path, err)
+		return
+	}
+
+	l.file, l.hash, l.vars = path, h, fileVars
+	l.saved = make(map[string]*string, len(env))
+	for _, name := range sortedKeys(env) {
+		if old, ok := os.LookupEnv(name); ok {
+			l.saved[name] = &old
+		} else {
+			l.saved[name] = nil
+		}
+		setEnv(name, env[name])
+	}
+}
+
+// Restores the environment variables set by the loaded file. Must be called
+// with the mutex held.
+func (l *loader) unload() {
+	for _, name := range sortedKeys(l.saved) {
+		setEnv(name, l.saved[name])
+	}
+	l.file, l.hash, l.vars, l.saved = "", "", vals.EmptyMap, nil
+}
+
+func setEnv(name string, value *string) {
+	if value == nil {
+		vars.UnsetEnv(name)
+	} else {
+		vars.SetEnv(name, *value)
+	}
+}
+
+func sortedKeys(m map[string]*string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Finds the nearest environment file in dir or its ancestors, and reads it.
+func find(dir string) (string, []byte, bool) {
+	for {
+		path := filepath.Join(dir, FileName)
+		if content, err := ioutil.ReadFile(path); err == nil {
+			return path, content, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil, false
+		}
+		dir = parent
+	}
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
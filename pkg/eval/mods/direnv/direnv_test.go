@@ -0,0 +1,131 @@
+package direnv
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+type fixture struct {
+	t      *testing.T
+	ev     *eval.Evaler
+	stderr *bytes.Buffer
+}
+
+func setup(t *testing.T) (*fixture, string, func()) {
+	dir, cleanupDir := testutil.InTestDir()
+	s, cleanupStore := store.MustGetTempStore()
+	testutil.ApplyDir(testutil.Dir{
+		"p": testutil.Dir{
+			FileName: "env = [&DIRENV_FOO=foo &DIRENV_BAR=$nil]; x = $dir",
+			"sub":    testutil.Dir{},
+		},
+		"q":   testutil.Dir{},
+		"bad": testutil.Dir{FileName: "env = foo"},
+		"ext": testutil.Dir{FileName: "env = [&DIRENV_FOO=(cat file)]"},
+	})
+	restoreFoo := testutil.WithTempEnv("DIRENV_FOO", "")
+	restoreBar := testutil.WithTempEnv("DIRENV_BAR", "bar")
+	setEnv("DIRENV_FOO", nil)
+
+	ev := eval.NewEvaler()
+	var stderr bytes.Buffer
+	ev.Global = eval.NsBuilder{}.AddNs("direnv", ns(ev, s, &stderr)).Ns()
+	return &fixture{t, ev, &stderr}, dir, func() {
+		restoreBar()
+		restoreFoo()
+		cleanupStore()
+		cleanupDir()
+	}
+}
+
+// Evaluates code, and checks that it puts the given values.
+func (f *fixture) testPuts(code string, want ...interface{}) {
+	f.t.Helper()
+	r := EvalAndCollect(f.t, f.ev, []string{code})
+	if r.Exception != nil || r.CompilationError != nil {
+		f.t.Fatalf("%s: got exception %v, compilation error %v",
+			code, r.Exception, r.CompilationError)
+	}
+	if !reflect.DeepEqual(r.ValueOut, want) {
+		f.t.Errorf("%s: got values %v, want %v", code, r.ValueOut, want)
+	}
+}
+
+// Checks that the messages shown since the last call contain the given
+// string, or are empty if the string is empty.
+func (f *fixture) testStderr(want string) {
+	f.t.Helper()
+	got := f.stderr.String()
+	f.stderr.Reset()
+	if want == "" && got != "" || !strings.Contains(got, want) {
+		f.t.Errorf("got messages %q, want %q", got, want)
+	}
+}
+
+func TestDirenv(t *testing.T) {
+	f, dir, cleanup := setup(t)
+	defer cleanup()
+	envFile := filepath.Join(dir, "p", FileName)
+
+	// Environment files are not loaded until enabled.
+	f.testPuts("cd p; put $E:DIRENV_FOO; cd ..", "")
+	f.testStderr("")
+
+	// Untrusted files are not loaded, and a message is shown once.
+	f.testPuts("direnv:enabled = $true")
+	f.testPuts("cd p; put $E:DIRENV_FOO $direnv:file", "", nil)
+	f.testStderr(envFile + " is not trusted")
+	f.testPuts("cd sub; cd ..; cd ..")
+	f.testStderr("")
+
+	// Allowing a file loads it. The environment is restored when leaving the
+	// directory.
+	f.testPuts("cd p; direnv:allow; put $E:DIRENV_FOO (has-env DIRENV_BAR) $direnv:file $direnv:vars[x]",
+		"foo", false, envFile, filepath.Join(dir, "p"))
+	f.testPuts("cd sub; put $E:DIRENV_FOO; cd ../..", "foo")
+	f.testPuts("put $E:DIRENV_FOO $E:DIRENV_BAR $direnv:file", "", "bar", nil)
+	f.testStderr("")
+
+	// Changing the file makes it untrusted again.
+	f.testPuts("echo 'env = [&DIRENV_FOO=changed]' > p/" + FileName)
+	f.testPuts("cd p; put $E:DIRENV_FOO; cd ..", "")
+	f.testStderr(envFile + " is not trusted")
+
+	// Denied files are not loaded, without messages.
+	f.testPuts("cd p; direnv:deny; cd ..")
+	f.testStderr("")
+	f.testPuts("cd p; put $E:DIRENV_FOO; cd ..", "")
+	f.testStderr("")
+
+	// Disabling unloads the file.
+	f.testPuts("cd p; direnv:allow; put $E:DIRENV_FOO", "changed")
+	f.testPuts("direnv:enabled = $false; put $E:DIRENV_FOO $direnv:vars; cd ..",
+		"", vals.EmptyMap)
+}
+
+func TestDirenv_Errors(t *testing.T) {
+	f, _, cleanup := setup(t)
+	defer cleanup()
+	f.testPuts("direnv:enabled = $true")
+
+	// Environment files are evaluated with restrictions.
+	f.testPuts("cd ext; direnv:allow; put $E:DIRENV_FOO; cd ..", "")
+	f.testStderr("running external commands is not allowed")
+
+	f.testPuts("cd bad; direnv:allow; cd ..")
+	f.testStderr("$env must be a map")
+
+	r := EvalAndCollect(t, f.ev, []string{"cd q; direnv:allow"})
+	if r.Exception == nil {
+		t.Errorf("direnv:allow without an environment file didn't throw")
+	}
+}
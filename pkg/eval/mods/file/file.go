@@ -0,0 +1,313 @@
+// Package file exposes functions for working with file handles as the file:
+// module.
+package file
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Ns is the namespace for the file: module.
+var Ns = eval.NsBuilder{}.AddGoFns("file:", map[string]interface{}{
+	"open":  open,
+	"close": closeFile,
+	"seek":  seek,
+	"pipe":  pipe,
+
+	"try-lock":  tryLockFile,
+	"unlock":    unlockFile,
+	"with-lock": withLock,
+
+	"write-atomic": writeAtomic,
+}).Ns()
+
+// Used to close files and pipes opened with &auto-close.
+var closeFn = eval.NewGoFn("file:close", closeFile)
+
+var errAutoCloseOutsideFunction = errors.New("&auto-close can only be used in functions")
+
+// Flags for the modes accepted by file:open, the same as those of fopen(3).
+var modeFlags = map[string]int{
+	"r":  os.O_RDONLY,
+	"w":  os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+	"a":  os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	"r+": os.O_RDWR,
+	"w+": os.O_RDWR | os.O_CREATE | os.O_TRUNC,
+	"a+": os.O_RDWR | os.O_CREATE | os.O_APPEND,
+}
+
+type openOpts struct {
+	Mode      string
+	Perm      int
+	AutoClose bool
+}
+
+func (opts *openOpts) SetDefaultOptions() {
+	opts.Mode = "r"
+	opts.Perm = 0644
+}
+
+//elvdoc:fn open
+//
+// ```elvish
+// file:open &mode=r &perm=0o644 &auto-close=$false $path
+// ```
+//
+// Opens the file at `$path`, and outputs a file value that can be used in
+// redirections.
+//
+// The `&mode` option is one of the following, with the same meanings as the
+// modes of the `fopen` function in C:
+//
+// -   `r`: Open for reading.
+//
+// -   `w`: Open for writing, creating the file if it doesn't exist and
+//     truncating it otherwise.
+//
+// -   `a`: Open for appending, creating the file if it doesn't exist.
+//
+// -   `r+`, `w+` and `a+`: The same as `r`, `w` and `a` respectively, but the
+//     file is opened for both reading and writing.
+//
+// The `&perm` option gives the permission bits used when a new file is
+// created, before the umask is applied.
+//
+// The file stays open until it is closed with [`file:close`](#fileclose). If
+// `&auto-close` is true, the file is also closed automatically when the
+// enclosing function returns, like with [`defer`](builtin.html#defer); it is an
+// error to use `&auto-close` outside functions.
+//
+// ```elvish-transcript
+// ~> f = (file:open &mode=w a.txt)
+// ~> echo 'This is' > $f
+// ~> echo 'a file.' > $f
+// ~> file:close $f
+// ~> fn first-line [path]{
+//      f = (file:open &auto-close $path)
+//      head -n1 < $f
+//    }
+// ~> first-line a.txt
+// This is
+// ```
+//
+// This supersedes the builtin [`fopen`](builtin.html#fopen) command, which can
+// only open files for reading.
+//
+// @cf file:close file:seek
+
+func open(fm *eval.Frame, opts openOpts, name string) (vals.File, error) {
+	flag, ok := modeFlags[opts.Mode]
+	if !ok {
+		return nil, errs.BadValue{What: "option &mode",
+			Valid: "r, w, a, r+, w+ or a+", Actual: parse.Quote(opts.Mode)}
+	}
+	if flag != os.O_RDONLY {
+		if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(name, flag, os.FileMode(opts.Perm))
+	if err != nil {
+		return nil, err
+	}
+	if opts.AutoClose {
+		if fm.Defer(closeFn, f) != nil {
+			f.Close()
+			return nil, errAutoCloseOutsideFunction
+		}
+	}
+	return f, nil
+}
+
+//elvdoc:fn close
+//
+// ```elvish
+// file:close $file-or-pipe
+// ```
+//
+// Closes a file opened with [`file:open`](#fileopen), or both ends of a pipe
+// created with [`file:pipe`](#filepipe). Ends of the pipe that are already
+// closed, for example with [`pwclose`](builtin.html#pwclose), are ignored.
+//
+// @cf file:open file:pipe
+
+func closeFile(v interface{}) error {
+	switch v := v.(type) {
+	case vals.File:
+		return v.Close()
+	case vals.Pipe:
+		errR, errW := v.ReadEnd.Close(), v.WriteEnd.Close()
+		if errR != nil && !errors.Is(errR, os.ErrClosed) {
+			return errR
+		}
+		if errW != nil && !errors.Is(errW, os.ErrClosed) {
+			return errW
+		}
+		return nil
+	default:
+		return errs.BadValue{What: "argument",
+			Valid: "file or pipe", Actual: vals.Kind(v)}
+	}
+}
+
+// Values for the &whence option of file:seek.
+var whences = map[string]int{
+	"start":   io.SeekStart,
+	"current": io.SeekCurrent,
+	"end":     io.SeekEnd,
+}
+
+type seekOpts struct{ Whence string }
+
+func (opts *seekOpts) SetDefaultOptions() { opts.Whence = "start" }
+
+//elvdoc:fn seek
+//
+// ```elvish
+// file:seek &whence=start $file $offset
+// ```
+//
+// Sets the offset for the next read or write on `$file` to `$offset` bytes
+// relative to the position given by `&whence`, which can be `start`,
+// `current` or `end`. Outputs the new offset relative to the start of the
+// file.
+//
+// ```elvish-transcript
+// ~> echo 0123456789 > a.txt
+// ~> f = (file:open a.txt)
+// ~> file:seek $f 4
+// ▶ 4
+// ~> head -c3 < $f
+// 456
+// ~> file:seek &whence=current $f 0
+// ▶ 7
+// ~> file:seek &whence=end $f -3
+// ▶ 8
+// ~> file:close $f
+// ```
+
+func seek(opts seekOpts, f vals.File, offset int) (int, error) {
+	whence, ok := whences[opts.Whence]
+	if !ok {
+		return 0, errs.BadValue{What: "option &whence",
+			Valid: "start, current or end", Actual: parse.Quote(opts.Whence)}
+	}
+	pos, err := f.Seek(int64(offset), whence)
+	return int(pos), err
+}
+
+type pipeOpts struct{ AutoClose bool }
+
+func (*pipeOpts) SetDefaultOptions() {}
+
+//elvdoc:fn pipe
+//
+// ```elvish
+// file:pipe &auto-close=$false
+// ```
+//
+// Creates a new pipe that can be used in redirections, like the builtin
+// [`pipe`](builtin.html#pipe) command. Redirecting input from the pipe with
+// `<` uses its read end, and redirecting output to it with `>` uses its write
+// end.
+//
+// The pipe can be closed with [`file:close`](#fileclose), or one end at a time
+// with [`prclose`](builtin.html#prclose) and
+// [`pwclose`](builtin.html#pwclose). The `&auto-close` option works like in
+// [`file:open`](#fileopen).
+//
+// ```elvish-transcript
+// ~> p = (file:pipe)
+// ~> echo 'lorem ipsum' > $p
+// ~> pwclose $p
+// ~> cat < $p
+// lorem ipsum
+// ~> file:close $p
+// ```
+//
+// @cf file:close
+
+func pipe(fm *eval.Frame, opts pipeOpts) (vals.Pipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return vals.Pipe{}, err
+	}
+	p := vals.NewPipe(r, w)
+	if opts.AutoClose {
+		if fm.Defer(closeFn, p) != nil {
+			closeFile(p)
+			return vals.Pipe{}, errAutoCloseOutsideFunction
+		}
+	}
+	return p, nil
+}
+
+type writeAtomicOpts struct{ Perm int }
+
+func (opts *writeAtomicOpts) SetDefaultOptions() { opts.Perm = 0644 }
+
+//elvdoc:fn write-atomic
+//
+// ```elvish
+// file:write-atomic &perm=0o644 $path
+// ```
+//
+// Writes the byte input to the file at `$path` atomically: the input is
+// written to a temporary file in the same directory, which then replaces
+// `$path` by being renamed. Other processes reading `$path` either see its old
+// content or all of the new content, never a partially written file.
+//
+// If `$path` already exists, its permission bits are kept; otherwise the new
+// file gets the permission bits given by `&perm`. If there is an error, `$path`
+// is left unchanged and the temporary file is removed.
+//
+// ```elvish-transcript
+// ~> put [&count=1] | to-json | file:write-atomic cache.json
+// ~> cat cache.json
+// {"count":"1"}
+// ```
+
+func writeAtomic(fm *eval.Frame, opts writeAtomicOpts, name string) error {
+	if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+		return err
+	}
+	perm := os.FileMode(opts.Perm)
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir, base := filepath.Split(name)
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	err = writeAndClose(tmp, fm.InputFile(), perm)
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func writeAndClose(tmp *os.File, r io.Reader, perm os.FileMode) error {
+	_, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestFile(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{"a": "0123456789\n"})
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("file", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		// Reading.
+		That("f = (file:open a); slurp < $f; file:close $f").Puts("0123456789\n"),
+		That("kind-of (file:open a)").Puts("file"),
+		That("file:open nonexistent").Throws(AnyError),
+		That("file:open &mode=x a").Throws(errs.BadValue{
+			What: "option &mode", Valid: "r, w, a, r+, w+ or a+", Actual: "x"}),
+
+		// Writing, appending and reading and writing.
+		That("f = (file:open &mode=w b); echo foo > $f; echo bar > $f; file:close $f",
+			"slurp < b").Puts("foo\nbar\n"),
+		That("f = (file:open &mode=a b); echo lorem > $f; file:close $f",
+			"slurp < b").Puts("foo\nbar\nlorem\n"),
+		That("f = (file:open &mode=w+ b); echo ipsum > $f",
+			"file:seek $f 0; slurp < $f; file:close $f").Puts("0", "ipsum\n"),
+		That("f = (file:open &mode=r+ b); print I > $f; file:close $f",
+			"slurp < b").Puts("Ipsum\n"),
+
+		// Seeking.
+		That("f = (file:open a)",
+			"file:seek $f 4; head -c3 < $f; echo",
+			"file:seek &whence=current $f 0",
+			"file:seek &whence=end $f -3",
+			"file:close $f").Puts("4", "7", "8").Prints("456\n"),
+		That("f = (file:open a); try { file:seek &whence=x $f 0 } finally { file:close $f }").
+			Throws(errs.BadValue{
+				What: "option &whence", Valid: "start, current or end", Actual: "x"}),
+
+		// Pipes.
+		That("p = (file:pipe); echo foo > $p; pwclose $p; slurp < $p; file:close $p").
+			Puts("foo\n"),
+		That("p = (file:pipe); file:close $p; file:close $p").DoesNothing(),
+		That("kind-of (file:pipe)").Puts("pipe"),
+
+		// Closing.
+		That("f = (file:open a); file:close $f; file:close $f").Throws(AnyError),
+		That("file:close foo").Throws(errs.BadValue{
+			What: "argument", Valid: "file or pipe", Actual: "string"}),
+
+		// Automatically closing.
+		That("fn f { file:open &auto-close a }", "g = (f); file:close $g").
+			Throws(AnyError),
+		That("fn f { file:open &auto-close a }", "g = (f); slurp < $g").
+			Throws(AnyError),
+		That("fn f { f = (file:open &auto-close a); slurp < $f }", "f").
+			Puts("0123456789\n"),
+		That("fn f { file:pipe &auto-close }", "p = (f); file:close $p").DoesNothing(),
+		That("file:open &auto-close a").
+			Throws(errAutoCloseOutsideFunction),
+		That("file:pipe &auto-close").
+			Throws(errAutoCloseOutsideFunction),
+	)
+}
+
+func TestOpen_Restricted(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{"a": "0123456789\n"})
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("file", Ns).Ns()
+		ev.Restrictions.NoFileWrites = true
+	}
+	violation := eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}
+	TestWithSetup(t, setup,
+		That("f = (file:open a); slurp < $f; file:close $f").Puts("0123456789\n"),
+		That("file:open &mode=w b").Throws(violation),
+		That("file:open &mode=a b").Throws(violation),
+		That("file:open &mode=r+ a").Throws(violation),
+		That("file:open &mode=w+ b").Throws(violation),
+		That("file:open &mode=a+ b").Throws(violation),
+		// The file is not truncated.
+		That("try { file:open &mode=w a } except { }", "slurp < a").
+			Puts("0123456789\n"),
+	)
+}
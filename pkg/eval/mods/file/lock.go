@@ -0,0 +1,116 @@
+package file
+
+import (
+	"os"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// How often file:with-lock tries to acquire a lock held by someone else.
+var lockPollInterval = 20 * time.Millisecond
+
+type lockOpts struct{ Shared bool }
+
+func (*lockOpts) SetDefaultOptions() {}
+
+//elvdoc:fn try-lock
+//
+// ```elvish
+// file:try-lock &shared=$false $file
+// ```
+//
+// Tries to acquire an advisory lock on `$file`, a file opened with
+// [`file:open`](#fileopen), without waiting. Outputs `$true` if the lock is
+// acquired, and `$false` if a conflicting lock is held through another file
+// handle, possibly by another process.
+//
+// By default, the lock is exclusive. If `&shared` is true, the lock is shared,
+// and only conflicts with exclusive locks.
+//
+// The lock is released with [`file:unlock`](#fileunlock), or when the file is
+// closed. Locks are advisory: they only affect other attempts to acquire locks,
+// not reading and writing the file.
+//
+// ```elvish-transcript
+// ~> f1 = (file:open &mode=a lock); f2 = (file:open &mode=a lock)
+// ~> file:try-lock $f1
+// ▶ $true
+// ~> file:try-lock $f2
+// ▶ $false
+// ~> file:unlock $f1
+// ~> file:try-lock $f2
+// ▶ $true
+// ```
+//
+// @cf file:with-lock
+
+func tryLockFile(opts lockOpts, f vals.File) (bool, error) {
+	return tryLock(f, opts.Shared)
+}
+
+//elvdoc:fn unlock
+//
+// ```elvish
+// file:unlock $file
+// ```
+//
+// Releases the lock on `$file` acquired with
+// [`file:try-lock`](#filetry-lock).
+
+func unlockFile(f vals.File) error { return unlock(f) }
+
+//elvdoc:fn with-lock
+//
+// ```elvish
+// file:with-lock &shared=$false $path $callable
+// ```
+//
+// Acquires an advisory lock on the file at `$path`, creating it if it doesn't
+// exist, calls `$callable`, and releases the lock. If a conflicting lock is
+// held, for example by another Elvish process running `file:with-lock` on the
+// same path, waits until it is released; the wait can be interrupted with
+// Ctrl-C. The `&shared` option is the same as in
+// [`file:try-lock`](#filetry-lock).
+//
+// This is useful for serializing access to a resource shared by several
+// processes:
+//
+// ```elvish
+// file:with-lock ~/.cache/my-cache.lock {
+//   # Only one process runs this at a time.
+//   update-cache
+// }
+// ```
+//
+// The lock file is not removed afterwards.
+//
+// @cf file:try-lock
+
+func withLock(fm *eval.Frame, opts lockOpts, path string, f eval.Callable) error {
+	if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for {
+		ok, err := tryLock(file, opts.Shared)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-fm.Interrupts():
+			return eval.ErrInterrupted
+		case <-time.After(lockPollInterval):
+		}
+	}
+	defer unlock(file)
+	return f.Call(fm, eval.NoArgs, eval.NoOpts)
+}
@@ -0,0 +1,14 @@
+// +build plan9 js
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockNotSupported = errors.New("file locking is not supported on this platform")
+
+func tryLock(*os.File, bool) (bool, error) { return false, errLockNotSupported }
+
+func unlock(*os.File) error { return errLockNotSupported }
@@ -0,0 +1,100 @@
+// +build !plan9,!js
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestLock(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("file", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("f1 = (file:open &mode=a l1); f2 = (file:open &mode=a l1)",
+			"file:try-lock $f1; file:try-lock $f2",
+			"file:unlock $f1; file:try-lock $f2",
+			"file:close $f1; file:close $f2").Puts(true, false, true),
+		// Closing the file releases the lock.
+		That("f1 = (file:open &mode=a l2); f2 = (file:open &mode=a l2)",
+			"file:try-lock $f1; file:close $f1",
+			"file:try-lock $f2; file:close $f2").Puts(true, true),
+		// Shared locks only conflict with exclusive locks.
+		That("f1 = (file:open l2); f2 = (file:open l2); f3 = (file:open &mode=a l2)",
+			"file:try-lock &shared $f1; file:try-lock &shared $f2; file:try-lock $f3",
+			"file:close $f1; file:close $f2; file:close $f3").Puts(true, true, false),
+
+		That("file:with-lock l3 { put foo }").Puts("foo"),
+		// The lock is held while the callable runs, and released afterwards.
+		That("f = (file:open l3)",
+			"file:with-lock l3 { file:try-lock $f }",
+			"file:try-lock $f; file:close $f").Puts(false, true),
+		// Exceptions are propagated, and the lock is released.
+		That("file:with-lock l3 { fail foo }").Throws(eval.FailError{Content: "foo"}),
+		That("file:with-lock l3 { put foo }").Puts("foo"),
+		// Waiting for the lock can be interrupted.
+		That("f = (file:open &mode=a l4); file:try-lock $f",
+			"try { with-timeout 0.05 { file:with-lock l4 { put bad } } } finally { file:close $f }").
+			Puts(true).Throws(ErrorWithType(eval.TimeoutExceeded{})),
+		That("file:with-lock nonexistent/l { }").Throws(AnyError),
+	)
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{"old": testutil.File{Perm: 0600, Content: "old"}})
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("file", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("echo foo | file:write-atomic new", "slurp < new").Puts("foo\n"),
+		That("print new | file:write-atomic old", "slurp < old").Puts("new"),
+		That("echo | file:write-atomic nonexistent/new").Throws(AnyError),
+	)
+
+	for name, want := range map[string]os.FileMode{"new": 0644, "old": 0600} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != want {
+			t.Errorf("%s has permission %v, want %v", name, perm, want)
+		}
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*")); len(tmps) > 0 {
+		t.Errorf("temporary files left: %v", tmps)
+	}
+}
+
+func TestWithLockAndWriteAtomic_Restricted(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("file", Ns).Ns()
+		ev.Restrictions.NoFileWrites = true
+	}
+	violation := eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}
+	TestWithSetup(t, setup,
+		That("file:with-lock l { put bad }").Throws(violation),
+		That("echo foo | file:write-atomic new").Throws(violation),
+	)
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) > 0 {
+		t.Errorf("files created: %v", files)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".*")); len(tmps) > 0 {
+		t.Errorf("temporary files created: %v", tmps)
+	}
+}
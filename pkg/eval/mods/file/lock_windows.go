@@ -0,0 +1,28 @@
+package file
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Lock the whole file, whatever its size is.
+const allBytes = ^uint32(0)
+
+func tryLock(f *os.File, shared bool) (bool, error) {
+	flags := uint32(windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if !shared {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0,
+		allBytes, allBytes, new(windows.Overlapped))
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0,
+		allBytes, allBytes, new(windows.Overlapped))
+}
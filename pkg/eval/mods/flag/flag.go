@@ -0,0 +1,510 @@
+// Package flag exposes functions for parsing command-line flags as the flag:
+// module.
+package flag
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Ns is the namespace for the flag: module.
+var Ns = eval.NsBuilder{}.AddGoFns("flag:", map[string]interface{}{
+	"parse": parseFlags,
+	"usage": usage,
+}).Ns()
+
+// ErrHelp is thrown by flag:parse when -h or --help is given and not defined.
+var ErrHelp = errors.New("help requested")
+
+var (
+	errSpecWithoutName = errors.New("flag spec must have a name")
+	errBadShort        = errors.New("short name of flag must be a single character")
+)
+
+// Types of flags.
+const (
+	boolFlag   = "bool"
+	stringFlag = "string"
+	numberFlag = "number"
+	listFlag   = "list"
+)
+
+type flagSpec struct {
+	name, short, typ, help string
+	// The default value, already converted to the type of the flag.
+	def interface{}
+}
+
+// Converts a list of maps to flag specs.
+func parseSpecs(specsArg interface{}) ([]*flagSpec, error) {
+	var specs []*flagSpec
+	seen := make(map[string]bool)
+	var errSpec error
+	err := vals.Iterate(specsArg, func(v interface{}) bool {
+		spec, err := parseSpec(v)
+		if err == nil {
+			for _, name := range []string{"--" + spec.name, "-" + spec.short} {
+				if name != "-" && seen[name] {
+					err = fmt.Errorf("flag %s defined more than once", name)
+				}
+				seen[name] = true
+			}
+		}
+		if err != nil {
+			errSpec = err
+			return false
+		}
+		specs = append(specs, spec)
+		return true
+	})
+	if err == nil {
+		err = errSpec
+	}
+	return specs, err
+}
+
+func parseSpec(v interface{}) (*flagSpec, error) {
+	spec := &flagSpec{}
+	var def interface{}
+	var errKey error
+	err := vals.IterateKeys(v, func(k interface{}) bool {
+		var value interface{}
+		value, errKey = vals.Index(v, k)
+		if errKey != nil {
+			return false
+		}
+		switch k {
+		case "name":
+			errKey = vals.ScanToGo(value, &spec.name)
+		case "short":
+			errKey = vals.ScanToGo(value, &spec.short)
+			if errKey == nil && utf8.RuneCountInString(spec.short) != 1 {
+				errKey = errBadShort
+			}
+		case "type":
+			errKey = vals.ScanToGo(value, &spec.typ)
+		case "help":
+			errKey = vals.ScanToGo(value, &spec.help)
+		case "default":
+			def = value
+		default:
+			errKey = errs.BadValue{What: "key of flag spec",
+				Valid: "name, short, type, help or default", Actual: vals.Repr(k, vals.NoPretty)}
+		}
+		return errKey == nil
+	})
+	if err == nil {
+		err = errKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if spec.name == "" {
+		return nil, errSpecWithoutName
+	}
+
+	if spec.typ == "" {
+		spec.typ = inferType(def)
+	}
+	switch spec.typ {
+	case boolFlag:
+		spec.def = false
+	case stringFlag:
+		spec.def = ""
+	case numberFlag:
+		spec.def = 0.0
+	case listFlag:
+		spec.def = vals.EmptyList
+	default:
+		return nil, errs.BadValue{What: "type of flag --" + spec.name,
+			Valid: "bool, string, number or list", Actual: parse.Quote(spec.typ)}
+	}
+	if def != nil {
+		spec.def, err = convertDefault(spec.typ, def)
+		if err != nil {
+			return nil, fmt.Errorf("bad default value for flag --%s: %v", spec.name, err)
+		}
+	}
+	return spec, nil
+}
+
+// Infers the type of a flag from its default value when the type is not given.
+func inferType(def interface{}) string {
+	switch def.(type) {
+	case bool:
+		return boolFlag
+	case float64, *big.Int, *big.Rat:
+		return numberFlag
+	case vals.List:
+		return listFlag
+	default:
+		return stringFlag
+	}
+}
+
+func convertDefault(typ string, def interface{}) (interface{}, error) {
+	switch typ {
+	case boolFlag:
+		var b bool
+		err := vals.ScanToGo(def, &b)
+		return b, err
+	case numberFlag:
+		return scanNum(def)
+	case listFlag:
+		var elems []interface{}
+		err := vals.Iterate(def, func(v interface{}) bool {
+			elems = append(elems, v)
+			return true
+		})
+		return vals.MakeList(elems...), err
+	default:
+		var s string
+		err := vals.ScanToGo(def, &s)
+		return s, err
+	}
+}
+
+func scanNum(v interface{}) (interface{}, error) {
+	n, err := vals.ScanNum(v)
+	switch n := n.(type) {
+	case *big.Int:
+		return vals.NormalizeBigInt(n), err
+	case *big.Rat:
+		return vals.NormalizeBigRat(n), err
+	}
+	return n, err
+}
+
+type parseOpts struct{ Prog string }
+
+func (*parseOpts) SetDefaultOptions() {}
+
+//elvdoc:fn parse
+//
+// ```elvish
+// flag:parse &prog='' $args $specs
+// ```
+//
+// Parses the command-line flags in `$args` according to `$specs`, and outputs
+// two values: a map from the names of all the flags to their values, and a
+// list of the remaining arguments.
+//
+// Each element of `$specs` is a map describing a flag, with the following keys:
+//
+// -   `name`: The name of the flag, which is given on the command line as
+//     `--name`. This key is required.
+//
+// -   `short`: An optional single-character alternative name, which is given
+//     as `-n`.
+//
+// -   `type`: One of `bool`, `string`, `number` and `list`. When omitted, the
+//     type is inferred from the default value, and is `string` if there is no
+//     default value either.
+//
+// -   `default`: The value of the flag when it is not given. When omitted, it
+//     is `$false`, an empty string, `(float64 0)` or an empty list, depending
+//     on the type.
+//
+// -   `help`: A description of the flag, shown in the usage message.
+//
+// Flags are parsed as follows:
+//
+// -   A bool flag is set to `$true` with `--name` or `-n`. It can also be set
+//     explicitly with `--name=true` or `--name=false`.
+//
+// -   Other flags take a value, given as `--name value`, `--name=value`,
+//     `-n value` or `-nvalue`. The values of number flags are parsed like the
+//     arguments of [`num`](builtin.html#num).
+//
+// -   A list flag can be given more than once, and its value is a list of all
+//     the values given, replacing its default value.
+//
+// -   Several single-character bool flags can be combined, like `-abc`.
+//
+// Parsing stops at the first argument that is not a flag, or after `--`.
+//
+// If `-h` or `--help` is given without being defined in `$specs`, the usage
+// message (see [`flag:usage`](#flagusage)) is written to the byte error
+// output and an exception with the message `help requested` is thrown. If the
+// flags are invalid, the usage message is also written, and an exception
+// describing the problem is thrown.
+//
+// ```elvish-transcript
+// ~> specs = [
+//      [&name=verbose &short=v &type=bool &help='Show more output']
+//      [&name=output &short=o &default=out.txt &help='Write to this file']
+//      [&name=jobs &type=number &default=1 &help='Number of parallel jobs']
+//      [&name=tag &type=list &help='Tags to add']
+//    ]
+// ~> flags rest = (flag:parse [-v --jobs=4 --tag a --tag b in.txt] $specs)
+// ~> put $flags
+// ▶ [&jobs=(float64 4) &output=out.txt &tag=[a b] &verbose=$true]
+// ~> put $rest
+// ▶ [in.txt]
+// ~> flag:parse [--bad] $specs
+// flag provided but not defined: --bad
+// Usage:
+//   -v, --verbose
+//         Show more output
+//   -o, --output string
+//         Write to this file (default out.txt)
+//   --jobs number
+//         Number of parallel jobs (default 1)
+//   --tag list
+//         Tags to add (can be given more than once)
+// Exception: flag provided but not defined: --bad
+// [tty 4], line 1: flag:parse [--bad] $specs
+// ```
+//
+// A script can use it like this:
+//
+// ```elvish
+// use flag
+// flags rest = (flag:parse &prog=myscript $args $specs)
+// if $flags[verbose] {
+//   echo 'Writing to '$flags[output]
+// }
+// ```
+
+func parseFlags(fm *eval.Frame, opts parseOpts, argsArg, specsArg interface{}) error {
+	specs, err := parseSpecs(specsArg)
+	if err != nil {
+		return err
+	}
+	var args []string
+	err = vals.Iterate(argsArg, func(v interface{}) bool {
+		args = append(args, vals.ToString(v))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	values, rest, err := parseArgs(specs, args)
+	if err != nil {
+		if err != ErrHelp {
+			fmt.Fprintln(fm.ErrorFile(), err)
+		}
+		fm.ErrorFile().WriteString(makeUsage(opts.Prog, specs))
+		return err
+	}
+	out := fm.OutputChan()
+	out <- values
+	restList := vals.EmptyList
+	for _, arg := range rest {
+		restList = restList.Cons(arg)
+	}
+	out <- restList
+	return nil
+}
+
+func parseArgs(specs []*flagSpec, args []string) (vals.Map, []string, error) {
+	byName := make(map[string]*flagSpec)
+	values := make(map[string]interface{})
+	for _, spec := range specs {
+		byName["--"+spec.name] = spec
+		if spec.short != "" {
+			byName["-"+spec.short] = spec
+		}
+		values[spec.name] = spec.def
+	}
+	// Flags of the list type that have been given, and no longer have their
+	// default values.
+	listGiven := make(map[string]bool)
+	set := func(spec *flagSpec, flag, arg string) error {
+		switch spec.typ {
+		case boolFlag:
+			b, err := strconv.ParseBool(arg)
+			if err != nil {
+				return fmt.Errorf("invalid boolean value %s for flag %s", parse.Quote(arg), flag)
+			}
+			values[spec.name] = b
+		case numberFlag:
+			n, err := scanNum(arg)
+			if err != nil {
+				return fmt.Errorf("invalid number %s for flag %s", parse.Quote(arg), flag)
+			}
+			values[spec.name] = n
+		case listFlag:
+			if !listGiven[spec.name] {
+				values[spec.name] = vals.EmptyList
+				listGiven[spec.name] = true
+			}
+			values[spec.name] = values[spec.name].(vals.List).Cons(arg)
+		default:
+			values[spec.name] = arg
+		}
+		return nil
+	}
+
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		if strings.HasPrefix(arg, "--") {
+			flag, value, hasValue := arg, "", false
+			if eq := strings.IndexByte(arg, '='); eq != -1 {
+				flag, value, hasValue = arg[:eq], arg[eq+1:], true
+			}
+			spec, ok := byName[flag]
+			switch {
+			case !ok && flag == "--help":
+				return nil, nil, ErrHelp
+			case !ok:
+				return nil, nil, fmt.Errorf("flag provided but not defined: %s", flag)
+			case !hasValue && spec.typ == boolFlag:
+				value = "true"
+			case !hasValue:
+				if i+1 == len(args) {
+					return nil, nil, fmt.Errorf("flag needs an argument: %s", flag)
+				}
+				i++
+				value = args[i]
+			}
+			if err := set(spec, flag, value); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		// A group of short flags, like -abc.
+		shorts := arg[1:]
+		for shorts != "" {
+			r, size := utf8.DecodeRuneInString(shorts)
+			flag := "-" + string(r)
+			shorts = shorts[size:]
+			spec, ok := byName[flag]
+			switch {
+			case !ok && flag == "-h":
+				return nil, nil, ErrHelp
+			case !ok:
+				return nil, nil, fmt.Errorf("flag provided but not defined: %s", flag)
+			case spec.typ == boolFlag:
+				if err := set(spec, flag, "true"); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			value := shorts
+			if value == "" {
+				if i+1 == len(args) {
+					return nil, nil, fmt.Errorf("flag needs an argument: %s", flag)
+				}
+				i++
+				value = args[i]
+			}
+			if err := set(spec, flag, value); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+	}
+
+	m := vals.EmptyMap
+	for name, value := range values {
+		m = m.Assoc(name, value)
+	}
+	return m, args[i:], nil
+}
+
+type usageOpts struct{ Prog string }
+
+func (*usageOpts) SetDefaultOptions() {}
+
+//elvdoc:fn usage
+//
+// ```elvish
+// flag:usage &prog='' $specs
+// ```
+//
+// Outputs the usage message for the flags described by `$specs`, in the same
+// format as [`flag:parse`](#flagparse) writes it. If `&prog` is not empty, it
+// is used as the name of the program in the first line.
+//
+// ```elvish-transcript
+// ~> print (flag:usage &prog=myscript [[&name=verbose &short=v &type=bool &help='Show more output'] [&name=jobs &type=number &default=1 &help='Number of parallel jobs']])
+// Usage of myscript:
+//   -v, --verbose
+//         Show more output
+//   --jobs number
+//         Number of parallel jobs (default 1)
+// ```
+
+func usage(opts usageOpts, specsArg interface{}) (string, error) {
+	specs, err := parseSpecs(specsArg)
+	if err != nil {
+		return "", err
+	}
+	return makeUsage(opts.Prog, specs), nil
+}
+
+func makeUsage(prog string, specs []*flagSpec) string {
+	var sb strings.Builder
+	if prog == "" {
+		sb.WriteString("Usage:\n")
+	} else {
+		sb.WriteString("Usage of " + prog + ":\n")
+	}
+	for _, spec := range specs {
+		sb.WriteString("  ")
+		if spec.short != "" {
+			sb.WriteString("-" + spec.short + ", ")
+		}
+		sb.WriteString("--" + spec.name)
+		if spec.typ != boolFlag {
+			sb.WriteString(" " + spec.typ)
+		}
+		sb.WriteString("\n")
+
+		var desc []string
+		if spec.help != "" {
+			desc = append(desc, spec.help)
+		}
+		if !isZeroValue(spec.def) {
+			desc = append(desc, "(default "+showDefault(spec.def)+")")
+		}
+		if spec.typ == listFlag {
+			desc = append(desc, "(can be given more than once)")
+		}
+		if len(desc) > 0 {
+			sb.WriteString("        " + strings.Join(desc, " ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func showDefault(v interface{}) string {
+	switch v.(type) {
+	case string, float64, *big.Int, *big.Rat:
+		return vals.ToString(v)
+	default:
+		return vals.Repr(v, vals.NoPretty)
+	}
+}
+
+func isZeroValue(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case vals.List:
+		return v.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package flag
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+const specs = `specs = [
+  [&name=verbose &short=v &type=bool &help='Show more output']
+  [&name=quiet &short=q &default=$false]
+  [&name=output &short=o &default=out.txt &help='Write to this file']
+  [&name=jobs &type=number &default=1]
+  [&name=tag &type=list &default=[x]]
+]; `
+
+func TestParse(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("flag", Ns).Ns()
+	}
+	defaults := vals.MakeMap(
+		"verbose", false, "quiet", false, "output", "out.txt",
+		"jobs", 1.0, "tag", vals.MakeList("x"))
+	with := func(kvs ...interface{}) vals.Map {
+		m := defaults
+		for i := 0; i < len(kvs); i += 2 {
+			m = m.Assoc(kvs[i], kvs[i+1])
+		}
+		return m
+	}
+	TestWithSetup(t, setup,
+		That(specs+"flag:parse [] $specs").Puts(defaults, vals.EmptyList),
+		That(specs+"flag:parse [a -v b] $specs").
+			Puts(defaults, vals.MakeList("a", "-v", "b")),
+		That(specs+"flag:parse [--verbose -o a.txt --jobs=4 b] $specs").
+			Puts(with("verbose", true, "output", "a.txt", "jobs", 4.0), vals.MakeList("b")),
+		That(specs+"flag:parse [--verbose=false --output=a=b] $specs").
+			Puts(with("output", "a=b"), vals.EmptyList),
+		// Combined short flags
+		That(specs+"flag:parse [-vq -oa.txt] $specs").
+			Puts(with("verbose", true, "quiet", true, "output", "a.txt"), vals.EmptyList),
+		That(specs+"flag:parse [-vo a.txt] $specs").
+			Puts(with("verbose", true, "output", "a.txt"), vals.EmptyList),
+		// List flags
+		That(specs+"flag:parse [--tag a --tag=b] $specs").
+			Puts(with("tag", vals.MakeList("a", "b")), vals.EmptyList),
+		// Numbers
+		That(specs+"flag:parse [--jobs 0x10] $specs").
+			Puts(with("jobs", 16.0), vals.EmptyList),
+		That(specs+"flags _ = (flag:parse [--jobs 1/2] $specs); put $flags[jobs]").
+			Puts(big.NewRat(1, 2)),
+		// Terminating flags
+		That(specs+"flag:parse [-v -- -q] $specs").
+			Puts(with("verbose", true), vals.MakeList("-q")),
+		That(specs+"flag:parse [- -v] $specs").
+			Puts(defaults, vals.MakeList("-", "-v")),
+
+		// Errors
+		That(specs+"flag:parse [--bad] $specs").
+			Throws(ErrorWithMessage("flag provided but not defined: --bad")).
+			PrintsStderrWith("flag provided but not defined: --bad\n"+usageText),
+		That(specs+"flag:parse [-x] $specs").
+			Throws(ErrorWithMessage("flag provided but not defined: -x")),
+		That(specs+"flag:parse [--output] $specs").
+			Throws(ErrorWithMessage("flag needs an argument: --output")),
+		That(specs+"flag:parse [-o] $specs").
+			Throws(ErrorWithMessage("flag needs an argument: -o")),
+		That(specs+"flag:parse [--jobs x] $specs").
+			Throws(ErrorWithMessage("invalid number x for flag --jobs")),
+		That(specs+"flag:parse [--verbose=x] $specs").
+			Throws(ErrorWithMessage("invalid boolean value x for flag --verbose")),
+		That(specs+"flag:parse [--help] $specs").Throws(ErrHelp).PrintsStderrWith(usageText),
+		That(specs+"flag:parse [-h] $specs").Throws(ErrHelp),
+		That("flag:parse [-h x] [[&name=host &short=h]]").
+			Puts(vals.MakeMap("host", "x"), vals.EmptyList),
+
+		// Bad specs
+		That("flag:parse [] [[&short=x]]").Throws(errSpecWithoutName),
+		That("flag:parse [] [[&name=a &short=xy]]").Throws(errBadShort),
+		That("flag:parse [] [[&name=a &type=int]]").Throws(AnyError),
+		That("flag:parse [] [[&name=a &bad=x]]").Throws(AnyError),
+		That("flag:parse [] [[&name=a &type=number &default=x]]").Throws(AnyError),
+		That("flag:parse [] [[&name=a] [&name=a]]").
+			Throws(ErrorWithMessage("flag --a defined more than once")),
+	)
+}
+
+func TestUsage(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("flag", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(specs+"flag:usage $specs").Puts(usageText),
+		That("flag:usage &prog=foo [[&name=a &type=bool]]").
+			Puts("Usage of foo:\n  --a\n"),
+	)
+}
+
+const usageText = `Usage:
+  -v, --verbose
+        Show more output
+  -q, --quiet
+  -o, --output string
+        Write to this file (default out.txt)
+  --jobs number
+        (default 1)
+  --tag list
+        (default [x]) (can be given more than once)
+`
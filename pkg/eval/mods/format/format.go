@@ -0,0 +1,50 @@
+// Package format exposes the code formatter as an Elvish module.
+package format
+
+import (
+	"github.com/elves/elvish/pkg/elvfmt"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Ns is the namespace for the format: module.
+var Ns = eval.NsBuilder{}.AddGoFns("format:", map[string]interface{}{
+	"code": code,
+}).Ns()
+
+//elvdoc:fn code
+//
+// ```elvish
+// format:code $code
+// ```
+//
+// Outputs `$code`, which is Elvish source code, in the canonical format. This
+// is the same format used by `elvish -fmt`. Throws the parse error if `$code`
+// cannot be parsed.
+//
+// The formatter only changes whitespace, newlines and semicolons between
+// syntax elements:
+//
+// -   Elements of a form are separated by one space, and pipe symbols are
+//     surrounded by spaces.
+//
+// -   Bodies of lambdas and captures spanning multiple lines are put on their
+//     own lines and indented by two spaces, and so are continuation lines.
+//
+// -   Line breaks between pipelines and list elements are kept, but runs of
+//     blank lines are collapsed into one.
+//
+// -   Forms and pipelines longer than 80 columns are broken into multiple
+//     lines.
+//
+// Comments are kept as is. The output always ends with a newline unless it is
+// empty.
+//
+// ```elvish-transcript
+// ~> format:code "if $x { echo  a|  wc\n  echo b}"
+// ▶ "if $x {\n  echo a | wc\n  echo b\n}\n"
+// ```
+
+func code(src string) (string, error) {
+	return elvfmt.Format(parse.Source{Name: "[format:code]", Code: src})
+}
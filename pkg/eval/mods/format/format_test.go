@@ -0,0 +1,21 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+func TestFormat(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("format", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`format:code "if $x { echo  a|  wc\n  echo b}"`).
+			Puts("if $x {\n  echo a | wc\n  echo b\n}\n"),
+		That(`format:code ''`).Puts(""),
+		That(`format:code 'echo ]'`).Throws(ErrorWithType(&parse.Error{})),
+	)
+}
@@ -4,8 +4,10 @@ package math
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
 )
 
@@ -344,6 +346,14 @@ import (
 // ▶ (float64 4)
 // ```
 //
+// If `$base` is an integer and `$exponent` is a non-negative integer, the
+// result is exact, even if it is too large to be represented by a `float64`:
+//
+// ```elvish-transcript
+// ~> math:pow 2 64
+// ▶ (num 18446744073709551616)
+// ```
+//
 // @cf math:pow10
 
 //elvdoc:fn pow10
@@ -490,17 +500,17 @@ var Ns = eval.NsBuilder{
 }.AddGoFns("math:", fns).Ns()
 
 var fns = map[string]interface{}{
-	"abs":           math.Abs,
+	"abs":           abs,
 	"acos":          math.Acos,
 	"acosh":         math.Acosh,
 	"asin":          math.Asin,
 	"asinh":         math.Asinh,
 	"atan":          math.Atan,
 	"atanh":         math.Atanh,
-	"ceil":          math.Ceil,
+	"ceil":          ceil,
 	"cos":           math.Cos,
 	"cosh":          math.Cosh,
-	"floor":         math.Floor,
+	"floor":         floor,
 	"is-inf":        isInf,
 	"is-nan":        math.IsNaN,
 	"log":           math.Log,
@@ -508,16 +518,16 @@ var fns = map[string]interface{}{
 	"log2":          math.Log2,
 	"max":           max,
 	"min":           min,
-	"pow":           math.Pow,
+	"pow":           pow,
 	"pow10":         math.Pow10,
-	"round":         math.Round,
-	"round-to-even": math.RoundToEven,
+	"round":         round,
+	"round-to-even": roundToEven,
 	"sin":           math.Sin,
 	"sinh":          math.Sinh,
 	"sqrt":          math.Sqrt,
 	"tan":           math.Tan,
 	"tanh":          math.Tanh,
-	"trunc":         math.Trunc,
+	"trunc":         trunc,
 }
 
 type isInfOpts struct{ Sign int }
@@ -528,16 +538,137 @@ func isInf(opts isInfOpts, arg float64) bool {
 	return math.IsInf(arg, opts.Sign)
 }
 
-func max(num float64, nums ...float64) float64 {
-	for i := 0; i < len(nums); i++ {
-		num = math.Max(num, nums[i])
+// Largest exponent for which pow computes an exact result, to avoid spending
+// too much time and memory on huge numbers.
+const maxExactExponent = 1 << 16
+
+func pow(base, exp interface{}) (interface{}, error) {
+	b, err := vals.ScanNum(base)
+	if err != nil {
+		return nil, err
+	}
+	e, err := vals.ScanNum(exp)
+	if err != nil {
+		return nil, err
+	}
+	if bi, ok := b.(*big.Int); ok {
+		if ei, ok := e.(*big.Int); ok && ei.Sign() >= 0 && ei.Cmp(big.NewInt(maxExactExponent)) <= 0 {
+			return vals.NormalizeBigInt(new(big.Int).Exp(bi, ei, nil)), nil
+		}
+	}
+	return math.Pow(vals.NumToFloat64(b), vals.NumToFloat64(e)), nil
+}
+
+// Wraps a function that rounds a float64 to an integer, so that it also
+// accepts exact numbers. Integers are returned as they are, and fr rounds
+// rationals exactly.
+func integerFn(ff func(float64) float64, fr func(*big.Rat) *big.Int) func(interface{}) (interface{}, error) {
+	return func(arg interface{}) (interface{}, error) {
+		n, err := vals.ScanNum(arg)
+		if err != nil {
+			return nil, err
+		}
+		switch n := n.(type) {
+		case *big.Int:
+			return vals.NormalizeBigInt(n), nil
+		case *big.Rat:
+			return vals.NormalizeBigInt(fr(n)), nil
+		default:
+			return ff(n.(float64)), nil
+		}
 	}
-	return num
 }
 
-func min(num float64, nums ...float64) float64 {
-	for i := 0; i < len(nums); i++ {
-		num = math.Min(num, nums[i])
+var (
+	bigOne  = big.NewInt(1)
+	bigHalf = big.NewRat(1, 2)
+
+	floor = integerFn(math.Floor, floorRat)
+	ceil  = integerFn(math.Ceil, func(r *big.Rat) *big.Int {
+		return new(big.Int).Neg(floorRat(new(big.Rat).Neg(r)))
+	})
+	trunc = integerFn(math.Trunc, func(r *big.Rat) *big.Int {
+		return new(big.Int).Quo(r.Num(), r.Denom())
+	})
+	round = integerFn(math.Round, func(r *big.Rat) *big.Int {
+		// Round half away from zero.
+		if r.Sign() < 0 {
+			return new(big.Int).Neg(floorRat(new(big.Rat).Sub(bigHalf, r)))
+		}
+		return floorRat(new(big.Rat).Add(r, bigHalf))
+	})
+	roundToEven = integerFn(math.RoundToEven, func(r *big.Rat) *big.Int {
+		f := floorRat(r)
+		diff := new(big.Rat).Sub(r, new(big.Rat).SetInt(f))
+		if c := diff.Cmp(bigHalf); c > 0 || (c == 0 && f.Bit(0) == 1) {
+			f.Add(f, bigOne)
+		}
+		return f
+	})
+)
+
+// Computes the largest integer not greater than r. The denominator of a
+// big.Rat is always positive, so Euclidean division rounds down.
+func floorRat(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func abs(arg interface{}) (interface{}, error) {
+	n, err := vals.ScanNum(arg)
+	if err != nil {
+		return nil, err
 	}
-	return num
+	switch n := n.(type) {
+	case *big.Int:
+		return vals.NormalizeBigInt(new(big.Int).Abs(n)), nil
+	case *big.Rat:
+		return vals.NormalizeBigRat(new(big.Rat).Abs(n)), nil
+	default:
+		return math.Abs(n.(float64)), nil
+	}
+}
+
+func max(num interface{}, nums ...interface{}) (interface{}, error) {
+	return extremum(1, num, nums)
+}
+
+func min(num interface{}, nums ...interface{}) (interface{}, error) {
+	return extremum(-1, num, nums)
+}
+
+// Finds the largest number among the arguments if sign is 1, or the smallest
+// if sign is -1. The result is NaN if any argument is NaN.
+func extremum(sign int, num interface{}, nums []interface{}) (interface{}, error) {
+	best, err := vals.ScanNum(num)
+	if err != nil {
+		return nil, err
+	}
+	nan := isNaN(best)
+	for _, arg := range nums {
+		n, err := vals.ScanNum(arg)
+		if err != nil {
+			return nil, err
+		}
+		if isNaN(n) {
+			nan = true
+		} else if vals.CmpNum(n, best) == sign {
+			best = n
+		}
+	}
+	if nan {
+		return math.NaN(), nil
+	}
+	switch best := best.(type) {
+	case *big.Int:
+		return vals.NormalizeBigInt(best), nil
+	case *big.Rat:
+		return vals.NormalizeBigRat(best), nil
+	default:
+		return best, nil
+	}
+}
+
+func isNaN(n interface{}) bool {
+	f, ok := n.(float64)
+	return ok && math.IsNaN(f)
 }
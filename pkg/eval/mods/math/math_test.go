@@ -2,6 +2,7 @@ package math
 
 import (
 	"math"
+	"math/big"
 	"testing"
 
 	"github.com/elves/elvish/pkg/eval"
@@ -19,12 +20,25 @@ func TestMath(t *testing.T) {
 
 		That(`math:abs 2.1`).Puts(2.1),
 		That(`math:abs -2.1`).Puts(2.1),
+		That(`math:abs -2`).Puts(2.0),
+		That(`math:abs -`+z).Puts(bigInt(z)),
+		That(`math:abs -1/2`).Puts(big.NewRat(1, 2)),
+		// Rationals that are integers are normalized.
+		That(`math:abs -4/2`).Puts(2.0),
+		That(`math:abs x`).Throws(AnyError),
 
 		That(`math:ceil 2.1`).Puts(3.0),
 		That(`math:ceil -2.1`).Puts(-2.0),
+		That(`math:ceil `+z).Puts(bigInt(z)),
+		That(`math:ceil 7/2`).Puts(4.0),
+		That(`math:ceil -7/2`).Puts(-3.0),
 
 		That(`math:floor 2.1`).Puts(2.0),
 		That(`math:floor -2.1`).Puts(-3.0),
+		That(`math:floor `+z).Puts(bigInt(z)),
+		That(`math:floor 7/2`).Puts(3.0),
+		That(`math:floor -7/2`).Puts(-4.0),
+		That(`math:floor `+z+`1/10`).Puts(bigInt(z)),
 
 		That(`math:is-inf 1.3`).Puts(false),
 		That(`math:is-inf &sign=0 inf`).Puts(true),
@@ -53,6 +67,9 @@ func TestMath(t *testing.T) {
 		That(`math:round -2.5`).Puts(-3.0),
 		That(`math:round (float64 Inf)`).Puts(math.Inf(1)),
 		That(`math:round (float64 NaN)`).Puts(math.NaN()),
+		That(`math:round 5/2`).Puts(3.0),
+		That(`math:round -5/2`).Puts(-3.0),
+		That(`math:round 7/3`).Puts(2.0),
 
 		That(`math:round-to-even 2.1`).Puts(2.0),
 		That(`math:round-to-even -2.1`).Puts(-2.0),
@@ -60,6 +77,10 @@ func TestMath(t *testing.T) {
 		That(`math:round-to-even -2.5`).Puts(-2.0),
 		That(`math:round-to-even (float64 Inf)`).Puts(math.Inf(1)),
 		That(`math:round-to-even (float64 NaN)`).Puts(math.NaN()),
+		That(`math:round-to-even 5/2`).Puts(2.0),
+		That(`math:round-to-even 7/2`).Puts(4.0),
+		That(`math:round-to-even -5/2`).Puts(-2.0),
+		That(`math:round-to-even 8/3`).Puts(3.0),
 
 		That(`math:trunc 2.1`).Puts(2.0),
 		That(`math:trunc -2.1`).Puts(-2.0),
@@ -67,6 +88,8 @@ func TestMath(t *testing.T) {
 		That(`math:trunc -2.5`).Puts(-2.0),
 		That(`math:trunc (float64 Inf)`).Puts(math.Inf(1)),
 		That(`math:trunc (float64 NaN)`).Puts(math.NaN()),
+		That(`math:trunc 7/2`).Puts(3.0),
+		That(`math:trunc -7/2`).Puts(-3.0),
 
 		That(`math:log $math:e`).Puts(1.0),
 		That(`math:log 1`).Puts(0.0),
@@ -154,6 +177,10 @@ func TestMath(t *testing.T) {
 		That(`math:pow 1 3`).Puts(1.0),
 		That(`math:pow 2 3`).Puts(8.0),
 		That(`math:pow -2 2`).Puts(4.0),
+		That(`math:pow 2 64`).Puts(new(big.Int).Lsh(big.NewInt(1), 64)),
+		That(`math:pow 2 -1`).Puts(0.5),
+		That(`math:pow 4 0.5`).Puts(2.0),
+		That(`math:pow x 2`).Throws(AnyError),
 
 		That(`math:pow10 0`).Puts(1.0),
 		That(`math:pow10 3`).Puts(1000.0),
@@ -165,6 +192,12 @@ func TestMath(t *testing.T) {
 		That(`math:max 42`).Puts(float64(42)),
 		That(`math:max 11 -3 1 7`).Puts(float64(11)),
 		That(`math:max 3 NaN 5`).Puts(math.NaN()),
+		That(`math:max `+z+` `+z1+` 1.5`).Puts(bigInt(z1)),
+		That(`math:max 1/3 1/4`).Puts(big.NewRat(1, 3)),
+		That(`math:max 1/3 4/2`).Puts(2.0),
+		That(`math:max 4/2`).Puts(2.0),
+		That(`math:max 1/2 0.75`).Puts(0.75),
+		That(`math:max 1 x`).Throws(AnyError),
 
 		That(`math:min`).Throws(
 			errs.ArityMismatch{What: "arguments here", ValidLow: 1, ValidHigh: -1, Actual: 0},
@@ -172,5 +205,21 @@ func TestMath(t *testing.T) {
 		That(`math:min 42`).Puts(float64(42)),
 		That(`math:min 11 -3 1 7`).Puts(float64(-3)),
 		That(`math:min 3 NaN 5`).Puts(math.NaN()),
+		That(`math:min `+z+` `+z1).Puts(bigInt(z)),
+		That(`math:min 1/3 1/4`).Puts(big.NewRat(1, 4)),
+		That(`math:min 6/3 5`).Puts(2.0),
 	)
 }
+
+const (
+	z  = "100000000000000000000"
+	z1 = "100000000000000000001"
+)
+
+func bigInt(s string) *big.Int {
+	z, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		panic("bad big.Int literal " + s)
+	}
+	return z
+}
@@ -0,0 +1,328 @@
+// Package net exposes functions for network connections as the net: module.
+package net
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/xiaq/persistent/hash"
+)
+
+// Ns is the namespace for the net: module.
+var Ns = eval.NsBuilder{}.AddGoFns("net:", map[string]interface{}{
+	"dial":        dial,
+	"listen":      listen,
+	"accept":      accept,
+	"serve":       serve,
+	"close":       close,
+	"close-write": closeWrite,
+}).Ns()
+
+// Conn is a network connection.
+type Conn struct {
+	conn net.Conn
+	// A duplicate of the file descriptor of conn, used in redirections.
+	file *os.File
+}
+
+var _ eval.RedirTarget = (*Conn)(nil)
+
+func newConn(c net.Conn) *Conn {
+	filer, ok := c.(interface{ File() (*os.File, error) })
+	if !ok {
+		return &Conn{c, nil}
+	}
+	// Getting the file fails on Windows; the connection can still be closed,
+	// but not used in redirections.
+	f, _ := filer.File()
+	return &Conn{c, f}
+}
+
+var errNotFile = errors.New("connection can't be used in redirections")
+
+func (*Conn) Kind() string                 { return "net-conn" }
+func (c *Conn) Equal(rhs interface{}) bool { return c == rhs }
+func (c *Conn) Hash() uint32               { return hash.Pointer(unsafe.Pointer(c)) }
+
+func (c *Conn) Repr(int) string {
+	return "<net-conn " + c.conn.LocalAddr().String() + " " +
+		c.conn.RemoteAddr().String() + ">"
+}
+
+func (c *Conn) Fields() vals.StructMap { return connFields{c} }
+
+// RedirFile implements eval.RedirTarget. Connections can be used with all
+// redirection modes.
+func (c *Conn) RedirFile(parse.RedirMode) (*os.File, error) {
+	if c.file == nil {
+		return nil, errNotFile
+	}
+	return c.file, nil
+}
+
+func (c *Conn) close() error {
+	if c.file != nil {
+		c.file.Close()
+	}
+	return c.conn.Close()
+}
+
+type connFields struct{ c *Conn }
+
+func (connFields) IsStructMap()         {}
+func (f connFields) Network() string    { return f.c.conn.LocalAddr().Network() }
+func (f connFields) LocalAddr() string  { return f.c.conn.LocalAddr().String() }
+func (f connFields) RemoteAddr() string { return f.c.conn.RemoteAddr().String() }
+
+// Listener is a network listener.
+type Listener struct {
+	l net.Listener
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+func (*Listener) Kind() string                 { return "net-listener" }
+func (l *Listener) Equal(rhs interface{}) bool { return l == rhs }
+func (l *Listener) Hash() uint32               { return hash.Pointer(unsafe.Pointer(l)) }
+func (l *Listener) Repr(int) string            { return "<net-listener " + l.l.Addr().String() + ">" }
+func (l *Listener) Fields() vals.StructMap     { return listenerFields{l} }
+
+func (l *Listener) close() error {
+	l.mutex.Lock()
+	l.closed = true
+	l.mutex.Unlock()
+	return l.l.Close()
+}
+
+func (l *Listener) isClosed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closed
+}
+
+// Accepts a connection, returning eval.ErrInterrupted if fm is interrupted
+// first.
+func (l *Listener) accept(fm *eval.Frame) (*Conn, error) {
+	type result struct {
+		c   net.Conn
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := l.l.Accept()
+		ch <- result{c, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return newConn(r.c), nil
+	case <-fm.Interrupts():
+		// Unblock Accept by setting a deadline in the past.
+		if d, ok := l.l.(interface{ SetDeadline(time.Time) error }); ok {
+			d.SetDeadline(time.Now())
+			if r := <-ch; r.c != nil {
+				r.c.Close()
+			}
+			d.SetDeadline(time.Time{})
+		}
+		return nil, eval.ErrInterrupted
+	}
+}
+
+type listenerFields struct{ l *Listener }
+
+func (listenerFields) IsStructMap()      {}
+func (f listenerFields) Network() string { return f.l.l.Addr().Network() }
+func (f listenerFields) Addr() string    { return f.l.l.Addr().String() }
+
+//elvdoc:fn dial
+//
+// ```elvish
+// net:dial $network $address
+// ```
+//
+// Connects to `$address` on `$network` and outputs the connection. The network
+// is one of `tcp`, `tcp4`, `tcp6` and `unix`; the address is like `host:port`
+// for TCP and a path for Unix sockets.
+//
+// A connection can be used in redirections like a file: redirecting the input
+// of a command from a connection with `<` reads from it, and redirecting the
+// output with `>` writes to it. Both can be done at the same time with `<>`.
+// Like with files, values can be read and written as JSON by redirecting with
+// `&values`. A connection has the fields `network`, `local-addr` and
+// `remote-addr`, and should be closed with `net:close` when it is no longer
+// needed. Using connections in redirections is not supported on Windows.
+// Example:
+//
+// ```elvish-transcript
+// ~> c = (net:dial tcp example.com:80)
+// ~> print "HEAD / HTTP/1.0\r\n\r\n" > $c
+// ~> read-line < $c
+// ▶ "HTTP/1.0 200 OK\r"
+// ~> net:close $c
+// ```
+//
+// @cf net:listen net:close
+
+func dial(network, address string) (*Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c), nil
+}
+
+//elvdoc:fn listen
+//
+// ```elvish
+// net:listen $network $address
+// ```
+//
+// Listens on `$address` on `$network`, and outputs the listener. The network
+// and the address are like in `net:dial`; a port of 0 in TCP addresses picks
+// an unused port. The listener has the fields `network` and `addr`, the latter
+// being the actual address it listens on.
+//
+// Use `net:accept` or `net:serve` to accept connections, and `net:close` to
+// close the listener.
+//
+// @cf net:accept net:serve net:close
+
+func listen(fm *eval.Frame, network, address string) (*Listener, error) {
+	if strings.HasPrefix(network, "unix") {
+		// Listening on a Unix domain socket creates a file.
+		if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{l: l}, nil
+}
+
+//elvdoc:fn accept
+//
+// ```elvish
+// net:accept $listener
+// ```
+//
+// Waits for a connection on `$listener` and outputs it.
+//
+// ```elvish-transcript
+// ~> l = (net:listen tcp 127.0.0.1:0)
+// ~> c = (net:dial tcp $l[addr])
+// ~> s = (net:accept $l)
+// ~> echo hello > $c
+// ~> read-line < $s
+// ▶ hello
+// ```
+//
+// @cf net:listen net:serve
+
+func accept(fm *eval.Frame, l *Listener) (*Conn, error) {
+	return l.accept(fm)
+}
+
+//elvdoc:fn serve
+//
+// ```elvish
+// net:serve $listener $callback
+// ```
+//
+// Accepts connections on `$listener`, and calls `$callback` with each
+// connection as the argument, with its standard input and output connected to
+// the connection. The calls run concurrently, and each connection is closed
+// when its call returns. Exceptions thrown by the callback are printed to the
+// standard error, and don't stop the serving.
+//
+// This command returns when `$listener` is closed with `net:close`, or throws
+// an exception when it is interrupted. Example of an echo server:
+//
+// ```elvish
+// net:serve (net:listen tcp 127.0.0.1:8000) [c]{ cat }
+// ```
+//
+// @cf net:listen net:accept
+
+func serve(fm *eval.Frame, l *Listener, callback eval.Callable) error {
+	ev := fm.Evaler
+	stderr := fm.ErrorFile()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		c, err := l.accept(fm)
+		if err != nil {
+			if err != eval.ErrInterrupted && l.isClosed() {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.close()
+			ports, cleanup := eval.PortsFromFiles(
+				[3]*os.File{c.file, c.file, stderr}, ev)
+			defer cleanup()
+			err := ev.Call(callback,
+				eval.CallCfg{Args: []interface{}{c}, From: "[net:serve]"},
+				eval.EvalCfg{Ports: ports[:], Context: fm.Context()})
+			if err != nil {
+				diag.ShowError(stderr, err)
+			}
+		}()
+	}
+}
+
+//elvdoc:fn close
+//
+// ```elvish
+// net:close $conn-or-listener
+// ```
+//
+// Closes a connection or a listener.
+
+var errCloseWrongType = errors.New("can only close connections and listeners")
+
+func close(v interface{}) error {
+	switch v := v.(type) {
+	case *Conn:
+		return v.close()
+	case *Listener:
+		return v.close()
+	default:
+		return errCloseWrongType
+	}
+}
+
+//elvdoc:fn close-write
+//
+// ```elvish
+// net:close-write $conn
+// ```
+//
+// Shuts down the writing side of a connection, so that the other side sees an
+// end of input, while it is still possible to read from the connection.
+
+var errCloseWriteNotSupported = errors.New("connection doesn't support closing the writing side")
+
+func closeWrite(c *Conn) error {
+	if cw, ok := c.conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errCloseWriteNotSupported
+}
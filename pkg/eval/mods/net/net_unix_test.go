@@ -0,0 +1,62 @@
+// +build !windows,!plan9,!js
+
+package net
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestNet(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("net", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`l = (net:listen tcp 127.0.0.1:0); c = (net:dial tcp $l[addr]); s = (net:accept $l)`,
+			`echo hello > $c; read-line < $s; echo world > $s; read-line < $c`,
+			`put (kind-of $c) (kind-of $l) $c[network] (eq $c[local-addr] $s[remote-addr])`,
+			`net:close $c; net:close $s; net:close $l`).
+			Puts("hello", "world", "net-conn", "net-listener", "tcp", true),
+		// Closing the writing side.
+		That(`l = (net:listen unix sock); c = (net:dial unix sock); s = (net:accept $l)`,
+			`echo foo > $c; net:close-write $c; slurp < $s`,
+			`net:close $c; net:close $s; net:close $l`).
+			Puts("foo\n"),
+		// Values.
+		That(`l = (net:listen tcp 127.0.0.1:0); c = (net:dial tcp $l[addr]); s = (net:accept $l)`,
+			`put [a b] >&values $c; net:close-write $c; only-values <&values $s`,
+			`net:close $c; net:close $s; net:close $l`).
+			Puts(vals.MakeList("a", "b")),
+		// Serving.
+		That(`l = (net:listen tcp 127.0.0.1:0)`,
+			`run-parallel { net:serve $l [c]{ tr a-z A-Z } } {`,
+			`  c = (net:dial tcp $l[addr]); echo foo > $c; net:close-write $c`,
+			`  slurp < $c; net:close $c; net:close $l }`).
+			Puts("FOO\n"),
+		That(`net:close foo`).Throws(errCloseWrongType),
+		That(`net:dial tcp 127.0.0.1:0`).Throws(AnyError),
+	)
+}
+
+func TestNet_Restricted(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("net", Ns).Ns()
+		ev.Restrictions = eval.Restrictions{NoFileWrites: true}
+	}
+	TestWithSetup(t, setup,
+		// Listening on a Unix domain socket creates a file.
+		That(`net:listen unix sock`).Throws(
+			eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}),
+		That(`l = (net:listen tcp 127.0.0.1:0); net:close $l`).DoesNothing(),
+	)
+}
@@ -0,0 +1,197 @@
+// Package ns exposes functions for inspecting namespaces.
+package ns
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Ns is the namespace for the ns: module.
+var Ns = eval.NsBuilder{}.AddGoFns("ns:", map[string]interface{}{
+	"names": names,
+	"has":   has,
+	"get":   get,
+}).Ns()
+
+//elvdoc:fn names
+//
+// ```elvish
+// ns:names $ns
+// ```
+//
+// Outputs the names of all the variables in `$ns`, which is either a namespace
+// or the name of a namespace that can be used at the top level, like `str:` or
+// `E:`. The name `''` refers to the global namespace together with the builtin
+// namespace.
+//
+// Functions and namespaces are variables whose names end with `~` and `:`
+// respectively.
+//
+// ```elvish-transcript
+// ~> ns:names (ns [&x=foo &y=bar]) | order
+// ▶ x
+// ▶ y
+// ~> use str
+// ~> ns:names str: | order | take 3
+// ▶ compare~
+// ▶ contains-any~
+// ▶ contains~
+// ```
+//
+// @cf ns:has ns:get
+
+func names(fm *eval.Frame, ns interface{}) error {
+	out := fm.OutputChan()
+	switch ns := ns.(type) {
+	case *eval.Ns:
+		ns.IterateKeys(func(k interface{}) bool {
+			out <- k
+			return true
+		})
+		return nil
+	case string:
+		fm.Evaler.EachVariableInTop(normalizeNsName(ns), func(name string) {
+			out <- name
+		})
+		return nil
+	default:
+		return errBadNs(ns)
+	}
+}
+
+//elvdoc:fn has
+//
+// ```elvish
+// ns:has $ns $name
+// ```
+//
+// Outputs whether `$ns`, which is like in `ns:names`, has a variable named
+// `$name`.
+//
+// ```elvish-transcript
+// ~> ns:has str: join~
+// ▶ $true
+// ~> ns:has E: NO_SUCH_ENV
+// ▶ $false
+// ```
+//
+// @cf ns:names ns:get
+
+func has(fm *eval.Frame, ns interface{}, name string) (bool, error) {
+	_, ok, err := lookup(fm, ns, name)
+	return ok, err
+}
+
+//elvdoc:fn get
+//
+// ```elvish
+// ns:get $ns $name
+// ```
+//
+// Outputs the value of the variable named `$name` in `$ns`, which is like in
+// `ns:names`. Throws an exception if there is no such variable.
+//
+// This is useful when the name of the variable is only known at runtime:
+//
+// ```elvish-transcript
+// ~> use str
+// ~> f = (ns:get str: to-upper~)
+// ~> $f abc
+// ▶ ABC
+// ~> ns:get E: HOME
+// ▶ /home/elf
+// ```
+//
+// @cf ns:names ns:has
+
+func get(fm *eval.Frame, ns interface{}, name string) (interface{}, error) {
+	v, ok, err := lookup(fm, ns, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vals.NoSuchKey(name)
+	}
+	return v, nil
+}
+
+func lookup(fm *eval.Frame, ns interface{}, name string) (interface{}, bool, error) {
+	switch ns := ns.(type) {
+	case *eval.Ns:
+		v, ok := ns.Index(name)
+		return v, ok, nil
+	case string:
+		switch nsName := normalizeNsName(ns); nsName {
+		case "E:":
+			v, ok := os.LookupEnv(name)
+			return v, ok, nil
+		case "e:":
+			cmd := strings.TrimSuffix(name, eval.FnSuffix)
+			if cmd == name {
+				return nil, false, nil
+			}
+			if _, err := exec.LookPath(cmd); err != nil {
+				return nil, false, nil
+			}
+			return eval.ExternalCmd{Name: cmd}, true, nil
+		default:
+			for _, ns := range topNs(fm, nsName) {
+				if v, ok := ns.Index(name); ok {
+					return v, true, nil
+				}
+			}
+			return nil, false, nil
+		}
+	default:
+		return nil, false, errBadNs(ns)
+	}
+}
+
+// Returns the namespaces to search for a namespace name that can be used at the
+// top level.
+func topNs(fm *eval.Frame, name string) []*eval.Ns {
+	switch name {
+	case "":
+		return []*eval.Ns{fm.Evaler.Global, fm.Evaler.Builtin}
+	case "builtin:":
+		return []*eval.Ns{fm.Evaler.Builtin}
+	}
+	segs := eval.SplitQNameSegs(name)
+	var ns *eval.Ns
+	for i, seg := range segs {
+		var v interface{}
+		var ok bool
+		if i == 0 {
+			v, ok = fm.Evaler.Global.Index(seg)
+			if !ok {
+				v, ok = fm.Evaler.Builtin.Index(seg)
+			}
+		} else {
+			v, ok = ns.Index(seg)
+		}
+		if ns, ok = v.(*eval.Ns); !ok {
+			return nil
+		}
+	}
+	return []*eval.Ns{ns}
+}
+
+// Adds the trailing colon to a non-empty namespace name if it is missing.
+func normalizeNsName(name string) string {
+	if name == "" || name == ":" {
+		return ""
+	}
+	if !strings.HasSuffix(name, ":") {
+		return name + ":"
+	}
+	return name
+}
+
+func errBadNs(ns interface{}) error {
+	return errs.BadValue{What: "namespace", Valid: "ns or string", Actual: vals.Kind(ns)}
+}
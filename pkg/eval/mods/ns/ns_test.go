@@ -0,0 +1,57 @@
+package ns
+
+import (
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestNs(t *testing.T) {
+	defer testutil.WithTempEnv("NS_TEST_VAR", "foo")()
+	os.Unsetenv("NS_TEST_NO_SUCH_VAR")
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("ns", Ns).
+			AddNs("m", eval.NsBuilder{
+				"x": vars.NewReadOnly("foo"),
+			}.AddNs("inner", eval.NsBuilder{"y": vars.NewReadOnly("bar")}.Ns()).Ns()).
+			Ns()
+	}
+	TestWithSetup(t, setup,
+		That("ns:names (ns [&x=foo &y=bar]) | order").Puts("x", "y"),
+		That("ns:names m: | order").Puts("inner:", "x"),
+		That("ns:names m | order").Puts("inner:", "x"),
+		That("ns:names m:inner:").Puts("y"),
+		That("ns:names no-such:").DoesNothing(),
+		That("has-value [(ns:names builtin:)] put~").Puts(true),
+		That("has-value [(ns:names '')] m:").Puts(true),
+		That("ns:names [x]").Throws(errs.BadValue{
+			What: "namespace", Valid: "ns or string", Actual: "list"}),
+
+		That("ns:has (ns [&x=foo]) x").Puts(true),
+		That("ns:has (ns [&x=foo]) y").Puts(false),
+		That("ns:has m:inner: y").Puts(true),
+		That("ns:has '' put~").Puts(true),
+		That("ns:has '' m:").Puts(true),
+		That("ns:has builtin: m:").Puts(false),
+		That("ns:has E: NS_TEST_VAR").Puts(true),
+		That("ns:has E: NS_TEST_NO_SUCH_VAR").Puts(false),
+		That("ns:has e: sh~").Puts(true),
+		That("ns:has e: sh").Puts(false),
+		That("ns:has e: no-such-command~").Puts(false),
+
+		That("ns:get (ns [&x=foo]) x").Puts("foo"),
+		That("ns:get m:inner: y").Puts("bar"),
+		That("ns:get (ns:get m: inner:) y").Puts("bar"),
+		That("ns:get E: NS_TEST_VAR").Puts("foo"),
+		That("ns:get e: sh~").Puts(eval.ExternalCmd{Name: "sh"}),
+		That("(ns:get '' put~) foo").Puts("foo"),
+		That("ns:get m: y").Throws(vals.NoSuchKey("y")),
+	)
+}
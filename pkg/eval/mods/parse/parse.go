@@ -0,0 +1,176 @@
+// Package parse exposes the parser as the parse: module, which outputs syntax
+// trees as structured values.
+package parse
+
+import (
+	"strconv"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/strutil"
+)
+
+// Ns is the namespace for the parse: module.
+var Ns = eval.NsBuilder{}.AddGoFns("parse:", map[string]interface{}{
+	"parse":  parseCode,
+	"errors": errors,
+}).Ns()
+
+// The name of the source used when parsing code.
+const srcName = "[parse:parse]"
+
+//elvdoc:fn parse
+//
+// ```elvish
+// parse:parse &partial=$false $code
+// ```
+//
+// Parses `$code` and outputs its syntax tree. Throws the parse error if `$code`
+// has syntax errors, unless `&partial` is true, in which case the best-effort
+// tree built by recovering from the errors is output instead; see
+// [`parse:errors`](#parseerrors) for finding the errors.
+//
+// Each node of the tree is a map with the following fields:
+//
+// -   `type`: The type of the node, one of `chunk`, `pipeline`, `form`,
+//     `assignment`, `redir`, `compound`, `indexing`, `array`, `primary`,
+//     `map-pair` and `sep`. A `sep` node is a leaf node for the text that has
+//     no structure of its own, like spaces, comments, brackets and operators.
+//
+// -   `start` and `end`: The range of the node in `$code`, as byte offsets;
+//     `start` is inclusive and `end` is exclusive.
+//
+// -   `text`: The part of `$code` the node is parsed from.
+//
+// -   `primary-type`: For a `primary` node, its type, one of `bareword`,
+//     `single-quoted`, `double-quoted`, `variable`, `wildcard`, `tilde`,
+//     `exception-capture`, `output-capture`, `list`, `lambda`, `map` and
+//     `braced`; an empty string for other nodes.
+//
+// -   `value`: For a `primary` node of type `bareword`, `single-quoted`,
+//     `double-quoted`, `variable`, `wildcard` or `tilde`, the unquoted value,
+//     like the variable name for `variable`; an empty string for other nodes.
+//
+// -   `children`: A list of the child nodes, in the order they appear in
+//     `$code`. The `text` of a node is always the concatenation of the `text`
+//     of its children.
+//
+// Example:
+//
+// ```elvish-transcript
+// ~> fn leaves [n]{ if (== 0 (count $n[children])) { put $n } else { each $leaves~ $n[children] } }
+// ~> leaves (parse:parse 'echo $x') | each [n]{ put [$n[type] $n[text]] }
+// ▶ [primary echo]
+// ▶ [sep ' ']
+// ▶ [primary '$x']
+// ```
+//
+// @cf parse:errors
+
+type parseOpts struct{ Partial bool }
+
+func (*parseOpts) SetDefaultOptions() {}
+
+func parseCode(opts parseOpts, code string) (nodeStruct, error) {
+	tree, err := parse.Parse(parse.Source{Name: srcName, Code: code})
+	if err != nil && !opts.Partial {
+		return nodeStruct{}, err
+	}
+	return convert(tree.Root), nil
+}
+
+//elvdoc:fn errors
+//
+// ```elvish
+// parse:errors $code
+// ```
+//
+// Outputs the syntax errors in `$code`, each as a map with a `message` field,
+// and `start` and `end` fields for its range, which are like those of the
+// nodes output by [`parse:parse`](#parseparse). Outputs nothing if there is
+// no syntax error.
+//
+// ```elvish-transcript
+// ~> parse:errors "echo (\necho ]"
+// ▶ [&message='unexpected rune '']''' &start=12 &end=13]
+// ▶ [&message='should be '')''' &start=13 &end=13]
+// ```
+//
+// @cf parse:parse
+
+func errors(fm *eval.Frame, code string) {
+	_, err := parse.Parse(parse.Source{Name: srcName, Code: code})
+	if err == nil {
+		return
+	}
+	out := fm.OutputChan()
+	for _, entry := range err.(*parse.Error).Entries {
+		out <- errorStruct{entry.Message,
+			strconv.Itoa(entry.Context.From), strconv.Itoa(entry.Context.To)}
+	}
+}
+
+type nodeStruct struct {
+	Type        string
+	Start       string
+	End         string
+	Text        string
+	PrimaryType string
+	Value       string
+	Children    vals.List
+}
+
+func (nodeStruct) IsStructMap() {}
+
+type errorStruct struct {
+	Message string
+	Start   string
+	End     string
+}
+
+func (errorStruct) IsStructMap() {}
+
+func convert(n parse.Node) nodeStruct {
+	r := n.Range()
+	ns := nodeStruct{
+		Type: nodeType(n), Start: strconv.Itoa(r.From), End: strconv.Itoa(r.To),
+		Text: parse.SourceText(n)}
+	if pn, ok := n.(*parse.Primary); ok {
+		ns.PrimaryType = strutil.CamelToDashed(pn.Type.String())
+		ns.Value = pn.Value
+	}
+	children := vals.EmptyList
+	for _, ch := range parse.Children(n) {
+		children = children.Cons(convert(ch))
+	}
+	ns.Children = children
+	return ns
+}
+
+func nodeType(n parse.Node) string {
+	switch n.(type) {
+	case *parse.Chunk:
+		return "chunk"
+	case *parse.Pipeline:
+		return "pipeline"
+	case *parse.Form:
+		return "form"
+	case *parse.Assignment:
+		return "assignment"
+	case *parse.Redir:
+		return "redir"
+	case *parse.Compound:
+		return "compound"
+	case *parse.Indexing:
+		return "indexing"
+	case *parse.Array:
+		return "array"
+	case *parse.Primary:
+		return "primary"
+	case *parse.MapPair:
+		return "map-pair"
+	default:
+		return "sep"
+	}
+}
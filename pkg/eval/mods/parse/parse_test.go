@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+func TestParse(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("parse", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`n = (parse:parse 'echo $x')`,
+			`put $n[type] $n[start] $n[end] $n[text] (count $n[children])`).
+			Puts("chunk", "0", "7", "echo $x", "1"),
+		That(`fn leaves [n]{ if (== 0 (count $n[children])) { put $n } else { each $leaves~ $n[children] } }`,
+			`leaves (parse:parse 'echo $x') | each [n]{ put [$n[type] $n[text]] }`).
+			Puts(vals.MakeList("primary", "echo"), vals.MakeList("sep", " "), vals.MakeList("primary", "$x")),
+		That(`n = (parse:parse 'echo $x')[children][0][children][0][children][2]`,
+			`put $n[type] $n[start] $n[end]`).
+			Puts("compound", "5", "7"),
+		That(`n = (parse:parse '$x')[children][0][children][0][children][0][children][0][children][0]`,
+			`put $n[type] $n[primary-type] $n[value]`).
+			Puts("primary", "variable", "x"),
+		That(`n = (parse:parse 'a | b')[children][0]`,
+			`put $n[primary-type] $n[value]`,
+			`each [n]{ put [$n[type] $n[text]] } $n[children]`).
+			Puts("", "",
+				vals.MakeList("form", "a "), vals.MakeList("sep", "|"),
+				vals.MakeList("sep", " "), vals.MakeList("form", "b")),
+		That(`n = (parse:parse 'a &b=c > d')[children][0][children][0]`,
+			`each [n]{ put $n[type] } $n[children]`).
+			Puts("compound", "sep", "map-pair", "sep", "redir"),
+
+		That(`parse:parse 'echo ]'`).Throws(ErrorWithType(&parse.Error{})),
+		That(`put (parse:parse &partial 'echo ]')[text]`).Puts("echo ]"),
+
+		That(`parse:errors 'echo x'`).DoesNothing(),
+		That(`parse:errors "echo (\necho ]" | each [e]{ put [$e[message] $e[start] $e[end]] }`).
+			Puts(vals.MakeList("unexpected rune ']'", "12", "13"),
+				vals.MakeList("should be ')'", "13", "13")),
+	)
+}
@@ -0,0 +1,80 @@
+package proc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const listRunsCommand = false
+
+// Where the proc filesystem is mounted. This is a variable to allow tests to
+// use a fake one.
+var procRoot = "/proc"
+
+// The unit of the CPU times in /proc/[pid]/stat, USER_HZ, is 100 on all the
+// architectures supported by Go.
+const clockTicks = 100
+
+var pageSize = os.Getpagesize()
+
+var errBadStat = errors.New("malformed stat file")
+
+func listProcesses(f func(process)) error {
+	dir, err := os.Open(procRoot)
+	if err != nil {
+		return err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := strconv.Atoi(name); err != nil {
+			continue
+		}
+		p, err := readProcess(name)
+		if err != nil {
+			// The process has most likely exited.
+			continue
+		}
+		f(p)
+	}
+	return nil
+}
+
+// Reads the information of a process from /proc/[pid]/stat. See proc(5) for
+// its format.
+func readProcess(pid string) (process, error) {
+	content, err := ioutil.ReadFile(filepath.Join(procRoot, pid, "stat"))
+	if err != nil {
+		return process{}, err
+	}
+	s := string(content)
+	// The second field is the executable name in parentheses, which may
+	// itself contain spaces and parentheses.
+	lparen, rparen := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if lparen == -1 || rparen < lparen {
+		return process{}, errBadStat
+	}
+	// Fields from the third one (state) onwards.
+	fields := strings.Fields(s[rparen+1:])
+	if len(fields) < 22 {
+		return process{}, errBadStat
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	rss, err3 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return process{}, errBadStat
+	}
+	return process{
+		Pid: pid, Ppid: fields[1], Name: s[lparen+1 : rparen],
+		Cpu: float64(utime+stime) / clockTicks,
+		Rss: strconv.FormatInt(rss*int64(pageSize), 10),
+	}, nil
+}
@@ -0,0 +1,47 @@
+package proc
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestListProcesses_Linux(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"1": testutil.Dir{
+			"stat": "1 (init) S 0 1 1 0 -1 4194560 1 2 3 4 150 50 0 0 20 0 1 0 1 1000 100 0",
+		},
+		"42": testutil.Dir{
+			"stat": "42 (a (weird) name) R 1 42 42 0 -1 0 0 0 0 0 5 5 0 0 20 0 1 0 2 1000 3 0",
+		},
+		// Malformed stat files, non-process directories and processes that
+		// have exited are skipped.
+		"43":      testutil.Dir{"stat": "43 (short) S 1"},
+		"44":      testutil.Dir{},
+		"self":    testutil.Dir{},
+		"cpuinfo": "",
+	})
+	defer func(old string) { procRoot = old }(procRoot)
+	procRoot = dir
+
+	var got []process
+	err := listProcesses(func(p process) { got = append(got, p) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []process{
+		{Pid: "1", Ppid: "0", Name: "init", Cpu: 2.0,
+			Rss: strconv.Itoa(100 * pageSize)},
+		{Pid: "42", Ppid: "1", Name: "a (weird) name", Cpu: 0.1,
+			Rss: strconv.Itoa(3 * pageSize)},
+	}
+	sort.Slice(got, func(i, j int) bool { return len(got[i].Pid) < len(got[j].Pid) })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+// +build plan9 js
+
+package proc
+
+import "errors"
+
+const listRunsCommand = false
+
+var errListNotSupported = errors.New("listing processes is not supported on this platform")
+
+func listProcesses(func(process)) error { return errListNotSupported }
@@ -0,0 +1,17 @@
+// +build !linux,!windows,!plan9,!js
+
+package proc
+
+import "os/exec"
+
+// Processes are listed by running ps.
+const listRunsCommand = true
+
+func listProcesses(f func(process)) error {
+	out, err := exec.Command("ps", psArgs...).Output()
+	if err != nil {
+		return err
+	}
+	parsePs(out, f)
+	return nil
+}
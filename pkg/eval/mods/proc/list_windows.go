@@ -0,0 +1,53 @@
+package proc
+
+import (
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const listRunsCommand = false
+
+func listProcesses(f func(process)) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		f(process{
+			Pid:  strconv.FormatUint(uint64(entry.ProcessID), 10),
+			Ppid: strconv.FormatUint(uint64(entry.ParentProcessID), 10),
+			Name: windows.UTF16ToString(entry.ExeFile[:]),
+			Cpu:  cpuTime(entry.ProcessID),
+		})
+	}
+	if err == windows.ERROR_NO_MORE_FILES {
+		return nil
+	}
+	return err
+}
+
+// Returns the CPU time used by a process in seconds, or "" if it is not
+// available.
+func cpuTime(pid uint32) interface{} {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+	var creation, exit, kernel, user windows.Filetime
+	if windows.GetProcessTimes(h, &creation, &exit, &kernel, &user) != nil {
+		return ""
+	}
+	// Filetime values are in units of 100 nanoseconds.
+	ticks := filetimeTicks(kernel) + filetimeTicks(user)
+	return float64(ticks) / 1e7
+}
+
+func filetimeTicks(t windows.Filetime) uint64 {
+	return uint64(t.HighDateTime)<<32 | uint64(t.LowDateTime)
+}
@@ -0,0 +1,163 @@
+// Package proc exposes information about the Elvish process, the system it runs
+// on and other running processes as the proc: module.
+package proc
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var pid
+//
+// The process ID of the Elvish process. This is the same as the builtin
+// [`$pid`](builtin.html#pid).
+
+//elvdoc:var ppid
+//
+// The process ID of the parent of the Elvish process.
+
+//elvdoc:var uid
+//
+// The real user ID of the Elvish process. On Windows, this is always `-1`.
+//
+// @cf $proc:euid $proc:gid
+
+//elvdoc:var euid
+//
+// The effective user ID of the Elvish process. On Windows, this is always
+// `-1`.
+
+//elvdoc:var gid
+//
+// The real group ID of the Elvish process. On Windows, this is always `-1`.
+//
+// @cf $proc:egid $proc:uid
+
+//elvdoc:var egid
+//
+// The effective group ID of the Elvish process. On Windows, this is always
+// `-1`.
+
+//elvdoc:var hostname
+//
+// The host name of the system, or an empty string if it can't be determined.
+//
+// @cf platform:hostname
+
+//elvdoc:var num-cpu
+//
+// The number of logical CPUs of the system.
+
+// Ns is the namespace for the proc: module.
+var Ns = eval.NsBuilder{
+	"pid":      vars.NewReadOnly(strconv.Itoa(os.Getpid())),
+	"ppid":     vars.FromGet(func() interface{} { return strconv.Itoa(os.Getppid()) }),
+	"uid":      vars.FromGet(func() interface{} { return strconv.Itoa(os.Getuid()) }),
+	"euid":     vars.FromGet(func() interface{} { return strconv.Itoa(os.Geteuid()) }),
+	"gid":      vars.FromGet(func() interface{} { return strconv.Itoa(os.Getgid()) }),
+	"egid":     vars.FromGet(func() interface{} { return strconv.Itoa(os.Getegid()) }),
+	"hostname": vars.FromGet(getHostname),
+	"num-cpu":  vars.NewReadOnly(strconv.Itoa(runtime.NumCPU())),
+}.AddGoFns("proc:", map[string]interface{}{
+	"uname": uname,
+	"list":  list,
+}).Ns()
+
+func getHostname() interface{} {
+	name, _ := os.Hostname()
+	return name
+}
+
+//elvdoc:fn uname
+//
+// ```elvish
+// proc:uname
+// ```
+//
+// Outputs a map describing the operating system, with the following keys,
+// named after the fields of the `uname` system call:
+//
+// -   `sysname`: The name of the operating system, like `Linux`, `Darwin` or
+//     `Windows`.
+//
+// -   `release`: The release of the operating system kernel.
+//
+// -   `version`: More information on the version of the kernel. This is empty
+//     on Windows.
+//
+// -   `machine`: The hardware type, like `x86_64`.
+//
+// ```elvish-transcript
+// ~> proc:uname
+// ▶ [&sysname=Linux &release=5.10.0-1-amd64 &version='#1 SMP Debian 5.10.4-1 (2020-12-31)' &machine=x86_64]
+// ```
+//
+// @cf platform:os platform:arch
+
+type unameStruct struct {
+	Sysname, Release, Version, Machine string
+}
+
+func (unameStruct) IsStructMap() {}
+
+//elvdoc:fn list
+//
+// ```elvish
+// proc:list
+// ```
+//
+// Outputs a map for each running process, with the following keys:
+//
+// -   `pid`: The process ID.
+//
+// -   `ppid`: The process ID of the parent process.
+//
+// -   `name`: The name of the executable, without the directory. On Linux, it
+//     is truncated to 15 bytes.
+//
+// -   `cpu`: The CPU time used by the process so far, in seconds.
+//
+// -   `rss`: The resident set size, the amount of memory occupied by the
+//     process in RAM, in bytes. This is empty on Windows.
+//
+// Processes that exit while they are being listed may be left out. The
+// information of some processes, like the CPU time of processes owned by other
+// users on Windows, may not be available, in which case the corresponding
+// values are empty strings.
+//
+// On Linux, the information is read from `/proc`; on other UNIX systems, it
+// comes from the `ps` command.
+//
+// ```elvish-transcript
+// ~> proc:list | each [p]{ if (eq $p[name] elvish) { put $p } }
+// ▶ [&pid=2049 &ppid=2001 &name=elvish &cpu=(float64 0.31) &rss=20176896]
+// ~> # The 3 processes using the most memory
+// ~> proc:list | order &reverse &less-than=[a b]{ < $a[rss] $b[rss] } | take 3 | each [p]{ put $p[name] }
+// ▶ firefox
+// ▶ Xorg
+// ▶ elvish
+// ```
+
+type process struct {
+	Pid, Ppid string
+	Name      string
+	// A float64, or "" if not available.
+	Cpu interface{}
+	Rss string
+}
+
+func (process) IsStructMap() {}
+
+func list(fm *eval.Frame) error {
+	if listRunsCommand {
+		if err := fm.CheckRestriction(eval.RestrictExternalCommands); err != nil {
+			return err
+		}
+	}
+	out := fm.OutputChan()
+	return listProcesses(func(p process) { out <- p })
+}
@@ -0,0 +1,51 @@
+package proc
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestProc(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("proc", Ns).Ns()
+	}
+	hostname, _ := os.Hostname()
+	TestWithSetup(t, setup,
+		That("put $proc:pid $proc:ppid").
+			Puts(strconv.Itoa(os.Getpid()), strconv.Itoa(os.Getppid())),
+		That("put $proc:uid $proc:euid $proc:gid $proc:egid").
+			Puts(strconv.Itoa(os.Getuid()), strconv.Itoa(os.Geteuid()),
+				strconv.Itoa(os.Getgid()), strconv.Itoa(os.Getegid())),
+		That("put $proc:hostname").Puts(hostname),
+		That("> $proc:num-cpu 0").Puts(true),
+
+		That("keys (proc:uname)").
+			Puts("sysname", "release", "version", "machine"),
+		That("!=s (proc:uname)[sysname] ''").Puts(true),
+
+		// The list includes the current process.
+		That("proc:list | each [p]{ if (eq $p[pid] $proc:pid) { put $p[ppid] } }").
+			Puts(strconv.Itoa(os.Getppid())),
+		That("proc:list | each [p]{ if (eq $p[pid] $proc:pid) { keys $p } }").
+			Puts("pid", "ppid", "name", "cpu", "rss"),
+	)
+}
+
+func TestList_Restricted(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("proc", Ns).Ns()
+		ev.Restrictions = eval.Restrictions{NoExternalCommands: true}
+	}
+	test := That("proc:list | each [p]{ if (eq $p[pid] $proc:pid) { put found } }")
+	if listRunsCommand {
+		test = test.Throws(eval.RestrictionViolation{
+			Restriction: eval.RestrictExternalCommands})
+	} else {
+		test = test.Puts("found")
+	}
+	TestWithSetup(t, setup, test)
+}
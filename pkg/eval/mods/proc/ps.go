@@ -0,0 +1,55 @@
+package proc
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Arguments to ps for listing processes on UNIX systems without /proc, in the
+// format parsed by parsePs.
+var psArgs = []string{"-A", "-o", "pid=,ppid=,rss=,time=,comm="}
+
+// Parses the output of ps with psArgs. The comm column comes last, since it
+// may contain spaces; it may also be a full path on some systems.
+func parsePs(out []byte, f func(process)) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		p := process{Pid: fields[0], Ppid: fields[1], Cpu: "", Rss: ""}
+		if kib, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			p.Rss = strconv.FormatInt(kib*1024, 10)
+		}
+		if cpu, ok := parseCPUTime(fields[3]); ok {
+			p.Cpu = cpu
+		}
+		p.Name = filepath.Base(strings.Join(fields[4:], " "))
+		f(p)
+	}
+}
+
+// Parses a CPU time in the format [[dd-]hh:]mm:ss[.ss] to seconds.
+func parseCPUTime(s string) (float64, bool) {
+	var days float64
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		d, err := strconv.ParseUint(s[:i], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		days, s = float64(d), s[i+1:]
+	}
+	var secs float64
+	for _, part := range strings.Split(s, ":") {
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil || f < 0 {
+			return 0, false
+		}
+		secs = secs*60 + f
+	}
+	return days*86400 + secs, true
+}
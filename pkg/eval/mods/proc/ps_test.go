@@ -0,0 +1,38 @@
+package proc
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/tt"
+)
+
+func TestParsePs(t *testing.T) {
+	out := []byte(`    1     0  1024 00:01:02 /sbin/launchd
+  420     1     4 1-02:03:04.50 Google Chrome Helper
+  bad line
+  421     1     x bad      sh
+`)
+	var got []process
+	parsePs(out, func(p process) { got = append(got, p) })
+	want := []process{
+		{Pid: "1", Ppid: "0", Name: "launchd", Cpu: 62.0, Rss: "1048576"},
+		{Pid: "420", Ppid: "1", Name: "Google Chrome Helper", Cpu: 93784.5, Rss: "4096"},
+		{Pid: "421", Ppid: "1", Name: "sh", Cpu: "", Rss: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseCPUTime(t *testing.T) {
+	Test(t, Fn("parseCPUTime", parseCPUTime), Table{
+		Args("0:00.03").Rets(0.03, true),
+		Args("01:02").Rets(62.0, true),
+		Args("01:02:03").Rets(3723.0, true),
+		Args("2-00:00:01").Rets(172801.0, true),
+		Args("x").Rets(0.0, false),
+		Args("x-00:00:01").Rets(0.0, false),
+		Args("-1:00").Rets(0.0, false),
+	})
+}
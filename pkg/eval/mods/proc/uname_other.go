@@ -0,0 +1,9 @@
+// +build plan9 js
+
+package proc
+
+import "runtime"
+
+func uname() (unameStruct, error) {
+	return unameStruct{Sysname: runtime.GOOS, Machine: runtime.GOARCH}, nil
+}
@@ -0,0 +1,28 @@
+// +build !windows,!plan9,!js
+
+package proc
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+func uname() (unameStruct, error) {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return unameStruct{}, err
+	}
+	return unameStruct{
+		Sysname: cString(u.Sysname[:]), Release: cString(u.Release[:]),
+		Version: cString(u.Version[:]), Machine: cString(u.Machine[:]),
+	}, nil
+}
+
+// Converts a NUL-terminated byte array to a string.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i != -1 {
+		b = b[:i]
+	}
+	return string(b)
+}
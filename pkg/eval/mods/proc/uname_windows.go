@@ -0,0 +1,17 @@
+package proc
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+func uname() (unameStruct, error) {
+	v := windows.RtlGetVersion()
+	return unameStruct{
+		Sysname: "Windows",
+		Release: fmt.Sprintf("%d.%d.%d", v.MajorVersion, v.MinorVersion, v.BuildNumber),
+		Machine: runtime.GOARCH,
+	}, nil
+}
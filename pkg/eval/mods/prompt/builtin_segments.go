@@ -0,0 +1,163 @@
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/fsutil"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+type builtinSegment struct {
+	compute func(m *module) (ui.Text, error)
+	// Default style.
+	style string
+}
+
+var builtinSegments = map[string]builtinSegment{
+	"cwd":      {cwdSegment, "blue"},
+	"git":      {gitSegment, "magenta"},
+	"kube":     {kubeSegment, "cyan"},
+	"venv":     {venvSegment, "yellow"},
+	"status":   {statusSegment, "red"},
+	"duration": {durationSegment, "yellow"},
+}
+
+func cwdSegment(*module) (ui.Text, error) {
+	return ui.T(fsutil.Getwd()), nil
+}
+
+func gitSegment(m *module) (ui.Text, error) {
+	status, _ := m.git.get()
+	if status == nil {
+		return nil, nil
+	}
+	var sb strings.Builder
+	sb.WriteString(status.branch)
+	if status.dirty {
+		sb.WriteString("*")
+	}
+	if status.hasUpstream && (status.ahead > 0 || status.behind > 0) {
+		sb.WriteString(" ")
+		if status.ahead > 0 {
+			sb.WriteString("↑" + strconv.Itoa(status.ahead))
+		}
+		if status.behind > 0 {
+			sb.WriteString("↓" + strconv.Itoa(status.behind))
+		}
+	}
+	return ui.T(sb.String()), nil
+}
+
+// Finds the Git directory of the repository containing dir, by looking for
+// .git in dir and its ancestors. The .git may also be a file pointing to the
+// actual Git directory, which is the case for worktrees and submodules.
+func findGitDir(dir string) (string, bool) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dotGit, true
+			}
+			content, err := ioutil.ReadFile(dotGit)
+			if err != nil {
+				return "", false
+			}
+			line := strings.TrimSpace(string(content))
+			if !strings.HasPrefix(line, "gitdir: ") {
+				return "", false
+			}
+			gitDir := strings.TrimPrefix(line, "gitdir: ")
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return gitDir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Returns the branch name from the content of a HEAD file, or the abbreviated
+// commit hash if HEAD is detached.
+func describeHead(head string) string {
+	if strings.HasPrefix(head, "ref: ") {
+		return strings.TrimPrefix(strings.TrimPrefix(head, "ref: "), "refs/heads/")
+	}
+	if len(head) > 7 {
+		return head[:7]
+	}
+	return head
+}
+
+func kubeSegment(*module) (ui.Text, error) {
+	path := ""
+	if paths := filepath.SplitList(os.Getenv(env.KUBECONFIG)); len(paths) > 0 {
+		path = paths[0]
+	} else {
+		home, err := fsutil.GetHome("")
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+	// The configuration is in YAML, but only the top-level current-context
+	// field is needed, so it is scanned for instead of parsing the whole file.
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "current-context:") {
+			context := strings.TrimSpace(strings.TrimPrefix(line, "current-context:"))
+			return ui.T(strings.Trim(context, `"'`)), nil
+		}
+	}
+	return nil, scanner.Err()
+}
+
+func venvSegment(*module) (ui.Text, error) {
+	if venv := os.Getenv(env.VIRTUAL_ENV); venv != "" {
+		return ui.T(filepath.Base(venv)), nil
+	}
+	return ui.T(os.Getenv(env.CONDA_DEFAULT_ENV)), nil
+}
+
+func statusSegment(m *module) (ui.Text, error) {
+	_, _, err := m.ed.LastCommand()
+	if err == nil {
+		return nil, nil
+	}
+	if exit, ok := eval.Reason(err).(eval.ExternalCmdExit); ok && exit.Exited() {
+		return ui.T("✘ " + strconv.Itoa(exit.ExitStatus())), nil
+	}
+	return ui.T("✘"), nil
+}
+
+// Commands shorter than this are not shown in the duration segment.
+const minDuration = 2 * time.Second
+
+func durationSegment(m *module) (ui.Text, error) {
+	_, d, _ := m.ed.LastCommand()
+	if d < minDuration {
+		return nil, nil
+	}
+	if d < time.Minute {
+		return ui.T(fmt.Sprintf("%.1fs", d.Seconds())), nil
+	}
+	return ui.T(d.Round(time.Second).String()), nil
+}
@@ -0,0 +1,187 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/fsutil"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+//elvdoc:fn compact-dir
+//
+// ```elvish
+// prompt:compact-dir &full=1 &length=1 &aliases=[&] &style='' $dir?
+// ```
+//
+// Outputs a shortened form of `$dir` as a styled text, in the style of the
+// fish shell: the last `&full` components are kept as they are, and the other
+// components are abbreviated to their first `&length` characters, or
+// `&length`+1 characters if they start with `.`. The default `$dir` is the
+// working directory.
+//
+// The home directory is abbreviated to `~`. The `&aliases` option is a map from
+// directories to names, and a directory in the map is abbreviated to its name
+// instead, like named directories in zsh. If several directories match, the
+// longest one is used. A `~` at the start of a directory in the map stands for
+// the home directory. The home directory and aliases are never abbreviated
+// further.
+//
+// The `&style` option is applied to the whole text, in the same format as the
+// `$style` argument of [`styled`](builtin.html#styled).
+//
+// Examples, with `/home/elf` as the home directory:
+//
+// ```elvish-transcript
+// ~> prompt:compact-dir /home/elf/src/elvish/pkg/eval
+// ▶ (styled ~/s/e/p/eval)
+// ~> prompt:compact-dir &full=2 /usr/local/share/man
+// ▶ (styled /u/l/share/man)
+// ~> prompt:compact-dir /home/elf/.config/elvish/lib
+// ▶ (styled ~/.c/e/lib)
+// ~> prompt:compact-dir &aliases=[&~/src/elvish='~elv'] ~/src/elvish/pkg/eval
+// ▶ (styled ~elv/p/eval)
+// ```
+//
+// To use it as a prompt segment:
+//
+// ```elvish
+// edit:prompt = (prompt:make (prompt:segment &style=blue { prompt:compact-dir }))
+// ```
+
+type compactDirOpts struct {
+	Full    int
+	Length  int
+	Aliases vals.Map
+	Style   string
+}
+
+func (o *compactDirOpts) SetDefaultOptions() {
+	o.Full = 1
+	o.Length = 1
+	o.Aliases = vals.EmptyMap
+}
+
+var (
+	errNegativeFull   = errors.New("full must not be negative")
+	errNonPositiveLen = errors.New("length must be positive")
+)
+
+func compactDirFn(opts compactDirOpts, args ...string) (ui.Text, error) {
+	var dir string
+	switch len(args) {
+	case 0:
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	case 1:
+		dir = args[0]
+	default:
+		return nil, errs.ArityMismatch{
+			What: "arguments here", ValidLow: 0, ValidHigh: 1, Actual: len(args)}
+	}
+	if opts.Full < 0 {
+		return nil, errNegativeFull
+	}
+	if opts.Length <= 0 {
+		return nil, errNonPositiveLen
+	}
+	home, _ := fsutil.GetHome("")
+	aliases, err := scanAliases(opts.Aliases, home)
+	if err != nil {
+		return nil, err
+	}
+	s := compactDir(dir, home, aliases, opts.Full, opts.Length)
+	if opts.Style == "" {
+		return ui.T(s), nil
+	}
+	styling := ui.ParseStyling(opts.Style)
+	if styling == nil {
+		return nil, fmt.Errorf("bad style %s", parse.Quote(opts.Style))
+	}
+	return ui.T(s, styling), nil
+}
+
+type dirAlias struct{ dir, name string }
+
+func scanAliases(m vals.Map, home string) ([]dirAlias, error) {
+	var aliases []dirAlias
+	for it := m.Iterator(); it.HasElem(); it.Next() {
+		k, v := it.Elem()
+		dir, ok1 := k.(string)
+		name, ok2 := v.(string)
+		if !ok1 || !ok2 {
+			return nil, errs.BadValue{What: "aliases",
+				Valid: "map from strings to strings", Actual: vals.Repr(m, vals.NoPretty)}
+		}
+		if home != "" && (dir == "~" || strings.HasPrefix(dir, "~"+string(filepath.Separator))) {
+			dir = home + dir[1:]
+		}
+		aliases = append(aliases, dirAlias{filepath.Clean(dir), name})
+	}
+	return aliases, nil
+}
+
+// Shortens a directory. See the doc of compact-dir for details.
+func compactDir(dir, home string, aliases []dirAlias, full, length int) string {
+	const sep = string(filepath.Separator)
+	dir = filepath.Clean(dir)
+
+	// Find the prefix that is never abbreviated, and the rest of the path.
+	prefix, rest := "", dir
+	prefixLen := -1
+	match := func(d, name string) {
+		if len(d) > prefixLen && (dir == d || strings.HasPrefix(dir, d+sep)) {
+			prefix, rest, prefixLen = name, strings.TrimPrefix(dir[len(d):], sep), len(d)
+		}
+	}
+	// Like fsutil.TildeAbbr, the home directory is not abbreviated if it is
+	// the root.
+	if home != "" && home != sep {
+		match(home, "~")
+	}
+	for _, alias := range aliases {
+		match(alias.dir, alias.name)
+	}
+	if prefixLen == -1 {
+		if vol := filepath.VolumeName(dir); vol != "" || strings.HasPrefix(dir, sep) {
+			root := vol + sep
+			if dir == vol || dir == root {
+				return root
+			}
+			prefix, rest = root, strings.TrimPrefix(dir[len(vol):], sep)
+		}
+	} else if rest != "" {
+		prefix += sep
+	}
+
+	components := strings.Split(rest, sep)
+	if rest == "" {
+		components = nil
+	}
+	for i := 0; i < len(components)-full; i++ {
+		components[i] = abbreviate(components[i], length)
+	}
+	return prefix + strings.Join(components, sep)
+}
+
+// Keeps the first n characters of a component, or n+1 characters if it starts
+// with a dot.
+func abbreviate(component string, n int) string {
+	if strings.HasPrefix(component, ".") {
+		n++
+	}
+	runes := []rune(component)
+	if len(runes) <= n {
+		return component
+	}
+	return string(runes[:n])
+}
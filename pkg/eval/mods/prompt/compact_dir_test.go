@@ -0,0 +1,80 @@
+package prompt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestCompactDir(t *testing.T) {
+	defer testutil.WithTempEnv("HOME", "/home/elf")()
+
+	TestWithSetup(t, setupWith(newFakeEditor()),
+		That(`prompt:compact-dir /home/elf/src/elvish/pkg`).
+			Puts(ui.T(filepath.FromSlash("~/s/e/pkg"))),
+		That(`prompt:compact-dir &full=2 &length=2 &style=blue /usr/local/share`).
+			Puts(ui.T(filepath.FromSlash("/us/local/share"), ui.FgBlue)),
+		That(`prompt:compact-dir &aliases=[&~/src/elvish='~elv'] /home/elf/src/elvish/pkg/eval`).
+			Puts(ui.T(filepath.FromSlash("~elv/p/eval"))),
+
+		That(`prompt:compact-dir &full=-1 /`).Throws(errNegativeFull),
+		That(`prompt:compact-dir &length=0 /`).Throws(errNonPositiveLen),
+		That(`prompt:compact-dir &style=bad /`).
+			Throws(ErrorWithMessage("bad style bad")),
+		That(`prompt:compact-dir &aliases=[&/a=[]] /`).
+			Throws(ErrorWithType(errs.BadValue{})),
+		That(`prompt:compact-dir / /`).
+			Throws(ErrorWithType(errs.ArityMismatch{})),
+	)
+}
+
+var compactDirTests = []struct {
+	dir     string
+	aliases []dirAlias
+	full    int
+	length  int
+	want    string
+}{
+	{dir: "/", full: 1, length: 1, want: "/"},
+	{dir: "/usr", full: 1, length: 1, want: "/usr"},
+	{dir: "/usr/local/bin", full: 1, length: 1, want: "/u/l/bin"},
+	{dir: "/usr/local/bin", full: 0, length: 1, want: "/u/l/b"},
+	{dir: "/usr/local/bin", full: 5, length: 1, want: "/usr/local/bin"},
+	{dir: "/usr/local/bin/", full: 1, length: 2, want: "/us/lo/bin"},
+	// Home directory.
+	{dir: "/home/elf", full: 1, length: 1, want: "~"},
+	{dir: "/home/elf/a/b", full: 1, length: 1, want: "~/a/b"},
+	{dir: "/home/elfie/a", full: 1, length: 1, want: "/h/e/a"},
+	// Dot files and non-ASCII names.
+	{dir: "/home/elf/.config/elvish", full: 1, length: 1, want: "~/.c/elvish"},
+	{dir: "/home/elf/文档/x", full: 1, length: 1, want: "~/文/x"},
+	// Aliases; the longest match is used.
+	{dir: "/home/elf/src/elvish/pkg", full: 1, length: 1,
+		aliases: []dirAlias{{"/home/elf/src", "SRC"}, {"/home/elf/src/elvish", "~elv"}},
+		want:    "~elv/pkg"},
+	{dir: "/home/elf/src/elvish", full: 1, length: 1,
+		aliases: []dirAlias{{"/home/elf/src/elvish", "~elv"}},
+		want:    "~elv"},
+	{dir: "/srv/www/a/b", full: 1, length: 1,
+		aliases: []dirAlias{{"/srv/www", "www"}},
+		want:    "www/a/b"},
+}
+
+func TestCompactDir_Go(t *testing.T) {
+	for _, test := range compactDirTests {
+		var aliases []dirAlias
+		for _, alias := range test.aliases {
+			aliases = append(aliases, dirAlias{filepath.FromSlash(alias.dir), alias.name})
+		}
+		got := compactDir(filepath.FromSlash(test.dir), filepath.FromSlash("/home/elf"),
+			aliases, test.full, test.length)
+		if want := filepath.FromSlash(test.want); got != want {
+			t.Errorf("compactDir(%q, full=%d, length=%d) -> %q, want %q",
+				test.dir, test.full, test.length, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+//elvdoc:var git
+//
+// The status of the Git repository of the working directory, or `$nil` if the
+// working directory is not in a Git repository. The status is a map with the
+// following keys:
+//
+// -   `branch`: The current branch, or the abbreviated commit hash if no branch
+//     is checked out.
+//
+// -   `dirty`: Whether there are uncommitted changes to tracked files.
+//
+// -   `ahead` and `behind`: The numbers of commits the current branch is ahead
+//     of and behind its upstream branch, or `$nil` if it doesn't have one.
+//
+// The branch is read from the `.git` directory directly. The other fields are
+// found by running `git status` in the background, so reading this variable
+// never blocks; they are `$nil` until `git status` has finished, or if it
+// fails. When it finishes, the prompts are updated.
+//
+// The status is cached until the working directory changes or a command is
+// run. On Linux, changes to the `.git` directory made outside of Elvish, like
+// by a `git fetch` in another terminal, also cause the status to be updated.
+// When running external commands is not allowed, like in the instant mode, the
+// cached status is used without being updated.
+//
+// Example:
+//
+// ```elvish
+// use prompt
+// edit:rprompt = {
+//   if (and $prompt:git $prompt:git[dirty]) { styled '●' red }
+// }
+// ```
+
+// The $prompt:git variable.
+type gitVar struct{ p *gitProvider }
+
+var _ eval.RestrictableVar = gitVar{}
+
+func (gitVar) Set(interface{}) error { return vars.ErrSetReadOnlyVar }
+
+func (v gitVar) Get() interface{} {
+	status, _ := v.p.get()
+	return status.value()
+}
+
+// Restrict returns a variable that doesn't update the status, which runs git,
+// if running external commands is not allowed.
+func (v gitVar) Restrict(fm *eval.Frame) vars.Var {
+	if fm.CheckRestriction(eval.RestrictExternalCommands) == nil {
+		return v
+	}
+	return vars.FromGet(func() interface{} { return v.p.cached().value() })
+}
+
+// Finds the status of the Git repository of the working directory.
+type gitProvider struct {
+	ed      Editor
+	watcher gitWatcher
+
+	mutex sync.Mutex
+	// The working directory and the number of commands run when the status was
+	// last refreshed.
+	refreshed bool
+	wd        string
+	n         int
+	// Set when the .git directory has changed since the last refresh.
+	changed bool
+	status  *gitStatus
+	// Incremented whenever the status changes.
+	gen int
+	// Whether git status is running in the background, and whether it should
+	// be run again after it finishes.
+	running bool
+	pending bool
+}
+
+type gitStatus struct {
+	gitDir string
+	branch string
+	// Whether the output of git status is known.
+	known       bool
+	dirty       bool
+	hasUpstream bool
+	ahead       int
+	behind      int
+}
+
+func newGitProvider(ed Editor) *gitProvider {
+	p := &gitProvider{ed: ed}
+	p.watcher = newGitWatcher(p.invalidate)
+	return p
+}
+
+// Returns the status of the Git repository of the working directory, or nil if
+// it is not in a Git repository, and the generation of the status.
+func (p *gitProvider) get() (*gitStatus, int) {
+	wd, _ := os.Getwd()
+	n, _, _ := p.ed.LastCommand()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.refreshed || p.wd != wd || p.n != n || p.changed {
+		p.refresh(wd, n)
+	}
+	return p.status, p.gen
+}
+
+// Returns the status without refreshing it.
+func (p *gitProvider) cached() *gitStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.status
+}
+
+// Returns the generation of the status without refreshing it.
+func (p *gitProvider) generation() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.gen
+}
+
+func (p *gitProvider) invalidate() {
+	p.mutex.Lock()
+	p.changed = true
+	p.mutex.Unlock()
+	p.ed.TriggerPrompts()
+}
+
+// Reads the branch and starts git status in the background. Must be called
+// with the mutex held.
+func (p *gitProvider) refresh(wd string, n int) {
+	p.refreshed, p.wd, p.n, p.changed = true, wd, n, false
+	gitDir, ok := findGitDir(wd)
+	if !ok {
+		p.setStatus(nil)
+		p.watcher.watch("")
+		return
+	}
+	status := &gitStatus{gitDir: gitDir, branch: readBranch(gitDir)}
+	if old := p.status; old != nil && old.gitDir == gitDir && old.branch == status.branch {
+		// Keep the previous output of git status until the new one is
+		// available.
+		status.known, status.dirty = old.known, old.dirty
+		status.hasUpstream, status.ahead, status.behind = old.hasUpstream, old.ahead, old.behind
+	}
+	p.setStatus(status)
+	p.watcher.watch(gitDir)
+	if p.running {
+		p.pending = true
+	} else {
+		p.running = true
+		go p.runGitStatus(wd)
+	}
+}
+
+// Sets the status, incrementing the generation if it has changed. Must be
+// called with the mutex held.
+func (p *gitProvider) setStatus(status *gitStatus) bool {
+	if status == p.status || (status != nil && p.status != nil && *status == *p.status) {
+		return false
+	}
+	p.status = status
+	p.gen++
+	return true
+}
+
+func (p *gitProvider) runGitStatus(wd string) {
+	updated := false
+	for {
+		out, err := gitStatusOutput(wd)
+		p.mutex.Lock()
+		if p.status != nil && p.wd == wd && err == nil {
+			status := *p.status
+			parseGitStatus(out, &status)
+			if p.setStatus(&status) {
+				updated = true
+			}
+		}
+		if p.pending && p.status != nil {
+			p.pending = false
+			wd = p.wd
+			p.mutex.Unlock()
+			continue
+		}
+		p.pending = false
+		p.running = false
+		p.mutex.Unlock()
+		break
+	}
+	if updated {
+		p.ed.TriggerPrompts()
+	}
+}
+
+// Runs git status in the given directory. Optional locks are disabled so that
+// git status doesn't write to the index, which would trigger the watcher.
+// Untracked files are not looked for, since that can be slow in large
+// repositories.
+func gitStatusOutput(wd string) ([]byte, error) {
+	cmd := exec.Command("git", "--no-optional-locks", "status",
+		"--porcelain=v2", "--branch", "--untracked-files=no")
+	cmd.Dir = wd
+	return cmd.Output()
+}
+
+// Parses the output of git status --porcelain=v2 --branch into the status.
+func parseGitStatus(out []byte, status *gitStatus) {
+	status.known, status.dirty = true, false
+	status.hasUpstream, status.ahead, status.behind = false, 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			status.dirty = true
+			continue
+		}
+		fields := strings.Fields(line[2:])
+		if len(fields) == 3 && fields[0] == "branch.ab" {
+			status.hasUpstream = true
+			status.ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "+"))
+			status.behind, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "-"))
+		}
+	}
+}
+
+func readBranch(gitDir string) string {
+	head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	return describeHead(strings.TrimSpace(string(head)))
+}
+
+// Converts the status to the value of $prompt:git.
+func (s *gitStatus) value() interface{} {
+	if s == nil {
+		return nil
+	}
+	var dirty, ahead, behind interface{}
+	if s.known {
+		dirty = s.dirty
+		if s.hasUpstream {
+			ahead, behind = strconv.Itoa(s.ahead), strconv.Itoa(s.behind)
+		}
+	}
+	return vals.MakeMap(
+		"branch", s.branch, "dirty", dirty, "ahead", ahead, "behind", behind)
+}
+
+// Watches a .git directory for changes.
+type gitWatcher interface {
+	// Starts watching the given .git directory, and stops watching the
+	// previous one. An empty string stops watching.
+	watch(gitDir string)
+}
@@ -0,0 +1,150 @@
+package prompt
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/testutil"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+func TestGit_NotInRepo(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	TestWithSetup(t, setupWith(newFakeEditor()),
+		That(`put $prompt:git`).Puts(nil),
+	)
+}
+
+func TestGit(t *testing.T) {
+	cleanup := inTestRepo(t)
+	defer cleanup()
+
+	ed := newFakeEditor()
+	ev := eval.NewEvaler()
+	setupWith(ed)(ev)
+
+	// The branch is available immediately, the rest when git status has
+	// finished.
+	testGit(t, ev, vals.MakeMap(
+		"branch", "main", "dirty", nil, "ahead", nil, "behind", nil))
+	waitGit(t, ev, vals.MakeMap(
+		"branch", "main", "dirty", false, "ahead", nil, "behind", nil))
+	select {
+	case <-ed.triggers:
+	default:
+		t.Errorf("prompts not triggered after git status finished")
+	}
+
+	// The status is cached until a command is run.
+	testutil.MustWriteFile("a", []byte("b"), 0600)
+	time.Sleep(testutil.ScaledMs(10))
+	testGit(t, ev, vals.MakeMap(
+		"branch", "main", "dirty", false, "ahead", nil, "behind", nil))
+	ed.n++
+	waitGit(t, ev, vals.MakeMap(
+		"branch", "main", "dirty", true, "ahead", nil, "behind", nil))
+	evals(t, ev, `f = (prompt:make git)`)
+	testPrompt(t, ev, ui.T("main*", ui.FgMagenta))
+}
+
+func TestGit_Restricted(t *testing.T) {
+	cleanup := inTestRepo(t)
+	defer cleanup()
+
+	setup := func(ev *eval.Evaler) {
+		setupWith(newFakeEditor())(ev)
+		ev.Restrictions = eval.Restrictions{NoExternalCommands: true}
+	}
+	TestWithSetup(t, setup,
+		// The status is not computed, since that runs git.
+		That(`put $prompt:git`).Puts(nil),
+		That(`f = (prompt:make git); $f`).Throws(
+			eval.RestrictionViolation{Restriction: eval.RestrictExternalCommands}),
+	)
+}
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		out  string
+		want gitStatus
+	}{
+		{"# branch.oid 0123\n# branch.head main\n",
+			gitStatus{known: true}},
+		{"# branch.head main\n# branch.upstream origin/main\n# branch.ab +2 -1\n",
+			gitStatus{known: true, hasUpstream: true, ahead: 2, behind: 1}},
+		{"# branch.head main\n1 .M N... 100644 100644 100644 0123 0123 a\n",
+			gitStatus{known: true, dirty: true}},
+	}
+	for _, test := range tests {
+		var got gitStatus
+		parseGitStatus([]byte(test.out), &got)
+		if got != test.want {
+			t.Errorf("parseGitStatus(%q) -> %+v, want %+v", test.out, got, test.want)
+		}
+	}
+}
+
+// Creates a Git repository with one commit on the main branch in a temporary
+// directory, and changes into it.
+func inTestRepo(t *testing.T) func() {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	_, cleanup := testutil.InTestDir()
+	git(t, "init", "-q")
+	git(t, "symbolic-ref", "HEAD", "refs/heads/main")
+	testutil.MustWriteFile("a", []byte("a"), 0600)
+	git(t, "add", "a")
+	git(t, "-c", "user.name=elf", "-c", "user.email=elf@example.com",
+		"commit", "-q", "-m", "init")
+	return cleanup
+}
+
+func git(t *testing.T, args ...string) {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func testGit(t *testing.T, ev *eval.Evaler, want interface{}) {
+	t.Helper()
+	if got := getGit(t, ev); !vals.Equal(got, want) {
+		t.Errorf("got $prompt:git %s, want %s",
+			vals.Repr(got, vals.NoPretty), vals.Repr(want, vals.NoPretty))
+	}
+}
+
+// Waits until $prompt:git has the wanted value.
+func waitGit(t *testing.T, ev *eval.Evaler, want interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(testutil.ScaledMs(2000))
+	for {
+		got := getGit(t, ev)
+		if vals.Equal(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got $prompt:git %s, want %s",
+				vals.Repr(got, vals.NoPretty), vals.Repr(want, vals.NoPretty))
+		}
+		time.Sleep(testutil.ScaledMs(10))
+	}
+}
+
+func getGit(t *testing.T, ev *eval.Evaler) interface{} {
+	t.Helper()
+	r := EvalAndCollect(t, ev, []string{`put $prompt:git`})
+	if len(r.ValueOut) != 1 {
+		t.Fatalf("got outputs %v, want one", r.ValueOut)
+	}
+	return r.ValueOut[0]
+}
@@ -0,0 +1,144 @@
+package prompt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const gitWatchMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_TO | unix.IN_MOVED_FROM
+
+// Files directly in the .git directory that affect the status. Changes to other
+// files, like the ones written by git status itself, are ignored.
+var gitDirFiles = map[string]bool{"HEAD": true, "index": true, "packed-refs": true}
+
+// Watches .git directories with inotify.
+type inotifyGitWatcher struct {
+	onChange func()
+
+	mutex sync.Mutex
+	// The inotify file descriptor, and an *os.File wrapping it. The file is
+	// non-blocking, so closing it also wakes up the goroutine reading it.
+	fd     int
+	file   *os.File
+	gitDir string
+	// Maps watch descriptors to the directories they are for.
+	watches map[int32]string
+}
+
+func newGitWatcher(onChange func()) gitWatcher {
+	return &inotifyGitWatcher{onChange: onChange, fd: -1}
+}
+
+func (w *inotifyGitWatcher) watch(gitDir string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if gitDir == w.gitDir {
+		return
+	}
+	w.gitDir = gitDir
+	for wd := range w.watches {
+		unix.InotifyRmWatch(w.fd, uint32(wd))
+	}
+	w.watches = make(map[int32]string)
+	if gitDir == "" {
+		if w.file != nil {
+			w.file.Close()
+			w.fd, w.file = -1, nil
+		}
+		return
+	}
+	if w.file == nil {
+		fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+		if err != nil {
+			// Changes are still picked up when a command is run.
+			return
+		}
+		w.fd, w.file = fd, os.NewFile(uintptr(fd), "inotify")
+		go w.read(w.file)
+	}
+	w.add(gitDir)
+	w.addTree(filepath.Join(gitDir, "refs", "heads"))
+	// Remote branches are needed to find how far the current branch is ahead
+	// of or behind its upstream.
+	w.addTree(filepath.Join(gitDir, "refs", "remotes"))
+}
+
+// Adds a watch. Must be called with the mutex held.
+func (w *inotifyGitWatcher) add(dir string) {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, gitWatchMask)
+	if err == nil {
+		w.watches[int32(wd)] = dir
+	}
+}
+
+// Adds watches for a directory and all its subdirectories, since branch names
+// like a/b are stored in nested directories. Must be called with the mutex
+// held.
+func (w *inotifyGitWatcher) addTree(dir string) {
+	w.add(dir)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			w.addTree(filepath.Join(dir, info.Name()))
+		}
+	}
+}
+
+func (w *inotifyGitWatcher) read(file *os.File) {
+	var buf [unix.SizeofInotifyEvent * 64]byte
+	for {
+		n, err := file.Read(buf[:])
+		if err != nil || n <= 0 {
+			// The file has been closed.
+			return
+		}
+		changed := false
+		w.mutex.Lock()
+		if w.file != file {
+			w.mutex.Unlock()
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			dir, ok := w.watches[event.Wd]
+			if !ok {
+				continue
+			}
+			if event.Mask&unix.IN_IGNORED != 0 {
+				// The directory has been removed.
+				delete(w.watches, event.Wd)
+				continue
+			}
+			if strings.HasSuffix(name, ".lock") {
+				continue
+			}
+			if dir != w.gitDir {
+				if event.Mask&unix.IN_ISDIR != 0 &&
+					event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					w.addTree(filepath.Join(dir, name))
+				}
+				changed = true
+			} else if gitDirFiles[name] {
+				changed = true
+			}
+		}
+		w.mutex.Unlock()
+		if changed {
+			w.onChange()
+		}
+	}
+}
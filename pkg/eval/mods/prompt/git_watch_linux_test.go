@@ -0,0 +1,105 @@
+package prompt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/testutil"
+	"golang.org/x/sys/unix"
+)
+
+func TestGit_Watch(t *testing.T) {
+	cleanup := inTestRepo(t)
+	defer cleanup()
+
+	ed := newFakeEditor()
+	ev := eval.NewEvaler()
+	setupWith(ed)(ev)
+	waitGit(t, ev, vals.MakeMap(
+		"branch", "main", "dirty", false, "ahead", nil, "behind", nil))
+
+	// Changes to the .git directory are picked up without running a command.
+	git(t, "checkout", "-q", "-b", "feature")
+	waitGit(t, ev, vals.MakeMap(
+		"branch", "feature", "dirty", false, "ahead", nil, "behind", nil))
+
+	// Changes to other files in the .git directory, like the lock files, are
+	// ignored.
+	time.Sleep(testutil.ScaledMs(50))
+	drain(ed.triggers)
+	testutil.MustWriteFile(".git/index.lock", nil, 0600)
+	testutil.MustWriteFile(".git/FETCH_HEAD", nil, 0600)
+	time.Sleep(testutil.ScaledMs(50))
+	select {
+	case <-ed.triggers:
+		t.Errorf("prompts triggered after writing files not affecting the status")
+	default:
+	}
+}
+
+func TestInotifyGitWatcher_NestedRefs(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{".git": testutil.Dir{
+		"HEAD": "ref: refs/heads/a/b\n",
+		"refs": testutil.Dir{
+			"heads":   testutil.Dir{"a": testutil.Dir{"b": "0123\n"}},
+			"remotes": testutil.Dir{},
+		},
+	}})
+
+	changes := make(chan struct{}, 100)
+	w := newGitWatcher(func() { changes <- struct{}{} })
+	w.watch(".git")
+	defer w.watch("")
+
+	// Existing nested directories are watched.
+	testutil.MustWriteFile(".git/refs/heads/a/b", []byte("4567\n"), 0600)
+	waitChange(t, changes)
+	// So are directories created after the watch started, and their
+	// subdirectories.
+	testutil.MustMkdirAll(".git/refs/remotes/origin/c")
+	waitChange(t, changes)
+	time.Sleep(testutil.ScaledMs(50))
+	drain(changes)
+	testutil.MustWriteFile(".git/refs/remotes/origin/c/d", []byte("0123\n"), 0600)
+	waitChange(t, changes)
+}
+
+func TestInotifyGitWatcher_ClosesFdWhenStopped(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{".git": testutil.Dir{"HEAD": ""}})
+
+	w := newGitWatcher(func() {}).(*inotifyGitWatcher)
+	w.watch(".git")
+	fd := w.fd
+	if fd == -1 {
+		t.Fatalf("inotify file descriptor not created")
+	}
+	w.watch("")
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != unix.EBADF {
+		t.Errorf("inotify file descriptor not closed after stopping")
+	}
+}
+
+func waitChange(t *testing.T, changes chan struct{}) {
+	t.Helper()
+	select {
+	case <-changes:
+	case <-time.After(testutil.ScaledMs(2000)):
+		t.Fatalf("change not picked up")
+	}
+}
+
+func drain(ch chan struct{}) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
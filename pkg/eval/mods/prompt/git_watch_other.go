@@ -0,0 +1,11 @@
+// +build !linux
+
+package prompt
+
+// On platforms other than Linux, .git directories are not watched, and changes
+// are picked up when a command is run or the working directory changes.
+type nopGitWatcher struct{}
+
+func newGitWatcher(func()) gitWatcher { return nopGitWatcher{} }
+
+func (nopGitWatcher) watch(string) {}
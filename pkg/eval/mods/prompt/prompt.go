@@ -0,0 +1,380 @@
+// Package prompt implements the prompt: module, which composes prompts from
+// segments.
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+// Editor is the part of the interactive editor that the prompt: module depends
+// on.
+type Editor interface {
+	// LastCommand returns the number of commands run so far, and the duration
+	// and the error of the last one.
+	LastCommand() (n int, duration time.Duration, err error)
+	// TriggerPrompts requests the prompts to be recomputed.
+	TriggerPrompts()
+}
+
+// Ns makes the namespace for the prompt: module. Segments written in Elvish are
+// called with the given Evaler.
+func Ns(ev *eval.Evaler, ed Editor) *eval.Ns {
+	m := &module{ev: ev, ed: ed, git: newGitProvider(ed)}
+	return eval.NsBuilder{
+		"git": gitVar{m.git},
+	}.AddGoFns("prompt:", map[string]interface{}{
+		"segment":     m.segment,
+		"make":        m.make,
+		"compact-dir": compactDirFn,
+	}).Ns()
+}
+
+//elvdoc:fn segment
+//
+// ```elvish
+// prompt:segment &style=$nil &async=$false &max-age=0 $source
+// ```
+//
+// Outputs a prompt segment, to be used with [`prompt:make`](#promptmake).
+//
+// The `$source` is either the name of a builtin segment, or a function that
+// outputs the content of the segment as strings or styled texts. The builtin
+// segments are:
+//
+// -   `cwd`: The working directory, with the home directory abbreviated to
+//     `~`.
+//
+// -   `git`: The current branch of the Git repository of the working directory,
+//     or the abbreviated commit hash if no branch is checked out, followed by
+//     `*` if there are uncommitted changes, and `↑` and `↓` with the numbers of
+//     commits ahead of and behind the upstream branch. See
+//     [`$prompt:git`](#promptgit).
+//
+// -   `kube`: The current context in the Kubernetes configuration.
+//
+// -   `venv`: The name of the active Python virtual environment or Conda
+//     environment.
+//
+// -   `status`: A cross if the last command has failed, followed by the exit
+//     status if it was an external command that exited.
+//
+// -   `duration`: The duration of the last command, if it is at least 2
+//     seconds.
+//
+// The `&style` option is a string like `'bold blue'`, in the same format as
+// the `$style` argument of [`styled`](builtin.html#styled). When it is `$nil`,
+// builtin segments use their default styles, and other segments are left
+// unstyled.
+//
+// The `&async` option controls whether the segment is computed in the
+// background. An asynchronous segment shows the previous content while it is
+// being computed, and the prompt is updated when it is done. The `git` segment
+// doesn't need to be asynchronous, since the slow part of finding the status
+// of the repository is already done in the background.
+//
+// The content of a segment is cached until the working directory changes, a
+// command is run or [`$prompt:git`](#promptgit) changes. If `&max-age` is
+// positive, the content is also recomputed when it is older than that many
+// seconds.
+//
+// Segments that throw exceptions are left out, and the exceptions are written
+// to the standard error of the prompt.
+
+//elvdoc:fn make
+//
+// ```elvish
+// prompt:make &separator=' ' &powerline=$false $segment...
+// ```
+//
+// Outputs a function that outputs a prompt composed from the given segments,
+// which is suitable as the value of
+// [`$edit:prompt`](edit.html#editprompt) or
+// [`$edit:rprompt`](edit.html#editrprompt). Each `$segment` is either the
+// output of [`prompt:segment`](#promptsegment), or a value that
+// `prompt:segment` accepts as its `$source`, in which case the segment uses the
+// default options.
+//
+// Segments with empty content are left out, and the remaining segments are
+// joined with `&separator`.
+//
+// When `&powerline` is true, each segment is padded with a space on both
+// sides, and the separator takes the background color of the segment on its
+// left as the foreground color, and the background color of the segment on its
+// right as the background color. The separator is also written after the last
+// segment. This is meant to be used with segments that have background colors
+// and a separator like `"\ue0b0"` from the Powerline fonts.
+//
+// Since computing the segments may run external commands, calling the function
+// throws an exception when running external commands is not allowed, like in
+// the instant mode.
+//
+// Examples:
+//
+// ```elvish
+// use prompt
+// edit:prompt = (prompt:make cwd git status (constantly '> '))
+// edit:rprompt = (prompt:make &separator=' | ' venv kube duration)
+// edit:prompt = (prompt:make &powerline &separator="\ue0b0" ^
+//   (prompt:segment &style='bg-blue' cwd) ^
+//   (prompt:segment &style='bg-magenta' git))
+// ```
+
+var errBadSegment = errors.New("segment must be a string or a callable")
+
+type module struct {
+	ev  *eval.Evaler
+	ed  Editor
+	git *gitProvider
+}
+
+type segmentOpts struct {
+	Style  interface{}
+	Async  bool
+	MaxAge float64
+}
+
+func (*segmentOpts) SetDefaultOptions() {}
+
+func (m *module) segment(opts segmentOpts, source interface{}) (*Segment, error) {
+	var s *Segment
+	switch source := source.(type) {
+	case string:
+		b, ok := builtinSegments[source]
+		if !ok {
+			return nil, fmt.Errorf("no builtin segment named %s", parse.Quote(source))
+		}
+		s = &Segment{name: source, compute: b.compute,
+			styling: ui.ParseStyling(b.style)}
+	case eval.Callable:
+		s = &Segment{name: vals.Repr(source, vals.NoPretty),
+			compute: func(m *module) (ui.Text, error) { return m.call(source) }}
+	default:
+		return nil, errBadSegment
+	}
+
+	switch style := opts.Style.(type) {
+	case nil:
+	case string:
+		if style == "" {
+			s.styling = nil
+		} else if s.styling = ui.ParseStyling(style); s.styling == nil {
+			return nil, fmt.Errorf("bad style %s", parse.Quote(style))
+		}
+	default:
+		return nil, errors.New("style must be a string")
+	}
+	s.async = opts.Async
+	s.maxAge = time.Duration(opts.MaxAge * float64(time.Second))
+	return s, nil
+}
+
+type makeOpts struct {
+	Separator string
+	Powerline bool
+}
+
+func (o *makeOpts) SetDefaultOptions() { o.Separator = " " }
+
+func (m *module) make(opts makeOpts, args ...interface{}) (eval.Callable, error) {
+	segments := make([]*Segment, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(*Segment); ok {
+			segments[i] = s
+			continue
+		}
+		s, err := m.segment(segmentOpts{}, arg)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = s
+	}
+	return eval.NewGoFn("<prompt>", func(fm *eval.Frame) error {
+		if err := fm.CheckRestriction(eval.RestrictExternalCommands); err != nil {
+			return err
+		}
+		texts := make([]ui.Text, 0, len(segments))
+		var stylings []ui.Styling
+		for _, s := range segments {
+			text, err := m.get(s)
+			if err != nil {
+				fmt.Fprintf(fm.ErrorFile(), "segment %s: %v\n", s.name, err)
+				continue
+			}
+			if isEmpty(text) {
+				continue
+			}
+			texts = append(texts, text)
+			stylings = append(stylings, s.styling)
+		}
+		fm.OutputChan() <- compose(texts, stylings, opts)
+		return nil
+	}), nil
+}
+
+func isEmpty(t ui.Text) bool {
+	for _, seg := range t {
+		if seg.Text != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Composes the contents of segments, which have already been styled with the
+// given stylings.
+func compose(texts []ui.Text, stylings []ui.Styling, opts makeOpts) ui.Text {
+	var result ui.Text
+	if !opts.Powerline {
+		for i, text := range texts {
+			if i > 0 {
+				result = ui.Concat(result, ui.T(opts.Separator))
+			}
+			result = ui.Concat(result, text)
+		}
+		return result
+	}
+	for i, text := range texts {
+		bg := background(stylings[i])
+		padding := ui.T(" ")
+		if stylings[i] != nil {
+			padding = ui.T(" ", stylings[i])
+		}
+		result = ui.Concat(result, padding, text, padding)
+		if i+1 < len(texts) {
+			result = ui.Concat(result, ui.T(opts.Separator,
+				ui.Fg(bg), ui.Bg(background(stylings[i+1]))))
+		} else {
+			result = ui.Concat(result, ui.T(opts.Separator, ui.Fg(bg)))
+		}
+	}
+	return result
+}
+
+func background(s ui.Styling) ui.Color {
+	if s == nil {
+		return nil
+	}
+	return ui.ApplyStyling(ui.Style{}, s).Background
+}
+
+// Segment is a segment of a prompt.
+type Segment struct {
+	name    string
+	compute func(m *module) (ui.Text, error)
+	styling ui.Styling
+	async   bool
+	maxAge  time.Duration
+
+	mutex     sync.Mutex
+	cached    bool
+	text      ui.Text
+	err       error
+	key       cacheKey
+	time      time.Time
+	computing bool
+}
+
+// The cached content of a segment is only valid when the working directory,
+// the number of commands run and the generation of the Git status are the
+// same.
+type cacheKey struct {
+	wd  string
+	n   int
+	git int
+}
+
+func (*Segment) Kind() string { return "prompt:segment" }
+
+func (s *Segment) Repr(int) string { return "<prompt:segment " + s.name + ">" }
+
+// Returns the styled content of a segment, using the cached content if it is
+// still valid. Asynchronous segments are computed in the background, and the
+// previous content is returned meanwhile if the working directory is the same.
+func (m *module) get(s *Segment) (ui.Text, error) {
+	wd, _ := os.Getwd()
+	n, _, _ := m.ed.LastCommand()
+	key := cacheKey{wd, n, m.git.generation()}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cached && s.key == key && (s.maxAge <= 0 || time.Since(s.time) < s.maxAge) {
+		return s.text, s.err
+	}
+	if !s.async {
+		s.store(key, m.computeStyled(s))
+		return s.text, s.err
+	}
+	if !s.computing {
+		s.computing = true
+		go func() {
+			r := m.computeStyled(s)
+			s.mutex.Lock()
+			s.store(key, r)
+			s.computing = false
+			s.mutex.Unlock()
+			m.ed.TriggerPrompts()
+		}()
+	}
+	if s.cached && s.key.wd == wd {
+		return s.text, s.err
+	}
+	return nil, nil
+}
+
+type result struct {
+	text ui.Text
+	err  error
+}
+
+func (m *module) computeStyled(s *Segment) result {
+	text, err := s.compute(m)
+	if s.styling != nil {
+		text = ui.StyleText(text, s.styling)
+	}
+	return result{text, err}
+}
+
+// Stores the result of computing the segment. Must be called with the mutex
+// held.
+func (s *Segment) store(key cacheKey, r result) {
+	s.cached, s.key, s.time = true, key, time.Now()
+	s.text, s.err = r.text, r.err
+}
+
+// Calls a function and concatenates its outputs to a styled text.
+func (m *module) call(fn eval.Callable) (ui.Text, error) {
+	var text ui.Text
+	var errConcat error
+	add := func(v interface{}) {
+		concat, err := text.Concat(v)
+		if err != nil {
+			errConcat = fmt.Errorf("invalid output type: %s", vals.Kind(v))
+			return
+		}
+		text = concat.(ui.Text)
+	}
+	var bytes strings.Builder
+	err := m.ev.CallWithOutputCallback(fn,
+		eval.CallCfg{From: "[prompt segment]"}, eval.EvalCfg{},
+		eval.OutputCallbacks{
+			Value: add,
+			Bytes: func(line string) { bytes.WriteString(line) },
+		})
+	if bytes.Len() > 0 {
+		add(ui.ParseSGREscapedText(strings.TrimSuffix(bytes.String(), "\n")))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return text, errConcat
+}
@@ -0,0 +1,182 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+	"github.com/elves/elvish/pkg/ui"
+)
+
+type fakeEditor struct {
+	n        int
+	duration time.Duration
+	err      error
+	triggers chan struct{}
+}
+
+func newFakeEditor() *fakeEditor {
+	return &fakeEditor{triggers: make(chan struct{}, 10)}
+}
+
+func (ed *fakeEditor) LastCommand() (int, time.Duration, error) {
+	return ed.n, ed.duration, ed.err
+}
+
+func (ed *fakeEditor) TriggerPrompts() {
+	select {
+	case ed.triggers <- struct{}{}:
+	default:
+	}
+}
+
+func setupWith(ed Editor) func(*eval.Evaler) {
+	return func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("prompt", Ns(ev, ed)).Ns()
+	}
+}
+
+func TestMake(t *testing.T) {
+	TestWithSetup(t, setupWith(newFakeEditor()),
+		That(`f = (prompt:make { put foo } { } { styled bar red })`, `$f`).
+			Puts(ui.Concat(ui.T("foo"), ui.T(" "), ui.T("bar", ui.FgRed))),
+		That(`f = (prompt:make &separator=' | ' { put foo } { echo bar })`, `$f`).
+			Puts(ui.Concat(ui.T("foo"), ui.T(" | "), ui.T("bar"))),
+		// Segments that throw exceptions are left out.
+		That(`f = (prompt:make { fail bad } { put foo })`, `$f`).
+			Puts(ui.T("foo")).PrintsStderrWith("bad"),
+		That(`prompt:make no-such-segment`).
+			Throws(ErrorWithMessage("no builtin segment named no-such-segment")),
+		That(`prompt:make [foo]`).Throws(errBadSegment),
+	)
+}
+
+func TestMake_Powerline(t *testing.T) {
+	TestWithSetup(t, setupWith(newFakeEditor()),
+		That(`f = (prompt:make &powerline &separator='>' `+
+			`(prompt:segment &style=bg-blue { put a }) `+
+			`(prompt:segment &style=bg-red { put b }))`, `$f`).
+			Puts(ui.Concat(
+				ui.T(" ", ui.BgBlue), ui.T("a", ui.BgBlue), ui.T(" ", ui.BgBlue),
+				ui.T(">", ui.FgBlue, ui.BgRed),
+				ui.T(" ", ui.BgRed), ui.T("b", ui.BgRed), ui.T(" ", ui.BgRed),
+				ui.T(">", ui.FgRed))),
+	)
+}
+
+func TestSegment(t *testing.T) {
+	TestWithSetup(t, setupWith(newFakeEditor()),
+		That(`f = (prompt:make (prompt:segment &style='bold red' { put foo }))`, `$f`).
+			Puts(ui.T("foo", ui.Bold, ui.FgRed)),
+		That(`kind-of (prompt:segment cwd)`).Puts("prompt:segment"),
+		That(`repr (prompt:segment cwd)`).Prints("<prompt:segment cwd>\n"),
+		That(`prompt:segment &style=bad-style cwd`).
+			Throws(ErrorWithMessage("bad style bad-style")),
+	)
+}
+
+func TestSegment_Cache(t *testing.T) {
+	ed := newFakeEditor()
+	ev := eval.NewEvaler()
+	setupWith(ed)(ev)
+	evals(t, ev, `n = 0`,
+		`f = (prompt:make { n = (+ $n 1); put $n })`)
+
+	testPrompt(t, ev, ui.T("1"))
+	// The cached content is used until a command is run.
+	testPrompt(t, ev, ui.T("1"))
+	ed.n++
+	testPrompt(t, ev, ui.T("2"))
+}
+
+func TestSegment_MaxAge(t *testing.T) {
+	ev := eval.NewEvaler()
+	setupWith(newFakeEditor())(ev)
+	evals(t, ev, `n = 0`,
+		`f = (prompt:make (prompt:segment &max-age=0.01 { n = (+ $n 1); put $n }))`)
+
+	testPrompt(t, ev, ui.T("1"))
+	time.Sleep(20 * time.Millisecond)
+	testPrompt(t, ev, ui.T("2"))
+}
+
+func TestSegment_Async(t *testing.T) {
+	ed := newFakeEditor()
+	ev := eval.NewEvaler()
+	setupWith(ed)(ev)
+	evals(t, ev, `f = (prompt:make (prompt:segment &async { put foo }))`)
+
+	// Nothing is shown before the segment has been computed.
+	testPrompt(t, ev, nil)
+	select {
+	case <-ed.triggers:
+	case <-time.After(testutil.ScaledMs(1000)):
+		t.Fatalf("prompts not triggered after computing the segment")
+	}
+	testPrompt(t, ev, ui.T("foo"))
+}
+
+func TestBuiltinSegments(t *testing.T) {
+	_, cleanup := testutil.InTempHome()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		".git": testutil.Dir{"HEAD": "ref: refs/heads/main\n"},
+		"d":    testutil.Dir{},
+		".kube": testutil.Dir{
+			"config": "apiVersion: v1\ncurrent-context: prod\n"},
+	})
+	defer testutil.WithTempEnv("KUBECONFIG", "")()
+	defer testutil.WithTempEnv("VIRTUAL_ENV", "/home/elf/venvs/proj")()
+
+	ed := newFakeEditor()
+	ed.duration = 3200 * time.Millisecond
+	ed.err = errors.New("failed")
+	TestWithSetup(t, setupWith(ed),
+		That(`f = (prompt:make cwd git kube venv status duration)`,
+			`$f`).
+			Puts(ui.Concat(
+				ui.T("~", ui.FgBlue), ui.T(" "),
+				ui.T("main", ui.FgMagenta), ui.T(" "),
+				ui.T("prod", ui.FgCyan), ui.T(" "),
+				ui.T("proj", ui.FgYellow), ui.T(" "),
+				ui.T("✘", ui.FgRed), ui.T(" "),
+				ui.T("3.2s", ui.FgYellow))),
+	)
+}
+
+func TestDescribeHead(t *testing.T) {
+	tests := []struct{ head, want string }{
+		{"ref: refs/heads/main", "main"},
+		{"ref: refs/heads/feature/x", "feature/x"},
+		{"0123456789abcdef", "0123456"},
+	}
+	for _, test := range tests {
+		if got := describeHead(test.head); got != test.want {
+			t.Errorf("describeHead(%q) -> %q, want %q", test.head, got, test.want)
+		}
+	}
+}
+
+func evals(t *testing.T, ev *eval.Evaler, codes ...string) {
+	t.Helper()
+	r := EvalAndCollect(t, ev, codes)
+	if r.Exception != nil || r.CompilationError != nil {
+		t.Fatalf("got exception %v, compilation error %v",
+			r.Exception, r.CompilationError)
+	}
+}
+
+func testPrompt(t *testing.T, ev *eval.Evaler, want ui.Text) {
+	t.Helper()
+	r := EvalAndCollect(t, ev, []string{`$f`})
+	if len(r.ValueOut) != 1 {
+		t.Fatalf("got outputs %v, want one", r.ValueOut)
+	}
+	got := r.ValueOut[0].(ui.Text)
+	if got.String() != want.String() {
+		t.Errorf("got prompt %q, want %q", got.String(), want.String())
+	}
+}
@@ -0,0 +1,175 @@
+// Package rand exposes functions for generating random values as the rand:
+// module.
+package rand
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Ns is the namespace for the rand: module.
+var Ns = eval.NsBuilder{}.AddGoFns("rand:", map[string]interface{}{
+	"float":   rand.Float64,
+	"normal":  normal,
+	"int":     randInt,
+	"bytes":   randBytes,
+	"shuffle": shuffle,
+	"uuid":    uuid,
+}).Ns()
+
+//elvdoc:fn float
+//
+// ```elvish
+// rand:float
+// ```
+//
+// Outputs a pseudo-random number in the interval [0, 1), uniformly
+// distributed. This is the same as the builtin [`rand`](builtin.html#rand).
+//
+// ```elvish-transcript
+// ~> rand:float
+// ▶ (float64 0.17843564133528436)
+// ```
+//
+// @cf rand:normal
+
+//elvdoc:fn normal
+//
+// ```elvish
+// rand:normal &mean=0 &stddev=1
+// ```
+//
+// Outputs a pseudo-random number from the normal distribution with the given
+// mean and standard deviation.
+//
+// ```elvish-transcript
+// ~> rand:normal
+// ▶ (float64 -0.5293212741298364)
+// ~> rand:normal &mean=100 &stddev=15
+// ▶ (float64 108.00742516305232)
+// ```
+//
+// @cf rand:float
+
+type normalOpts struct {
+	Mean   float64
+	Stddev float64
+}
+
+func (opts *normalOpts) SetDefaultOptions() { opts.Stddev = 1 }
+
+func normal(opts normalOpts) float64 {
+	return rand.NormFloat64()*opts.Stddev + opts.Mean
+}
+
+//elvdoc:fn int
+//
+// ```elvish
+// rand:int $low $high
+// ```
+//
+// Outputs a pseudo-random integer in the interval [$low, $high), uniformly
+// distributed. This is the same as the builtin
+// [`randint`](builtin.html#randint).
+//
+// ```elvish-transcript
+// ~> # Emulate dice
+// ~> rand:int 1 7
+// ▶ 6
+// ```
+
+func randInt(low, high int) (int, error) {
+	if low >= high {
+		return 0, errs.BadValue{What: "high",
+			Valid: fmt.Sprintf("greater than low (%d)", low), Actual: strconv.Itoa(high)}
+	}
+	return low + rand.Intn(high-low), nil
+}
+
+//elvdoc:fn bytes
+//
+// ```elvish
+// rand:bytes $n
+// ```
+//
+// Outputs `$n` random bytes from a cryptographically secure source, suitable
+// for generating keys and tokens.
+//
+// ```elvish-transcript
+// ~> rand:bytes 8
+// ▶ (bytes:from-hex 5f1d35e2b6a0c9c4)
+// ~> bytes:to-base64 &url (rand:bytes 18)
+// ▶ 7yT1mHc8Vb2lZ0q4nXo3Ks6d
+// ```
+
+func randBytes(n int) (vals.Bytes, error) {
+	if n < 0 {
+		return "", errs.BadValue{What: "n",
+			Valid: "non-negative", Actual: strconv.Itoa(n)}
+	}
+	b := make([]byte, n)
+	_, err := crand.Read(b)
+	return vals.Bytes(b), err
+}
+
+//elvdoc:fn shuffle
+//
+// ```elvish
+// rand:shuffle $list
+// ```
+//
+// Outputs a list with the elements of `$list` in a pseudo-random order.
+// `$list` can be any iterable value.
+//
+// ```elvish-transcript
+// ~> rand:shuffle [a b c d]
+// ▶ [c a d b]
+// ~> rand:shuffle [(range 5)]
+// ▶ [(float64 3) (float64 0) (float64 4) (float64 1) (float64 2)]
+// ```
+
+func shuffle(list interface{}) (vals.List, error) {
+	var elems []interface{}
+	err := vals.Iterate(list, func(v interface{}) bool {
+		elems = append(elems, v)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(elems), func(i, j int) {
+		elems[i], elems[j] = elems[j], elems[i]
+	})
+	return vals.MakeList(elems...), nil
+}
+
+//elvdoc:fn uuid
+//
+// ```elvish
+// rand:uuid
+// ```
+//
+// Outputs a random (version 4) UUID, generated from a cryptographically
+// secure source.
+//
+// ```elvish-transcript
+// ~> rand:uuid
+// ▶ 6b1a0d1c-4e4f-4c3b-9a6e-2f5d8c7b1e90
+// ```
+
+func uuid() (string, error) {
+	var b [16]byte
+	_, err := crand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // Variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
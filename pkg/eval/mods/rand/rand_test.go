@@ -0,0 +1,43 @@
+package rand
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestRand(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("rand", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("x = (rand:float); and (<= 0 $x) (< $x 1)").Puts(true),
+		That("kind-of (rand:normal)").Puts("number"),
+		// With a standard deviation of 0, the output is always the mean.
+		That("rand:normal &mean=10 &stddev=0").Puts(10.0),
+
+		That("x = (rand:int 1 3); or (== $x 1) (== $x 2)").Puts(true),
+		That("rand:int 2 3").Puts("2"),
+		That("rand:int 3 3").Throws(errs.BadValue{
+			What: "high", Valid: "greater than low (3)", Actual: "3"}),
+
+		That("kind-of (rand:bytes 4)").Puts("bytes"),
+		That("count (rand:bytes 16)").Puts("16"),
+		That("count (rand:bytes 0)").Puts("0"),
+		That("rand:bytes -1").Throws(errs.BadValue{
+			What: "n", Valid: "non-negative", Actual: "-1"}),
+
+		That("l = (rand:shuffle [a b c d e]); count $l; order $l").
+			Puts("5", "a", "b", "c", "d", "e"),
+		That("rand:shuffle []").Puts(vals.EmptyList),
+		That("rand:shuffle (num 1)").Throws(AnyError),
+
+		That("u = (rand:uuid); count $u; put $u[8] $u[13] $u[14] $u[18] $u[23]").
+			Puts("36", "-", "-", "4", "-", "-"),
+		That("has-value [8 9 a b] (rand:uuid)[19]").Puts(true),
+		That("!=s (rand:uuid) (rand:uuid)").Puts(true),
+	)
+}
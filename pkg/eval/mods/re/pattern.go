@@ -0,0 +1,83 @@
+package re
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/xiaq/persistent/hash"
+)
+
+// A precompiled pattern, created by re:compile. Patterns with the same source
+// and options are equal, regardless of when they were compiled.
+type pattern struct {
+	source  string
+	posix   bool
+	longest bool
+	re      *regexp.Regexp
+}
+
+func newPattern(source string, posix, longest bool) (pattern, error) {
+	re, err := compile(source, posix)
+	if err != nil {
+		return pattern{}, err
+	}
+	if longest {
+		re.Longest()
+	}
+	return pattern{source, posix, longest, re}, nil
+}
+
+func (pattern) Kind() string { return "re:pattern" }
+
+// String returns the source of the pattern.
+func (p pattern) String() string { return p.source }
+
+func (p pattern) Equal(rhs interface{}) bool {
+	q, ok := rhs.(pattern)
+	return ok && p.source == q.source && p.posix == q.posix && p.longest == q.longest
+}
+
+func (p pattern) Hash() uint32 {
+	return hash.DJB(hash.String(p.source), boolHash(p.posix), boolHash(p.longest))
+}
+
+func boolHash(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Repr returns code that evaluates to an equal pattern.
+func (p pattern) Repr(int) string {
+	s := "(re:compile "
+	if p.posix {
+		s += "&posix "
+	}
+	if p.longest {
+		s += "&longest "
+	}
+	return s + parse.Quote(p.source) + ")"
+}
+
+// Converts a pattern argument, which is either a string or a pattern, to a
+// compiled regular expression. The posix and longest options are added to
+// those of a pattern.
+func makePattern(arg interface{}, posix, longest bool) (*regexp.Regexp, error) {
+	switch arg := arg.(type) {
+	case string:
+		p, err := newPattern(arg, posix, longest)
+		return p.re, err
+	case pattern:
+		if (posix && !arg.posix) || (longest && !arg.longest) {
+			p, err := newPattern(arg.source, posix || arg.posix, longest || arg.longest)
+			return p.re, err
+		}
+		return arg.re, nil
+	default:
+		return nil, fmt.Errorf(
+			"pattern must be string or re:pattern, got %s", vals.Kind(arg))
+	}
+}
@@ -15,17 +15,29 @@ var Ns = eval.NsBuilder{}.AddGoFns("re:", fns).Ns()
 
 var fns = map[string]interface{}{
 	"quote":   regexp.QuoteMeta,
+	"compile": compileFn,
 	"match":   match,
 	"find":    find,
 	"replace": replace,
 	"split":   split,
 }
 
+type compileOpts struct {
+	Posix   bool
+	Longest bool
+}
+
+func (*compileOpts) SetDefaultOptions() {}
+
+func compileFn(opts compileOpts, source string) (pattern, error) {
+	return newPattern(source, opts.Posix, opts.Longest)
+}
+
 type matchOpts struct{ Posix bool }
 
 func (*matchOpts) SetDefaultOptions() {}
 
-func match(opts matchOpts, argPattern, source string) (bool, error) {
+func match(opts matchOpts, argPattern interface{}, source string) (bool, error) {
 	pattern, err := makePattern(argPattern, opts.Posix, false)
 	if err != nil {
 		return false, err
@@ -42,7 +54,7 @@ type findOpts struct {
 
 func (o *findOpts) SetDefaultOptions() { o.Max = -1 }
 
-func find(fm *eval.Frame, opts findOpts, argPattern, source string) error {
+func find(fm *eval.Frame, opts findOpts, argPattern interface{}, source string) error {
 	out := fm.OutputChan()
 
 	pattern, err := makePattern(argPattern, opts.Posix, opts.Longest)
@@ -77,7 +89,7 @@ type replaceOpts struct {
 
 func (*replaceOpts) SetDefaultOptions() {}
 
-func replace(fm *eval.Frame, opts replaceOpts, argPattern string, argRepl interface{}, source string) (string, error) {
+func replace(fm *eval.Frame, opts replaceOpts, argPattern interface{}, argRepl interface{}, source string) (string, error) {
 
 	pattern, err := makePattern(argPattern, opts.Posix, opts.Longest)
 	if err != nil {
@@ -130,7 +142,7 @@ func replace(fm *eval.Frame, opts replaceOpts, argPattern string, argRepl interf
 	}
 }
 
-func split(fm *eval.Frame, opts findOpts, argPattern, source string) error {
+func split(fm *eval.Frame, opts findOpts, argPattern interface{}, source string) error {
 	out := fm.OutputChan()
 
 	pattern, err := makePattern(argPattern, opts.Posix, opts.Longest)
@@ -145,17 +157,6 @@ func split(fm *eval.Frame, opts findOpts, argPattern, source string) error {
 	return nil
 }
 
-func makePattern(p string, posix, longest bool) (*regexp.Regexp, error) {
-	pattern, err := compile(p, posix)
-	if err != nil {
-		return nil, err
-	}
-	if longest {
-		pattern.Longest()
-	}
-	return pattern, nil
-}
-
 func compile(pattern string, posix bool) (*regexp.Regexp, error) {
 	if posix {
 		return regexp.CompilePOSIX(pattern)
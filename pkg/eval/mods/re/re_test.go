@@ -66,5 +66,29 @@ func TestRe(t *testing.T) {
 
 		That("re:quote a.txt").Puts(`a\.txt`),
 		That("re:quote '(*)'").Puts(`\(\*\)`),
+
+		That("p = (re:compile '[a-z]+'); re:match $p '+ab+'").Puts(true),
+		That("p = (re:compile '[a-z]+'); put (re:find $p 'ab cd')[text]").Puts("ab", "cd"),
+		That("p = (re:compile :); re:split $p a:b").Puts("a", "b"),
+		That("p = (re:compile '(ba|z)sh'); re:replace $p '${1}SH' 'bash and zsh'").
+			Puts("baSH and zSH"),
+		// Options of compiled patterns
+		That("p = (re:compile &longest 'a(x|xy)'); put (re:find $p axy)[text]").Puts("axy"),
+		That("p = (re:compile 'a(x|xy)'); put (re:find &longest $p axy)[text]").Puts("axy"),
+		That("p = (re:compile 'a(x|xy)'); re:find &longest $p axy | nop (all); put (re:find $p axy)[text]").
+			Puts("ax"),
+		// Invalid pattern in re:compile
+		That("re:compile '('").Throws(AnyError),
+		// Pattern is neither string nor compiled pattern
+		That("re:match [] x").Throws(AnyError),
+
+		// Compiled patterns as values
+		That("kind-of (re:compile .)").Puts("re:pattern"),
+		That("to-string (re:compile 'a.b')").Puts("a.b"),
+		That("repr (re:compile &posix &longest 'a b')").Prints("(re:compile &posix &longest 'a b')\n"),
+		That("eq (re:compile 'a.b') (re:compile 'a.b')").Puts(true),
+		That("eq (re:compile 'a.b') (re:compile &longest 'a.b')").Puts(false),
+		That("eq (re:compile 'a.b') 'a.b'").Puts(false),
+		That("put [&(re:compile 'a.b')=x][(re:compile 'a.b')]").Puts("x"),
 	)
 }
@@ -0,0 +1,99 @@
+// Package runtime exposes functions for inspecting the Elvish runtime as the
+// runtime: module.
+package runtime
+
+import (
+	"os"
+
+	"github.com/elves/elvish/pkg/eval"
+)
+
+// Ns makes the namespace for the runtime: module, with variables that control
+// the given Evaler.
+func Ns(ev *eval.Evaler) *eval.Ns {
+	traceExec, traceExecPrefix := ev.TraceExecVars()
+	return eval.NsBuilder{
+		"trace-exec":        traceExec,
+		"trace-exec-prefix": traceExecPrefix,
+	}.AddGoFns("runtime:", map[string]interface{}{
+		"profile": profile,
+	}).Ns()
+}
+
+//elvdoc:var trace-exec
+//
+// Whether to print every command to stderr before calling it, which is useful
+// for debugging scripts, like `set -x` in POSIX shells. Defaults to `$false`.
+//
+// Each command is printed after its arguments and options are evaluated,
+// followed by where it is in the source code. Special commands, like `if` and
+// `var`, are not printed, but the commands they run are.
+//
+// ```elvish-transcript
+// ~> runtime:trace-exec = $true
+// ~> x = foo; echo $x (put bar)
+// + put bar ([tty 2]:1)
+// + echo foo bar ([tty 2]:1)
+// foo bar
+// ```
+//
+// @cf runtime:trace-exec-prefix
+
+//elvdoc:var trace-exec-prefix
+//
+// The string printed before each command when
+// [`$runtime:trace-exec`](#runtimetrace-exec) is `$true`. Defaults to `'+ '`.
+
+//elvdoc:fn profile
+//
+// ```elvish
+// runtime:profile $path $fn
+// ```
+//
+// Calls `$fn` with no arguments, and writes the time spent in each command it
+// calls, directly or indirectly, to the file at `$path`. Exceptions thrown by
+// `$fn` are propagated after the file is written.
+//
+// The file is in the "folded stacks" format, which can be turned into a flame
+// graph by [FlameGraph](https://github.com/brendangregg/FlameGraph) or opened
+// directly in [speedscope](https://www.speedscope.app). Each line contains the
+// call stack of a command, outermost first and separated by semicolons,
+// followed by the time spent in the command itself, excluding the commands it
+// calls, in microseconds. Each frame is the name of a command and where it was
+// called.
+//
+// ```elvish-transcript
+// ~> fn f { sleep 0.1; echo done }
+// ~> runtime:profile prof.txt { f }
+// done
+// ~> cat prof.txt
+// f ([tty 2]:1) 14
+// f ([tty 2]:1);echo ([tty 1]:1) 21
+// f ([tty 2]:1);sleep ([tty 1]:1) 100172
+// ```
+//
+// Since commands in a pipeline run in parallel, the times of all the commands
+// in a pipeline can add up to more than the time of the pipeline itself.
+//
+// To trace the whole Elvish process instead, use the `-trace` flag of the
+// `elvish` command, which writes an event for every command call and every
+// external command to a file, one JSON object per line.
+
+func profile(fm *eval.Frame, path string, f eval.Callable) error {
+	if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	p := eval.NewProfiler(fm)
+	remove := fm.Evaler.AddTracer(p)
+	errCall := f.Call(fm, nil, eval.NoOpts)
+	remove()
+	if err := p.WriteFolded(out); err != nil {
+		return err
+	}
+	return errCall
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestProfile(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("runtime", Ns(ev)).Ns()
+	}
+
+	TestWithSetup(t, setup,
+		That("fn f { put x }",
+			"runtime:profile prof { f; put [y] | each [v]{ nop } }").Puts("x"),
+		// Exceptions are propagated after the profile is written.
+		That("runtime:profile prof2 { fail bad }").Throws(eval.FailError{Content: "bad"}),
+		That("runtime:profile /a/bad/path { }").Throws(AnyError),
+	)
+
+	wantProfile := "" +
+		"each ([test]:2)\n" +
+		"each ([test]:2);nop ([test]:2)\n" +
+		"f ([test]:2)\n" +
+		"f ([test]:2);put ([test]:1)\n" +
+		"put ([test]:2)\n"
+	if got := stripCounts(t, "prof"); got != wantProfile {
+		t.Errorf("got profile:\n%s\nwant:\n%s", got, wantProfile)
+	}
+	if got, want := stripCounts(t, "prof2"), "fail ([test]:1)\n"; got != want {
+		t.Errorf("got profile:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProfile_Restricted(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("runtime", Ns(ev)).Ns()
+		ev.Restrictions.NoFileWrites = true
+	}
+
+	TestWithSetup(t, setup,
+		That("runtime:profile prof { }").Throws(
+			eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}),
+	)
+}
+
+func stripCounts(t *testing.T, name string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return regexp.MustCompile(`(?m) \d+$`).ReplaceAllString(string(content), "")
+}
+
+func TestTraceExec(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("runtime", Ns(ev)).Ns()
+	}
+
+	TestWithSetup(t, setup,
+		That("put $runtime:trace-exec $runtime:trace-exec-prefix").
+			Puts(false, "+ "),
+		That("runtime:trace-exec = $true; echo (put foo) 'a b' &sep=,").
+			Prints("foo,a b\n").
+			PrintsStderrWith("+ put foo ([test]:1)\n+ echo foo 'a b' &sep=',' ([test]:1)\n"),
+		That("runtime:trace-exec = $true; runtime:trace-exec-prefix = '>> '; [@a]{ nop $@a } x").
+			PrintsStderrWith(">> [@a]{ nop $@a } x ([test]:1)\n>> nop x ([test]:1)\n"),
+		That("runtime:trace-exec = foo").Throws(AnyError),
+	)
+}
@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Format of encrypted secrets: a version byte, followed by the salt used for
+// deriving the key from the passphrase, the nonce and the ciphertext sealed
+// with AES-256-GCM. The name of the secret is used as additional data, so that
+// the value of one secret can't be passed off as another.
+const (
+	formatVersion = 1
+	saltSize      = 16
+	keySize       = 32
+	headerSize    = 1 + saltSize
+)
+
+// Number of PBKDF2 iterations used for deriving keys.
+const iterations = 100000
+
+var errBadSecret = errors.New(
+	"cannot decrypt secret; the passphrase may be wrong or the secret corrupted")
+
+func newSalt(rand io.Reader) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	_, err := io.ReadFull(rand, salt)
+	return salt, err
+}
+
+func encrypt(rand io.Reader, key, salt []byte, name, plaintext string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, headerSize+len(nonce)+len(plaintext)+aead.Overhead())
+	data = append(data, formatVersion)
+	data = append(data, salt...)
+	data = append(data, nonce...)
+	return aead.Seal(data, nonce, []byte(plaintext), []byte(name)), nil
+}
+
+// Returns the salt of an encrypted secret.
+func saltOf(data []byte) ([]byte, error) {
+	if len(data) < headerSize || data[0] != formatVersion {
+		return nil, errBadSecret
+	}
+	return data[1:headerSize], nil
+}
+
+func decrypt(key []byte, name string, data []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < headerSize+aead.NonceSize() {
+		return "", errBadSecret
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	ciphertext := data[headerSize+aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", errBadSecret
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Derives a key from a passphrase with PBKDF2, using HMAC-SHA256 as the
+// pseudorandom function.
+func deriveKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, keySize, sha256.New)
+}
@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// Service and account names of the keychain item that keeps the passphrase.
+const (
+	keychainService = "elvish"
+	keychainAccount = "secret-passphrase"
+	keychainLabel   = "Elvish secret passphrase"
+)
+
+var (
+	errNoKeychain       = errors.New("no supported keychain; need security or secret-tool")
+	errNotInKeychain    = errors.New("passphrase not found in keychain")
+	errKeychainNotSaved = errors.New("cannot save passphrase in keychain")
+	errBadPassphrase    = errors.New("passphrase must not contain control characters")
+)
+
+// A keychain keeps the passphrase in an OS-provided credential store.
+type keychain interface {
+	get() (string, error)
+	set(passphrase string) error
+	del() error
+}
+
+// A keychain backed by the security command on macOS, or the secret-tool
+// command of libsecret on other Unix systems, whichever is found first.
+type commandKeychain struct{}
+
+func (commandKeychain) get() (string, error) {
+	if path, err := exec.LookPath("security"); err == nil {
+		out, err := exec.Command(path, "find-generic-password",
+			"-s", keychainService, "-a", keychainAccount, "-w").Output()
+		if err != nil {
+			return "", errNotInKeychain
+		}
+		// The password is output with a trailing newline.
+		return strings.TrimSuffix(string(out), "\n"), nil
+	}
+	if path, err := exec.LookPath("secret-tool"); err == nil {
+		out, err := exec.Command(path, "lookup",
+			"service", keychainService, "account", keychainAccount).Output()
+		if err != nil || len(out) == 0 {
+			return "", errNotInKeychain
+		}
+		return string(out), nil
+	}
+	return "", errNoKeychain
+}
+
+func (commandKeychain) set(passphrase string) error {
+	// The interactive mode of security reads one command per line, so a
+	// newline in the passphrase would end the command, and the rest would be
+	// run as another command. Other control characters are rejected too, so
+	// that they are handled the same way by both commands.
+	if strings.IndexFunc(passphrase, unicode.IsControl) != -1 {
+		return errBadPassphrase
+	}
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("security"); err == nil {
+		// Pass the command in interactive mode, so that the passphrase does not
+		// appear in the arguments of any process.
+		cmd = exec.Command(path, "-i")
+		cmd.Stdin = strings.NewReader(
+			"add-generic-password -U -s " + keychainService +
+				" -a " + keychainAccount + " -l " + quoteSecurity(keychainLabel) +
+				" -w " + quoteSecurity(passphrase) + "\n")
+	} else if path, err := exec.LookPath("secret-tool"); err == nil {
+		cmd = exec.Command(path, "store", "--label="+keychainLabel,
+			"service", keychainService, "account", keychainAccount)
+		cmd.Stdin = strings.NewReader(passphrase)
+	} else {
+		return errNoKeychain
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil || stderr.Len() > 0 {
+		// security -i exits with 0 even if the command fails, but reports the
+		// error on stderr.
+		return errKeychainNotSaved
+	}
+	return nil
+}
+
+func (commandKeychain) del() error {
+	if path, err := exec.LookPath("security"); err == nil {
+		err := exec.Command(path, "delete-generic-password",
+			"-s", keychainService, "-a", keychainAccount).Run()
+		if err != nil {
+			return errNotInKeychain
+		}
+		return nil
+	}
+	if path, err := exec.LookPath("secret-tool"); err == nil {
+		// secret-tool clear exits with 0 even if there is no matching item, so
+		// check that it exists first.
+		out, err := exec.Command(path, "lookup",
+			"service", keychainService, "account", keychainAccount).Output()
+		if err != nil || len(out) == 0 {
+			return errNotInKeychain
+		}
+		err = exec.Command(path, "clear",
+			"service", keychainService, "account", keychainAccount).Run()
+		if err != nil {
+			return errNotInKeychain
+		}
+		return nil
+	}
+	return errNoKeychain
+}
+
+// Quotes a string for the interactive mode of security, which splits commands
+// into words like a POSIX shell. The string must not contain newlines.
+func quoteSecurity(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,253 @@
+// Package secret implements the store:secret: module, which keeps small secrets
+// like API tokens encrypted in the persistent store.
+package secret
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/store"
+)
+
+var errLocked = errors.New(
+	"secrets are locked; use store:secret:unlock, set $E:" +
+		env.ELVISH_SECRET_PASSPHRASE + " or save the passphrase in the keychain")
+
+// Ns makes the store:secret: namespace. Secrets are encrypted and decrypted in
+// the Elvish process; the store only ever sees encrypted values.
+func Ns(s store.Store) *eval.Ns {
+	return ns(&keyring{store: s, rand: rand.Reader, keychain: commandKeychain{}})
+}
+
+func ns(k *keyring) *eval.Ns {
+	return eval.NsBuilder{}.AddGoFns("store:secret:", map[string]interface{}{
+		"unlock": k.unlock,
+		"lock":   k.lock,
+
+		"keychain-save": k.keychainSave,
+		"keychain-del":  k.keychainDel,
+
+		"get": k.get,
+		"set": k.set,
+		"del": k.del,
+	}).Ns()
+}
+
+type keyring struct {
+	store    store.Store
+	rand     io.Reader
+	keychain keychain
+
+	mutex      sync.Mutex
+	passphrase *string
+	// Keys derived from keysPassphrase, indexed by salt. Deriving keys is
+	// deliberately slow, so they are cached until the passphrase changes.
+	keys           map[string][]byte
+	keysPassphrase string
+	// Salt used for encrypting secrets in this session.
+	salt []byte
+}
+
+//elvdoc:fn unlock
+//
+// ```elvish
+// store:secret:unlock $passphrase
+// ```
+//
+// Sets the passphrase used for encrypting and decrypting secrets in this
+// session. If secrets are accessed without calling this command first, the
+// passphrase is taken from `$E:ELVISH_SECRET_PASSPHRASE`, or the OS keychain if
+// that is not set either.
+//
+// Since the passphrase is not stored anywhere, it is not checked until a
+// secret is read.
+
+func (k *keyring) unlock(passphrase string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.passphrase = &passphrase
+	k.keys, k.keysPassphrase = nil, ""
+	k.salt = nil
+}
+
+//elvdoc:fn lock
+//
+// ```elvish
+// store:secret:lock
+// ```
+//
+// Forgets the passphrase set with `store:secret:unlock` or read from the OS
+// keychain, and all the keys derived from it.
+
+func (k *keyring) lock() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.passphrase = nil
+	k.keys, k.keysPassphrase = nil, ""
+	k.salt = nil
+}
+
+//elvdoc:fn keychain-save
+//
+// ```elvish
+// store:secret:keychain-save $passphrase
+// ```
+//
+// Saves the passphrase in the OS keychain, so that it is found without calling
+// `store:secret:unlock` in later sessions. The keychain is accessed with the
+// `security` command on macOS, and the `secret-tool` command of libsecret on
+// other Unix systems. The passphrase can't contain control characters like
+// newlines.
+//
+// This also unlocks secrets in this session.
+
+func (k *keyring) keychainSave(fm *eval.Frame, passphrase string) error {
+	if err := fm.CheckRestriction(eval.RestrictExternalCommands); err != nil {
+		return err
+	}
+	err := k.keychain.set(passphrase)
+	if err != nil {
+		return err
+	}
+	k.unlock(passphrase)
+	return nil
+}
+
+//elvdoc:fn keychain-del
+//
+// ```elvish
+// store:secret:keychain-del
+// ```
+//
+// Deletes the passphrase from the OS keychain. This does not lock secrets in
+// this session.
+
+func (k *keyring) keychainDel(fm *eval.Frame) error {
+	if err := fm.CheckRestriction(eval.RestrictExternalCommands); err != nil {
+		return err
+	}
+	return k.keychain.del()
+}
+
+//elvdoc:fn get
+//
+// ```elvish
+// store:secret:get $name
+// ```
+//
+// Outputs the value of the secret with the given name. Throws an exception if
+// there is no such secret or it can't be decrypted with the passphrase.
+
+func (k *keyring) get(fm *eval.Frame, name string) (string, error) {
+	data, err := k.store.Secret(name)
+	if err != nil {
+		return "", err
+	}
+	salt, err := saltOf(data)
+	if err != nil {
+		return "", err
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	key, err := k.key(fm, salt)
+	if err != nil {
+		return "", err
+	}
+	return decrypt(key, name, data)
+}
+
+//elvdoc:fn set
+//
+// ```elvish
+// store:secret:set $name $value
+// ```
+//
+// Encrypts the value with the passphrase and saves it as the secret with the
+// given name, replacing any existing secret with the same name.
+
+func (k *keyring) set(fm *eval.Frame, name, value string) error {
+	if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+		return err
+	}
+	k.mutex.Lock()
+	if k.salt == nil {
+		salt, err := newSalt(k.rand)
+		if err != nil {
+			k.mutex.Unlock()
+			return err
+		}
+		k.salt = salt
+	}
+	salt := k.salt
+	key, err := k.key(fm, salt)
+	k.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	data, err := encrypt(k.rand, key, salt, name, value)
+	if err != nil {
+		return err
+	}
+	return k.store.SetSecret(name, data)
+}
+
+//elvdoc:fn del
+//
+// ```elvish
+// store:secret:del $name
+// ```
+//
+// Deletes the secret with the given name. This does not need the passphrase.
+
+func (k *keyring) del(fm *eval.Frame, name string) error {
+	if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+		return err
+	}
+	return k.store.DelSecret(name)
+}
+
+// Returns the key for the given salt. Must be called with the mutex held.
+func (k *keyring) key(fm *eval.Frame, salt []byte) ([]byte, error) {
+	passphrase, err := k.currentPassphrase(fm)
+	if err != nil {
+		return nil, err
+	}
+	if k.keys == nil || passphrase != k.keysPassphrase {
+		// The passphrase may change without calling unlock or lock, when
+		// $E:ELVISH_SECRET_PASSPHRASE is changed.
+		k.keys = make(map[string][]byte)
+		k.keysPassphrase = passphrase
+	}
+	if key, ok := k.keys[string(salt)]; ok {
+		return key, nil
+	}
+	key := deriveKey(passphrase, salt)
+	k.keys[string(salt)] = key
+	return key, nil
+}
+
+// Returns the passphrase set with unlock, or found in the environment or the
+// keychain. Must be called with the mutex held.
+func (k *keyring) currentPassphrase(fm *eval.Frame) (string, error) {
+	if k.passphrase != nil {
+		return *k.passphrase, nil
+	}
+	if p, ok := os.LookupEnv(env.ELVISH_SECRET_PASSPHRASE); ok && p != "" {
+		return p, nil
+	}
+	if err := fm.CheckRestriction(eval.RestrictExternalCommands); err != nil {
+		// Reading the keychain runs an external command.
+		return "", err
+	}
+	p, err := k.keychain.get()
+	if err != nil {
+		return "", errLocked
+	}
+	k.passphrase = &p
+	return p, nil
+}
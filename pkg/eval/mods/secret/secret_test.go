@@ -0,0 +1,185 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/elves/elvish/pkg/env"
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestSecret(t *testing.T) {
+	restore := testutil.WithTempEnv(env.ELVISH_SECRET_PASSPHRASE, "")
+	defer restore()
+	s, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	setup := func(ev *eval.Evaler) {
+		k := &keyring{store: s, rand: rand.Reader, keychain: &fakeKeychain{}}
+		ev.Global = storeNs(k)
+	}
+
+	TestWithSetup(t, setup,
+		// Secrets can't be accessed before unlocking.
+		That("store:secret:set token hunter2").Throws(errLocked),
+		// Round trip.
+		That("store:secret:unlock pass; store:secret:set token hunter2",
+			"store:secret:get token").
+			Puts("hunter2"),
+		// Wrong passphrase.
+		That("store:secret:unlock pass; store:secret:set token hunter2",
+			"store:secret:unlock wrong; store:secret:get token").Throws(errBadSecret),
+		// Locking forgets the passphrase.
+		That("store:secret:unlock pass; store:secret:set token hunter2",
+			"store:secret:lock; store:secret:get token").Throws(errLocked),
+		// Deleting.
+		That("store:secret:unlock pass; store:secret:set token hunter2",
+			"store:secret:del token; store:secret:get token").Throws(AnyError),
+	)
+}
+
+func TestSecret_PassphraseFromEnv(t *testing.T) {
+	restore := testutil.WithTempEnv(env.ELVISH_SECRET_PASSPHRASE, "pass")
+	defer restore()
+	s, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	setup := func(ev *eval.Evaler) {
+		k := &keyring{store: s, rand: rand.Reader, keychain: &fakeKeychain{}}
+		ev.Global = storeNs(k)
+	}
+
+	TestWithSetup(t, setup,
+		That("store:secret:set token hunter2; store:secret:get token").Puts("hunter2"),
+		That("store:secret:unlock pass; store:secret:get token").Puts("hunter2"),
+		// Keys derived from an old passphrase are not used.
+		That("store:secret:set token hunter2",
+			"set-env "+env.ELVISH_SECRET_PASSPHRASE+" wrong; store:secret:get token").
+			Throws(errBadSecret),
+	)
+}
+
+func TestSecret_Keychain(t *testing.T) {
+	restore := testutil.WithTempEnv(env.ELVISH_SECRET_PASSPHRASE, "")
+	defer restore()
+	s, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	kc := &fakeKeychain{}
+	setup := func(ev *eval.Evaler) {
+		ev.Global = storeNs(&keyring{store: s, rand: rand.Reader, keychain: kc})
+	}
+
+	TestWithSetup(t, setup,
+		That("store:secret:keychain-save pass; store:secret:set token hunter2").
+			DoesNothing(),
+		// The passphrase is found in the keychain in a new session.
+		That("store:secret:get token").Puts("hunter2"),
+		// A passphrase set with unlock takes precedence.
+		That("store:secret:unlock wrong; store:secret:get token").
+			Throws(errBadSecret),
+		That("store:secret:keychain-del; store:secret:get token").
+			Throws(errLocked),
+		That("store:secret:keychain-del").Throws(errNotInKeychain),
+	)
+}
+
+func TestSecret_Restricted(t *testing.T) {
+	restore := testutil.WithTempEnv(env.ELVISH_SECRET_PASSPHRASE, "")
+	defer restore()
+	s, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	kc := &fakeKeychain{}
+	setup := func(ev *eval.Evaler) {
+		ev.Global = storeNs(&keyring{store: s, rand: rand.Reader, keychain: kc})
+		ev.Restrictions = eval.Restrictions{
+			NoExternalCommands: true, NoFileWrites: true}
+	}
+	kc.set("pass")
+	salt, _ := newSalt(rand.Reader)
+	data, _ := encrypt(rand.Reader, deriveKey("pass", salt), salt, "token", "hunter2")
+	s.SetSecret("token", data)
+	external := eval.RestrictionViolation{
+		Restriction: eval.RestrictExternalCommands}
+	fileWrites := eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}
+
+	TestWithSetup(t, setup,
+		That("store:secret:keychain-save pass").Throws(external),
+		That("store:secret:keychain-del").Throws(external),
+		// Reading the passphrase from the keychain runs an external command.
+		That("store:secret:get token").Throws(external),
+		That("store:secret:unlock pass; store:secret:set token hunter2").
+			Throws(fileWrites),
+		That("store:secret:del token").Throws(fileWrites),
+	)
+	if kc.passphrase == nil {
+		t.Errorf("passphrase deleted from keychain")
+	}
+}
+
+func TestCommandKeychain_RejectsControlCharacters(t *testing.T) {
+	for _, passphrase := range []string{"a\nb", "a\rb", "a\x00b"} {
+		if err := (commandKeychain{}).set(passphrase); err != errBadPassphrase {
+			t.Errorf("set(%q) returned %v, want errBadPassphrase", passphrase, err)
+		}
+	}
+}
+
+func TestSecret_StoreOnlySeesCiphertext(t *testing.T) {
+	s, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	k := &keyring{store: s, rand: bytes.NewReader(bytes.Repeat([]byte{1}, 100))}
+	ev := eval.NewEvaler()
+	ev.Global = storeNs(k)
+	err := ev.Eval(parse.Source{Name: "[test]",
+		Code: "store:secret:unlock pass; store:secret:set token hunter2"},
+		eval.EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := s.Secret("token")
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Errorf("stored secret contains plain text: %q", data)
+	}
+}
+
+func TestDecrypt_NameIsAuthenticated(t *testing.T) {
+	key := deriveKey("pass", make([]byte, saltSize))
+	data, err := encrypt(bytes.NewReader(make([]byte, 100)),
+		key, make([]byte, saltSize), "a", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decrypt(key, "b", data); err != errBadSecret {
+		t.Errorf("decrypting with another name returned %v", err)
+	}
+}
+
+func storeNs(k *keyring) *eval.Ns {
+	return eval.NsBuilder{}.AddNs("store",
+		eval.NsBuilder{}.AddNs("secret", ns(k)).Ns()).Ns()
+}
+
+type fakeKeychain struct{ passphrase *string }
+
+func (kc *fakeKeychain) get() (string, error) {
+	if kc.passphrase == nil {
+		return "", errNotInKeychain
+	}
+	return *kc.passphrase, nil
+}
+
+func (kc *fakeKeychain) set(passphrase string) error {
+	kc.passphrase = &passphrase
+	return nil
+}
+
+func (kc *fakeKeychain) del() error {
+	if kc.passphrase == nil {
+		return errNotInKeychain
+	}
+	kc.passphrase = nil
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package signal exposes functions for handling signals as the signal: module.
+package signal
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Ns is the namespace for the signal: module.
+var Ns = eval.NsBuilder{}.AddGoFns("signal:", map[string]interface{}{
+	"trap":   trap,
+	"untrap": untrap,
+	"names":  names,
+}).Ns()
+
+//elvdoc:fn trap
+//
+// ```elvish
+// signal:trap $signal $callback
+// ```
+//
+// Registers `$callback` to be called with no arguments every time Elvish
+// receives `$signal`, replacing any callback registered for the same signal
+// before. The signal can be given by its name with or without the `SIG`
+// prefix, like `SIGTERM` or `TERM`, or by its number.
+//
+// The callback replaces the default handling of the signal by Elvish. For
+// example, trapping `SIGHUP` prevents Elvish from exiting when receiving it,
+// and trapping `SIGINT` prevents it from interrupting the code being
+// evaluated:
+//
+// ```elvish
+// signal:trap INT { echo 'Interrupted, cleaning up' ; rm -f $tmpfile }
+// ```
+//
+// The callback is called in the background, concurrently with the code being
+// evaluated, including when Elvish is waiting for an external command to
+// finish. Note that the terminal sends `SIGINT` to external commands in the
+// foreground too, so they may still exit when Ctrl-C is pressed.
+//
+// A callback for `SIGINT` is only called while code is being evaluated. While
+// the interactive editor is reading a command, Ctrl-C still just clears the
+// command line; the editor's own reactions to other signals, like redrawing
+// when the terminal is resized, are not affected by traps either.
+//
+// The output of the callback goes to the standard output and error of the
+// Elvish process, and exceptions it throws are printed.
+//
+// @cf signal:untrap
+
+func trap(fm *eval.Frame, name string, callback eval.Callable) error {
+	sig, err := parseSignal(name)
+	if err != nil {
+		return err
+	}
+	ev := fm.Evaler
+	eval.TrapSignal(sig, func() {
+		ports, cleanup := eval.PortsFromFiles(
+			[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, ev)
+		defer cleanup()
+		err := ev.Call(callback,
+			eval.CallCfg{From: "[signal:trap]"}, eval.EvalCfg{Ports: ports[:]})
+		if err != nil {
+			diag.ShowError(os.Stderr, err)
+		}
+	})
+	return nil
+}
+
+//elvdoc:fn untrap
+//
+// ```elvish
+// signal:untrap $signal
+// ```
+//
+// Removes the callback registered for `$signal` with `signal:trap`, restoring
+// the default handling of the signal. Does nothing if there is no such
+// callback.
+//
+// @cf signal:trap
+
+func untrap(name string) error {
+	sig, err := parseSignal(name)
+	if err != nil {
+		return err
+	}
+	eval.TrapSignal(sig, nil)
+	return nil
+}
+
+//elvdoc:fn names
+//
+// ```elvish
+// signal:names
+// ```
+//
+// Outputs the names of all the signals that can be trapped on the current
+// platform, with the `SIG` prefix.
+//
+// ```elvish-transcript
+// ~> signal:names | take 3
+// ▶ SIGABRT
+// ▶ SIGALRM
+// ▶ SIGCHLD
+// ```
+
+func names(fm *eval.Frame) {
+	out := fm.OutputChan()
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out <- name
+	}
+}
+
+func parseSignal(name string) (os.Signal, error) {
+	if sig, ok := signals[name]; ok {
+		return sig, nil
+	}
+	if sig, ok := signals["SIG"+name]; ok {
+		return sig, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		for _, sig := range signals {
+			if int(sig) == n {
+				return sig, nil
+			}
+		}
+	}
+	return nil, errs.BadValue{What: "signal",
+		Valid: "signal name or number", Actual: parse.Quote(name)}
+}
@@ -0,0 +1,34 @@
+// +build !windows,!plan9,!js
+
+package signal
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestTrap(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("signal", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`x = 0; signal:trap USR2 { x = 1 }`,
+			`kill -USR2 $pid; while (eq $x 0) { sleep 0.01 }`,
+			`signal:untrap USR2; put $x`).Puts("1"),
+		// Trapping SIGINT prevents the evaluation from being interrupted.
+		That(`x = 0; signal:trap SIGINT { x = 1 }`,
+			`kill -INT $pid; while (eq $x 0) { sleep 0.01 }`,
+			`signal:untrap INT; put $x`).Puts("1"),
+		// The trap can be replaced, and signals can be given by number.
+		That(`x = 0; signal:trap HUP { x = 1 }; signal:trap 1 { x = 2 }`,
+			`kill -HUP $pid; while (eq $x 0) { sleep 0.01 }`,
+			`signal:untrap SIGHUP; put $x`).Puts("2"),
+		That(`signal:untrap USR2`).DoesNothing(),
+		That(`signal:trap BAD { }`).Throws(errs.BadValue{What: "signal",
+			Valid: "signal name or number", Actual: "BAD"}),
+		That(`signal:names | take 2`).Puts("SIGABRT", "SIGALRM"),
+	)
+}
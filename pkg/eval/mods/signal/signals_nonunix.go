@@ -0,0 +1,9 @@
+// +build windows plan9 js
+
+package signal
+
+import "syscall"
+
+var signals = map[string]syscall.Signal{
+	"SIGINT": syscall.SIGINT,
+}
@@ -1,13 +1,93 @@
 package store
 
 import (
+	"time"
+
 	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/mods/secret"
+	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/store"
 )
 
+// Options of store:set-retention-policy. A nil field means that the option is
+// not given, and the current value is kept.
+type retentionOpts struct {
+	MaxCmds     interface{}
+	MaxCmdAge   interface{}
+	MinDirScore interface{}
+}
+
+func (*retentionOpts) SetDefaultOptions() {}
+
 func Ns(s store.Store) *eval.Ns {
-	return eval.NsBuilder{}.AddGoFns("store:", map[string]interface{}{
-		"del-dir": s.DelDir,
-		"del-cmd": s.DelCmd,
+	return eval.NsBuilder{}.AddNs("secret", secret.Ns(s)).AddGoFns("store:", map[string]interface{}{
+		"del-dir": func(fm *eval.Frame, dir string) error {
+			if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+				return err
+			}
+			return s.DelDir(dir)
+		},
+		"del-cmd": func(fm *eval.Frame, seq int) error {
+			if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+				return err
+			}
+			return s.DelCmd(seq)
+		},
+
+		"compact": func(fm *eval.Frame) error {
+			if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+				return err
+			}
+			return s.Compact()
+		},
+		"retention-policy": func() (vals.Map, error) {
+			p, err := s.RetentionPolicy()
+			if err != nil {
+				return nil, err
+			}
+			return vals.MakeMap(
+				"max-cmds", vals.FromGo(p.MaxCmds),
+				"max-cmd-age", p.MaxCmdAge.Seconds(),
+				"min-dir-score", p.MinDirScore), nil
+		},
+		"set-retention-policy": func(fm *eval.Frame, opts retentionOpts) error {
+			if err := fm.CheckRestriction(eval.RestrictFileWrites); err != nil {
+				return err
+			}
+			p, err := s.RetentionPolicy()
+			if err != nil {
+				return err
+			}
+			err = mergeRetentionOpts(&p, opts)
+			if err != nil {
+				return err
+			}
+			return s.SetRetentionPolicy(p)
+		},
 	}).Ns()
 }
+
+// Updates the fields of p for which an option is given.
+func mergeRetentionOpts(p *store.RetentionPolicy, opts retentionOpts) error {
+	if opts.MaxCmds != nil {
+		err := vals.ScanToGo(opts.MaxCmds, &p.MaxCmds)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.MaxCmdAge != nil {
+		var age float64
+		err := vals.ScanToGo(opts.MaxCmdAge, &age)
+		if err != nil {
+			return err
+		}
+		p.MaxCmdAge = time.Duration(age * float64(time.Second))
+	}
+	if opts.MinDirScore != nil {
+		err := vals.ScanToGo(opts.MinDirScore, &p.MinDirScore)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
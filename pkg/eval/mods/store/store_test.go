@@ -0,0 +1,58 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestSetRetentionPolicy(t *testing.T) {
+	dir, cleanup := testutil.TestDir()
+	defer cleanup()
+	s := store.NewFileStore(filepath.Join(dir, "db"))
+	defer s.Close()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("store", Ns(s)).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`store:set-retention-policy &max-cmds=10 &min-dir-score=2`,
+			`store:retention-policy`).
+			Puts(vals.MakeMap("max-cmds", "10", "max-cmd-age", 0.0, "min-dir-score", 2.0)),
+		// Options that are not given keep their current values.
+		That(`store:set-retention-policy &max-cmd-age=60`,
+			`store:retention-policy`).
+			Puts(vals.MakeMap("max-cmds", "10", "max-cmd-age", 60.0, "min-dir-score", 2.0)),
+		That(`store:set-retention-policy &max-cmds=0`,
+			`store:retention-policy`).
+			Puts(vals.MakeMap("max-cmds", "0", "max-cmd-age", 60.0, "min-dir-score", 2.0)),
+		That(`store:set-retention-policy &max-cmds=foo`).Throws(AnyError),
+	)
+}
+
+func TestStore_Restricted(t *testing.T) {
+	dir, cleanup := testutil.TestDir()
+	defer cleanup()
+	s := store.NewFileStore(filepath.Join(dir, "db"))
+	defer s.Close()
+
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("store", Ns(s)).Ns()
+		ev.Restrictions = eval.Restrictions{NoFileWrites: true}
+	}
+	fileWrites := eval.RestrictionViolation{Restriction: eval.RestrictFileWrites}
+	TestWithSetup(t, setup,
+		That(`store:del-dir /tmp`).Throws(fileWrites),
+		That(`store:del-cmd 1`).Throws(fileWrites),
+		That(`store:compact`).Throws(fileWrites),
+		That(`store:set-retention-policy &max-cmds=10`).Throws(fileWrites),
+		// Reading is still allowed.
+		That(`store:retention-policy`).
+			Puts(vals.MakeMap("max-cmds", "0", "max-cmd-age", 0.0, "min-dir-score", 0.0)),
+	)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/eval/errs"
 	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/elves/elvish/pkg/wcwidth"
 )
 
 //elvdoc:fn compare
@@ -96,6 +98,32 @@ import (
 // ▶ $false
 // ```
 
+//elvdoc:fn fields
+//
+// ```elvish
+// str:fields $str
+// ```
+//
+// Outputs the fields of `$str`, which are separated by one or more Unicode
+// whitespace characters. Leading and trailing whitespace is ignored. Examples:
+//
+// ```elvish-transcript
+// ~> str:fields "  lorem ipsum\tdolor  "
+// ▶ lorem
+// ▶ ipsum
+// ▶ dolor
+// ~> str:fields "  "
+// ```
+//
+// @cf str:split
+
+func fields(fm *eval.Frame, s string) {
+	out := fm.OutputChan()
+	for _, field := range strings.Fields(s) {
+		out <- field
+	}
+}
+
 //elvdoc:fn from-codepoints
 //
 // ```elvish
@@ -305,6 +333,54 @@ func join(sep string, inputs eval.Inputs) (string, error) {
 // ▶ -1
 // ```
 
+//elvdoc:fn pad
+//
+// ```elvish
+// str:pad &left=$false &char=' ' $str $width
+// ```
+//
+// Pads `$str` with `$char` on the right, or on the left if `$left` is true,
+// until it is at least `$width` columns wide on the terminal. Wide characters
+// like CJK characters take two columns, and combining characters take none.
+// `$char` must be a single character one column wide. Examples:
+//
+// ```elvish-transcript
+// ~> str:pad abc 5
+// ▶ 'abc  '
+// ~> str:pad &left &char=0 42 5
+// ▶ 00042
+// ~> str:pad 你好 5
+// ▶ '你好 '
+// ~> str:pad abcdef 3
+// ▶ abcdef
+// ```
+
+type padOpts struct {
+	Left bool
+	Char string
+}
+
+func (o *padOpts) SetDefaultOptions() { o.Char = " " }
+
+func pad(opts padOpts, s string, width int) (string, error) {
+	if r, size := utf8.DecodeRuneInString(opts.Char); size == 0 || size != len(opts.Char) || wcwidth.OfRune(r) != 1 {
+		return "", errs.BadValue{
+			What:   "&char option of str:pad",
+			Valid:  "single character one column wide",
+			Actual: parse.Quote(opts.Char),
+		}
+	}
+	n := width - wcwidth.Of(s)
+	if n <= 0 {
+		return s, nil
+	}
+	padding := strings.Repeat(opts.Char, n)
+	if opts.Left {
+		return padding + s, nil
+	}
+	return s + padding, nil
+}
+
 //elvdoc:fn replace
 //
 // ```elvish
@@ -351,7 +427,7 @@ func replace(opts maxOpt, old, repl, s string) string {
 // Etymology: Various languages, in particular
 // [Python](https://docs.python.org/3.6/library/stdtypes.html#str.split).
 //
-// @cf str:join
+// @cf str:fields str:join
 
 func split(fm *eval.Frame, opts maxOpt, sep, s string) {
 	out := fm.OutputChan()
@@ -572,7 +648,8 @@ var fns = map[string]interface{}{
 	"contains-any": strings.ContainsAny,
 	"count":        strings.Count,
 	"equal-fold":   strings.EqualFold,
-	// TODO: Fields, FieldsFunc
+	"fields":       fields,
+	// TODO: FieldsFunc
 	"from-codepoints": fromCodepoints,
 	"from-utf8-bytes": fromUtf8Bytes,
 	"has-prefix":      strings.HasPrefix,
@@ -583,6 +660,7 @@ var fns = map[string]interface{}{
 	"join":       join,
 	"last-index": strings.LastIndex,
 	// TODO: LastIndexFunc, Map, Repeat
+	"pad":     pad,
 	"replace": replace,
 	"split":   split,
 	// TODO: SplitAfter
@@ -35,6 +35,10 @@ func TestStr(t *testing.T) {
 		That(`str:equal-fold abc ABC`).Puts(true),
 		That(`str:equal-fold abc A`).Puts(false),
 
+		That(`str:fields "  lorem ipsum\tdolor\n "`).Puts("lorem", "ipsum", "dolor"),
+		That(`str:fields "a\u3000b"`).Puts("a", "b"),
+		That(`str:fields "  "`).DoesNothing(),
+
 		That(`str:from-codepoints 0x61`).Puts("a"),
 		That(`str:from-codepoints 0x4f60 0x597d`).Puts("你好"),
 		That(`str:from-codepoints -0x1`).Throws(errs.OutOfRange{
@@ -92,6 +96,18 @@ func TestStr(t *testing.T) {
 		That(`str:last-index "elven speak elvish" "elv"`).Puts("12"),
 		That(`str:last-index "elven speak elvish" "romulan"`).Puts("-1"),
 
+		That(`str:pad abc 5`).Puts("abc  "),
+		That(`str:pad &left abc 5`).Puts("  abc"),
+		That(`str:pad &left &char=0 42 5`).Puts("00042"),
+		That(`str:pad 你好 5`).Puts("你好 "),
+		That(`str:pad abcdef 3`).Puts("abcdef"),
+		That(`str:pad &char=ab x 3`).Throws(errs.BadValue{
+			What:   "&char option of str:pad",
+			Valid:  "single character one column wide",
+			Actual: "ab"}),
+		That(`str:pad &char=你 x 3`).Throws(AnyError),
+		That(`str:pad &char='' x 3`).Throws(AnyError),
+
 		That(`str:replace : / ":usr:bin:tmp"`).Puts("/usr/bin/tmp"),
 		That(`str:replace &max=2 : / :usr:bin:tmp`).Puts("/usr/bin:tmp"),
 
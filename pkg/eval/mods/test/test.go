@@ -0,0 +1,327 @@
+// Package test exposes functions for writing tests of Elvish code as the test:
+// module.
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+// Ns makes the namespace for the test: module, which reports test results to
+// the given Reporter.
+func Ns(r *Reporter) *eval.Ns {
+	return eval.NsBuilder{
+		"format": vars.FromSetGet(r.setFormat, r.getFormat),
+	}.AddGoFns("test:", map[string]interface{}{
+		"describe": r.describe,
+		"it":       r.it,
+
+		"assert":        assert,
+		"assert-eq":     assertEq,
+		"assert-throws": assertThrows,
+	}).Ns()
+}
+
+// Reporter keeps track of the results of tests, and writes them in the TAP or
+// the JSON Lines format. It is safe for concurrent use.
+type Reporter struct {
+	mu     sync.Mutex
+	format string
+	// Names given to describe, outermost first.
+	names     []string
+	n, failed int
+}
+
+// NewReporter creates a new Reporter. The format is either "tap" or "json".
+func NewReporter(format string) *Reporter {
+	return &Reporter{format: format}
+}
+
+//elvdoc:var format
+//
+// The format in which test results are written, either `tap` for the [Test
+// Anything Protocol](https://testanything.org) or `json` for [JSON
+// Lines](https://jsonlines.org). Defaults to `tap`. When running tests with
+// `elvish -test`, this is initialized to `json` if the `-json` flag is also
+// given.
+
+func (r *Reporter) getFormat() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.format
+}
+
+func (r *Reporter) setFormat(v interface{}) error {
+	format, ok := v.(string)
+	if !ok || (format != "tap" && format != "json") {
+		return errs.BadValue{What: "$test:format",
+			Valid: "tap or json", Actual: vals.Repr(v, vals.NoPretty)}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.format = format
+	return nil
+}
+
+// Describe calls f, adding name to the names of the tests reported while f is
+// running.
+func (r *Reporter) Describe(name string, f func() error) error {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.names = r.names[:len(r.names)-1]
+		r.mu.Unlock()
+	}()
+	return f()
+}
+
+// Report writes the result of a test to w. The test has failed if err is not
+// nil, in which case the error message and the output of the test are also
+// written.
+func (r *Reporter) Report(w io.Writer, name string, err error, output []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n++
+	if err != nil {
+		r.failed++
+	}
+	fullName := strings.Join(append(r.names[:len(r.names):len(r.names)], name), " ")
+	fullName = strings.TrimSpace(fullName)
+
+	if r.format == "json" {
+		result := testResult{Name: fullName, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			result.Output = output
+		}
+		return writeJSON(w, result)
+	}
+	var sb strings.Builder
+	if err == nil {
+		fmt.Fprintf(&sb, "ok %d - %s\n", r.n, fullName)
+	} else {
+		fmt.Fprintf(&sb, "not ok %d - %s\n", r.n, fullName)
+		writeDiagnostics(&sb, "", strings.Split(err.Error(), "\n"))
+		if len(output) > 0 {
+			sb.WriteString("# output:\n")
+			writeDiagnostics(&sb, "  ", output)
+		}
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// Plan writes the number of tests reported so far to w. In the TAP format, this
+// is the plan line, like "1..3"; in the JSON format, it is an object with the
+// total number of tests and the number of failed tests.
+func (r *Reporter) Plan(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.format == "json" {
+		return writeJSON(w, testPlan{Total: r.n, Failed: r.failed})
+	}
+	_, err := fmt.Fprintf(w, "1..%d\n", r.n)
+	return err
+}
+
+// Failed returns the number of failed tests reported so far.
+func (r *Reporter) Failed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failed
+}
+
+type testResult struct {
+	Name   string   `json:"name"`
+	OK     bool     `json:"ok"`
+	Error  string   `json:"error,omitempty"`
+	Output []string `json:"output,omitempty"`
+}
+
+type testPlan struct {
+	Total  int `json:"total"`
+	Failed int `json:"failed"`
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func writeDiagnostics(sb *strings.Builder, indent string, lines []string) {
+	for _, line := range lines {
+		sb.WriteString("# " + indent + line + "\n")
+	}
+}
+
+//elvdoc:fn describe
+//
+// ```elvish
+// test:describe $name $fn
+// ```
+//
+// Calls `$fn` with no arguments. The names of the tests run by `$fn` with
+// [`test:it`](#testit) are prefixed with `$name`. Calls to `test:describe` can
+// be nested.
+//
+// ```elvish-transcript
+// ~> test:describe str {
+//      test:it 'joins strings' { test:assert-eq (str:join , [a b]) a,b }
+//    }
+// ok 1 - str joins strings
+// ```
+
+func (r *Reporter) describe(fm *eval.Frame, name string, f eval.Callable) error {
+	return r.Describe(name, func() error {
+		return f.Call(fm, eval.NoArgs, eval.NoOpts)
+	})
+}
+
+//elvdoc:fn it
+//
+// ```elvish
+// test:it $name $fn
+// ```
+//
+// Calls `$fn` with no arguments as a test named `$name`, and writes the result
+// to the byte output in the format given by [`$test:format`](#testformat).
+// The test fails if `$fn` throws an exception. The output of `$fn` is
+// captured, and only written along with the exception when the test fails.
+//
+// Exceptions thrown by `$fn` don't propagate, so the tests following a failed
+// test still run.
+//
+// ```elvish-transcript
+// ~> test:it 'converts to upper case' { test:assert-eq (str:to-upper abc) ABC }
+// ok 1 - converts to upper case
+// ~> test:it 'converts to lower case' { echo debugging; test:assert-eq (str:to-lower ABC) Abc }
+// not ok 2 - converts to lower case
+// # expected Abc, got abc
+// # output:
+// #   debugging
+// ~> test:format = json
+// ~> test:it 'converts to upper case' { test:assert-eq (str:to-upper abc) ABC }
+// {"name":"converts to upper case","ok":true}
+// ```
+//
+// @cf test:describe
+
+func (r *Reporter) it(fm *eval.Frame, name string, f eval.Callable) error {
+	output, err := fm.CaptureOutput(func(fm *eval.Frame) error {
+		return f.Call(fm, eval.NoArgs, eval.NoOpts)
+	})
+	if eval.Reason(err) == eval.ErrInterrupted {
+		return err
+	}
+	lines := make([]string, len(output))
+	for i, v := range output {
+		lines[i] = vals.ToString(v)
+	}
+	return r.Report(fm.OutputFile(), name, err, lines)
+}
+
+type assertOpts struct{ Msg string }
+
+func (opts *assertOpts) SetDefaultOptions() { opts.Msg = "assertion failed" }
+
+//elvdoc:fn assert
+//
+// ```elvish
+// test:assert &msg='assertion failed' $value
+// ```
+//
+// Throws an exception with the message `&msg` if `$value` is
+// [booleanly](builtin.html#bool) false.
+//
+// ```elvish-transcript
+// ~> test:assert (has-key [&a=1] a)
+// ~> test:assert &msg='no key b' (has-key [&a=1] b)
+// Exception: no key b
+// [tty 2], line 1: test:assert &msg='no key b' (has-key [&a=1] b)
+// ```
+//
+// @cf test:assert-eq
+
+func assert(opts assertOpts, v interface{}) error {
+	if !vals.Bool(v) {
+		return errors.New(opts.Msg)
+	}
+	return nil
+}
+
+//elvdoc:fn assert-eq
+//
+// ```elvish
+// test:assert-eq $actual $expected
+// ```
+//
+// Throws an exception if `$actual` is not [equal](builtin.html#eq) to
+// `$expected`. The message of the exception shows both values.
+//
+// ```elvish-transcript
+// ~> test:assert-eq [(str:split , a,b)] [a b]
+// ~> test:assert-eq (str:to-lower ABC) Abc
+// Exception: expected Abc, got abc
+// [tty 2], line 1: test:assert-eq (str:to-lower ABC) Abc
+// ```
+//
+// @cf test:assert
+
+func assertEq(actual, expected interface{}) error {
+	if !vals.Equal(actual, expected) {
+		return fmt.Errorf("expected %s, got %s",
+			vals.Repr(expected, vals.NoPretty), vals.Repr(actual, vals.NoPretty))
+	}
+	return nil
+}
+
+var errNoException = errors.New("expected an exception, got none")
+
+//elvdoc:fn assert-throws
+//
+// ```elvish
+// test:assert-throws $fn
+// ```
+//
+// Calls `$fn` with no arguments, and throws an exception if it doesn't throw
+// one. Otherwise, outputs the exception thrown by `$fn`, which can be
+// examined further. The outputs of `$fn` are passed through.
+//
+// ```elvish-transcript
+// ~> test:assert-throws { fail bad }
+// ▶ [&reason=[&content=bad &type=fail]]
+// ~> (test:assert-throws { fail bad })[reason][content]
+// ▶ bad
+// ~> test:assert-throws { nop }
+// Exception: expected an exception, got none
+// [tty 2], line 1: test:assert-throws { nop }
+// ```
+
+func assertThrows(fm *eval.Frame, f eval.Callable) (*eval.Exception, error) {
+	err := f.Call(fm, eval.NoArgs, eval.NoOpts)
+	switch {
+	case err == nil:
+		return nil, errNoException
+	case eval.Reason(err) == eval.ErrInterrupted:
+		return nil, err
+	}
+	if exc, ok := err.(*eval.Exception); ok {
+		return exc, nil
+	}
+	return &eval.Exception{Reason: err}, nil
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestTest(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("test", Ns(NewReporter("tap"))).Ns()
+	}
+	TestWithSetup(t, setup,
+		That("test:it a { }; test:it b { fail bad }").
+			Prints("ok 1 - a\nnot ok 2 - b\n# bad\n"),
+		That("test:it a { echo foo; put bar; fail bad }").
+			Prints("not ok 1 - a\n# bad\n# output:\n#   bar\n#   foo\n"),
+		That("test:describe a { test:describe b { test:it c { } }; test:it d { } }").
+			Prints("ok 1 - a b c\nok 2 - a d\n"),
+		That("test:format = json; test:it a { }; test:it b { put x; fail bad }").
+			Prints(`{"name":"a","ok":true}`+"\n"+
+				`{"name":"b","ok":false,"error":"bad","output":["x"]}`+"\n"),
+		That("put $test:format").Puts("tap"),
+		That("test:format = xml").Throws(AnyError),
+
+		That("test:assert $true").DoesNothing(),
+		That("test:assert $false").Throws(ErrorWithMessage("assertion failed")),
+		That("test:assert &msg=bad $nil").Throws(ErrorWithMessage("bad")),
+
+		That("test:assert-eq [a b] [a b]").DoesNothing(),
+		That("test:assert-eq a b").Throws(ErrorWithMessage("expected b, got a")),
+		That("test:assert-eq [a] [&a=b]").
+			Throws(ErrorWithMessage("expected [&a=b], got [a]")),
+
+		That("put (test:assert-throws { fail bad })[reason][content]").Puts("bad"),
+		That("test:assert-throws { put foo; fail bad } | take 1").Puts("foo"),
+		That("test:assert-throws { nop }").Throws(errNoException),
+	)
+}
+
+func TestReporter(t *testing.T) {
+	r := NewReporter("tap")
+	var buf bytes.Buffer
+	r.Describe("f.elv", func() error {
+		r.Report(&buf, "", errors.New("line 1\nline 2"), nil)
+		return nil
+	})
+	r.Report(&buf, "a", nil, nil)
+	r.Plan(&buf)
+
+	want := "not ok 1 - f.elv\n# line 1\n# line 2\nok 2 - a\n1..2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if failed := r.Failed(); failed != 1 {
+		t.Errorf("got %v failed, want 1", failed)
+	}
+
+	buf.Reset()
+	NewReporter("json").Plan(&buf)
+	if got, want := buf.String(), `{"total":0,"failed":0}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
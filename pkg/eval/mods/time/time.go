@@ -0,0 +1,444 @@
+// Package time exposes functions for working with times and durations as the
+// time: module.
+package time
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Ns is the namespace for the time: module.
+var Ns = eval.NsBuilder{}.AddGoFns("time:", map[string]interface{}{
+	"now":     now,
+	"since":   since,
+	"measure": measure,
+
+	"duration": toDuration,
+	"seconds":  seconds,
+
+	"parse":   parse,
+	"format":  format,
+	"unix":    unix,
+	"to-unix": toUnix,
+	"in":      in,
+
+	"add":     add,
+	"sub":     sub,
+	"compare": compare,
+}).Ns()
+
+// A duration argument, in any form accepted by vals.ScanDuration.
+type duration time.Duration
+
+func (d *duration) ScanElvish(v interface{}) error {
+	d2, err := vals.ScanDuration(v)
+	*d = duration(d2)
+	return err
+}
+
+//elvdoc:fn now
+//
+// ```elvish
+// time:now
+// ```
+//
+// Outputs the current time. The time also carries a reading of a monotonic
+// clock, so the durations computed from it with [`time:since`](#timesince)
+// and [`time:sub`](#timesub) are not affected by changes to the system clock.
+//
+// ```elvish-transcript
+// ~> time:now
+// ▶ (time:parse 2021-01-02T03:04:05.678901+08:00)
+// ```
+
+func now() vals.Time { return vals.Time{Time: time.Now()} }
+
+//elvdoc:fn since
+//
+// ```elvish
+// time:since $time
+// ```
+//
+// Outputs the duration elapsed since `$time`.
+//
+// ```elvish-transcript
+// ~> t = (time:now)
+// ~> sleep 1; time:since $t
+// ▶ (time:duration 1.001234567s)
+// ```
+//
+// @cf time:measure
+
+func since(t vals.Time) vals.Duration {
+	return vals.Duration{Duration: time.Since(t.Time)}
+}
+
+//elvdoc:fn measure
+//
+// ```elvish
+// time:measure $callable
+// ```
+//
+// Calls `$callable`, and outputs how long it took using a monotonic clock. The
+// outputs of `$callable` are passed through before the duration. If
+// `$callable` throws an exception, it is propagated and no duration is
+// output.
+//
+// ```elvish-transcript
+// ~> time:measure { sleep 0.1 }
+// ▶ (time:duration 100.801234ms)
+// ~> time:seconds (time:measure { sleep 0.1 })
+// ▶ (float64 0.100812345)
+// ```
+//
+// This is like the builtin [`time`](builtin.html#time) command, except that
+// the duration is output as a value.
+
+func measure(fm *eval.Frame, f eval.Callable) (vals.Duration, error) {
+	t0 := time.Now()
+	err := f.Call(fm, eval.NoArgs, eval.NoOpts)
+	if err != nil {
+		return vals.Duration{}, err
+	}
+	return vals.Duration{Duration: time.Since(t0)}, nil
+}
+
+//elvdoc:fn duration
+//
+// ```elvish
+// time:duration $duration
+// ```
+//
+// Converts `$duration` to a duration value. It can be a number of seconds, or
+// a string of decimal numbers, each with a unit suffix, like `300ms` or
+// `1h30m`. This is the same format accepted by [`sleep`](builtin.html#sleep).
+//
+// ```elvish-transcript
+// ~> time:duration 1.5
+// ▶ (time:duration 1.5s)
+// ~> time:duration 90m
+// ▶ (time:duration 1h30m0s)
+// ```
+
+func toDuration(d duration) vals.Duration {
+	return vals.Duration{Duration: time.Duration(d)}
+}
+
+//elvdoc:fn seconds
+//
+// ```elvish
+// time:seconds $duration
+// ```
+//
+// Outputs `$duration` as a number of seconds.
+//
+// ```elvish-transcript
+// ~> time:seconds (time:duration 1m30s)
+// ▶ (float64 90)
+// ```
+
+func seconds(d duration) float64 { return time.Duration(d).Seconds() }
+
+// Layouts that can be referred to by name in the &layout option of time:parse
+// and time:format.
+var layouts = map[string]string{
+	"ansic":        time.ANSIC,
+	"unix-date":    time.UnixDate,
+	"ruby-date":    time.RubyDate,
+	"rfc822":       time.RFC822,
+	"rfc822z":      time.RFC822Z,
+	"rfc850":       time.RFC850,
+	"rfc1123":      time.RFC1123,
+	"rfc1123z":     time.RFC1123Z,
+	"rfc3339":      time.RFC3339,
+	"rfc3339-nano": time.RFC3339Nano,
+	"kitchen":      time.Kitchen,
+}
+
+func layout(s string) string {
+	if l, ok := layouts[s]; ok {
+		return l
+	}
+	return s
+}
+
+// Loads a time zone. The name "local" refers to the local time zone; other
+// names are passed to time.LoadLocation.
+func location(name string) (*time.Location, error) {
+	if name == "local" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+type parseOpts struct {
+	Layout string
+	TZ     string `name:"tz"`
+}
+
+func (opts *parseOpts) SetDefaultOptions() {
+	opts.Layout = "rfc3339"
+	opts.TZ = "local"
+}
+
+//elvdoc:fn parse
+//
+// ```elvish
+// time:parse &layout=rfc3339 &tz=local $string
+// ```
+//
+// Parses `$string` as a time.
+//
+// The `&layout` option is either a layout string in the format of Go's
+// [time package](https://golang.org/pkg/time/#pkg-constants), which uses the
+// reference time `Mon Jan 2 15:04:05 MST 2006` to show the format, or one of
+// the names `ansic`, `unix-date`, `ruby-date`, `rfc822`, `rfc822z`, `rfc850`,
+// `rfc1123`, `rfc1123z`, `rfc3339`, `rfc3339-nano` and `kitchen`. Fractional
+// seconds are accepted after the seconds even if the layout doesn't have
+// them.
+//
+// The `&tz` option specifies the time zone for strings without time zone
+// information. It can be `local`, `UTC` or a name in the IANA Time Zone
+// database like `Asia/Shanghai`.
+//
+// ```elvish-transcript
+// ~> time:parse 2021-01-02T03:04:05Z
+// ▶ (time:parse 2021-01-02T03:04:05Z)
+// ~> time:parse &layout='2006-01-02 15:04' &tz=Asia/Shanghai '2021-01-02 03:04'
+// ▶ (time:parse 2021-01-02T03:04:00+08:00)
+// ```
+//
+// @cf time:format
+
+func parse(opts parseOpts, s string) (vals.Time, error) {
+	loc, err := location(opts.TZ)
+	if err != nil {
+		return vals.Time{}, err
+	}
+	t, err := time.ParseInLocation(layout(opts.Layout), s, loc)
+	return vals.Time{Time: t}, err
+}
+
+type formatOpts struct{ Layout string }
+
+func (opts *formatOpts) SetDefaultOptions() { opts.Layout = "rfc3339" }
+
+//elvdoc:fn format
+//
+// ```elvish
+// time:format &layout=rfc3339 $time
+// ```
+//
+// Formats `$time` as a string. The `&layout` option is the same as in
+// [`time:parse`](#timeparse).
+//
+// ```elvish-transcript
+// ~> t = (time:parse 2021-01-02T03:04:05Z)
+// ~> time:format $t
+// ▶ 2021-01-02T03:04:05Z
+// ~> time:format &layout='Jan 2, 2006' $t
+// ▶ 'Jan 2, 2021'
+// ```
+//
+// @cf time:parse
+
+func format(opts formatOpts, t vals.Time) string {
+	return t.Format(layout(opts.Layout))
+}
+
+//elvdoc:fn unix
+//
+// ```elvish
+// time:unix $seconds
+// ```
+//
+// Outputs the time that is `$seconds` seconds after the Unix epoch, January 1,
+// 1970 UTC, in the local time zone.
+//
+// ```elvish-transcript
+// ~> time:in UTC (time:unix 1609556645)
+// ▶ (time:parse 2021-01-02T03:04:05Z)
+// ```
+//
+// @cf time:to-unix
+
+func unix(secs float64) vals.Time {
+	whole := math.Floor(secs)
+	return vals.Time{Time: time.Unix(int64(whole), int64((secs-whole)*1e9))}
+}
+
+//elvdoc:fn to-unix
+//
+// ```elvish
+// time:to-unix $time
+// ```
+//
+// Outputs the number of seconds elapsed between the Unix epoch and `$time`.
+//
+// ```elvish-transcript
+// ~> time:to-unix (time:parse 2021-01-02T03:04:05.5Z)
+// ▶ (float64 1609556645.5)
+// ```
+//
+// @cf time:unix
+
+func toUnix(t vals.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+//elvdoc:fn in
+//
+// ```elvish
+// time:in $tz $time
+// ```
+//
+// Outputs the same instant as `$time` in the time zone `$tz`, which can be
+// `local`, `UTC` or a name in the IANA Time Zone database.
+//
+// ```elvish-transcript
+// ~> time:in Asia/Shanghai (time:parse 2021-01-02T03:04:05Z)
+// ▶ (time:parse 2021-01-02T11:04:05+08:00)
+// ```
+
+func in(tz string, t vals.Time) (vals.Time, error) {
+	loc, err := location(tz)
+	if err != nil {
+		return vals.Time{}, err
+	}
+	return vals.Time{Time: t.In(loc)}, nil
+}
+
+var (
+	errSubDurationTime     = errors.New("cannot subtract a time from a duration")
+	errCompareTimeDuration = errors.New("cannot compare a time with a duration")
+)
+
+//elvdoc:fn add
+//
+// ```elvish
+// time:add $time-or-duration $duration...
+// ```
+//
+// Adds durations to a time or a duration. Durations can be given in any form
+// accepted by [`time:duration`](#timeduration).
+//
+// ```elvish-transcript
+// ~> time:add (time:parse 2021-01-02T03:04:05Z) 1h 30m
+// ▶ (time:parse 2021-01-02T04:34:05Z)
+// ~> time:add 1h -1s
+// ▶ (time:duration 59m59s)
+// ```
+
+func add(a interface{}, ds ...duration) (interface{}, error) {
+	var sum time.Duration
+	for _, d := range ds {
+		sum += time.Duration(d)
+	}
+	if t, ok := a.(vals.Time); ok {
+		return vals.Time{Time: t.Add(sum)}, nil
+	}
+	d, err := vals.ScanDuration(a)
+	if err != nil {
+		return nil, err
+	}
+	return vals.Duration{Duration: d + sum}, nil
+}
+
+//elvdoc:fn sub
+//
+// ```elvish
+// time:sub $a $b
+// ```
+//
+// Subtracts `$b` from `$a`. When both are times, outputs the duration between
+// them; when `$a` is a time and `$b` is a duration, outputs a time; when both
+// are durations, outputs a duration.
+//
+// ```elvish-transcript
+// ~> time:sub (time:parse 2021-01-02T00:00:00Z) (time:parse 2021-01-01T00:00:00Z)
+// ▶ (time:duration 24h0m0s)
+// ~> time:sub (time:parse 2021-01-02T00:00:00Z) 1h
+// ▶ (time:parse 2021-01-01T23:00:00Z)
+// ~> time:sub 1h 1s
+// ▶ (time:duration 59m59s)
+// ```
+
+func sub(a, b interface{}) (interface{}, error) {
+	if ta, ok := a.(vals.Time); ok {
+		if tb, ok := b.(vals.Time); ok {
+			return vals.Duration{Duration: ta.Sub(tb.Time)}, nil
+		}
+		d, err := vals.ScanDuration(b)
+		if err != nil {
+			return nil, err
+		}
+		return vals.Time{Time: ta.Add(-d)}, nil
+	}
+	if _, ok := b.(vals.Time); ok {
+		return nil, errSubDurationTime
+	}
+	da, err := vals.ScanDuration(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := vals.ScanDuration(b)
+	if err != nil {
+		return nil, err
+	}
+	return vals.Duration{Duration: da - db}, nil
+}
+
+//elvdoc:fn compare
+//
+// ```elvish
+// time:compare $a $b
+// ```
+//
+// Compares two times or two durations, and outputs -1 if `$a` is earlier or
+// shorter than `$b`, 0 if they are equal, and 1 otherwise. Times in different
+// time zones are compared by the instants they represent.
+//
+// ```elvish-transcript
+// ~> time:compare (time:parse 2021-01-02T03:04:05Z) (time:parse 2021-01-02T11:04:05+08:00)
+// ▶ 0
+// ~> time:compare 1m 30s
+// ▶ 1
+// ```
+//
+// @cf str:compare
+
+func compare(a, b interface{}) (int, error) {
+	ta, aIsTime := a.(vals.Time)
+	tb, bIsTime := b.(vals.Time)
+	switch {
+	case aIsTime && bIsTime:
+		switch {
+		case ta.Before(tb.Time):
+			return -1, nil
+		case ta.After(tb.Time):
+			return 1, nil
+		}
+		return 0, nil
+	case aIsTime || bIsTime:
+		return 0, errCompareTimeDuration
+	}
+	da, err := vals.ScanDuration(a)
+	if err != nil {
+		return 0, err
+	}
+	db, err := vals.ScanDuration(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case da < db:
+		return -1, nil
+	case da > db:
+		return 1, nil
+	}
+	return 0, nil
+}
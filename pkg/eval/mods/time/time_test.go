@@ -0,0 +1,89 @@
+package time
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+func TestTime(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("time", Ns).Ns()
+	}
+	utc := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	TestWithSetup(t, setup,
+		That("kind-of (time:now)").Puts("time"),
+		That("kind-of (time:since (time:now))").Puts("duration"),
+		That("< 0 (time:seconds (time:since (time:now)))").Puts(true),
+		That("time:measure { put foo } | take 1").Puts("foo"),
+		That("d = (time:measure { nop }); kind-of $d").Puts("duration"),
+		That("time:measure { fail bad }").Throws(eval.FailError{Content: "bad"}),
+
+		That("time:duration 1.5").Puts(vals.Duration{Duration: 1500 * time.Millisecond}),
+		That("time:duration 1h30m").Puts(vals.Duration{Duration: 90 * time.Minute}),
+		That("time:duration (time:duration 1s)").Puts(vals.Duration{Duration: time.Second}),
+		That("time:duration foo").Throws(AnyError),
+		That("time:seconds 1m30s").Puts(90.0),
+		That("repr (time:duration 90m)").Prints("(time:duration 1h30m0s)\n"),
+		That("to-string (time:duration 90m)").Puts("1h30m0s"),
+
+		That("time:parse 2021-01-02T03:04:05Z").Puts(vals.Time{Time: utc}),
+		That("time:parse 2021-01-02T11:04:05+08:00").Puts(vals.Time{Time: utc}),
+		That("time:parse &layout=rfc1123 'Sat, 02 Jan 2021 03:04:05 UTC'").
+			Puts(vals.Time{Time: utc}),
+		That("time:parse &layout='2006-01-02 15:04:05' &tz=Asia/Shanghai '2021-01-02 11:04:05'").
+			Puts(vals.Time{Time: utc}),
+		That("time:parse &tz=Nowhere/Land 2021-01-02T03:04:05Z").Throws(AnyError),
+		That("time:parse 2021-01-02").Throws(AnyError),
+		That("repr (time:in UTC (time:parse 2021-01-02T11:04:05+08:00))").
+			Prints("(time:parse 2021-01-02T03:04:05Z)\n"),
+		That("time:in Nowhere/Land (time:now)").Throws(AnyError),
+
+		That("time:format (time:parse 2021-01-02T11:04:05.5+08:00)").
+			Puts("2021-01-02T11:04:05+08:00"),
+		That("time:format &layout=rfc3339-nano (time:parse 2021-01-02T11:04:05.5+08:00)").
+			Puts("2021-01-02T11:04:05.5+08:00"),
+		That("time:format &layout='Jan 2, 2006' (time:parse 2021-01-02T03:04:05Z)").
+			Puts("Jan 2, 2021"),
+		That("time:format 2021-01-02T03:04:05Z").Throws(AnyError),
+
+		That("time:unix 1609556645").Puts(vals.Time{Time: utc}),
+		That("time:unix 1609556645.5").Puts(vals.Time{Time: utc.Add(time.Second / 2)}),
+		That("time:to-unix (time:parse 2021-01-02T03:04:05.5Z)").Puts(1609556645.5),
+
+		That("time:add (time:parse 2021-01-02T03:04:05Z) 1h 30m").
+			Puts(vals.Time{Time: utc.Add(90 * time.Minute)}),
+		That("time:add 1h -1s").Puts(vals.Duration{Duration: time.Hour - time.Second}),
+		That("time:add 1h").Puts(vals.Duration{Duration: time.Hour}),
+		That("time:add foo 1h").Throws(AnyError),
+		That("time:add 1h (time:now)").Throws(AnyError),
+
+		That("time:sub (time:parse 2021-01-02T03:04:05Z) (time:parse 2021-01-01T03:04:05Z)").
+			Puts(vals.Duration{Duration: 24 * time.Hour}),
+		That("time:sub (time:parse 2021-01-02T03:04:05Z) 1h").
+			Puts(vals.Time{Time: utc.Add(-time.Hour)}),
+		That("time:sub 1h 1s").Puts(vals.Duration{Duration: time.Hour - time.Second}),
+		That("time:sub 1h (time:now)").Throws(errSubDurationTime),
+		That("time:sub (time:now) foo").Throws(AnyError),
+
+		That("time:compare (time:parse 2021-01-02T03:04:05Z) (time:parse 2021-01-02T11:04:05+08:00)").
+			Puts("0"),
+		That("time:compare (time:parse 2021-01-01T00:00:00Z) (time:parse 2021-01-02T00:00:00Z)").
+			Puts("-1"),
+		That("time:compare (time:parse 2021-01-02T00:00:00Z) (time:parse 2021-01-01T00:00:00Z)").
+			Puts("1"),
+		That("time:compare 1m 30s").Puts("1"),
+		That("time:compare 30s 1m").Puts("-1"),
+		That("time:compare 60 1m").Puts("0"),
+		That("time:compare (time:now) 1s").Throws(errCompareTimeDuration),
+
+		// Times are equal when they are the same instant.
+		That("eq (time:parse 2021-01-02T03:04:05Z) (time:parse 2021-01-02T11:04:05+08:00)").
+			Puts(true),
+		// Durations can be used with sleep.
+		That("sleep (time:duration 0)").DoesNothing(),
+	)
+}
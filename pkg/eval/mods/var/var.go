@@ -0,0 +1,72 @@
+// Package varmod exposes functions for working with variables as the var:
+// module. The package is not named var, since that is a Go keyword.
+package varmod
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+// Ns is the namespace for the var: module.
+var Ns = eval.NsBuilder{}.AddGoFns("var:", map[string]interface{}{
+	"watch": watch,
+}).Ns()
+
+//elvdoc:fn watch
+//
+// ```elvish
+// var:watch $name $callback
+// ```
+//
+// Registers `$callback` to be called with the new value of the variable named
+// `$name` every time it is assigned, including when an element of it is
+// assigned. The name is resolved in the scope of the caller, and may be
+// qualified, like `edit:prompt`. Outputs a function that unregisters the
+// callback when called.
+//
+// The callback is called after the new value has been stored. Its output goes
+// to the standard output and error of the Elvish process, and exceptions it
+// throws are printed instead of being propagated to the assignment.
+//
+// Throws an exception if there is no variable named `$name`, or if the variable
+// can't be watched, like read-only variables.
+//
+// Environment variables can be watched, including list views like
+// `E:list:PATH` and `paths`. The callback is called when they are changed by
+// assignments, `del`, `set-env`, `unset-env` or `cd`.
+//
+// ```elvish-transcript
+// ~> x = foo
+// ~> unwatch = (var:watch x [v]{ echo 'x is now '$v })
+// ~> x = bar
+// x is now bar
+// ~> $unwatch
+// ~> x = lorem
+// ```
+
+func watch(fm *eval.Frame, name string, callback eval.Callable) (eval.Callable, error) {
+	variable := fm.ResolveVar(name)
+	if variable == nil {
+		return nil, fmt.Errorf("variable $%s not found", name)
+	}
+	ev := fm.Evaler
+	unwatch, err := vars.Watch(variable, func(v interface{}) {
+		ports, cleanup := eval.PortsFromFiles(
+			[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, ev)
+		defer cleanup()
+		err := ev.Call(callback,
+			eval.CallCfg{Args: []interface{}{v}, From: "[var:watch]"},
+			eval.EvalCfg{Ports: ports[:]})
+		if err != nil {
+			diag.ShowError(os.Stderr, err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eval.NewGoFn("var:unwatch", unwatch), nil
+}
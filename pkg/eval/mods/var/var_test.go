@@ -0,0 +1,41 @@
+package varmod
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+func TestWatch(t *testing.T) {
+	setup := func(ev *eval.Evaler) {
+		ev.Global = eval.NsBuilder{}.AddNs("var", Ns).Ns()
+	}
+	TestWithSetup(t, setup,
+		That(`x = a; log = []`,
+			`unwatch = (var:watch x [v]{ log = [$@log $v] })`,
+			`x = b; x = c; $unwatch; x = d; put $log`).
+			Puts(vals.MakeList("b", "c")),
+		// Assigning an element.
+		That(`x = [a]; log = []`,
+			`_ = (var:watch x [v]{ log = [$@log $v] })`,
+			`x[0] = b; put $log`).
+			Puts(vals.MakeList(vals.MakeList("b"))),
+		// Watching a qualified variable and a local variable of a closure.
+		That(`n: = (ns [&k=v]); log = []`,
+			`_ = (var:watch n:k [v]{ log = [$@log $v] })`,
+			`{ y = a; _ = (var:watch y [v]{ log = [$@log y=$v] }); y = b }`,
+			`n:k = w; put $log`).
+			Puts(vals.MakeList("y=b", "w")),
+
+		That("var:watch x { }").Throws(errors.New("variable $x not found")),
+		That("var:watch true { }").Throws(vars.ErrNotWatchable),
+		// Environment variables.
+		That(`log = []; _ = (var:watch E:VAR_TEST [v]{ log = [$@log $v] })`,
+			`E:VAR_TEST = a; set-env VAR_TEST b; del E:VAR_TEST; put $log`).
+			Puts(vals.MakeList("a", "b", "")),
+	)
+}
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"fmt"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/elves/elvish/pkg/eval/vars"
@@ -56,6 +57,13 @@ func (ns *Ns) Index(k interface{}) (interface{}, bool) {
 	return nil, false
 }
 
+// IndexString looks up a variable with the given name, and returns the
+// variable itself if it exists.
+func (ns *Ns) IndexString(k string) (vars.Var, bool) {
+	variable := ns.indexInner(k)
+	return variable, variable != nil
+}
+
 func (ns *Ns) indexInner(k string) vars.Var {
 	i := ns.lookup(k)
 	if i != -1 {
@@ -64,6 +72,27 @@ func (ns *Ns) indexInner(k string) vars.Var {
 	return nil
 }
 
+// Like indexInner, but uses the index stored in hint if it was stored for the
+// same namespace and still refers to the same name, and stores the index in
+// hint otherwise.
+func (ns *Ns) indexInnerWithHint(k string, hint *atomic.Value) vars.Var {
+	if h, ok := hint.Load().(nsHint); ok && h.ns == ns &&
+		h.index < len(ns.names) && ns.names[h.index] == k {
+		return ns.slots[h.index]
+	}
+	i := ns.lookup(k)
+	if i == -1 {
+		return nil
+	}
+	hint.Store(nsHint{ns, i})
+	return ns.slots[i]
+}
+
+type nsHint struct {
+	ns    *Ns
+	index int
+}
+
 func (ns *Ns) lookup(k string) int {
 	for i, name := range ns.names {
 		if name == k {
@@ -101,6 +130,28 @@ func (ns *Ns) Append(ns2 *Ns) {
 	ns.names = append(ns.names, ns2.names...)
 }
 
+// Replaces the variables in ns with those in ns2 in place. Names in both
+// namespaces keep their indices, and names only in ns are kept with nil slots,
+// so that indices into ns cached by compiled code stay valid.
+func (ns *Ns) replace(ns2 *Ns) {
+	slots := make([]vars.Var, len(ns.slots), len(ns.slots)+len(ns2.slots))
+	names := append([]string(nil), ns.names...)
+	replaced := make(map[string]bool, len(ns2.names))
+	for i, name := range ns2.names {
+		if replaced[name] {
+			continue
+		}
+		replaced[name] = true
+		if j := ns.lookup(name); j != -1 {
+			slots[j] = ns2.slots[i]
+		} else {
+			slots = append(slots, ns2.slots[i])
+			names = append(names, name)
+		}
+	}
+	ns.slots, ns.names = slots, names
+}
+
 func (ns *Ns) static() *staticNs {
 	return &staticNs{ns.names, make([]bool, len(ns.names))}
 }
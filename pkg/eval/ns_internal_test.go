@@ -0,0 +1,27 @@
+package eval
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+func TestIndexInnerWithHint_ChecksHint(t *testing.T) {
+	x, y, newY := vars.FromInit("x"), vars.FromInit("y"), vars.FromInit("new y")
+	ns := &Ns{[]vars.Var{x, y}, []string{"x", "y"}}
+	var hint atomic.Value
+	if v := ns.indexInnerWithHint("y", &hint); v != y {
+		t.Errorf("got %v, want %v", v, y)
+	}
+
+	// The hint is not used after the namespace has changed in place.
+	*ns = Ns{[]vars.Var{newY}, []string{"y"}}
+	if v := ns.indexInnerWithHint("y", &hint); v != newY {
+		t.Errorf("got %v, want %v", v, newY)
+	}
+	*ns = Ns{[]vars.Var{x, y}, []string{"x", "z"}}
+	if v := ns.indexInnerWithHint("y", &hint); v != nil {
+		t.Errorf("got %v, want nil", v)
+	}
+}
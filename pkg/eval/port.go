@@ -2,10 +2,12 @@ package eval
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/strutil"
@@ -17,11 +19,15 @@ type Port struct {
 	Chan      chan interface{}
 	CloseFile bool
 	CloseChan bool
+	// If not nil, called when the Port is closed, after closing the channel.
+	// It finishes the goroutine serving a values redirection, and returns its
+	// error.
+	redirDone func() error
 }
 
 // Fork returns a copy of a Port with the Close* flags unset.
 func (p *Port) Fork() *Port {
-	return &Port{p.File, p.Chan, false, false}
+	return &Port{File: p.File, Chan: p.Chan}
 }
 
 // Close closes a Port.
@@ -35,6 +41,9 @@ func (p *Port) Close() {
 	if p.CloseChan {
 		close(p.Chan)
 	}
+	if p.redirDone != nil {
+		p.redirDone()
+	}
 }
 
 var (
@@ -188,3 +197,108 @@ func CaptureStringPort() (*Port, func() []string, error) {
 		return lines
 	}, nil
 }
+
+// OutputCallbacks contains functions to call with the outputs of code. Used in
+// CallbackPorts and (*Evaler).CallWithOutputCallback. Nil callbacks discard
+// the corresponding outputs.
+//
+// The callbacks are called on separate goroutines, but never concurrently with
+// each other, and never after the cleanup function of the ports has returned.
+type OutputCallbacks struct {
+	// Called with each value written to stdout.
+	Value func(v interface{})
+	// Called with each line written to stdout, including the trailing newline.
+	// A last line without a trailing newline is also passed.
+	Bytes func(line string)
+	// Like Bytes, but for stderr. Values written to stderr are discarded.
+	StderrBytes func(line string)
+	// How long to wait for the remaining outputs after the ports are closed.
+	// Processes started in the background may keep the byte outputs open
+	// indefinitely; outputs written after the timeout are discarded. A
+	// non-positive value means waiting until all the outputs are closed.
+	DrainTimeout time.Duration
+}
+
+// CallbackPorts returns a stdout and a stderr *Port, whose outputs are passed
+// to the callbacks in cbs. It also returns a function to close the ports and
+// wait for the callbacks to finish.
+func CallbackPorts(cbs OutputCallbacks) (stdout, stderr *Port, done func(), err error) {
+	r1, w1, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r2, w2, err := os.Pipe()
+	if err != nil {
+		r1.Close()
+		w1.Close()
+		return nil, nil, nil, err
+	}
+	ch := make(chan interface{}, outputCaptureBufferSize)
+
+	var (
+		cbMutex sync.Mutex
+		stopped bool
+	)
+	call := func(f func()) {
+		cbMutex.Lock()
+		defer cbMutex.Unlock()
+		if !stopped {
+			f()
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for v := range ch {
+			if cbs.Value != nil {
+				call(func() { cbs.Value(v) })
+			}
+		}
+	}()
+	relayLines := func(r *os.File, cb func(string)) {
+		defer wg.Done()
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" && cb != nil {
+				call(func() { cb(line) })
+			}
+			if err != nil {
+				if err != io.EOF && !errors.Is(err, os.ErrClosed) {
+					logger.Println("error on reading:", err)
+				}
+				return
+			}
+		}
+	}
+	go relayLines(r1, cbs.Bytes)
+	go relayLines(r2, cbs.StderrBytes)
+
+	stdout = &Port{Chan: ch, CloseChan: true, File: w1, CloseFile: true}
+	stderr = &Port{Chan: BlackholeChan, File: w2, CloseFile: true}
+	done = func() {
+		stdout.Close()
+		stderr.Close()
+		relaysDone := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(relaysDone)
+		}()
+		if cbs.DrainTimeout > 0 {
+			select {
+			case <-relaysDone:
+			case <-time.After(cbs.DrainTimeout):
+			}
+		} else {
+			<-relaysDone
+		}
+		// This also stops the byte relays if they are still running.
+		r1.Close()
+		r2.Close()
+		cbMutex.Lock()
+		defer cbMutex.Unlock()
+		stopped = true
+	}
+	return stdout, stderr, done, nil
+}
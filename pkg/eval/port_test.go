@@ -0,0 +1,69 @@
+package eval_test
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+)
+
+func TestCallbackPorts(t *testing.T) {
+	var c outputCollector
+	stdout, stderr, done, err := CallbackPorts(c.callbacks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout.Chan <- "foo"
+	stdout.File.WriteString("bar\nbaz")
+	stderr.Chan <- "discarded"
+	stderr.File.WriteString("quux\n")
+	done()
+
+	c.check(t, []string{"bytes bar\n", "bytes baz", "stderr quux\n", "value foo"})
+}
+
+func TestCallWithOutputCallback(t *testing.T) {
+	var c outputCollector
+	f := NewGoFn("f", func(fm *Frame, arg string) {
+		fm.OutputChan() <- arg
+		fm.OutputFile().WriteString("bytes\n")
+		fm.ErrorFile().WriteString("error\n")
+	})
+	err := NewEvaler().CallWithOutputCallback(f,
+		CallCfg{Args: []interface{}{"arg"}}, EvalCfg{}, c.callbacks())
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+
+	c.check(t, []string{"bytes bytes\n", "stderr error\n", "value arg"})
+}
+
+type outputCollector struct {
+	mutex   sync.Mutex
+	outputs []string
+}
+
+func (c *outputCollector) add(s string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.outputs = append(c.outputs, s)
+}
+
+func (c *outputCollector) callbacks() OutputCallbacks {
+	return OutputCallbacks{
+		Value:       func(v interface{}) { c.add("value " + v.(string)) },
+		Bytes:       func(line string) { c.add("bytes " + line) },
+		StderrBytes: func(line string) { c.add("stderr " + line) },
+	}
+}
+
+func (c *outputCollector) check(t *testing.T, want []string) {
+	t.Helper()
+	// The relative order of the outputs is not deterministic.
+	sort.Strings(c.outputs)
+	if !reflect.DeepEqual(c.outputs, want) {
+		t.Errorf("got outputs %q, want %q", c.outputs, want)
+	}
+}
@@ -0,0 +1,50 @@
+// +build !windows,!plan9
+
+package eval_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/elves/elvish/pkg/eval"
+)
+
+func TestCallbackPorts_DrainTimeout(t *testing.T) {
+	lines := make(chan string, 10)
+	stdout, _, done, err := CallbackPorts(OutputCallbacks{
+		Bytes:        func(line string) { lines <- line },
+		DrainTimeout: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a background process that inherits the output.
+	fd, err := syscall.Dup(int(stdout.File.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	background := os.NewFile(uintptr(fd), "background")
+	defer background.Close()
+
+	doneReturned := make(chan struct{})
+	go func() {
+		done()
+		close(doneReturned)
+	}()
+	select {
+	case <-doneReturned:
+	case <-time.After(time.Second):
+		t.Fatalf("cleanup function did not return")
+	}
+
+	// Outputs written after the cleanup function returns are discarded.
+	background.WriteString("late\n")
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case line := <-lines:
+		t.Errorf("callback called with %q after cleanup", line)
+	default:
+	}
+}
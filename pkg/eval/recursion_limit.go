@@ -0,0 +1,44 @@
+package eval
+
+import "fmt"
+
+const (
+	// DefaultMaxCallDepth is the default value of Evaler.MaxCallDepth.
+	DefaultMaxCallDepth = 10000
+	// DefaultMaxPipelineDepth is the default value of
+	// Evaler.MaxPipelineDepth.
+	DefaultMaxPipelineDepth = 20000
+)
+
+// RecursionLimitExceeded is thrown when the depth of nested function calls or
+// pipelines exceeds the limit set in the Evaler.
+type RecursionLimitExceeded struct {
+	// What is nested, either "function calls" or "pipelines".
+	What  string
+	Limit int
+}
+
+// Error implements the error interface.
+func (e RecursionLimitExceeded) Error() string {
+	return fmt.Sprintf("recursion limit exceeded: more than %d nested %s",
+		e.Limit, e.What)
+}
+
+// Increments the depth of nested function calls of the frame, which must be
+// dedicated to the call. Returns an error if the limit has been reached.
+func (fm *Frame) enterCall() error {
+	if limit := fm.MaxCallDepth; limit > 0 && fm.callDepth >= limit {
+		return RecursionLimitExceeded{"function calls", limit}
+	}
+	fm.callDepth++
+	return nil
+}
+
+// Checks that a pipeline can be executed within the frame without exceeding
+// the limit of nested pipelines.
+func (fm *Frame) checkPipelineDepth() error {
+	if limit := fm.MaxPipelineDepth; limit > 0 && fm.pipelineDepth >= limit {
+		return RecursionLimitExceeded{"pipelines", limit}
+	}
+	return nil
+}
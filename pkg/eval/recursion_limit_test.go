@@ -0,0 +1,43 @@
+package eval_test
+
+import (
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestRecursionLimit(t *testing.T) {
+	TestWithSetup(t, setMaxDepths(10, 100),
+		That("fn f { f }; f").
+			Throws(RecursionLimitExceeded{"function calls", 10}),
+		// Recursions within the limit are fine.
+		That("fn f [n]{ if (> $n 0) { f (- $n 1) } }; f 3").DoesNothing(),
+		// The exception can be caught.
+		That("fn f { f }; try { f } except recursion-error { put caught }").
+			Puts("caught"),
+		That("fn f { f }; put ?(f)[class][name]").Puts("recursion-error"),
+	)
+
+	TestWithSetup(t, setMaxDepths(0, 10),
+		That("fn f { f }; f").
+			Throws(RecursionLimitExceeded{"pipelines", 10}),
+		That("fn f { put (f) }; f").
+			Throws(RecursionLimitExceeded{"pipelines", 10}),
+	)
+}
+
+func TestRecursionLimit_Default(t *testing.T) {
+	Test(t,
+		That("fn f { f }; f").
+			Throws(RecursionLimitExceeded{"function calls", DefaultMaxCallDepth}),
+	)
+}
+
+func setMaxDepths(calls, pipelines int) func(*Evaler) {
+	return func(ev *Evaler) {
+		ev.MaxCallDepth = calls
+		ev.MaxPipelineDepth = pipelines
+	}
+}
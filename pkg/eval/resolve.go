@@ -26,7 +26,7 @@ func (ev *evalerScopes) EachVariableInTop(ns string, f func(s string)) {
 		fsutil.EachExternal(func(cmd string) {
 			f(cmd + FnSuffix)
 		})
-	case "E:":
+	case "E:", "E:" + envListPrefix:
 		for _, s := range os.Environ() {
 			if i := strings.IndexByte(s, '='); i > 0 {
 				f(s[:i])
@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"os"
+
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+// Restrictions limits what code evaluated by an Evaler can do to the world
+// outside the Evaler. Code that tries to do something that is not allowed gets
+// a RestrictionViolation exception, which can be caught like any other
+// exception.
+//
+// Restrictions are useful for evaluating code that should be free of side
+// effects, like code evaluated for previewing or untrusted completion hooks.
+// They can apply to an entire Evaler, or only to one evaluation with
+// EvalCfg.Restrictions. They are not a security boundary on their own:
+// functions implemented in Go, including those provided by modules, are
+// responsible for checking the restrictions with (*Frame).CheckRestriction
+// before doing anything restricted.
+type Restrictions struct {
+	// Disallows running external commands, including with the exec command.
+	NoExternalCommands bool
+	// Disallows opening files for writing, for example with output
+	// redirections.
+	NoFileWrites bool
+	// Disallows changing environment variables and the working directory.
+	NoEnvChanges bool
+}
+
+// Restriction identifies one of the restrictions in Restrictions.
+type Restriction int
+
+// Possible values of Restriction.
+const (
+	RestrictExternalCommands Restriction = iota
+	RestrictFileWrites
+	RestrictEnvChanges
+)
+
+var restrictionDescriptions = [...]string{
+	RestrictExternalCommands: "running external commands",
+	RestrictFileWrites:       "writing files",
+	RestrictEnvChanges:       "changing the environment",
+}
+
+// RestrictionViolation is thrown when code tries to do something disallowed by
+// the Restrictions in effect.
+type RestrictionViolation struct {
+	Restriction Restriction
+}
+
+// Error implements the error interface.
+func (e RestrictionViolation) Error() string {
+	return "restriction violated: " +
+		restrictionDescriptions[e.Restriction] + " is not allowed"
+}
+
+// Returns whether the restriction is in effect.
+func (r Restrictions) has(restriction Restriction) bool {
+	switch restriction {
+	case RestrictExternalCommands:
+		return r.NoExternalCommands
+	case RestrictFileWrites:
+		return r.NoFileWrites
+	case RestrictEnvChanges:
+		return r.NoEnvChanges
+	}
+	return false
+}
+
+// CheckRestriction returns a RestrictionViolation if the restriction is in
+// effect for the Evaler, and nil otherwise. Code with access to a Frame should
+// use (*Frame).CheckRestriction instead, which also takes the restrictions of
+// the evaluation into account.
+func (ev *Evaler) CheckRestriction(restriction Restriction) error {
+	if ev.Restrictions.has(restriction) {
+		return RestrictionViolation{restriction}
+	}
+	return nil
+}
+
+// CheckRestriction returns a RestrictionViolation if the restriction is in
+// effect for the Evaler or the current evaluation, and nil otherwise.
+func (fm *Frame) CheckRestriction(restriction Restriction) error {
+	if fm.restrictions.has(restriction) {
+		return RestrictionViolation{restriction}
+	}
+	return fm.Evaler.CheckRestriction(restriction)
+}
+
+// Chdir is like (*Evaler).Chdir, but also checks the restrictions of the
+// current evaluation.
+func (fm *Frame) Chdir(path string) error {
+	if err := fm.CheckRestriction(RestrictEnvChanges); err != nil {
+		return err
+	}
+	return fm.Evaler.Chdir(path)
+}
+
+// RestrictableVar is implemented by variables that may do something restricted
+// when used, like running an external command when read. When such a variable
+// is referred to, the variable returned by Restrict is used instead; it should
+// check the restrictions of the Frame.
+type RestrictableVar interface {
+	vars.Var
+	Restrict(fm *Frame) vars.Var
+}
+
+// Returns whether opening a file with the flag would write to it.
+func isWriteFlag(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0
+}
+
+// Returns a variable that reads from the environment variable v but throws a
+// RestrictionViolation when modified, if environment changes are restricted.
+// Returns v itself otherwise.
+func (fm *Frame) restrictEnvVar(v vars.Var) vars.Var {
+	if fm.CheckRestriction(RestrictEnvChanges) == nil {
+		return v
+	}
+	return restrictedEnvVar{v}
+}
+
+type restrictedEnvVar struct {
+	vars.Var
+}
+
+var _ vars.Unsettable = restrictedEnvVar{}
+
+func (restrictedEnvVar) Set(interface{}) error {
+	return RestrictionViolation{RestrictEnvChanges}
+}
+
+func (v restrictedEnvVar) IsSet() bool {
+	u, ok := v.Var.(vars.Unsettable)
+	return ok && u.IsSet()
+}
+
+func (restrictedEnvVar) Unset() error {
+	return RestrictionViolation{RestrictEnvChanges}
+}
@@ -0,0 +1,109 @@
+package eval_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestRestrictions(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	restoreX := testutil.WithTempEnv("X", "old")
+	defer restoreX()
+	testutil.MustMkdirAll("d")
+	testutil.MustWriteFile("in", []byte("foo\n"), 0600)
+
+	external := RestrictionViolation{RestrictExternalCommands}
+	fileWrites := RestrictionViolation{RestrictFileWrites}
+	envChanges := RestrictionViolation{RestrictEnvChanges}
+
+	TestWithSetup(t, restrict(Restrictions{
+		NoExternalCommands: true, NoFileWrites: true, NoEnvChanges: true}),
+		That("e:true").Throws(external, "e:true"),
+		That("exec true").Throws(external, "exec true"),
+
+		That("echo foo > out").Throws(fileWrites, "> out"),
+		That("echo foo >> out").Throws(fileWrites, ">> out"),
+		That("nop <> out").Throws(fileWrites, "<> out"),
+		// Reading files is still allowed.
+		That("slurp < in").Puts("foo\n"),
+
+		That("put $E:X").Puts("old"),
+		That("E:X = new").Throws(envChanges, "E:X = new"),
+		That("set-env X new").Throws(envChanges, "set-env X new"),
+		That("unset-env X").Throws(envChanges, "unset-env X"),
+		That("del E:X").Throws(envChanges, "E:X"),
+		That("E:X=new nop").Throws(AnyError),
+		That("paths = [/bin]").Throws(envChanges, "paths = [/bin]"),
+		That("cd d").Throws(envChanges, "cd d"),
+		That("./d").Throws(envChanges, "./d"),
+		That("pwd = d").Throws(envChanges, "pwd = d"),
+
+		// Violations can be caught.
+		That("try { e:true } except restriction-error { put caught }").
+			Puts("caught"),
+		That("put ?(set-env X new)[class][name]").Puts("restriction-error"),
+	)
+
+	if os.Getenv("X") != "old" {
+		t.Errorf("$E:X was changed to %q", os.Getenv("X"))
+	}
+	if _, err := os.Stat("out"); err == nil {
+		t.Errorf("file out was created")
+	}
+
+	// Each restriction can be enabled individually.
+	TestWithSetup(t, restrict(Restrictions{NoFileWrites: true}),
+		That("put $E:X; set-env X new; put $E:X").Puts("old", "new"),
+		That("echo foo > out").Throws(fileWrites),
+	)
+	TestWithSetup(t, restrict(Restrictions{NoEnvChanges: true}),
+		That("echo foo > out; slurp < out").Puts("foo\n"),
+		That("set-env X new").Throws(envChanges),
+	)
+}
+
+func TestRestrictions_EvalCfg(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+	restoreX := testutil.WithTempEnv("X", "old")
+	defer restoreX()
+	testutil.MustMkdirAll("d")
+
+	ev := NewEvaler()
+	r := Restrictions{NoFileWrites: true, NoEnvChanges: true}
+	for _, code := range []string{
+		"echo foo > out", "set-env X new", "cd d", "pwd = d",
+		// Restrictions also apply to closures and nested evaluations.
+		"fn f { set-env X new }; f", "eval 'echo foo > out'",
+		"run-parallel { set-env X new }",
+	} {
+		err := ev.Eval(parse.Source{Name: "[test]", Code: code},
+			EvalCfg{Restrictions: r})
+		if _, ok := Reason(err).(RestrictionViolation); !ok {
+			t.Errorf("%s: got error %v, want RestrictionViolation", code, err)
+		}
+	}
+	if os.Getenv("X") != "old" {
+		t.Errorf("$E:X was changed to %q", os.Getenv("X"))
+	}
+	if _, err := os.Stat("out"); err == nil {
+		t.Errorf("file out was created")
+	}
+
+	// The restrictions don't apply to other evaluations.
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "set-env X new"}, EvalCfg{})
+	if err != nil || os.Getenv("X") != "new" {
+		t.Errorf("unrestricted evaluation failed: %v", err)
+	}
+}
+
+func restrict(r Restrictions) func(*Evaler) {
+	return func(ev *Evaler) { ev.Restrictions = r }
+}
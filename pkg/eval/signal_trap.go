@@ -0,0 +1,79 @@
+package eval
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Signal traps are callbacks that replace the default handling of signals by
+// Elvish. Since signals are delivered to the whole process, traps are global.
+
+var (
+	trapsMutex sync.Mutex
+	traps      = map[os.Signal]func(){}
+	// Channel that receives all the trapped signals; created when the first
+	// trap is registered.
+	trapCh chan os.Signal
+	// Number of active listeners created by ListenInterrupts. A trap for
+	// SIGINT is only called while there are active listeners, so that it
+	// doesn't interfere with the handling of SIGINT by the editor.
+	interruptListeners int32
+)
+
+// TrapSignal arranges for f to be called in a new goroutine every time the
+// process receives sig, replacing any existing trap for sig. If f is nil, the
+// existing trap is removed.
+//
+// When SIGINT or SIGQUIT is trapped, listeners created by ListenInterrupts no
+// longer interrupt evaluations when receiving it. A trap for SIGINT is only
+// called when there are such listeners, that is, while code is being
+// evaluated.
+func TrapSignal(sig os.Signal, f func()) {
+	trapsMutex.Lock()
+	defer trapsMutex.Unlock()
+	if f == nil {
+		delete(traps, sig)
+	} else {
+		traps[sig] = f
+	}
+	if trapCh == nil {
+		trapCh = make(chan os.Signal, 16)
+		go relayTrappedSignals(trapCh)
+	}
+	// Resubscribe to the signals that are still trapped. Stopping the channel
+	// restores the default action of signals no longer trapped, unless they
+	// are also subscribed to elsewhere.
+	signal.Stop(trapCh)
+	sigs := make([]os.Signal, 0, len(traps))
+	for sig := range traps {
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) > 0 {
+		signal.Notify(trapCh, sigs...)
+	}
+}
+
+// IsSignalTrapped returns whether there is a trap for sig.
+func IsSignalTrapped(sig os.Signal) bool {
+	trapsMutex.Lock()
+	defer trapsMutex.Unlock()
+	_, ok := traps[sig]
+	return ok
+}
+
+func relayTrappedSignals(ch <-chan os.Signal) {
+	for sig := range ch {
+		if sig == syscall.SIGINT && atomic.LoadInt32(&interruptListeners) == 0 {
+			continue
+		}
+		trapsMutex.Lock()
+		f := traps[sig]
+		trapsMutex.Unlock()
+		if f != nil {
+			go f()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+)
+
+// Signature describes the arguments and options of a user-defined function. It
+// contains the same information as the arg-names, arg-types, rest-arg,
+// opt-names and opt-defaults fields of the function, along with its
+// documentation.
+type Signature struct {
+	ArgNames []string
+	// Types of the arguments, in the same order as ArgNames. Arguments without
+	// type annotations have the type "any".
+	ArgTypes []string
+	// The index of the rest argument. -1 if there is no rest argument.
+	RestArg     int
+	OptNames    []string
+	OptDefaults []interface{}
+	// The documentation of the function, like the doc field of the output of
+	// fn-info.
+	Doc string
+}
+
+// SignatureOf returns the signature of fn if it is a user-defined function.
+// Otherwise it returns false as the second return value.
+func SignatureOf(fn Callable) (Signature, bool) {
+	c, ok := fn.(*closure)
+	if !ok {
+		return Signature{}, false
+	}
+	argTypes := make([]string, len(c.ArgNames))
+	for i := range argTypes {
+		if argTypes[i] = c.argType(i); argTypes[i] == "" {
+			argTypes[i] = "any"
+		}
+	}
+	return Signature{
+		ArgNames: c.ArgNames, ArgTypes: argTypes, RestArg: c.RestArg,
+		OptNames: c.OptNames, OptDefaults: c.OptDefaults, Doc: c.docText()}, true
+}
+
+// ArgIndex returns the index of the argument that receives the i-th (0-based)
+// value passed to the function, assuming that no more values follow it. It
+// returns -1 if there is no such argument.
+func (s Signature) ArgIndex(i int) int {
+	switch {
+	case s.RestArg != -1 && i >= s.RestArg:
+		return s.RestArg
+	case i < len(s.ArgNames):
+		return i
+	default:
+		return -1
+	}
+}
+
+// ArgString returns the i-th argument in the syntax of function signatures,
+// like "x", "x:num" or "@rest".
+func (s Signature) ArgString(i int) string {
+	var sb strings.Builder
+	if i == s.RestArg {
+		sb.WriteByte('@')
+	}
+	sb.WriteString(s.ArgNames[i])
+	if s.ArgTypes[i] != "any" {
+		sb.WriteString(":" + s.ArgTypes[i])
+	}
+	return sb.String()
+}
+
+// OptString returns the i-th option in the syntax of function signatures, like
+// "&k=v".
+func (s Signature) OptString(i int) string {
+	return "&" + s.OptNames[i] + "=" + vals.Repr(s.OptDefaults[i], vals.NoPretty)
+}
+
+// String returns the signature in the syntax of function signatures, like
+// "[x:num @rest &k=v]".
+func (s Signature) String() string {
+	var params []string
+	for i := range s.ArgNames {
+		params = append(params, s.ArgString(i))
+	}
+	for i := range s.OptNames {
+		params = append(params, s.OptString(i))
+	}
+	return "[" + strings.Join(params, " ") + "]"
+}
@@ -0,0 +1,92 @@
+package eval_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+func TestSignatureOf(t *testing.T) {
+	ev := NewEvaler()
+	err := ev.Eval(parse.Source{Name: "[test]",
+		Code: "fn f [x y:num @rest:string &k=v &l=[a]]{ }"}, EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, _ := ev.Global.Index("f~")
+
+	sig, ok := SignatureOf(fn.(Callable))
+	if !ok {
+		t.Fatalf("SignatureOf returns false for user-defined function")
+	}
+	wantSig := Signature{
+		ArgNames: []string{"x", "y", "rest"},
+		ArgTypes: []string{"any", "num", "string"},
+		RestArg:  2, OptNames: []string{"k", "l"},
+	}
+	gotOptDefaults := sig.OptDefaults
+	sig.OptDefaults = nil
+	if !reflect.DeepEqual(sig, wantSig) {
+		t.Errorf("got signature %v, want %v", sig, wantSig)
+	}
+	if len(gotOptDefaults) != 2 || gotOptDefaults[0] != "v" {
+		t.Errorf("got option defaults %v", gotOptDefaults)
+	}
+	sig.OptDefaults = gotOptDefaults
+
+	if s := sig.String(); s != "[x y:num @rest:string &k=v &l=[a]]" {
+		t.Errorf("got string %q", s)
+	}
+	for i, want := range []int{0, 1, 2, 2, 2} {
+		if got := sig.ArgIndex(i); got != want {
+			t.Errorf("ArgIndex(%d) -> %d, want %d", i, got, want)
+		}
+	}
+
+	put, _ := ev.Builtin.Index("put~")
+	if _, ok := SignatureOf(put.(Callable)); ok {
+		t.Errorf("SignatureOf returns true for builtin function")
+	}
+}
+
+func TestSignature_ArgIndex_NoRestArg(t *testing.T) {
+	sig := Signature{ArgNames: []string{"x", "y"}, RestArg: -1}
+	for i, want := range []int{0, 1, -1} {
+		if got := sig.ArgIndex(i); got != want {
+			t.Errorf("ArgIndex(%d) -> %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestRequiresArgs(t *testing.T) {
+	ev := NewEvaler()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: `
+		fn no-args { }
+		fn rest-arg [@a]{ }
+		fn default-arg [x=foo]{ }
+		fn arg [x]{ }`}, EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ns   *Ns
+		want bool
+	}{
+		{"no-args~", ev.Global, false},
+		{"rest-arg~", ev.Global, false},
+		{"default-arg~", ev.Global, false},
+		{"arg~", ev.Global, true},
+		{"nop~", ev.Builtin, false},
+		{"put~", ev.Builtin, false},
+		{"has-prefix~", ev.Builtin, true},
+	}
+	for _, test := range tests {
+		fn, _ := test.ns.Index(test.name)
+		if got := RequiresArgs(fn.(Callable)); got != test.want {
+			t.Errorf("RequiresArgs(%s) -> %v, want %v", test.name, got, test.want)
+		}
+	}
+}
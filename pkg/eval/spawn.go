@@ -0,0 +1,378 @@
+package eval
+
+import (
+	"strconv"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Priority, resource limits and process groups of external commands.
+
+func init() {
+	addBuiltinFns(map[string]interface{}{
+		"nice":   nice,
+		"ionice": ionice,
+		"ulimit": ulimit,
+	})
+}
+
+// Attributes applied to external commands when they are spawned. A spawnAttrs
+// is never modified after it is stored in a Frame, so that it can be shared by
+// forked frames.
+type spawnAttrs struct {
+	// Increment of the niceness, relative to the niceness of Elvish.
+	nice int
+	// The I/O scheduling class and the priority level within the class. The
+	// class is 0 if the I/O priority is not changed.
+	ioClass, ioLevel int
+	// Resource limits, applied in order.
+	rlimits []rlimitSetting
+	// Whether to start the command in a new process group.
+	newPgroup bool
+}
+
+type rlimitSetting struct {
+	resource string
+	// The limit, or rlimUnlimited.
+	limit uint64
+	// Whether to set the hard limit in addition to the soft limit.
+	hard bool
+}
+
+const rlimUnlimited = ^uint64(0)
+
+// I/O scheduling classes, with the same values as in the ioprio_set system call
+// of Linux.
+const (
+	ioClassRealtime = 1 + iota
+	ioClassBestEffort
+	ioClassIdle
+)
+
+var ioClassNames = [...]string{
+	ioClassRealtime:   "realtime",
+	ioClassBestEffort: "best-effort",
+	ioClassIdle:       "idle",
+}
+
+// Returns a copy of attrs that can be modified. The argument may be nil.
+func (attrs *spawnAttrs) clone() *spawnAttrs {
+	if attrs == nil {
+		return &spawnAttrs{}
+	}
+	clone := *attrs
+	clone.rlimits = append([]rlimitSetting(nil), attrs.rlimits...)
+	return &clone
+}
+
+// Returns whether attrs changes anything about spawned commands. The receiver
+// may be nil.
+func (attrs *spawnAttrs) isEmpty() bool {
+	return attrs == nil || (attrs.nice == 0 && attrs.ioClass == 0 &&
+		len(attrs.rlimits) == 0 && !attrs.newPgroup)
+}
+
+// Returns attrs with the options passed to an external command applied.
+// Returns ErrExternalCmdOpts if there is an unknown option.
+func (attrs *spawnAttrs) withOpts(opts map[string]interface{}) (*spawnAttrs, error) {
+	if len(opts) == 0 {
+		return attrs, nil
+	}
+	attrs = attrs.clone()
+	// The level is set after the class, so that &ionice-level can be used
+	// without &ionice-class.
+	var ioLevel interface{}
+	for name, v := range opts {
+		var err error
+		switch name {
+		case "nice":
+			var n int
+			err = vals.ScanToGo(v, &n)
+			attrs.nice += n
+		case "ionice-class":
+			var class string
+			err = vals.ScanToGo(v, &class)
+			if err == nil {
+				attrs.ioClass, err = parseIOClass(class)
+			}
+		case "ionice-level":
+			ioLevel = v
+		case "ulimit":
+			var errLimit error
+			err = vals.IterateKeys(v, func(k interface{}) bool {
+				var resource string
+				var limit interface{}
+				if errLimit = vals.ScanToGo(k, &resource); errLimit != nil {
+					return false
+				}
+				if limit, errLimit = vals.Index(v, k); errLimit != nil {
+					return false
+				}
+				errLimit = attrs.addRlimit(resource, limit, false)
+				return errLimit == nil
+			})
+			if err == nil {
+				err = errLimit
+			}
+		case "new-pgroup":
+			err = vals.ScanToGo(v, &attrs.newPgroup)
+		default:
+			return nil, ErrExternalCmdOpts
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ioLevel != nil {
+		if attrs.ioClass == 0 {
+			attrs.ioClass = ioClassBestEffort
+		}
+		err := vals.ScanToGo(ioLevel, &attrs.ioLevel)
+		if err == nil {
+			err = checkIOLevel(attrs.ioLevel)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attrs, nil
+}
+
+func (attrs *spawnAttrs) addRlimit(resource string, limitArg interface{}, hard bool) error {
+	if err := checkRlimitResource(resource); err != nil {
+		return err
+	}
+	limit, err := parseRlimit(limitArg)
+	if err != nil {
+		return err
+	}
+	attrs.rlimits = append(attrs.rlimits, rlimitSetting{resource, limit, hard})
+	return nil
+}
+
+func parseIOClass(s string) (int, error) {
+	for i, name := range ioClassNames {
+		if s != "" && name == s {
+			return i, nil
+		}
+	}
+	return 0, errs.BadValue{What: "I/O scheduling class",
+		Valid: "realtime, best-effort or idle", Actual: parse.Quote(s)}
+}
+
+func checkIOLevel(level int) error {
+	if level < 0 || level > 7 {
+		return errs.OutOfRange{What: "I/O priority level",
+			ValidLow: "0", ValidHigh: "7", Actual: strconv.Itoa(level)}
+	}
+	return nil
+}
+
+func parseRlimit(v interface{}) (uint64, error) {
+	if v == "unlimited" {
+		return rlimUnlimited, nil
+	}
+	var limit int
+	err := vals.ScanToGo(v, &limit)
+	if err != nil || limit < 0 {
+		return 0, errs.BadValue{What: "resource limit",
+			Valid: "non-negative integer or unlimited", Actual: vals.Repr(v, vals.NoPretty)}
+	}
+	return uint64(limit), nil
+}
+
+func formatRlimit(limit uint64) string {
+	if limit == rlimUnlimited {
+		return "unlimited"
+	}
+	return strconv.FormatUint(limit, 10)
+}
+
+// Calls f with the spawn attributes replaced with attrs.
+func callWithSpawnAttrs(fm *Frame, name string, attrs *spawnAttrs, f Callable) error {
+	newFm := fm.fork(name)
+	newFm.spawn = attrs
+	return f.Call(newFm, NoArgs, NoOpts)
+}
+
+//elvdoc:fn nice
+//
+// ```elvish
+// nice &n=10 $callable
+// ```
+//
+// Calls `$callable`, running all the external commands it spawns with their
+// niceness increased by `$n` relative to Elvish. A higher niceness means a
+// lower scheduling priority; a negative `$n` raises the priority, which
+// usually requires special privileges. Calls to `nice` can be nested, in which
+// case the increments add up:
+//
+// ```elvish-transcript
+// ~> nice { e:nice }
+// 10
+// ~> nice &n=5 { nice &n=3 { e:nice } }
+// 8
+// ```
+//
+// Only external commands are affected; code written in Elvish still runs with
+// the priority of Elvish itself. The niceness of a single command can also be
+// set with the `&nice` option of external commands, as in `e:make &nice=10`.
+//
+// @cf ionice ulimit
+
+type niceOpts struct{ N int }
+
+func (o *niceOpts) SetDefaultOptions() { o.N = 10 }
+
+func nice(fm *Frame, opts niceOpts, f Callable) error {
+	attrs := fm.spawn.clone()
+	attrs.nice += opts.N
+	return callWithSpawnAttrs(fm, "nice", attrs, f)
+}
+
+//elvdoc:fn ionice
+//
+// ```elvish
+// ionice &class=best-effort &level=4 $callable
+// ```
+//
+// Calls `$callable`, running all the external commands it spawns with the
+// given I/O scheduling class and priority level within the class. The class
+// is one of `realtime`, `best-effort` and `idle`, and the level is an integer
+// from 0 (highest priority) to 7 (lowest priority); the level is ignored for
+// the `idle` class. Example:
+//
+// ```elvish-transcript
+// ~> ionice &class=idle { e:ionice }
+// idle
+// ```
+//
+// The I/O priority of a single command can also be set with the
+// `&ionice-class` and `&ionice-level` options of external commands.
+//
+// Setting I/O priorities is only supported on Linux; on other systems,
+// spawning external commands within `$callable` throws an exception.
+//
+// @cf nice ulimit
+
+type ioniceOpts struct {
+	Class string
+	Level int
+}
+
+func (o *ioniceOpts) SetDefaultOptions() { o.Class, o.Level = "best-effort", 4 }
+
+func ionice(fm *Frame, opts ioniceOpts, f Callable) error {
+	class, err := parseIOClass(opts.Class)
+	if err != nil {
+		return err
+	}
+	if err := checkIOLevel(opts.Level); err != nil {
+		return err
+	}
+	attrs := fm.spawn.clone()
+	attrs.ioClass, attrs.ioLevel = class, opts.Level
+	return callWithSpawnAttrs(fm, "ionice", attrs, f)
+}
+
+//elvdoc:fn ulimit
+//
+// ```elvish
+// ulimit &hard=$false
+// ulimit &hard=$false $resource
+// ulimit &hard=$false $resource $limit $callable
+// ```
+//
+// Queries or sets resource limits.
+//
+// Without arguments, outputs a map from the names of all the supported
+// resources to their current limits. With a `$resource` argument, outputs the
+// current limit of that resource. The limits are the soft limits, or the hard
+// limits if `&hard` is true. A limit is either a number or `unlimited`.
+//
+// With `$limit` and `$callable` arguments, calls `$callable`, running all the
+// external commands it spawns with the soft limit of `$resource` set to
+// `$limit`; if `&hard` is true, the hard limit is set too. The limits of
+// Elvish itself are not changed. Example:
+//
+// ```elvish-transcript
+// ~> ulimit nofile
+// ▶ 1024
+// ~> ulimit nofile 256 { sh -c 'ulimit -n' }
+// 256
+// ```
+//
+// The supported resources are:
+//
+// -   `as`: The maximum size of the virtual memory in bytes (Linux only).
+//
+// -   `core`: The maximum size of core files in bytes.
+//
+// -   `cpu`: The maximum CPU time in seconds.
+//
+// -   `data`: The maximum size of the data segment in bytes.
+//
+// -   `fsize`: The maximum size of files written, in bytes.
+//
+// -   `nofile`: The maximum number of open files.
+//
+// -   `stack`: The maximum size of the stack in bytes.
+//
+// The limits of a single command can also be set with the `&ulimit` option of
+// external commands, whose value is a map from resource names to limits, like
+// `e:make &ulimit=[&cpu=60 &core=0]`.
+//
+// The limits are set by a copy of Elvish that is started as a helper and then
+// executes the command, so the command never runs without them. None of the
+// forms are supported on Windows.
+//
+// @cf nice ionice
+
+type ulimitOpts struct{ Hard bool }
+
+func (o *ulimitOpts) SetDefaultOptions() {}
+
+func ulimit(fm *Frame, opts ulimitOpts, args ...interface{}) error {
+	switch len(args) {
+	case 0:
+		m := vals.EmptyMap
+		for _, resource := range rlimitResources() {
+			limit, err := getRlimit(resource, opts.Hard)
+			if err != nil {
+				return err
+			}
+			m = m.Assoc(resource, formatRlimit(limit))
+		}
+		fm.OutputChan() <- m
+		return nil
+	case 1:
+		var resource string
+		if err := vals.ScanToGo(args[0], &resource); err != nil {
+			return err
+		}
+		limit, err := getRlimit(resource, opts.Hard)
+		if err != nil {
+			return err
+		}
+		fm.OutputChan() <- formatRlimit(limit)
+		return nil
+	case 3:
+		var resource string
+		var f Callable
+		if err := vals.ScanToGo(args[0], &resource); err != nil {
+			return err
+		}
+		if err := vals.ScanToGo(args[2], &f); err != nil {
+			return err
+		}
+		attrs := fm.spawn.clone()
+		if err := attrs.addRlimit(resource, args[1], opts.Hard); err != nil {
+			return err
+		}
+		return callWithSpawnAttrs(fm, "ulimit", attrs, f)
+	default:
+		return ErrArgs
+	}
+}
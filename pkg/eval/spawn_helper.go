@@ -0,0 +1,24 @@
+package eval
+
+import (
+	"os"
+
+	"github.com/elves/elvish/pkg/prog"
+)
+
+// SpawnHelperProgram is the subprogram that applies spawn attributes that can
+// only be set by a process itself, like resource limits, and then executes an
+// external command. Elvish starts a copy of itself running this subprogram
+// when spawning such commands, so it must be included by the main program.
+var SpawnHelperProgram prog.Program = spawnHelperProgram{}
+
+type spawnHelperProgram struct{}
+
+func (spawnHelperProgram) ShouldRun(f *prog.Flags) bool { return f.SpawnHelper }
+
+func (spawnHelperProgram) Run(fds [3]*os.File, f *prog.Flags, args []string) error {
+	if len(args) < 2 {
+		return prog.BadUsage("-spawn-helper requires a path and the arguments of the command")
+	}
+	return runSpawnHelperProgram(args[0], args[1:])
+}
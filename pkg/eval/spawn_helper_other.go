@@ -0,0 +1,9 @@
+// +build windows plan9
+
+package eval
+
+import "errors"
+
+func runSpawnHelperProgram(string, []string) error {
+	return errors.New("-spawn-helper is not supported on this platform")
+}
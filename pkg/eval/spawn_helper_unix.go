@@ -0,0 +1,114 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"syscall"
+
+	"github.com/elves/elvish/pkg/prog"
+	"github.com/elves/elvish/pkg/sys"
+)
+
+// Some spawn attributes, like resource limits, apply to a whole process, and
+// can only be set by the process itself. To apply them before the program
+// starts, Elvish starts a copy of itself as a helper, which sets the attributes
+// and then executes the program in the same process.
+//
+// The helper is SpawnHelperProgram, started with the -spawn-helper flag and an
+// environment variable containing the attributes to set as JSON. Its arguments
+// are the path of the program, followed by the arguments of the program.
+const spawnHelperEnv = "ELVISH_SPAWN_HELPER"
+
+type spawnHelperSpec struct {
+	// The FD to write errors to. It is closed when the program is executed.
+	ErrFD int
+	// Whether to set the niceness, and the niceness to set.
+	SetNice bool `json:",omitempty"`
+	Nice    int  `json:",omitempty"`
+	Rlimits []spawnRlimit
+}
+
+type spawnRlimit struct {
+	Resource int
+	Cur, Max uint64
+}
+
+func runSpawnHelperProgram(path string, args []string) error {
+	specJSON, ok := os.LookupEnv(spawnHelperEnv)
+	if !ok {
+		return errors.New("-spawn-helper can only be used by Elvish itself")
+	}
+	var spec spawnHelperSpec
+	if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
+		return err
+	}
+	err := runSpawnHelper(&spec, path, args)
+	// Only reached when the program could not be executed.
+	os.NewFile(uintptr(spec.ErrFD), "").WriteString(err.Error())
+	return prog.Exit(127)
+}
+
+func runSpawnHelper(spec *spawnHelperSpec, path string, args []string) error {
+	syscall.CloseOnExec(spec.ErrFD)
+	os.Unsetenv(spawnHelperEnv)
+	if spec.SetNice {
+		if err := sys.SetNice(0, spec.Nice); err != nil {
+			return os.NewSyscallError("setpriority", err)
+		}
+	}
+	for _, rlim := range spec.Rlimits {
+		err := sys.Setrlimit(rlim.Resource, sys.Rlimit{Cur: rlim.Cur, Max: rlim.Max})
+		if err != nil {
+			return os.NewSyscallError("setrlimit", err)
+		}
+	}
+	err := syscall.Exec(path, args, os.Environ())
+	return &os.PathError{Op: "exec", Path: path, Err: err}
+}
+
+// Starts a process through the helper, which applies spec before executing the
+// program. It returns an error if the helper fails to apply spec or to execute
+// the program.
+func startWithHelper(path string, args []string, procAttr *os.ProcAttr, spec spawnHelperSpec) (*os.Process, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	spec.ErrFD = len(procAttr.Files)
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	env := procAttr.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	helperAttr := *procAttr
+	helperAttr.Files = append(procAttr.Files[:len(procAttr.Files):len(procAttr.Files)], w)
+	helperAttr.Env = append(env[:len(env):len(env)], spawnHelperEnv+"="+string(specJSON))
+
+	helperArgs := append([]string{args[0], "-spawn-helper", "--", path}, args...)
+	proc, err := os.StartProcess(self, helperArgs, &helperAttr)
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+	// The pipe is closed without any message when the program is executed.
+	msg, _ := ioutil.ReadAll(r)
+	if len(msg) > 0 {
+		proc.Wait()
+		return nil, errors.New(string(msg))
+	}
+	return proc, nil
+}
@@ -0,0 +1,65 @@
+package eval
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/elves/elvish/pkg/sys"
+)
+
+// Starts a process with the spawn attributes applied.
+func startProcess(path string, args []string, procAttr *os.ProcAttr, attrs *spawnAttrs) (*os.Process, error) {
+	if attrs == nil || (attrs.nice == 0 && attrs.ioClass == 0 && len(attrs.rlimits) == 0) {
+		return os.StartProcess(path, args, procAttr)
+	}
+	start := os.StartProcess
+	if len(attrs.rlimits) > 0 {
+		// Resource limits are shared by all the threads of a process, so they
+		// can't be set just for the thread that starts the process. Instead,
+		// start a helper that sets them and executes the program.
+		rlims, err := combineRlimits(attrs.rlimits)
+		if err != nil {
+			return nil, err
+		}
+		start = func(path string, args []string, procAttr *os.ProcAttr) (*os.Process, error) {
+			return startWithHelper(path, args, procAttr, spawnHelperSpec{Rlimits: rlims})
+		}
+	}
+	var proc *os.Process
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// On Linux, the niceness and the I/O priority are attributes of
+		// threads, and are inherited by processes forked from a thread. Set
+		// them on a dedicated thread before starting the process, so that the
+		// process never runs without them. Since raising the priority back
+		// usually requires privileges, the thread is never unlocked, which
+		// causes it to be terminated when the goroutine exits.
+		runtime.LockOSThread()
+		if err = setThreadPriority(attrs); err != nil {
+			return
+		}
+		proc, err = start(path, args, procAttr)
+	}()
+	<-done
+	return proc, err
+}
+
+// Sets the niceness and I/O priority of the calling thread.
+func setThreadPriority(attrs *spawnAttrs) error {
+	if attrs.nice != 0 {
+		current, err := sys.GetNice(0)
+		if err != nil {
+			return err
+		}
+		err = sys.SetNice(0, current+attrs.nice)
+		if err != nil {
+			return err
+		}
+	}
+	if attrs.ioClass != 0 {
+		return sys.SetIOPriority(0, attrs.ioClass, attrs.ioLevel)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package eval_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestNice(t *testing.T) {
+	Test(t,
+		That("nice { sh -c 'echo $(nice)' }").Prints("10\n"),
+		That("nice &n=5 { nice &n=3 { sh -c 'echo $(nice)' } }").Prints("8\n"),
+		That("sh -c 'echo $(nice)' &nice=2").Prints("2\n"),
+		// Elvish itself is not affected.
+		That("nice { nop }; sh -c 'echo $(nice)'").Prints("0\n"),
+	)
+}
+
+func TestUlimit(t *testing.T) {
+	Test(t,
+		That("ulimit nofile 100 { sh -c 'ulimit -n' }").Prints("100\n"),
+		That("sh -c 'ulimit -n' &ulimit=[&nofile=50]").Prints("50\n"),
+		That("ulimit nofile 100 { sh -c 'ulimit -n' &ulimit=[&nofile=50] }").
+			Prints("50\n"),
+		That("ulimit &hard core 0 { sh -c 'ulimit -Hc' }").Prints("0\n"),
+		That("ulimit cpu unlimited { sh -c 'ulimit -t' }").
+			Prints("unlimited\n"),
+		// The limits are set by a helper, which doesn't leave any trace in the
+		// environment of the command.
+		That("ulimit nofile 100 { sh -c 'echo ${ELVISH_SPAWN_HELPER-unset}' }").
+			Prints("unset\n"),
+		// Failures to set the limits are reported as errors.
+		That("ulimit &hard nofile 10 { ulimit nofile 20 { e:true } }").
+			Throws(AnyError),
+
+		// Querying limits.
+		That("ulimit nofile | eq (one) (ulimit)[nofile]").Puts(true),
+		That("keys (ulimit &hard) | count").Puts("7"),
+
+		That("ulimit foo").Throws(errs.BadValue{
+			What:  "resource",
+			Valid: "as, core, cpu, data, fsize, nofile, stack", Actual: "foo"}),
+		That("ulimit nofile -1 { }").Throws(errs.BadValue{
+			What:  "resource limit",
+			Valid: "non-negative integer or unlimited", Actual: "-1"}),
+	)
+}
+
+func TestIonice(t *testing.T) {
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice not found")
+	}
+	Test(t,
+		That("ionice &class=idle { e:ionice }").Prints("idle\n"),
+		That("ionice &level=6 { e:ionice }").Prints("best-effort: prio 6\n"),
+		That("e:ionice &ionice-level=7").Prints("best-effort: prio 7\n"),
+	)
+}
+
+func TestNewPgroup(t *testing.T) {
+	// Fields 1 and 5 of /proc/self/stat are the process ID and the process
+	// group ID.
+	script := "sh -c 'read pid _ _ _ pgrp _ < /proc/self/stat; " +
+		"[ $pid = $pgrp ] && echo new || echo inherited'"
+	Test(t,
+		That(script+" &new-pgroup=$true").Prints("new\n"),
+		That(script).Prints("inherited\n"),
+	)
+}
@@ -0,0 +1,37 @@
+// +build !windows,!plan9,!linux
+
+package eval
+
+import (
+	"os"
+
+	"github.com/elves/elvish/pkg/sys"
+)
+
+// Starts a process with the spawn attributes applied.
+//
+// The niceness and resource limits are applied by a helper before it executes
+// the program, so the program never runs without them. Changing the I/O
+// priority is only supported on Linux.
+func startProcess(path string, args []string, procAttr *os.ProcAttr, attrs *spawnAttrs) (*os.Process, error) {
+	if attrs == nil || (attrs.nice == 0 && attrs.ioClass == 0 && len(attrs.rlimits) == 0) {
+		return os.StartProcess(path, args, procAttr)
+	}
+	if attrs.ioClass != 0 {
+		return nil, sys.ErrNotSupported
+	}
+	var spec spawnHelperSpec
+	if attrs.nice != 0 {
+		current, err := sys.GetNice(0)
+		if err != nil {
+			return nil, err
+		}
+		spec.SetNice, spec.Nice = true, current+attrs.nice
+	}
+	rlims, err := combineRlimits(attrs.rlimits)
+	if err != nil {
+		return nil, err
+	}
+	spec.Rlimits = rlims
+	return startWithHelper(path, args, procAttr, spec)
+}
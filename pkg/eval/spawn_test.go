@@ -0,0 +1,38 @@
+package eval_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/prog"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+)
+
+func TestMain(m *testing.M) {
+	// Commands with some spawn attributes are started through a copy of the
+	// current executable, which is the test binary.
+	if len(os.Args) > 1 && os.Args[1] == "-spawn-helper" {
+		os.Exit(prog.Run([3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
+			SpawnHelperProgram))
+	}
+	os.Exit(m.Run())
+}
+
+func TestSpawnAttrs_BadArguments(t *testing.T) {
+	Test(t,
+		That("ionice &class=x { }").Throws(errs.BadValue{
+			What:  "I/O scheduling class",
+			Valid: "realtime, best-effort or idle", Actual: "x"}),
+		That("ionice &level=8 { }").Throws(errs.OutOfRange{
+			What: "I/O priority level", ValidLow: "0", ValidHigh: "7", Actual: "8"}),
+		That("e:true &ionice-class=x").Throws(AnyError),
+		That("e:true &ionice-level=-1").Throws(AnyError),
+		That("e:true &ulimit=foo").Throws(AnyError),
+		That("e:true &nice=foo").Throws(AnyError),
+		That("e:true &no-such-option").Throws(ErrExternalCmdOpts),
+		That("ulimit a b").Throws(ErrArgs),
+	)
+}
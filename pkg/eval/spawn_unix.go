@@ -0,0 +1,75 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/elves/elvish/pkg/eval/errs"
+	"github.com/elves/elvish/pkg/sys"
+)
+
+func rlimitResources() []string {
+	names := make([]string, 0, len(sys.RlimitResources))
+	for name := range sys.RlimitResources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkRlimitResource(name string) error {
+	if _, ok := sys.RlimitResources[name]; !ok {
+		return errs.BadValue{What: "resource",
+			Valid: strings.Join(rlimitResources(), ", "), Actual: name}
+	}
+	return nil
+}
+
+func getRlimit(resource string, hard bool) (uint64, error) {
+	if err := checkRlimitResource(resource); err != nil {
+		return 0, err
+	}
+	rlim, err := sys.Getrlimit(sys.RlimitResources[resource])
+	if err != nil {
+		return 0, err
+	}
+	limit := rlim.Cur
+	if hard {
+		limit = rlim.Max
+	}
+	if limit == sys.RlimInfinity {
+		return rlimUnlimited, nil
+	}
+	return limit, nil
+}
+
+// Combines resource limit settings into the limits to set. Settings of the
+// same resource are combined, starting from the limits inherited from Elvish.
+func combineRlimits(settings []rlimitSetting) ([]spawnRlimit, error) {
+	var rlims []spawnRlimit
+	indices := make(map[int]int)
+	for _, setting := range settings {
+		resource := sys.RlimitResources[setting.resource]
+		i, ok := indices[resource]
+		if !ok {
+			rlim, err := sys.Getrlimit(resource)
+			if err != nil {
+				return nil, err
+			}
+			i = len(rlims)
+			indices[resource] = i
+			rlims = append(rlims, spawnRlimit{resource, rlim.Cur, rlim.Max})
+		}
+		limit := setting.limit
+		if limit == rlimUnlimited {
+			limit = sys.RlimInfinity
+		}
+		rlims[i].Cur = limit
+		if setting.hard {
+			rlims[i].Max = limit
+		}
+	}
+	return rlims, nil
+}
@@ -0,0 +1,18 @@
+package eval
+
+import "os"
+
+func rlimitResources() []string { return nil }
+
+func checkRlimitResource(string) error { return errNotSupportedOnWindows }
+
+func getRlimit(string, bool) (uint64, error) { return 0, errNotSupportedOnWindows }
+
+// Starts a process with the spawn attributes applied. Only starting a new
+// process group is supported on Windows, which is done by the caller.
+func startProcess(path string, args []string, procAttr *os.ProcAttr, attrs *spawnAttrs) (*os.Process, error) {
+	if attrs != nil && (attrs.nice != 0 || attrs.ioClass != 0 || len(attrs.rlimits) > 0) {
+		return nil, errNotSupportedOnWindows
+	}
+	return os.StartProcess(path, args, procAttr)
+}
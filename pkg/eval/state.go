@@ -11,6 +11,14 @@ type state struct {
 	notifyBgJobSuccess bool
 	// The current number of background jobs.
 	numBgJobs int
+	// The number of values buffered between commands in a pipeline.
+	pipelineBufferSize int
+	// Whether to print commands before calling them, and the prefix to print
+	// before them.
+	traceExec       bool
+	traceExecPrefix string
+	// Whether to change to directories used as commands without a slash.
+	autoCd bool
 }
 
 func (s *state) getValuePrefix() string {
@@ -25,6 +33,18 @@ func (s *state) getNotifyBgJobSuccess() bool {
 	return s.notifyBgJobSuccess
 }
 
+func (s *state) getPipelineBufferSize() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.pipelineBufferSize
+}
+
+func (s *state) setPipelineBufferSize(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pipelineBufferSize = n
+}
+
 func (s *state) getNumBgJobs() int {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -36,3 +56,15 @@ func (s *state) addNumBgJobs(delta int) {
 	defer s.mutex.Unlock()
 	s.numBgJobs += delta
 }
+
+func (s *state) getTraceExec() (bool, string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.traceExec, s.traceExecPrefix
+}
+
+func (s *state) getAutoCd() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.autoCd
+}
@@ -0,0 +1,30 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+)
+
+// Strict mode makes mistakes that would otherwise go unnoticed throw
+// exceptions. It is turned on with "pragma strict", and applies to the rest of
+// the scope the pragma appears in, including functions defined in it, but not
+// to other modules or to code compiled separately.
+
+// UnsetEnvError is thrown in strict mode when reading an environment variable
+// that is not set.
+type UnsetEnvError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e UnsetEnvError) Error() string {
+	return fmt.Sprintf("environment variable $E:%s is not set", e.Name)
+}
+
+// Returns an UnsetEnvError if the environment variable is not set.
+func checkEnvSet(name string) error {
+	if _, ok := os.LookupEnv(name); !ok {
+		return UnsetEnvError{name}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package eval_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+
+	. "github.com/elves/elvish/pkg/eval/evaltest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestStrict(t *testing.T) {
+	restore := testutil.WithTempEnv("X", "foo")
+	defer restore()
+	restoreUnset := testutil.WithTempEnv("UNSET", "")
+	defer restoreUnset()
+	testutil.Must(os.Unsetenv("UNSET"))
+	restoreSet := testutil.WithTempEnv("SET_IN_TEST", "")
+	defer restoreSet()
+	testutil.Must(os.Unsetenv("SET_IN_TEST"))
+
+	Test(t,
+		// Without strict mode, unset environment variables are empty.
+		That("put $E:UNSET").Puts(""),
+
+		That("pragma strict; put $E:X").Puts("foo"),
+		That("pragma strict; put $E:UNSET").
+			Throws(UnsetEnvError{"UNSET"}, "$E:UNSET"),
+		That("pragma strict; put $E:list:UNSET").
+			Throws(UnsetEnvError{"UNSET"}, "$E:list:UNSET"),
+		That("pragma strict; put $E:UNSET[0]").
+			Throws(UnsetEnvError{"UNSET"}, "$E:UNSET"),
+		That("pragma strict; try { put $E:UNSET } except { put caught }").
+			Puts("caught"),
+		// Setting is still allowed.
+		That("pragma strict; E:SET_IN_TEST = bar; put $E:SET_IN_TEST").
+			Puts("bar"),
+
+		// Strict mode applies to the rest of the scope, including functions
+		// defined in it.
+		That("put $E:UNSET; pragma strict; put $E:UNSET").
+			Puts("").Throws(UnsetEnvError{"UNSET"}, "$E:UNSET"),
+		That("pragma strict; fn f { put $E:UNSET }; f").
+			Throws(UnsetEnvError{"UNSET"}, "$E:UNSET", "f"),
+		// It doesn't apply to functions defined before it, or outside the
+		// scope.
+		That("fn f { put $E:UNSET }; pragma strict; f").Puts(""),
+		That("{ pragma strict }; put $E:UNSET").Puts(""),
+		// It doesn't apply to code compiled separately, like modules.
+		That("pragma strict; eval 'put $E:UNSET'").Puts(""),
+
+		// "use strict" is the same as "pragma strict".
+		That("use strict; put $E:UNSET").
+			Throws(UnsetEnvError{"UNSET"}, "$E:UNSET"),
+		That("{ use strict }; put $E:UNSET").Puts(""),
+
+		That("pragma bad").DoesNotCompile(),
+		That("pragma").DoesNotCompile(),
+		That("pragma strict extra").DoesNotCompile(),
+	)
+}
+
+func TestStrict_DoesNotApplyToModules(t *testing.T) {
+	restore := testutil.WithTempEnv("UNSET", "")
+	defer restore()
+	testutil.Must(os.Unsetenv("UNSET"))
+	libdir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"lax.elv":    "fn f { put $E:UNSET }",
+		"strict.elv": "fn f { }",
+		"mod.elv":    "pragma strict; fn f { put $E:UNSET }",
+	})
+
+	TestWithSetup(t, func(ev *Evaler) { ev.SetLibDir(libdir) },
+		That("pragma strict; use lax; lax:f").Puts(""),
+		That("use mod; mod:f").Throws(UnsetEnvError{"UNSET"}),
+		// "use strict" turns on strict mode even if there is a module named
+		// strict.
+		That("use strict; put $E:UNSET").Throws(UnsetEnvError{"UNSET"}),
+		That("use strict; strict:f").Throws(AnyError),
+	)
+}
@@ -0,0 +1,262 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer receives events about the evaluation of code. Its Trace method may be
+// called from multiple goroutines concurrently, and should return quickly
+// since it is called synchronously during evaluation.
+type Tracer interface {
+	Trace(e TraceEvent)
+}
+
+// TraceEventKind is the kind of a TraceEvent.
+type TraceEventKind int
+
+// Possible values for TraceEventKind.
+const (
+	// A command has finished running.
+	TraceCall TraceEventKind = iota
+	// An external command has been started.
+	TraceExternalStart
+	// An external command has exited.
+	TraceExternalEnd
+)
+
+var traceEventKindNames = [...]string{"call", "external-start", "external-end"}
+
+func (k TraceEventKind) String() string {
+	if 0 <= k && int(k) < len(traceEventKindNames) {
+		return traceEventKindNames[k]
+	}
+	return fmt.Sprintf("TraceEventKind(%d)", int(k))
+}
+
+// TraceEvent is an event emitted to Tracer's.
+type TraceEvent struct {
+	Kind TraceEventKind
+	// The call stack, innermost first. Stack.Head is the source range of the
+	// command, and Stack.FnName is its name.
+	Stack *StackTrace
+	// When the command was started.
+	Start time.Time
+	// How long the command ran. Zero for TraceExternalStart.
+	Duration time.Duration
+	// The process ID of the external command. Zero for TraceCall.
+	Pid int
+	// The error the command finished with. Always nil for TraceExternalStart.
+	Err error
+}
+
+// AddTracer adds a Tracer to the Evaler, and returns a function to remove it.
+// It is safe to call AddTracer concurrently with evaluation.
+func (ev *Evaler) AddTracer(t Tracer) (remove func()) {
+	ev.tracersMutex.Lock()
+	defer ev.tracersMutex.Unlock()
+	old := ev.getTracers()
+	tracers := make([]Tracer, len(old), len(old)+1)
+	copy(tracers, old)
+	ev.tracers.Store(append(tracers, t))
+
+	var once sync.Once
+	return func() { once.Do(func() { ev.removeTracer(t) }) }
+}
+
+func (ev *Evaler) removeTracer(t Tracer) {
+	ev.tracersMutex.Lock()
+	defer ev.tracersMutex.Unlock()
+	old := ev.getTracers()
+	tracers := make([]Tracer, 0, len(old))
+	removed := false
+	for _, t2 := range old {
+		if t2 == t && !removed {
+			removed = true
+			continue
+		}
+		tracers = append(tracers, t2)
+	}
+	ev.tracers.Store(tracers)
+}
+
+func (ev *Evaler) getTracers() []Tracer {
+	tracers, _ := ev.tracers.Load().([]Tracer)
+	return tracers
+}
+
+// Whether there are any tracers. Used to avoid building events when nobody is
+// interested.
+func (ev *Evaler) tracing() bool {
+	return len(ev.getTracers()) > 0
+}
+
+// Sends an event to all tracers.
+func (ev *Evaler) trace(e TraceEvent) {
+	for _, t := range ev.getTracers() {
+		t.Trace(e)
+	}
+}
+
+// NewTraceWriter returns a Tracer that writes each event as a line of JSON to
+// w. Errors writing to w are ignored.
+//
+// Each line is an object with the following fields, with fields that don't
+// apply to the event omitted:
+//
+//   - kind: one of "call", "external-start" and "external-end".
+//   - name: the name of the command.
+//   - src: the name of the source code, like a file name.
+//   - line: the 1-based line number of the command in the source.
+//   - from, to: the byte range of the command in the source.
+//   - start: when the command started, in RFC 3339 format.
+//   - duration_ns: how long the command ran in nanoseconds.
+//   - pid: the process ID of an external command.
+//   - error: the error the command finished with.
+func NewTraceWriter(w io.Writer) Tracer {
+	return &traceWriter{enc: json.NewEncoder(w)}
+}
+
+type traceWriter struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+}
+
+type traceLine struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name,omitempty"`
+	Src        string `json:"src"`
+	Line       int    `json:"line"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Start      string `json:"start"`
+	DurationNs int64  `json:"duration_ns,omitempty"`
+	Pid        int    `json:"pid,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (tw *traceWriter) Trace(e TraceEvent) {
+	l := traceLine{
+		Kind:       e.Kind.String(),
+		Start:      e.Start.Format(time.RFC3339Nano),
+		DurationNs: int64(e.Duration),
+		Pid:        e.Pid,
+	}
+	if e.Stack != nil {
+		l.Name = e.Stack.FnName
+		if head := e.Stack.Head; head != nil {
+			l.Src, l.From, l.To = head.Name, head.From, head.To
+			l.Line = lineOf(head.Source, head.From)
+		}
+	}
+	if e.Err != nil {
+		l.Error = Reason(e.Err).Error()
+	}
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	tw.enc.Encode(l)
+}
+
+// Profiler is a Tracer that records the time spent in each command called by
+// some code, keyed by the call stack of the command.
+type Profiler struct {
+	root  *StackTrace
+	mutex sync.Mutex
+	total map[string]time.Duration
+}
+
+// NewProfiler returns a Profiler that records commands called from the code
+// running in fm. The Profiler needs to be added to the Evaler with AddTracer.
+func NewProfiler(fm *Frame) *Profiler {
+	return &Profiler{root: fm.traceback, total: make(map[string]time.Duration)}
+}
+
+// Trace implements the Tracer interface.
+func (p *Profiler) Trace(e TraceEvent) {
+	if e.Kind != TraceCall {
+		return
+	}
+	var frames []string
+	found := p.root == nil
+	for st := e.Stack; st != nil; st = st.Next {
+		if st == p.root {
+			found = true
+			break
+		}
+		frames = append(frames, profileFrame(st))
+	}
+	if !found || len(frames) == 0 {
+		return
+	}
+	// Reverse to get outermost first.
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	key := strings.Join(frames, ";")
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.total[key] += e.Duration
+}
+
+// WriteFolded writes the recorded profile in the "folded stacks" format used
+// by FlameGraph and compatible tools like speedscope. Each line contains the
+// frames of a call stack, outermost first and separated by semicolons,
+// followed by a space and the time spent in the innermost frame itself in
+// microseconds. Lines are sorted by the call stack.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	self := make(map[string]time.Duration, len(p.total))
+	for key, d := range p.total {
+		self[key] += d
+		if i := strings.LastIndexByte(key, ';'); i != -1 {
+			// Commands in a pipeline run in parallel, so the children of a
+			// frame may take longer than the frame itself; clamp at 0 when
+			// writing.
+			self[key[:i]] -= d
+		}
+	}
+	keys := make([]string, 0, len(p.total))
+	for key := range p.total {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		us := self[key].Microseconds()
+		if us < 0 {
+			us = 0
+		}
+		_, err := fmt.Fprintf(w, "%s %d\n", key, us)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the name of a frame in a profile, like "put ([tty 1]:1)".
+func profileFrame(st *StackTrace) string {
+	name := st.FnName
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if st.Head != nil {
+		name = fmt.Sprintf("%s (%s:%d)",
+			name, st.Head.Name, lineOf(st.Head.Source, st.Head.From))
+	}
+	// Semicolons separate frames in the folded format.
+	return strings.ReplaceAll(name, ";", ",")
+}
+
+// Returns the 1-based line number of the byte at index i of src.
+func lineOf(src string, i int) int {
+	if i > len(src) {
+		i = len(src)
+	}
+	return strings.Count(src[:i], "\n") + 1
+}
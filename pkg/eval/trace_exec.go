@@ -0,0 +1,53 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elves/elvish/pkg/eval/vals"
+	"github.com/elves/elvish/pkg/eval/vars"
+)
+
+// TraceExecVars returns the variables that control exec tracing, which prints
+// every command to stderr before it is called, like "set -x" in POSIX shells.
+// The enabled variable is whether exec tracing is enabled, and the prefix
+// variable is the string printed before each command.
+func (ev *Evaler) TraceExecVars() (enabled, prefix vars.Var) {
+	return vars.FromPtrWithMutex(&ev.state.traceExec, &ev.state.mutex),
+		vars.FromPtrWithMutex(&ev.state.traceExecPrefix, &ev.state.mutex)
+}
+
+// Prints a command about to be called if exec tracing is enabled. The command
+// is printed with its arguments and options expanded, followed by where it is
+// in the source.
+func (fm *Frame) traceExec(op *formOp, headFn Callable, args []interface{}, opts map[string]interface{}) {
+	enabled, prefix := fm.state.getTraceExec()
+	if !enabled {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	if name := fnName(headFn); name != "" {
+		sb.WriteString(name)
+	} else {
+		// Anonymous functions are shown as they are written in the source.
+		r := op.headOp.Range()
+		sb.WriteString(fm.srcMeta.Code[r.From:r.To])
+	}
+	for _, arg := range args {
+		sb.WriteByte(' ')
+		sb.WriteString(vals.Repr(arg, vals.NoPretty))
+	}
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " &%s=%s", k, vals.Repr(opts[k], vals.NoPretty))
+	}
+	ctx := fm.srcMeta.Context(op)
+	fmt.Fprintf(&sb, " (%s:%d)\n", ctx.Name, lineOf(ctx.Source, ctx.From))
+	fm.ErrorFile().WriteString(sb.String())
+}
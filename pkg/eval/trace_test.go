@@ -0,0 +1,100 @@
+package eval_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/eval"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []TraceEvent
+}
+
+func (r *eventRecorder) Trace(e TraceEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestAddTracer(t *testing.T) {
+	ev := NewEvaler()
+	r := &eventRecorder{}
+	remove := ev.AddTracer(r)
+
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "fn f { put x }; f; fail bad"}, EvalCfg{})
+	if err == nil {
+		t.Errorf("got nil error, want error from fail")
+	}
+	if len(r.events) != 3 {
+		t.Fatalf("got %d events, want 3", len(r.events))
+	}
+	put, f, fail := r.events[0], r.events[1], r.events[2]
+	if put.Kind != TraceCall || put.Stack.FnName != "put" {
+		t.Errorf("got first event %v %q, want call of put", put.Kind, put.Stack.FnName)
+	}
+	if put.Stack.Next != f.Stack {
+		t.Errorf("stack of put is not nested in stack of f")
+	}
+	if f.Stack.FnName != "f" || f.Stack.Head.Name != "[test]" {
+		t.Errorf("got second event %q from %q, want f from [test]",
+			f.Stack.FnName, f.Stack.Head.Name)
+	}
+	if f.Duration < put.Duration {
+		t.Errorf("f took %v, shorter than the put it called (%v)", f.Duration, put.Duration)
+	}
+	if fail.Stack.FnName != "fail" || fail.Err == nil {
+		t.Errorf("got third event %q with error %v, want fail with error",
+			fail.Stack.FnName, fail.Err)
+	}
+
+	remove()
+	ev.Eval(parse.Source{Name: "[test]", Code: "put x"}, EvalCfg{})
+	if len(r.events) != 3 {
+		t.Errorf("got events after the tracer is removed")
+	}
+}
+
+func TestNewTraceWriter(t *testing.T) {
+	ev := NewEvaler()
+	var buf bytes.Buffer
+	remove := ev.AddTracer(NewTraceWriter(&buf))
+	defer remove()
+
+	ev.Eval(parse.Source{Name: "[test]", Code: "nop\nfail bad"}, EvalCfg{})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var entry map[string]interface{}
+	err := json.Unmarshal([]byte(lines[1]), &entry)
+	if err != nil {
+		t.Fatalf("cannot parse line as JSON: %v", err)
+	}
+	for key, want := range map[string]interface{}{
+		"kind": "call", "name": "fail", "src": "[test]",
+		"line": 2.0, "from": 4.0, "to": 12.0, "error": "bad"} {
+		if entry[key] != want {
+			t.Errorf("got %s = %v, want %v", key, entry[key], want)
+		}
+	}
+	if _, ok := entry["duration_ns"]; !ok {
+		t.Errorf("duration_ns missing")
+	}
+}
+
+func TestTraceEventKind_String(t *testing.T) {
+	for kind, want := range map[TraceEventKind]string{
+		TraceCall: "call", TraceExternalStart: "external-start",
+		TraceExternalEnd: "external-end", 10: "TraceEventKind(10)"} {
+		if got := kind.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -57,3 +57,54 @@ func assocList(l List, k, v interface{}) (interface{}, error) {
 	}
 	return l.Assoc(index.Lower, v), nil
 }
+
+var errEmptyPath = errors.New("path must not be empty")
+
+// AssocIn is like Assoc, but takes a path of keys into nested containers. The
+// container at each level of the path is replaced with a modified version
+// using Assoc. Missing keys of maps along the path are associated with empty
+// maps. If the path is empty, it returns v.
+func AssocIn(a interface{}, path []interface{}, v interface{}) (interface{}, error) {
+	return updateIn(a, path, true, func(interface{}) (interface{}, error) {
+		return v, nil
+	})
+}
+
+// UpdateIn takes a container, a path of keys into nested containers and a
+// function, and returns a modified version of the container in which the value
+// at the path is replaced with the result of calling f with the old value. All
+// the keys along the path must exist. If the path is empty, it returns the
+// result of calling f with a.
+func UpdateIn(a interface{}, path []interface{}, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	return updateIn(a, path, false, f)
+}
+
+func updateIn(a interface{}, path []interface{}, create bool, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return f(a)
+	}
+	k := path[0]
+	var child interface{}
+	if create && isMap(a) && !HasKey(a, k) {
+		child = EmptyMap
+	} else {
+		var err error
+		child, err = Index(a, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	newChild, err := updateIn(child, path[1:], create, f)
+	if err != nil {
+		return nil, err
+	}
+	return Assoc(a, k, newChild)
+}
+
+func isMap(a interface{}) bool {
+	switch a.(type) {
+	case Map, OrderedMap:
+		return true
+	}
+	return false
+}
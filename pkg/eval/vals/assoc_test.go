@@ -44,3 +44,35 @@ func TestAssoc(t *testing.T) {
 		Args(struct{}{}, "x", "y").Rets(nil, errAssocUnsupported),
 	})
 }
+
+func TestAssocIn(t *testing.T) {
+	Test(t, Fn("AssocIn", AssocIn), Table{
+		Args("x", []interface{}{}, "y").Rets("y", nil),
+		Args(MakeMap("a", MakeMap("b", MakeList("x", "y"))), []interface{}{"a", "b", "0"}, "z").
+			Rets(Eq(MakeMap("a", MakeMap("b", MakeList("z", "y")))), nil),
+		// Missing keys of maps are created.
+		Args(EmptyMap, []interface{}{"a", "b"}, "c").
+			Rets(Eq(MakeMap("a", MakeMap("b", "c"))), nil),
+		Args(MakeOrderedMap("z", "1"), []interface{}{"a", "b"}, "c").
+			Rets(Eq(MakeOrderedMap("z", "1", "a", MakeMap("b", "c"))), nil),
+		// Indices of lists must exist.
+		Args(MakeList("x"), []interface{}{"1", "a"}, "c").Rets(nil, errs.OutOfRange{
+			What: "index here", ValidLow: "0", ValidHigh: "0", Actual: "1"}),
+		Args(MakeMap("a", struct{}{}), []interface{}{"a", "b"}, "c").
+			Rets(nil, errNotIndexable),
+	})
+}
+
+func TestUpdateIn(t *testing.T) {
+	appendX := func(v interface{}) (interface{}, error) { return v.(string) + "x", nil }
+	Test(t, Fn("UpdateIn", UpdateIn), Table{
+		Args("a", []interface{}{}, appendX).Rets("ax", nil),
+		Args(MakeMap("a", MakeList("b", "c")), []interface{}{"a", "1"}, appendX).
+			Rets(Eq(MakeMap("a", MakeList("b", "cx"))), nil),
+		// Missing keys are not created.
+		Args(EmptyMap, []interface{}{"a"}, appendX).Rets(nil, NoSuchKey("a")),
+		Args("a", []interface{}{}, func(interface{}) (interface{}, error) {
+			return nil, errCustomAssoc
+		}).Rets(nil, errCustomAssoc),
+	})
+}
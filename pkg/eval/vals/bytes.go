@@ -0,0 +1,74 @@
+package vals
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/xiaq/persistent/hash"
+)
+
+// Bytes is an immutable sequence of bytes. Strings are also sequences of bytes,
+// but indexing a string works on codepoints and requires the string to be
+// valid UTF-8 at the indices; indexing Bytes always works on individual bytes.
+// Converting Bytes to a string with ToString gives the bytes as they are.
+type Bytes string
+
+// Kind returns "bytes".
+func (b Bytes) Kind() string { return "bytes" }
+
+// Equal returns whether other is a Bytes with the same content. Bytes are
+// never equal to strings.
+func (b Bytes) Equal(other interface{}) bool {
+	b2, ok := other.(Bytes)
+	return ok && b == b2
+}
+
+// Hash returns the hash of the content.
+func (b Bytes) Hash() uint32 { return hash.String(string(b)) }
+
+// Repr returns a call of bytes:from-hex that creates an equal Bytes, like
+// "(bytes:from-hex 68656c6c6f)".
+func (b Bytes) Repr(int) string {
+	if b == "" {
+		return "(bytes:from-hex '')"
+	}
+	return "(bytes:from-hex " + hex.EncodeToString([]byte(b)) + ")"
+}
+
+// String returns the content as a string.
+func (b Bytes) String() string { return string(b) }
+
+// Len returns the number of bytes.
+func (b Bytes) Len() int { return len(b) }
+
+// Index returns the byte at an index as a number, or the Bytes in a slice
+// index.
+func (b Bytes) Index(k interface{}) (interface{}, error) {
+	index, err := ConvertListIndex(k, len(b))
+	if err != nil {
+		return nil, err
+	}
+	if index.Slice {
+		return b[index.Lower:index.Upper], nil
+	}
+	return strconv.Itoa(int(b[index.Lower])), nil
+}
+
+// Concat concatenates the bytes with another Bytes or a string.
+func (b Bytes) Concat(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case Bytes:
+		return b + v, nil
+	case string:
+		return b + Bytes(v), nil
+	}
+	return nil, ErrConcatNotImplemented
+}
+
+// RConcat concatenates a string with the bytes.
+func (b Bytes) RConcat(v interface{}) (interface{}, error) {
+	if v, ok := v.(string); ok {
+		return Bytes(v) + b, nil
+	}
+	return nil, ErrConcatNotImplemented
+}
@@ -0,0 +1,37 @@
+package vals
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/tt"
+	"github.com/xiaq/persistent/hash"
+)
+
+func TestBytes(t *testing.T) {
+	TestValue(t, Bytes("a\xffc")).
+		Kind("bytes").
+		Bool(true).
+		Hash(hash.String("a\xffc")).
+		Repr("(bytes:from-hex 61ff63)").
+		Len(3).
+		Equal(Bytes("a\xffc")).
+		NotEqual("a\xffc", Bytes("abc")).
+		Index("1", "255").
+		Index("-1", "99").
+		Index("1..", Bytes("\xffc")).
+		Index("0..=1", Bytes("a\xff")).
+		IndexError("3", posIndexOutOfRange("3", 3))
+
+	TestValue(t, Bytes("")).
+		Repr("(bytes:from-hex '')").
+		Len(0)
+
+	tt.Test(t, tt.Fn("Concat", Concat), tt.Table{
+		tt.Args(Bytes("a"), Bytes("\xff")).Rets(Bytes("a\xff"), nil),
+		tt.Args(Bytes("a"), "b").Rets(Bytes("ab"), nil),
+		tt.Args("a", Bytes("b")).Rets(Bytes("ab"), nil),
+	})
+	tt.Test(t, tt.Fn("ToString", ToString), tt.Table{
+		tt.Args(Bytes("a\xff")).Rets("a\xff"),
+	})
+}
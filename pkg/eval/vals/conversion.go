@@ -3,6 +3,7 @@ package vals
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
 	"unicode/utf8"
@@ -48,6 +49,7 @@ var (
 	errMustHaveSingleRune = errors.New("must have a single rune")
 	errMustBeNumber       = errors.New("must be number")
 	errMustBeInteger      = errors.New("must be integer")
+	errIntegerTooLarge    = errors.New("integer too large")
 )
 
 // ScanToGo converts an Elvish value to a Go value. the pointer points to. It
@@ -121,11 +123,13 @@ func elvToFloat(arg interface{}) (float64, error) {
 		if err == nil {
 			return f, nil
 		}
-		i, err := strconv.ParseInt(arg, 0, 64)
+		n, err := ScanNum(arg)
 		if err == nil {
-			return float64(i), err
+			return NumToFloat64(n), nil
 		}
 		return 0, cannotParseAs{"number", Repr(arg, -1)}
+	case *big.Int, *big.Rat:
+		return NumToFloat64(arg), nil
 	default:
 		return 0, errMustBeNumber
 	}
@@ -145,6 +149,11 @@ func elvToInt(arg interface{}) (int, error) {
 			return int(num), nil
 		}
 		return 0, cannotParseAs{"integer", Repr(arg, -1)}
+	case *big.Int:
+		if !arg.IsInt64() || int64(int(arg.Int64())) != arg.Int64() {
+			return 0, errIntegerTooLarge
+		}
+		return int(arg.Int64()), nil
 	default:
 		return 0, errMustBeInteger
 	}
@@ -1,6 +1,7 @@
 package vals
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -27,12 +28,18 @@ func TestScanToGo(t *testing.T) {
 		Args(12.0, 0).Rets(12),
 		Args("23", 0.0).Rets(23.0),
 		Args("0x23", 0.0).Rets(float64(0x23)),
+		Args("1/2", 0.0).Rets(0.5),
+		Args(big.NewInt(12), 0).Rets(12),
+		Args(big.NewInt(12), 0.0).Rets(12.0),
+		Args(big.NewRat(1, 2), 0.0).Rets(0.5),
 		Args("x", ' ').Rets('x'),
 		Args("foo", "").Rets("foo"),
 		Args(someType{"foo"}, someType{}).Rets(someType{"foo"}),
 		Args(nil, nil).Rets(nil),
 
 		Args(0.5, 0).Rets(0, errMustBeInteger),
+		Args(new(big.Int).Lsh(big.NewInt(1), 64), 0).Rets(0, errIntegerTooLarge),
+		Args(big.NewRat(1, 2), 0).Rets(0, errMustBeInteger),
 		Args("x", someType{}).Rets(Any, wrongType{"!!vals.someType", "string"}),
 		Args(someType{}, 0).Rets(Any, errMustBeInteger),
 		Args("x", 0).Rets(Any, cannotParseAs{"integer", "x"}),
@@ -1,5 +1,7 @@
 package vals
 
+import "errors"
+
 // Dissocer wraps the Dissoc method.
 type Dissocer interface {
 	// Dissoc returns a slightly modified version of the receiver with key k
@@ -7,6 +9,8 @@ type Dissocer interface {
 	Dissoc(k interface{}) interface{}
 }
 
+var errDissocUnsupported = errors.New("dissoc is not supported")
+
 // Dissoc takes a container and a key, and returns a modified version of the
 // container, with the given key dissociated with any value. It is implemented
 // for the Map type and types satisfying the Dissocer interface. For other
@@ -21,3 +25,34 @@ func Dissoc(a, k interface{}) interface{} {
 		return nil
 	}
 }
+
+// DissocIn is like Dissoc, but takes a path of keys into nested containers,
+// and dissociates the last key from the container it leads to. The containers
+// along the path are replaced with modified versions using Assoc. If a key of a
+// map along the path is missing, it returns a unchanged. It returns an error if
+// the path is empty, or the last container does not support Dissoc.
+func DissocIn(a interface{}, path []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errEmptyPath
+	}
+	k := path[0]
+	if len(path) == 1 {
+		a2 := Dissoc(a, k)
+		if a2 == nil {
+			return nil, errDissocUnsupported
+		}
+		return a2, nil
+	}
+	if isMap(a) && !HasKey(a, k) {
+		return a, nil
+	}
+	child, err := Index(a, k)
+	if err != nil {
+		return nil, err
+	}
+	newChild, err := DissocIn(child, path[1:])
+	if err != nil {
+		return nil, err
+	}
+	return Assoc(a, k, newChild)
+}
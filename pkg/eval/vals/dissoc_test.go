@@ -17,3 +17,16 @@ func TestDissoc(t *testing.T) {
 		Args("", "x").Rets(nil),
 	})
 }
+
+func TestDissocIn(t *testing.T) {
+	Test(t, Fn("DissocIn", DissocIn), Table{
+		Args(MakeMap("a", MakeMap("b", "c", "d", "e")), []interface{}{"a", "b"}).
+			Rets(Eq(MakeMap("a", MakeMap("d", "e"))), nil),
+		Args(MakeList(MakeMap("a", "b")), []interface{}{"0", "a"}).
+			Rets(Eq(MakeList(EmptyMap)), nil),
+		// Missing keys leave the container unchanged.
+		Args(MakeMap("a", "b"), []interface{}{"x", "y"}).Rets(Eq(MakeMap("a", "b")), nil),
+		Args(MakeMap("a", "b"), []interface{}{}).Rets(nil, errEmptyPath),
+		Args(MakeMap("a", "b"), []interface{}{"a", "x"}).Rets(nil, errDissocUnsupported),
+	})
+}
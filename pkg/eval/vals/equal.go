@@ -1,6 +1,7 @@
 package vals
 
 import (
+	"math/big"
 	"reflect"
 )
 
@@ -23,6 +24,10 @@ func Equal(x, y interface{}) bool {
 		return x == y
 	case float64:
 		return x == y
+	case *big.Int:
+		return equalBigInt(x, y)
+	case *big.Rat:
+		return equalBigRat(x, y)
 	case string:
 		return x == y
 	case Equaler:
@@ -1,6 +1,7 @@
 package vals
 
 import (
+	"math/big"
 	"os"
 	"testing"
 
@@ -23,6 +24,13 @@ func TestEqual(t *testing.T) {
 
 		Args(1.0, 1.0).Rets(true),
 
+		Args(big.NewInt(10), big.NewInt(10)).Rets(true),
+		Args(big.NewInt(10), big.NewInt(11)).Rets(false),
+		Args(big.NewInt(10), 10.0).Rets(false),
+		Args(big.NewRat(1, 2), big.NewRat(2, 4)).Rets(true),
+		Args(big.NewRat(1, 2), big.NewRat(1, 3)).Rets(false),
+		Args(big.NewRat(1, 2), 0.5).Rets(false),
+
 		Args("lorem", "lorem").Rets(true),
 		Args("lorem", "ipsum").Rets(false),
 
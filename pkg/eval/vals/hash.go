@@ -2,6 +2,7 @@ package vals
 
 import (
 	"math"
+	"math/big"
 	"reflect"
 
 	"github.com/xiaq/persistent/hash"
@@ -26,6 +27,8 @@ func Hash(v interface{}) uint32 {
 		return 0
 	case float64:
 		return hash.UInt64(math.Float64bits(v))
+	case *big.Int, *big.Rat:
+		return hash.String(ToString(v))
 	case string:
 		return hash.String(v)
 	case Hasher:
@@ -2,6 +2,7 @@ package vals
 
 import (
 	"math"
+	"math/big"
 	"os"
 	"testing"
 
@@ -21,6 +22,8 @@ func TestHash(t *testing.T) {
 		Args(true).Rets(uint32(1)),
 		Args(1.0).Rets(hash.UInt64(math.Float64bits(1.0))),
 		Args("foo").Rets(hash.String("foo")),
+		Args(big.NewInt(10)).Rets(hash.String("10")),
+		Args(big.NewRat(1, 3)).Rets(hash.String("1/3")),
 		Args(os.Stdin).Rets(hash.UIntPtr(os.Stdin.Fd())),
 		Args(MakeList("foo", "bar")).Rets(hash.DJB(Hash("foo"), Hash("bar"))),
 		Args(MakeMap("foo", "bar")).
@@ -105,7 +105,8 @@ func ConvertListIndex(rawIndex interface{}, n int) (*ListIndex, error) {
 }
 
 // Index = Number |
-//         Number ( ':' | '..' | '..=' ) Number
+//
+//	Number ( ':' | '..' | '..=' ) Number
 func parseIndexString(s string, n int) (slice bool, i int, j int, err error) {
 	low, sep, high := splitIndexString(s)
 	if sep == "" {
@@ -2,6 +2,7 @@ package vals
 
 import (
 	"fmt"
+	"math/big"
 )
 
 // Kinder wraps the Kind method.
@@ -27,7 +28,7 @@ func Kind(v interface{}) string {
 		return "bool"
 	case string:
 		return "string"
-	case float64:
+	case float64, *big.Int, *big.Rat:
 		return "number"
 	case Kinder:
 		return v.Kind()
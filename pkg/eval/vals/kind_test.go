@@ -1,6 +1,7 @@
 package vals
 
 import (
+	"math/big"
 	"os"
 	"testing"
 
@@ -15,6 +16,8 @@ func TestKind(t *testing.T) {
 		Args(true).Rets("bool"),
 		Args("").Rets("string"),
 		Args(1.0).Rets("number"),
+		Args(big.NewInt(1)).Rets("number"),
+		Args(big.NewRat(1, 2)).Rets("number"),
 		Args(os.Stdin).Rets("file"),
 		Args(EmptyList).Rets("list"),
 		Args(EmptyMap).Rets("map"),
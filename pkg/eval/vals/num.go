@@ -0,0 +1,154 @@
+package vals
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Numbers.
+//
+// Elvish numbers are usually float64 values, or strings that can be parsed as
+// numbers. Arithmetic on integers and rationals is exact: when the exact result
+// is an integer that can't be represented by a float64 without losing
+// precision, it is a *big.Int; when it is a fraction that arises from rational
+// arguments like "1/3", it is a *big.Rat. Conversely, a float64 that is an
+// integer within ±2^53 is treated as an exact integer, so that exact results
+// stay exact when used again. All of these values have kind "number".
+
+// Largest integer such that all integers with a smaller absolute value can be
+// represented exactly by a float64.
+const maxExactFloat64 = 1 << 53
+
+var (
+	bigMaxExactFloat64 = big.NewInt(maxExactFloat64)
+	bigMinExactFloat64 = big.NewInt(-maxExactFloat64)
+)
+
+// ScanNum converts an Elvish value to a number suitable for arithmetic. It
+// returns a float64 for inexact numbers, and a *big.Int or *big.Rat for exact
+// ones. Strings are exact when they are integers like "10" or "0x10", or
+// fractions like "1/3". Integers are decimal unless they have a "0x", "0o" or
+// "0b" prefix; a leading zero doesn't make them octal.
+//
+// The returned *big.Int and *big.Rat values may be shared, and must not be
+// modified.
+func ScanNum(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case float64, *big.Int, *big.Rat:
+		return v, nil
+	case string:
+		if n, ok := parseBigInt(v); ok {
+			return n, nil
+		}
+		if i := strings.IndexByte(v, '/'); i != -1 {
+			num, ok1 := parseBigInt(v[:i])
+			denom, ok2 := parseBigInt(v[i+1:])
+			if ok1 && ok2 && denom.Sign() != 0 {
+				return new(big.Rat).SetFrac(num, denom), nil
+			}
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+		return nil, cannotParseAs{"number", Repr(v, -1)}
+	default:
+		return nil, errMustBeNumber
+	}
+}
+
+// Parses an integer with an optional sign. It is in base 10 unless it has a
+// "0x", "0o" or "0b" prefix.
+func parseBigInt(s string) (*big.Int, bool) {
+	digits := s
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		digits = digits[1:]
+	}
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return new(big.Int).SetString(s, 0)
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// NormalizeBigInt returns n as a float64 if it can be represented exactly by
+// one, and n itself otherwise.
+func NormalizeBigInt(n *big.Int) interface{} {
+	if n.Cmp(bigMinExactFloat64) >= 0 && n.Cmp(bigMaxExactFloat64) <= 0 {
+		return float64(n.Int64())
+	}
+	return n
+}
+
+// NormalizeBigRat returns r as an integer normalized with NormalizeBigInt if it
+// is an integer, and r itself otherwise.
+func NormalizeBigRat(r *big.Rat) interface{} {
+	if r.IsInt() {
+		return NormalizeBigInt(new(big.Int).Set(r.Num()))
+	}
+	return r
+}
+
+// NumToFloat64 converts a number returned by ScanNum to a float64, possibly
+// losing precision.
+func NumToFloat64(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	default:
+		return math.NaN()
+	}
+}
+
+// NumToBigRat converts an exact number returned by ScanNum to a *big.Rat. A
+// float64 is exact if it is an integer within ±2^53, since exact integer
+// results in that range are normalized to float64 values by NormalizeBigInt.
+// It returns nil for other float64 values.
+func NumToBigRat(v interface{}) *big.Rat {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxExactFloat64 {
+			return new(big.Rat).SetInt64(int64(v))
+		}
+		return nil
+	case *big.Int:
+		return new(big.Rat).SetInt(v)
+	case *big.Rat:
+		return v
+	default:
+		return nil
+	}
+}
+
+func equalBigInt(x *big.Int, y interface{}) bool {
+	yy, ok := y.(*big.Int)
+	return ok && x.Cmp(yy) == 0
+}
+
+func equalBigRat(x *big.Rat, y interface{}) bool {
+	yy, ok := y.(*big.Rat)
+	return ok && x.Cmp(yy) == 0
+}
+
+// CmpNum compares two numbers returned by ScanNum, and returns -1, 0 or 1
+// like (*big.Int).Cmp. Exact numbers are compared exactly; otherwise both
+// numbers are converted to float64. The result is 0 if either number is NaN.
+func CmpNum(x, y interface{}) int {
+	if xr, yr := NumToBigRat(x), NumToBigRat(y); xr != nil && yr != nil {
+		return xr.Cmp(yr)
+	}
+	xf, yf := NumToFloat64(x), NumToFloat64(y)
+	switch {
+	case xf < yf:
+		return -1
+	case xf > yf:
+		return 1
+	default:
+		return 0
+	}
+}
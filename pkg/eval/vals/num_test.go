@@ -0,0 +1,94 @@
+package vals
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/tt"
+)
+
+// Converts the result of ScanNum to a string that is easy to compare.
+func scanNumString(v interface{}) (string, error) {
+	n, err := ScanNum(v)
+	switch n := n.(type) {
+	case float64:
+		return "float64 " + formatFloat64(n), err
+	case *big.Int:
+		return "big.Int " + n.String(), err
+	case *big.Rat:
+		return "big.Rat " + n.String(), err
+	default:
+		return "", err
+	}
+}
+
+func TestScanNum(t *testing.T) {
+	Test(t, Fn("scanNumString", scanNumString), Table{
+		Args("10").Rets("big.Int 10", nil),
+		Args("0x10").Rets("big.Int 16", nil),
+		Args("-0x10").Rets("big.Int -16", nil),
+		Args("0o10").Rets("big.Int 8", nil),
+		Args("0b10").Rets("big.Int 2", nil),
+		// Leading zeros don't make numbers octal.
+		Args("010").Rets("big.Int 10", nil),
+		Args("-010").Rets("big.Int -10", nil),
+		Args("09").Rets("big.Int 9", nil),
+		Args("010/3").Rets("big.Rat 10/3", nil),
+		Args("0x10/3").Rets("big.Rat 16/3", nil),
+		Args("010.5").Rets("float64 10.5", nil),
+		Args("100000000000000000000").Rets("big.Int 100000000000000000000", nil),
+		Args("1/3").Rets("big.Rat 1/3", nil),
+		Args("2/4").Rets("big.Rat 1/2", nil),
+		Args("1.5").Rets("float64 1.5", nil),
+		Args("1e3").Rets("float64 1000", nil),
+		Args("Inf").Rets("float64 +Inf", nil),
+		Args(1.5).Rets("float64 1.5", nil),
+		Args(big.NewInt(10)).Rets("big.Int 10", nil),
+
+		Args("x").Rets("", cannotParseAs{"number", "x"}),
+		Args("1/x").Rets("", cannotParseAs{"number", "1/x"}),
+		Args("1/0").Rets("", cannotParseAs{"number", "1/0"}),
+		Args("0x").Rets("", cannotParseAs{"number", "0x"}),
+		Args(true).Rets("", errMustBeNumber),
+	})
+}
+
+var (
+	maxExact    = big.NewInt(maxExactFloat64)
+	maxExactInc = new(big.Int).Add(maxExact, big.NewInt(1))
+)
+
+func TestNormalizeBigInt(t *testing.T) {
+	Test(t, Fn("NormalizeBigInt", NormalizeBigInt), Table{
+		Args(big.NewInt(10)).Rets(10.0),
+		Args(maxExact).Rets(float64(maxExactFloat64)),
+		Args(maxExactInc).Rets(maxExactInc),
+	})
+}
+
+func TestNormalizeBigRat(t *testing.T) {
+	Test(t, Fn("NormalizeBigRat", NormalizeBigRat), Table{
+		Args(big.NewRat(4, 2)).Rets(2.0),
+		Args(big.NewRat(1, 2)).Rets(big.NewRat(1, 2)),
+	})
+}
+
+func TestNumToFloat64(t *testing.T) {
+	Test(t, Fn("NumToFloat64", NumToFloat64), Table{
+		Args(1.5).Rets(1.5),
+		Args(big.NewInt(10)).Rets(10.0),
+		Args(big.NewRat(1, 4)).Rets(0.25),
+	})
+}
+
+func TestCmpNum(t *testing.T) {
+	Test(t, Fn("CmpNum", CmpNum), Table{
+		Args(1.0, 2.0).Rets(-1),
+		Args(big.NewInt(2), 1.5).Rets(1),
+		Args(maxExactInc, maxExact).Rets(1),
+		Args(big.NewRat(1, 3), big.NewRat(2, 6)).Rets(0),
+		Args(big.NewRat(1, 3), big.NewInt(0)).Rets(1),
+		Args(math.NaN(), 1.0).Rets(0),
+	})
+}
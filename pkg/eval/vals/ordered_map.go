@@ -0,0 +1,140 @@
+package vals
+
+import (
+	"github.com/xiaq/persistent/hash"
+	"github.com/xiaq/persistent/vector"
+)
+
+// OrderedMap is a persistent map that remembers the order in which its keys
+// were first inserted. Iterating its keys, its Repr and its conversion to JSON
+// all follow that order. Changing the value of an existing key does not
+// change its position.
+//
+// The zero value is not usable; start from EmptyOrderedMap instead.
+type OrderedMap struct {
+	m    Map
+	keys vector.Vector
+}
+
+// EmptyOrderedMap is an empty OrderedMap.
+var EmptyOrderedMap = OrderedMap{EmptyMap, vector.Empty}
+
+// MakeOrderedMap creates an OrderedMap from arguments that are alternately
+// keys and values. It panics if the number of arguments is odd.
+func MakeOrderedMap(a ...interface{}) OrderedMap {
+	if len(a)%2 == 1 {
+		panic("odd number of arguments to MakeOrderedMap")
+	}
+	m := EmptyOrderedMap
+	for i := 0; i < len(a); i += 2 {
+		m = m.Put(a[i], a[i+1])
+	}
+	return m
+}
+
+// Kind returns "ordered-map".
+func (m OrderedMap) Kind() string { return "ordered-map" }
+
+// Equal returns whether other is an OrderedMap with equal pairs in the same
+// order.
+func (m OrderedMap) Equal(other interface{}) bool {
+	m2, ok := other.(OrderedMap)
+	if !ok || m.Len() != m2.Len() {
+		return false
+	}
+	it2 := m2.keys.Iterator()
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		k, k2 := it.Elem(), it2.Elem()
+		v, _ := m.m.Index(k)
+		v2, _ := m2.m.Index(k2)
+		if !Equal(k, k2) || !Equal(v, v2) {
+			return false
+		}
+		it2.Next()
+	}
+	return true
+}
+
+// Hash returns the hash of the pairs, in order.
+func (m OrderedMap) Hash() uint32 {
+	h := hash.DJBInit
+	m.IteratePairs(func(k, v interface{}) bool {
+		h = hash.DJBCombine(h, Hash(k))
+		h = hash.DJBCombine(h, Hash(v))
+		return true
+	})
+	return h
+}
+
+// Repr returns a call of make-ordered-map that creates an equal OrderedMap,
+// like "(make-ordered-map [[a 1] [b 2]])".
+func (m OrderedMap) Repr(indent int) string {
+	b := NewListReprBuilder(indent)
+	m.IteratePairs(func(k, v interface{}) bool {
+		b.WriteElem("[" + Repr(k, NoPretty) + " " + Repr(v, NoPretty) + "]")
+		return true
+	})
+	return "(make-ordered-map " + b.String() + ")"
+}
+
+// Len returns the number of pairs.
+func (m OrderedMap) Len() int { return m.m.Len() }
+
+// Index returns the value associated with k.
+func (m OrderedMap) Index(k interface{}) (interface{}, bool) { return m.m.Index(k) }
+
+// HasKey returns whether k is a key of the map.
+func (m OrderedMap) HasKey(k interface{}) bool {
+	_, ok := m.m.Index(k)
+	return ok
+}
+
+// IterateKeys calls f with the keys, in insertion order.
+func (m OrderedMap) IterateKeys(f func(interface{}) bool) {
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		if !f(it.Elem()) {
+			return
+		}
+	}
+}
+
+// IteratePairs calls f with the keys and their values, in insertion order.
+func (m OrderedMap) IteratePairs(f func(k, v interface{}) bool) {
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		k := it.Elem()
+		v, _ := m.m.Index(k)
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// Put returns a copy of the map with k associated with v. A new key is added
+// at the end.
+func (m OrderedMap) Put(k, v interface{}) OrderedMap {
+	keys := m.keys
+	if !m.HasKey(k) {
+		keys = keys.Cons(k)
+	}
+	return OrderedMap{m.m.Assoc(k, v), keys}
+}
+
+// Assoc implements the Assocer interface.
+func (m OrderedMap) Assoc(k, v interface{}) (interface{}, error) {
+	return m.Put(k, v), nil
+}
+
+// Dissoc returns a copy of the map with k removed. The order of the remaining
+// keys is unchanged.
+func (m OrderedMap) Dissoc(k interface{}) interface{} {
+	if !m.HasKey(k) {
+		return m
+	}
+	keys := vector.Empty
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		if !Equal(it.Elem(), k) {
+			keys = keys.Cons(it.Elem())
+		}
+	}
+	return OrderedMap{m.m.Dissoc(k), keys}
+}
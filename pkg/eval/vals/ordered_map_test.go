@@ -0,0 +1,42 @@
+package vals
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/tt"
+	"github.com/xiaq/persistent/hash"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := MakeOrderedMap("z", "1", "a", "2")
+
+	TestValue(t, m).
+		Kind("ordered-map").
+		Bool(true).
+		Hash(hash.DJB(Hash("z"), Hash("1"), Hash("a"), Hash("2"))).
+		Repr("(make-ordered-map [[z 1] [a 2]])").
+		Len(2).
+		Equal(MakeOrderedMap("z", "1", "a", "2")).
+		NotEqual(MakeOrderedMap("a", "2", "z", "1"), MakeOrderedMap("z", "1"),
+			MakeOrderedMap("z", "1", "a", "3"), MakeMap("z", "1", "a", "2")).
+		HasKey("z", "a").
+		HasNoKey("b").
+		AllKeys("z", "a").
+		Index("a", "2").
+		IndexError("b", NoSuchKey("b"))
+
+	TestValue(t, EmptyOrderedMap).
+		Repr("(make-ordered-map [])").
+		Len(0)
+
+	tt.Test(t, tt.Fn("Assoc", Assoc), tt.Table{
+		// Existing keys keep their positions.
+		tt.Args(m, "z", "3").Rets(Eq(MakeOrderedMap("z", "3", "a", "2")), nil),
+		// New keys are added at the end.
+		tt.Args(m, "m", "3").Rets(Eq(MakeOrderedMap("z", "1", "a", "2", "m", "3")), nil),
+	})
+	tt.Test(t, tt.Fn("Dissoc", Dissoc), tt.Table{
+		tt.Args(m, "z").Rets(Eq(MakeOrderedMap("a", "2"))),
+		tt.Args(m, "b").Rets(Eq(m)),
+	})
+}
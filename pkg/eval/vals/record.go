@@ -0,0 +1,179 @@
+package vals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elves/elvish/pkg/parse"
+	"github.com/xiaq/persistent/hash"
+)
+
+// RecordType is a named type of records with a fixed list of fields. Two
+// record types with the same name and fields are still distinct types.
+type RecordType struct {
+	name   string
+	fields []string
+	index  map[string]int
+}
+
+var errEmptyRecordName = errors.New("record type name must not be empty")
+
+// NewRecordType creates a new record type. The name must not be empty, and
+// the field names must be distinct.
+func NewRecordType(name string, fields ...string) (*RecordType, error) {
+	if name == "" {
+		return nil, errEmptyRecordName
+	}
+	index := make(map[string]int, len(fields))
+	for i, field := range fields {
+		if _, dup := index[field]; dup {
+			return nil, fmt.Errorf("duplicate field %s in record type %s",
+				parse.Quote(field), parse.Quote(name))
+		}
+		index[field] = i
+	}
+	return &RecordType{name, append([]string(nil), fields...), index}, nil
+}
+
+// Name returns the name of the record type, which is also the kind of its
+// records.
+func (t *RecordType) Name() string { return t.name }
+
+// FieldNames returns the names of the fields of the record type. The returned
+// slice must not be modified.
+func (t *RecordType) FieldNames() []string { return t.fields }
+
+// HasField returns whether the record type has a field with the given name.
+func (t *RecordType) HasField(name string) bool {
+	_, ok := t.index[name]
+	return ok
+}
+
+// Kind returns "record-type".
+func (t *RecordType) Kind() string { return "record-type" }
+
+// Repr returns the name and fields of the record type.
+func (t *RecordType) Repr(int) string {
+	quoted := make([]string, len(t.fields))
+	for i, field := range t.fields {
+		quoted[i] = parse.Quote(field)
+	}
+	return fmt.Sprintf("<record-type %s [%s]>", parse.Quote(t.name), strings.Join(quoted, " "))
+}
+
+// New creates a record of the type with values of the fields, in the same
+// order as the fields.
+func (t *RecordType) New(values ...interface{}) (Record, error) {
+	if len(values) != len(t.fields) {
+		return Record{}, fmt.Errorf("record type %s has %d fields, got %d values",
+			parse.Quote(t.name), len(t.fields), len(values))
+	}
+	return Record{t, append([]interface{}(nil), values...)}, nil
+}
+
+// Record is a value of a record type. It behaves like a read-only map whose
+// keys are the names of the fields, and can be iterated to get the values of
+// the fields in order.
+type Record struct {
+	t      *RecordType
+	values []interface{}
+}
+
+// Type returns the type of the record.
+func (r Record) Type() *RecordType { return r.t }
+
+// Kind returns the name of the record type.
+func (r Record) Kind() string { return r.t.name }
+
+// Equal returns whether other is a record of the same type with equal values.
+func (r Record) Equal(other interface{}) bool {
+	r2, ok := other.(Record)
+	if !ok || r.t != r2.t {
+		return false
+	}
+	for i, v := range r.values {
+		if !Equal(v, r2.values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns the hash of the name of the type and the values.
+func (r Record) Hash() uint32 {
+	h := hash.DJBCombine(hash.DJBInit, hash.String(r.t.name))
+	for _, v := range r.values {
+		h = hash.DJBCombine(h, Hash(v))
+	}
+	return h
+}
+
+// Repr returns a call of the constructor of the record type with the values
+// as options, like "(point &x=(num 1) &y=(num 2))".
+func (r Record) Repr(indent int) string {
+	var sb strings.Builder
+	sb.WriteString("(" + parse.Quote(r.t.name))
+	for i, field := range r.t.fields {
+		sb.WriteString(" &" + parse.Quote(field) + "=" + Repr(r.values[i], NoPretty))
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// Len returns the number of fields.
+func (r Record) Len() int { return len(r.values) }
+
+// Index returns the value of a field.
+func (r Record) Index(k interface{}) (interface{}, bool) {
+	i, ok := r.fieldIndex(k)
+	if !ok {
+		return nil, false
+	}
+	return r.values[i], true
+}
+
+// HasKey returns whether k is the name of a field.
+func (r Record) HasKey(k interface{}) bool {
+	_, ok := r.fieldIndex(k)
+	return ok
+}
+
+// IterateKeys calls f with the names of the fields, in order.
+func (r Record) IterateKeys(f func(interface{}) bool) {
+	for _, field := range r.t.fields {
+		if !f(field) {
+			return
+		}
+	}
+}
+
+// Iterate calls f with the values of the fields, in order.
+func (r Record) Iterate(f func(interface{}) bool) {
+	for _, v := range r.values {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Assoc returns a copy of the record with the value of a field replaced. The
+// key must be the name of an existing field.
+func (r Record) Assoc(k, v interface{}) (interface{}, error) {
+	i, ok := r.fieldIndex(k)
+	if !ok {
+		return nil, NoSuchKey(k)
+	}
+	values := append([]interface{}(nil), r.values...)
+	values[i] = v
+	return Record{r.t, values}, nil
+}
+
+func (r Record) fieldIndex(k interface{}) (int, bool) {
+	field, ok := k.(string)
+	if !ok {
+		return 0, false
+	}
+	i, ok := r.t.index[field]
+	return i, ok
+}
@@ -0,0 +1,68 @@
+package vals
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elves/elvish/pkg/tt"
+	"github.com/xiaq/persistent/hash"
+)
+
+func TestRecord(t *testing.T) {
+	point, _ := NewRecordType("point", "x", "y")
+	point2, _ := NewRecordType("point", "x", "y")
+	p, _ := point.New("1", "2")
+	p2, _ := point2.New("1", "2")
+
+	TestValue(t, p).
+		Kind("point").
+		Bool(true).
+		Hash(hash.DJB(Hash("point"), Hash("1"), Hash("2"))).
+		Repr("(point &x=1 &y=2)").
+		Len(2).
+		Equal(mustNewRecord(point, "1", "2")).
+		// Record types are nominal.
+		NotEqual(p2, mustNewRecord(point, "1", "3"), MakeMap("x", "1", "y", "2")).
+		HasKey("x", "y").
+		HasNoKey("z", 1.0).
+		AllKeys("x", "y").
+		Index("x", "1").
+		Index("y", "2").
+		IndexError("z", NoSuchKey("z"))
+
+	TestValue(t, point).
+		Kind("record-type").
+		Repr("<record-type point [x y]>")
+
+	tt.Test(t, tt.Fn("Collect", Collect), tt.Table{
+		tt.Args(p).Rets([]interface{}{"1", "2"}, nil),
+	})
+	tt.Test(t, tt.Fn("Assoc", Assoc), tt.Table{
+		tt.Args(p, "y", "3").Rets(mustNewRecord(point, "1", "3"), nil),
+		tt.Args(p, "z", "3").Rets(nil, NoSuchKey("z")),
+	})
+}
+
+func TestRecordType(t *testing.T) {
+	point, _ := NewRecordType("point", "x", "y")
+	tt.Test(t, tt.Fn("NewRecordType", NewRecordType), tt.Table{
+		tt.Args("").Rets((*RecordType)(nil), errEmptyRecordName),
+		tt.Args("p", "x", "x").Rets((*RecordType)(nil),
+			errors.New("duplicate field x in record type p")),
+	})
+	tt.Test(t, tt.Fn("New", point.New), tt.Table{
+		tt.Args("1").Rets(Record{},
+			errors.New("record type point has 2 fields, got 1 values")),
+	})
+	if point.Name() != "point" || len(point.FieldNames()) != 2 {
+		t.Errorf("got name %q and fields %q", point.Name(), point.FieldNames())
+	}
+}
+
+func mustNewRecord(t *RecordType, values ...interface{}) Record {
+	r, err := t.New(values...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
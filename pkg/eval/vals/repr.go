@@ -3,6 +3,7 @@ package vals
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 
 	"github.com/elves/elvish/pkg/parse"
@@ -44,6 +45,8 @@ func Repr(v interface{}, indent int) string {
 		return parse.Quote(v)
 	case float64:
 		return "(float64 " + formatFloat64(v) + ")"
+	case *big.Int, *big.Rat:
+		return "(num " + ToString(v) + ")"
 	case Reprer:
 		return v.Repr(indent)
 	case File:
@@ -2,6 +2,7 @@ package vals
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"testing"
 
@@ -24,6 +25,8 @@ func TestRepr(t *testing.T) {
 		Args("foo").Rets("foo"),
 		Args(1.0).Rets("(float64 1)"),
 		Args(1e10).Rets("(float64 10000000000)"),
+		Args(big.NewInt(10)).Rets("(num 10)"),
+		Args(big.NewRat(1, 3)).Rets("(num 1/3)"),
 		Args(os.Stdin).Rets(
 			fmt.Sprintf("<file{%s %d}>", os.Stdin.Name(), os.Stdin.Fd())),
 		Args(EmptyList).Rets("[]"),
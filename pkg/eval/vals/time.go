@@ -0,0 +1,89 @@
+package vals
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/xiaq/persistent/hash"
+)
+
+// Time is a point in time. It wraps time.Time, and two Time values are equal
+// when they represent the same instant, even if they are in different time
+// zones.
+type Time struct{ time.Time }
+
+// Kind returns "time".
+func (t Time) Kind() string { return "time" }
+
+// Equal returns whether other is a Time representing the same instant.
+func (t Time) Equal(other interface{}) bool {
+	t2, ok := other.(Time)
+	return ok && t.Time.Equal(t2.Time)
+}
+
+// Hash returns the hash of the instant.
+func (t Time) Hash() uint32 { return hash.UInt64(uint64(t.UnixNano())) }
+
+// Repr returns a call of time:parse that creates an equal Time, like
+// "(time:parse 2021-01-02T03:04:05Z)".
+func (t Time) Repr(int) string { return "(time:parse " + t.String() + ")" }
+
+// String returns the time in the RFC 3339 format, with fractional seconds if
+// they are not zero.
+func (t Time) String() string { return t.Format(time.RFC3339Nano) }
+
+// Duration is an amount of time. It wraps time.Duration.
+type Duration struct{ time.Duration }
+
+// Kind returns "duration".
+func (d Duration) Kind() string { return "duration" }
+
+// Equal returns whether other is the same Duration.
+func (d Duration) Equal(other interface{}) bool {
+	d2, ok := other.(Duration)
+	return ok && d == d2
+}
+
+// Hash returns the hash of the number of nanoseconds.
+func (d Duration) Hash() uint32 { return hash.UInt64(uint64(d.Duration)) }
+
+// Repr returns a call of time:duration that creates an equal Duration, like
+// "(time:duration 1h30m0s)".
+func (d Duration) Repr(int) string { return "(time:duration " + d.String() + ")" }
+
+// MarshalJSON encodes the Duration as a JSON string like "1h30m0s", in the same
+// way a Time is encoded as a JSON string.
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+var errMustBeDuration = errors.New("must be duration, number or string")
+
+// ScanDuration converts an Elvish value to a time.Duration. A Duration is
+// converted as is; a number, or a string that can be parsed as a number, is
+// taken as a number of seconds; any other string is parsed with
+// time.ParseDuration, like "1h30m" or "300ms".
+func ScanDuration(v interface{}) (time.Duration, error) {
+	switch v := v.(type) {
+	case Duration:
+		return v.Duration, nil
+	case float64, *big.Int, *big.Rat:
+		return secondsToDuration(NumToFloat64(v)), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return secondsToDuration(f), nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, cannotParseAs{"duration", Repr(v, -1)}
+		}
+		return d, nil
+	default:
+		return 0, errMustBeDuration
+	}
+}
+
+func secondsToDuration(f float64) time.Duration {
+	return time.Duration(f * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package vals
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/elves/elvish/pkg/tt"
+	"github.com/xiaq/persistent/hash"
+)
+
+func TestTime(t *testing.T) {
+	utc := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	plus8 := utc.In(time.FixedZone("", 8*3600))
+
+	TestValue(t, Time{utc}).
+		Kind("time").
+		Bool(true).
+		Hash(hash.UInt64(uint64(utc.UnixNano()))).
+		Repr("(time:parse 2021-01-02T03:04:05Z)").
+		Equal(Time{utc}).
+		Equal(Time{plus8}).
+		NotEqual(Time{utc.Add(time.Nanosecond)}, "2021-01-02T03:04:05Z")
+
+	TestValue(t, Time{plus8.Add(time.Millisecond)}).
+		Repr("(time:parse 2021-01-02T11:04:05.001+08:00)")
+
+	Test(t, Fn("ToString", ToString), Table{
+		Args(Time{utc}).Rets("2021-01-02T03:04:05Z"),
+		Args(Duration{90 * time.Minute}).Rets("1h30m0s"),
+	})
+}
+
+func TestDuration(t *testing.T) {
+	TestValue(t, Duration{1500 * time.Millisecond}).
+		Kind("duration").
+		Bool(true).
+		Hash(hash.UInt64(uint64(1500*time.Millisecond))).
+		Repr("(time:duration 1.5s)").
+		Equal(Duration{1500 * time.Millisecond}).
+		NotEqual(Duration{time.Second}, "1.5s", 1.5)
+
+	Test(t, Fn("json.Marshal", json.Marshal), Table{
+		Args(Duration{90 * time.Minute}).Rets([]byte(`"1h30m0s"`), nil),
+	})
+}
+
+func TestScanDuration(t *testing.T) {
+	Test(t, Fn("ScanDuration", ScanDuration), Table{
+		Args(Duration{time.Minute}).Rets(time.Minute, nil),
+		Args(1.5).Rets(1500*time.Millisecond, nil),
+		Args(big.NewInt(2)).Rets(2*time.Second, nil),
+		Args(big.NewRat(1, 2)).Rets(500*time.Millisecond, nil),
+		Args("0.25").Rets(250*time.Millisecond, nil),
+		Args("1h30m").Rets(90*time.Minute, nil),
+		Args("-1m").Rets(-time.Minute, nil),
+		Args("x").Rets(time.Duration(0), cannotParseAs{"duration", "x"}),
+		Args([]int{}).Rets(time.Duration(0), errMustBeDuration),
+	})
+}
@@ -1,12 +1,11 @@
 package eval
 
 import (
-	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/eval/vars"
-	"github.com/elves/elvish/pkg/prog"
 )
 
 // This file implements variable resolution. Elvish has fully static lexical
@@ -30,15 +29,32 @@ type varRef struct {
 	scope    varScope
 	index    int
 	subNames []string
+	// Where each of subNames was found the last time the varRef was
+	// dereferenced, to avoid searching for them again in the same namespaces.
+	// They are atomic since the same varRef can be dereferenced concurrently.
+	subHints []atomic.Value
+}
+
+// Creates a varRef for a variable in a scope with indexed slots, with rest
+// being the part of the qualified name after the first segment.
+func newIndexedVarRef(scope varScope, index int, rest string) *varRef {
+	subNames := SplitQNameSegs(rest)
+	return &varRef{scope: scope, index: index,
+		subNames: subNames, subHints: make([]atomic.Value, len(subNames))}
 }
 
 type varScope int
 
+// The prefix of names in the E: namespace that refer to list views of
+// environment variables, like E:list:PATH.
+const envListPrefix = "list:"
+
 const (
 	localScope varScope = 1 + iota
 	captureScope
 	builtinScope
 	envScope
+	envListScope
 	externalScope
 )
 
@@ -69,7 +85,7 @@ func resolveVarRefLocal(s scopeSearcher, qname string) *varRef {
 	first, rest := SplitQName(qname)
 	index := s.searchLocal(first)
 	if index != -1 {
-		return &varRef{scope: localScope, index: index, subNames: SplitQNameSegs(rest)}
+		return newIndexedVarRef(localScope, index, rest)
 	}
 	return nil
 }
@@ -77,7 +93,7 @@ func resolveVarRefLocal(s scopeSearcher, qname string) *varRef {
 func resolveVarRefCapture(s scopeSearcher, qname string) *varRef {
 	first, rest := SplitQName(qname)
 	if index := s.searchCapture(first); index != -1 {
-		return &varRef{scope: captureScope, index: index, subNames: SplitQNameSegs(rest)}
+		return newIndexedVarRef(captureScope, index, rest)
 	}
 	return nil
 }
@@ -99,11 +115,15 @@ func resolveVarRefBuiltin(s scopeSearcher, qname string, r diag.Ranger) *varRef
 				return &varRef{scope: externalScope, subNames: []string{rest[:len(rest)-1]}}
 			}
 		case "E:":
+			// E:list:NAME is a list view of a PATH-like environment variable.
+			if name := strings.TrimPrefix(rest, envListPrefix); name != rest && name != "" {
+				return &varRef{scope: envListScope, subNames: []string{name}}
+			}
 			return &varRef{scope: envScope, subNames: []string{rest}}
 		}
 	}
 	if index := s.searchBuiltin(first, r); index != -1 {
-		return &varRef{scope: builtinScope, index: index, subNames: SplitQNameSegs(rest)}
+		return newIndexedVarRef(builtinScope, index, rest)
 	}
 	return nil
 }
@@ -129,16 +149,23 @@ func resolveCmdHeadInternally(s scopeSearcher, head string, r diag.Ranger) (comp
 // Dereferences a varRef into a Var.
 func deref(fm *Frame, ref *varRef) vars.Var {
 	variable, subNames := derefBase(fm, ref)
-	for _, subName := range subNames {
+	for i, subName := range subNames {
 		ns, ok := variable.Get().(*Ns)
 		if !ok {
 			return nil
 		}
-		variable = ns.indexInner(subName)
+		if i < len(ref.subHints) {
+			variable = ns.indexInnerWithHint(subName, &ref.subHints[i])
+		} else {
+			variable = ns.indexInner(subName)
+		}
 		if variable == nil {
 			return nil
 		}
 	}
+	if r, ok := variable.(RestrictableVar); ok {
+		variable = r.Restrict(fm)
+	}
 	return variable
 }
 
@@ -149,9 +176,17 @@ func derefBase(fm *Frame, ref *varRef) (vars.Var, []string) {
 	case captureScope:
 		return fm.up.slots[ref.index], ref.subNames
 	case builtinScope:
-		return fm.Builtin.slots[ref.index], ref.subNames
+		v := fm.Builtin.slots[ref.index]
+		switch v.(type) {
+		case *envListVar, pwdVar:
+			// $paths and $pwd
+			v = fm.restrictEnvVar(v)
+		}
+		return v, ref.subNames
 	case envScope:
-		return vars.FromEnv(ref.subNames[0]), nil
+		return fm.restrictEnvVar(vars.FromEnv(ref.subNames[0])), nil
+	case envListScope:
+		return fm.restrictEnvVar(NewEnvListVar(ref.subNames[0])), nil
 	case externalScope:
 		return vars.NewReadOnly(ExternalCmd{ref.subNames[0]}), nil
 	default:
@@ -188,34 +223,23 @@ func (cp *compiler) searchBuiltin(k string, r diag.Ranger) int {
 }
 
 func (cp *compiler) checkDeprecatedBuiltin(name string, r diag.Ranger) {
-	if cp.warn == nil || r == nil {
+	if r == nil {
 		return
 	}
-	msg := ""
-	switch name {
-	case "-source~":
-		msg = `the "source" command is deprecated; use "eval" instead`
-	case "ord~":
-		msg = `the "ord" command is deprecated; use "str:to-codepoints" instead`
-	case "chr~":
-		msg = `the "chr" command is deprecated; use "str:from-codepoints" instead`
-	case "has-prefix~":
-		msg = `the "has-prefix" command is deprecated; use "str:has-prefix" instead`
-	case "has-suffix~":
-		msg = `the "has-suffix" command is deprecated; use "str:has-suffix" instead`
-	case "esleep~":
-		msg = `the "esleep" command is deprecated; use "sleep" instead`
-	default:
-		return
+	if dep, ok := deprecatedBuiltins[name]; ok {
+		cp.deprecate(r, dep)
 	}
-	dep := deprecation{cp.srcMeta.Name, r.Range(), msg}
-	if prog.ShowDeprecations && cp.deprecations.register(dep) {
-		err := diag.Error{
-			Type: "deprecation", Message: msg,
-			Context: diag.Context{
-				Name: cp.srcMeta.Name, Source: cp.srcMeta.Code, Ranging: r.Range()}}
-		fmt.Fprintln(cp.warn, err.Show(""))
+}
+
+// ResolveVar looks up the variable with the given qualified name, like "x" or
+// "edit:binding", in the scope of the frame. It returns nil if there is no such
+// variable.
+func (fm *Frame) ResolveVar(qname string) vars.Var {
+	ref := resolveVarRef(fm, qname, nil)
+	if ref == nil {
+		return nil
 	}
+	return deref(fm, ref)
 }
 
 func (fm *Frame) searchLocal(k string) int {
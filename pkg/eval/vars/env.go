@@ -3,6 +3,7 @@ package vars
 import (
 	"errors"
 	"os"
+	"sync"
 )
 
 var errEnvMustBeString = errors.New("environment variable can only be set string values")
@@ -11,10 +12,14 @@ type envVariable struct {
 	name string
 }
 
+var (
+	_ Watchable  = envVariable{}
+	_ Unsettable = envVariable{}
+)
+
 func (ev envVariable) Set(val interface{}) error {
 	if s, ok := val.(string); ok {
-		os.Setenv(ev.name, s)
-		return nil
+		return SetEnv(ev.name, s)
 	}
 	return errEnvMustBeString
 }
@@ -23,7 +28,77 @@ func (ev envVariable) Get() interface{} {
 	return os.Getenv(ev.name)
 }
 
+// IsSet implements the Unsettable interface.
+func (ev envVariable) IsSet() bool {
+	_, ok := os.LookupEnv(ev.name)
+	return ok
+}
+
+// Unset implements the Unsettable interface.
+func (ev envVariable) Unset() error {
+	return UnsetEnv(ev.name)
+}
+
+// Watch implements the Watchable interface. The watchers are shared by all the
+// variables for the same environment variable.
+func (ev envVariable) Watch(f func(newValue interface{})) func() {
+	return WatchEnv(ev.name, f)
+}
+
 // FromEnv returns a Var corresponding to the named environment variable.
 func FromEnv(name string) Var {
 	return envVariable{name}
 }
+
+var (
+	envWatchersMutex sync.Mutex
+	envWatchers      = map[string]*watchers{}
+)
+
+func envWatchersFor(name string) *watchers {
+	envWatchersMutex.Lock()
+	defer envWatchersMutex.Unlock()
+	ws, ok := envWatchers[name]
+	if !ok {
+		ws = new(watchers)
+		envWatchers[name] = ws
+	}
+	return ws
+}
+
+// SetEnv sets an environment variable, and calls its watchers with the new
+// value.
+func SetEnv(name, value string) error {
+	if err := os.Setenv(name, value); err != nil {
+		return err
+	}
+	notifyEnv(name)
+	return nil
+}
+
+// UnsetEnv unsets an environment variable, and calls its watchers with an
+// empty string.
+func UnsetEnv(name string) error {
+	if err := os.Unsetenv(name); err != nil {
+		return err
+	}
+	notifyEnv(name)
+	return nil
+}
+
+func notifyEnv(name string) {
+	envWatchersMutex.Lock()
+	ws := envWatchers[name]
+	envWatchersMutex.Unlock()
+	if ws != nil {
+		ws.notify(func() interface{} { return os.Getenv(name) })
+	}
+}
+
+// WatchEnv registers f to be called with the new value of an environment
+// variable every time it is changed with SetEnv, UnsetEnv or a variable
+// returned by FromEnv, and returns a function that unregisters f. Changes made
+// with the os package directly are not noticed.
+func WatchEnv(name string, f func(newValue interface{})) func() {
+	return envWatchersFor(name).add(f)
+}
@@ -2,6 +2,7 @@ package vars
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -17,3 +18,19 @@ func TestFromEnv(t *testing.T) {
 		t.Errorf("envVariable.Set doesn't alter env value")
 	}
 }
+
+func TestWatchEnv(t *testing.T) {
+	name := "elvish_test_watch"
+	var got []interface{}
+	unwatch := WatchEnv(name, func(v interface{}) { got = append(got, v) })
+	FromEnv(name).Set("foo")
+	SetEnv(name, "bar")
+	UnsetEnv(name)
+	unwatch()
+	SetEnv(name, "lorem")
+	os.Unsetenv(name)
+
+	if want := []interface{}{"foo", "bar", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("watcher got %v, want %v", got, want)
+	}
+}
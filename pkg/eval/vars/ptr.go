@@ -8,15 +8,18 @@ import (
 )
 
 type PtrVar struct {
-	ptr   interface{}
-	mutex *sync.RWMutex
+	ptr      interface{}
+	mutex    *sync.RWMutex
+	watchers *watchers
 }
 
+var _ Watchable = PtrVar{}
+
 // FromPtrWithMutex creates a variable from a pointer. The variable is kept in
 // sync with the value the pointer points to, converting with vals.ScanToGo and
 // vals.FromGo when Get and Set. Its access is guarded by the supplied mutex.
 func FromPtrWithMutex(p interface{}, m *sync.RWMutex) PtrVar {
-	return PtrVar{p, m}
+	return PtrVar{p, m, new(watchers)}
 }
 
 // FromPtr creates a variable from a pointer. The variable is kept in sync with
@@ -44,9 +47,20 @@ func (v PtrVar) GetRaw() interface{} {
 	return reflect.Indirect(reflect.ValueOf(v.ptr)).Interface()
 }
 
-// Set sets the value pointed by the pointer, after conversion using ScanToGo.
+// Set sets the value pointed by the pointer, after conversion using ScanToGo,
+// and then calls the watchers with the new value.
 func (v PtrVar) Set(val interface{}) error {
 	v.mutex.Lock()
-	defer v.mutex.Unlock()
-	return vals.ScanToGo(val, v.ptr)
+	err := vals.ScanToGo(val, v.ptr)
+	v.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	v.watchers.notify(v.Get)
+	return nil
+}
+
+// Watch implements the Watchable interface.
+func (v PtrVar) Watch(f func(newValue interface{})) func() {
+	return v.watchers.add(f)
 }
@@ -6,3 +6,13 @@ type Var interface {
 	Set(v interface{}) error
 	Get() interface{}
 }
+
+// Unsettable is implemented by variables that can be unset, like environment
+// variables.
+type Unsettable interface {
+	Var
+	// IsSet returns whether the variable is set.
+	IsSet() bool
+	// Unset unsets the variable.
+	Unset() error
+}
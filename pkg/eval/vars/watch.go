@@ -0,0 +1,69 @@
+package vars
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotWatchable is returned by Watch when the variable does not support
+// watching.
+var ErrNotWatchable = errors.New("variable cannot be watched")
+
+// Watchable is implemented by variables that can notify watchers when they are
+// set.
+type Watchable interface {
+	Var
+	// Watch registers f to be called with the new value of the variable after
+	// each successful Set, and returns a function that unregisters f.
+	Watch(f func(newValue interface{})) (unwatch func())
+}
+
+// Watch registers f to be called with the new value of v after each successful
+// Set, and returns a function that unregisters f. It returns ErrNotWatchable if
+// v does not implement Watchable.
+//
+// Watchers are called synchronously from the goroutine calling Set, after the
+// new value has been stored, in the order they were registered.
+func Watch(v Var, f func(newValue interface{})) (func(), error) {
+	if w, ok := v.(Watchable); ok {
+		return w.Watch(f), nil
+	}
+	return nil, ErrNotWatchable
+}
+
+type watchers struct {
+	mutex sync.Mutex
+	fns   []*func(interface{})
+}
+
+func (ws *watchers) add(f func(interface{})) func() {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	p := &f
+	ws.fns = append(ws.fns, p)
+	return func() {
+		ws.mutex.Lock()
+		defer ws.mutex.Unlock()
+		for i, q := range ws.fns {
+			if q == p {
+				ws.fns = append(ws.fns[:i:i], ws.fns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Calls the watchers with the value returned by get, which is only called if
+// there are any watchers.
+func (ws *watchers) notify(get func() interface{}) {
+	ws.mutex.Lock()
+	fns := ws.fns
+	ws.mutex.Unlock()
+	if len(fns) == 0 {
+		return
+	}
+	v := get()
+	for _, f := range fns {
+		(*f)(v)
+	}
+}
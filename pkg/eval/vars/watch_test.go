@@ -0,0 +1,47 @@
+package vars
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	v := FromInit("a")
+	var got1, got2 []interface{}
+	unwatch1, err := Watch(v, func(nv interface{}) { got1 = append(got1, nv) })
+	if err != nil {
+		t.Fatalf("Watch returns error %v", err)
+	}
+	Watch(v, func(nv interface{}) { got2 = append(got2, nv) })
+
+	v.Set("b")
+	unwatch1()
+	v.Set("c")
+
+	if want := []interface{}{"b"}; !reflect.DeepEqual(got1, want) {
+		t.Errorf("first watcher got %v, want %v", got1, want)
+	}
+	if want := []interface{}{"b", "c"}; !reflect.DeepEqual(got2, want) {
+		t.Errorf("second watcher got %v, want %v", got2, want)
+	}
+}
+
+func TestWatch_NotCalledOnFailedSet(t *testing.T) {
+	i := 10
+	v := FromPtr(&i)
+	called := false
+	Watch(v, func(interface{}) { called = true })
+	if v.Set("x") == nil {
+		t.Errorf("Set with incompatible value returns no error")
+	}
+	if called {
+		t.Errorf("watcher called after failed Set")
+	}
+}
+
+func TestWatch_NotWatchable(t *testing.T) {
+	_, err := Watch(NewReadOnly("x"), func(interface{}) {})
+	if err != ErrNotWatchable {
+		t.Errorf("Watch returns error %v, want ErrNotWatchable", err)
+	}
+}
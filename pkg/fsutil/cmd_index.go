@@ -0,0 +1,209 @@
+package fsutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/pkg/env"
+)
+
+// DefaultCommandIndexInterval is the default minimum interval between two
+// checks of the directories of a CommandIndex.
+const DefaultCommandIndexInterval = time.Second
+
+// CommandIndex is an index of the external commands in the directories of
+// PATH. It is meant for looking up commands frequently, like when highlighting
+// or completing code on every keystroke, without reading the directories every
+// time.
+//
+// The directories are read when the index is first used and whenever PATH
+// changes. After that, lookups use the index, and the modification times of
+// the directories are checked in the background, at most once per interval; a
+// directory is read again when its modification time has changed, which
+// happens when commands are added to or removed from it. As a result, a new
+// command may not be found until shortly after it is added. Making an existing
+// file executable, like with chmod +x, doesn't change the modification time of
+// the directory, so such a command is not found until the directory changes
+// in another way.
+//
+// Relative directories in PATH are resolved against the working directory,
+// and indexed like absolute ones. When the working directory changes, the
+// index is updated with the directories they now refer to; directories that
+// have been read before are not read again unless they have changed.
+//
+// It is safe to use a CommandIndex concurrently.
+type CommandIndex struct {
+	interval time.Duration
+
+	mutex sync.Mutex
+	// Whether the directories have been read, and the value of PATH and the
+	// working directory when they were read. The working directory is only
+	// set if PATH has relative directories.
+	indexed bool
+	path    string
+	wd      string
+	dirs    []indexedDir
+	// The commands in all the directories; replaced rather than modified, so
+	// they can be used without holding the mutex.
+	names  map[string]bool
+	sorted []string
+	// When the directories were last checked, and whether they are being
+	// checked in the background.
+	checked  time.Time
+	checking bool
+}
+
+type indexedDir struct {
+	path    string
+	modTime time.Time
+	names   []string
+}
+
+// NewCommandIndex creates a new CommandIndex that checks its directories at
+// most once per interval.
+func NewCommandIndex(interval time.Duration) *CommandIndex {
+	return &CommandIndex{interval: interval}
+}
+
+// Has returns whether there is an external command with the given name in one
+// of the directories of PATH.
+func (ix *CommandIndex) Has(name string) bool {
+	names, _ := ix.get()
+	return names[name]
+}
+
+// Each calls f for the name of each external command in the directories of
+// PATH, in lexicographical order. Each name is only used once, even if it
+// appears in multiple directories.
+func (ix *CommandIndex) Each(f func(string)) {
+	_, sorted := ix.get()
+	for _, name := range sorted {
+		f(name)
+	}
+}
+
+// Returns the indexed commands, reading the directories first if PATH or the
+// directories it refers to have changed, and starting a check in the
+// background if it is due.
+func (ix *CommandIndex) get() (map[string]bool, []string) {
+	path := os.Getenv(env.PATH)
+	var wd string
+	if hasRelativeDir(path) {
+		wd, _ = os.Getwd()
+	}
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	if !ix.indexed || path != ix.path || wd != ix.wd {
+		ix.indexed, ix.path, ix.wd = true, path, wd
+		ix.setDirs(scanDirs(dirPaths(path, wd), ix.dirs))
+		ix.checked = time.Now()
+	} else if !ix.checking && time.Since(ix.checked) >= ix.interval {
+		ix.checking = true
+		go ix.check(path, wd, ix.dirs)
+	}
+	return ix.names, ix.sorted
+}
+
+// Reads the directories whose modification times have changed again, and
+// updates the index unless PATH or the working directory has changed in the
+// meantime.
+func (ix *CommandIndex) check(path, wd string, old []indexedDir) {
+	dirs := scanDirs(dirPaths(path, wd), old)
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	if ix.path == path && ix.wd == wd {
+		ix.setDirs(dirs)
+	}
+	ix.checked = time.Now()
+	ix.checking = false
+}
+
+// Updates the directories and the names. Must be called with the mutex held.
+func (ix *CommandIndex) setDirs(dirs []indexedDir) {
+	ix.dirs = dirs
+	names := make(map[string]bool)
+	var sorted []string
+	for _, dir := range dirs {
+		for _, name := range dir.names {
+			if !names[name] {
+				names[name] = true
+				sorted = append(sorted, name)
+			}
+		}
+	}
+	sort.Strings(sorted)
+	ix.names, ix.sorted = names, sorted
+}
+
+func hasRelativeDir(path string) bool {
+	for _, dir := range filepath.SplitList(path) {
+		if !filepath.IsAbs(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the absolute paths of the directories in PATH. Relative directories
+// are resolved against wd, and left out if wd is empty.
+func dirPaths(path, wd string) []string {
+	var paths []string
+	for _, dir := range filepath.SplitList(path) {
+		if !filepath.IsAbs(dir) {
+			if wd == "" {
+				continue
+			}
+			// An empty directory means the working directory, which Join
+			// also handles.
+			dir = filepath.Join(wd, dir)
+		}
+		paths = append(paths, dir)
+	}
+	return paths
+}
+
+// Reads the given directories, reusing the entries in old for directories
+// whose modification times haven't changed.
+func scanDirs(paths []string, old []indexedDir) []indexedDir {
+	oldByPath := make(map[string]indexedDir, len(old))
+	for _, dir := range old {
+		oldByPath[dir.path] = dir
+	}
+	dirs := make([]indexedDir, len(paths))
+	for i, path := range paths {
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		if dir, ok := oldByPath[path]; ok && dir.modTime.Equal(modTime) {
+			dirs[i] = dir
+			continue
+		}
+		dirs[i] = indexedDir{path, modTime, commandsInDir(path)}
+	}
+	return dirs
+}
+
+func commandsInDir(dir string) []string {
+	// Errors are ignored; directories that can't be read have no commands.
+	infos, _ := ioutil.ReadDir(dir)
+	var names []string
+	for _, info := range infos {
+		if info.Mode()&os.ModeSymlink != 0 {
+			// Use the mode of the target of the symlink.
+			target, err := os.Stat(filepath.Join(dir, info.Name()))
+			if err != nil {
+				continue
+			}
+			info = target
+		}
+		if info.Mode().IsRegular() {
+			names = append(names, commandNames(info.Name(), info.Mode())...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,14 @@
+// +build !windows
+
+package fsutil
+
+import "os"
+
+// Returns the names of the commands provided by a regular file in a directory
+// of PATH.
+func commandNames(name string, mode os.FileMode) []string {
+	if mode&0111 == 0 {
+		return nil
+	}
+	return []string{name}
+}
@@ -0,0 +1,135 @@
+// +build !windows,!plan9,!js
+
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestCommandIndex(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"bin1": testutil.Dir{
+			"file": "",
+			"dir":  testutil.Dir{},
+			"cmd1": testutil.File{Perm: 0755, Content: ""},
+			"cmd2": testutil.File{Perm: 0755, Content: ""},
+		},
+		"bin2": testutil.Dir{
+			"cmd2": testutil.File{Perm: 0755, Content: ""},
+			"cmd3": testutil.File{Perm: 0755, Content: ""},
+		},
+	})
+	testutil.Must(os.Symlink(filepath.Join(dir, "bin2", "cmd3"), filepath.Join(dir, "bin1", "link")))
+	testutil.Must(os.Symlink(filepath.Join(dir, "bin1", "dir"), filepath.Join(dir, "bin1", "dirlink")))
+	bin1, bin2 := filepath.Join(dir, "bin1"), filepath.Join(dir, "bin2")
+	restorePath := testutil.WithTempEnv("PATH", "/foo:"+bin1+":bin2:"+bin2)
+	defer restorePath()
+
+	ix := NewCommandIndex(time.Hour)
+	testEach(t, ix, "cmd1", "cmd2", "cmd3", "link")
+	for _, name := range []string{"cmd1", "cmd3", "link"} {
+		if !ix.Has(name) {
+			t.Errorf("Has(%q) -> false, want true", name)
+		}
+	}
+	for _, name := range []string{"file", "dir", "dirlink", "bad"} {
+		if ix.Has(name) {
+			t.Errorf("Has(%q) -> true, want false", name)
+		}
+	}
+
+	// Changes to PATH take effect immediately.
+	os.Setenv("PATH", bin2)
+	testEach(t, ix, "cmd2", "cmd3")
+	os.Setenv("PATH", bin1+":"+bin2)
+	testEach(t, ix, "cmd1", "cmd2", "cmd3", "link")
+
+	// New commands are not found until the directories are checked.
+	testutil.MustWriteFile(filepath.Join(bin2, "cmd4"), nil, 0755)
+	bumpModTime(bin2)
+	if ix.Has("cmd4") {
+		t.Errorf("Has(cmd4) -> true before checking directories")
+	}
+}
+
+func TestCommandIndex_RelativeDirs(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"bin": testutil.Dir{
+			"cmd1": testutil.File{Perm: 0755, Content: ""},
+			"cmd3": testutil.File{Perm: 0755, Content: ""},
+		},
+		"abs": testutil.Dir{
+			"cmd1": testutil.File{Perm: 0755, Content: ""},
+			"cmd2": testutil.File{Perm: 0755, Content: ""},
+		},
+	})
+	restorePath := testutil.WithTempEnv("PATH", "bin:"+filepath.Join(dir, "abs"))
+	defer restorePath()
+
+	ix := NewCommandIndex(time.Hour)
+	testEach(t, ix, "cmd1", "cmd2", "cmd3")
+
+	// Relative directories are resolved against the working directory.
+	testutil.MustChdir("bin")
+	if ix.Has("cmd3") {
+		t.Errorf("Has(cmd3) -> true after changing the working directory")
+	}
+	testEach(t, ix, "cmd1", "cmd2")
+
+	testutil.MustChdir("..")
+	if !ix.Has("cmd3") {
+		t.Errorf("Has(cmd3) -> false after changing back the working directory")
+	}
+	testEach(t, ix, "cmd1", "cmd2", "cmd3")
+}
+
+func TestCommandIndex_ChecksInBackground(t *testing.T) {
+	dir, cleanup := testutil.InTestDir()
+	defer cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"cmd1": testutil.File{Perm: 0755, Content: ""},
+	})
+	restorePath := testutil.WithTempEnv("PATH", dir)
+	defer restorePath()
+
+	ix := NewCommandIndex(0)
+	testEach(t, ix, "cmd1")
+
+	testutil.MustWriteFile("cmd2", nil, 0755)
+	testutil.Must(os.Remove("cmd1"))
+	bumpModTime(dir)
+	deadline := time.Now().Add(testutil.ScaledMs(2000))
+	for !ix.Has("cmd2") || ix.Has("cmd1") {
+		if time.Now().After(deadline) {
+			t.Fatalf("index not updated after the directory changed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	testEach(t, ix, "cmd2")
+}
+
+func testEach(t *testing.T, ix *CommandIndex, wantNames ...string) {
+	t.Helper()
+	var names []string
+	ix.Each(func(name string) { names = append(names, name) })
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("Each called with %v, want %v", names, wantNames)
+	}
+}
+
+// Changes the modification time of a directory, in case it has not changed
+// because of the resolution of modification times.
+func bumpModTime(dir string) {
+	t := time.Now().Add(time.Hour)
+	testutil.Must(os.Chtimes(dir, t, t))
+}
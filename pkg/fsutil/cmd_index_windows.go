@@ -0,0 +1,29 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/pkg/env"
+)
+
+// Returns the names of the commands provided by a regular file in a directory
+// of PATH. Files with an extension in PATHEXT can be run with or without the
+// extension.
+func commandNames(name string, mode os.FileMode) []string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return nil
+	}
+	pathExt := os.Getenv(env.PATHEXT)
+	if pathExt == "" {
+		pathExt = ".com;.exe;.bat;.cmd"
+	}
+	for _, e := range strings.Split(strings.ToLower(pathExt), ";") {
+		if e == ext {
+			return []string{name, name[:len(name)-len(ext)]}
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -48,7 +49,7 @@ func (p Pattern) Glob(cb func(PathInfo) bool) bool {
 		}
 	}
 
-	return glob(segs, dir, cb)
+	return glob(&p, segs, dir, 0, cb)
 }
 
 func isDrive(s string) bool {
@@ -58,12 +59,14 @@ func isDrive(s string) bool {
 
 // glob finds all filenames matching the given Segments in the given dir, and
 // calls the callback on all of them. If the callback returns false, globbing is
-// interrupted, and glob returns false. Otherwise it returns true.
-func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
+// interrupted, and glob returns false. Otherwise it returns true. The depth
+// argument is the number of levels of directories StarStar segments have
+// descended into so far.
+func glob(p *Pattern, segs []Segment, dir string, depth int, cb func(PathInfo) bool) bool {
 	// Consume non-wildcard path elements simply by following the path. This may
 	// seem like an optimization, but is actually required for "." and ".." to
 	// be used as path elements, as they do not appear in the result of ReadDir.
-	for len(segs) > 1 && IsLiteral(segs[0]) && IsSlash(segs[1]) {
+	for len(segs) > 1 && p.canFollow(segs[0]) && IsSlash(segs[1]) {
 		elem := segs[0].(Literal).Data
 		segs = segs[2:]
 		dir += elem + "/"
@@ -77,7 +80,7 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 			return cb(PathInfo{dir, info})
 		}
 		return true
-	} else if len(segs) == 1 && IsLiteral(segs[0]) {
+	} else if len(segs) == 1 && p.canFollow(segs[0]) {
 		path := dir + segs[0].(Literal).Data
 		if info, err := os.Stat(path); err == nil {
 			return cb(PathInfo{path, info})
@@ -134,11 +137,19 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 			first, rest = segs[:i+1], segs[i:]
 		}
 
-		for _, info := range infos {
-			name := info.Name()
-			if matchElement(first, name) && info.IsDir() {
-				if !glob(rest, dir+name+"/", cb) {
-					return false
+		// If the slash is matched by a StarStar, recursing descends into one
+		// more level of directories, which may exceed MaxDepth.
+		restDepth := depth
+		if !slash {
+			restDepth++
+		}
+		if slash || p.MaxDepth == 0 || restDepth < p.MaxDepth {
+			for _, info := range infos {
+				name := info.Name()
+				if matchElement(first, name, p.IgnoreCase) && info.IsDir() {
+					if !glob(p, rest, dir+name+"/", restDepth, cb) {
+						return false
+					}
 				}
 			}
 		}
@@ -154,7 +165,7 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 	// the entire pattern with all files.
 	for _, info := range infos {
 		name := info.Name()
-		if matchElement(segs, name) {
+		if matchElement(segs, name, p.IgnoreCase) {
 			dirname := dir + name
 			info, err := os.Stat(dirname)
 			if err != nil {
@@ -168,6 +179,17 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 	return true
 }
 
+// canFollow returns whether seg is a Literal that can be matched by simply
+// following the path. When matching case-insensitively, this is only true for
+// literals without any letters, like "." and "..".
+func (p *Pattern) canFollow(seg Segment) bool {
+	lit, ok := seg.(Literal)
+	if !ok {
+		return false
+	}
+	return !p.IgnoreCase || strings.ToLower(lit.Data) == strings.ToUpper(lit.Data)
+}
+
 // readDir is just like ioutil.ReadDir except that it treats an argument of ""
 // as ".".
 func readDir(dir string) ([]os.FileInfo, error) {
@@ -179,7 +201,7 @@ func readDir(dir string) ([]os.FileInfo, error) {
 
 // matchElement matches a path element against segments, which may not contain
 // any Slash segments. It treats StarStar segments as they are Star segments.
-func matchElement(segs []Segment, name string) bool {
+func matchElement(segs []Segment, name string, ignoreCase bool) bool {
 	if len(segs) == 0 {
 		return name == ""
 	}
@@ -213,7 +235,7 @@ segs:
 
 		// Match at the current position. If this is the last chunk, we need to
 		// make sure name is exhausted by the matching.
-		ok, rest := matchFixedLength(chunk, name)
+		ok, rest := matchFixedLength(chunk, name, ignoreCase)
 		if ok && (rest == "" || len(segs) > 0) {
 			name = rest
 			continue
@@ -228,7 +250,7 @@ segs:
 				if !startingStar.Match(r) {
 					break
 				}
-				ok, rest := matchFixedLength(chunk, name[j:])
+				ok, rest := matchFixedLength(chunk, name[j:], ignoreCase)
 				if ok && (rest == "" || len(segs) > 0) {
 					name = rest
 					continue segs
@@ -243,7 +265,7 @@ segs:
 // matchFixedLength returns whether a run of fixed-length segments (Literal and
 // Question) matches a prefix of name. It returns whether the match is
 // successful and if if it is, the remaining part of name.
-func matchFixedLength(segs []Segment, name string) (bool, string) {
+func matchFixedLength(segs []Segment, name string, ignoreCase bool) (bool, string) {
 	for _, seg := range segs {
 		if name == "" {
 			return false, ""
@@ -251,7 +273,7 @@ func matchFixedLength(segs []Segment, name string) (bool, string) {
 		switch seg := seg.(type) {
 		case Literal:
 			n := len(seg.Data)
-			if len(name) < n || name[:n] != seg.Data {
+			if len(name) < n || !matchLiteral(name[:n], seg.Data, ignoreCase) {
 				return false, ""
 			}
 			name = name[n:]
@@ -271,3 +293,64 @@ func matchFixedLength(segs []Segment, name string) (bool, string) {
 	}
 	return true, name
 }
+
+func matchLiteral(s, lit string, ignoreCase bool) bool {
+	if ignoreCase {
+		return strings.EqualFold(s, lit)
+	}
+	return s == lit
+}
+
+// Match returns whether a path matches the Pattern, without accessing the
+// filesystem. Unlike in Glob, Wild segments match a leading "." regardless of
+// MatchHidden, and DirOverride and MaxDepth are ignored.
+func (p Pattern) Match(path string) bool {
+	return match(p.Segments, path, p.IgnoreCase)
+}
+
+func match(segs []Segment, name string, ignoreCase bool) bool {
+	for len(segs) > 0 {
+		switch seg := segs[0].(type) {
+		case Slash:
+			if name == "" || name[0] != '/' {
+				return false
+			}
+			name = strings.TrimLeft(name, "/")
+		case Literal:
+			n := len(seg.Data)
+			if len(name) < n || !matchLiteral(name[:n], seg.Data, ignoreCase) {
+				return false
+			}
+			name = name[n:]
+		case Wild:
+			if seg.Type == Question {
+				r, n := utf8.DecodeRuneInString(name)
+				if name == "" || r == '/' || !seg.Match(r) {
+					return false
+				}
+				name = name[n:]
+				break
+			}
+			// Try all possible prefixes of name for the star, shortest first.
+			for i := 0; ; {
+				if match(segs[1:], name[i:], ignoreCase) {
+					return true
+				}
+				if i == len(name) {
+					return false
+				}
+				r, n := utf8.DecodeRuneInString(name[i:])
+				if r == '/' {
+					if seg.Type == Star {
+						return false
+					}
+				} else if !seg.Match(r) {
+					return false
+				}
+				i += n
+			}
+		}
+		segs = segs[1:]
+	}
+	return name == ""
+}
@@ -107,3 +107,69 @@ func testGlob(t *testing.T, abs bool) {
 		}
 	}
 }
+
+func TestGlob_IgnoreCaseAndMaxDepth(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	testutil.MustMkdirAll("Dir/sub")
+	testutil.MustCreateEmpty("a.GO", "b.go", "Dir/c.go", "Dir/sub/d.go")
+
+	tests := []struct {
+		pattern    string
+		ignoreCase bool
+		maxDepth   int
+		want       []string
+	}{
+		{"*.go", false, 0, []string{"b.go"}},
+		{"*.go", true, 0, []string{"a.GO", "b.go"}},
+		{"dir/*.go", true, 0, []string{"Dir/c.go"}},
+		{"**.go", false, 1, []string{"b.go"}},
+		{"**.go", false, 2, []string{"Dir/c.go", "b.go"}},
+		{"**.go", true, 0, []string{"Dir/c.go", "Dir/sub/d.go", "a.GO", "b.go"}},
+		// MaxDepth doesn't limit literal slashes.
+		{"Dir/**.go", false, 1, []string{"Dir/c.go"}},
+	}
+	for _, test := range tests {
+		p := Parse(test.pattern)
+		p.IgnoreCase = test.ignoreCase
+		p.MaxDepth = test.maxDepth
+		results := []string{}
+		p.Glob(func(pathInfo PathInfo) bool {
+			results = append(results, pathInfo.Path)
+			return true
+		})
+		sort.Strings(results)
+		if !reflect.DeepEqual(results, test.want) {
+			t.Errorf("%q with IgnoreCase=%v MaxDepth=%v => %v, want %v",
+				test.pattern, test.ignoreCase, test.maxDepth, results, test.want)
+		}
+	}
+}
+
+var matchCases = []struct {
+	pattern string
+	path    string
+	want    bool
+}{
+	{"*_test.go", "a_test.go", true},
+	{"*_test.go", "a.go", false},
+	{"*_test.go", "d/a_test.go", false},
+	{"**_test.go", "d/a_test.go", true},
+	{"d/?.go", "d/a.go", true},
+	{"d/?.go", "d/ab.go", false},
+	{"?", "/", false},
+	{"*", ".hidden", true},
+	{"lit", "lit", true},
+	{"lit", "literal", false},
+}
+
+func TestPattern_Match(t *testing.T) {
+	for _, tc := range matchCases {
+		got := Parse(tc.pattern).Match(tc.path)
+		if got != tc.want {
+			t.Errorf("Parse(%q).Match(%q) => %v, want %v",
+				tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
@@ -4,6 +4,13 @@ package glob
 type Pattern struct {
 	Segments    []Segment
 	DirOverride string
+	// If true, letters in Literal segments match case-insensitively.
+	IgnoreCase bool
+	// The maximum number of levels of directories the pattern may descend
+	// into with StarStar segments, like the -maxdepth option of find(1). A
+	// value of 1 means that StarStar segments only match within one
+	// directory. A value of 0 means no limit.
+	MaxDepth int
 }
 
 // Segment is the building block of Pattern.
@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"unicode"
 
 	"github.com/elves/elvish/pkg/diag"
@@ -28,6 +29,11 @@ type Tree struct {
 
 // Parse parses the given source. The returned error always has type *Error
 // if it is not nil.
+//
+// When the source contains syntax errors, the returned tree is still a
+// best-effort AST of the source: the parser skips the offending text up to the
+// next newline, semicolon or pipe symbol and resumes parsing from there. The
+// skipped text is kept in the parse tree as Sep nodes.
 func Parse(src Source) (Tree, error) {
 	return ParseWithDeprecation(src, nil)
 }
@@ -44,7 +50,7 @@ func ParseWithDeprecation(src Source, w io.Writer) (Tree, error) {
 // n, writing deprecation warnings to the given io.Writer if it is not nil. If
 // the error is not nil, it always has type *Error.
 func ParseAs(src Source, n Node, w io.Writer) error {
-	ps := &parser{srcName: src.Name, src: src.Code, warn: w}
+	ps := &parser{srcMeta: src, src: src.Code, warn: w}
 	ps.parse(n)
 	ps.done()
 	return ps.assembleError()
@@ -59,6 +65,7 @@ var (
 	errShouldBeFilename           = newError("", "a composite term representing filename")
 	errShouldBeArray              = newError("", "spaced")
 	errStringUnterminated         = newError("string not terminated")
+	errInsufficientIndent         = newError("line indented less than the closing '''")
 	errChainedAssignment          = newError("chained assignment not yet supported")
 	errInvalidEscape              = newError("invalid escape sequence")
 	errInvalidEscapeOct           = newError("invalid escape sequence", "octal digit")
@@ -84,11 +91,24 @@ type Chunk struct {
 
 func (bn *Chunk) parse(ps *parser) {
 	bn.parseSeps(ps)
-	for startsPipeline(ps.peek()) {
-		ps.parse(&Pipeline{}).addTo(&bn.Pipelines, bn)
-		if bn.parseSeps(ps) == 0 {
-			break
+	for {
+		for startsPipeline(ps.peek()) {
+			ps.parse(&Pipeline{}).addTo(&bn.Pipelines, bn)
+			if bn.parseSeps(ps) == 0 {
+				break
+			}
 		}
+		r := ps.peek()
+		if ps.endsChunk(r) {
+			return
+		}
+		// Recover from text that can't start a pipeline, by skipping it up to
+		// the next pipeline separator or pipe symbol. A pipe symbol here can't
+		// continue the previous pipeline, so it is skipped too.
+		ps.error(fmt.Errorf("unexpected rune %q", r))
+		ps.skipJunk(bn)
+		parseSep(bn, ps, '|')
+		bn.parseSeps(ps)
 	}
 }
 
@@ -125,13 +145,16 @@ type Pipeline struct {
 
 func (pn *Pipeline) parse(ps *parser) {
 	ps.parse(&Form{}).addTo(&pn.Forms, pn)
+	pn.recover(ps)
 	for parseSep(pn, ps, '|') {
 		parseSpacesAndNewlines(pn, ps)
 		if !startsForm(ps.peek()) {
 			ps.error(errShouldBeForm)
-			return
+			ps.skipJunk(pn)
+			continue
 		}
 		ps.parse(&Form{}).addTo(&pn.Forms, pn)
+		pn.recover(ps)
 	}
 	parseSpaces(pn, ps)
 	if ps.peek() == '&' {
@@ -142,6 +165,17 @@ func (pn *Pipeline) parse(ps *parser) {
 	}
 }
 
+// Recovers from text after a form that can't continue the pipeline, by
+// skipping it up to the next pipeline separator or pipe symbol.
+func (pn *Pipeline) recover(ps *parser) {
+	r := ps.peek()
+	if r == '|' || r == '&' || isPipelineSep(r) || ps.endsChunk(r) {
+		return
+	}
+	ps.error(fmt.Errorf("unexpected rune %q", r))
+	ps.skipJunk(pn)
+}
+
 func startsPipeline(r rune) bool {
 	return startsForm(r)
 }
@@ -298,11 +332,13 @@ func checkVariableInAssignment(p *Primary, ps *parser) bool {
 	return true
 }
 
-// Redir = { Compound } { '<'|'>'|'<>'|'>>' } { Space } ( '&'? Compound )
+// Redir = { Compound } { '<'|'>'|'<>'|'>>' } { Space }
+//         ( '&' ( 'values' | 'bytes' ) Space { Space } )? ( '&'? Compound )
 type Redir struct {
 	node
 	Left      *Compound
 	Mode      RedirMode
+	Part      PortPart
 	RightIsFd bool
 	Right     *Compound
 }
@@ -334,7 +370,15 @@ func (rn *Redir) parse(ps *parser) {
 	addSep(rn, ps)
 	parseSpaces(rn, ps)
 	if parseSep(rn, ps, '&') {
-		rn.RightIsFd = true
+		rn.Part = parsePortPart(rn, ps)
+		if rn.Part == WholePort {
+			rn.RightIsFd = true
+		} else {
+			parseSpaces(rn, ps)
+			if parseSep(rn, ps, '&') {
+				rn.RightIsFd = true
+			}
+		}
 	}
 	ps.parse(&Compound{}).addAs(&rn.Right, rn)
 	if len(rn.Right.Indexings) == 0 {
@@ -351,6 +395,26 @@ func isRedirSign(r rune) bool {
 	return r == '<' || r == '>'
 }
 
+// Parses the "values" or "bytes" keyword after a redirection sign and "&". The
+// keyword must be followed by whitespace; otherwise it is parsed as a fd.
+func parsePortPart(rn *Redir, ps *parser) PortPart {
+	for _, keyword := range [...]struct {
+		text string
+		part PortPart
+	}{{"values", ValuesOnly}, {"bytes", BytesOnly}} {
+		rest := ps.src[ps.pos:]
+		if strings.HasPrefix(rest, keyword.text) && len(rest) > len(keyword.text) &&
+			IsInlineWhitespace(rune(rest[len(keyword.text)])) {
+			for range keyword.text {
+				ps.next()
+			}
+			addSep(rn, ps)
+			return keyword.part
+		}
+	}
+	return WholePort
+}
+
 // RedirMode records the mode of an IO redirection.
 type RedirMode int
 
@@ -363,6 +427,19 @@ const (
 	Append
 )
 
+// PortPart records which part of a port an IO redirection applies to.
+type PortPart int
+
+// Possible values for PortPart.
+const (
+	// The redirection applies to both the value channel and the byte file.
+	WholePort PortPart = iota
+	// The redirection applies to the value channel only.
+	ValuesOnly
+	// The redirection applies to the byte file only.
+	BytesOnly
+)
+
 // Compound = { Indexing }
 type Compound struct {
 	node
@@ -548,6 +625,10 @@ func (pn *Primary) parse(ps *parser) {
 func (pn *Primary) singleQuoted(ps *parser) {
 	pn.Type = SingleQuoted
 	ps.next()
+	if ps.hasPrefix("''\n") || ps.hasPrefix("''\r\n") {
+		pn.multiLineQuoted(ps)
+		return
+	}
 	var buf bytes.Buffer
 	defer func() { pn.Value = buf.String() }()
 	for {
@@ -570,6 +651,56 @@ func (pn *Primary) singleQuoted(ps *parser) {
 	}
 }
 
+// Parses a multi-line single-quoted string, which starts with ''' followed by a
+// newline, and ends with a line consisting of optional inline whitespace and
+// '''. The content is the lines in between, with the indentation of the closing
+// line removed from each of them.
+func (pn *Primary) multiLineQuoted(ps *parser) {
+	// Skip the rest of the opening delimiter and the newline.
+	ps.pos += strings.IndexByte(ps.src[ps.pos:], '\n') + 1
+	var lines []string
+	var lineStarts []int
+	for {
+		line := ps.src[ps.pos:]
+		i := strings.IndexByte(line, '\n')
+		if i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSuffix(line, "\r")
+		if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "'''") {
+			indent := line[:len(line)-len(trimmed)]
+			ps.pos += len(indent) + 3
+			pn.Value = dedent(ps, lines, lineStarts, indent)
+			return
+		}
+		if i == -1 {
+			ps.pos = len(ps.src)
+			ps.error(errStringUnterminated)
+			return
+		}
+		lines = append(lines, line)
+		lineStarts = append(lineStarts, ps.pos)
+		ps.pos += i + 1
+	}
+}
+
+// Removes indent from each line and joins them with newlines. Lines consisting
+// of only whitespace become empty; other lines must start with indent.
+func dedent(ps *parser, lines []string, lineStarts []int, indent string) string {
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, indent):
+			lines[i] = line[len(indent):]
+		case strings.TrimLeft(line, " \t") == "":
+			lines[i] = ""
+		default:
+			begin := lineStarts[i]
+			ps.errorp(diag.Ranging{From: begin, To: begin + len(line)}, errInsufficientIndent)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (pn *Primary) doubleQuoted(ps *parser) {
 	pn.Type = DoubleQuoted
 	ps.next()
@@ -724,7 +855,9 @@ func (pn *Primary) exitusCapture(ps *parser) {
 
 	pn.Type = ExceptionCapture
 
+	ps.pushCloser(')')
 	ps.parse(&Chunk{}).addAs(&pn.Chunk, pn)
+	ps.popCloser()
 
 	if !parseSep(pn, ps, ')') {
 		ps.error(errShouldBeRParen)
@@ -735,7 +868,9 @@ func (pn *Primary) outputCapture(ps *parser) {
 	pn.Type = OutputCapture
 	parseSep(pn, ps, '(')
 
+	ps.pushCloser(')')
 	ps.parse(&Chunk{}).addAs(&pn.Chunk, pn)
+	ps.popCloser()
 
 	if !parseSep(pn, ps, ')') {
 		ps.error(errShouldBeRParen)
@@ -796,7 +931,9 @@ items:
 // lambda parses a lambda expression. The opening brace has been seen.
 func (pn *Primary) lambda(ps *parser) {
 	pn.Type = Lambda
+	ps.pushCloser('}')
 	ps.parse(&Chunk{}).addAs(&pn.Chunk, pn)
+	ps.popCloser()
 	if !parseSep(pn, ps, '}') {
 		ps.error(errShouldBeRBrace)
 	}
@@ -66,6 +66,16 @@ var goodCases = []struct {
 			{"Redir", fs{"Left": "6", "Mode": ReadWrite, "Right": "d"}},
 		},
 	}}},
+	// Redirections of one part of a port
+	{"a >&values b 2>&bytes  &1 <&values c >&bytesx", ast{"Chunk/Pipeline/Form", fs{
+		"Head": "a",
+		"Redirs": []ast{
+			{"Redir", fs{"Mode": Write, "Part": ValuesOnly, "Right": "b"}},
+			{"Redir", fs{"Left": "2", "Mode": Write, "Part": BytesOnly, "RightIsFd": true, "Right": "1"}},
+			{"Redir", fs{"Mode": Read, "Part": ValuesOnly, "Right": "c"}},
+			{"Redir", fs{"Mode": Write, "Part": WholePort, "RightIsFd": true, "Right": "bytesx"}},
+		},
+	}}},
 	// Options (structure of MapPair tested below with map)
 	{"a &a=1 x &b=2", ast{"Chunk/Pipeline/Form", fs{
 		"Head": "a",
@@ -98,6 +108,21 @@ var goodCases = []struct {
 	{"a '''x''y'''", a(ast{"Compound/Indexing/Primary", fs{
 		"Type": SingleQuoted, "Value": "'x'y'",
 	}})},
+	// Multi-line single quote
+	{"a '''\n  x\n\n    ''y\n  '''", a(ast{"Compound/Indexing/Primary", fs{
+		"Type": SingleQuoted, "Value": "x\n\n  ''y",
+	}})},
+	// Lines with only whitespace can be indented less.
+	{"a '''\r\n  x\r\n \r\n  '''", a(ast{"Compound/Indexing/Primary", fs{
+		"Type": SingleQuoted, "Value": "x\n",
+	}})},
+	{"a '''\n'''", a(ast{"Compound/Indexing/Primary", fs{
+		"Type": SingleQuoted, "Value": "",
+	}})},
+	// The closing delimiter can be followed by other elements.
+	{"a '''\nx\n'''[0] b", a(ast{"Compound/Indexing", fs{
+		"Head": "'''\nx\n'''", "Indicies": []string{"0"},
+	}}, "b")},
 	// Double quote
 	{`a "[\c?\c@\cI\^I\^[]"`, // control char sequences
 		a(ast{"Compound/Indexing/Primary", fs{
@@ -323,6 +348,65 @@ func TestParse(t *testing.T) {
 	}
 }
 
+var recoveryCases = []struct {
+	src     string
+	ast     ast
+	nErrors int
+}{
+	// Stray closing bracket; parsing resumes after the newline.
+	{"a ]\nb", ast{"Chunk", fs{"Pipelines": []string{"a ]", "b"}}}, 1},
+	// Parsing resumes after a semicolon.
+	{") x; b", ast{"Chunk", fs{"Pipelines": []string{"b"}}}, 1},
+	// Parsing resumes after a pipe symbol, continuing the pipeline.
+	{"a ] x | b", ast{
+		"Chunk/Pipeline", fs{"Forms": []string{"a ", "b"}}}, 1},
+	// Missing form in the middle of a pipeline.
+	{"a | ) | b", ast{
+		"Chunk/Pipeline", fs{"Forms": []string{"a ", "b"}}}, 1},
+	// Pipe symbol at the start of a pipeline.
+	{"| b", ast{"Chunk", fs{"Pipelines": []string{"b"}}}, 1},
+	// Recovery inside an output capture stops at the closing parenthesis.
+	{"a (b ] x) c", a(
+		ast{"Compound/Indexing/Primary", fs{
+			"Type":  OutputCapture,
+			"Chunk": ast{"Chunk/Pipeline", fs{"Forms": []string{"b "}}},
+		}},
+		"c"), 1},
+	// An unclosed output capture doesn't consume the enclosing lambda.
+	{"a { b (c } d", a(
+		ast{"Compound/Indexing/Primary", fs{
+			"Type":  Lambda,
+			"Chunk": ast{"Chunk", fs{"Pipelines": []string{"b (c "}}},
+		}},
+		"d"), 1},
+}
+
+func TestParse_Recovery(t *testing.T) {
+	for _, tc := range recoveryCases {
+		tree, err := Parse(SourceForTest(tc.src))
+		if err == nil {
+			t.Errorf("Parse(%q) returns no error", tc.src)
+		} else if n := len(err.(*Error).Entries); n != tc.nErrors {
+			t.Errorf("Parse(%q) returns %d errors, want %d: %v", tc.src, n, tc.nErrors, err)
+		}
+		if tree.Root.sourceText != tc.src {
+			t.Errorf("Parse(%q) returns tree with source text %q", tc.src, tree.Root.sourceText)
+		}
+		err = checkParseTree(tree.Root)
+		if err != nil {
+			t.Errorf("Parse(%q) returns bad parse tree: %v", tc.src, err)
+			fmt.Fprintf(os.Stderr, "Parse tree of %q:\n", tc.src)
+			pprintParseTree(tree.Root, os.Stderr)
+		}
+		err = checkAST(tree.Root, tc.ast)
+		if err != nil {
+			t.Errorf("Parse(%q) returns bad AST: %v", tc.src, err)
+			fmt.Fprintf(os.Stderr, "AST of %q:\n", tc.src)
+			pprintAST(tree.Root, os.Stderr)
+		}
+	}
+}
+
 var parseErrorTests = []struct {
 	src      string
 	errPart  string
@@ -356,6 +440,9 @@ var parseErrorTests = []struct {
 	// Unterminated string.
 	{src: "'a", errAtEnd: true, errMsg: "string not terminated"},
 	{src: `"a`, errAtEnd: true, errMsg: "string not terminated"},
+	{src: "a '''\nx\n''''''", errAtEnd: true, errMsg: "string not terminated"},
+	// Insufficient indentation in multi-line string.
+	{src: "a '''\n  x\n y\n  '''", errPart: " y", errMsg: "line indented less than the closing '''"},
 	// Bad escape sequence.
 	{src: `a "\^` + "\t", errPart: "\t",
 		errMsg: "invalid control sequence, should be a codepoint between 0x3F and 0x5F"},
@@ -16,12 +16,14 @@ import (
 //
 // NOTE: The str member is assumed to be valid UF-8.
 type parser struct {
-	srcName string
+	srcMeta Source
 	src     string
 	pos     int
 	overEOF int
 	errors  Error
 	warn    io.Writer
+	// Closing brackets of the nested chunks being parsed, innermost last.
+	closers []rune
 }
 
 func (ps *parser) parse(n Node) parsed {
@@ -69,6 +71,34 @@ func (ps *parser) assembleError() error {
 
 const eof rune = -1
 
+func (ps *parser) pushCloser(r rune) { ps.closers = append(ps.closers, r) }
+func (ps *parser) popCloser()        { ps.closers = ps.closers[:len(ps.closers)-1] }
+
+// Returns whether r ends the chunk being parsed, either because it is the end
+// of the source or it closes one of the enclosing chunks. Closers of outer
+// chunks are included, so that an unclosed inner chunk doesn't consume the rest
+// of its parent.
+func (ps *parser) endsChunk(r rune) bool {
+	if r == eof {
+		return true
+	}
+	for _, closer := range ps.closers {
+		if r == closer {
+			return true
+		}
+	}
+	return false
+}
+
+// Skips text that can't be parsed, up to the next pipeline separator, pipe
+// symbol or the end of the chunk being parsed, and adds it to n as a Sep.
+func (ps *parser) skipJunk(n Node) {
+	for r := ps.peek(); !(r == '|' || isPipelineSep(r) || ps.endsChunk(r)); r = ps.peek() {
+		ps.next()
+	}
+	addSep(n, ps)
+}
+
 func (ps *parser) peek() rune {
 	if ps.pos == len(ps.src) {
 		return eof
@@ -101,7 +131,7 @@ func (ps *parser) backup() {
 }
 
 func (ps *parser) errorp(r diag.Ranger, e error) {
-	ps.errors.add(e.Error(), diag.NewContext(ps.srcName, ps.src, r))
+	ps.errors.add(e.Error(), ps.srcMeta.Context(r))
 }
 
 func (ps *parser) error(e error) {
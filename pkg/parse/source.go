@@ -2,6 +2,8 @@ package parse
 
 import (
 	"fmt"
+
+	"github.com/elves/elvish/pkg/diag"
 )
 
 // TODO(xiaq): Move this into the diag package after implementing phantom types.
@@ -11,6 +13,23 @@ type Source struct {
 	Name   string
 	Code   string
 	IsFile bool
+
+	// A pointer, so that Source remains comparable.
+	sourceMap *SourceMap
+}
+
+// SourceMap maps parts of the code of a Source to the sources they are
+// generated from.
+type SourceMap []Mapping
+
+// Mapping maps a range of the code of a Source to a range in another source,
+// from which the code is generated.
+type Mapping struct {
+	// The range in the generated code.
+	From, To int
+	// The source the range is generated from, and the range in it.
+	Origin               Source
+	OriginFrom, OriginTo int
 }
 
 // SourceForTest returns a Source used for testing.
@@ -18,6 +37,46 @@ func SourceForTest(code string) Source {
 	return Source{Name: "[test]", Code: code}
 }
 
+// WithSourceMap returns a copy of src with the given source map, which is used
+// by Context to report positions relative to the sources the code is generated
+// from.
+func (src Source) WithSourceMap(m SourceMap) Source {
+	src.sourceMap = &m
+	return src
+}
+
+// Locate returns the source and the range in it that the given range of the
+// code is generated from. If the range lies within a range of the source map,
+// it is mapped to the origin of the mapping, recursively; otherwise it is
+// returned as is, along with src. The position within the origin is preserved
+// when the two ranges of the mapping have the same length; otherwise the
+// whole range of the origin is returned.
+func (src Source) Locate(r diag.Ranger) (Source, diag.Ranging) {
+	rg := r.Range()
+	if src.sourceMap == nil {
+		return src, rg
+	}
+	for _, m := range *src.sourceMap {
+		if m.From <= rg.From && rg.To <= m.To {
+			origin := diag.Ranging{From: m.OriginFrom, To: m.OriginTo}
+			if m.To-m.From == m.OriginTo-m.OriginFrom {
+				origin = diag.Ranging{
+					From: m.OriginFrom + rg.From - m.From,
+					To:   m.OriginFrom + rg.To - m.From}
+			}
+			return m.Origin.Locate(origin)
+		}
+	}
+	return src, rg
+}
+
+// Context returns a diag.Context for the given range of the code, which points
+// to the source the range is generated from as determined by Locate.
+func (src Source) Context(r diag.Ranger) *diag.Context {
+	src, rg := src.Locate(r)
+	return diag.NewContext(src.Name, src.Code, rg)
+}
+
 // IsStructMap marks that Source is a structmap.
 func (src Source) IsStructMap() {}
 
@@ -3,6 +3,7 @@ package parse_test
 import (
 	"testing"
 
+	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/eval/vals"
 	. "github.com/elves/elvish/pkg/parse"
 )
@@ -16,3 +17,54 @@ func TestSourceAsStructMap(t *testing.T) {
 	vals.TestValue(t, Source{Name: "/etc/rc.elv", Code: "echo", IsFile: true}).
 		Index("is-file", true)
 }
+
+func TestSource_Locate(t *testing.T) {
+	tpl := Source{Name: "tpl.elv", Code: "echo {{x}}; fail", IsFile: true}
+	gen := Source{Name: "gen", Code: "echo foo; fail"}.WithSourceMap(SourceMap{
+		{From: 0, To: 5, Origin: tpl, OriginFrom: 0, OriginTo: 5},
+		{From: 5, To: 8, Origin: tpl, OriginFrom: 5, OriginTo: 10},
+		{From: 8, To: 14, Origin: tpl, OriginFrom: 10, OriginTo: 16},
+	})
+	// Source maps are followed recursively.
+	gen2 := Source{Name: "gen2", Code: "nop; echo foo; fail"}.WithSourceMap(SourceMap{
+		{From: 5, To: 19, Origin: gen, OriginFrom: 0, OriginTo: 14},
+	})
+
+	tests := []struct {
+		src      Source
+		from, to int
+		wantSrc  Source
+		wantFrom int
+		wantTo   int
+	}{
+		// Ranges with the same length preserve the relative position.
+		{gen, 0, 4, tpl, 0, 4},
+		{gen, 10, 14, tpl, 12, 16},
+		// Ranges with different lengths map to the whole range.
+		{gen, 6, 7, tpl, 5, 10},
+		// Ranges not within one mapping are not mapped.
+		{gen, 0, 8, gen, 0, 8},
+		{gen2, 0, 3, gen2, 0, 3},
+		{gen2, 15, 19, tpl, 12, 16},
+	}
+	for _, test := range tests {
+		src, r := test.src.Locate(diag.Ranging{From: test.from, To: test.to})
+		if src.Name != test.wantSrc.Name || r.From != test.wantFrom || r.To != test.wantTo {
+			t.Errorf("%s.Locate(%d, %d) -> %s, %d, %d, want %s, %d, %d",
+				test.src.Name, test.from, test.to, src.Name, r.From, r.To,
+				test.wantSrc.Name, test.wantFrom, test.wantTo)
+		}
+	}
+}
+
+func TestSource_ParseErrorsAreLocated(t *testing.T) {
+	tpl := Source{Name: "tpl.elv", Code: "nop\necho ]"}
+	gen := Source{Name: "gen", Code: "echo ]"}.WithSourceMap(SourceMap{
+		{From: 0, To: 6, Origin: tpl, OriginFrom: 4, OriginTo: 10},
+	})
+	_, err := Parse(gen)
+	ctx := GetError(err).Entries[0].Context
+	if ctx.Name != "tpl.elv" || ctx.From != 9 || ctx.To != 10 {
+		t.Errorf("got context %s %d-%d, want tpl.elv 9-10", ctx.Name, ctx.From, ctx.To)
+	}
+}
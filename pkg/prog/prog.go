@@ -10,9 +10,11 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"runtime/pprof"
 	"strconv"
+	"strings"
 
 	"github.com/elves/elvish/pkg/logutil"
 )
@@ -21,24 +23,98 @@ import (
 // resembles "elvi".
 const defaultWebPort = 3171
 
-// ShowDeprecations is a global flag that controls whether to show deprecations.
-var ShowDeprecations = false
+// DeprecationLevel controls which deprecations are shown. Each deprecation has
+// a level, the minor version of Elvish in which it was introduced, like 15 for
+// 0.15.0, and is only shown when its level is at most DeprecationLevel. It is
+// 0 by default, hiding all deprecations.
+var DeprecationLevel = 0
 
-// SetShowDeprecations sets ShowDeprecations to the given value, and returns a
+// AllDeprecations is a value of DeprecationLevel that shows all deprecations.
+const AllDeprecations = math.MaxInt32
+
+// ShowDeprecation returns whether to show a deprecation of the given level.
+func ShowDeprecation(level int) bool {
+	return level <= DeprecationLevel
+}
+
+// SetDeprecationLevel sets DeprecationLevel to the given value, and returns a
 // function to restore the old value.
+func SetDeprecationLevel(level int) func() {
+	save := DeprecationLevel
+	DeprecationLevel = level
+	return func() { DeprecationLevel = save }
+}
+
+// SetShowDeprecations sets DeprecationLevel to AllDeprecations if b is true and
+// 0 otherwise, and returns a function to restore the old value.
 func SetShowDeprecations(b bool) func() {
-	save := ShowDeprecations
-	ShowDeprecations = b
-	return func() { ShowDeprecations = save }
+	if b {
+		return SetDeprecationLevel(AllDeprecations)
+	}
+	return SetDeprecationLevel(0)
+}
+
+// A flag.Value for DeprecationLevel. It can be used like a boolean flag, in
+// which case it shows either all or no deprecations, or with a level.
+type deprecationLevelFlag struct{ level *int }
+
+func (f deprecationLevelFlag) IsBoolFlag() bool { return true }
+
+func (f deprecationLevelFlag) String() string {
+	if f.level == nil {
+		return "false"
+	}
+	switch *f.level {
+	case 0:
+		return "false"
+	case AllDeprecations:
+		return "true"
+	default:
+		return strconv.Itoa(*f.level)
+	}
+}
+
+func (f deprecationLevelFlag) Set(s string) error {
+	if b, err := strconv.ParseBool(s); err == nil {
+		if b {
+			*f.level = AllDeprecations
+		} else {
+			*f.level = 0
+		}
+		return nil
+	}
+	level, err := strconv.Atoi(s)
+	if err != nil || level < 0 {
+		return fmt.Errorf("must be a boolean or a non-negative level, got %q", s)
+	}
+	*f.level = level
+	return nil
+}
+
+// A flag.Value that can be given multiple times, collecting all the values.
+type stringsFlag struct{ values *[]string }
+
+func (f stringsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, " ")
+}
+
+func (f stringsFlag) Set(s string) error {
+	*f.values = append(*f.values, s)
+	return nil
 }
 
 // Flags keeps command-line flags.
 type Flags struct {
-	Log, LogPrefix, CPUProfile string
+	Log, LogPrefix, CPUProfile, Trace string
 
 	Help, Version, BuildInfo, JSON bool
 
-	CodeInArg, CompileOnly, NoRc bool
+	CodeInArg, CompileOnly, NoRc, Fmt, Test bool
+	// Code snippets given with -e, in order.
+	Eval []string
 
 	Web  bool
 	Port int
@@ -46,6 +122,8 @@ type Flags struct {
 	Daemon bool
 	Forked int
 
+	SpawnHelper bool
+
 	Bin, DB, Sock string
 }
 
@@ -57,6 +135,7 @@ func newFlagSet(stderr io.Writer, f *Flags) *flag.FlagSet {
 	fs.StringVar(&f.Log, "log", "", "a file to write debug log to")
 	fs.StringVar(&f.LogPrefix, "logprefix", "", "the prefix for the daemon log file")
 	fs.StringVar(&f.CPUProfile, "cpuprofile", "", "write cpu profile to file")
+	fs.StringVar(&f.Trace, "trace", "", "write a trace of evaluation events to file")
 
 	fs.BoolVar(&f.Help, "help", false, "show usage help and quit")
 	fs.BoolVar(&f.Version, "version", false, "show version and quit")
@@ -64,19 +143,27 @@ func newFlagSet(stderr io.Writer, f *Flags) *flag.FlagSet {
 	fs.BoolVar(&f.JSON, "json", false, "show output in JSON. Useful with -buildinfo.")
 
 	fs.BoolVar(&f.CodeInArg, "c", false, "take first argument as code to execute")
-	fs.BoolVar(&f.CompileOnly, "compileonly", false, "Parse/Compile but do not execute")
+	fs.Var(stringsFlag{&f.Eval}, "e",
+		"code to execute; can be given multiple times, in which case they are executed in order")
+	fs.BoolVar(&f.CompileOnly, "compile-only", false, "parse and compile a script without running it")
+	fs.BoolVar(&f.CompileOnly, "compileonly", false, "same as -compile-only")
 	fs.BoolVar(&f.NoRc, "norc", false, "run elvish without invoking rc.elv")
+	fs.BoolVar(&f.Fmt, "fmt", false, "format the given files, or stdin, and write the result to stdout")
+	fs.BoolVar(&f.Test, "test", false, "run the tests in the given files or directories, or the working directory")
 
 	fs.BoolVar(&f.Web, "web", false, "run backend of web interface")
 	fs.IntVar(&f.Port, "port", defaultWebPort, "the port of the web backend")
 
 	fs.BoolVar(&f.Daemon, "daemon", false, "run daemon instead of shell")
+	fs.BoolVar(&f.SpawnHelper, "spawn-helper", false,
+		"apply spawn attributes and execute the given command; used internally")
 
 	fs.StringVar(&f.Bin, "bin", "", "path to the elvish binary")
 	fs.StringVar(&f.DB, "db", "", "path to the database")
 	fs.StringVar(&f.Sock, "sock", "", "path to the daemon socket")
 
-	fs.BoolVar(&ShowDeprecations, "show-deprecations", ShowDeprecations, "whether to show deprecations")
+	fs.Var(deprecationLevelFlag{&DeprecationLevel}, "show-deprecations",
+		"show deprecations; use -show-deprecations=N to only show those introduced in 0.N.x or earlier")
 
 	return fs
 }
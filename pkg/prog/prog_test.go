@@ -2,6 +2,7 @@ package prog
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	. "github.com/elves/elvish/pkg/prog/progtest"
@@ -56,9 +57,46 @@ func TestShowDeprecations(t *testing.T) {
 	defer f.Cleanup()
 
 	Run(f.Fds(), Elvish("-show-deprecations"), testProgram{shouldRun: true})
-	if !ShowDeprecations {
-		t.Errorf("ShowDeprecations = false, want true")
+	if DeprecationLevel != AllDeprecations {
+		t.Errorf("DeprecationLevel = %d, want AllDeprecations", DeprecationLevel)
 	}
+	if !ShowDeprecation(15) {
+		t.Errorf("ShowDeprecation(15) = false, want true")
+	}
+}
+
+func TestShowDeprecations_Level(t *testing.T) {
+	restore := SetShowDeprecations(false)
+	defer restore()
+	f := Setup()
+	defer f.Cleanup()
+
+	Run(f.Fds(), Elvish("-show-deprecations=14"), testProgram{shouldRun: true})
+	if DeprecationLevel != 14 {
+		t.Errorf("DeprecationLevel = %d, want 14", DeprecationLevel)
+	}
+	if !ShowDeprecation(14) || ShowDeprecation(15) {
+		t.Errorf("want deprecations of level 14 but not 15 to be shown")
+	}
+
+	Run(f.Fds(), Elvish("-show-deprecations=false"), testProgram{shouldRun: true})
+	if DeprecationLevel != 0 {
+		t.Errorf("DeprecationLevel = %d, want 0", DeprecationLevel)
+	}
+}
+
+func TestShowDeprecations_BadLevel(t *testing.T) {
+	restore := SetShowDeprecations(false)
+	defer restore()
+	f := Setup()
+	defer f.Cleanup()
+
+	exit := Run(f.Fds(), Elvish("-show-deprecations=x"), testProgram{shouldRun: true})
+
+	if exit != 2 {
+		t.Errorf("got exit %v, want 2", exit)
+	}
+	f.TestOutSnippet(t, 2, "must be a boolean or a non-negative level")
 }
 
 func TestNoProgram(t *testing.T) {
@@ -143,3 +181,44 @@ func (p testProgram) Run(fds [3]*os.File, _ *Flags, args []string) error {
 	fds[1].WriteString(p.writeOut)
 	return p.returnErr
 }
+
+func TestEvalFlag(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	p := &flagsProgram{}
+	Run(f.Fds(), Elvish("-e", "echo a", "-e", "echo b", "arg"), p)
+
+	if want := []string{"echo a", "echo b"}; !reflect.DeepEqual(p.flags.Eval, want) {
+		t.Errorf("got Eval %v, want %v", p.flags.Eval, want)
+	}
+	if want := []string{"arg"}; !reflect.DeepEqual(p.args, want) {
+		t.Errorf("got args %v, want %v", p.args, want)
+	}
+}
+
+func TestCompileOnlyFlag(t *testing.T) {
+	for _, flag := range []string{"-compileonly", "-compile-only", "--compile-only"} {
+		f := Setup()
+		p := &flagsProgram{}
+		Run(f.Fds(), Elvish(flag, "a.elv"), p)
+		f.Cleanup()
+
+		if !p.flags.CompileOnly {
+			t.Errorf("%s didn't set CompileOnly", flag)
+		}
+	}
+}
+
+// A Program that always runs and records the flags and arguments.
+type flagsProgram struct {
+	flags *Flags
+	args  []string
+}
+
+func (p *flagsProgram) ShouldRun(*Flags) bool { return true }
+
+func (p *flagsProgram) Run(fds [3]*os.File, f *Flags, args []string) error {
+	p.flags, p.args = f, args
+	return nil
+}
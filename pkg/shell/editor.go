@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/elves/elvish/pkg/strutil"
 )
@@ -13,6 +14,18 @@ type editor interface {
 	ReadCode() (string, error)
 }
 
+// An optional interface implemented by editors that can capture the output of
+// the commands they read.
+type outputCapturer interface {
+	CaptureOutput(out *os.File) (*os.File, func())
+}
+
+// An optional interface implemented by editors that want to know the duration
+// and the error of the commands they read.
+type commandReporter interface {
+	ReportCommand(duration time.Duration, err error)
+}
+
 type minEditor struct {
 	in  *bufio.Reader
 	out io.Writer
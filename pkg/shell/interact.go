@@ -13,6 +13,7 @@ import (
 	"github.com/elves/elvish/pkg/diag"
 	"github.com/elves/elvish/pkg/edit"
 	"github.com/elves/elvish/pkg/eval"
+	promptmod "github.com/elves/elvish/pkg/eval/mods/prompt"
 	"github.com/elves/elvish/pkg/eval/vals"
 	"github.com/elves/elvish/pkg/eval/vars"
 	"github.com/elves/elvish/pkg/parse"
@@ -28,6 +29,8 @@ var interactiveRescueShell bool = true
 type InteractConfig struct {
 	SpawnDaemon bool
 	Paths       Paths
+	// If not empty, evaluation events are traced to this file.
+	Trace string
 }
 
 // Interactive mode panic handler.
@@ -48,7 +51,7 @@ func Interact(fds [3]*os.File, cfg *InteractConfig) {
 	if interactiveRescueShell {
 		defer handlePanic()
 	}
-	ev, cleanup := setupShell(fds, cfg.Paths, cfg.SpawnDaemon)
+	ev, cleanup := setupShell(fds, cfg.Paths, cfg.SpawnDaemon, cfg.Trace)
 	defer cleanup()
 
 	// Build Editor.
@@ -56,6 +59,7 @@ func Interact(fds [3]*os.File, cfg *InteractConfig) {
 	if sys.IsATTY(fds[0]) {
 		newed := edit.NewEditor(cli.StdTTY, ev, ev.DaemonClient)
 		ev.Builtin.Append(eval.NsBuilder{}.AddNs("edit", newed.Ns()).Ns())
+		ev.InstallModule("prompt", promptmod.Ns(ev, newed))
 		ed = newed
 	} else {
 		ed = newMinEditor(fds[0], fds[2])
@@ -100,12 +104,22 @@ func Interact(fds [3]*os.File, cfg *InteractConfig) {
 		// No error; reset cooldown.
 		cooldown = time.Second
 
-		err = evalInTTY(ev, fds,
-			parse.Source{Name: fmt.Sprintf("[tty %v]", cmdNum), Code: line})
+		src := parse.Source{Name: fmt.Sprintf("[tty %v]", cmdNum), Code: line}
+		start := time.Now()
+		if c, ok := ed.(outputCapturer); ok {
+			out, done := c.CaptureOutput(fds[1])
+			err = evalInTTYWithOutput(ev, fds, out, src)
+			done()
+		} else {
+			err = evalInTTY(ev, fds, src)
+		}
 		term.Sanitize(fds[0], fds[2])
 		if err != nil {
 			diag.ShowError(fds[2], err)
 		}
+		if r, ok := ed.(commandReporter); ok {
+			r.ReportCommand(time.Since(start), err)
+		}
 	}
 }
 
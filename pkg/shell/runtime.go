@@ -10,13 +10,30 @@ import (
 
 	"github.com/elves/elvish/pkg/daemon"
 	"github.com/elves/elvish/pkg/eval"
+	aliasmod "github.com/elves/elvish/pkg/eval/mods/alias"
+	bytesmod "github.com/elves/elvish/pkg/eval/mods/bytes"
 	daemonmod "github.com/elves/elvish/pkg/eval/mods/daemon"
+	"github.com/elves/elvish/pkg/eval/mods/direnv"
+	"github.com/elves/elvish/pkg/eval/mods/file"
+	"github.com/elves/elvish/pkg/eval/mods/flag"
+	"github.com/elves/elvish/pkg/eval/mods/format"
 	mathmod "github.com/elves/elvish/pkg/eval/mods/math"
+	netmod "github.com/elves/elvish/pkg/eval/mods/net"
+	nsmod "github.com/elves/elvish/pkg/eval/mods/ns"
+	parsemod "github.com/elves/elvish/pkg/eval/mods/parse"
 	"github.com/elves/elvish/pkg/eval/mods/platform"
+	"github.com/elves/elvish/pkg/eval/mods/proc"
+	"github.com/elves/elvish/pkg/eval/mods/rand"
 	"github.com/elves/elvish/pkg/eval/mods/re"
-	"github.com/elves/elvish/pkg/eval/mods/store"
+	runtimemod "github.com/elves/elvish/pkg/eval/mods/runtime"
+	signalmod "github.com/elves/elvish/pkg/eval/mods/signal"
+	storemod "github.com/elves/elvish/pkg/eval/mods/store"
 	"github.com/elves/elvish/pkg/eval/mods/str"
+	testmod "github.com/elves/elvish/pkg/eval/mods/test"
+	timemod "github.com/elves/elvish/pkg/eval/mods/time"
 	"github.com/elves/elvish/pkg/eval/mods/unix"
+	varmod "github.com/elves/elvish/pkg/eval/mods/var"
+	"github.com/elves/elvish/pkg/store"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -39,6 +56,7 @@ const (
 
 const (
 	daemonWontWorkMsg     = "Daemon-related functions will likely not work."
+	localStoreMsg         = "Accessing the database directly instead; daemon-related functions will be slower."
 	connectionShutdownFmt = "Socket file %s exists but is not responding to request. This is likely due to abnormal shutdown of the daemon. Going to remove socket file and re-spawn a daemon.\n"
 )
 
@@ -49,10 +67,25 @@ var errInvalidDB = errors.New("daemon reported that database is invalid. If you
 func InitRuntime(stderr io.Writer, p Paths, spawn bool) *eval.Evaler {
 	ev := eval.NewEvaler()
 	ev.SetLibDir(p.LibDir)
+	ev.InstallModule("alias", aliasmod.Ns(ev))
+	ev.InstallModule("bytes", bytesmod.Ns)
+	ev.InstallModule("file", file.Ns)
+	ev.InstallModule("flag", flag.Ns)
+	ev.InstallModule("format", format.Ns)
 	ev.InstallModule("math", mathmod.Ns)
+	ev.InstallModule("net", netmod.Ns)
+	ev.InstallModule("ns", nsmod.Ns)
+	ev.InstallModule("parse", parsemod.Ns)
 	ev.InstallModule("platform", platform.Ns)
+	ev.InstallModule("proc", proc.Ns)
+	ev.InstallModule("rand", rand.Ns)
 	ev.InstallModule("re", re.Ns)
+	ev.InstallModule("runtime", runtimemod.Ns(ev))
+	ev.InstallModule("signal", signalmod.Ns)
 	ev.InstallModule("str", str.Ns)
+	ev.InstallModule("test", testmod.Ns(testmod.NewReporter("tap")))
+	ev.InstallModule("time", timemod.Ns)
+	ev.InstallModule("var", varmod.Ns)
 	if unix.ExposeUnixNs {
 		ev.InstallModule("unix", unix.Ns)
 	}
@@ -69,12 +102,18 @@ func InitRuntime(stderr io.Writer, p Paths, spawn bool) *eval.Evaler {
 		client, err := connectToDaemon(stderr, spawnCfg)
 		if err != nil {
 			fmt.Fprintln(stderr, "Cannot connect to daemon:", err)
-			fmt.Fprintln(stderr, daemonWontWorkMsg)
+			if err != errInvalidDB && localStoreWorks(p.Db) {
+				fmt.Fprintln(stderr, localStoreMsg)
+				client = daemon.NewLocalClient(p.Db)
+			} else {
+				fmt.Fprintln(stderr, daemonWontWorkMsg)
+			}
 		}
 		// Even if error is not nil, we install daemon-related functionalities
 		// anyway. Daemon may eventually come online and become functional.
 		ev.InstallDaemonClient(client)
-		ev.InstallModule("store", store.Ns(client))
+		ev.InstallModule("store", storemod.Ns(client))
+		ev.InstallModule("direnv", direnv.Ns(ev, client))
 		ev.InstallModule("daemon", daemonmod.Ns(client, spawnCfg))
 	}
 	return ev
@@ -163,6 +202,18 @@ func connectToDaemon(stderr io.Writer, spawnCfg *daemon.SpawnConfig) (daemon.Cli
 	return cl, fmt.Errorf("daemon unreachable after waiting for %s", daemonWaitLoops*daemonWaitPerLoop)
 }
 
+// Reports whether the database can be accessed directly, without the daemon.
+func localStoreWorks(dbPath string) bool {
+	s := store.NewFileStore(dbPath)
+	defer s.Close()
+	_, err := s.NextCmdSeq()
+	if err != nil {
+		logger.Println("cannot access database directly:", err)
+		return false
+	}
+	return true
+}
+
 func detectDaemon(sockpath string, cl daemon.Client) (daemonStatus, error) {
 	_, err := os.Stat(sockpath)
 	if err != nil {
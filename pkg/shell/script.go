@@ -10,73 +10,166 @@ import (
 	"unicode/utf8"
 
 	"github.com/elves/elvish/pkg/diag"
+	"github.com/elves/elvish/pkg/eval"
 	"github.com/elves/elvish/pkg/parse"
 )
 
+// Exit statuses of scripts, other than those of external commands that cause
+// the script to fail; see exitStatusOf.
+const (
+	// An uncaught exception that is not caused by an external command.
+	exitException = 1
+	// Parse or compilation errors.
+	exitCompileError = 2
+	// The script can't be read, or is not UTF-8.
+	exitCannotRead = 126
+	// The script doesn't exist.
+	exitNotFound = 127
+	// The script was interrupted, 128 + SIGINT.
+	exitInterrupted = 130
+)
+
 // ScriptConfig keeps configuration for the script mode.
 type ScriptConfig struct {
 	SpawnDaemon bool
 	Paths       Paths
+	// If not empty, evaluation events are traced to this file.
+	Trace string
 
-	Cmd         bool
+	// If true, the first argument is the code to execute instead of the path
+	// of the script.
+	Cmd bool
+	// If not empty, these pieces of code are executed in order instead of a
+	// script, and all the arguments are available to them in $args.
+	Snippets    []string
 	CompileOnly bool
 	JSON        bool
 }
 
 // Script executes a shell script.
 func Script(fds [3]*os.File, args []string, cfg *ScriptConfig) int {
-	ev, cleanup := setupShell(fds, cfg.Paths, cfg.SpawnDaemon)
+	ev, cleanup := setupShell(fds, cfg.Paths, cfg.SpawnDaemon, cfg.Trace)
 	defer cleanup()
 
-	arg0 := args[0]
-	ev.SetArgs(args[1:])
-
-	var name, code string
-	if cfg.Cmd {
-		name = "code from -c"
-		code = arg0
-	} else {
-		var err error
-		name, err = filepath.Abs(arg0)
-		if err != nil {
-			fmt.Fprintf(fds[2],
-				"cannot get full path of script %q: %v\n", arg0, err)
-			return 2
+	var srcs []parse.Source
+	if len(cfg.Snippets) > 0 {
+		ev.SetArgs(args)
+		for i, code := range cfg.Snippets {
+			srcs = append(srcs, parse.Source{
+				Name: fmt.Sprintf("code from -e #%d", i+1), Code: code, IsFile: true})
 		}
-		code, err = readFileUTF8(name)
-		if err != nil {
-			fmt.Fprintf(fds[2], "cannot read script %q: %v\n", name, err)
-			return 2
+	} else {
+		arg0 := args[0]
+		ev.SetArgs(args[1:])
+
+		var name, code string
+		if cfg.Cmd {
+			name = "code from -c"
+			code = arg0
+		} else {
+			var err error
+			name, err = filepath.Abs(arg0)
+			if err != nil {
+				fmt.Fprintf(fds[2],
+					"cannot get full path of script %q: %v\n", arg0, err)
+				return exitCannotRead
+			}
+			code, err = readFileUTF8(name)
+			if err != nil {
+				fmt.Fprintf(fds[2], "cannot read script %q: %v\n", name, err)
+				if os.IsNotExist(err) {
+					return exitNotFound
+				}
+				return exitCannotRead
+			}
 		}
+		srcs = []parse.Source{{Name: name, Code: code, IsFile: true}}
 	}
 
-	src := parse.Source{Name: name, Code: code, IsFile: true}
 	if cfg.CompileOnly {
-		parseErr, compileErr := ev.Check(src, fds[2])
+		var parseErrs []*parse.Error
+		var compileErrs []*diag.Error
+		for _, src := range srcs {
+			parseErr, compileErr := ev.Check(src, fds[2])
+			if parseErr != nil {
+				parseErrs = append(parseErrs, parseErr)
+			}
+			compileErrs = append(compileErrs, eval.GetCompilationErrors(compileErr)...)
+		}
 		if cfg.JSON {
-			fmt.Fprintf(fds[1], "%s\n", errorsToJSON(parseErr, compileErr))
+			fmt.Fprintf(fds[1], "%s\n", errorsToJSON(parseErrs, compileErrs))
 		} else {
-			if parseErr != nil {
-				diag.ShowError(fds[2], parseErr)
+			for _, err := range parseErrs {
+				diag.ShowError(fds[2], err)
 			}
-			if compileErr != nil {
-				diag.ShowError(fds[2], compileErr)
+			for _, err := range compileErrs {
+				diag.ShowError(fds[2], err)
 			}
 		}
-		if parseErr != nil || compileErr != nil {
-			return 2
+		if len(parseErrs) > 0 || len(compileErrs) > 0 {
+			return exitCompileError
 		}
 	} else {
-		err := evalInTTY(ev, fds, src)
-		if err != nil {
-			diag.ShowError(fds[2], err)
-			return 2
+		// Pieces of code given with -e are executed in order, stopping at the
+		// first one that throws an exception.
+		for _, src := range srcs {
+			err := evalInTTY(ev, fds, src)
+			if err != nil {
+				diag.ShowError(fds[2], err)
+				return exitStatusOf(err)
+			}
 		}
 	}
 
 	return 0
 }
 
+// Returns the exit status of a script that failed with err:
+//
+//   - If err is caused by an external command that exited with a non-zero
+//     status, the status of the command.
+//
+//   - If err is caused by an external command killed by a signal, 128 plus the
+//     signal number, like in POSIX shells.
+//
+//   - If err is a pipeline error, the exit status derived from the last error
+//     in the pipeline that is caused by an external command, or exitException
+//     if there is no such error.
+//
+//   - If err is a parse or compilation error, exitCompileError.
+//
+//   - If the script was interrupted, exitInterrupted.
+//
+//   - Otherwise, exitException.
+func exitStatusOf(err error) int {
+	if parse.GetError(err) != nil || eval.GetCompilationError(err) != nil {
+		return exitCompileError
+	}
+	switch reason := eval.Reason(err).(type) {
+	case eval.ExternalCmdExit:
+		ws := reason.WaitStatus
+		switch {
+		case ws.Exited():
+			return ws.ExitStatus()
+		case ws.Signaled():
+			return 128 + int(ws.Signal())
+		}
+	case eval.PipelineError:
+		for i := len(reason.Errors) - 1; i >= 0; i-- {
+			if exc := reason.Errors[i]; exc != nil && exc.Reason != nil {
+				if status := exitStatusOf(exc); status != exitException {
+					return status
+				}
+			}
+		}
+	default:
+		if reason == eval.ErrInterrupted {
+			return exitInterrupted
+		}
+	}
+	return exitException
+}
+
 var errSourceNotUTF8 = errors.New("source is not UTF-8")
 
 func readFileUTF8(fname string) (string, error) {
@@ -99,15 +192,15 @@ type errorInJSON struct {
 }
 
 // Converts parse and compilation errors into JSON.
-func errorsToJSON(parseErr *parse.Error, compileErr *diag.Error) []byte {
+func errorsToJSON(parseErrs []*parse.Error, compileErrs []*diag.Error) []byte {
 	var converted []errorInJSON
-	if parseErr != nil {
+	for _, parseErr := range parseErrs {
 		for _, e := range parseErr.Entries {
 			converted = append(converted,
 				errorInJSON{e.Context.Name, e.Context.From, e.Context.To, e.Message})
 		}
 	}
-	if compileErr != nil {
+	for _, compileErr := range compileErrs {
 		converted = append(converted,
 			errorInJSON{compileErr.Context.Name,
 				compileErr.Context.From, compileErr.Context.To, compileErr.Message})
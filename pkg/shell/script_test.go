@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	. "github.com/elves/elvish/pkg/prog/progtest"
@@ -23,8 +25,8 @@ func TestScript_BadFile(t *testing.T) {
 
 	ret := Script(f.Fds(), []string{"a.elv"}, &ScriptConfig{})
 
-	if ret != 2 {
-		t.Errorf("got ret %v, want 2", ret)
+	if ret != 127 {
+		t.Errorf("got ret %v, want 127", ret)
 	}
 	f.TestOutSnippet(t, 2, "cannot read script")
 	f.TestOut(t, 1, "")
@@ -40,6 +42,67 @@ func TestScript_Cmd(t *testing.T) {
 	f.TestOut(t, 2, "")
 }
 
+func TestScript_CmdWithArgs(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	Script(f.Fds(), []string{"echo $@args", "a b", "$c"}, &ScriptConfig{Cmd: true})
+
+	f.TestOut(t, 1, "a b $c\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestScript_Snippets(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	Script(f.Fds(), []string{"a", "b"},
+		&ScriptConfig{Snippets: []string{"x = foo", "echo $x $@args"}})
+
+	f.TestOut(t, 1, "foo a b\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestScript_SnippetsStopAtFirstException(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	exit := Script(f.Fds(), nil,
+		&ScriptConfig{Snippets: []string{"echo a", "fail bad", "echo b"}})
+
+	if exit != 1 {
+		t.Errorf("got exit %v, want 1", exit)
+	}
+	f.TestOut(t, 1, "a\n")
+	f.TestOutSnippet(t, 2, "bad")
+}
+
+func TestScript_Trace(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	Script(f.Fds(), []string{"echo hello"}, &ScriptConfig{Cmd: true, Trace: "trace"})
+
+	f.TestOut(t, 1, "hello\n")
+	content, err := ioutil.ReadFile("trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"name":"echo"`) {
+		t.Errorf("trace does not contain call to echo:\n%s", content)
+	}
+}
+
+func TestScript_BadTracePath(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	Script(f.Fds(), []string{"echo hello"}, &ScriptConfig{Cmd: true, Trace: "/a/bad/path"})
+
+	f.TestOut(t, 1, "hello\n")
+	f.TestOutSnippet(t, 2, "cannot create trace file")
+}
+
 var scriptErrorTests = []struct {
 	name        string
 	code        string
@@ -69,13 +132,17 @@ var scriptErrorTests = []struct {
 		code: "echo $a", compileOnly: true, json: true,
 		wantExit: 2,
 		wantOut:  `[{"fileName":"code from -c","start":5,"end":7,"message":"variable $a not found"}]` + "\n"},
+	{name: "multiple compile errors with -compileonly and -json",
+		code: "echo $a; echo $b", compileOnly: true, json: true,
+		wantExit: 2,
+		wantOut:  `[{"fileName":"code from -c","start":5,"end":7,"message":"variable $a not found"},{"fileName":"code from -c","start":14,"end":16,"message":"variable $b not found"}]` + "\n"},
 	{name: "parse error and compile error with -compileonly and -json",
 		code: "echo [$a", compileOnly: true, json: true,
 		wantExit: 2,
 		wantOut:  `[{"fileName":"code from -c","start":8,"end":8,"message":"should be ']'"},{"fileName":"code from -c","start":6,"end":8,"message":"variable $a not found"}]` + "\n"},
 	{name: "exception",
 		code:     "fail failure",
-		wantExit: 2,
+		wantExit: 1,
 		wantOut:  "",
 		wantErr:  "fail failure"},
 	{name: "exception with -compileonly",
@@ -91,7 +158,7 @@ func TestScript_Error(t *testing.T) {
 			exit := Script(f.Fds(), []string{test.code}, &ScriptConfig{
 				Cmd: true, CompileOnly: test.compileOnly, JSON: test.json})
 			if exit != test.wantExit {
-				t.Errorf("got exit code %v, want 2", test.wantExit)
+				t.Errorf("got exit code %v, want %v", exit, test.wantExit)
 			}
 			f.TestOut(t, 1, test.wantOut)
 			// When testing stderr output, we either only test that there is no
@@ -0,0 +1,42 @@
+// +build !windows,!plan9,!js
+
+package shell
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/eval"
+
+	. "github.com/elves/elvish/pkg/prog/progtest"
+)
+
+var scriptExitStatusTests = []struct {
+	name     string
+	code     string
+	wantExit int
+}{
+	{"external command", "sh -c 'exit 3'", 3},
+	{"external command killed by signal", "sh -c 'kill -TERM $$'", 128 + 15},
+	{"pipeline", "sh -c 'exit 4' | sh -c 'exit 5'", 5},
+	{"pipeline with exception", "sh -c 'exit 4' | fail bad", 4},
+	{"pipeline with only exceptions", "fail bad | fail bad", 1},
+}
+
+func TestScript_ExitStatus(t *testing.T) {
+	for _, test := range scriptExitStatusTests {
+		t.Run(test.name, func(t *testing.T) {
+			f := Setup()
+			defer f.Cleanup()
+			exit := Script(f.Fds(), []string{test.code}, &ScriptConfig{Cmd: true})
+			if exit != test.wantExit {
+				t.Errorf("got exit code %v, want %v", exit, test.wantExit)
+			}
+		})
+	}
+}
+
+func TestExitStatusOf_Interrupted(t *testing.T) {
+	if status := exitStatusOf(eval.ErrInterrupted); status != 130 {
+		t.Errorf("got %v, want 130", status)
+	}
+}
@@ -2,6 +2,8 @@
 package shell
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strconv"
@@ -30,41 +32,81 @@ func (program) Run(fds [3]*os.File, f *prog.Flags, args []string) error {
 	if f.NoRc {
 		p.Rc = ""
 	}
-	if len(args) > 0 {
+	if len(f.Eval) > 0 && f.CodeInArg {
+		return prog.BadUsage("-c and -e cannot be used together")
+	}
+	if f.Test {
+		if len(f.Eval) > 0 || f.CodeInArg {
+			return prog.BadUsage("-test cannot be used with -c or -e")
+		}
+		return prog.Exit(RunTests(fds, args, &TestConfig{Paths: p, JSON: f.JSON}))
+	}
+	if len(args) > 0 || len(f.Eval) > 0 {
 		exit := Script(
 			fds, args, &ScriptConfig{
-				Paths: p,
-				Cmd:   f.CodeInArg, CompileOnly: f.CompileOnly, JSON: f.JSON})
+				Paths: p, Trace: f.Trace,
+				Cmd: f.CodeInArg, Snippets: f.Eval,
+				CompileOnly: f.CompileOnly, JSON: f.JSON})
 		return prog.Exit(exit)
 	}
-	Interact(fds, &InteractConfig{SpawnDaemon: true, Paths: p})
+	Interact(fds, &InteractConfig{SpawnDaemon: true, Paths: p, Trace: f.Trace})
 	return nil
 }
 
-func setupShell(fds [3]*os.File, p Paths, spawn bool) (*eval.Evaler, func()) {
+func setupShell(fds [3]*os.File, p Paths, spawn bool, trace string) (*eval.Evaler, func()) {
 	restoreTTY := term.SetupGlobal()
 	ev := InitRuntime(fds[2], p, spawn)
+	stopTrace := startTrace(fds[2], ev, trace)
 	restoreSHLVL := incSHLVL()
 	sigCh := sys.NotifySignals()
 
 	go func() {
 		for sig := range sigCh {
 			logger.Println("signal", sig)
-			handleSignal(sig, fds[2])
+			if !eval.IsSignalTrapped(sig) {
+				handleSignal(sig, fds[2])
+			}
 		}
 	}()
 
 	return ev, func() {
 		signal.Stop(sigCh)
 		restoreSHLVL()
+		stopTrace()
 		CleanupRuntime(fds[2], ev)
 		restoreTTY()
 	}
 }
 
+// Starts writing evaluation events to the file at path if it is not empty, and
+// returns a function to stop doing so.
+func startTrace(stderr io.Writer, ev *eval.Evaler, path string) func() {
+	if path == "" {
+		return func() {}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: cannot create trace file:", err)
+		fmt.Fprintln(stderr, "Continuing without tracing.")
+		return func() {}
+	}
+	remove := ev.AddTracer(eval.NewTraceWriter(f))
+	return func() {
+		remove()
+		f.Close()
+	}
+}
+
 func evalInTTY(ev *eval.Evaler, fds [3]*os.File, src parse.Source) error {
+	return evalInTTYWithOutput(ev, fds, fds[1], src)
+}
+
+// Like evalInTTY, but byte output to stdout is written to out. Value output is
+// still written to fds[1].
+func evalInTTYWithOutput(ev *eval.Evaler, fds [3]*os.File, out *os.File, src parse.Source) error {
 	ports, cleanup := eval.PortsFromFiles(fds, ev)
 	defer cleanup()
+	ports[1].File = out
 	return ev.Eval(src, eval.EvalCfg{
 		Ports: ports[:], Interrupt: eval.ListenInterrupts, PutInFg: true})
 }
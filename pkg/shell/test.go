@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	testmod "github.com/elves/elvish/pkg/eval/mods/test"
+	"github.com/elves/elvish/pkg/parse"
+)
+
+// Suffix of the names of files that are run as tests when a directory is given
+// to the test mode.
+const testFileSuffix = "_test.elv"
+
+// TestConfig keeps configuration for the test mode.
+type TestConfig struct {
+	Paths Paths
+	// If true, test results are written in the JSON Lines format instead of
+	// TAP.
+	JSON bool
+}
+
+// RunTests runs test files, and returns the exit status, which is 0 when all
+// the tests pass and exitException otherwise.
+//
+// Each argument is either a test file, or a directory that is searched
+// recursively for files whose names end in _test.elv, skipping hidden
+// directories; when there are no arguments, the working directory is searched.
+// Each test file is evaluated with a fresh Evaler. The results of the tests
+// run with test:it are written to the standard output, followed by the number
+// of tests. An exception thrown by a test file outside test:it, including a
+// parse or compilation error, is reported as a failed test named after the
+// file.
+func RunTests(fds [3]*os.File, args []string, cfg *TestConfig) int {
+	format := "tap"
+	if cfg.JSON {
+		format = "json"
+	}
+	r := testmod.NewReporter(format)
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	for _, arg := range args {
+		files, err := findTestFiles(arg)
+		if err != nil {
+			r.Report(fds[1], arg, err, nil)
+		}
+		for _, file := range files {
+			r.Describe(file, func() error {
+				err := runTestFile(fds, cfg.Paths, r, file)
+				if err != nil {
+					r.Report(fds[1], "", err, nil)
+				}
+				return nil
+			})
+		}
+	}
+
+	r.Plan(fds[1])
+	if r.Failed() > 0 {
+		return exitException
+	}
+	return 0
+}
+
+func findTestFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+	var files []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(info.Name(), testFileSuffix) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func runTestFile(fds [3]*os.File, p Paths, r *testmod.Reporter, file string) error {
+	name, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	code, err := readFileUTF8(name)
+	if err != nil {
+		return err
+	}
+	ev := InitRuntime(fds[2], p, false)
+	defer CleanupRuntime(fds[2], ev)
+	ev.InstallModule("test", testmod.Ns(r))
+	return evalInTTY(ev, fds, parse.Source{Name: name, Code: code, IsFile: true})
+}
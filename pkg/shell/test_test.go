@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/elves/elvish/pkg/prog/progtest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestRunTests(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{
+		"lib": testutil.Dir{
+			"m.elv": "fn double [x]{ put $x$x }",
+			"m_test.elv": `
+use test
+use ./m
+test:describe double {
+  test:it works { test:assert-eq (m:double a) aa }
+  test:it fails { test:assert-eq (m:double a) a }
+}`,
+			"not-a-test.elv": "fail bad",
+		},
+		".hidden":      testutil.Dir{"x_test.elv": "fail bad"},
+		"bad_test.elv": "use test; test:it a { }; fail bad",
+	})
+
+	exit := RunTests(f.Fds(), nil, &TestConfig{})
+	mTest := filepath.Join("lib", "m_test.elv")
+
+	if exit != exitException {
+		t.Errorf("got exit %v, want %v", exit, exitException)
+	}
+	f.TestOut(t, 1,
+		"ok 1 - bad_test.elv a\n"+
+			"not ok 2 - bad_test.elv\n# bad\n"+
+			"ok 3 - "+mTest+" double works\n"+
+			"not ok 4 - "+mTest+" double fails\n# expected a, got aa\n"+
+			"1..4\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestRunTests_JSON(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+	testutil.ApplyDir(testutil.Dir{"a_test.elv": "use test; test:it a { }"})
+
+	exit := RunTests(f.Fds(), []string{"a_test.elv"}, &TestConfig{JSON: true})
+
+	if exit != 0 {
+		t.Errorf("got exit %v, want 0", exit)
+	}
+	f.TestOut(t, 1,
+		`{"name":"a_test.elv a","ok":true}`+"\n"+`{"total":1,"failed":0}`+"\n")
+	f.TestOut(t, 2, "")
+}
+
+func TestRunTests_NonexistentPath(t *testing.T) {
+	f := Setup()
+	defer f.Cleanup()
+
+	exit := RunTests(f.Fds(), []string{"nonexistent"}, &TestConfig{})
+
+	if exit != exitException {
+		t.Errorf("got exit %v, want %v", exit, exitException)
+	}
+	f.TestOutSnippet(t, 1, "not ok 1 - nonexistent\n")
+	f.TestOutSnippet(t, 1, "1..1\n")
+}
@@ -4,6 +4,11 @@ const (
 	bucketCmd       = "cmd"
 	bucketDir       = "dir"
 	bucketSharedVar = "shared_var"
+	bucketCmdTime   = "cmd_time"
+	bucketRetention = "retention"
+	bucketSecret    = "secret"
+	bucketDirStack  = "dir_stack"
+	bucketEnvTrust  = "env_trust"
 )
 
 // The following buckets were used before and are thus reserved:
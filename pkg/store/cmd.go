@@ -17,7 +17,7 @@ func init() {
 // NextCmdSeq returns the next sequence number of the command history.
 func (s *dbStore) NextCmdSeq() (int, error) {
 	var seq uint64
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		seq = b.Sequence() + 1
 		return nil
@@ -31,29 +31,38 @@ func (s *dbStore) AddCmd(cmd string) (int, error) {
 		seq uint64
 		err error
 	)
-	err = s.db.Update(func(tx *bolt.Tx) error {
+	err = s.update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		seq, err = b.NextSequence()
 		if err != nil {
 			return err
 		}
-		return b.Put(marshalSeq(seq), []byte(cmd))
+		key := marshalSeq(seq)
+		err = tx.Bucket([]byte(bucketCmdTime)).Put(key, marshalTime(now()))
+		if err != nil {
+			return err
+		}
+		return b.Put(key, []byte(cmd))
 	})
 	return int(seq), err
 }
 
 // DelCmd deletes a command history item with the given sequence number.
 func (s *dbStore) DelCmd(seq int) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketCmd))
-		return b.Delete(marshalSeq(uint64(seq)))
+	return s.update(func(tx *bolt.Tx) error {
+		key := marshalSeq(uint64(seq))
+		err := tx.Bucket([]byte(bucketCmdTime)).Delete(key)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketCmd)).Delete(key)
 	})
 }
 
 // Cmd queries the command history item with the specified sequence number.
 func (s *dbStore) Cmd(seq int) (string, error) {
 	var cmd string
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		v := b.Get(marshalSeq(uint64(seq)))
 		if v == nil {
@@ -68,7 +77,7 @@ func (s *dbStore) Cmd(seq int) (string, error) {
 // IterateCmds iterates all the commands in the specified range, and calls the
 // callback with the content of each command sequentially.
 func (s *dbStore) IterateCmds(from, upto int, f func(Cmd)) error {
-	return s.db.View(func(tx *bolt.Tx) error {
+	return s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		c := b.Cursor()
 		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil && unmarshalSeq(k) < uint64(upto); k, v = c.Next() {
@@ -102,7 +111,7 @@ func (s *dbStore) CmdsWithSeq(from, upto int) ([]Cmd, error) {
 // with the given prefix.
 func (s *dbStore) NextCmd(from int, prefix string) (Cmd, error) {
 	var cmd Cmd
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		c := b.Cursor()
 		p := []byte(prefix)
@@ -121,7 +130,7 @@ func (s *dbStore) NextCmd(from int, prefix string) (Cmd, error) {
 // with the given prefix.
 func (s *dbStore) PrevCmd(upto int, prefix string) (Cmd, error) {
 	var cmd Cmd
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketCmd))
 		c := b.Cursor()
 		p := []byte(prefix)
@@ -26,7 +26,10 @@ type DBStore interface {
 }
 
 type dbStore struct {
-	db *bolt.DB
+	// Mutex for db. It is only locked for writing when the database is being
+	// replaced by Compact.
+	mutex sync.RWMutex
+	db    *bolt.DB
 	// Waits is used for registering outstanding operations on the
 	waits sync.WaitGroup
 }
@@ -69,6 +72,18 @@ func NewStoreFromDB(db *bolt.DB) (DBStore, error) {
 	return st, err
 }
 
+func (s *dbStore) view(f func(*bolt.Tx) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db.View(f)
+}
+
+func (s *dbStore) update(f func(*bolt.Tx) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db.Update(f)
+}
+
 // Waits returns a WaitGroup used to register outstanding storage requests when
 // making calls asynchronously.
 func (s *dbStore) Waits() *sync.WaitGroup {
@@ -82,5 +97,7 @@ func (s *dbStore) Close() error {
 		return nil
 	}
 	s.waits.Wait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	return s.db.Close()
 }
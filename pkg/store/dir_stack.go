@@ -0,0 +1,60 @@
+package store
+
+import (
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrEmptyDirStack is returned by Store.PopDir when the directory stack is
+// empty.
+var ErrEmptyDirStack = errors.New("directory stack is empty")
+
+func init() {
+	initDB["initialize directory stack table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketDirStack))
+		return err
+	}
+}
+
+// PushDir pushes a directory onto the directory stack.
+func (s *dbStore) PushDir(d string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketDirStack))
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(marshalSeq(seq), []byte(d))
+	})
+}
+
+// PopDir removes the directory on the top of the directory stack and returns
+// it.
+func (s *dbStore) PopDir() (string, error) {
+	var dir string
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketDirStack))
+		k, v := b.Cursor().Last()
+		if k == nil {
+			return ErrEmptyDirStack
+		}
+		dir = string(v)
+		return b.Delete(k)
+	})
+	return dir, err
+}
+
+// DirStack returns all directories on the directory stack, starting from the
+// top.
+func (s *dbStore) DirStack() ([]string, error) {
+	var dirs []string
+	err := s.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucketDirStack)).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			dirs = append(dirs, string(v))
+		}
+		return nil
+	})
+	return dirs, err
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// EnvTrust records whether a per-directory environment file is trusted. The
+// zero value means that the file has been neither allowed nor denied.
+type EnvTrust struct {
+	Allowed bool
+	Denied  bool
+	// The hash of the content of the file when it was allowed. The file is
+	// only trusted as long as its content has the same hash.
+	Hash string
+}
+
+// Values in the env trust bucket are either "deny", or "allow " followed by
+// the hash.
+const (
+	envTrustAllow = "allow "
+	envTrustDeny  = "deny"
+)
+
+func init() {
+	initDB["initialize env trust table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketEnvTrust))
+		return err
+	}
+}
+
+// EnvTrust gets whether the environment file at the given path is trusted.
+func (s *dbStore) EnvTrust(path string) (EnvTrust, error) {
+	var t EnvTrust
+	err := s.view(func(tx *bolt.Tx) error {
+		v := string(tx.Bucket([]byte(bucketEnvTrust)).Get([]byte(path)))
+		switch {
+		case v == envTrustDeny:
+			t.Denied = true
+		case strings.HasPrefix(v, envTrustAllow):
+			t.Allowed, t.Hash = true, strings.TrimPrefix(v, envTrustAllow)
+		}
+		return nil
+	})
+	return t, err
+}
+
+// SetEnvTrust sets whether the environment file at the given path is trusted.
+// Setting the zero value forgets the file.
+func (s *dbStore) SetEnvTrust(path string, t EnvTrust) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketEnvTrust))
+		switch {
+		case t.Denied:
+			return b.Put([]byte(path), []byte(envTrustDeny))
+		case t.Allowed:
+			return b.Put([]byte(path), []byte(envTrustAllow+t.Hash))
+		default:
+			return b.Delete([]byte(path))
+		}
+	})
+}
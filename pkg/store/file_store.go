@@ -0,0 +1,210 @@
+package store
+
+import "sync"
+
+// NewFileStore creates a DBStore that accesses the database file directly,
+// without going through the daemon.
+//
+// The database is opened when it is first accessed, and kept open until the
+// store is closed; operations on the store are serialized. Since bolt holds an
+// exclusive flock on the database file while it is open, other processes can't
+// open the database in the meantime.
+func NewFileStore(dbname string) DBStore {
+	return &fileStore{dbname: dbname}
+}
+
+type fileStore struct {
+	dbname string
+	waits  sync.WaitGroup
+
+	mutex sync.Mutex
+	// Nil until the database is opened.
+	st DBStore
+}
+
+func (s *fileStore) withStore(f func(DBStore) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.st == nil {
+		st, err := NewStore(s.dbname)
+		if err != nil {
+			return err
+		}
+		s.st = st
+	}
+	return f(s.st)
+}
+
+// Waits returns a WaitGroup used to register outstanding operations, like the
+// one of DBStore.
+func (s *fileStore) Waits() *sync.WaitGroup { return &s.waits }
+
+// Close waits for all outstanding operations to finish, and closes the
+// database if it has been opened.
+func (s *fileStore) Close() error {
+	s.waits.Wait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.st == nil {
+		return nil
+	}
+	err := s.st.Close()
+	s.st = nil
+	return err
+}
+
+func (s *fileStore) NextCmdSeq() (seq int, err error) {
+	err = s.withStore(func(st DBStore) error {
+		seq, err = st.NextCmdSeq()
+		return err
+	})
+	return seq, err
+}
+
+func (s *fileStore) AddCmd(text string) (seq int, err error) {
+	err = s.withStore(func(st DBStore) error {
+		seq, err = st.AddCmd(text)
+		return err
+	})
+	return seq, err
+}
+
+func (s *fileStore) DelCmd(seq int) error {
+	return s.withStore(func(st DBStore) error { return st.DelCmd(seq) })
+}
+
+func (s *fileStore) Cmd(seq int) (text string, err error) {
+	err = s.withStore(func(st DBStore) error {
+		text, err = st.Cmd(seq)
+		return err
+	})
+	return text, err
+}
+
+func (s *fileStore) Cmds(from, upto int) (cmds []string, err error) {
+	err = s.withStore(func(st DBStore) error {
+		cmds, err = st.Cmds(from, upto)
+		return err
+	})
+	return cmds, err
+}
+
+func (s *fileStore) CmdsWithSeq(from, upto int) (cmds []Cmd, err error) {
+	err = s.withStore(func(st DBStore) error {
+		cmds, err = st.CmdsWithSeq(from, upto)
+		return err
+	})
+	return cmds, err
+}
+
+func (s *fileStore) NextCmd(from int, prefix string) (cmd Cmd, err error) {
+	err = s.withStore(func(st DBStore) error {
+		cmd, err = st.NextCmd(from, prefix)
+		return err
+	})
+	return cmd, err
+}
+
+func (s *fileStore) PrevCmd(upto int, prefix string) (cmd Cmd, err error) {
+	err = s.withStore(func(st DBStore) error {
+		cmd, err = st.PrevCmd(upto, prefix)
+		return err
+	})
+	return cmd, err
+}
+
+func (s *fileStore) AddDir(dir string, incFactor float64) error {
+	return s.withStore(func(st DBStore) error { return st.AddDir(dir, incFactor) })
+}
+
+func (s *fileStore) DelDir(dir string) error {
+	return s.withStore(func(st DBStore) error { return st.DelDir(dir) })
+}
+
+func (s *fileStore) Dirs(blacklist map[string]struct{}) (dirs []Dir, err error) {
+	err = s.withStore(func(st DBStore) error {
+		dirs, err = st.Dirs(blacklist)
+		return err
+	})
+	return dirs, err
+}
+
+func (s *fileStore) PushDir(dir string) error {
+	return s.withStore(func(st DBStore) error { return st.PushDir(dir) })
+}
+
+func (s *fileStore) PopDir() (dir string, err error) {
+	err = s.withStore(func(st DBStore) error {
+		dir, err = st.PopDir()
+		return err
+	})
+	return dir, err
+}
+
+func (s *fileStore) DirStack() (dirs []string, err error) {
+	err = s.withStore(func(st DBStore) error {
+		dirs, err = st.DirStack()
+		return err
+	})
+	return dirs, err
+}
+
+func (s *fileStore) SharedVar(name string) (value string, err error) {
+	err = s.withStore(func(st DBStore) error {
+		value, err = st.SharedVar(name)
+		return err
+	})
+	return value, err
+}
+
+func (s *fileStore) SetSharedVar(name, value string) error {
+	return s.withStore(func(st DBStore) error { return st.SetSharedVar(name, value) })
+}
+
+func (s *fileStore) DelSharedVar(name string) error {
+	return s.withStore(func(st DBStore) error { return st.DelSharedVar(name) })
+}
+
+func (s *fileStore) Secret(name string) (value []byte, err error) {
+	err = s.withStore(func(st DBStore) error {
+		value, err = st.Secret(name)
+		return err
+	})
+	return value, err
+}
+
+func (s *fileStore) SetSecret(name string, value []byte) error {
+	return s.withStore(func(st DBStore) error { return st.SetSecret(name, value) })
+}
+
+func (s *fileStore) DelSecret(name string) error {
+	return s.withStore(func(st DBStore) error { return st.DelSecret(name) })
+}
+
+func (s *fileStore) EnvTrust(path string) (t EnvTrust, err error) {
+	err = s.withStore(func(st DBStore) error {
+		t, err = st.EnvTrust(path)
+		return err
+	})
+	return t, err
+}
+
+func (s *fileStore) SetEnvTrust(path string, t EnvTrust) error {
+	return s.withStore(func(st DBStore) error { return st.SetEnvTrust(path, t) })
+}
+
+func (s *fileStore) RetentionPolicy() (p RetentionPolicy, err error) {
+	err = s.withStore(func(st DBStore) error {
+		p, err = st.RetentionPolicy()
+		return err
+	})
+	return p, err
+}
+
+func (s *fileStore) SetRetentionPolicy(p RetentionPolicy) error {
+	return s.withStore(func(st DBStore) error { return st.SetRetentionPolicy(p) })
+}
+
+func (s *fileStore) Compact() error {
+	return s.withStore(func(st DBStore) error { return st.Compact() })
+}
@@ -0,0 +1,50 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/store/storetest"
+	"github.com/elves/elvish/pkg/testutil"
+)
+
+func TestFileStore(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	for _, test := range []func(*testing.T, store.Store){
+		storetest.TestCmd, storetest.TestDir, storetest.TestDirStack,
+		storetest.TestSharedVar, storetest.TestSecret, storetest.TestEnvTrust,
+		storetest.TestRetention,
+	} {
+		s := store.NewFileStore("db")
+		test(t, s)
+		if err := s.Close(); err != nil {
+			t.Errorf("Close -> error %v, want nil", err)
+		}
+	}
+}
+
+func TestFileStore_ReleasesDatabaseOnClose(t *testing.T) {
+	_, cleanup := testutil.InTestDir()
+	defer cleanup()
+
+	s := store.NewFileStore("db")
+	if _, err := s.AddCmd("echo foo"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	// Opening the database with NewStore fails if another Store still holds
+	// the lock on it.
+	st, err := store.NewStore("db")
+	if err != nil {
+		t.Fatalf("NewStore -> error %v, want nil", err)
+	}
+	st.Close()
+
+	// The database is reopened when the store is used again.
+	if cmd, err := s.Cmd(1); cmd != "echo foo" || err != nil {
+		t.Errorf("Cmd(1) -> (%q, %v), want (%q, nil)", cmd, err, "echo foo")
+	}
+	s.Close()
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RetentionPolicy specifies which entries are pruned when a Store is
+// compacted. A zero value in any field means no limit.
+type RetentionPolicy struct {
+	// Maximum number of commands to keep. When there are more commands, the
+	// oldest ones are pruned.
+	MaxCmds int
+	// Maximum age of commands to keep. Commands added before the time of
+	// commands was recorded are never pruned because of their age.
+	MaxCmdAge time.Duration
+	// Minimum score of directories to keep. Since the scores of all
+	// directories decay whenever a directory is added, directories that have
+	// not been visited for a long time have low scores.
+	MinDirScore float64
+}
+
+// Keys in the retention bucket.
+const (
+	keyMaxCmds     = "max-cmds"
+	keyMaxCmdAge   = "max-cmd-age"
+	keyMinDirScore = "min-dir-score"
+)
+
+// Used for getting the current time; can be overridden in tests.
+var now = time.Now
+
+func init() {
+	initDB["initialize command time table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdTime))
+		return err
+	}
+	initDB["initialize retention policy table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketRetention))
+		return err
+	}
+}
+
+// RetentionPolicy returns the retention policy used by Compact.
+func (s *dbStore) RetentionPolicy() (RetentionPolicy, error) {
+	var p RetentionPolicy
+	err := s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketRetention))
+		p = RetentionPolicy{
+			MaxCmds:     int(getUint64(b, keyMaxCmds)),
+			MaxCmdAge:   time.Duration(getUint64(b, keyMaxCmdAge)),
+			MinDirScore: math.Float64frombits(getUint64(b, keyMinDirScore)),
+		}
+		return nil
+	})
+	return p, err
+}
+
+// SetRetentionPolicy sets the retention policy used by Compact.
+func (s *dbStore) SetRetentionPolicy(p RetentionPolicy) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketRetention))
+		for k, v := range map[string]uint64{
+			keyMaxCmds:     uint64(p.MaxCmds),
+			keyMaxCmdAge:   uint64(p.MaxCmdAge),
+			keyMinDirScore: math.Float64bits(p.MinDirScore),
+		} {
+			err := b.Put([]byte(k), marshalSeq(v))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Compact prunes entries according to the retention policy, and rewrites the
+// database file to reclaim the space that was used by pruned entries.
+func (s *dbStore) Compact() error {
+	p, err := s.RetentionPolicy()
+	if err != nil {
+		return err
+	}
+	err = s.update(func(tx *bolt.Tx) error { return prune(tx, p) })
+	if err != nil {
+		return err
+	}
+	return s.rewrite()
+}
+
+func prune(tx *bolt.Tx, p RetentionPolicy) error {
+	cmds := tx.Bucket([]byte(bucketCmd))
+	times := tx.Bucket([]byte(bucketCmdTime))
+	var cmdsToDel [][]byte
+
+	if p.MaxCmds > 0 {
+		n := cmds.Stats().KeyN
+		c := cmds.Cursor()
+		for k, _ := c.First(); k != nil && n > p.MaxCmds; k, _ = c.Next() {
+			cmdsToDel = append(cmdsToDel, k)
+			n--
+		}
+	}
+	if p.MaxCmdAge > 0 {
+		cutoff := now().Add(-p.MaxCmdAge)
+		c := times.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if unmarshalTime(v).Before(cutoff) {
+				cmdsToDel = append(cmdsToDel, k)
+			}
+		}
+	}
+	for _, k := range cmdsToDel {
+		if err := cmds.Delete(k); err != nil {
+			return err
+		}
+		if err := times.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	if p.MinDirScore > 0 {
+		dirs := tx.Bucket([]byte(bucketDir))
+		var dirsToDel [][]byte
+		c := dirs.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if unmarshalScore(v) < p.MinDirScore {
+				dirsToDel = append(dirsToDel, k)
+			}
+		}
+		for _, k := range dirsToDel {
+			if err := dirs.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Opens the database file after it has been replaced. This is a variable to
+// allow tests to simulate failures.
+var openRewritten = dbWithDefaultOptions
+
+// Copies the content of the database to a new file, and replaces the database
+// file with it. Deleting entries in bolt does not shrink the database file, so
+// this is needed to actually reclaim space.
+//
+// The files are swapped while the database is still open, so that the store
+// remains usable if anything fails: the open database follows its file when
+// it is renamed, and the old file is moved back if the new one can't be
+// opened. On Windows, where open files can't be renamed, the database is
+// closed first, and the old file is reopened on failure.
+func (s *dbStore) rewrite() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := s.db.Path()
+	newPath, oldPath := path+".compact", path+".old"
+	// Remove any leftovers from an earlier failed compaction.
+	os.Remove(newPath)
+	os.Remove(oldPath)
+	newDB, err := dbWithDefaultOptions(newPath)
+	if err != nil {
+		return err
+	}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return newDB.Update(func(newTx *bolt.Tx) error {
+			return copyBuckets(tx, newTx)
+		})
+	})
+	if closeErr := newDB.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(newPath)
+		return err
+	}
+
+	closed := false
+	err = os.Rename(path, oldPath)
+	if err != nil {
+		closed = true
+		s.db.Close()
+		err = os.Rename(path, oldPath)
+	}
+	if err == nil {
+		err = os.Rename(newPath, path)
+		if err != nil {
+			os.Rename(oldPath, path)
+		}
+	}
+	if err == nil {
+		newDB, err = openRewritten(path)
+		if err == nil {
+			if !closed {
+				s.db.Close()
+			}
+			s.db = newDB
+			os.Remove(oldPath)
+			return nil
+		}
+		os.Rename(oldPath, path)
+	}
+	os.Remove(newPath)
+	if closed {
+		db, reopenErr := dbWithDefaultOptions(path)
+		if reopenErr != nil {
+			return reopenErr
+		}
+		s.db = db
+	}
+	return err
+}
+
+func copyBuckets(tx, newTx *bolt.Tx) error {
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		newB, err := newTx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		err = newB.SetSequence(b.Sequence())
+		if err != nil {
+			return err
+		}
+		return b.ForEach(newB.Put)
+	})
+}
+
+func marshalTime(t time.Time) []byte {
+	return marshalSeq(uint64(t.Unix()))
+}
+
+func unmarshalTime(data []byte) time.Time {
+	return time.Unix(int64(unmarshalSeq(data)), 0)
+}
+
+func getUint64(b *bolt.Bucket, key string) uint64 {
+	v := b.Get([]byte(key))
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
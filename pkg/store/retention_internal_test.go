@@ -0,0 +1,69 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCompact_PrunesOldCmds(t *testing.T) {
+	tStore, cleanup := MustGetTempStore()
+	defer cleanup()
+
+	t0 := time.Unix(1600000000, 0)
+	defer func(f func() time.Time) { now = f }(now)
+	for i, cmd := range []string{"echo foo", "echo bar", "echo lorem"} {
+		now = func() time.Time { return t0.Add(time.Duration(i) * time.Hour) }
+		tStore.AddCmd(cmd)
+	}
+
+	tStore.SetRetentionPolicy(RetentionPolicy{MaxCmdAge: 90 * time.Minute})
+	err := tStore.Compact()
+	if err != nil {
+		t.Errorf("Compact() => %v, want nil", err)
+	}
+
+	cmds, _ := tStore.Cmds(0, 100)
+	wantCmds := []string{"echo bar", "echo lorem"}
+	if !reflect.DeepEqual(cmds, wantCmds) {
+		t.Errorf("After Compact, Cmds() => %v, want %v", cmds, wantCmds)
+	}
+}
+
+func TestCompact_KeepsDatabaseUsableOnFailure(t *testing.T) {
+	tStore, cleanup := MustGetTempStore()
+	defer cleanup()
+	path := tStore.(*dbStore).db.Path()
+	tStore.AddCmd("echo foo")
+
+	errOpen := errors.New("cannot open")
+	defer func(f func(string) (*bolt.DB, error)) { openRewritten = f }(openRewritten)
+	openRewritten = func(string) (*bolt.DB, error) { return nil, errOpen }
+	err := tStore.Compact()
+	if err != errOpen {
+		t.Errorf("Compact() => %v, want %v", err, errOpen)
+	}
+
+	// The store still uses the database file.
+	tStore.AddCmd("echo bar")
+	tStore.Close()
+	reopened, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	cmds, _ := reopened.Cmds(0, 100)
+	wantCmds := []string{"echo foo", "echo bar"}
+	if !reflect.DeepEqual(cmds, wantCmds) {
+		t.Errorf("After failed Compact, Cmds() => %v, want %v", cmds, wantCmds)
+	}
+	for _, leftover := range []string{path + ".compact", path + ".old"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s left behind", leftover)
+		}
+	}
+}
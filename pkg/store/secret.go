@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNoSecret is returned by Store.Secret when there is no such secret.
+var ErrNoSecret = errors.New("no such secret")
+
+func init() {
+	initDB["initialize secret table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketSecret))
+		return err
+	}
+}
+
+// Secret gets the value of a secret. The value is stored as is; encrypting and
+// decrypting it is up to the caller.
+func (s *dbStore) Secret(n string) ([]byte, error) {
+	var value []byte
+	err := s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSecret))
+		v := b.Get([]byte(n))
+		if v == nil {
+			return ErrNoSecret
+		}
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	return value, err
+}
+
+// SetSecret sets the value of a secret.
+func (s *dbStore) SetSecret(n string, v []byte) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSecret))
+		return b.Put([]byte(n), v)
+	})
+}
+
+// DelSecret deletes a secret.
+func (s *dbStore) DelSecret(n string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSecret))
+		return b.Delete([]byte(n))
+	})
+}
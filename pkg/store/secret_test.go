@@ -0,0 +1,14 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/store"
+	"github.com/elves/elvish/pkg/store/storetest"
+)
+
+func TestSecret(t *testing.T) {
+	tStore, cleanup := store.MustGetTempStore()
+	defer cleanup()
+	storetest.TestSecret(t, tStore)
+}
@@ -19,7 +19,7 @@ func init() {
 // SharedVar gets the value of a shared variable.
 func (s *dbStore) SharedVar(n string) (string, error) {
 	var value string
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketSharedVar))
 		v := b.Get([]byte(n))
 		if v == nil {
@@ -33,7 +33,7 @@ func (s *dbStore) SharedVar(n string) (string, error) {
 
 // SetSharedVar sets the value of a shared variable.
 func (s *dbStore) SetSharedVar(n, v string) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
+	return s.update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketSharedVar))
 		return b.Put([]byte(n), []byte(v))
 	})
@@ -41,7 +41,7 @@ func (s *dbStore) SetSharedVar(n, v string) error {
 
 // DelSharedVar deletes a shared variable.
 func (s *dbStore) DelSharedVar(n string) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
+	return s.update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketSharedVar))
 		return b.Delete([]byte(n))
 	})
@@ -25,9 +25,24 @@ type Store interface {
 	DelDir(dir string) error
 	Dirs(blacklist map[string]struct{}) ([]Dir, error)
 
+	PushDir(dir string) error
+	PopDir() (string, error)
+	DirStack() ([]string, error)
+
 	SharedVar(name string) (string, error)
 	SetSharedVar(name, value string) error
 	DelSharedVar(name string) error
+
+	Secret(name string) ([]byte, error)
+	SetSecret(name string, value []byte) error
+	DelSecret(name string) error
+
+	EnvTrust(path string) (EnvTrust, error)
+	SetEnvTrust(path string, t EnvTrust) error
+
+	RetentionPolicy() (RetentionPolicy, error)
+	SetRetentionPolicy(p RetentionPolicy) error
+	Compact() error
 }
 
 // Dir is an entry in the directory history.
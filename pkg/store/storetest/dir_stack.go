@@ -0,0 +1,43 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/pkg/store"
+)
+
+// TestDirStack tests the directory stack functionality of a Store.
+func TestDirStack(t *testing.T, tStore store.Store) {
+	// Popping from an empty stack should return ErrEmptyDirStack.
+	_, err := tStore.PopDir()
+	if !matchErr(err, store.ErrEmptyDirStack) {
+		t.Error("want ErrEmptyDirStack, got", err)
+	}
+
+	for _, dir := range []string{"/usr", "/tmp", "/usr"} {
+		err := tStore.PushDir(dir)
+		if err != nil {
+			t.Errorf("tStore.PushDir(%q) => %v, want nil", dir, err)
+		}
+	}
+	dirs, err := tStore.DirStack()
+	wantDirs := []string{"/usr", "/tmp", "/usr"}
+	if !reflect.DeepEqual(dirs, wantDirs) || err != nil {
+		t.Errorf("tStore.DirStack() => (%v, %v), want (%v, nil)",
+			dirs, err, wantDirs)
+	}
+
+	// Directories are popped in reverse order.
+	for _, wantDir := range []string{"/usr", "/tmp", "/usr"} {
+		dir, err := tStore.PopDir()
+		if dir != wantDir || err != nil {
+			t.Errorf("tStore.PopDir() => (%q, %v), want (%q, nil)",
+				dir, err, wantDir)
+		}
+	}
+	dirs, err = tStore.DirStack()
+	if len(dirs) != 0 || err != nil {
+		t.Errorf("tStore.DirStack() => (%v, %v), want (empty, nil)", dirs, err)
+	}
+}
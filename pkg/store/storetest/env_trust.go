@@ -0,0 +1,42 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/store"
+)
+
+// TestEnvTrust tests the env trust functionality of a Store.
+func TestEnvTrust(t *testing.T, tStore store.Store) {
+	path := "/home/elf/project/.elvish-env"
+	allowed := store.EnvTrust{Allowed: true, Hash: "0123abcd"}
+	denied := store.EnvTrust{Denied: true}
+
+	// A file that has never been allowed or denied gets the zero value.
+	testEnvTrust(t, tStore, path, store.EnvTrust{})
+
+	// Allowing and denying a file.
+	setEnvTrust(t, tStore, path, allowed)
+	testEnvTrust(t, tStore, path, allowed)
+	setEnvTrust(t, tStore, path, denied)
+	testEnvTrust(t, tStore, path, denied)
+
+	// Setting the zero value forgets the file.
+	setEnvTrust(t, tStore, path, store.EnvTrust{})
+	testEnvTrust(t, tStore, path, store.EnvTrust{})
+}
+
+func setEnvTrust(t *testing.T, tStore store.Store, path string, trust store.EnvTrust) {
+	t.Helper()
+	if err := tStore.SetEnvTrust(path, trust); err != nil {
+		t.Errorf("SetEnvTrust(%q, %v) -> %v, want no error", path, trust, err)
+	}
+}
+
+func testEnvTrust(t *testing.T, tStore store.Store, path string, want store.EnvTrust) {
+	t.Helper()
+	got, err := tStore.EnvTrust(path)
+	if got != want || err != nil {
+		t.Errorf("EnvTrust(%q) -> (%v, %v), want (%v, nil)", path, got, err, want)
+	}
+}
@@ -0,0 +1,69 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/pkg/store"
+)
+
+// TestRetention tests the retention functionality of a Store.
+func TestRetention(t *testing.T, tStore store.Store) {
+	p, err := tStore.RetentionPolicy()
+	if p != (store.RetentionPolicy{}) || err != nil {
+		t.Errorf("tStore.RetentionPolicy() => (%v, %v), want zero value and nil", p, err)
+	}
+
+	wantPolicy := store.RetentionPolicy{
+		MaxCmds: 2, MaxCmdAge: time.Hour, MinDirScore: 5}
+	err = tStore.SetRetentionPolicy(wantPolicy)
+	if err != nil {
+		t.Errorf("tStore.SetRetentionPolicy(%v) => %v, want nil", wantPolicy, err)
+	}
+	p, err = tStore.RetentionPolicy()
+	if p != wantPolicy || err != nil {
+		t.Errorf("tStore.RetentionPolicy() => (%v, %v), want (%v, nil)",
+			p, err, wantPolicy)
+	}
+
+	startSeq, _ := tStore.NextCmdSeq()
+	for _, cmd := range []string{"echo foo", "echo bar", "echo lorem"} {
+		tStore.AddCmd(cmd)
+	}
+	// The score of /usr decays to below 5 after 50 more directories are added.
+	tStore.AddDir("/usr", 1)
+	for i := 0; i < 50; i++ {
+		tStore.AddDir("/tmp", 1)
+	}
+
+	err = tStore.Compact()
+	if err != nil {
+		t.Errorf("tStore.Compact() => %v, want nil", err)
+	}
+
+	cmds, err := tStore.CmdsWithSeq(0, startSeq+3)
+	wantCmds := []store.Cmd{
+		{Text: "echo bar", Seq: startSeq + 1}, {Text: "echo lorem", Seq: startSeq + 2}}
+	if !reflect.DeepEqual(cmds, wantCmds) || err != nil {
+		t.Errorf("After Compact, tStore.CmdsWithSeq() => (%v, %v), want (%v, nil)",
+			cmds, err, wantCmds)
+	}
+	dirs, err := tStore.Dirs(store.NoBlacklist)
+	if len(dirs) == 0 || dirs[0].Path != "/tmp" || err != nil {
+		t.Errorf("After Compact, tStore.Dirs() => (%v, %v), want /tmp first",
+			dirs, err)
+	}
+	for _, dir := range dirs {
+		if dir.Path == "/usr" {
+			t.Errorf("After Compact, tStore.Dirs() => %v, want /usr pruned", dirs)
+		}
+	}
+
+	// The sequence of commands is preserved by compaction.
+	seq, err := tStore.NextCmdSeq()
+	if seq != startSeq+3 || err != nil {
+		t.Errorf("After Compact, tStore.NextCmdSeq() => (%v, %v), want (%v, nil)",
+			seq, err, startSeq+3)
+	}
+}
@@ -0,0 +1,51 @@
+package storetest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elves/elvish/pkg/store"
+)
+
+// TestSecret tests the secret functionality of a Store.
+func TestSecret(t *testing.T, tStore store.Store) {
+	name := "token"
+	value1 := []byte("\x00opaque\xff")
+	value2 := []byte("another opaque value")
+
+	// Getting an nonexistent secret should return ErrNoSecret.
+	_, err := tStore.Secret(name)
+	if !matchErr(err, store.ErrNoSecret) {
+		t.Error("want ErrNoSecret, got", err)
+	}
+
+	// Setting a secret for the first time creates it.
+	err = tStore.SetSecret(name, value1)
+	if err != nil {
+		t.Error("want no error, got", err)
+	}
+	v, err := tStore.Secret(name)
+	if !bytes.Equal(v, value1) || err != nil {
+		t.Errorf("want %q and no error, got %q and %v", value1, v, err)
+	}
+
+	// Setting an existing secret updates its value.
+	err = tStore.SetSecret(name, value2)
+	if err != nil {
+		t.Error("want no error, got", err)
+	}
+	v, err = tStore.Secret(name)
+	if !bytes.Equal(v, value2) || err != nil {
+		t.Errorf("want %q and no error, got %q and %v", value2, v, err)
+	}
+
+	// After deleting a secret, access to it cause ErrNoSecret.
+	err = tStore.DelSecret(name)
+	if err != nil {
+		t.Error("want no error, got", err)
+	}
+	_, err = tStore.Secret(name)
+	if !matchErr(err, store.ErrNoSecret) {
+		t.Error("want ErrNoSecret, got", err)
+	}
+}
@@ -0,0 +1,44 @@
+package sys
+
+import "golang.org/x/sys/unix"
+
+func init() {
+	// RLIMIT_AS is not available on all Unix systems.
+	RlimitResources["as"] = unix.RLIMIT_AS
+}
+
+// GetNice returns the niceness of a process. A pid of 0 means the current
+// process.
+func GetNice(pid int) (int, error) {
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, pid)
+	if err != nil {
+		return 0, err
+	}
+	// The raw system call returns 20 minus the niceness, so that the return
+	// value is never negative.
+	return 20 - prio, nil
+}
+
+// I/O scheduling classes used in SetIOPriority.
+const (
+	IOPrioClassRealtime   = 1
+	IOPrioClassBestEffort = 2
+	IOPrioClassIdle       = 3
+)
+
+// From the ioprio_set(2) manual page.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// SetIOPriority sets the I/O scheduling class and the priority level within
+// the class of a process.
+func SetIOPriority(pid, class, level int) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET,
+		ioprioWhoProcess, uintptr(pid), uintptr(class<<ioprioClassShift|level))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
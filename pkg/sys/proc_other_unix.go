@@ -0,0 +1,24 @@
+// +build !windows,!plan9,!linux
+
+package sys
+
+import "golang.org/x/sys/unix"
+
+// GetNice returns the niceness of a process. A pid of 0 means the current
+// process.
+func GetNice(pid int) (int, error) {
+	return unix.Getpriority(unix.PRIO_PROCESS, pid)
+}
+
+// I/O scheduling classes used in SetIOPriority.
+const (
+	IOPrioClassRealtime = 1 + iota
+	IOPrioClassBestEffort
+	IOPrioClassIdle
+)
+
+// SetIOPriority sets the I/O scheduling class and the priority level within
+// the class of a process. It is only supported on Linux.
+func SetIOPriority(pid, class, level int) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,55 @@
+// +build !windows,!plan9
+
+package sys
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrNotSupported is returned by functions that are not supported on the
+// current platform.
+var ErrNotSupported = errors.New("not supported on this platform")
+
+// Rlimit is the soft and hard limit of a resource.
+type Rlimit struct {
+	Cur, Max uint64
+}
+
+// RlimInfinity is the value of a limit that means no limit.
+const RlimInfinity uint64 = unix.RLIM_INFINITY
+
+// RlimitResources maps the names of resources that can be limited to their
+// values used in Getrlimit and Setrlimit.
+var RlimitResources = map[string]int{
+	"core":   unix.RLIMIT_CORE,
+	"cpu":    unix.RLIMIT_CPU,
+	"data":   unix.RLIMIT_DATA,
+	"fsize":  unix.RLIMIT_FSIZE,
+	"nofile": unix.RLIMIT_NOFILE,
+	"stack":  unix.RLIMIT_STACK,
+}
+
+// Getrlimit returns the limit of a resource for the current process.
+func Getrlimit(resource int) (Rlimit, error) {
+	var rlim unix.Rlimit
+	err := unix.Getrlimit(resource, &rlim)
+	// The fields are signed on some systems.
+	return Rlimit{uint64(rlim.Cur), uint64(rlim.Max)}, err
+}
+
+// Setrlimit sets the limit of a resource for the current process.
+func Setrlimit(resource int, rlim Rlimit) error {
+	var new unix.Rlimit
+	// The fields are signed on some systems, but always 64 bits wide.
+	*(*uint64)(unsafe.Pointer(&new.Cur)) = rlim.Cur
+	*(*uint64)(unsafe.Pointer(&new.Max)) = rlim.Max
+	return unix.Setrlimit(resource, &new)
+}
+
+// SetNice sets the niceness of a process.
+func SetNice(pid, nice int) error {
+	return unix.Setpriority(unix.PRIO_PROCESS, pid, nice)
+}
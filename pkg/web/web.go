@@ -31,6 +31,9 @@ func (program) Run(fds [3]*os.File, f *prog.Flags, args []string) error {
 	if f.CodeInArg {
 		return prog.BadUsage("-c cannot be used together with -web")
 	}
+	if len(f.Eval) > 0 {
+		return prog.BadUsage("-e cannot be used together with -web")
+	}
 	p := Web{BinPath: f.Bin, SockPath: f.Sock, DbPath: f.DB, Port: f.Port}
 	return p.Main(fds, nil)
 }